@@ -0,0 +1,797 @@
+package abi
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/pkg/errors"
+)
+
+type Kind int
+
+const (
+	KindUint Kind = iota
+	KindUfixed
+	KindByte
+	KindBool
+	KindAddress
+	KindString
+	KindStaticArray
+	KindDynamicArray
+	KindTuple
+)
+
+const (
+	addressLength = 32
+	lengthSize    = 2
+)
+
+type Type struct {
+	Kind Kind
+
+	Size      int // bit size for uint/ufixed, length for static arrays
+	Precision int // ufixed precision
+
+	Elem   *Type
+	Fields []Type
+}
+
+func (t Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return fmt.Sprintf("uint%d", t.Size)
+	case KindUfixed:
+		return fmt.Sprintf("ufixed%dx%d", t.Size, t.Precision)
+	case KindByte:
+		return "byte"
+	case KindBool:
+		return "bool"
+	case KindAddress:
+		return "address"
+	case KindString:
+		return "string"
+	case KindStaticArray:
+		return fmt.Sprintf("%s[%d]", t.Elem.String(), t.Size)
+	case KindDynamicArray:
+		return fmt.Sprintf("%s[]", t.Elem.String())
+	case KindTuple:
+		names := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			names[i] = f.String()
+		}
+		return fmt.Sprintf("(%s)", strings.Join(names, ","))
+	default:
+		return "(unknown)"
+	}
+}
+
+// IsDynamic reports whether the encoded length of the type depends on the value.
+func (t Type) IsDynamic() bool {
+	switch t.Kind {
+	case KindString, KindDynamicArray:
+		return true
+	case KindStaticArray:
+		return t.Elem.IsDynamic()
+	case KindTuple:
+		for _, f := range t.Fields {
+			if f.IsDynamic() {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// ByteLen returns the encoded length of a static type.
+func (t Type) ByteLen() (int, error) {
+	switch t.Kind {
+	case KindUint, KindUfixed:
+		return t.Size / 8, nil
+	case KindByte, KindBool:
+		return 1, nil
+	case KindAddress:
+		return addressLength, nil
+	case KindStaticArray:
+		if t.Elem.Kind == KindBool {
+			return (t.Size + 7) / 8, nil
+		}
+
+		l, err := t.Elem.ByteLen()
+		if err != nil {
+			return 0, err
+		}
+
+		return l * t.Size, nil
+	case KindTuple:
+		total := 0
+		for i := 0; i < len(t.Fields); i++ {
+			f := t.Fields[i]
+			if f.Kind == KindBool {
+				n := boolRun(t.Fields, i)
+				total += (n + 7) / 8
+				i += n - 1
+				continue
+			}
+
+			l, err := f.ByteLen()
+			if err != nil {
+				return 0, err
+			}
+
+			total += l
+		}
+		return total, nil
+	default:
+		return 0, errors.Errorf("dynamic type has no static length: %s", t)
+	}
+}
+
+func boolRun(ts []Type, i int) int {
+	n := 0
+	for j := i; j < len(ts) && ts[j].Kind == KindBool; j++ {
+		n++
+	}
+	return n
+}
+
+func ParseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return Type{}, errors.New("empty type")
+	}
+
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open == -1 {
+			return Type{}, errors.Errorf("missing array open bracket: %s", s)
+		}
+
+		elem, err := ParseType(s[:open])
+		if err != nil {
+			return Type{}, err
+		}
+
+		n := s[open+1 : len(s)-1]
+		if n == "" {
+			return Type{Kind: KindDynamicArray, Elem: &elem}, nil
+		}
+
+		if n[0] == '0' && len(n) > 1 {
+			return Type{}, errors.Errorf("static array length with leading zero: %s", s)
+		}
+
+		l, err := strconv.ParseUint(n, 10, 16)
+		if err != nil {
+			return Type{}, errors.Wrapf(err, "failed to parse static array length: %s", s)
+		}
+
+		return Type{Kind: KindStaticArray, Elem: &elem, Size: int(l)}, nil
+	}
+
+	if strings.HasPrefix(s, "(") {
+		if !strings.HasSuffix(s, ")") {
+			return Type{}, errors.Errorf("missing tuple close paren: %s", s)
+		}
+
+		parts, err := SplitTuple(s[1 : len(s)-1])
+		if err != nil {
+			return Type{}, err
+		}
+
+		fs := make([]Type, len(parts))
+		for i, p := range parts {
+			f, err := ParseType(p)
+			if err != nil {
+				return Type{}, err
+			}
+			fs[i] = f
+		}
+
+		return Type{Kind: KindTuple, Fields: fs}, nil
+	}
+
+	switch s {
+	case "byte":
+		return Type{Kind: KindByte}, nil
+	case "bool":
+		return Type{Kind: KindBool}, nil
+	case "address":
+		return Type{Kind: KindAddress}, nil
+	case "string":
+		return Type{Kind: KindString}, nil
+	}
+
+	if strings.HasPrefix(s, "uint") {
+		n, err := parseBitSize(s[4:])
+		if err != nil {
+			return Type{}, errors.Wrapf(err, "invalid uint type: %s", s)
+		}
+
+		return Type{Kind: KindUint, Size: n}, nil
+	}
+
+	if strings.HasPrefix(s, "ufixed") {
+		parts := strings.SplitN(s[6:], "x", 2)
+		if len(parts) != 2 {
+			return Type{}, errors.Errorf("invalid ufixed type: %s", s)
+		}
+
+		n, err := parseBitSize(parts[0])
+		if err != nil {
+			return Type{}, errors.Wrapf(err, "invalid ufixed type: %s", s)
+		}
+
+		p, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil || p < 1 || p > 160 {
+			return Type{}, errors.Errorf("invalid ufixed precision: %s", s)
+		}
+
+		return Type{Kind: KindUfixed, Size: n, Precision: int(p)}, nil
+	}
+
+	return Type{}, errors.Errorf("unknown type: %s", s)
+}
+
+func parseBitSize(s string) (int, error) {
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 8 || n > 512 || n%8 != 0 {
+		return 0, errors.Errorf("bit size must be a multiple of 8 in range [8, 512], got: %d", n)
+	}
+
+	return int(n), nil
+}
+
+// SplitTuple splits comma separated tuple elements respecting nested parens.
+func SplitTuple(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+
+	var res []string
+
+	depth := 0
+	p := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.Errorf("unbalanced parens: %s", s)
+			}
+		case ',':
+			if depth == 0 {
+				res = append(res, s[p:i])
+				p = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, errors.Errorf("unbalanced parens: %s", s)
+	}
+
+	res = append(res, s[p:])
+
+	for _, r := range res {
+		if r == "" {
+			return nil, errors.Errorf("empty tuple element: %s", s)
+		}
+	}
+
+	return res, nil
+}
+
+func (t Type) childTypes(v interface{}) ([]Type, []interface{}, error) {
+	switch t.Kind {
+	case KindTuple:
+		vs, err := toSlice(v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(vs) != len(t.Fields) {
+			return nil, nil, errors.Errorf("tuple length mismatch - expected: %d, got: %d", len(t.Fields), len(vs))
+		}
+
+		return t.Fields, vs, nil
+	case KindStaticArray, KindDynamicArray:
+		vs, err := toSlice(v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if t.Kind == KindStaticArray && len(vs) != t.Size {
+			return nil, nil, errors.Errorf("static array length mismatch - expected: %d, got: %d", t.Size, len(vs))
+		}
+
+		ts := make([]Type, len(vs))
+		for i := range ts {
+			ts[i] = *t.Elem
+		}
+
+		return ts, vs, nil
+	default:
+		return nil, nil, errors.Errorf("not a compound type: %s", t)
+	}
+}
+
+func toSlice(v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, nil
+	case []byte:
+		res := make([]interface{}, len(v))
+		for i, b := range v {
+			res[i] = b
+		}
+		return res, nil
+	case string:
+		res := make([]interface{}, len(v))
+		for i := 0; i < len(v); i++ {
+			res[i] = v[i]
+		}
+		return res, nil
+	default:
+		return nil, errors.Errorf("unsupported compound value: %T", v)
+	}
+}
+
+func toBigInt(v interface{}) (*big.Int, error) {
+	switch v := v.(type) {
+	case *big.Int:
+		return v, nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case uint8:
+		return big.NewInt(int64(v)), nil
+	case uint32:
+		return big.NewInt(int64(v)), nil
+	case int:
+		if v < 0 {
+			return nil, errors.Errorf("negative value: %d", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case int64:
+		if v < 0 {
+			return nil, errors.Errorf("negative value: %d", v)
+		}
+		return big.NewInt(v), nil
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return nil, errors.Errorf("not an unsigned integer: %v", v)
+		}
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case json.Number:
+		return toBigInt(string(v))
+	case string:
+		i, ok := new(big.Int).SetString(v, 0)
+		if !ok || i.Sign() < 0 {
+			return nil, errors.Errorf("not an unsigned integer: %s", v)
+		}
+		return i, nil
+	default:
+		return nil, errors.Errorf("unsupported integer value: %T", v)
+	}
+}
+
+func parseFixed(v interface{}, precision int) (*big.Int, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = string(v)
+	default:
+		return toBigInt(v)
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+
+	frac := ""
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+
+	if len(frac) > precision {
+		return nil, errors.Errorf("too many decimal places - max: %d, got: %s", precision, s)
+	}
+
+	frac += strings.Repeat("0", precision-len(frac))
+
+	return toBigInt(parts[0] + frac)
+}
+
+func encodeUint(i *big.Int, size int) ([]byte, error) {
+	n := size / 8
+	if i.BitLen() > size {
+		return nil, errors.Errorf("value overflows uint%d: %s", size, i)
+	}
+
+	return i.FillBytes(make([]byte, n)), nil
+}
+
+func Encode(t Type, v interface{}) ([]byte, error) {
+	switch t.Kind {
+	case KindUint:
+		i, err := toBigInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return encodeUint(i, t.Size)
+	case KindUfixed:
+		i, err := parseFixed(v, t.Precision)
+		if err != nil {
+			return nil, err
+		}
+		return encodeUint(i, t.Size)
+	case KindByte:
+		i, err := toBigInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return encodeUint(i, 8)
+	case KindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.Errorf("not a bool: %v", v)
+		}
+		if b {
+			return []byte{0x80}, nil
+		}
+		return []byte{0x00}, nil
+	case KindAddress:
+		switch v := v.(type) {
+		case string:
+			a, err := types.DecodeAddress(v)
+			if err != nil {
+				return nil, err
+			}
+			return a[:], nil
+		case types.Address:
+			return v[:], nil
+		case []byte:
+			if len(v) != addressLength {
+				return nil, errors.Errorf("address must be %d bytes, got: %d", addressLength, len(v))
+			}
+			return v, nil
+		default:
+			return nil, errors.Errorf("unsupported address value: %T", v)
+		}
+	case KindString:
+		var bs []byte
+		switch v := v.(type) {
+		case string:
+			bs = []byte(v)
+		case []byte:
+			bs = v
+		default:
+			return nil, errors.Errorf("not a string: %T", v)
+		}
+
+		if len(bs) > 0xffff {
+			return nil, errors.Errorf("string too long: %d", len(bs))
+		}
+
+		res := make([]byte, lengthSize, lengthSize+len(bs))
+		binary.BigEndian.PutUint16(res, uint16(len(bs)))
+		return append(res, bs...), nil
+	case KindStaticArray, KindDynamicArray, KindTuple:
+		ts, vs, err := t.childTypes(v)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := encodeTuple(ts, vs)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Kind == KindDynamicArray {
+			if len(vs) > 0xffff {
+				return nil, errors.Errorf("array too long: %d", len(vs))
+			}
+
+			res := make([]byte, lengthSize, lengthSize+len(body))
+			binary.BigEndian.PutUint16(res, uint16(len(vs)))
+			return append(res, body...), nil
+		}
+
+		return body, nil
+	default:
+		return nil, errors.Errorf("unsupported type: %s", t)
+	}
+}
+
+func encodeTuple(ts []Type, vs []interface{}) ([]byte, error) {
+	var heads [][]byte
+	var tails [][]byte
+	var dyn []bool
+
+	for i := 0; i < len(ts); i++ {
+		t := ts[i]
+
+		if t.Kind == KindBool {
+			n := boolRun(ts, i)
+			packed := make([]byte, (n+7)/8)
+			for j := 0; j < n; j++ {
+				b, ok := vs[i+j].(bool)
+				if !ok {
+					return nil, errors.Errorf("not a bool: %v", vs[i+j])
+				}
+				if b {
+					packed[j/8] |= 0x80 >> (j % 8)
+				}
+			}
+
+			heads = append(heads, packed)
+			tails = append(tails, nil)
+			dyn = append(dyn, false)
+
+			i += n - 1
+			continue
+		}
+
+		enc, err := Encode(t, vs[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode element %d", i)
+		}
+
+		if t.IsDynamic() {
+			heads = append(heads, make([]byte, lengthSize))
+			tails = append(tails, enc)
+			dyn = append(dyn, true)
+		} else {
+			heads = append(heads, enc)
+			tails = append(tails, nil)
+			dyn = append(dyn, false)
+		}
+	}
+
+	headLen := 0
+	for _, h := range heads {
+		headLen += len(h)
+	}
+
+	offset := headLen
+	for i, d := range dyn {
+		if !d {
+			continue
+		}
+
+		if offset > 0xffff {
+			return nil, errors.New("encoded value too long")
+		}
+
+		binary.BigEndian.PutUint16(heads[i], uint16(offset))
+		offset += len(tails[i])
+	}
+
+	res := make([]byte, 0, offset)
+	for _, h := range heads {
+		res = append(res, h...)
+	}
+	for _, t := range tails {
+		res = append(res, t...)
+	}
+
+	return res, nil
+}
+
+// Decode decodes an encoded value. Integers up to 64 bits decode to uint64,
+// larger ones to *big.Int, ufixed values to decimal strings, addresses to
+// their checksummed string form, byte arrays to []byte and other compound
+// types to []interface{}.
+func Decode(t Type, bs []byte) (interface{}, error) {
+	switch t.Kind {
+	case KindUint, KindByte, KindUfixed, KindBool, KindAddress:
+		l, _ := t.ByteLen()
+		if len(bs) != l {
+			return nil, errors.Errorf("%s must be %d bytes, got: %d", t, l, len(bs))
+		}
+	}
+
+	switch t.Kind {
+	case KindUint:
+		i := new(big.Int).SetBytes(bs)
+		if t.Size <= 64 {
+			return i.Uint64(), nil
+		}
+		return i, nil
+	case KindUfixed:
+		return formatFixed(new(big.Int).SetBytes(bs), t.Precision), nil
+	case KindByte:
+		return bs[0], nil
+	case KindBool:
+		switch bs[0] {
+		case 0x80:
+			return true, nil
+		case 0x00:
+			return false, nil
+		default:
+			return nil, errors.Errorf("invalid bool encoding: 0x%02x", bs[0])
+		}
+	case KindAddress:
+		var a types.Address
+		copy(a[:], bs)
+		return a.String(), nil
+	case KindString:
+		if len(bs) < lengthSize {
+			return nil, errors.New("string too short")
+		}
+
+		l := int(binary.BigEndian.Uint16(bs))
+		if len(bs) != lengthSize+l {
+			return nil, errors.Errorf("string length mismatch - expected: %d, got: %d", l, len(bs)-lengthSize)
+		}
+
+		return string(bs[lengthSize:]), nil
+	case KindStaticArray, KindDynamicArray:
+		n := t.Size
+		if t.Kind == KindDynamicArray {
+			if len(bs) < lengthSize {
+				return nil, errors.New("array too short")
+			}
+
+			n = int(binary.BigEndian.Uint16(bs))
+			bs = bs[lengthSize:]
+		}
+
+		ts := make([]Type, n)
+		for i := range ts {
+			ts[i] = *t.Elem
+		}
+
+		vs, err := decodeTuple(ts, bs)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Elem.Kind == KindByte {
+			res := make([]byte, len(vs))
+			for i, v := range vs {
+				res[i] = v.(byte)
+			}
+			return res, nil
+		}
+
+		return vs, nil
+	case KindTuple:
+		return decodeTuple(t.Fields, bs)
+	default:
+		return nil, errors.Errorf("unsupported type: %s", t)
+	}
+}
+
+func decodeTuple(ts []Type, bs []byte) ([]interface{}, error) {
+	res := make([]interface{}, len(ts))
+
+	type segment struct {
+		index  int
+		offset int
+	}
+
+	var dyns []segment
+
+	p := 0
+	for i := 0; i < len(ts); i++ {
+		t := ts[i]
+
+		if t.Kind == KindBool {
+			n := boolRun(ts, i)
+			l := (n + 7) / 8
+			if p+l > len(bs) {
+				return nil, errors.New("encoded value too short")
+			}
+
+			for j := 0; j < n; j++ {
+				res[i+j] = bs[p+j/8]&(0x80>>(j%8)) != 0
+			}
+
+			p += l
+			i += n - 1
+			continue
+		}
+
+		if t.IsDynamic() {
+			if p+lengthSize > len(bs) {
+				return nil, errors.New("encoded value too short")
+			}
+
+			dyns = append(dyns, segment{index: i, offset: int(binary.BigEndian.Uint16(bs[p:]))})
+			p += lengthSize
+			continue
+		}
+
+		l, err := t.ByteLen()
+		if err != nil {
+			return nil, err
+		}
+
+		if p+l > len(bs) {
+			return nil, errors.New("encoded value too short")
+		}
+
+		v, err := Decode(t, bs[p:p+l])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode element %d", i)
+		}
+
+		res[i] = v
+		p += l
+	}
+
+	if len(dyns) == 0 && p != len(bs) {
+		return nil, errors.Errorf("unexpected trailing bytes: %d", len(bs)-p)
+	}
+
+	for i, d := range dyns {
+		end := len(bs)
+		if i < len(dyns)-1 {
+			end = dyns[i+1].offset
+		}
+
+		if d.offset < p || d.offset > end || end > len(bs) {
+			return nil, errors.Errorf("invalid dynamic element offset: %d", d.offset)
+		}
+
+		v, err := Decode(ts[d.index], bs[d.offset:end])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode element %d", d.index)
+		}
+
+		res[d.index] = v
+	}
+
+	return res, nil
+}
+
+func formatFixed(i *big.Int, precision int) string {
+	s := i.String()
+	if len(s) <= precision {
+		s = strings.Repeat("0", precision-len(s)+1) + s
+	}
+
+	return s[:len(s)-precision] + "." + s[len(s)-precision:]
+}
+
+// Format renders a decoded value in a compact, human readable form.
+func Format(t Type, v interface{}) string {
+	switch t.Kind {
+	case KindString:
+		return strconv.Quote(v.(string))
+	case KindStaticArray, KindDynamicArray:
+		if bs, ok := v.([]byte); ok {
+			return fmt.Sprintf("0x%x", bs)
+		}
+
+		vs := v.([]interface{})
+		items := make([]string, len(vs))
+		for i, item := range vs {
+			items[i] = Format(*t.Elem, item)
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case KindTuple:
+		vs := v.([]interface{})
+		items := make([]string, len(vs))
+		for i, item := range vs {
+			items[i] = Format(t.Fields[i], item)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(items, ", "))
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,199 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []string{
+		"uint64",
+		"uint8",
+		"ufixed64x2",
+		"byte",
+		"bool",
+		"address",
+		"string",
+		"byte[]",
+		"byte[32]",
+		"uint64[3][]",
+		"(uint64,string)",
+		"(bool,(byte[],address)[2])",
+		"()",
+	}
+
+	for _, test := range tests {
+		typ, err := ParseType(test)
+		if err != nil {
+			t.Errorf("failed to parse %s: %s", test, err)
+			continue
+		}
+
+		if typ.String() != test {
+			t.Errorf("unexpected type string - expected: %s, actual: %s", test, typ.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"uint7",
+		"uint0",
+		"uint520",
+		"ufixed64",
+		"ufixed64x0",
+		"byte[01]",
+		"(uint64,",
+		"(uint64,)",
+		"foo",
+	}
+
+	for _, test := range invalid {
+		_, err := ParseType(test)
+		if err == nil {
+			t.Errorf("expected error for: %s", test)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	type test struct {
+		t string
+		v interface{}
+		e string
+	}
+
+	tests := []test{
+		{t: "uint64", v: uint64(1), e: "0000000000000001"},
+		{t: "uint8", v: uint64(255), e: "ff"},
+		{t: "ufixed16x2", v: "1.23", e: "007b"},
+		{t: "bool", v: true, e: "80"},
+		{t: "string", v: "abc", e: "0003616263"},
+		{t: "byte[]", v: []byte{1, 2}, e: "00020102"},
+		{t: "byte[2]", v: []byte{1, 2}, e: "0102"},
+		{t: "bool[3]", v: []interface{}{true, false, true}, e: "a0"},
+		{t: "(bool,bool,uint8)", v: []interface{}{false, true, uint64(7)}, e: "4007"},
+		{t: "(uint16,string)", v: []interface{}{uint64(1), "a"}, e: "0001" + "0004" + "000161"},
+		{t: "string[]", v: []interface{}{"a", "bc"}, e: "0002" + "0004" + "0007" + "000161" + "00026263"},
+	}
+
+	for _, test := range tests {
+		typ, err := ParseType(test.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		enc, err := Encode(typ, test.v)
+		if err != nil {
+			t.Errorf("failed to encode %s: %s", test.t, err)
+			continue
+		}
+
+		if hex.EncodeToString(enc) != test.e {
+			t.Errorf("unexpected encoding of %s - expected: %s, actual: %x", test.t, test.e, enc)
+		}
+
+		dec, err := Decode(typ, enc)
+		if err != nil {
+			t.Errorf("failed to decode %s: %s", test.t, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(dec, test.v) {
+			t.Errorf("unexpected decoded value of %s - expected: %#v, actual: %#v", test.t, test.v, dec)
+		}
+	}
+}
+
+func TestEncodeOverflow(t *testing.T) {
+	typ, err := ParseType("uint8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Encode(typ, uint64(256))
+	if err == nil {
+		t.Error("expected overflow error")
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	m, err := ParseMethod("add(uint64,uint64)uint128")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel, _ := hex.DecodeString("8aa3b61f")
+	if !bytes.Equal(m.Selector(), sel) {
+		t.Errorf("unexpected selector: %x", m.Selector())
+	}
+
+	m, err = ParseMethod("opt(asset,pay,(uint8,bool))void")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Args[0].IsReference() || !m.Args[1].IsTransaction() || m.Args[2].Type == nil || m.Returns != nil {
+		t.Errorf("unexpected method args: %#v", m)
+	}
+
+	args, err := m.EncodeArgs([]interface{}{[]interface{}{uint64(1), true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(args) != 2 || hex.EncodeToString(args[1]) != "0180" {
+		t.Errorf("unexpected encoded args: %x", args)
+	}
+}
+
+func TestCanonicalMethod(t *testing.T) {
+	tests := map[string]string{
+		"add(uint64,uint64)uint128":       "add(uint64,uint64)uint128",
+		"add(uint, uint)uint":             "add(uint64,uint64)uint64",
+		"set((bytes,addr)[ 2 ],acct)void": "set((byte[],address)[2],account)void",
+		"hello(str)str":                   "hello(string)string",
+		"broken(uint":                     "broken(uint",
+	}
+
+	for sig, expected := range tests {
+		if c := CanonicalMethod(sig); c != expected {
+			t.Errorf("unexpected canonical method of %s: %s, expected: %s", sig, c, expected)
+		}
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	m, err := ParseMethod("get()(uint64,string)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := ParseEvent("Paid(uint64)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, _ := hex.DecodeString("151f7c75" + "0000000000000002" + "000a" + "000161")
+	l := DecodeLog(ret, &m, nil)
+	if l.Kind != LogReturn || l.String() != `return (2, "a")` {
+		t.Errorf("unexpected return log: %s", l)
+	}
+
+	ev := append(e.Selector(), 0, 0, 0, 0, 0, 0, 0, 5)
+	l = DecodeLog(ev, &m, []Event{e})
+	if l.Kind != LogEvent || l.String() != "event Paid(5)" {
+		t.Errorf("unexpected event log: %s", l)
+	}
+
+	l = DecodeLog([]byte("hi"), &m, []Event{e})
+	if l.Kind != LogRaw || l.String() != `"hi" (0x6869)` {
+		t.Errorf("unexpected raw log: %s", l)
+	}
+
+	l = DecodeLog([]byte{0x01}, nil, nil)
+	if l.String() != "0x01" {
+		t.Errorf("unexpected binary log: %s", l)
+	}
+}
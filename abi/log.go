@@ -0,0 +1,119 @@
+package abi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReturnPrefix marks a log entry holding an ARC-4 method return value.
+var ReturnPrefix = []byte{0x15, 0x1f, 0x7c, 0x75}
+
+// Event is an ARC-28 event definition.
+type Event struct {
+	Name string
+	Args []Type
+}
+
+// ParseEvent parses an event signature, e.g. "Transfer(address,address,uint64)".
+func ParseEvent(sig string) (Event, error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return Event{}, errors.Errorf("invalid event signature: %s", sig)
+	}
+
+	t, err := ParseType(sig[open:])
+	if err != nil {
+		return Event{}, errors.Wrapf(err, "invalid event args: %s", sig)
+	}
+
+	return Event{
+		Name: sig[:open],
+		Args: t.Fields,
+	}, nil
+}
+
+func (e Event) String() string {
+	return e.Name + Type{Kind: KindTuple, Fields: e.Args}.String()
+}
+
+func (e Event) Selector() []byte {
+	h := sha512.Sum512_256([]byte(e.String()))
+	return h[:SelectorLength]
+}
+
+type LogKind int
+
+const (
+	LogRaw LogKind = iota
+	LogReturn
+	LogEvent
+)
+
+type Log struct {
+	Kind LogKind
+	Raw  []byte
+
+	Event *Event
+	Type  *Type
+	Value interface{}
+}
+
+// DecodeLog interprets a logged value as a method return value or one of the
+// given events, falling back to a raw entry when neither matches.
+func DecodeLog(bs []byte, m *Method, events []Event) Log {
+	if m != nil && m.Returns != nil && bytes.HasPrefix(bs, ReturnPrefix) {
+		v, err := Decode(*m.Returns, bs[len(ReturnPrefix):])
+		if err == nil {
+			return Log{Kind: LogReturn, Raw: bs, Type: m.Returns, Value: v}
+		}
+	}
+
+	if len(bs) >= SelectorLength {
+		for i := range events {
+			e := &events[i]
+			if !bytes.Equal(bs[:SelectorLength], e.Selector()) {
+				continue
+			}
+
+			t := Type{Kind: KindTuple, Fields: e.Args}
+			v, err := Decode(t, bs[SelectorLength:])
+			if err == nil {
+				return Log{Kind: LogEvent, Raw: bs, Event: e, Type: &t, Value: v}
+			}
+		}
+	}
+
+	return Log{Kind: LogRaw, Raw: bs}
+}
+
+func (l Log) String() string {
+	switch l.Kind {
+	case LogReturn:
+		return fmt.Sprintf("return %s", Format(*l.Type, l.Value))
+	case LogEvent:
+		return fmt.Sprintf("event %s%s", l.Event.Name, Format(*l.Type, l.Value))
+	default:
+		return FormatBytes(l.Raw)
+	}
+}
+
+// FormatBytes renders bytes as hex, prefixed with the quoted text when all bytes are printable ASCII.
+func FormatBytes(bs []byte) string {
+	h := fmt.Sprintf("0x%x", bs)
+	if len(bs) == 0 {
+		return h
+	}
+
+	for _, b := range bs {
+		if b < 0x20 || b > 0x7e {
+			return h
+		}
+	}
+
+	return fmt.Sprintf("%s (%s)", strconv.Quote(string(bs)), h)
+}
@@ -0,0 +1,268 @@
+package abi
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SelectorLength = 4
+)
+
+var refTypes = map[string]bool{
+	"account":     true,
+	"application": true,
+	"asset":       true,
+}
+
+var txnTypes = map[string]bool{
+	"txn":    true,
+	"pay":    true,
+	"keyreg": true,
+	"acfg":   true,
+	"axfer":  true,
+	"afrz":   true,
+	"appl":   true,
+}
+
+type Arg struct {
+	// Name is the original type name; reference and transaction args have no ABI Type.
+	Name string
+	Type *Type
+}
+
+func (a Arg) IsReference() bool {
+	return refTypes[a.Name]
+}
+
+func (a Arg) IsTransaction() bool {
+	return txnTypes[a.Name]
+}
+
+type Method struct {
+	Name    string
+	Args    []Arg
+	Returns *Type // nil for void
+}
+
+func parseArg(s string) (Arg, error) {
+	if refTypes[s] || txnTypes[s] {
+		return Arg{Name: s}, nil
+	}
+
+	t, err := ParseType(s)
+	if err != nil {
+		return Arg{}, err
+	}
+
+	return Arg{Name: s, Type: &t}, nil
+}
+
+// splitMethod splits the method signature into the name, the arg types and the return type.
+func splitMethod(sig string) (string, []string, string, error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 {
+		return "", nil, "", errors.Errorf("invalid method signature: %s", sig)
+	}
+
+	depth := 0
+	end := -1
+	for i := open; i < len(sig); i++ {
+		switch sig[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		if depth == 0 {
+			end = i
+			break
+		}
+	}
+
+	if end == -1 {
+		return "", nil, "", errors.Errorf("unbalanced parens in method signature: %s", sig)
+	}
+
+	parts, err := SplitTuple(sig[open+1 : end])
+	if err != nil {
+		return "", nil, "", errors.Wrapf(err, "invalid method args: %s", sig)
+	}
+
+	return sig[:open], parts, sig[end+1:], nil
+}
+
+// ParseMethod parses a method signature, e.g. "add(uint64,uint64)uint128".
+func ParseMethod(sig string) (Method, error) {
+	name, parts, ret, err := splitMethod(sig)
+	if err != nil {
+		return Method{}, err
+	}
+
+	m := Method{
+		Name: name,
+		Args: make([]Arg, len(parts)),
+	}
+
+	for i, p := range parts {
+		a, err := parseArg(p)
+		if err != nil {
+			return Method{}, errors.Wrapf(err, "invalid method arg %d", i)
+		}
+		m.Args[i] = a
+	}
+
+	if ret == "" {
+		return Method{}, errors.Errorf("missing method return type: %s", sig)
+	}
+
+	if ret != "void" {
+		t, err := ParseType(ret)
+		if err != nil {
+			return Method{}, errors.Wrapf(err, "invalid method return type: %s", sig)
+		}
+		m.Returns = &t
+	}
+
+	return m, nil
+}
+
+// typeAliases are the common non-canonical type names and their ARC-4 names.
+var typeAliases = map[string]string{
+	"uint":        "uint64",
+	"int":         "uint64",
+	"boolean":     "bool",
+	"bytes":       "byte[]",
+	"str":         "string",
+	"addr":        "address",
+	"acct":        "account",
+	"app":         "application",
+	"transaction": "txn",
+}
+
+// CanonicalType returns the ARC-4 name of the type with the aliases replaced and the spaces removed,
+// e.g. "(uint, bytes)" is "(uint64,byte[])".
+func CanonicalType(s string) string {
+	s = strings.TrimSpace(s)
+
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open == -1 {
+			return s
+		}
+
+		return CanonicalType(s[:open]) + "[" + strings.TrimSpace(s[open+1:len(s)-1]) + "]"
+	}
+
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		parts, err := SplitTuple(s[1 : len(s)-1])
+		if err != nil {
+			return s
+		}
+
+		for i, p := range parts {
+			parts[i] = CanonicalType(p)
+		}
+
+		return "(" + strings.Join(parts, ",") + ")"
+	}
+
+	if a, ok := typeAliases[s]; ok {
+		return a
+	}
+
+	return s
+}
+
+// CanonicalMethod returns the signature with the canonical arg and return types,
+// the signature that can not be split is returned as is.
+func CanonicalMethod(sig string) string {
+	name, parts, ret, err := splitMethod(sig)
+	if err != nil {
+		return sig
+	}
+
+	for i, p := range parts {
+		parts[i] = CanonicalType(p)
+	}
+
+	ret = strings.TrimSpace(ret)
+	if ret != "void" && ret != "" {
+		ret = CanonicalType(ret)
+	}
+
+	return fmt.Sprintf("%s(%s)%s", strings.TrimSpace(name), strings.Join(parts, ","), ret)
+}
+
+func (m Method) String() string {
+	args := make([]string, len(m.Args))
+	for i, a := range m.Args {
+		if a.Type != nil {
+			args[i] = a.Type.String()
+		} else {
+			args[i] = a.Name
+		}
+	}
+
+	ret := "void"
+	if m.Returns != nil {
+		ret = m.Returns.String()
+	}
+
+	return fmt.Sprintf("%s(%s)%s", m.Name, strings.Join(args, ","), ret)
+}
+
+func (m Method) Selector() []byte {
+	h := sha512.Sum512_256([]byte(m.String()))
+	return h[:SelectorLength]
+}
+
+// EncodeArgs encodes the method application args: the selector followed by
+// one arg per value, with args beyond the 15th packed into a trailing tuple.
+func (m Method) EncodeArgs(vs []interface{}) ([][]byte, error) {
+	var ts []Type
+	for _, a := range m.Args {
+		if a.Type == nil {
+			continue
+		}
+		ts = append(ts, *a.Type)
+	}
+
+	if len(vs) != len(ts) {
+		return nil, errors.Errorf("args count mismatch - expected: %d, got: %d", len(ts), len(vs))
+	}
+
+	res := [][]byte{m.Selector()}
+
+	const maxArgs = 15
+
+	var packed []byte
+	if len(ts) > maxArgs {
+		tail := Type{Kind: KindTuple, Fields: ts[maxArgs-1:]}
+		enc, err := Encode(tail, vs[maxArgs-1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode packed args")
+		}
+
+		packed = enc
+		ts = ts[:maxArgs-1]
+	}
+
+	for i, t := range ts {
+		enc, err := Encode(t, vs[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode arg %d", i)
+		}
+		res = append(res, enc)
+	}
+
+	if packed != nil {
+		res = append(res, packed)
+	}
+
+	return res, nil
+}
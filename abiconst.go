@@ -0,0 +1,332 @@
+package teal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dragmz/teal/abi"
+)
+
+// abiConstantMaxLen limits the length of the literals searched for the ABI encoded tuples
+const abiConstantMaxLen = 512
+
+// abiConstantMaxFields limits the number of the fields of the detected tuples
+const abiConstantMaxFields = 8
+
+// AbiConstant is a byte literal whose value looks like an ABI encoded value, Begin and End are the range of the literal value.
+type AbiConstant struct {
+	Index int
+	Line  int
+	Begin int
+	End   int
+
+	Type  abi.Type
+	Value interface{}
+}
+
+// Short renders the value with the types of the fields and the long addresses shortened,
+// e.g. (uint64 1000000, address 7ZUEC…).
+func (c AbiConstant) Short() string {
+	return formatAbiTyped(c.Type, c.Value, true)
+}
+
+// String renders the value with the types of the fields.
+func (c AbiConstant) String() string {
+	return formatAbiTyped(c.Type, c.Value, false)
+}
+
+func formatAbiTyped(t abi.Type, v interface{}, short bool) string {
+	switch t.Kind {
+	case abi.KindTuple:
+		vs := v.([]interface{})
+		items := make([]string, len(vs))
+		for i, item := range vs {
+			items[i] = formatAbiTyped(t.Fields[i], item, short)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(items, ", "))
+	case abi.KindAddress:
+		s := v.(string)
+		if short && len(s) > 5 {
+			s = s[:5] + "…"
+		}
+		return "address " + s
+	default:
+		return t.String() + " " + abi.Format(t, v)
+	}
+}
+
+// plausibleUint reports whether the 8 bytes are likely an encoded uint64 rather than random bytes, i.e. the value is below 2^48.
+func plausibleUint(bs []byte) bool {
+	return bs[0] == 0 && bs[1] == 0
+}
+
+func printableString(bs []byte) bool {
+	if len(bs) == 0 || !utf8.Valid(bs) {
+		return false
+	}
+
+	for _, r := range string(bs) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lengthPrefixed returns the type of the value with the 2 byte length prefix, e.g. a string or a uint64 array.
+func lengthPrefixed(bs []byte) (string, bool) {
+	if len(bs) <= 2 {
+		return "", false
+	}
+
+	n := int(binary.BigEndian.Uint16(bs))
+	if n == 0 {
+		return "", false
+	}
+
+	switch len(bs) - 2 {
+	case n:
+		if printableString(bs[2:]) {
+			return "string", true
+		}
+		return "byte[]", true
+	case 8 * n:
+		return "uint64[]", true
+	case 32 * n:
+		return "address[]", true
+	}
+
+	return "", false
+}
+
+// abiTupleCandidates returns the tuples of the uint64, the address and the length prefixed dynamic fields the value can be decoded as,
+// the dynamic fields come with the offsets in the head pointing right after it.
+func abiTupleCandidates(bs []byte) []string {
+	var res []string
+
+	var fields []string
+	var offsets []int
+
+	head := -1
+
+	var walk func(p int)
+	walk = func(p int) {
+		if len(fields) > abiConstantMaxFields {
+			return
+		}
+
+		if (head < 0 && p == len(bs)) || p == head {
+			if len(fields) < 2 {
+				return
+			}
+
+			ts := append([]string{}, fields...)
+
+			if head >= 0 {
+				// the dynamic fields span from their offset to the next one
+				ends := append(append([]int{}, offsets[1:]...), len(bs))
+
+				j := 0
+				for i, f := range ts {
+					if f != "" {
+						continue
+					}
+
+					t, ok := lengthPrefixed(bs[offsets[j]:ends[j]])
+					if !ok {
+						return
+					}
+
+					ts[i] = t
+					j++
+				}
+			}
+
+			res = append(res, "("+strings.Join(ts, ",")+")")
+			return
+		}
+
+		limit := len(bs)
+		if head >= 0 {
+			limit = head
+		}
+
+		if p+8 <= limit && plausibleUint(bs[p:]) {
+			fields = append(fields, "uint64")
+			walk(p + 8)
+			fields = fields[:len(fields)-1]
+		}
+
+		if p+32 <= limit {
+			fields = append(fields, "address")
+			walk(p + 32)
+			fields = fields[:len(fields)-1]
+		}
+
+		if p+2 <= limit {
+			off := int(binary.BigEndian.Uint16(bs[p:]))
+
+			prev := head
+			if len(offsets) > 0 {
+				prev = offsets[len(offsets)-1]
+			}
+
+			if (head < 0 && off >= p+2 && off < len(bs)) || (head >= 0 && off > prev && off < len(bs)) {
+				first := head < 0
+				if first {
+					head = off
+				}
+
+				fields = append(fields, "")
+				offsets = append(offsets, off)
+				walk(p + 2)
+				fields = fields[:len(fields)-1]
+				offsets = offsets[:len(offsets)-1]
+
+				if first {
+					head = -1
+				}
+			}
+		}
+	}
+
+	walk(0)
+
+	return res
+}
+
+// abiCandidateRank orders the candidates, the dynamic fields with the matching offsets and the length prefixes
+// are the strongest signals, the addresses the weakest.
+func abiCandidateRank(s string) int {
+	rank := 0
+
+	if strings.Contains(s, "string") || strings.Contains(s, "[]") {
+		rank += 4
+	}
+
+	if !strings.Contains(s, "address") {
+		rank += 2
+	}
+
+	if strings.Contains(s, "uint64") {
+		rank++
+	}
+
+	return rank
+}
+
+// DetectAbiValue returns the ABI type and the decoded value of the bytes if they look like an ABI encoded value:
+// a uint64 below 2^48, a length prefixed string or array, or a tuple of the uint64, the address and the length prefixed fields.
+// A single address is not detected as any 32 bytes are a valid one.
+func DetectAbiValue(bs []byte) (abi.Type, interface{}, bool) {
+	var cs []string
+
+	if len(bs) == 8 && plausibleUint(bs) {
+		cs = append(cs, "uint64")
+	}
+
+	if t, ok := lengthPrefixed(bs); ok && t != "byte[]" {
+		cs = append(cs, t)
+	}
+
+	if len(bs) <= abiConstantMaxLen {
+		best := ""
+		for _, t := range abiTupleCandidates(bs) {
+			if best == "" || abiCandidateRank(t) > abiCandidateRank(best) {
+				best = t
+			}
+		}
+
+		if best != "" {
+			cs = append(cs, best)
+		}
+	}
+
+	for _, c := range cs {
+		t, err := abi.ParseType(c)
+		if err != nil {
+			continue
+		}
+
+		v, err := abi.Decode(t, bs)
+		if err != nil {
+			continue
+		}
+
+		return t, v, true
+	}
+
+	return abi.Type{}, nil, false
+}
+
+// AbiConstants returns the hex, base64 and base32 byte literals that look like ABI encoded values.
+func (r ProcessResult) AbiConstants() []AbiConstant {
+	var res []AbiConstant
+
+	for i, op := range r.Listing {
+		if i >= len(r.Sublines) {
+			break
+		}
+
+		var bs []byte
+		switch op := op.(type) {
+		case *ByteExpr:
+			bs = op.Value
+		case *PushBytesExpr:
+			bs = op.Value
+		default:
+			continue
+		}
+
+		sub := r.Sublines[i]
+		if len(sub.Tokens) < 2 {
+			continue
+		}
+
+		// the value is the last token of the literal, e.g. of byte base64 X
+		t := sub.Tokens[len(sub.Tokens)-1]
+		for _, tt := range sub.Tokens[1:] {
+			if tt.Type() == TokenComment {
+				break
+			}
+			t = tt
+		}
+
+		// the quoted strings are readable already
+		if _, err := strconv.Unquote(t.String()); err == nil {
+			continue
+		}
+
+		at, v, ok := DetectAbiValue(bs)
+		if !ok {
+			continue
+		}
+
+		res = append(res, AbiConstant{
+			Index: i,
+			Line:  sub.Line,
+			Begin: t.Begin(),
+			End:   t.End(),
+			Type:  at,
+			Value: v,
+		})
+	}
+
+	return res
+}
+
+// AbiConstantAt returns the ABI encoded literal at the position.
+func (r ProcessResult) AbiConstantAt(line int, ch int) (AbiConstant, bool) {
+	for _, c := range r.AbiConstants() {
+		if c.Line == line && ch >= c.Begin && ch <= c.End {
+			return c, true
+		}
+	}
+
+	return AbiConstant{}, false
+}
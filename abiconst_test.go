@@ -0,0 +1,96 @@
+package teal
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/dragmz/teal/abi"
+)
+
+func TestDetectAbiValue(t *testing.T) {
+	addr, err := types.DecodeAddress("7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tuple, err := abi.ParseType("(uint64,address)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := abi.Encode(tuple, []interface{}{uint64(1000000), addr.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dynamic, err := abi.ParseType("(uint64,string)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc2, err := abi.Encode(dynamic, []interface{}{uint64(7), "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hex   string
+		short string
+	}{
+		{"00000000000f4240", "uint64 1000000"},
+		{"000568656c6c6f", `string "hello"`},
+		{"000200000000000000010000000000000002", "uint64[] [1, 2]"},
+		{hex.EncodeToString(enc), "(uint64 1000000, address 7ZUEC…)"},
+		{hex.EncodeToString(enc2), `(uint64 7, string "hello")`},
+		{"00000000000000010000000000000002", "(uint64 1, uint64 2)"},
+		{"ffffffffffffffff", ""},
+		{"0102", ""},
+		{hex.EncodeToString(addr[:]), ""},
+	}
+
+	for _, test := range tests {
+		bs, err := hex.DecodeString(test.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		at, v, ok := DetectAbiValue(bs)
+		if test.short == "" {
+			if ok {
+				t.Errorf("unexpected value of %s: %s", test.hex, formatAbiTyped(at, v, true))
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("expected a value of %s", test.hex)
+			continue
+		}
+
+		if s := (AbiConstant{Type: at, Value: v}).Short(); s != test.short {
+			t.Errorf("unexpected value of %s: %s", test.hex, s)
+		}
+	}
+}
+
+func TestAbiConstants(t *testing.T) {
+	res := Process("#pragma version 8\nbyte 0x00000000000f4240\npushbytes 0x000568656c6c6f\nbyte \"hello\"\nbyte base64(AAAAAAAPQkA=)\n==")
+
+	cs := res.AbiConstants()
+	if len(cs) != 3 {
+		t.Fatalf("unexpected constants: %+v", cs)
+	}
+
+	if cs[0].Line != 1 || cs[0].Begin != 5 || cs[0].String() != "uint64 1000000" {
+		t.Errorf("unexpected constant: %+v", cs[0])
+	}
+
+	if cs[2].Line != 4 || cs[2].String() != "uint64 1000000" {
+		t.Errorf("unexpected constant: %+v", cs[2])
+	}
+
+	if c, ok := res.AbiConstantAt(2, 12); !ok || c.String() != `string "hello"` {
+		t.Errorf("unexpected constant at the position: %+v", c)
+	}
+}
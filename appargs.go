@@ -0,0 +1,195 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UncheckedAppArg is an application arg read on a path that does not check NumAppArgs first.
+type UncheckedAppArg struct {
+	// Index is the listing index of the read of the highest arg on the path
+	Index int
+
+	// First is the listing index of the first unchecked read on the path, the check goes before it
+	First int
+
+	// Arg is the highest arg index read on the path
+	Arg uint64
+}
+
+// checksLength reports whether the conditions of the path imply that the length field of the transaction is > n, e.g. txn NumAppArgs > n.
+func checksLength(lits []pcond, length TxnField, n uint64) bool {
+	x := fmt.Sprintf("txn %s", length)
+
+	for _, l := range lits {
+		if l.x != x || !l.yconst {
+			continue
+		}
+
+		k, err := strconv.ParseUint(l.y, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch l.op {
+		case "==", ">=":
+			if k > n {
+				return true
+			}
+		case ">":
+			if k >= n {
+				return true
+			}
+		case "!=":
+			if k == 0 && n == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// readsTxna reports whether the program reads any of the array fields of the transaction.
+func readsTxna(listing Listing, fields ...TxnField) bool {
+	for _, op := range listing {
+		var field TxnField
+
+		switch op := op.(type) {
+		case *TxnaExpr:
+			field = op.Field
+		case *TxnasExpr:
+			field = op.Field
+		default:
+			continue
+		}
+
+		for _, f := range fields {
+			if f == field {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// UncheckedAppArgs explores the paths of the program and returns, for each of the paths reading
+// the application args with the constant indexes, the highest arg read without checking that
+// txn NumAppArgs is greater than its index. The reads fail the call when fewer args are passed.
+func (r *ProcessResult) UncheckedAppArgs() []UncheckedAppArg {
+	if !readsTxna(r.Listing, ApplicationArgs) {
+		return nil
+	}
+
+	found := map[int]UncheckedAppArg{}
+
+	w := newPreWalk(r)
+	w.pathsOnly = true
+	w.txnaRead = func(p *prePath, i int, field TxnField, n uint64) {
+		if field != ApplicationArgs || checksLength(p.lits, NumAppArgs, n) {
+			return
+		}
+
+		if p.args == 0 {
+			p.args = i + 1
+		}
+
+		first := p.args - 1
+
+		u, ok := found[first]
+		if !ok || n > u.Arg {
+			found[first] = UncheckedAppArg{
+				Index: i,
+				First: first,
+				Arg:   n,
+			}
+		}
+	}
+	w.walk()
+
+	var all []UncheckedAppArg
+	for _, u := range found {
+		all = append(all, u)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Index != all[j].Index {
+			return all[i].Index < all[j].Index
+		}
+		return all[i].First < all[j].First
+	})
+
+	// the paths with the same highest read keep the earliest check position
+	var res []UncheckedAppArg
+	for _, u := range all {
+		if len(res) > 0 && res[len(res)-1].Index == u.Index {
+			continue
+		}
+		res = append(res, u)
+	}
+
+	return res
+}
+
+// numAppArgsFix inserts the NumAppArgs assert before the first unchecked read of the path.
+func (r ProcessResult) numAppArgsFix(u UncheckedAppArg) FixAction {
+	sub := r.Sublines[u.First]
+
+	lines := []string{
+		fmt.Sprintf("txn %s", NumAppArgs),
+		fmt.Sprintf("int %d", u.Arg),
+		">",
+		"assert",
+		"",
+	}
+
+	return FixAction{
+		Title: fmt.Sprintf("Assert txn %s > %d", NumAppArgs, u.Arg),
+		Kind:  FixNumAppArgs,
+		Edits: []FixEdit{
+			{
+				Start:   FixPosition{Line: sub.Line, Character: sub.Tokens.Begin()},
+				End:     FixPosition{Line: sub.Line, Character: sub.Tokens.Begin()},
+				NewText: strings.Join(lines, r.Eol),
+			},
+		},
+	}
+}
+
+type UncheckedAppArgsRule struct{}
+
+func (r UncheckedAppArgsRule) Id() string {
+	return "LINT0028"
+}
+
+func (r UncheckedAppArgsRule) Desc() string {
+	return "Reports the application args read without checking NumAppArgs"
+}
+
+var UncheckedAppArgsRuleInstance = UncheckedAppArgsRule{}
+
+func checkUncheckedAppArgs(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, u := range res.UncheckedAppArgs() {
+		if u.Index >= len(res.Sublines) {
+			continue
+		}
+
+		sub := res.Sublines[u.Index]
+
+		diags = append(diags, lintError{
+			error: fmt.Errorf("ApplicationArgs %d is read without checking txn %s > %d - the call fails with fewer args", u.Arg, NumAppArgs, u.Arg),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     codeEnd(sub),
+			s:     DiagWarn,
+			r:     UncheckedAppArgsRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,106 @@
+package teal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUncheckedAppArgs(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []uint64
+	}{
+		{src: "#pragma version 8\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 2\nbtoi\n+\nreturn", expected: []uint64{2}},
+		{src: "#pragma version 8\ntxn NumAppArgs\nint 2\n>\nassert\ntxna ApplicationArgs 2\nlen\nreturn"},
+		{src: "#pragma version 8\ntxn NumAppArgs\nint 1\n>=\nassert\ntxna ApplicationArgs 1\nlen\nreturn", expected: []uint64{1}},
+		{src: "#pragma version 8\ntxn NumAppArgs\nbz skip\ntxna ApplicationArgs 0\nlen\nreturn\nskip:\nint 1\nreturn"},
+		{src: "#pragma version 8\nint 1\ntxnas ApplicationArgs\nlen\nreturn", expected: []uint64{1}},
+		// the check on the other path does not count
+		{src: "#pragma version 8\ntxn OnCompletion\nbnz other\ntxn NumAppArgs\nint 1\n==\nassert\ntxna ApplicationArgs 0\nlen\nreturn\nother:\ntxna ApplicationArgs 0\nlen\nreturn", expected: []uint64{0}},
+	}
+
+	for _, test := range tests {
+		res := Process(test.src)
+
+		us := res.UncheckedAppArgs()
+		if len(us) != len(test.expected) {
+			t.Errorf("expected %v, got: %+v, source:\n%s", test.expected, us, test.src)
+			continue
+		}
+
+		for i, u := range us {
+			if u.Arg != test.expected[i] {
+				t.Errorf("expected arg %d, got: %d", test.expected[i], u.Arg)
+			}
+		}
+
+		n := 0
+		for _, d := range res.Diagnostics {
+			if d.Rule() == UncheckedAppArgsRuleInstance.Id() {
+				n++
+			}
+		}
+
+		if n != len(test.expected) {
+			t.Errorf("expected %d warnings, got: %d", len(test.expected), n)
+		}
+	}
+}
+
+func TestNumAppArgsFix(t *testing.T) {
+	src := "#pragma version 8\nint 1\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 1\nbtoi\n+\n+\nreturn"
+
+	res := Process(src)
+
+	var fas []FixAction
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+		if fa.Kind == FixNumAppArgs {
+			fas = append(fas, fa)
+		}
+	}
+
+	if len(fas) != 1 {
+		t.Fatalf("expected a fix, got: %+v", fas)
+	}
+
+	fixed, _ := ApplyFixes(src, fas)
+
+	expected := "#pragma version 8\nint 1\ntxn NumAppArgs\nint 1\n>\nassert\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 1\nbtoi\n+\n+\nreturn"
+	if fixed != expected {
+		t.Errorf("unexpected fix:\n%s", fixed)
+	}
+
+	if us := Process(fixed).UncheckedAppArgs(); len(us) != 0 {
+		t.Errorf("unexpected reads: %+v", us)
+	}
+}
+
+// the path checks run the bounded precondition walk in every Process call
+func TestPathChecksLoops(t *testing.T) {
+	for _, src := range preconditionGrowthSources {
+		start := time.Now()
+
+		res := Process(src)
+		if res == nil {
+			t.Fatalf("missing result, source:\n%s", src)
+		}
+
+		if d := time.Since(start); d > 10*time.Second {
+			t.Errorf("processing took %s, source:\n%s", d, src)
+		}
+	}
+
+	// the unchecked reads before the loop are still reported
+	res := Process("#pragma version 8\ntxna ApplicationArgs 1\nl1:\nsha256\nint 1\nbnz l1")
+
+	found := false
+	for _, d := range res.Diagnostics {
+		if d.Rule() == UncheckedAppArgsRuleInstance.Id() {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("missing unchecked args diagnostic: %v", res.Diagnostics)
+	}
+}
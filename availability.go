@@ -0,0 +1,247 @@
+package teal
+
+import (
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/pkg/errors"
+)
+
+// availabilityVersion is the version the ops take the addresses and the ids of the foreign references directly
+const availabilityVersion = 4
+
+// refArray is a foreign array of the app call transaction.
+type refArray struct {
+	field  TxnField
+	length TxnField
+
+	// implicit is set if the index 0 is available without being passed, e.g. the sender of Accounts
+	implicit bool
+
+	name string
+}
+
+var refArrays = []refArray{
+	{field: Accounts, length: NumAccounts, implicit: true, name: "foreign accounts"},
+	{field: Assets, length: NumAssets, name: "foreign assets"},
+	{field: Applications, length: NumApplications, implicit: true, name: "foreign apps"},
+}
+
+func refArrayOf(field TxnField) (refArray, bool) {
+	for _, a := range refArrays {
+		if a.field == field {
+			return a, true
+		}
+	}
+
+	return refArray{}, false
+}
+
+// refArg is an account, an asset or an app arg of an op, Depth is its position below the top of the stack.
+type refArg struct {
+	Depth int
+	Field TxnField
+}
+
+// refArgs returns the args of the op that must be available to the call.
+func refArgs(op Op) []refArg {
+	switch op.(type) {
+	case *BalanceExpr, *MinBalanceExpr, *AcctParamsGetExpr:
+		return []refArg{{Depth: 0, Field: Accounts}}
+	case *AppOptedInExpr:
+		return []refArg{{Depth: 1, Field: Accounts}, {Depth: 0, Field: Applications}}
+	case *AssetHoldingGetExpr:
+		return []refArg{{Depth: 1, Field: Accounts}, {Depth: 0, Field: Assets}}
+	case *AppLocalGetExpr, *AppLocalDelExpr:
+		return []refArg{{Depth: 1, Field: Accounts}}
+	case *AppLocalGetExExpr:
+		return []refArg{{Depth: 2, Field: Accounts}, {Depth: 1, Field: Applications}}
+	case *AppLocalPutExpr:
+		return []refArg{{Depth: 2, Field: Accounts}}
+	case *AppGlobalGetExExpr:
+		return []refArg{{Depth: 1, Field: Applications}}
+	case *AssetParamsGetExpr:
+		return []refArg{{Depth: 0, Field: Assets}}
+	case *AppParamsGetExpr:
+		return []refArg{{Depth: 0, Field: Applications}}
+	}
+
+	return nil
+}
+
+// stackProducer returns the listing index of the op pushing the value at the depth below the top of the stack before the op at index i,
+// following the straight-line code only.
+func stackProducer(listing Listing, i int, depth int) (int, bool) {
+	for j := i - 1; j >= 0; j-- {
+		op := listing[j]
+
+		switch op.(type) {
+		case *LabelExpr, *BExpr, *ReturnExpr, *ErrExpr, *RetSubExpr, *CallSubExpr, *SwitchExpr, *MatchExpr:
+			return 0, false
+		}
+
+		need, delta, ok := stackEffect(op)
+		if !ok {
+			return 0, false
+		}
+
+		outs := need + delta
+		if depth < outs {
+			if outs != 1 {
+				return 0, false
+			}
+			return j, true
+		}
+
+		depth += need - outs
+	}
+
+	return 0, false
+}
+
+// UnavailableRef is a reference to an account, an asset or an app the call may not make available.
+type UnavailableRef struct {
+	// Index is the listing index of the reading op or of the constant
+	Index int
+
+	Field TxnField
+
+	// Arg is the index of the array read, set if Constant is not
+	Arg uint64
+
+	// Constant is the address or the id pushed as a constant for the op at OpIndex
+	Constant string
+	OpIndex  int
+	Op       string
+}
+
+func (u UnavailableRef) String() string {
+	a, _ := refArrayOf(u.Field)
+
+	if u.Constant != "" {
+		return fmt.Sprintf("%s must be passed in the %s of the call to be available to %s", u.Constant, a.name, u.Op)
+	}
+
+	n := u.Arg
+	if !a.implicit {
+		n++
+	}
+
+	return fmt.Sprintf("txna %s %d is read without checking txn %s >= %d - the call fails with fewer %s", u.Field, u.Arg, a.length, n, a.name)
+}
+
+// UnavailableRefs returns the reads of the Accounts, Assets and Applications arrays not checked against their lengths on a path,
+// and since v4 the addresses and the ids pushed as constants for the ops that require them in the foreign arrays of the call.
+func (r *ProcessResult) UnavailableRefs() []UnavailableRef {
+	var res []UnavailableRef
+
+	seen := map[int]bool{}
+
+	if readsTxna(r.Listing, Accounts, Assets, Applications) {
+		w := newPreWalk(r)
+		w.pathsOnly = true
+		w.txnaRead = func(p *prePath, i int, field TxnField, n uint64) {
+			a, ok := refArrayOf(field)
+			if !ok || seen[i] {
+				return
+			}
+
+			if a.implicit {
+				if n == 0 || checksLength(p.lits, a.length, n-1) {
+					return
+				}
+			} else if checksLength(p.lits, a.length, n) {
+				return
+			}
+
+			seen[i] = true
+
+			res = append(res, UnavailableRef{
+				Index: i,
+				Field: field,
+				Arg:   n,
+			})
+		}
+		w.walk()
+	}
+
+	if r.Version >= availabilityVersion {
+		for i, op := range r.Listing {
+			for _, arg := range refArgs(op) {
+				j, ok := stackProducer(r.Listing, i, arg.Depth)
+				if !ok || seen[j] {
+					continue
+				}
+
+				var c string
+
+				switch arg.Field {
+				case Accounts:
+					bs, ok := literalAddress(r.Listing[j])
+					if !ok || len(bs) != len(types.Address{}) {
+						continue
+					}
+
+					var a types.Address
+					copy(a[:], bs)
+					c = a.String()
+				default:
+					// the small values are the indexes of the arrays
+					v, ok := constUint(r.Listing[j])
+					if !ok || v < 256 {
+						continue
+					}
+
+					c = fmt.Sprintf("%d", v)
+				}
+
+				seen[j] = true
+
+				res = append(res, UnavailableRef{
+					Index:    j,
+					Field:    arg.Field,
+					Constant: c,
+					OpIndex:  i,
+					Op:       opName(op.String()),
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+type UnavailableRefRule struct{}
+
+func (r UnavailableRefRule) Id() string {
+	return "LINT0029"
+}
+
+func (r UnavailableRefRule) Desc() string {
+	return "Reports the accounts, assets and apps the call may not make available"
+}
+
+var UnavailableRefRuleInstance = UnavailableRefRule{}
+
+func checkUnavailableRefs(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, u := range res.UnavailableRefs() {
+		if u.Index >= len(res.Sublines) {
+			continue
+		}
+
+		sub := res.Sublines[u.Index]
+
+		diags = append(diags, lintError{
+			error: errors.New(u.String()),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     codeEnd(sub),
+			s:     DiagWarn,
+			r:     UnavailableRefRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
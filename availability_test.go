@@ -0,0 +1,67 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestUnavailableRefs(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "#pragma version 8\ntxna Accounts 0\nbalance\nreturn"},
+		{src: "#pragma version 8\ntxna Accounts 2\nbalance\nreturn", expected: []string{"txna Accounts 2 is read without checking txn NumAccounts >= 2 - the call fails with fewer foreign accounts"}},
+		{src: "#pragma version 8\ntxn NumAccounts\nint 2\n==\nassert\ntxna Accounts 2\nbalance\nreturn"},
+		{src: "#pragma version 8\ntxna Assets 0\nasset_params_get AssetTotal\npop\nreturn", expected: []string{"txna Assets 0 is read without checking txn NumAssets >= 1 - the call fails with fewer foreign assets"}},
+		{src: "#pragma version 8\ntxn NumAssets\nint 1\n>\nbz skip\ntxna Assets 1\nasset_params_get AssetTotal\npop\nreturn\nskip:\nint 1\nreturn"},
+		{src: "#pragma version 8\naddr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q\nbalance\nreturn", expected: []string{"7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q must be passed in the foreign accounts of the call to be available to balance"}},
+		{src: "#pragma version 8\ntxn Sender\nint 31566704\nasset_holding_get AssetBalance\npop\nreturn", expected: []string{"31566704 must be passed in the foreign assets of the call to be available to asset_holding_get"}},
+		{src: "#pragma version 8\nint 1234\nbyte \"counter\"\napp_global_get_ex\npop\nreturn", expected: []string{"1234 must be passed in the foreign apps of the call to be available to app_global_get_ex"}},
+		// the small values are the indexes of the foreign arrays
+		{src: "#pragma version 8\ntxn Sender\nint 1\nasset_holding_get AssetBalance\npop\nreturn"},
+		// before v4 the ops take the indexes only
+		{src: "#pragma version 3\nint 1234\nasset_params_get AssetTotal\npop\nreturn"},
+	}
+
+	for _, test := range tests {
+		res := Process(test.src)
+
+		us := res.UnavailableRefs()
+		if len(us) != len(test.expected) {
+			t.Errorf("expected %v, got: %+v, source:\n%s", test.expected, us, test.src)
+			continue
+		}
+
+		for i, u := range us {
+			if u.String() != test.expected[i] {
+				t.Errorf("expected: %s, got: %s", test.expected[i], u)
+			}
+		}
+
+		n := 0
+		for _, d := range res.Diagnostics {
+			if d.Rule() == UnavailableRefRuleInstance.Id() {
+				n++
+			}
+		}
+
+		if n != len(test.expected) {
+			t.Errorf("expected %d warnings, got: %d", len(test.expected), n)
+		}
+	}
+}
+
+func TestStackProducer(t *testing.T) {
+	res := Process("#pragma version 8\naddr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q\nbyte \"k\"\nbyte \"x\"\nconcat\napp_local_get")
+
+	i := len(res.Listing) - 1
+
+	j, ok := stackProducer(res.Listing, i, 1)
+	if !ok || j != 1 {
+		t.Errorf("unexpected producer: %d %v", j, ok)
+	}
+
+	if j, ok := stackProducer(res.Listing, i, 0); !ok || j != 4 {
+		t.Errorf("unexpected producer: %d %v", j, ok)
+	}
+}
@@ -0,0 +1,77 @@
+// Package bench has the representative corpora used to benchmark the processing of the programs
+// and compares the benchmark results to catch the performance regressions.
+package bench
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed testdata/*.teal
+var files embed.FS
+
+// Corpus is a named program source.
+type Corpus struct {
+	Name   string
+	Source string
+}
+
+// Corpora returns the handwritten and the disassembled programs and the generated program of about the given number of lines.
+func Corpora(lines int) []Corpus {
+	var cs []Corpus
+
+	for _, name := range []string{"handwritten", "disassembled"} {
+		bs, err := files.ReadFile("testdata/" + name + ".teal")
+		if err != nil {
+			panic(err)
+		}
+
+		cs = append(cs, Corpus{Name: name, Source: string(bs)})
+	}
+
+	cs = append(cs, Corpus{Name: "generated", Source: Generate(lines)})
+
+	return cs
+}
+
+// Generate returns a deterministic program of about the number of lines with branches, subroutines, state and scratch accesses.
+func Generate(lines int) string {
+	var sb strings.Builder
+
+	sb.WriteString("#pragma version 8\n")
+	sb.WriteString("txn ApplicationID\nbz create\n")
+
+	// each block is 16 lines
+	blocks := lines / 16
+	if blocks < 1 {
+		blocks = 1
+	}
+
+	for i := 0; i < blocks; i++ {
+		fmt.Fprintf(&sb, "block_%d:\n", i)
+		fmt.Fprintf(&sb, "txna ApplicationArgs %d\n", i%16)
+		sb.WriteString("btoi\n")
+		fmt.Fprintf(&sb, "int %d\n", i+1)
+		sb.WriteString("+\n")
+		fmt.Fprintf(&sb, "store %d // scratch %d: value_%d\n", i%256, i%256, i)
+		fmt.Fprintf(&sb, "byte \"key_%d\"\n", i)
+		fmt.Fprintf(&sb, "load %d\n", i%256)
+		sb.WriteString("app_global_put\n")
+		fmt.Fprintf(&sb, "load %d\n", i%256)
+		fmt.Fprintf(&sb, "callsub sub_%d\n", i)
+		sb.WriteString("pop\n")
+		fmt.Fprintf(&sb, "load %d\n", i%256)
+		fmt.Fprintf(&sb, "bz block_%d\n", (i+1)%blocks)
+		sb.WriteString("int 1\n")
+		sb.WriteString("return\n")
+	}
+
+	for i := 0; i < blocks; i++ {
+		fmt.Fprintf(&sb, "sub_%d:\nproto 1 1\nframe_dig -1\nint %d\n*\nretsub\n", i, i+2)
+	}
+
+	sb.WriteString("create:\nint 1\nreturn\n")
+
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+// generatedLines is the size of the large generated corpus
+const generatedLines = 5000
+
+func BenchmarkLex(b *testing.B) {
+	for _, c := range Corpora(generatedLines) {
+		b.Run(c.Name, func(b *testing.B) {
+			b.SetBytes(int64(len(c.Source)))
+
+			for i := 0; i < b.N; i++ {
+				z := &teal.Lexer{Source: []byte(c.Source)}
+				for z.Scan() {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkProcess(b *testing.B) {
+	for _, c := range Corpora(generatedLines) {
+		b.Run(c.Name, func(b *testing.B) {
+			b.SetBytes(int64(len(c.Source)))
+
+			for i := 0; i < b.N; i++ {
+				teal.Process(c.Source)
+			}
+		})
+	}
+}
+
+func BenchmarkLint(b *testing.B) {
+	for _, c := range Corpora(generatedLines) {
+		res := teal.Process(c.Source)
+
+		b.Run(c.Name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				res.Listing.Lint()
+			}
+		})
+	}
+}
+
+func TestCorpora(t *testing.T) {
+	for _, c := range Corpora(1000) {
+		res := teal.Process(c.Source)
+		for _, d := range res.Diagnostics {
+			if d.Severity() == teal.DiagErr {
+				t.Errorf("corpus %s has errors: %d: %s", c.Name, d.Line(), d)
+			}
+		}
+	}
+}
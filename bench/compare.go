@@ -0,0 +1,93 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// benchLineRegexp matches the go test -bench result lines, the name without the GOMAXPROCS suffix
+var benchLineRegexp = regexp.MustCompile(`^(Benchmark\S*?)(?:-\d+)?\s+\d+\s+([0-9.]+) ns/op`)
+
+// ReadResults reads the ns/op of the benchmarks from the go test -bench output, the median of the repeated runs.
+func ReadResults(r io.Reader) (map[string]float64, error) {
+	runs := map[string][]float64{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		m := benchLineRegexp.FindStringSubmatch(strings.TrimSpace(s.Text()))
+		if m == nil {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse result of %s", m[1])
+		}
+
+		runs[m[1]] = append(runs[m[1]], v)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read results")
+	}
+
+	res := map[string]float64{}
+	for name, vs := range runs {
+		sort.Float64s(vs)
+
+		if len(vs)%2 == 1 {
+			res[name] = vs[len(vs)/2]
+		} else {
+			res[name] = (vs[len(vs)/2-1] + vs[len(vs)/2]) / 2
+		}
+	}
+
+	return res, nil
+}
+
+// Change is the change of a benchmark result between the runs.
+type Change struct {
+	Name string
+	Old  float64
+	New  float64
+
+	// Delta is the change in percent, positive if slower
+	Delta float64
+
+	// Regression is set if the benchmark is slower by more than the threshold
+	Regression bool
+}
+
+// Compare returns the changes of the benchmarks present in both of the results sorted by name.
+func Compare(old, new map[string]float64, threshold float64) []Change {
+	var cs []Change
+
+	for name, o := range old {
+		n, ok := new[name]
+		if !ok || o == 0 {
+			continue
+		}
+
+		d := (n - o) / o * 100
+
+		cs = append(cs, Change{
+			Name:       name,
+			Old:        o,
+			New:        n,
+			Delta:      d,
+			Regression: d > threshold,
+		})
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].Name < cs[j].Name
+	})
+
+	return cs
+}
@@ -0,0 +1,43 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	old, err := ReadResults(strings.NewReader(`goos: linux
+BenchmarkLex/handwritten-8         	   1000	     30000 ns/op	  33.73 MB/s
+BenchmarkLex/handwritten-8         	   1000	     32000 ns/op	  33.73 MB/s
+BenchmarkLex/handwritten-8         	   1000	     90000 ns/op	  33.73 MB/s
+BenchmarkProcess/handwritten-8     	    100	    300000 ns/op
+BenchmarkLint/handwritten-8        	   1000	     20000 ns/op
+PASS`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if old["BenchmarkLex/handwritten"] != 32000 {
+		t.Errorf("expected the median, got: %v", old)
+	}
+
+	new, err := ReadResults(strings.NewReader(`BenchmarkLex/handwritten-8         	   1000	     33000 ns/op
+BenchmarkProcess/handwritten-8     	    100	    360000 ns/op
+BenchmarkLint/other-8              	   1000	     20000 ns/op`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := Compare(old, new, 10)
+	if len(cs) != 2 {
+		t.Fatalf("unexpected changes: %v", cs)
+	}
+
+	if cs[0].Name != "BenchmarkLex/handwritten" || cs[0].Regression {
+		t.Errorf("unexpected change: %+v", cs[0])
+	}
+
+	if cs[1].Name != "BenchmarkProcess/handwritten" || !cs[1].Regression || cs[1].Delta != 20 {
+		t.Errorf("unexpected change: %+v", cs[1])
+	}
+}
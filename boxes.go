@@ -0,0 +1,108 @@
+package teal
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func hasBoxOps(listing Listing) bool {
+	for _, op := range listing {
+		switch op.(type) {
+		case *BoxGetExpr, *BoxPutExpr, *BoxCreateExpr, *BoxLenExpr, *BoxDelExpr, *BoxReplaceExpr, *BoxExtractExpr:
+			return true
+		}
+	}
+
+	return false
+}
+
+// boxPrefix returns the constant prefix of the key and whether the key continues with user input after it.
+func boxPrefix(key VmValue) ([]byte, bool) {
+	var prefix []byte
+
+	for _, p := range key.Parts() {
+		bs, ok := p.Bytes()
+		if !ok {
+			return prefix, p.IsUser()
+		}
+
+		prefix = append(prefix, bs...)
+	}
+
+	return prefix, false
+}
+
+// boxKeyCollision returns the reason why the box key may collide with the other keys.
+func boxKeyCollision(key VmValue, keys []VmValue) (string, bool) {
+	parts := key.Parts()
+	for _, p := range parts[:len(parts)-1] {
+		if p.IsUser() && len(p.Lengths()) != 1 {
+			name, _ := keyName(key)
+			return fmt.Sprintf("box name \"%s\" concatenates user input of unknown length without a length prefix", name), true
+		}
+	}
+
+	prefix, user := boxPrefix(key)
+	if !user {
+		return "", false
+	}
+
+	name, _ := keyName(key)
+
+	for _, other := range keys {
+		oname, _ := keyName(other)
+		if oname == name {
+			continue
+		}
+
+		oprefix, _ := boxPrefix(other)
+		if len(oprefix) > len(prefix) && bytes.HasPrefix(oprefix, prefix) {
+			return fmt.Sprintf("box name \"%s\" may collide with \"%s\"", name, oname), true
+		}
+	}
+
+	return "", false
+}
+
+func checkBoxKeys(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	vm := NewVm(res)
+	vm.RunAll(stateMaxBranches)
+
+	var keys []VmValue
+	for _, a := range vm.Accesses {
+		if a.Scope == VmAccessBox {
+			keys = append(keys, a.Key)
+		}
+	}
+
+	seen := map[int]bool{}
+
+	for _, a := range vm.Accesses {
+		if a.Scope != VmAccessBox || seen[a.Line] || a.Line >= len(res.Sublines) {
+			continue
+		}
+
+		reason, ok := boxKeyCollision(a.Key, keys)
+		if !ok {
+			continue
+		}
+
+		seen[a.Line] = true
+
+		sub := res.Sublines[a.Line]
+		diags = append(diags, lintError{
+			error: errors.New(reason),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagWarn,
+			r:     BoxKeyCollisionRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,67 @@
+package teal
+
+import "testing"
+
+func TestBoxKeys(t *testing.T) {
+	res := Process(`#pragma version 8
+byte "config"
+int 32
+box_create
+pop
+byte "user"
+txna ApplicationArgs 0
+concat
+byte "x"
+box_put
+byte "username"
+txna ApplicationArgs 1
+concat
+box_del
+pop
+txna ApplicationArgs 0
+byte "-"
+concat
+txna ApplicationArgs 1
+concat
+box_len
+assert
+pop
+int 7
+itob
+txna ApplicationArgs 2
+concat
+box_len
+assert
+pop
+int 1
+return`)
+
+	s := AnalyzeState(res)
+
+	names := []string{"config", "user{txna ApplicationArgs 0}", "username{txna ApplicationArgs 1}", "{itob}{txna ApplicationArgs 2}", "{txna ApplicationArgs 0}-{txna ApplicationArgs 1}"}
+	if len(s.Boxes) != len(names) {
+		t.Fatalf("unexpected boxes: %+v", s.Boxes)
+	}
+
+	found := map[string]bool{}
+	for _, b := range s.Boxes {
+		found[b.Key] = true
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			t.Errorf("missing box: %s", name)
+		}
+	}
+
+	var lines []int
+	for _, d := range res.Diagnostics {
+		if d.Rule() == BoxKeyCollisionRuleInstance.Id() {
+			lines = append(lines, d.Line())
+		}
+	}
+
+	if len(lines) != 2 || lines[0] != 9 || lines[1] != 20 {
+		t.Errorf("unexpected collision diagnostics: %v", res.Diagnostics)
+	}
+}
@@ -0,0 +1,191 @@
+package teal
+
+import "github.com/pkg/errors"
+
+// BoxAccess is a box op with a statically known minimal size of the box it accesses.
+type BoxAccess struct {
+	// Index is the listing index of the box op
+	Index int
+	Op    string
+
+	// Name is the box name if it is a constant
+	Name string
+
+	// Size is the size of the box the op accesses at least, e.g. the start plus the length of box_extract
+	Size uint64
+}
+
+// boxSizeArgs returns the stack depths of the name, the start and the length of the box op,
+// the start is -1 for the ops writing the whole box and the length is read from the bytes for box_replace and box_put.
+func boxSizeArgs(op Op) (name int, start int, length int, bytes bool, ok bool) {
+	switch op.(type) {
+	case *BoxExtractExpr:
+		return 2, 1, 0, false, true
+	case *BoxReplaceExpr:
+		return 2, 1, 0, true, true
+	case *BoxCreateExpr:
+		return 1, -1, 0, false, true
+	case *BoxPutExpr:
+		return 1, -1, 0, true, true
+	}
+
+	return 0, 0, 0, false, false
+}
+
+// BoxAccesses returns the box ops with the statically known box extents.
+func (r *ProcessResult) BoxAccesses() []BoxAccess {
+	var res []BoxAccess
+
+	for i, op := range r.Listing {
+		name, start, length, bytes, ok := boxSizeArgs(op)
+		if !ok {
+			continue
+		}
+
+		j, ok := stackProducer(r.Listing, i, length)
+		if !ok {
+			continue
+		}
+
+		var size uint64
+		if bytes {
+			bs, ok := constBytes(r.Listing[j])
+			if !ok {
+				continue
+			}
+			size = uint64(len(bs))
+		} else {
+			size, ok = constUint(r.Listing[j])
+			if !ok {
+				continue
+			}
+		}
+
+		// the unknown start leaves the length as the lower bound
+		if start >= 0 {
+			if j, ok := stackProducer(r.Listing, i, start); ok {
+				if v, ok := constUint(r.Listing[j]); ok && size+v >= size {
+					size += v
+				}
+			}
+		}
+
+		a := BoxAccess{
+			Index: i,
+			Op:    opName(op.String()),
+			Size:  size,
+		}
+
+		if j, ok := stackProducer(r.Listing, i, name); ok {
+			if bs, ok := constBytes(r.Listing[j]); ok {
+				a.Name = string(bs)
+			}
+		}
+
+		res = append(res, a)
+	}
+
+	return res
+}
+
+// BoxBudget is the estimated box I/O of a call against the budget of its box references.
+type BoxBudget struct {
+	// Bytes is the known size of the boxes the call accesses, the boxes of the unknown names are counted separately
+	Bytes uint64
+
+	// Refs is the number of the box references the bytes require
+	Refs int
+
+	Budget   uint64
+	Exceeded bool
+}
+
+// boxRefs returns the number of the box references covering the size.
+func boxRefs(size uint64, p ConsensusParams) int {
+	per := uint64(p.BytesPerBoxReference)
+	if per == 0 {
+		return 0
+	}
+
+	return int((size + per - 1) / per)
+}
+
+// EstimateBoxIO returns the box I/O of the program against the budget of the box references declared by the call.
+func EstimateBoxIO(res *ProcessResult, refs int) BoxBudget {
+	p := LatestConsensus()
+
+	sizes := map[string]uint64{}
+	var unnamed uint64
+
+	for _, a := range res.BoxAccesses() {
+		if a.Name == "" {
+			unnamed += a.Size
+			continue
+		}
+
+		if a.Size > sizes[a.Name] {
+			sizes[a.Name] = a.Size
+		}
+	}
+
+	b := BoxBudget{
+		Bytes:  unnamed,
+		Budget: uint64(refs) * uint64(p.BytesPerBoxReference),
+	}
+
+	for _, s := range sizes {
+		b.Bytes += s
+	}
+
+	b.Refs = boxRefs(b.Bytes, p)
+	b.Exceeded = b.Bytes > b.Budget
+
+	return b
+}
+
+type BoxBudgetRule struct{}
+
+func (r BoxBudgetRule) Id() string {
+	return "LINT0031"
+}
+
+func (r BoxBudgetRule) Desc() string {
+	return "Checks the statically known box_extract and box_replace extents against the box I/O budget of a single app call"
+}
+
+var BoxBudgetRuleInstance = BoxBudgetRule{}
+
+func checkBoxBudget(res *ProcessResult, p ConsensusParams) []Diagnostic {
+	var diags []Diagnostic
+
+	if p.MaxAppBoxReferences == 0 {
+		return nil
+	}
+
+	budget := uint64(p.MaxAppBoxReferences) * uint64(p.BytesPerBoxReference)
+
+	for _, a := range res.BoxAccesses() {
+		switch res.Listing[a.Index].(type) {
+		case *BoxExtractExpr, *BoxReplaceExpr:
+		default:
+			continue
+		}
+
+		if a.Size <= budget || a.Index >= len(res.Sublines) {
+			continue
+		}
+
+		sub := res.Sublines[a.Index]
+
+		diags = append(diags, lintError{
+			error: errors.Errorf("%s accesses %d bytes of the box, which needs %d box references - more than the %d of a single app call, the other app calls of the group must add the box references", a.Op, a.Size, boxRefs(a.Size, p), p.MaxAppBoxReferences),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     codeEnd(sub),
+			s:     DiagWarn,
+			r:     BoxBudgetRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,97 @@
+package teal
+
+import "testing"
+
+func TestBoxAccesses(t *testing.T) {
+	res := Process(`#pragma version 8
+byte "data"
+int 8192
+int 64
+box_extract
+pop
+byte "data"
+int 100
+byte 0x0102
+box_replace
+byte "log"
+int 2048
+box_create
+pop
+txna ApplicationArgs 0
+byte 0x01020304
+box_put
+byte "data"
+txna ApplicationArgs 1
+btoi
+int 16
+box_extract
+pop
+int 1
+return`)
+
+	expected := []BoxAccess{
+		{Index: 4, Op: "box_extract", Name: "data", Size: 8256},
+		{Index: 9, Op: "box_replace", Name: "data", Size: 102},
+		{Index: 12, Op: "box_create", Name: "log", Size: 2048},
+		{Index: 16, Op: "box_put", Size: 4},
+		// the unknown start leaves the length
+		{Index: 21, Op: "box_extract", Name: "data", Size: 16},
+	}
+
+	as := res.BoxAccesses()
+	if len(as) != len(expected) {
+		t.Fatalf("expected %+v, got: %+v", expected, as)
+	}
+
+	for i, a := range as {
+		if a != expected[i] {
+			t.Errorf("expected: %+v, got: %+v", expected[i], a)
+		}
+	}
+
+	b := EstimateBoxIO(res, 8)
+	if b.Bytes != 8256+2048+4 || b.Refs != 11 || b.Budget != 8192 || !b.Exceeded {
+		t.Errorf("unexpected box io: %+v", b)
+	}
+
+	if b := EstimateBoxIO(res, 11); b.Exceeded {
+		t.Errorf("unexpected exceeded box io: %+v", b)
+	}
+
+	var lines []int
+	for _, d := range res.Diagnostics {
+		if d.Rule() == BoxBudgetRuleInstance.Id() {
+			lines = append(lines, d.Line())
+		}
+	}
+
+	if len(lines) != 1 || lines[0] != 4 {
+		t.Errorf("unexpected box budget diagnostics: %v", res.Diagnostics)
+	}
+}
+
+func TestBoxBudgetConsensus(t *testing.T) {
+	src := "#pragma version 8\nbyte \"data\"\nint 9000\nbyte 0x01\nbox_replace\nint 1\nreturn"
+
+	p := LatestConsensus()
+	p.MaxAppBoxReferences = 16
+
+	for _, test := range []struct {
+		p        ConsensusParams
+		expected int
+	}{
+		{p: LatestConsensus(), expected: 1},
+		{p: p, expected: 0},
+	} {
+		n := 0
+		for _, d := range Process(src, WithConsensus(test.p)).Diagnostics {
+			if d.Rule() == BoxBudgetRuleInstance.Id() {
+				n++
+			}
+		}
+
+		if n != test.expected {
+			t.Errorf("expected %d diagnostics with %d box references, got: %d", test.expected, test.p.MaxAppBoxReferences, n)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Package bridge lets tealsp discover the tealdbg sessions of the same program and read their runtime state.
+//
+// A debug session listens on a loopback port and registers itself with a session file in the discovery dir.
+// A client finds the session by the program path, connects and sends a single JSON request line,
+// the session replies with a single JSON response line and closes the connection.
+package bridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// Version is the protocol version, the sessions of the other versions are ignored
+const Version = 1
+
+// timeout of the client connection and of the request handling
+const timeout = time.Second
+
+// Session is the registration of a debug session in the discovery dir.
+type Session struct {
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	Addr    string `json:"addr"`
+	Pid     int    `json:"pid"`
+
+	file string
+}
+
+type request struct {
+	Op string `json:"op"`
+}
+
+type response struct {
+	State *teal.DebugState `json:"state,omitempty"`
+	Error string           `json:"error,omitempty"`
+}
+
+type config struct {
+	dir string
+}
+
+type Option func(c *config)
+
+// WithDir sets the discovery dir, used by the tests.
+func WithDir(dir string) Option {
+	return func(c *config) {
+		c.dir = dir
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		dir: filepath.Join(os.TempDir(), "teal-bridge"),
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// samePath reports whether the paths point to the same file.
+func samePath(a, b string) bool {
+	clean := func(p string) string {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		return filepath.Clean(p)
+	}
+
+	a, b = clean(a), clean(b)
+
+	if os.PathSeparator == '\\' {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// Server serves the last published state of a debug session.
+type Server struct {
+	l    net.Listener
+	file string
+
+	mu    sync.Mutex
+	state *teal.DebugState
+
+	wg sync.WaitGroup
+}
+
+// Listen starts serving the state of the debugged program and registers the session.
+func Listen(path string, opts ...Option) (*Server, error) {
+	c := newConfig(opts)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+
+	s := &Server{l: l}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	err = s.register(c.dir, Session{
+		Version: Version,
+		Path:    path,
+		Addr:    l.Addr().String(),
+		Pid:     os.Getpid(),
+	})
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) register(dir string, sess Session) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create discovery dir")
+	}
+
+	f, err := os.CreateTemp(dir, "session-*.json")
+	if err != nil {
+		return errors.Wrap(err, "failed to create session file")
+	}
+	defer f.Close()
+
+	s.file = f.Name()
+
+	err = json.NewEncoder(f).Encode(sess)
+	if err != nil {
+		os.Remove(s.file)
+		return errors.Wrap(err, "failed to write session file")
+	}
+
+	return nil
+}
+
+// Publish replaces the state served to the clients.
+func (s *Server) Publish(state teal.DebugState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = &state
+}
+
+// Close stops serving and removes the session registration.
+func (s *Server) Close() error {
+	os.Remove(s.file)
+
+	err := s.l.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var resp response
+
+	var req request
+	err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req)
+
+	switch {
+	case err != nil:
+		resp.Error = "invalid request"
+	case req.Op == "state":
+		s.mu.Lock()
+		resp.State = s.state
+		s.mu.Unlock()
+	default:
+		resp.Error = "unknown op: " + req.Op
+	}
+
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Sessions returns the registered sessions, the session files of the ended processes are removed.
+func Sessions(opts ...Option) ([]Session, error) {
+	c := newConfig(opts)
+
+	es, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read discovery dir")
+	}
+
+	var ss []Session
+
+	for _, e := range es {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+
+		file := filepath.Join(c.dir, e.Name())
+
+		bs, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		var sess Session
+		if err := json.Unmarshal(bs, &sess); err != nil || sess.Version != Version {
+			continue
+		}
+
+		sess.file = file
+		ss = append(ss, sess)
+	}
+
+	return ss, nil
+}
+
+// Query reads the state of the session, nil if nothing has been published yet.
+func Query(sess Session) (*teal.DebugState, error) {
+	conn, err := net.DialTimeout("tcp", sess.Addr, timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to session")
+	}
+
+	return query(conn)
+}
+
+func query(conn net.Conn) (*teal.DebugState, error) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	err := json.NewEncoder(conn).Encode(request{Op: "state"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+
+	var resp response
+	err = json.NewDecoder(bufio.NewReader(conn)).Decode(&resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return resp.State, nil
+}
+
+// State returns the state of the active debug session of the program, nil if there is none.
+// The unreachable sessions are considered stale and unregistered.
+func State(path string, opts ...Option) (*teal.DebugState, error) {
+	ss, err := Sessions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sess := range ss {
+		if !samePath(sess.Path, path) {
+			continue
+		}
+
+		conn, err := net.DialTimeout("tcp", sess.Addr, timeout)
+		if err != nil {
+			os.Remove(sess.file)
+			continue
+		}
+
+		st, err := query(conn)
+		if err == nil && st != nil {
+			return st, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.teal")
+
+	s, err := Listen(path, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	st, err := State(path, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != nil {
+		t.Fatalf("unexpected state before publish: %v", st)
+	}
+
+	expected := teal.DebugState{Path: path, Line: 3, Stack: []string{"uint64: 1"}}
+	s.Publish(expected)
+
+	st, err = State(path, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st == nil || !reflect.DeepEqual(*st, expected) {
+		t.Fatalf("expected: %v, got: %v", expected, st)
+	}
+
+	st, err = State(filepath.Join(dir, "other.teal"), WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != nil {
+		t.Fatalf("unexpected state of another program: %v", st)
+	}
+}
+
+func TestStaleSession(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.teal")
+
+	s, err := Listen(path, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := s.file
+
+	// simulate a crashed session: the listener is gone but the registration is left behind
+	s.l.Close()
+	s.wg.Wait()
+
+	st, err := State(path, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != nil {
+		t.Fatalf("unexpected state: %v", st)
+	}
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected the stale session file to be removed: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package teal
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// SubroutineBudget is the budget consumed by a subroutine on a path, the main program is named MainName.
+type SubroutineBudget struct {
+	Name  string `json:"name"`
+	Calls int    `json:"calls"`
+
+	// Inclusive counts the ops of the subroutine and of the subroutines it calls, Exclusive only its own ops
+	Inclusive int `json:"inclusive"`
+	Exclusive int `json:"exclusive"`
+}
+
+// budgetEvent opens or closes a subroutine at the budget consumed before it.
+type budgetEvent struct {
+	open bool
+	name string
+	at   int
+}
+
+// walkBudget replays the traced ops with their costs on the call stack, the callsub is charged to the caller
+// and the retsub to the callee. It returns the total consumed budget.
+func (b *VmBranch) walkBudget(op func(stack []string, cost int), event func(e budgetEvent)) int {
+	stack := []string{MainName}
+	event(budgetEvent{open: true, name: MainName})
+
+	at := 0
+	for i, o := range b.Trace {
+		cost := 1
+		if i < len(b.Costs) {
+			cost = b.Costs[i]
+		}
+
+		op(stack, cost)
+		at += cost
+
+		switch o := o.(type) {
+		case *CallSubExpr:
+			stack = append(stack, o.Label.Name)
+			event(budgetEvent{open: true, name: o.Label.Name, at: at})
+		case *RetSubExpr:
+			if len(stack) > 1 {
+				event(budgetEvent{name: stack[len(stack)-1], at: at})
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	// the program may exit inside of a subroutine
+	for i := len(stack) - 1; i >= 0; i-- {
+		event(budgetEvent{name: stack[i], at: at})
+	}
+
+	return at
+}
+
+// SubroutineBudgets returns the budget consumed by the subroutines on the branch path in the order of their first call.
+func (b *VmBranch) SubroutineBudgets() []SubroutineBudget {
+	var res []SubroutineBudget
+	index := map[string]int{}
+
+	get := func(name string) *SubroutineBudget {
+		i, ok := index[name]
+		if !ok {
+			i = len(res)
+			index[name] = i
+			res = append(res, SubroutineBudget{Name: name})
+		}
+		return &res[i]
+	}
+
+	b.walkBudget(func(stack []string, cost int) {
+		get(stack[len(stack)-1]).Exclusive += cost
+
+		// the recursive calls are counted once
+		seen := map[string]bool{}
+		for _, name := range stack {
+			if !seen[name] {
+				seen[name] = true
+				get(name).Inclusive += cost
+			}
+		}
+	}, func(e budgetEvent) {
+		if e.open {
+			get(e.name).Calls++
+		}
+	})
+
+	return res
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+type speedscopeEvent struct {
+	Type  string `json:"type"`
+	Frame int    `json:"frame"`
+	At    int    `json:"at"`
+}
+
+type speedscopeProfile struct {
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Unit       string `json:"unit"`
+	StartValue int    `json:"startValue"`
+	EndValue   int    `json:"endValue"`
+
+	// Events are set for the evented profiles, Samples and Weights for the sampled ones
+	Events  []speedscopeEvent `json:"events,omitempty"`
+	Samples [][]int           `json:"samples,omitempty"`
+	Weights []int             `json:"weights,omitempty"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Name               string              `json:"name"`
+	Exporter           string              `json:"exporter"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+}
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// WriteSpeedscope writes the budget consumed by the subroutines on the branch path as an evented speedscope profile,
+// the flame graph shows which of the subroutines burns the budget. The file is the source path shown for the frames.
+func (b *VmBranch) WriteSpeedscope(w io.Writer, name string, file string) error {
+	var frames []speedscopeFrame
+	index := map[string]int{}
+
+	frame := func(sub string) int {
+		i, ok := index[sub]
+		if !ok {
+			i = len(frames)
+			index[sub] = i
+
+			f := speedscopeFrame{Name: sub, File: file}
+			if ln, ok := b.vm.syms[sub]; ok {
+				f.Line = b.vm.sourceLine(ln) + 1
+			}
+
+			frames = append(frames, f)
+		}
+		return i
+	}
+
+	events := []speedscopeEvent{}
+
+	total := b.walkBudget(func(stack []string, cost int) {}, func(e budgetEvent) {
+		t := "C"
+		if e.open {
+			t = "O"
+		}
+
+		events = append(events, speedscopeEvent{Type: t, Frame: frame(e.name), At: e.at})
+	})
+
+	err := json.NewEncoder(w).Encode(speedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     name,
+		Exporter: "teal",
+		Shared:   speedscopeShared{Frames: frames},
+		Profiles: []speedscopeProfile{
+			{
+				Type:     "evented",
+				Name:     name,
+				Unit:     "none",
+				EndValue: total,
+				Events:   events,
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to write speedscope profile")
+	}
+
+	return nil
+}
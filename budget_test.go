@@ -0,0 +1,78 @@
+package teal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const budgetSource = `#pragma version 8
+callsub a
+callsub b
+int 1
+return
+a:
+int 1
+pop
+callsub b
+retsub
+b:
+int 2
+pop
+retsub`
+
+func TestSubroutineBudgets(t *testing.T) {
+	res := Process(budgetSource)
+
+	c := EstimateCost(res, VmDefaultBudget)
+	if c.Max != 14 {
+		t.Fatalf("unexpected cost: %d", c.Max)
+	}
+
+	expected := []SubroutineBudget{
+		{Name: MainName, Calls: 1, Inclusive: 14, Exclusive: 4},
+		{Name: "a", Calls: 1, Inclusive: 7, Exclusive: 4},
+		{Name: "b", Calls: 2, Inclusive: 6, Exclusive: 6},
+	}
+
+	if !reflect.DeepEqual(c.Subroutines, expected) {
+		t.Errorf("expected: %v, got: %v", expected, c.Subroutines)
+	}
+}
+
+func TestWriteSpeedscope(t *testing.T) {
+	res := Process(budgetSource)
+
+	c := EstimateCost(res, VmDefaultBudget)
+
+	buf := &bytes.Buffer{}
+	err := c.Path.WriteSpeedscope(buf, "p", "p.teal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var f speedscopeFile
+	err = json.Unmarshal(buf.Bytes(), &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.Shared.Frames) != 3 || f.Shared.Frames[2].Name != "b" || f.Shared.Frames[2].Line != 11 {
+		t.Fatalf("unexpected frames: %+v", f.Shared.Frames)
+	}
+
+	p := f.Profiles[0]
+	if p.EndValue != 14 || len(p.Events) != 8 {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+
+	// b called from a opens after the callsub of a and its own ops
+	if e := p.Events[2]; e.Type != "O" || e.Frame != 2 || e.At != 4 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	if e := p.Events[7]; e.Type != "C" || e.Frame != 0 || e.At != 14 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
@@ -0,0 +1,92 @@
+package teal
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"runtime/debug"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const buildHashPrefix = "sha256:"
+
+// BuildInfo describes the assembled program so that the build can be reproduced and the deployed program verified.
+type BuildInfo struct {
+	SourceHash string `json:"source_hash"`
+
+	// Tool is the module and version of the tool that generated the build info
+	Tool string `json:"tool"`
+
+	// Assembler is the version of the assembler that produced the program
+	Assembler string `json:"assembler,omitempty"`
+
+	// Version is the AVM version of the program
+	Version uint64 `json:"version"`
+
+	Options map[string]string `json:"options,omitempty"`
+
+	ProgramHash string `json:"program_hash"`
+}
+
+func buildHash(bs []byte) string {
+	h := sha256.Sum256(bs)
+	return buildHashPrefix + hex.EncodeToString(h[:])
+}
+
+func buildTool() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "teal"
+	}
+
+	for _, dep := range bi.Deps {
+		if dep.Path == "github.com/dragmz/teal" {
+			return dep.Path + "@" + dep.Version
+		}
+	}
+
+	if strings.HasPrefix(bi.Main.Path, "github.com/dragmz/teal") {
+		return bi.Main.Path + "@" + bi.Main.Version
+	}
+
+	return "teal"
+}
+
+// NewBuildInfo returns the build info of the program assembled from the source.
+func NewBuildInfo(source []byte, program []byte) BuildInfo {
+	return BuildInfo{
+		SourceHash:  buildHash(source),
+		Tool:        buildTool(),
+		Version:     Process(string(source)).Version,
+		ProgramHash: buildHash(program),
+	}
+}
+
+// VerifySource checks that the source matches the build info.
+func (b BuildInfo) VerifySource(source []byte) error {
+	if h := buildHash(source); h != b.SourceHash {
+		return errors.Errorf("source hash mismatch: expected %s, got %s", b.SourceHash, h)
+	}
+
+	return nil
+}
+
+// VerifyProgram checks that the program bytes, e.g. of a deployed app, match the build info.
+func (b BuildInfo) VerifyProgram(program []byte) error {
+	v, n := binary.Uvarint(program)
+	if n <= 0 {
+		return errors.New("failed to read program version")
+	}
+
+	if v != b.Version {
+		return errors.Errorf("program version mismatch: expected %d, got %d", b.Version, v)
+	}
+
+	if h := buildHash(program); h != b.ProgramHash {
+		return errors.Errorf("program hash mismatch: expected %s, got %s", b.ProgramHash, h)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestBuildInfo(t *testing.T) {
+	src := []byte("#pragma version 8\nint 1\nreturn\n")
+
+	// pushint 1, return
+	prog := []byte{0x08, 0x81, 0x01, 0x43}
+
+	b := NewBuildInfo(src, prog)
+	if b.Version != 8 {
+		t.Fatalf("unexpected version: %d", b.Version)
+	}
+
+	if err := b.VerifySource(src); err != nil {
+		t.Error(err)
+	}
+
+	if err := b.VerifySource([]byte("#pragma version 8\nint 0\nreturn\n")); err == nil {
+		t.Error("expected source mismatch")
+	}
+
+	if err := b.VerifyProgram(prog); err != nil {
+		t.Error(err)
+	}
+
+	if err := b.VerifyProgram([]byte{0x08, 0x81, 0x00, 0x43}); err == nil {
+		t.Error("expected program hash mismatch")
+	}
+
+	if err := b.VerifyProgram([]byte{0x07, 0x81, 0x01, 0x43}); err == nil {
+		t.Error("expected program version mismatch")
+	}
+
+	if err := b.VerifyProgram(nil); err == nil {
+		t.Error("expected empty program error")
+	}
+}
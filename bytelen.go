@@ -0,0 +1,147 @@
+package teal
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// txnFieldLengths are the lengths of the fixed size transaction fields, e.g. the addresses
+var txnFieldLengths = map[TxnField]int{
+	Sender:                  32,
+	Receiver:                32,
+	CloseRemainderTo:        32,
+	VotePK:                  32,
+	SelectionPK:             32,
+	Lease:                   32,
+	TxID:                    32,
+	AssetSender:             32,
+	AssetReceiver:           32,
+	AssetCloseTo:            32,
+	RekeyTo:                 32,
+	ConfigAssetManager:      32,
+	ConfigAssetReserve:      32,
+	ConfigAssetFreeze:       32,
+	ConfigAssetClawback:     32,
+	ConfigAssetMetadataHash: 32,
+	FreezeAssetAccount:      32,
+	Accounts:                32,
+	StateProofPK:            64,
+}
+
+// globalFieldLengths are the lengths of the fixed size global fields
+var globalFieldLengths = map[GlobalField]int{
+	ZeroAddress:               32,
+	GroupID:                   32,
+	CreatorAddress:            32,
+	CurrentApplicationAddress: 32,
+	CallerApplicationAddress:  32,
+}
+
+// vmTxnField returns the unknown value of the transaction field, with the length of the fixed size fields.
+func vmTxnField(spec txnFieldSpec) VmValue {
+	if n, ok := txnFieldLengths[spec.field]; ok {
+		return VmValue{T: VmTypeBytes, src: vmFixedValue{n: n, s: spec.field.String()}}
+	}
+
+	return VmValue{T: spec.Type().Vm()}
+}
+
+// vmGlobalField returns the unknown value of the global field, with the length of the fixed size fields.
+func vmGlobalField(spec globalFieldSpec) VmValue {
+	if n, ok := globalFieldLengths[spec.field]; ok {
+		return VmValue{T: VmTypeBytes, src: vmFixedValue{n: n, s: spec.field.String()}}
+	}
+
+	return VmValue{T: spec.Type().Vm()}
+}
+
+// VmLengthMismatch is a comparison of the byte values of the different known lengths, always false with == and true with !=.
+type VmLengthMismatch struct {
+	Line int
+	X    VmValue
+	Y    VmValue
+}
+
+// describeBytes formats the constant bytes as a string literal if printable or the value source otherwise.
+func describeBytes(v VmValue) string {
+	if bs, ok := v.Bytes(); ok {
+		s := FormatKey(bs)
+		if s == string(bs) {
+			return fmt.Sprintf("%q", s)
+		}
+
+		return s
+	}
+
+	if v.src != nil {
+		return v.src.String()
+	}
+
+	return "?"
+}
+
+func (m VmLengthMismatch) String() string {
+	return fmt.Sprintf("comparing bytes of different lengths: %s (%d bytes) and %s (%d bytes)", describeBytes(m.X), m.X.Lengths()[0], describeBytes(m.Y), m.Y.Lengths()[0])
+}
+
+// checkLengths records the comparison of the byte values with the different known lengths.
+func (b *VmBranch) checkLengths(x VmValue, y VmValue) {
+	if x.T != VmTypeBytes || y.T != VmTypeBytes {
+		return
+	}
+
+	xl := x.Lengths()
+	yl := y.Lengths()
+
+	if len(xl) != 1 || len(yl) != 1 || xl[0] == yl[0] {
+		return
+	}
+
+	b.vm.LengthMismatches = append(b.vm.LengthMismatches, VmLengthMismatch{
+		Line: b.Line,
+		X:    x,
+		Y:    y,
+	})
+}
+
+func hasEqualityOps(listing Listing) bool {
+	for _, op := range listing {
+		switch op.(type) {
+		case *EqExpr, *NeqExpr:
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkLengthMismatches reports the == and != comparisons of the byte values with the different known lengths.
+func checkLengthMismatches(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	vm := NewVm(res)
+	vm.RunAll(stateMaxBranches)
+
+	seen := map[int]bool{}
+
+	for _, m := range vm.LengthMismatches {
+		if seen[m.Line] || m.Line >= len(res.Sublines) {
+			continue
+		}
+
+		seen[m.Line] = true
+
+		sub := res.Sublines[m.Line]
+		diags = append(diags, lintError{
+			error: errors.New(m.String()),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagWarn,
+			r:     ByteLengthMismatchRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,83 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestByteLengthMismatch(t *testing.T) {
+	type test struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}
+
+	tests := []test{
+		{
+			name: "encoded address",
+			src:  "#pragma version 8\ntxn Sender\nbyte \"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\"\n==\nreturn",
+			line: 3,
+			msg:  "Sender (32 bytes) and \"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\" (58 bytes)",
+		},
+		{
+			name: "itob",
+			src:  "#pragma version 8\nint 1\nitob\nbyte 0x00000001\n!=\nreturn",
+			line: 4,
+			msg:  "itob (8 bytes) and 0x00000001 (4 bytes)",
+		},
+		{
+			name: "concat",
+			src:  "#pragma version 8\ntxn Sender\nbyte \"x\"\nconcat\nglobal ZeroAddress\n==\nreturn",
+			line: 5,
+			msg:  "(33 bytes) and ZeroAddress (32 bytes)",
+		},
+		{
+			name: "addresses",
+			src:  "#pragma version 8\ntxn Sender\nglobal CreatorAddress\n==\nreturn",
+			line: -1,
+		},
+		{
+			name: "unknown length",
+			src:  "#pragma version 8\ntxna ApplicationArgs 0\nbyte \"abc\"\n==\nreturn",
+			line: -1,
+		},
+		{
+			name: "uint64",
+			src:  "#pragma version 8\nint 1\nint 2\n==\nreturn",
+			line: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := Process(test.src)
+
+			var ds []Diagnostic
+			for _, d := range res.Diagnostics {
+				if d.Rule() == ByteLengthMismatchRuleInstance.Id() {
+					ds = append(ds, d)
+				}
+			}
+
+			if test.line < 0 {
+				if len(ds) > 0 {
+					t.Fatalf("unexpected diagnostics: %v", ds)
+				}
+				return
+			}
+
+			if len(ds) != 1 {
+				t.Fatalf("expected a single diagnostic, got: %v", ds)
+			}
+
+			if ds[0].Line() != test.line {
+				t.Errorf("expected line: %d, got: %d", test.line, ds[0].Line())
+			}
+
+			if !strings.Contains(ds[0].String(), test.msg) {
+				t.Errorf("expected message: %s, got: %s", test.msg, ds[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package teal
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxByteMathSize is the max size of the b+, b-, b*, b/, b%, bsqrt and byte comparison operands - 512 bits
+	MaxByteMathSize = 64
+
+	// MaxStringSize is the max size of a byte value
+	MaxStringSize = 4096
+)
+
+// vmByteMath evaluates the big-endian arithmetic on the operands when both of them are known.
+// The branch fails if an operand exceeds MaxByteMathSize or f returns an error, e.g. on division by zero.
+func vmByteMath(b *VmBranch, f func(x, y *big.Int) (*big.Int, error)) {
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+
+	bs, ok := vmKnownBytes(x, y)
+	if !ok {
+		b.push(VmValue{T: VmTypeBytes})
+		b.Line++
+		return
+	}
+
+	if len(bs[0]) > MaxByteMathSize || len(bs[1]) > MaxByteMathSize {
+		b.failf("byte math operand exceeds %d bytes", MaxByteMathSize)
+		return
+	}
+
+	r, err := f(new(big.Int).SetBytes(bs[0]), new(big.Int).SetBytes(bs[1]))
+	if err != nil {
+		b.failf("%s", err)
+		return
+	}
+
+	b.push(NewVmBytes(r.Bytes()))
+	b.Line++
+}
+
+// vmByteCompare evaluates the big-endian comparison of the operands when both of them are known.
+func vmByteCompare(b *VmBranch, f func(c int) bool) {
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+
+	bs, ok := vmKnownBytes(x, y)
+	if !ok {
+		b.push(VmValue{T: VmTypeUint64})
+		b.Line++
+		return
+	}
+
+	if len(bs[0]) > MaxByteMathSize || len(bs[1]) > MaxByteMathSize {
+		b.failf("byte math operand exceeds %d bytes", MaxByteMathSize)
+		return
+	}
+
+	b.push(vmBool(f(new(big.Int).SetBytes(bs[0]).Cmp(new(big.Int).SetBytes(bs[1])))))
+	b.Line++
+}
+
+// vmByteBitwise evaluates the bitwise op on the operands when both of them are known.
+// The shorter operand is zero-extended on the left to the length of the longer one.
+func vmByteBitwise(b *VmBranch, f func(x, y byte) byte) {
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+
+	bs, ok := vmKnownBytes(x, y)
+	if !ok {
+		b.push(VmValue{T: VmTypeBytes})
+		b.Line++
+		return
+	}
+
+	xs, ys := bs[0], bs[1]
+
+	n := len(xs)
+	if len(ys) > n {
+		n = len(ys)
+	}
+
+	res := make([]byte, n)
+	for i := range res {
+		var xb, yb byte
+
+		if k := i - (n - len(xs)); k >= 0 {
+			xb = xs[k]
+		}
+
+		if k := i - (n - len(ys)); k >= 0 {
+			yb = ys[k]
+		}
+
+		res[i] = f(xb, yb)
+	}
+
+	b.push(NewVmBytes(res))
+	b.Line++
+}
+
+func bytesPlus(x, y *big.Int) (*big.Int, error) {
+	return new(big.Int).Add(x, y), nil
+}
+
+func bytesMinus(x, y *big.Int) (*big.Int, error) {
+	if x.Cmp(y) < 0 {
+		return nil, errors.New("byte math underflow")
+	}
+
+	return new(big.Int).Sub(x, y), nil
+}
+
+func bytesMul(x, y *big.Int) (*big.Int, error) {
+	return new(big.Int).Mul(x, y), nil
+}
+
+func bytesDiv(x, y *big.Int) (*big.Int, error) {
+	if y.Sign() == 0 {
+		return nil, errors.New("division by zero")
+	}
+
+	return new(big.Int).Div(x, y), nil
+}
+
+func bytesModulo(x, y *big.Int) (*big.Int, error) {
+	if y.Sign() == 0 {
+		return nil, errors.New("division by zero")
+	}
+
+	return new(big.Int).Mod(x, y), nil
+}
@@ -0,0 +1,95 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestByteMath(t *testing.T) {
+	max := "0x" + strings.Repeat("ff", MaxByteMathSize)
+	over := "0x01" + strings.Repeat("00", MaxByteMathSize)
+
+	tests := []struct {
+		args []string
+		op   string
+		res  string
+		fail bool
+	}{
+		{args: []string{"0x01", "0x02"}, op: "b+", res: "0x03"},
+		{args: []string{"0xff", "0x01"}, op: "b+", res: "0x0100"},
+		{args: []string{max, "0x01"}, op: "b+", res: over},
+		{args: []string{over, "0x01"}, op: "b+", fail: true},
+		{args: []string{"0x0100", "0x01"}, op: "b-", res: "0xff"},
+		{args: []string{"0x0001", "0x01"}, op: "b-", res: `""`},
+		{args: []string{"0x01", "0x02"}, op: "b-", fail: true},
+		{args: []string{"0xffff", "0xffff"}, op: "b*", res: "0xfffe0001"},
+		{args: []string{"0x0100", "0x02"}, op: "b/", res: "0x80"},
+		{args: []string{"0x10", "0x00"}, op: "b/", fail: true},
+		{args: []string{"0x0101", "0x10"}, op: "b%", res: "0x01"},
+		{args: []string{"0x10", `""`}, op: "b%", fail: true},
+		{args: []string{"0x0f00", "0xf0"}, op: "b|", res: "0x0ff0"},
+		{args: []string{"0xff00", "0x0f"}, op: "b&", res: "0x0000"},
+		{args: []string{"0xf0", "0x0ff0"}, op: "b^", res: "0x0f00"},
+		{args: []string{"0x00ff"}, op: "b~", res: "0xff00"},
+		{args: []string{"0x10"}, op: "bsqrt", res: "0x04"},
+		{args: []string{"0x0101"}, op: "bsqrt", res: "0x10"},
+		{args: []string{over}, op: "bsqrt", fail: true},
+		{args: []string{"int 3"}, op: "bzero", res: "0x000000"},
+	}
+
+	for _, test := range tests {
+		var lines []string
+
+		lines = append(lines, "#pragma version 8")
+		for _, arg := range test.args {
+			if strings.HasPrefix(arg, "int ") {
+				lines = append(lines, arg)
+			} else {
+				lines = append(lines, "byte "+arg)
+			}
+		}
+
+		lines = append(lines, test.op)
+
+		expected := VmExitApprove
+		if test.fail {
+			lines = append(lines, "pop", "int 1", "return")
+			expected = VmExitFail
+		} else {
+			lines = append(lines, "byte "+test.res, "==", "return")
+		}
+
+		if exit := runExit(t, strings.Join(lines, "\n")); exit != expected {
+			t.Errorf("unexpected exit for %s %s: %s", test.op, strings.Join(test.args, " "), exit)
+		}
+	}
+}
+
+func TestByteCompare(t *testing.T) {
+	tests := []struct {
+		x, y string
+		op   string
+		res  bool
+	}{
+		{"0x0001", "0x02", "b<", true},
+		{"0x02", "0x0001", "b<", false},
+		{"0x0100", "0xff", "b>", true},
+		{"0x01", "0x0001", "b<=", true},
+		{"0x01", "0x02", "b>=", false},
+		{"0x0001", "0x01", "b==", true},
+		{"0x0001", "0x01", "b!=", false},
+		{`""`, "0x00", "b==", true},
+	}
+
+	for _, test := range tests {
+		expected := VmExitReject
+		if test.res {
+			expected = VmExitApprove
+		}
+
+		if exit := runExit(t, fmt.Sprintf("#pragma version 8\nbyte %s\nbyte %s\n%s\nreturn", test.x, test.y, test.op)); exit != expected {
+			t.Errorf("unexpected exit for %s %s %s: %s", test.x, test.op, test.y, exit)
+		}
+	}
+}
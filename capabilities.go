@@ -0,0 +1,102 @@
+package teal
+
+// Capability is an effect the program may have, with the source lines enabling it.
+type Capability struct {
+	Possible bool  `json:"possible"`
+	Lines    []int `json:"lines,omitempty"`
+}
+
+func (c *Capability) add(line int) {
+	c.Possible = true
+	c.Lines = append(c.Lines, line)
+}
+
+// Capabilities is the summary of the sensitive effects of a program, e.g. for the wallet risk engines.
+// The inner transaction fields set to values that are not constants are assumed to enable the capability.
+type Capabilities struct {
+	// InnerTxns is set if the program submits inner transactions
+	InnerTxns Capability `json:"innerTxns"`
+
+	// Rekey is set if an inner transaction sets RekeyTo to an address other than the zero address
+	Rekey Capability `json:"rekey"`
+
+	// Clawback is set if an inner transaction sets AssetSender
+	Clawback Capability `json:"clawback"`
+
+	// UpdateApp and DeleteApp are set if an inner transaction sets OnCompletion to UpdateApplication or DeleteApplication
+	UpdateApp Capability `json:"updateApp"`
+	DeleteApp Capability `json:"deleteApp"`
+
+	// Updatable and Deletable are set if the program may approve its own update or deletion
+	Updatable bool `json:"updatable"`
+	Deletable bool `json:"deletable"`
+}
+
+func isZeroAddress(op Op) bool {
+	e, ok := op.(*GlobalExpr)
+	return ok && e.Field == ZeroAddress
+}
+
+func uintConst(op Op) (uint64, bool) {
+	switch op := op.(type) {
+	case *IntExpr:
+		return op.Value, true
+	case *PushIntExpr:
+		return op.Value, true
+	}
+
+	return 0, false
+}
+
+// AnalyzeCapabilities reports whether the program can rekey accounts, claw back assets, update or delete apps
+// with inner transactions and whether it may approve its own update or deletion.
+func AnalyzeCapabilities(res *ProcessResult) Capabilities {
+	var c Capabilities
+
+	var prev Op
+
+	for i, op := range res.Listing {
+		switch op.(type) {
+		case *EmptyExpr, *CommentExpr, *LabelExpr, *PragmaExpr:
+			continue
+		}
+
+		line := res.SourceLine(i)
+
+		switch op := op.(type) {
+		case *ItxnSubmitExpr:
+			c.InnerTxns.add(line)
+		case *ItxnFieldExpr:
+			switch op.Field {
+			case RekeyTo:
+				if prev == nil || !isZeroAddress(prev) {
+					c.Rekey.add(line)
+				}
+			case AssetSender:
+				c.Clawback.add(line)
+			case OnCompletion:
+				v, ok := uintConst(prev)
+				if !ok || v == uint64(UpdateApplication) {
+					c.UpdateApp.add(line)
+				}
+				if !ok || v == uint64(DeleteApplication) {
+					c.DeleteApp.add(line)
+				}
+			}
+		}
+
+		prev = op
+	}
+
+	l := AnalyzeLifecycle(res)
+
+	if lc, ok := l.Case(UpdateApplication, false); ok {
+		c.Updatable = lc.Approve
+	}
+
+	if lc, ok := l.Case(DeleteApplication, false); ok {
+		c.Deletable = lc.Approve
+	}
+
+	return c
+}
@@ -0,0 +1,89 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	res := Process(`#pragma version 8
+txn OnCompletion
+int UpdateApplication
+==
+bnz update
+txn OnCompletion
+int NoOp
+==
+assert
+itxn_begin
+int axfer
+itxn_field TypeEnum
+txna Accounts 1
+itxn_field AssetSender
+global ZeroAddress
+itxn_field RekeyTo
+itxn_submit
+itxn_begin
+int appl
+itxn_field TypeEnum
+int DeleteApplication
+itxn_field OnCompletion
+txna Accounts 2
+itxn_field RekeyTo
+itxn_submit
+int 1
+return
+update:
+txn Sender
+global CreatorAddress
+==
+return`)
+
+	for _, d := range res.Diagnostics {
+		if d.Severity() == DiagErr {
+			t.Fatalf("unexpected error: %s", d)
+		}
+	}
+
+	c := AnalyzeCapabilities(res)
+
+	type test struct {
+		name  string
+		c     Capability
+		lines []int
+	}
+
+	tests := []test{
+		{"inner txns", c.InnerTxns, []int{16, 24}},
+		{"rekey", c.Rekey, []int{23}},
+		{"clawback", c.Clawback, []int{13}},
+		{"update app", c.UpdateApp, nil},
+		{"delete app", c.DeleteApp, []int{21}},
+	}
+
+	for _, test := range tests {
+		if test.c.Possible != (len(test.lines) > 0) {
+			t.Errorf("%s: unexpected possible: %v", test.name, test.c.Possible)
+			continue
+		}
+
+		if len(test.c.Lines) != len(test.lines) {
+			t.Errorf("%s: unexpected lines: %v, expected: %v", test.name, test.c.Lines, test.lines)
+			continue
+		}
+
+		for i, l := range test.lines {
+			if test.c.Lines[i] != l {
+				t.Errorf("%s: unexpected lines: %v, expected: %v", test.name, test.c.Lines, test.lines)
+				break
+			}
+		}
+	}
+
+	if !c.Updatable {
+		t.Error("expected updatable")
+	}
+
+	if c.Deletable {
+		t.Error("unexpected deletable")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealabi"
+	"github.com/dragmz/teal/internal/cli/tealbench"
+	"github.com/dragmz/teal/internal/cli/tealbuild"
+	"github.com/dragmz/teal/internal/cli/tealcompat"
+	"github.com/dragmz/teal/internal/cli/tealdbg"
+	"github.com/dragmz/teal/internal/cli/tealdiff"
+	"github.com/dragmz/teal/internal/cli/tealgrep"
+	"github.com/dragmz/teal/internal/cli/tealint"
+	"github.com/dragmz/teal/internal/cli/tealplay"
+	"github.com/dragmz/teal/internal/cli/tealprof"
+	"github.com/dragmz/teal/internal/cli/tealsarif"
+	"github.com/dragmz/teal/internal/cli/tealsp"
+	"github.com/dragmz/teal/internal/cli/tealtokenize"
+)
+
+func command() cli.Command {
+	return cli.Command{
+		Commands: []cli.Command{
+			tealint.Command(),
+			tealsarif.Command(),
+			tealabi.Command(),
+			tealdiff.Command(),
+			tealsp.Command(),
+			tealdbg.Command(),
+			tealtokenize.Command(),
+			tealbuild.Command(),
+			tealcompat.Command(),
+			tealbench.Command(),
+			tealprof.Command(),
+			tealgrep.Command(),
+			tealplay.Command(),
+			cli.CompletionCommand("teal", command),
+		},
+	}
+}
+
+func main() {
+	os.Exit(cli.Main(command(), "teal", os.Args[1:]))
+}
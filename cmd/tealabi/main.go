@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealabi"
+)
+
+func main() {
+	os.Exit(cli.Main(tealabi.Command(), "tealabi", os.Args[1:]))
+}
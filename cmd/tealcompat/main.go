@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealcompat"
+)
+
+func main() {
+	os.Exit(cli.Main(tealcompat.Command(), "tealcompat", os.Args[1:]))
+}
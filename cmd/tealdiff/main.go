@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealdiff"
+)
+
+func main() {
+	os.Exit(cli.Main(tealdiff.Command(), "tealdiff", os.Args[1:]))
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealgrep"
+)
+
+func main() {
+	os.Exit(cli.Main(tealgrep.Command(), "tealgrep", os.Args[1:]))
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealint"
+)
+
+func main() {
+	os.Exit(cli.Main(tealint.Command(), "tealint", os.Args[1:]))
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/cli/tealplay"
+)
+
+func main() {
+	os.Exit(cli.Main(tealplay.Command(), "tealplay", os.Args[1:]))
+}
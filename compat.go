@@ -0,0 +1,64 @@
+package teal
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompatDiff is a line that goal clerk compile and the default processing treat differently.
+type CompatDiff struct {
+	Line int `json:"line"`
+
+	// Goal is the error reported by goal, empty if goal accepts the line
+	Goal string `json:"goal,omitempty"`
+
+	// Teal is the error reported by the default processing, empty if it accepts the line
+	Teal string `json:"teal,omitempty"`
+}
+
+func errorsByLine(res *ProcessResult) map[int]string {
+	es := map[int][]string{}
+	for _, d := range res.Diagnostics {
+		if d.Severity() == DiagErr {
+			es[d.Line()] = append(es[d.Line()], d.String())
+		}
+	}
+
+	m := map[int]string{}
+	for l, ss := range es {
+		m[l] = strings.Join(ss, "; ")
+	}
+
+	return m
+}
+
+// CheckGoalCompat returns the lines where the default processing and goal clerk compile disagree.
+func CheckGoalCompat(source string, opts ...ProcessOption) []CompatDiff {
+	teal := errorsByLine(Process(source, opts...))
+	goal := errorsByLine(Process(source, append([]ProcessOption{WithGoalCompat()}, opts...)...))
+
+	lines := map[int]bool{}
+	for l := range teal {
+		lines[l] = true
+	}
+	for l := range goal {
+		lines[l] = true
+	}
+
+	var ds []CompatDiff
+	for l := range lines {
+		if teal[l] != goal[l] {
+			ds = append(ds, CompatDiff{
+				Line: l,
+				Goal: goal[l],
+				Teal: teal[l],
+			})
+		}
+	}
+
+	sort.Slice(ds, func(i, j int) bool {
+		return ds[i].Line < ds[j].Line
+	})
+
+	return ds
+}
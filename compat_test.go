@@ -0,0 +1,81 @@
+package teal
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goalFixture is the outcome of goal clerk compile for the source.
+type goalFixture struct {
+	Name    string   `json:"name"`
+	Source  string   `json:"source"`
+	Listing []string `json:"listing"`
+	Errors  []int    `json:"errors"`
+	Message string   `json:"message"`
+}
+
+func TestGoalCompatFixtures(t *testing.T) {
+	bs, err := os.ReadFile("testdata/goal/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fs []goalFixture
+	if err := json.Unmarshal(bs, &fs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range fs {
+		t.Run(f.Name, func(t *testing.T) {
+			res := Process(f.Source, WithGoalCompat())
+
+			var lines []int
+			var msgs []string
+			for _, d := range res.Diagnostics {
+				if d.Severity() == DiagErr {
+					lines = append(lines, d.Line())
+					msgs = append(msgs, d.String())
+				}
+			}
+
+			if !reflect.DeepEqual(lines, f.Errors) {
+				t.Fatalf("unexpected error lines: %v, errors: %v", lines, msgs)
+			}
+
+			if f.Message != "" && !strings.Contains(strings.Join(msgs, "\n"), f.Message) {
+				t.Errorf("expected error: %s, got: %v", f.Message, msgs)
+			}
+
+			if f.Listing == nil {
+				return
+			}
+
+			var listing []string
+			for _, op := range res.Listing {
+				listing = append(listing, op.String())
+			}
+
+			if !reflect.DeepEqual(listing, f.Listing) {
+				t.Errorf("unexpected listing: %q, expected: %q", listing, f.Listing)
+			}
+		})
+	}
+}
+
+func TestCheckGoalCompat(t *testing.T) {
+	ds := CheckGoalCompat("#pragma version 8\n#pragma version 8\nint 1\n")
+	if len(ds) != 1 {
+		t.Fatalf("unexpected diffs: %v", ds)
+	}
+
+	if ds[0].Line != 1 || ds[0].Goal != "" || ds[0].Teal == "" {
+		t.Errorf("unexpected diff: %+v", ds[0])
+	}
+
+	if ds := CheckGoalCompat("#pragma version 8\nint 1\n"); len(ds) != 0 {
+		t.Errorf("unexpected diffs: %v", ds)
+	}
+}
@@ -264,6 +264,30 @@ func (p Program) Compile(c *compiler) Listing {
 	return l
 }
 
+func (e *NestedExpr) Compile(c *compiler) []Op {
+	var l []Op
+
+	if e.Label != nil {
+		l = append(l, e.Label)
+	}
+
+	return c.compile(l, e.Body...)
+}
+
+func (e *FuncExpr) Compile(c *compiler) []Op {
+	l := []Op{e.Label}
+
+	if e.Proto != nil {
+		l = append(l, e.Proto)
+	}
+
+	if e.Block != nil {
+		l = append(l, e.Block.Compile(c)...)
+	}
+
+	return l
+}
+
 func (c *compiler) compile(to []Op, exprs ...Expr) []Op {
 	for i, e := range exprs {
 		switch e := e.(type) {
@@ -0,0 +1,260 @@
+package teal
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ConsensusParams are the limits of the protocol the programs are checked against.
+type ConsensusParams struct {
+	// Version is the consensus protocol version, e.g. 36
+	Version uint64
+
+	// LogicSigVersion is the max AVM version of the programs
+	LogicSigVersion uint64
+
+	LogicSigMaxSize int
+	LogicSigMaxCost int
+
+	// MaxAppProgramLen is the max size of the approval and the clear state program per page,
+	// MaxAppTotalProgramLen the max size of both of them without the extra pages
+	MaxAppProgramLen        int
+	MaxAppTotalProgramLen   int
+	MaxExtraAppProgramPages int
+
+	MaxAppProgramCost int
+
+	MaxAppArgs        int
+	MaxAppTotalArgLen int
+
+	// the foreign arrays of the app call, MaxAppTotalTxnReferences limits their total length with the box references
+	MaxAppTxnAccounts        int
+	MaxAppTxnForeignApps     int
+	MaxAppTxnForeignAssets   int
+	MaxAppTotalTxnReferences int
+
+	// MaxAppBoxReferences is 0 before the boxes
+	MaxAppBoxReferences  int
+	BytesPerBoxReference int
+	MaxBoxSize           int
+}
+
+// consensusVersions are the versions changing the program limits, the versions in between inherit the previous ones.
+var consensusVersions = func() []ConsensusParams {
+	var res []ConsensusParams
+
+	p := ConsensusParams{
+		Version:         18,
+		LogicSigVersion: 1,
+		LogicSigMaxSize: 1000,
+		LogicSigMaxCost: 20000,
+	}
+	res = append(res, p)
+
+	// applications
+	p.Version = 24
+	p.LogicSigVersion = 2
+	p.MaxAppProgramLen = 1024
+	p.MaxAppTotalProgramLen = 2048
+	p.MaxAppProgramCost = 700
+	p.MaxAppArgs = 16
+	p.MaxAppTotalArgLen = 2048
+	p.MaxAppTxnAccounts = 4
+	p.MaxAppTxnForeignApps = 2
+	p.MaxAppTxnForeignAssets = 2
+	p.MaxAppTotalTxnReferences = 8
+	res = append(res, p)
+
+	p.Version = 26
+	p.LogicSigVersion = 3
+	p.MaxAppTxnForeignApps = 8
+	p.MaxAppTxnForeignAssets = 8
+	res = append(res, p)
+
+	// extra program pages
+	p.Version = 28
+	p.LogicSigVersion = 4
+	p.MaxAppProgramLen = 2048
+	p.MaxAppTotalProgramLen = 2048
+	p.MaxExtraAppProgramPages = 3
+	res = append(res, p)
+
+	p.Version = 30
+	p.LogicSigVersion = 5
+	res = append(res, p)
+
+	p.Version = 31
+	p.LogicSigVersion = 6
+	res = append(res, p)
+
+	p.Version = 34
+	p.LogicSigVersion = 7
+	res = append(res, p)
+
+	// boxes
+	p.Version = 36
+	p.LogicSigVersion = 8
+	p.MaxAppBoxReferences = 8
+	p.BytesPerBoxReference = 1024
+	p.MaxBoxSize = 32768
+	res = append(res, p)
+
+	p.Version = 37
+	p.LogicSigVersion = 9
+	res = append(res, p)
+
+	p.Version = 38
+	p.LogicSigVersion = 10
+	res = append(res, p)
+
+	// dynamic round times, the latest known version
+	p.Version = 39
+	res = append(res, p)
+
+	return res
+}()
+
+// LatestConsensus returns the limits of the latest known consensus version.
+func LatestConsensus() ConsensusParams {
+	return consensusVersions[len(consensusVersions)-1]
+}
+
+// Consensus returns the limits of the consensus version, the versions without the program changes have the limits
+// of the previous one.
+func Consensus(version uint64) (ConsensusParams, error) {
+	if version < consensusVersions[0].Version {
+		return ConsensusParams{}, errors.Errorf("consensus version %d predates the programs, the first one is %d", version, consensusVersions[0].Version)
+	}
+
+	latest := LatestConsensus()
+	if version > latest.Version {
+		return ConsensusParams{}, errors.Errorf("consensus version %d is newer than the latest known %d", version, latest.Version)
+	}
+
+	var p ConsensusParams
+	for _, c := range consensusVersions {
+		if c.Version > version {
+			break
+		}
+		p = c
+	}
+
+	p.Version = version
+
+	return p, nil
+}
+
+// WithConsensus checks the program against the limits of the consensus version, e.g. its size and the indexes
+// of the foreign arrays.
+func WithConsensus(p ConsensusParams) ProcessOption {
+	return func(c *processConfig) {
+		c.consensus = &p
+	}
+}
+
+type ConsensusRule struct{}
+
+func (r ConsensusRule) Id() string {
+	return "LINT0030"
+}
+
+func (r ConsensusRule) Desc() string {
+	return "Checks the program against the limits of the selected consensus version"
+}
+
+var ConsensusRuleInstance = ConsensusRule{}
+
+// consensusArrayLimit returns the max index of the txna array the app call can pass in the consensus version.
+func consensusArrayLimit(p ConsensusParams, field TxnField) (int, string, bool) {
+	switch field {
+	case ApplicationArgs:
+		return p.MaxAppArgs - 1, fmt.Sprintf("MaxAppArgs %d", p.MaxAppArgs), true
+	case Accounts:
+		// the index 0 is the sender
+		return p.MaxAppTxnAccounts, fmt.Sprintf("MaxAppTxnAccounts %d", p.MaxAppTxnAccounts), true
+	case Applications:
+		// the index 0 is the called app
+		return p.MaxAppTxnForeignApps, fmt.Sprintf("MaxAppTxnForeignApps %d", p.MaxAppTxnForeignApps), true
+	case Assets:
+		return p.MaxAppTxnForeignAssets - 1, fmt.Sprintf("MaxAppTxnForeignAssets %d", p.MaxAppTxnForeignAssets), true
+	}
+
+	return 0, "", false
+}
+
+func checkConsensus(res *ProcessResult, p ConsensusParams) []Diagnostic {
+	var diags []Diagnostic
+
+	report := func(i int, msg string) {
+		l, b, e := 0, 0, 0
+		if i >= 0 && i < len(res.Sublines) {
+			sub := res.Sublines[i]
+			l, b, e = sub.Line, sub.Tokens.Begin(), codeEnd(sub)
+		}
+
+		diags = append(diags, lintError{
+			error: errors.New(msg),
+			l:     l,
+			b:     b,
+			e:     e,
+			s:     DiagErr,
+			r:     ConsensusRuleInstance.Id(),
+		})
+	}
+
+	pragma := -1
+	for i, op := range res.Listing {
+		if _, ok := op.(*PragmaExpr); ok {
+			pragma = i
+			break
+		}
+	}
+
+	if res.Version > p.LogicSigVersion {
+		report(pragma, fmt.Sprintf("version %d is not available in consensus v%d, the max is %d", res.Version, p.Version, p.LogicSigVersion))
+	}
+
+	size := EstimateSize(res)
+
+	if res.Mode == ModeSig {
+		if size > p.LogicSigMaxSize {
+			report(pragma, fmt.Sprintf("program size %d exceeds LogicSigMaxSize %d of consensus v%d", size, p.LogicSigMaxSize, p.Version))
+		}
+
+		if c := EstimateCost(res, p.LogicSigMaxCost); c.Exceeded {
+			report(pragma, fmt.Sprintf("a path of the program exceeds LogicSigMaxCost %d of consensus v%d", p.LogicSigMaxCost, p.Version))
+		}
+
+		return diags
+	}
+
+	if max := p.MaxAppProgramLen * (1 + p.MaxExtraAppProgramPages); size > max {
+		report(pragma, fmt.Sprintf("program size %d exceeds MaxAppProgramLen %d with %d extra pages (%d) of consensus v%d", size, p.MaxAppProgramLen, p.MaxExtraAppProgramPages, max, p.Version))
+	}
+
+	for i, op := range res.Listing {
+		var field TxnField
+		var index uint8
+
+		switch op := op.(type) {
+		case *TxnaExpr:
+			field, index = op.Field, op.Index
+		case *GtxnaExpr:
+			field, index = op.Field, op.Index
+		case *GtxnsaExpr:
+			field, index = op.Field, op.Index
+		default:
+			continue
+		}
+
+		max, limit, ok := consensusArrayLimit(p, field)
+		if !ok || int(index) <= max {
+			continue
+		}
+
+		report(i, fmt.Sprintf("%s %d is never available, %s of consensus v%d", field, index, limit, p.Version))
+	}
+
+	return diags
+}
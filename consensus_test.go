@@ -0,0 +1,125 @@
+package teal
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConsensusVersions(t *testing.T) {
+	tests := []struct {
+		version  uint64
+		avm      uint64
+		pages    int
+		assets   int
+		boxes    int
+		hasError bool
+	}{
+		{version: 17, hasError: true},
+		{version: 18, avm: 1},
+		{version: 24, avm: 2, assets: 2},
+		{version: 27, avm: 3, assets: 8},
+		{version: 28, avm: 4, pages: 3, assets: 8},
+		{version: 35, avm: 7, pages: 3, assets: 8},
+		{version: 36, avm: 8, pages: 3, assets: 8, boxes: 8},
+		{version: 37, avm: 9, pages: 3, assets: 8, boxes: 8},
+		{version: 38, avm: 10, pages: 3, assets: 8, boxes: 8},
+		{version: 39, avm: 10, pages: 3, assets: 8, boxes: 8},
+		{version: 40, hasError: true},
+	}
+
+	for _, test := range tests {
+		p, err := Consensus(test.version)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("expected an error for v%d", test.version)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error for v%d: %s", test.version, err)
+			continue
+		}
+
+		if p.Version != test.version || p.LogicSigVersion != test.avm || p.MaxExtraAppProgramPages != test.pages || p.MaxAppTxnForeignAssets != test.assets || p.MaxAppBoxReferences != test.boxes {
+			t.Errorf("unexpected params for v%d: %+v", test.version, p)
+		}
+	}
+
+	if LatestConsensus().LogicSigVersion != 10 {
+		t.Errorf("unexpected latest params: %+v", LatestConsensus())
+	}
+}
+
+func TestConsensusLimits(t *testing.T) {
+	big := "byte 0x" + strings.Repeat("00", 1000)
+	pages := "byte 0x" + strings.Repeat("01", 1000) + "\nbyte 0x" + strings.Repeat("02", 1000) + "\nbyte 0x" + strings.Repeat("03", 1000)
+
+	tests := []struct {
+		src      string
+		version  uint64
+		expected []string
+	}{
+		{src: "#pragma version 8\ntxna Accounts 4\ntxna Applications 8\ntxna Assets 7\ntxna ApplicationArgs 15\nreturn", version: 36},
+		{src: "#pragma version 8\ntxna Accounts 5\nbalance\nreturn", version: 36, expected: []string{"Accounts 5 is never available, MaxAppTxnAccounts 4 of consensus v36"}},
+		{src: "#pragma version 8\ngtxna 0 ApplicationArgs 16\nlen\nreturn", version: 36, expected: []string{"ApplicationArgs 16 is never available, MaxAppArgs 16 of consensus v36"}},
+		{src: "#pragma version 3\nint 0\ngtxnsa Assets 8\nreturn", version: 26, expected: []string{"Assets 8 is never available, MaxAppTxnForeignAssets 8 of consensus v26"}},
+		{src: "#pragma version 8\nint 1\nreturn", version: 31, expected: []string{"version 8 is not available in consensus v31, the max is 6"}},
+		{src: "#pragma version 8\n//#pragma mode logicsig\n" + big + "\nlen\nreturn", version: 36, expected: []string{"program size 1006 exceeds LogicSigMaxSize 1000 of consensus v36"}},
+		{src: "#pragma version 8\n//#pragma mode logicsig\nbyte 0x00\nlen\nreturn", version: 36},
+		{src: "#pragma version 3\n" + pages + "\npop\npop\nlen\nreturn", version: 26, expected: []string{"program size 3014 exceeds MaxAppProgramLen 1024 with 0 extra pages (1024) of consensus v26"}},
+		{src: "#pragma version 3\n" + pages + "\npop\npop\nlen\nreturn", version: 28},
+		{src: "#pragma version 10\nbyte \"box\"\nint 0\nint 1\nbyte 0x01\nbox_splice\nint 1\nreturn", version: 39},
+		{src: "#pragma version 10\nint 1\nreturn", version: 37, expected: []string{"version 10 is not available in consensus v37, the max is 9"}},
+	}
+
+	for _, test := range tests {
+		p, err := Consensus(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Process(test.src, WithConsensus(p))
+
+		var msgs []string
+		for _, d := range res.Diagnostics {
+			if d.Rule() == ConsensusRuleInstance.Id() {
+				if d.Severity() != DiagErr {
+					t.Errorf("unexpected severity: %s", d.Severity())
+				}
+				msgs = append(msgs, d.String())
+			}
+		}
+
+		if len(msgs) != len(test.expected) {
+			t.Errorf("expected %v, got: %v, source:\n%.200s", test.expected, msgs, test.src)
+			continue
+		}
+
+		for i, m := range msgs {
+			if m != test.expected[i] {
+				t.Errorf("expected: %s, got: %s", test.expected[i], m)
+			}
+		}
+	}
+
+	// the AVM 9 programs are available in the latest consensus
+	bs, err := os.ReadFile("examples/ok/ec.teal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range Process(string(bs), WithConsensus(LatestConsensus())).Diagnostics {
+		if d.Rule() == ConsensusRuleInstance.Id() {
+			t.Errorf("unexpected diagnostic of ec.teal in the latest consensus: %s", d)
+		}
+	}
+
+	// the limits are checked only with the option
+	for _, d := range Process("#pragma version 8\ntxna Accounts 5\nbalance\nreturn").Diagnostics {
+		if d.Rule() == ConsensusRuleInstance.Id() {
+			t.Errorf("unexpected diagnostic without the consensus: %s", d)
+		}
+	}
+}
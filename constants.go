@@ -0,0 +1,101 @@
+package teal
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseConstants parses the JSON object of the named constants, e.g. {"FEE": 1000, "PREFIX": "0x01"}.
+// The string values are the literals written in place of the names, e.g. an address for addr TREASURY, the numbers are the uint64 values.
+func ParseConstants(bs []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+
+	err := json.Unmarshal(bs, &raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse constants")
+	}
+
+	res := make(map[string]string, len(raw))
+
+	for name, v := range raw {
+		if name == "" || strings.ContainsAny(name, " \t;:") {
+			return nil, errors.Errorf("invalid constant name: '%s'", name)
+		}
+
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			res[name] = s
+			continue
+		}
+
+		var n json.Number
+		if json.Unmarshal(v, &n) == nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				return nil, errors.Errorf("constant '%s' is not a uint64: %s", name, n)
+			}
+
+			res[name] = n.String()
+			continue
+		}
+
+		return nil, errors.Errorf("constant '%s' must be a string or a number", name)
+	}
+
+	return res, nil
+}
+
+// ReadConstants reads the JSON file of the named constants.
+func ReadConstants(path string) (map[string]string, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read constants")
+	}
+
+	return ParseConstants(bs)
+}
+
+// WithConstants substitutes the values for the op args of the constant names, e.g. int FEE with the FEE of 1000 is read as int 1000.
+// The later values take precedence.
+func WithConstants(cs map[string]string) ProcessOption {
+	return func(c *processConfig) {
+		if c.constants == nil {
+			c.constants = map[string]string{}
+		}
+		for name, v := range cs {
+			c.constants[name] = v
+		}
+	}
+}
+
+// substituteConstants returns the tokens with the args of the constant names replaced by the values,
+// the positions are kept so the diagnostics point at the names.
+func substituteConstants(ts []Token, cs map[string]string) []Token {
+	if len(cs) == 0 {
+		return ts
+	}
+
+	var res []Token
+
+	for i := 1; i < len(ts); i++ {
+		v, ok := cs[ts[i].v]
+		if !ok || ts[i].t != TokenValue {
+			continue
+		}
+
+		if res == nil {
+			res = append([]Token{}, ts...)
+		}
+
+		res[i].v = v
+	}
+
+	if res == nil {
+		return ts
+	}
+
+	return res
+}
@@ -0,0 +1,52 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestWithConstants(t *testing.T) {
+	cs, err := ParseConstants([]byte(`{"FEE": 1000, "NAME": "\"hello\"", "TREASURY": "7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Process("#pragma version 8\nint FEE\npop\nbyte NAME\npop\naddr TREASURY\npop\nint 1", WithConstants(cs))
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	if v, ok := constUint(res.Listing[1]); !ok || v != 1000 {
+		t.Errorf("unexpected int: %s", res.Listing[1])
+	}
+
+	if v, ok := constBytes(res.Listing[3]); !ok || string(v) != "hello" {
+		t.Errorf("unexpected byte: %s", res.Listing[3])
+	}
+
+	if res.Lines[1][1].String() != "FEE" {
+		t.Errorf("expected the source tokens kept, got: %s", res.Lines[1][1])
+	}
+
+	res = Process("#pragma version 8\nint FEE")
+	if len(res.Diagnostics) == 0 {
+		t.Error("expected the unknown name diagnostic without the constants")
+	}
+
+	for _, s := range []string{`{"A": -1}`, `{"A": true}`, `{"A B": 1}`} {
+		if _, err := ParseConstants([]byte(s)); err == nil {
+			t.Errorf("expected an error: %s", s)
+		}
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	res := Process("int 1\nint 2\nbox_len", WithVersion(8))
+	if res.Version != 8 || len(res.Diagnostics) != 0 {
+		t.Errorf("unexpected version %d, diagnostics: %v", res.Version, res.Diagnostics)
+	}
+
+	res = Process("#pragma version 6\nint 1", WithVersion(8))
+	if res.Version != 6 {
+		t.Errorf("expected the pragma version, got: %d", res.Version)
+	}
+}
@@ -0,0 +1,183 @@
+package teal
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// isConstIntExpr reports whether the int operand is an arithmetic expression, e.g. 1000000*10, rather than a single value.
+// The negative values are left to the literal parsing to report them as out of range.
+func isConstIntExpr(s string) bool {
+	return !strings.HasPrefix(s, "-") && strings.ContainsAny(s, "+-*/%()")
+}
+
+// constIntExpr evaluates the int operand expressions of the +, -, *, / and % operators with parentheses.
+// The operands are the uint64 literals and the TypeEnum and OnCompletion names.
+type constIntExpr struct {
+	s string
+	i int
+}
+
+func evalConstInt(s string) (uint64, error) {
+	e := &constIntExpr{s: s}
+
+	v, err := e.sum()
+	if err != nil {
+		return 0, err
+	}
+
+	if e.i < len(e.s) {
+		return 0, errors.Errorf("unexpected %q at %d in expression: %s", e.s[e.i], e.i, s)
+	}
+
+	return v, nil
+}
+
+func (e *constIntExpr) peek() byte {
+	if e.i < len(e.s) {
+		return e.s[e.i]
+	}
+
+	return 0
+}
+
+func (e *constIntExpr) sum() (uint64, error) {
+	v, err := e.product()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := e.peek()
+		if op != '+' && op != '-' {
+			return v, nil
+		}
+		e.i++
+
+		r, err := e.product()
+		if err != nil {
+			return 0, err
+		}
+
+		var c uint64
+		if op == '+' {
+			v, c = bits.Add64(v, r, 0)
+			if c != 0 {
+				return 0, errors.Errorf("expression overflows uint64: %s", e.s)
+			}
+		} else {
+			v, c = bits.Sub64(v, r, 0)
+			if c != 0 {
+				return 0, errors.Errorf("expression underflows uint64: %s", e.s)
+			}
+		}
+	}
+}
+
+func (e *constIntExpr) product() (uint64, error) {
+	v, err := e.factor()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := e.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return v, nil
+		}
+		e.i++
+
+		r, err := e.factor()
+		if err != nil {
+			return 0, err
+		}
+
+		switch op {
+		case '*':
+			hi, lo := bits.Mul64(v, r)
+			if hi != 0 {
+				return 0, errors.Errorf("expression overflows uint64: %s", e.s)
+			}
+			v = lo
+		default:
+			if r == 0 {
+				return 0, errors.Errorf("division by zero in expression: %s", e.s)
+			}
+			if op == '/' {
+				v /= r
+			} else {
+				v %= r
+			}
+		}
+	}
+}
+
+func (e *constIntExpr) factor() (uint64, error) {
+	if e.peek() == '(' {
+		e.i++
+
+		v, err := e.sum()
+		if err != nil {
+			return 0, err
+		}
+
+		if e.peek() != ')' {
+			return 0, errors.Errorf("missing ) in expression: %s", e.s)
+		}
+		e.i++
+
+		return v, nil
+	}
+
+	start := e.i
+	for e.i < len(e.s) && !strings.ContainsRune("+-*/%()", rune(e.s[e.i])) {
+		e.i++
+	}
+
+	name := e.s[start:e.i]
+	if name == "" {
+		return 0, errors.Errorf("missing operand at %d in expression: %s", start, e.s)
+	}
+
+	if v, ok := txnTypeMap[name]; ok {
+		return v, nil
+	}
+
+	if v, ok := onCompletionMap[name]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.ParseUint(name, 0, 64)
+	if err != nil {
+		return 0, rangeError(name, err, "uint64", 0, math.MaxUint64)
+	}
+
+	return v, nil
+}
+
+// constIntDoc returns the value of the int operand given by an expression or a name.
+func constIntDoc(s string) string {
+	if isConstIntExpr(s) {
+		v, err := evalConstInt(s)
+		if err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s = %d", s, v)
+	}
+
+	if v, ok := txnTypeMap[s]; ok {
+		return fmt.Sprintf("%s = %d", s, v)
+	}
+
+	if v, ok := onCompletionMap[s]; ok {
+		return fmt.Sprintf("%s = %d", s, v)
+	}
+
+	return ""
+}
@@ -0,0 +1,80 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestEvalConstInt(t *testing.T) {
+	type test struct {
+		s   string
+		v   uint64
+		err bool
+	}
+
+	tests := []test{
+		{s: "1000000*10", v: 10000000},
+		{s: "1+2*3", v: 7},
+		{s: "(1+2)*3", v: 9},
+		{s: "10-2-3", v: 5},
+		{s: "7/2", v: 3},
+		{s: "7%4", v: 3},
+		{s: "0x10*2", v: 32},
+		{s: "axfer*10", v: 40},
+		{s: "DeleteApplication+1", v: 6},
+		{s: "1-2", err: true},
+		{s: "18446744073709551615+1", err: true},
+		{s: "4294967296*4294967296", err: true},
+		{s: "1/0", err: true},
+		{s: "(1+2", err: true},
+		{s: "1+", err: true},
+		{s: "1+x", err: true},
+		{s: "1)", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			v, err := evalConstInt(test.s)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error, got: %d", v)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v != test.v {
+				t.Errorf("expected: %d, got: %d", test.v, v)
+			}
+		})
+	}
+}
+
+func TestIntExpr(t *testing.T) {
+	res := Process("#pragma version 8\nint 1000000*10\nint pay\n+\nreturn\n")
+	for _, d := range res.Diagnostics {
+		if d.Severity() == DiagErr {
+			t.Fatalf("unexpected error: %s", d)
+		}
+	}
+
+	e, ok := res.Listing[1].(*IntExpr)
+	if !ok || e.Value != 10000000 {
+		t.Fatalf("unexpected op: %s", res.Listing[1])
+	}
+
+	if doc := res.DocAt(1, 6); doc != "1000000*10 = 10000000" {
+		t.Errorf("unexpected doc: %s", doc)
+	}
+
+	if doc := res.DocAt(2, 5); doc != "pay = 1" {
+		t.Errorf("unexpected doc: %s", doc)
+	}
+
+	res = Process("#pragma version 8\nint 1000000*10\nreturn\n", WithGoalCompat())
+	if len(res.Diagnostics) == 0 || res.Diagnostics[0].Line() != 1 {
+		t.Errorf("expected the expression to be rejected in goal mode: %v", res.Diagnostics)
+	}
+}
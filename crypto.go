@@ -0,0 +1,111 @@
+package teal
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// vmKnownBytes returns the bytes of the values when all of them are known byte constants.
+func vmKnownBytes(vs ...VmValue) ([][]byte, bool) {
+	res := make([][]byte, len(vs))
+
+	for i, v := range vs {
+		bs, ok := v.Bytes()
+		if !ok {
+			return nil, false
+		}
+
+		res[i] = bs
+	}
+
+	return res, true
+}
+
+func sha256Sum(bs []byte) []byte {
+	h := sha256.Sum256(bs)
+	return h[:]
+}
+
+func sha512_256Sum(bs []byte) []byte {
+	h := sha512.Sum512_256(bs)
+	return h[:]
+}
+
+func sha3_256Sum(bs []byte) []byte {
+	h := sha3.Sum256(bs)
+	return h[:]
+}
+
+func keccak256Sum(bs []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(bs)
+	return h.Sum(nil)
+}
+
+// vmHash pushes the hash of the value when the value is known, the unknown value otherwise.
+func vmHash(b *VmBranch, v VmValue, f func([]byte) []byte, unknown VmValue) {
+	if bs, ok := v.Bytes(); ok {
+		b.push(NewVmBytes(f(bs)))
+		return
+	}
+
+	b.push(unknown)
+}
+
+// ed25519VerifyBare verifies the signature of the data, ok is false for malformed signatures and keys that fail the program.
+func ed25519VerifyBare(data []byte, sig []byte, pk []byte) (valid bool, ok bool) {
+	if len(sig) != ed25519.SignatureSize || len(pk) != ed25519.PublicKeySize {
+		return false, false
+	}
+
+	return ed25519.Verify(ed25519.PublicKey(pk), data, sig), true
+}
+
+// ecdsaVerify verifies the signature (r, s) of the 32 bytes hash against the public key (x, y) on the curve.
+func ecdsaVerify(curve EcdsaCurve, hash []byte, r []byte, s []byte, x []byte, y []byte) bool {
+	if len(hash) != 32 || len(x) != 32 || len(y) != 32 || len(r) > 32 || len(s) > 32 {
+		return false
+	}
+
+	switch curve {
+	case Secp256k1:
+		pk, err := secp256k1.ParsePubKey(append(append([]byte{0x04}, x...), y...))
+		if err != nil {
+			return false
+		}
+
+		var rs, ss secp256k1.ModNScalar
+		if rs.SetByteSlice(r) || ss.SetByteSlice(s) {
+			return false
+		}
+
+		// the signatures must be normalized to the lower s values
+		if ss.IsOverHalfOrder() {
+			return false
+		}
+
+		return secp256k1ecdsa.NewSignature(&rs, &ss).Verify(hash, pk)
+	case Secp256r1:
+		pk := ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !pk.Curve.IsOnCurve(pk.X, pk.Y) {
+			return false
+		}
+
+		return ecdsa.Verify(&pk, hash, new(big.Int).SetBytes(r), new(big.Int).SetBytes(s))
+	default:
+		return false
+	}
+}
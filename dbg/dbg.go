@@ -2,6 +2,7 @@ package dbg
 
 import (
 	"bufio"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,8 +10,11 @@ import (
 	"net/textproto"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/abi"
+	"github.com/dragmz/teal/bridge"
 	"github.com/pkg/errors"
 )
 
@@ -45,6 +49,18 @@ type dbgVm struct {
 	tvm  *teal.Vm
 	name string
 	path string
+
+	method *abi.Method
+	events []abi.Event
+
+	frames  map[string]teal.FrameNames
+	scratch map[int]string
+
+	// publish writes the state next to the program on each stop
+	publish bool
+
+	// bridge serves the state to tealsp
+	bridge *bridge.Server
 }
 
 type dbgBreakpoint struct {
@@ -102,7 +118,12 @@ type dapInitializeRequestParams struct {
 }
 
 type dapLaunchRequestParams struct {
-	Program string `json:"program"`
+	Program string   `json:"program"`
+	Method  string   `json:"method,omitempty"`
+	Events  []string `json:"events,omitempty"`
+
+	// PublishState writes the state file read by tealsp to show the inline values
+	PublishState bool `json:"publishState,omitempty"`
 }
 
 type dapStackTraceRequestParams struct {
@@ -136,6 +157,25 @@ type dapVariablesRequestParams struct {
 	Count              *int `json:"count,omitempty"`
 }
 
+type dapDataBreakpointInfoRequestParams struct {
+	VariablesReference *int   `json:"variablesReference,omitempty"`
+	Name               string `json:"name"`
+}
+
+type dapDataBreakpointInfoResponse struct {
+	DataId      *string  `json:"dataId"`
+	Description string   `json:"description"`
+	AccessTypes []string `json:"accessTypes,omitempty"`
+}
+
+type dapDataBreakpoint struct {
+	DataId string `json:"dataId"`
+}
+
+type dapSetDataBreakpointsRequestParams struct {
+	Breakpoints []dapDataBreakpoint `json:"breakpoints"`
+}
+
 type dapVariablesResponse struct {
 	Variables []dapVariable `json:"variables"`
 }
@@ -167,6 +207,18 @@ type dapContinueRequestParams struct {
 	ThreadId int `json:"threadId"`
 }
 
+type dapEvaluateRequestParams struct {
+	Expression string `json:"expression"`
+	FrameId    *int   `json:"frameId,omitempty"`
+	Context    string `json:"context,omitempty"`
+}
+
+type dapEvaluateResponse struct {
+	Result             string `json:"result"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
 type dapInitializeRequest dapRequest[*dapInitializeRequestParams]
 type dapSetBreakpointsRequest dapRequest[*dapSetBreakpointsRequestParams]
 type dapLaunchRequest dapRequest[*dapLaunchRequestParams]
@@ -175,6 +227,9 @@ type dapScopesRequest dapRequest[*dapScopesRequestParams]
 type dapVariablesRequest dapRequest[*dapVariablesRequestParams]
 type dapNextRequest dapRequest[*dapNextRequestParams]
 type dapContinueRequest dapRequest[*dapContinueRequestParams]
+type dapDataBreakpointInfoRequest dapRequest[*dapDataBreakpointInfoRequestParams]
+type dapSetDataBreakpointsRequest dapRequest[*dapSetDataBreakpointsRequestParams]
+type dapEvaluateRequest dapRequest[*dapEvaluateRequestParams]
 
 type dapCapabilities struct {
 	SupportsInstructionBreakpoints    *bool `json:"supportsInstructionBreakpoints,omitempty"`
@@ -182,6 +237,9 @@ type dapCapabilities struct {
 	SupportsFunctionBreakpoints       *bool `json:"supportsFunctionBreakpoints,omitempty"`
 	SupportsModulesRequest            *bool `json:"supportsModulesRequest,omitempty"`
 	SupportsConfigurationDoneRequest  *bool `json:"supportsConfigurationDoneRequest,omitempty"`
+	SupportsDataBreakpoints           *bool `json:"supportsDataBreakpoints,omitempty"`
+	SupportsStepBack                  *bool `json:"supportsStepBack,omitempty"`
+	SupportsEvaluateForHovers         *bool `json:"supportsEvaluateForHovers,omitempty"`
 }
 
 type dapResponse struct {
@@ -302,6 +360,25 @@ func (l *dbg) notify(event string, params interface{}) error {
 	})
 }
 
+// stopped publishes the debug state for the editor inline values and notifies about the stop.
+func (l *dbg) stopped(params dapStoppedEventParams) error {
+	if l.vm != nil {
+		st := teal.NewDebugState(l.vm.tvm, l.vm.path)
+
+		if l.vm.bridge != nil {
+			l.vm.bridge.Publish(st)
+		}
+
+		if l.vm.publish {
+			if err := teal.WriteDebugState(st); err != nil {
+				l.trace(fmt.Sprintf("failed to publish debug state: %s", err))
+			}
+		}
+	}
+
+	return l.notify("stopped", params)
+}
+
 func (l *dbg) handle(h dapHeader, b []byte) error {
 	switch h.Type {
 	case "request":
@@ -327,6 +404,9 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 
 			err = l.reply(h.Seq, req.Command, "", dapCapabilities{
 				SupportsConfigurationDoneRequest: yes,
+				SupportsDataBreakpoints:          yes,
+				SupportsStepBack:                 yes,
+				SupportsEvaluateForHovers:        yes,
 			}, nil)
 			if err != nil {
 				return err
@@ -349,12 +429,38 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 
 			src := string(bs)
 
-			res := teal.Process(src)
+			res := teal.Process(src, teal.WithPath(lreq.Arguments.Program))
 
 			l.vm = &dbgVm{
 				tvm:  teal.NewVm(res),
 				name: lreq.Arguments.Program,
 				path: lreq.Arguments.Program,
+
+				frames:  res.FrameNames(),
+				scratch: res.ScratchNames(),
+
+				publish: lreq.Arguments.PublishState,
+			}
+
+			l.vm.bridge, err = bridge.Listen(l.vm.path)
+			if err != nil {
+				l.trace(fmt.Sprintf("failed to start bridge: %s", err))
+			}
+
+			if lreq.Arguments.Method != "" {
+				m, err := abi.ParseMethod(lreq.Arguments.Method)
+				if err != nil {
+					return errors.Wrap(err, "failed to parse launch method")
+				}
+				l.vm.method = &m
+			}
+
+			for _, sig := range lreq.Arguments.Events {
+				e, err := abi.ParseEvent(sig)
+				if err != nil {
+					return errors.Wrap(err, "failed to parse launch event")
+				}
+				l.vm.events = append(l.vm.events, e)
 			}
 
 			err = l.reply(h.Seq, req.Command, "", nil, nil)
@@ -376,16 +482,37 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 				*tid = l.vm.tvm.Branch.Id
 			}
 
-			return l.notify("stopped", dapStoppedEventParams{
+			return l.stopped(dapStoppedEventParams{
 				Reason:            "entry",
 				AllThreadsStopped: yes,
 				ThreadId:          tid,
 			})
 
 		case "disconnect":
+			if l.vm != nil {
+				if l.vm.bridge != nil {
+					l.vm.bridge.Close()
+				}
+				if l.vm.publish {
+					os.Remove(teal.DebugStatePath(l.vm.path))
+				}
+			}
 			return l.reply(h.Seq, req.Command, "", nil, nil)
 		case "evaluate":
-			return l.reply(h.Seq, req.Command, "", nil, nil)
+			ereq, err := read[dapEvaluateRequest](b)
+			if err != nil {
+				return err
+			}
+
+			v, err := l.evaluate(ereq.Arguments.Expression, ereq.Arguments.FrameId)
+			if err != nil {
+				return l.reply(h.Seq, req.Command, err.Error(), nil, err)
+			}
+
+			return l.reply(h.Seq, req.Command, "", dapEvaluateResponse{
+				Result: formatValue(v),
+				Type:   v.T.String(),
+			}, nil)
 		case "setFunctionBreakpoints":
 			return l.reply(h.Seq, req.Command, "", nil, nil)
 		case "setBreakpoints":
@@ -439,6 +566,63 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 
 			return nil
 
+		case "dataBreakpointInfo":
+			dreq, err := read[dapDataBreakpointInfoRequest](b)
+			if err != nil {
+				return err
+			}
+
+			var res dapDataBreakpointInfoResponse
+
+			id, ok := dataBreakpointId(dreq.Arguments.VariablesReference, dreq.Arguments.Name)
+			if ok {
+				w, err := parseWatchpoint(id)
+				if err != nil {
+					res.Description = err.Error()
+				} else {
+					res.DataId = &id
+					res.Description = w.String()
+					res.AccessTypes = []string{"write"}
+				}
+			} else {
+				res.Description = "data breakpoints are supported on scratch slots, stack items and state keys, e.g. global:counter"
+			}
+
+			return l.reply(h.Seq, req.Command, "", res, nil)
+
+		case "setDataBreakpoints":
+			dreq, err := read[dapSetDataBreakpointsRequest](b)
+			if err != nil {
+				return err
+			}
+
+			bs := []dapBreakpoint{}
+			ws := []teal.VmWatchpoint{}
+
+			for _, db := range dreq.Arguments.Breakpoints {
+				w, err := parseWatchpoint(db.DataId)
+				if err != nil {
+					bs = append(bs, dapBreakpoint{
+						Verified: false,
+						Message:  err.Error(),
+					})
+					continue
+				}
+
+				ws = append(ws, w)
+				bs = append(bs, dapBreakpoint{
+					Verified: true,
+				})
+			}
+
+			if l.vm != nil {
+				l.vm.tvm.SetWatchpoints(ws)
+			}
+
+			return l.reply(h.Seq, req.Command, "", dapSetBreakpointsResponse{
+				Breakpoints: bs,
+			}, nil)
+
 		case "setInstructionBreakpoints":
 			return l.reply(h.Seq, req.Command, "", nil, nil)
 		case "setExceptionBreakpoints":
@@ -469,7 +653,7 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 				*tid = l.vm.tvm.Branch.Id
 			}
 
-			return l.notify("stopped", dapStoppedEventParams{
+			return l.stopped(dapStoppedEventParams{
 				Reason:            "pause",
 				AllThreadsStopped: yes,
 				ThreadId:          tid,
@@ -491,13 +675,33 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 				l.vm.tvm.Run()
 
 				if l.vm.tvm.Error != nil {
-					return l.notify("stopped", dapStoppedEventParams{
+					return l.stopped(dapStoppedEventParams{
 						Reason:            "exception",
 						AllThreadsStopped: yes,
 						Description:       fmt.Sprintf("Error: %s", l.vm.tvm.Error),
 					})
 				}
 
+				if len(l.vm.tvm.Watched) > 0 {
+					var tid int
+					var ds []string
+
+					for id, ws := range l.vm.tvm.Watched {
+						tid = id
+						for _, i := range ws {
+							ds = append(ds, l.vm.tvm.Watchpoints[i].String())
+						}
+						break
+					}
+
+					return l.stopped(dapStoppedEventParams{
+						Reason:            "data breakpoint",
+						Description:       strings.Join(ds, ", "),
+						AllThreadsStopped: yes,
+						ThreadId:          &tid,
+					})
+				}
+
 				if len(l.vm.tvm.Triggered) > 0 {
 					var tid int
 					ids := l.vm.tvm.Triggered
@@ -510,20 +714,55 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 						}
 					}
 
-					return l.notify("stopped", dapStoppedEventParams{
+					return l.stopped(dapStoppedEventParams{
 						Reason:            "breakpoint",
 						AllThreadsStopped: yes,
 						ThreadId:          &tid,
 						HitBreakpointIds:  ids[tid],
 					})
 				} else if l.vm.tvm.Branch == nil {
-					return l.notify("stopped", dapStoppedEventParams{
+					return l.stopped(dapStoppedEventParams{
 						Reason:            "breakpoint",
 						AllThreadsStopped: yes,
 					})
 				}
 			}
 
+		case "stepBack", "reverseContinue":
+			err := l.reply(h.Seq, req.Command, "", nil, nil)
+			if err != nil {
+				return err
+			}
+
+			if l.vm == nil {
+				return nil
+			}
+
+			reason := "step"
+
+			if req.Command == "stepBack" {
+				l.vm.tvm.StepBack()
+			} else {
+				l.vm.tvm.ReverseContinue()
+
+				reason = "breakpoint"
+				if len(l.vm.tvm.History) == 0 {
+					reason = "entry"
+				}
+			}
+
+			var tid *int
+			if l.vm.tvm.Branch != nil {
+				tid = new(int)
+				*tid = l.vm.tvm.Branch.Id
+			}
+
+			return l.stopped(dapStoppedEventParams{
+				Reason:            reason,
+				AllThreadsStopped: yes,
+				ThreadId:          tid,
+			})
+
 		case "configurationDone":
 			return l.reply(h.Seq, req.Command, "", nil, nil)
 		case "next":
@@ -542,7 +781,7 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 				l.vm.tvm.Step()
 
 				if l.vm.tvm.Error != nil {
-					return l.notify("stopped", dapStoppedEventParams{
+					return l.stopped(dapStoppedEventParams{
 						Reason:            "exception",
 						AllThreadsStopped: yes,
 						Description:       fmt.Sprintf("Error: %s", l.vm.tvm.Error),
@@ -556,7 +795,7 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 				*tid = l.vm.tvm.Branch.Id
 			}
 
-			return l.notify("stopped", dapStoppedEventParams{
+			return l.stopped(dapStoppedEventParams{
 				Reason:            "step",
 				AllThreadsStopped: yes,
 				ThreadId:          tid,
@@ -588,7 +827,7 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 								for i := len(b.Stack.Items) - 1; i >= 0; i-- {
 									vs = append(vs, dapVariable{
 										Name:  strconv.Itoa(i),
-										Value: b.Stack.Items[i].String(),
+										Value: formatValue(b.Stack.Items[i]),
 									})
 								}
 							case 2:
@@ -606,9 +845,14 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 								for i := s; i < e; i++ {
 									v := l.vm.tvm.Scratch.Items[i]
 									if v.T != teal.VmTypeNone {
+										name := strconv.Itoa(i)
+										if alias, ok := l.vm.scratch[i]; ok {
+											name = fmt.Sprintf("%d (%s)", i, alias)
+										}
+
 										vs = append(vs, dapVariable{
-											Name:  strconv.Itoa(i),
-											Value: v.String(),
+											Name:  name,
+											Value: formatValue(v),
 										})
 									}
 								}
@@ -631,6 +875,15 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 										Value: v.String(),
 									})
 								}
+							case 4:
+								for i, v := range b.Logs {
+									vs = append(vs, dapVariable{
+										Name:  strconv.Itoa(i),
+										Value: l.vm.formatLog(v),
+									})
+								}
+							case 5:
+								vs = append(vs, l.vm.frameVariables(b)...)
 							}
 						}
 					}
@@ -676,6 +929,20 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 							VariablesReference: 4 + 10*b.Id,
 							IndexedVariables:   &tracelen,
 						})
+
+						logslen := len(b.Logs)
+						ss = append(ss, dapScope{
+							Name:               "Logs",
+							VariablesReference: 5 + 10*b.Id,
+							IndexedVariables:   &logslen,
+						})
+
+						if len(b.Frames) > 0 {
+							ss = append(ss, dapScope{
+								Name:               "Frame",
+								VariablesReference: 6 + 10*b.Id,
+							})
+						}
 					}
 				}
 			}
@@ -695,6 +962,8 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 			if l.vm != nil {
 				for _, b := range l.vm.tvm.Branches {
 					if b.Id == sreq.Arguments.ThreadId {
+						res := l.vm.tvm.Process
+
 						line := b.Line
 						name := b.Name
 						for i := len(b.Frames) - 1; i >= 0; i-- {
@@ -702,8 +971,8 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 							sf = append(sf, dapStackFrame{
 								Id:     b.Id,
 								Name:   name,
-								Line:   line + l.lz,
-								Column: l.cz,
+								Line:   res.SourceLine(line) + l.lz,
+								Column: l.column(line),
 								Source: &dapSource{
 									Name: l.vm.name,
 									Path: l.vm.path,
@@ -716,8 +985,8 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 						sf = append(sf, dapStackFrame{
 							Id:     b.Id,
 							Name:   name,
-							Line:   line + l.lz,
-							Column: l.cz,
+							Line:   res.SourceLine(line) + l.lz,
+							Column: l.column(line),
 							Source: &dapSource{
 								Name: l.vm.name,
 								Path: l.vm.path,
@@ -739,6 +1008,169 @@ func (l *dbg) handle(h dapHeader, b []byte) error {
 	return nil
 }
 
+var abiString = abi.Type{Kind: abi.KindString}
+
+// formatValue appends the ARC-4 string interpretation of byte constants that are length-prefixed.
+func formatValue(v teal.VmValue) string {
+	res := v.String()
+
+	bs, ok := v.Bytes()
+	if !ok {
+		return res
+	}
+
+	s, err := abi.Decode(abiString, bs)
+	if err != nil {
+		return res
+	}
+
+	return fmt.Sprintf("%s (abi: %s)", res, abi.Format(abiString, s))
+}
+
+// evaluate evaluates the watch or hover expression against the branch of the frame, the current branch if not set.
+func (l *dbg) evaluate(expr string, frame *int) (teal.VmValue, error) {
+	if l.vm == nil {
+		return teal.VmValue{}, errors.New("no program is running")
+	}
+
+	b := l.vm.tvm.Branch
+	if frame != nil {
+		for _, fb := range l.vm.tvm.Branches {
+			if fb.Id == *frame {
+				b = fb
+			}
+		}
+	}
+
+	if b == nil {
+		return teal.VmValue{}, errors.New("no branch is paused")
+	}
+
+	return b.Evaluate(expr)
+}
+
+// column returns the client column of the listing item, pointing at the instruction on lines with multiple ones.
+func (l *dbg) column(i int) int {
+	res := l.vm.tvm.Process
+	if i >= 0 && i < len(res.Sublines) {
+		return res.Sublines[i].Tokens.Begin() + l.cz
+	}
+
+	return l.cz
+}
+
+// formatLog decodes constant log values using the launch method and events.
+// parseWatchpoint parses data breakpoint ids: scratch:N, stack:N, global:key, local:key or box:key.
+// Keys starting with 0x are hex encoded.
+func parseWatchpoint(id string) (teal.VmWatchpoint, error) {
+	kind, arg, ok := strings.Cut(id, ":")
+	if !ok {
+		return teal.VmWatchpoint{}, errors.Errorf("invalid data breakpoint id: %s", id)
+	}
+
+	switch kind {
+	case "scratch":
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 0 || i > 255 {
+			return teal.VmWatchpoint{}, errors.Errorf("invalid scratch slot: %s", arg)
+		}
+
+		return teal.VmWatchpoint{Kind: teal.VmWatchScratch, Slot: i}, nil
+	case "stack":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return teal.VmWatchpoint{}, errors.Errorf("invalid stack depth: %s", arg)
+		}
+
+		return teal.VmWatchpoint{Kind: teal.VmWatchStack, Depth: n}, nil
+	}
+
+	var scope teal.VmAccessScope
+
+	switch kind {
+	case "global":
+		scope = teal.VmAccessGlobal
+	case "local":
+		scope = teal.VmAccessLocal
+	case "box":
+		scope = teal.VmAccessBox
+	default:
+		return teal.VmWatchpoint{}, errors.Errorf("unknown data breakpoint kind: %s", kind)
+	}
+
+	key := []byte(arg)
+	if strings.HasPrefix(arg, "0x") {
+		bs, err := hex.DecodeString(arg[2:])
+		if err != nil {
+			return teal.VmWatchpoint{}, errors.Wrap(err, "failed to decode key")
+		}
+		key = bs
+	}
+
+	return teal.VmWatchpoint{Kind: teal.VmWatchState, Scope: scope, Key: key}, nil
+}
+
+// dataBreakpointId returns the data breakpoint id for the variable or the name itself if it is not a variable.
+func dataBreakpointId(ref *int, name string) (string, bool) {
+	if ref == nil || *ref <= 0 {
+		return name, name != ""
+	}
+
+	slot, _, _ := strings.Cut(name, " ")
+
+	switch (*ref - 1) % 10 {
+	case 1:
+		i, err := strconv.Atoi(slot)
+		if err != nil {
+			return "", false
+		}
+
+		return fmt.Sprintf("stack:%d", i+1), true
+	case 2:
+		return "scratch:" + slot, true
+	default:
+		return "", false
+	}
+}
+
+// frameVariables lists the current frame arguments and locals named after the subroutine proto comment.
+func (v *dbgVm) frameVariables(b *teal.VmBranch) []dapVariable {
+	var vs []dapVariable
+
+	f := b.Frames[len(b.Frames)-1]
+	names := v.frames[f.Sub]
+	base := f.Base()
+
+	for i := -int(f.NumArgs); i < 0; i++ {
+		if base+i < 0 {
+			continue
+		}
+
+		vs = append(vs, dapVariable{
+			Name:  names.Arg(i),
+			Value: formatValue(b.Stack.Items[base+i]),
+		})
+	}
+
+	for i := base; i < len(b.Stack.Items); i++ {
+		vs = append(vs, dapVariable{
+			Name:  names.Local(i - base),
+			Value: formatValue(b.Stack.Items[i]),
+		})
+	}
+
+	return vs
+}
+
+func (v *dbgVm) formatLog(val teal.VmValue) string {
+	bs, ok := val.Bytes()
+	if !ok {
+		return val.String()
+	}
+
+	return abi.DecodeLog(bs, v.method, v.events).String()
+}
+
 func (l *dbg) write(v interface{}) error {
 	rb, err := json.Marshal(v)
 	if err != nil {
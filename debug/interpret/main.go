@@ -18,12 +18,11 @@ func run(a args) error {
 		return errors.Wrap(err, "failed to read source file")
 	}
 
-	res := teal.Process(string(bs))
+	res := teal.Process(string(bs), teal.WithPath(a.Path))
 
 	vm := teal.NewVm(res)
 
-	vm.Run()
-	return nil
+	return errors.Wrap(vm.Run(), "failed to run the program")
 }
 
 func main() {
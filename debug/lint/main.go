@@ -10,8 +10,12 @@ import (
 
 func main() {
 	var path string
+	var strict bool
+	var permissive bool
 
 	flag.StringVar(&path, "path", "", "path to teal file")
+	flag.BoolVar(&strict, "strict", false, "report selected warnings as errors")
+	flag.BoolVar(&permissive, "permissive", false, "silence style rules")
 	flag.Parse()
 
 	s, err := os.ReadFile(path)
@@ -19,7 +23,15 @@ func main() {
 		panic(err)
 	}
 
-	res := teal.Process(string(s))
+	opts := []teal.ProcessOption{teal.WithPath(path)}
+	if strict {
+		opts = append(opts, teal.WithStrict())
+	}
+	if permissive {
+		opts = append(opts, teal.WithPermissive())
+	}
+
+	res := teal.Process(string(s), opts...)
 	for _, d := range res.Diagnostics {
 		fmt.Printf("%d:%d-%d:%s %s\n", d.Line(), d.Begin(), d.End(), d.Severity(), d)
 	}
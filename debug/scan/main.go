@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"time"
@@ -54,6 +55,27 @@ func run(a args) error {
 		for b := range ch {
 			fmt.Printf("Block: %d at %s\n", b.Round, time.Now())
 			for txidx, tx := range b.Payset {
+				if len(tx.Lsig.Logic) > 0 {
+					err := func() error {
+						resp, err := dac.TealDisassemble(tx.Lsig.Logic).Do(context.Background())
+						if err != nil {
+							return errors.Wrap(err, "failed to disassemble")
+						}
+
+						res := teal.ProcessPermissive(resp.Result)
+
+						if m, ok := teal.MatchTemplate(res); ok {
+							fmt.Printf("%d:%d: logicsig %s\n", b.Round, txidx, m)
+						}
+
+						return nil
+					}()
+
+					if err != nil {
+						fmt.Printf("Failed to process logicsig - err: %s\n", err)
+					}
+				}
+
 				if tx.Txn.Type != "appl" {
 					continue
 				}
@@ -68,7 +90,20 @@ func run(a args) error {
 						return errors.Wrap(err, "failed to disassemble")
 					}
 
-					res := teal.Process(resp.Result)
+					res := teal.ProcessPermissive(resp.Result)
+
+					caps, err := json.Marshal(teal.AnalyzeCapabilities(res))
+					if err != nil {
+						return errors.Wrap(err, "failed to encode capabilities")
+					}
+					fmt.Printf("%d:%d: capabilities: %s\n", b.Round, txidx, caps)
+
+					risk := teal.AnalyzeRisk(res)
+					fmt.Printf("%d:%d: %s\n", b.Round, txidx, risk)
+					for _, f := range risk.Findings {
+						fmt.Printf("%d:%d:%d: %s (+%d)\n", b.Round, txidx, f.Lines[0], f.Message, f.Score)
+					}
+
 					if len(res.Diagnostics) > 0 {
 						for _, err := range res.Diagnostics {
 							fmt.Printf("%d:%d:%d: %s\n", b.Round, txidx, err.Line(), err)
@@ -0,0 +1,186 @@
+package teal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// DebugState is the snapshot of the debugged program published by the debugger when the execution stops.
+type DebugState struct {
+	Path string `json:"path"`
+
+	// Line is the source line of the next instruction, -1 when the program exited
+	Line int `json:"line"`
+
+	// Stack is the stack of the current branch, bottom first
+	Stack []string `json:"stack,omitempty"`
+
+	// Scratch has the values of the used scratch slots
+	Scratch map[int]string `json:"scratch,omitempty"`
+
+	// Frame has the values of the current frame keyed by the frame_dig index, the arguments are negative
+	Frame map[int]string `json:"frame,omitempty"`
+}
+
+// NewDebugState returns the state of the current branch of the VM.
+func NewDebugState(v *Vm, path string) DebugState {
+	s := DebugState{
+		Path: path,
+		Line: -1,
+	}
+
+	for i, val := range v.Scratch.Items {
+		if val.T == VmTypeNone {
+			continue
+		}
+
+		if s.Scratch == nil {
+			s.Scratch = map[int]string{}
+		}
+		s.Scratch[i] = val.String()
+	}
+
+	b := v.Branch
+	if b == nil {
+		return s
+	}
+
+	if b.Line >= 0 && b.Line < len(v.listing) {
+		s.Line = v.sourceLine(b.Line)
+	}
+
+	for _, val := range b.Stack.Items {
+		s.Stack = append(s.Stack, val.String())
+	}
+
+	if len(b.Frames) > 0 {
+		f := b.Frames[len(b.Frames)-1]
+		base := f.Base()
+
+		s.Frame = map[int]string{}
+		for i := base - int(f.NumArgs); i < len(b.Stack.Items); i++ {
+			if i < 0 {
+				continue
+			}
+			s.Frame[i-base] = s.Stack[i]
+		}
+	}
+
+	return s
+}
+
+// DebugStatePath returns the path of the debug state file published for the program.
+func DebugStatePath(path string) string {
+	return path + ".dbg.json"
+}
+
+// WriteDebugState publishes the state next to the debugged program.
+func WriteDebugState(s DebugState) error {
+	bs, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode debug state")
+	}
+
+	err = os.WriteFile(DebugStatePath(s.Path), bs, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write debug state")
+	}
+
+	return nil
+}
+
+// ReadDebugState reads the state published for the program, nil if there is none.
+func ReadDebugState(path string) (*DebugState, error) {
+	bs, err := os.ReadFile(DebugStatePath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read debug state")
+	}
+
+	var s DebugState
+
+	err = json.Unmarshal(bs, &s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode debug state")
+	}
+
+	return &s, nil
+}
+
+// InlineValue is the runtime value shown next to the instruction.
+type InlineValue struct {
+	Line  int
+	Begin int
+	End   int
+	Text  string
+}
+
+// InlineValues returns the values of the scratch slots and the frame slots used by the instructions in the range
+// and the stack top at the current line.
+func (r ProcessResult) InlineValues(s DebugState, rg Range) []InlineValue {
+	var vs []InlineValue
+
+	names := r.ScratchNames()
+	top := false
+
+	for i, sub := range r.Sublines {
+		if sub.Line < rg.StartLine() || sub.Line > rg.EndLine() || len(sub.Tokens) == 0 || i >= len(r.Listing) {
+			continue
+		}
+
+		add := func(text string) {
+			vs = append(vs, InlineValue{
+				Line:  sub.Line,
+				Begin: sub.Tokens.Begin(),
+				End:   sub.Tokens.End(),
+				Text:  text,
+			})
+		}
+
+		scratch := func(index uint8) {
+			v, ok := s.Scratch[int(index)]
+			if !ok {
+				return
+			}
+
+			name := fmt.Sprintf("scratch[%d]", index)
+			if alias, ok := names[int(index)]; ok {
+				name = alias
+			}
+
+			add(fmt.Sprintf("%s = %s", name, v))
+		}
+
+		frame := func(index int8) {
+			v, ok := s.Frame[int(index)]
+			if !ok {
+				return
+			}
+
+			add(fmt.Sprintf("frame[%d] = %s", index, v))
+		}
+
+		switch op := r.Listing[i].(type) {
+		case *LoadExpr:
+			scratch(op.Index)
+		case *StoreExpr:
+			scratch(op.Index)
+		case *FrameDigExpr:
+			frame(op.Index)
+		case *FrameBuryExpr:
+			frame(op.Index)
+		}
+
+		if sub.Line == s.Line && len(s.Stack) > 0 && !top {
+			top = true
+			add(fmt.Sprintf("top = %s", s.Stack[len(s.Stack)-1]))
+		}
+	}
+
+	return vs
+}
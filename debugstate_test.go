@@ -0,0 +1,81 @@
+package teal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDebugStateInlineValues(t *testing.T) {
+	res := Process(`#pragma version 8
+int 5
+store 1 // scratch 1: amount
+int 7
+callsub sub
+return
+sub:
+proto 1 1
+frame_dig -1
+load 1
++
+retsub`)
+
+	vm := NewVm(res)
+	for i := 0; i < 6; i++ {
+		vm.Step()
+	}
+
+	s := NewDebugState(vm, "app.teal")
+
+	if s.Line != 9 {
+		t.Fatalf("unexpected line: %d", s.Line)
+	}
+
+	if s.Scratch[1] != "uint64: 5" {
+		t.Errorf("unexpected scratch: %v", s.Scratch)
+	}
+
+	if s.Frame[-1] != "uint64: 7" {
+		t.Errorf("unexpected frame: %v", s.Frame)
+	}
+
+	vs := res.InlineValues(s, LinesRange{Start: 0, End: 11})
+
+	var texts []string
+	for _, v := range vs {
+		texts = append(texts, v.Text)
+	}
+
+	expected := []string{"amount = uint64: 5", "frame[-1] = uint64: 7", "amount = uint64: 5", "top = uint64: 7"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("expected: %v, got: %v", expected, texts)
+	}
+}
+
+func TestDebugStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.teal")
+
+	s, err := ReadDebugState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != nil {
+		t.Fatalf("unexpected state: %v", s)
+	}
+
+	expected := DebugState{Path: path, Line: 2, Stack: []string{"1"}, Scratch: map[int]string{0: "1"}}
+
+	err = WriteDebugState(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = ReadDebugState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(*s, expected) {
+		t.Errorf("expected: %v, got: %v", expected, *s)
+	}
+}
@@ -0,0 +1,145 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Deprecation is a legacy construct with a suggested mechanical rewrite of its source range, if there is one.
+type Deprecation struct {
+	Line  int
+	Begin int
+	End   int
+
+	Message     string
+	Replacement string
+}
+
+// bn256Replacements are the ec_* ops that superseded the bn256_* ops.
+var bn256Replacements = map[string]string{
+	"bn256_add":        "ec_add BN254_G1",
+	"bn256_scalar_mul": "ec_scalar_mul BN254_G1",
+	"bn256_pairing":    "ec_pairing_check BN254_G1",
+}
+
+func (r ProcessResult) deprecation(i int, msg string, replacement string) Deprecation {
+	sub := r.Sublines[i]
+
+	return Deprecation{
+		Line:        sub.Line,
+		Begin:       sub.Tokens.Begin(),
+		End:         sub.Tokens.End(),
+		Message:     msg,
+		Replacement: replacement,
+	}
+}
+
+// Deprecations returns the legacy constructs of the program that have better alternatives in its version and mode.
+func (r ProcessResult) Deprecations() []Deprecation {
+	var res []Deprecation
+
+	for i, op := range r.Listing {
+		if i >= len(r.Sublines) {
+			break
+		}
+
+		switch op := op.(type) {
+		case *ArgExpr, *Arg0Expr, *Arg1Expr, *Arg2Expr, *Arg3Expr:
+			if r.Mode != ModeApp {
+				continue
+			}
+
+			index := 0
+			switch op := op.(type) {
+			case *ArgExpr:
+				index = int(op.Index)
+			case *Arg1Expr:
+				index = 1
+			case *Arg2Expr:
+				index = 2
+			case *Arg3Expr:
+				index = 3
+			}
+
+			res = append(res, r.deprecation(i, fmt.Sprintf("%s reads logic signature arguments, use txna ApplicationArgs %d in app mode", op, index), fmt.Sprintf("txna ApplicationArgs %d", index)))
+		case *SubstringExpr:
+			if r.Version < 5 {
+				continue
+			}
+
+			msg := OpDeprecation("substring", r.Version, r.Mode)
+
+			if op.End > op.Start {
+				res = append(res, r.deprecation(i, msg, fmt.Sprintf("extract %d %d", op.Start, op.End-op.Start)))
+			} else {
+				res = append(res, r.deprecation(i, msg, ""))
+			}
+		case *Substring3Expr:
+			if r.Version < 5 {
+				continue
+			}
+
+			res = append(res, r.deprecation(i, OpDeprecation("substring3", r.Version, r.Mode), ""))
+		case *IntcBlockExpr:
+			if r.Version < 3 {
+				continue
+			}
+
+			res = append(res, r.deprecation(i, OpDeprecation("intcblock", r.Version, r.Mode), ""))
+		case *BytecBlockExpr:
+			if r.Version < 3 {
+				continue
+			}
+
+			res = append(res, r.deprecation(i, OpDeprecation("bytecblock", r.Version, r.Mode), ""))
+		default:
+			toks := r.Sublines[i].Tokens
+			if len(toks) == 0 {
+				continue
+			}
+
+			name := toks[0].String()
+			if !strings.HasPrefix(name, "bn256_") {
+				continue
+			}
+
+			if repl, ok := bn256Replacements[name]; ok {
+				res = append(res, r.deprecation(i, OpDeprecation(name, r.Version, r.Mode), repl))
+			}
+		}
+	}
+
+	return res
+}
+
+// OpDeprecation returns the deprecation message of the op in the version and mode or an empty string if the op is not deprecated.
+func OpDeprecation(name string, version uint64, mode ProgramMode) string {
+	switch name {
+	case "arg", "arg_0", "arg_1", "arg_2", "arg_3":
+		if mode == ModeApp {
+			return fmt.Sprintf("%s reads logic signature arguments, use txna ApplicationArgs in app mode", name)
+		}
+	case "substring":
+		if version >= 5 {
+			return "extract takes the length instead of the end index and is clearer than substring"
+		}
+	case "substring3":
+		if version >= 5 {
+			return "extract3 takes the length instead of the end index and is clearer than substring3"
+		}
+	case "intcblock":
+		if version >= 3 {
+			return "intcblock is managed by the assembler, use int or pushint instead"
+		}
+	case "bytecblock":
+		if version >= 3 {
+			return "bytecblock is managed by the assembler, use byte or pushbytes instead"
+		}
+	default:
+		if repl, ok := bn256Replacements[name]; ok {
+			return fmt.Sprintf("%s was superseded by %s", name, repl)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,53 @@
+package teal
+
+import "testing"
+
+func TestDeprecations(t *testing.T) {
+	res := Process(`#pragma version 9
+intcblock 1 2
+arg 2
+arg_1
+byte 0x0102
+substring 0 1
+substring3
+bn256_add`)
+
+	expected := []Deprecation{
+		{Line: 1, Begin: 0, End: 13, Message: "intcblock is managed by the assembler, use int or pushint instead"},
+		{Line: 2, Begin: 0, End: 5, Message: "arg 2 reads logic signature arguments, use txna ApplicationArgs 2 in app mode", Replacement: "txna ApplicationArgs 2"},
+		{Line: 3, Begin: 0, End: 5, Message: "arg_1 reads logic signature arguments, use txna ApplicationArgs 1 in app mode", Replacement: "txna ApplicationArgs 1"},
+		{Line: 5, Begin: 0, End: 13, Message: "extract takes the length instead of the end index and is clearer than substring", Replacement: "extract 0 1"},
+		{Line: 6, Begin: 0, End: 10, Message: "extract3 takes the length instead of the end index and is clearer than substring3"},
+		{Line: 7, Begin: 0, End: 9, Message: "bn256_add was superseded by ec_add BN254_G1", Replacement: "ec_add BN254_G1"},
+	}
+
+	ds := res.Deprecations()
+	if len(ds) != len(expected) {
+		t.Fatalf("unexpected deprecations: %+v", ds)
+	}
+
+	for i, d := range ds {
+		if d != expected[i] {
+			t.Errorf("unexpected deprecation: %+v, expected: %+v", d, expected[i])
+		}
+	}
+
+	count := 0
+	for _, d := range res.Diagnostics {
+		if d.Rule() == DeprecatedRuleInstance.Id() {
+			count++
+		}
+	}
+
+	if count != len(expected) {
+		t.Errorf("unexpected deprecation diagnostics: %v", res.Diagnostics)
+	}
+
+	if len(ProcessPermissive("#pragma version 9\nintcblock 1\nintc_0").Diagnostics) != 0 {
+		t.Error("expected deprecations to be silenced in permissive mode")
+	}
+
+	if len(Process("#pragma version 2\nintcblock 1\nintc_0").Diagnostics) != 0 {
+		t.Error("unexpected deprecations before pushint")
+	}
+}
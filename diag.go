@@ -1,5 +1,7 @@
 package teal
 
+import "github.com/pkg/errors"
+
 type DiagnosticSeverity int
 
 func (s DiagnosticSeverity) String() string {
@@ -10,6 +12,8 @@ func (s DiagnosticSeverity) String() string {
 		return "warn"
 	case DiagErr:
 		return "error"
+	case DiagHint:
+		return "hint"
 	default:
 		panic("unsupported severity")
 	}
@@ -33,3 +37,15 @@ type Diagnostic interface {
 
 	Rule() string
 }
+
+// NewDiagnostic returns the diagnostic of the rule at the zero-based line and the character range, e.g. for the analyzers.
+func NewDiagnostic(line int, begin int, end int, s DiagnosticSeverity, rule string, msg string) Diagnostic {
+	return lintError{
+		error: errors.New(msg),
+		l:     line,
+		b:     begin,
+		e:     end,
+		s:     s,
+		r:     rule,
+	}
+}
@@ -0,0 +1,320 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DiffKind int
+
+const (
+	DiffEqual DiffKind = iota
+	DiffAdded
+	DiffRemoved
+	DiffModified
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffEqual:
+		return "equal"
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	default:
+		return "(none)"
+	}
+}
+
+// DiffLine is a single instruction difference, lines are -1 if the instruction is missing on the side.
+type DiffLine struct {
+	Kind DiffKind
+
+	Old     string
+	OldLine int
+
+	New     string
+	NewLine int
+}
+
+// DiffBlock is a difference of the instructions that follow a label, the instructions before the first label are in the MainName block.
+type DiffBlock struct {
+	Label string
+	Kind  DiffKind
+	Lines []DiffLine
+}
+
+type SourceDiff struct {
+	Blocks []DiffBlock
+}
+
+type diffInstr struct {
+	s    string
+	line int
+}
+
+type diffBlock struct {
+	label  string
+	instrs []diffInstr
+}
+
+func diffBlocks(res *ProcessResult) []diffBlock {
+	bs := []diffBlock{{label: MainName}}
+
+	for i, op := range res.Listing {
+		switch op := op.(type) {
+		case *LabelExpr:
+			bs = append(bs, diffBlock{label: op.Name})
+			continue
+		case Nop:
+			continue
+		}
+
+		b := &bs[len(bs)-1]
+		b.instrs = append(b.instrs, diffInstr{s: op.String(), line: res.SourceLine(i)})
+	}
+
+	if len(bs[0].instrs) == 0 {
+		bs = bs[1:]
+	}
+
+	return bs
+}
+
+type lcsOp struct {
+	kind DiffKind
+	i    int
+	j    int
+}
+
+// lcs aligns the sequences by their longest common subsequence.
+func lcs(a []string, b []string) []lcsOp {
+	n := len(a)
+	m := len(b)
+
+	t := make([][]int, n+1)
+	for i := range t {
+		t[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				t[i][j] = t[i+1][j+1] + 1
+			} else if t[i+1][j] >= t[i][j+1] {
+				t[i][j] = t[i+1][j]
+			} else {
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+
+	var ops []lcsOp
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lcsOp{kind: DiffEqual, i: i, j: j})
+			i++
+			j++
+		case t[i+1][j] >= t[i][j+1]:
+			ops = append(ops, lcsOp{kind: DiffRemoved, i: i, j: -1})
+			i++
+		default:
+			ops = append(ops, lcsOp{kind: DiffAdded, i: -1, j: j})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		ops = append(ops, lcsOp{kind: DiffRemoved, i: i, j: -1})
+	}
+
+	for ; j < m; j++ {
+		ops = append(ops, lcsOp{kind: DiffAdded, i: -1, j: j})
+	}
+
+	return ops
+}
+
+func opName(s string) string {
+	name, _, _ := strings.Cut(s, " ")
+	return name
+}
+
+func diffInstrs(a []diffInstr, b []diffInstr) []DiffLine {
+	as := make([]string, len(a))
+	for i, in := range a {
+		as[i] = in.s
+	}
+
+	bs := make([]string, len(b))
+	for i, in := range b {
+		bs[i] = in.s
+	}
+
+	var res []DiffLine
+
+	ops := lcs(as, bs)
+	for k := 0; k < len(ops); k++ {
+		op := ops[k]
+
+		switch op.kind {
+		case DiffEqual:
+			res = append(res, DiffLine{Kind: DiffEqual, Old: a[op.i].s, OldLine: a[op.i].line, New: b[op.j].s, NewLine: b[op.j].line})
+		case DiffRemoved:
+			// a removal directly followed by an addition of the same op is a modification
+			if k+1 < len(ops) && ops[k+1].kind == DiffAdded && opName(a[op.i].s) == opName(b[ops[k+1].j].s) {
+				n := ops[k+1]
+				res = append(res, DiffLine{Kind: DiffModified, Old: a[op.i].s, OldLine: a[op.i].line, New: b[n.j].s, NewLine: b[n.j].line})
+				k++
+				continue
+			}
+
+			res = append(res, DiffLine{Kind: DiffRemoved, Old: a[op.i].s, OldLine: a[op.i].line, NewLine: -1})
+		case DiffAdded:
+			res = append(res, DiffLine{Kind: DiffAdded, OldLine: -1, New: b[op.j].s, NewLine: b[op.j].line})
+		}
+	}
+
+	return res
+}
+
+func linesKind(lines []DiffLine) DiffKind {
+	for _, l := range lines {
+		if l.Kind != DiffEqual {
+			return DiffModified
+		}
+	}
+
+	return DiffEqual
+}
+
+// Diff compares the programs instructions aligned by the labelled blocks.
+func Diff(oldSrc string, newSrc string) SourceDiff {
+	ob := diffBlocks(Process(oldSrc))
+	nb := diffBlocks(Process(newSrc))
+
+	on := make([]string, len(ob))
+	for i, b := range ob {
+		on[i] = b.label
+	}
+
+	nn := make([]string, len(nb))
+	for i, b := range nb {
+		nn[i] = b.label
+	}
+
+	oi := map[string]int{}
+	for i, b := range ob {
+		oi[b.label] = i
+	}
+
+	ni := map[string]int{}
+	for i, b := range nb {
+		ni[b.label] = i
+	}
+
+	var d SourceDiff
+
+	for _, op := range lcs(on, nn) {
+		switch op.kind {
+		case DiffEqual:
+			lines := diffInstrs(ob[op.i].instrs, nb[op.j].instrs)
+			d.Blocks = append(d.Blocks, DiffBlock{Label: nb[op.j].label, Kind: linesKind(lines), Lines: lines})
+		case DiffRemoved:
+			if _, ok := ni[ob[op.i].label]; ok {
+				// moved blocks are reported at their new position
+				continue
+			}
+
+			d.Blocks = append(d.Blocks, DiffBlock{Label: ob[op.i].label, Kind: DiffRemoved, Lines: diffInstrs(ob[op.i].instrs, nil)})
+		case DiffAdded:
+			if i, ok := oi[nb[op.j].label]; ok {
+				lines := diffInstrs(ob[i].instrs, nb[op.j].instrs)
+				d.Blocks = append(d.Blocks, DiffBlock{Label: nb[op.j].label, Kind: linesKind(lines), Lines: lines})
+				continue
+			}
+
+			d.Blocks = append(d.Blocks, DiffBlock{Label: nb[op.j].label, Kind: DiffAdded, Lines: diffInstrs(nil, nb[op.j].instrs)})
+		}
+	}
+
+	return d
+}
+
+// Changed returns true if any of the blocks differ.
+func (d SourceDiff) Changed() bool {
+	for _, b := range d.Blocks {
+		if b.Kind != DiffEqual {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l DiffLine) String() string {
+	switch l.Kind {
+	case DiffAdded:
+		return "+ " + l.New
+	case DiffRemoved:
+		return "- " + l.Old
+	case DiffModified:
+		return fmt.Sprintf("~ %s -> %s", l.Old, l.New)
+	default:
+		return "  " + l.New
+	}
+}
+
+func (b DiffBlock) Header() string {
+	switch b.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s:", b.Label)
+	case DiffRemoved:
+		return fmt.Sprintf("- %s:", b.Label)
+	default:
+		return fmt.Sprintf("@@ %s @@", b.Label)
+	}
+}
+
+func (d SourceDiff) String() string {
+	var sb strings.Builder
+
+	for _, b := range d.Blocks {
+		if b.Kind == DiffEqual {
+			continue
+		}
+
+		sb.WriteString(b.Header())
+		sb.WriteString("\n")
+
+		for _, l := range b.Lines {
+			sb.WriteString(l.String())
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// DiffText compares the source lines as text, e.g. to preview the fixes that change the labels and the pragmas.
+// The line numbers are zero-based.
+func DiffText(oldSrc string, newSrc string) []DiffLine {
+	split := func(s string) []diffInstr {
+		ls := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+
+		is := make([]diffInstr, len(ls))
+		for i, l := range ls {
+			is[i] = diffInstr{s: strings.TrimSuffix(l, "\r"), line: i}
+		}
+
+		return is
+	}
+
+	return diffInstrs(split(oldSrc), split(newSrc))
+}
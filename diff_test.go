@@ -0,0 +1,80 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	d := Diff(`#pragma version 8
+int 1
+bnz a
+b b
+a:
+int 2
+pop
+b end
+b:
+int 3
+pop
+end:
+int 1
+return`, `#pragma version 8
+int 1
+bnz a
+b c
+b:
+int 3
+pop
+a:
+int 5
+pop
+b end
+c:
+int 4
+pop
+end:
+int 1
+return`)
+
+	if !d.Changed() {
+		t.Fatal("expected changes")
+	}
+
+	s := d.String()
+	expected := `@@ (main) @@
+  int 1
+  bnz a
+~ b b -> b c
+@@ a @@
+~ int 2 -> int 5
+  pop
+  b end
++ c:
++ int 4
++ pop
+`
+	if s != expected {
+		t.Errorf("unexpected diff:\n%s", s)
+	}
+
+	if Diff("int 1\n", "// comment\nint 1\n").Changed() {
+		t.Error("unexpected changes for a comment only difference")
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	ls := DiffText("#pragma version 2\nunused:\nint 1\r\n", "#pragma version 8\nint 1\n")
+
+	var changed []string
+	for _, l := range ls {
+		if l.Kind != DiffEqual {
+			changed = append(changed, l.String())
+		}
+	}
+
+	expected := []string{"- #pragma version 2", "- unused:", "+ #pragma version 8"}
+	if strings.Join(changed, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected diff: %q", changed)
+	}
+}
@@ -0,0 +1,58 @@
+package teal
+
+// vmEcBackend implements the elliptic curve ops on the encoded points and scalars.
+// An error fails the program, e.g. for a point that is not on the curve.
+type vmEcBackend interface {
+	Add(g EcGroup, x []byte, y []byte) ([]byte, error)
+	ScalarMul(g EcGroup, p []byte, k []byte) ([]byte, error)
+	PairingCheck(g EcGroup, x []byte, y []byte) (bool, error)
+	MultiExp(g EcGroup, ps []byte, ks []byte) ([]byte, error)
+	SubgroupCheck(g EcGroup, p []byte) (bool, error)
+	MapTo(g EcGroup, f []byte) ([]byte, error)
+}
+
+// ecBackend evaluates the ec_* ops, it is only available in the builds with the ecvm tag
+var ecBackend vmEcBackend
+
+// EcAvailable returns true if the VM evaluates the ec_* ops.
+func EcAvailable() bool {
+	return ecBackend != nil
+}
+
+// vmEcBytes pushes the result of the op on the known values, the unknown value otherwise.
+func vmEcBytes(b *VmBranch, vs []VmValue, f func(bs [][]byte) ([]byte, error)) {
+	bs, ok := vmKnownBytes(vs...)
+	if !ok || ecBackend == nil {
+		b.push(VmValue{T: VmTypeBytes})
+		b.Line++
+		return
+	}
+
+	res, err := f(bs)
+	if err != nil {
+		b.failf("%s", err)
+		return
+	}
+
+	b.push(NewVmBytes(res))
+	b.Line++
+}
+
+// vmEcBool pushes the result of the check on the known values, the unknown value otherwise.
+func vmEcBool(b *VmBranch, vs []VmValue, f func(bs [][]byte) (bool, error)) {
+	bs, ok := vmKnownBytes(vs...)
+	if !ok || ecBackend == nil {
+		b.push(VmValue{T: VmTypeUint64})
+		b.Line++
+		return
+	}
+
+	res, err := f(bs)
+	if err != nil {
+		b.failf("%s", err)
+		return
+	}
+
+	b.push(vmBool(res))
+	b.Line++
+}
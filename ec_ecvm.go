@@ -0,0 +1,426 @@
+//go:build ecvm
+
+package teal
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381"
+	blsfp "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	bnfp "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	ecBackend = gnarkEc{}
+}
+
+// ecScalarSize is the max size of the scalars and the size of each ec_multi_exp scalar
+const ecScalarSize = 32
+
+// ecPoint is an affine point of one of the gnark-crypto groups.
+type ecPoint[P any] interface {
+	*P
+	Add(a, b *P) *P
+	ScalarMultiplication(a *P, s *big.Int) *P
+	IsOnCurve() bool
+	IsInSubGroup() bool
+}
+
+// ecGroup encodes the points of the group as the concatenated big-endian coordinates.
+// The Fp2 coordinates of the G2 points are encoded with the real part first, the point at infinity is all zeros.
+type ecGroup[P any, PP ecPoint[P]] struct {
+	size   int
+	decode func(bs []byte) (P, error)
+	encode func(p *P) []byte
+	mapTo  func(bs []byte) (P, error)
+}
+
+var errEcNotOnCurve = errors.New("point is not on the curve")
+
+func (g ecGroup[P, PP]) point(bs []byte) (P, error) {
+	if len(bs) != g.size {
+		var p P
+		return p, errors.Errorf("point must be %d bytes, got: %d", g.size, len(bs))
+	}
+
+	p, err := g.decode(bs)
+	if err != nil {
+		return p, err
+	}
+
+	if !PP(&p).IsOnCurve() {
+		return p, errEcNotOnCurve
+	}
+
+	return p, nil
+}
+
+func (g ecGroup[P, PP]) points(bs []byte) ([]P, error) {
+	if len(bs)%g.size != 0 {
+		return nil, errors.Errorf("points must be a multiple of %d bytes, got: %d", g.size, len(bs))
+	}
+
+	var res []P
+	for i := 0; i < len(bs); i += g.size {
+		p, err := g.point(bs[i : i+g.size])
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+func (g ecGroup[P, PP]) add(x []byte, y []byte) ([]byte, error) {
+	p, err := g.point(x)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := g.point(y)
+	if err != nil {
+		return nil, err
+	}
+
+	var r P
+	PP(&r).Add(&p, &q)
+
+	return g.encode(&r), nil
+}
+
+func (g ecGroup[P, PP]) scalarMul(x []byte, k []byte) ([]byte, error) {
+	if len(k) > ecScalarSize {
+		return nil, errors.Errorf("scalar must be at most %d bytes, got: %d", ecScalarSize, len(k))
+	}
+
+	p, err := g.point(x)
+	if err != nil {
+		return nil, err
+	}
+
+	var r P
+	PP(&r).ScalarMultiplication(&p, new(big.Int).SetBytes(k))
+
+	return g.encode(&r), nil
+}
+
+func (g ecGroup[P, PP]) multiExp(ps []byte, ks []byte) ([]byte, error) {
+	points, err := g.points(ps)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("no points")
+	}
+
+	if len(ks) != len(points)*ecScalarSize {
+		return nil, errors.Errorf("expected %d scalars of %d bytes, got: %d bytes", len(points), ecScalarSize, len(ks))
+	}
+
+	var r P
+	for i := range points {
+		var t P
+		PP(&t).ScalarMultiplication(&points[i], new(big.Int).SetBytes(ks[i*ecScalarSize:(i+1)*ecScalarSize]))
+		PP(&r).Add(&r, &t)
+	}
+
+	return g.encode(&r), nil
+}
+
+func (g ecGroup[P, PP]) subgroupCheck(x []byte) (bool, error) {
+	p, err := g.point(x)
+	if err != nil {
+		return false, err
+	}
+
+	return PP(&p).IsInSubGroup(), nil
+}
+
+func (g ecGroup[P, PP]) mapToBytes(f []byte) ([]byte, error) {
+	p, err := g.mapTo(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.encode(&p), nil
+}
+
+// ecGroupOps are the group ops independent of the point type.
+type ecGroupOps interface {
+	add(x []byte, y []byte) ([]byte, error)
+	scalarMul(x []byte, k []byte) ([]byte, error)
+	multiExp(ps []byte, ks []byte) ([]byte, error)
+	subgroupCheck(x []byte) (bool, error)
+	mapToBytes(f []byte) ([]byte, error)
+}
+
+func bnFp(bs []byte) (bnfp.Element, error) {
+	var e bnfp.Element
+	err := e.SetBytesCanonical(bs)
+	return e, err
+}
+
+func blsFp(bs []byte) (blsfp.Element, error) {
+	var e blsfp.Element
+	err := e.SetBytesCanonical(bs)
+	return e, err
+}
+
+// ecFields decodes the concatenated field elements of the size.
+func ecFields[E any](bs []byte, size int, n int, f func(bs []byte) (E, error)) ([]E, error) {
+	if len(bs) != size*n {
+		return nil, errors.Errorf("expected %d bytes, got: %d", size*n, len(bs))
+	}
+
+	res := make([]E, n)
+	for i := range res {
+		e, err := f(bs[i*size : (i+1)*size])
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = e
+	}
+
+	return res, nil
+}
+
+func ecConcat(parts ...[]byte) []byte {
+	var res []byte
+	for _, p := range parts {
+		res = append(res, p...)
+	}
+
+	return res
+}
+
+var bn254G1 = ecGroup[bn254.G1Affine, *bn254.G1Affine]{
+	size: 2 * bnfp.Bytes,
+	decode: func(bs []byte) (bn254.G1Affine, error) {
+		es, err := ecFields(bs, bnfp.Bytes, 2, bnFp)
+		if err != nil {
+			return bn254.G1Affine{}, err
+		}
+
+		return bn254.G1Affine{X: es[0], Y: es[1]}, nil
+	},
+	encode: func(p *bn254.G1Affine) []byte {
+		x, y := p.X.Bytes(), p.Y.Bytes()
+		return ecConcat(x[:], y[:])
+	},
+	mapTo: func(bs []byte) (bn254.G1Affine, error) {
+		es, err := ecFields(bs, bnfp.Bytes, 1, bnFp)
+		if err != nil {
+			return bn254.G1Affine{}, err
+		}
+
+		return bn254.MapToG1(es[0]), nil
+	},
+}
+
+var bn254G2 = ecGroup[bn254.G2Affine, *bn254.G2Affine]{
+	size: 4 * bnfp.Bytes,
+	decode: func(bs []byte) (bn254.G2Affine, error) {
+		es, err := ecFields(bs, bnfp.Bytes, 4, bnFp)
+		if err != nil {
+			return bn254.G2Affine{}, err
+		}
+
+		var p bn254.G2Affine
+		p.X.A0, p.X.A1, p.Y.A0, p.Y.A1 = es[0], es[1], es[2], es[3]
+
+		return p, nil
+	},
+	encode: func(p *bn254.G2Affine) []byte {
+		xa0, xa1, ya0, ya1 := p.X.A0.Bytes(), p.X.A1.Bytes(), p.Y.A0.Bytes(), p.Y.A1.Bytes()
+		return ecConcat(xa0[:], xa1[:], ya0[:], ya1[:])
+	},
+	mapTo: func(bs []byte) (bn254.G2Affine, error) {
+		es, err := ecFields(bs, bnfp.Bytes, 2, bnFp)
+		if err != nil {
+			return bn254.G2Affine{}, err
+		}
+
+		return bn254.MapToG2(bn254.E2{A0: es[0], A1: es[1]}), nil
+	},
+}
+
+var bls12381G1 = ecGroup[bls12381.G1Affine, *bls12381.G1Affine]{
+	size: 2 * blsfp.Bytes,
+	decode: func(bs []byte) (bls12381.G1Affine, error) {
+		es, err := ecFields(bs, blsfp.Bytes, 2, blsFp)
+		if err != nil {
+			return bls12381.G1Affine{}, err
+		}
+
+		return bls12381.G1Affine{X: es[0], Y: es[1]}, nil
+	},
+	encode: func(p *bls12381.G1Affine) []byte {
+		x, y := p.X.Bytes(), p.Y.Bytes()
+		return ecConcat(x[:], y[:])
+	},
+	mapTo: func(bs []byte) (bls12381.G1Affine, error) {
+		es, err := ecFields(bs, blsfp.Bytes, 1, blsFp)
+		if err != nil {
+			return bls12381.G1Affine{}, err
+		}
+
+		return bls12381.MapToG1(es[0]), nil
+	},
+}
+
+var bls12381G2 = ecGroup[bls12381.G2Affine, *bls12381.G2Affine]{
+	size: 4 * blsfp.Bytes,
+	decode: func(bs []byte) (bls12381.G2Affine, error) {
+		es, err := ecFields(bs, blsfp.Bytes, 4, blsFp)
+		if err != nil {
+			return bls12381.G2Affine{}, err
+		}
+
+		var p bls12381.G2Affine
+		p.X.A0, p.X.A1, p.Y.A0, p.Y.A1 = es[0], es[1], es[2], es[3]
+
+		return p, nil
+	},
+	encode: func(p *bls12381.G2Affine) []byte {
+		xa0, xa1, ya0, ya1 := p.X.A0.Bytes(), p.X.A1.Bytes(), p.Y.A0.Bytes(), p.Y.A1.Bytes()
+		return ecConcat(xa0[:], xa1[:], ya0[:], ya1[:])
+	},
+	mapTo: func(bs []byte) (bls12381.G2Affine, error) {
+		es, err := ecFields(bs, blsfp.Bytes, 2, blsFp)
+		if err != nil {
+			return bls12381.G2Affine{}, err
+		}
+
+		return bls12381.MapToG2(bls12381.E2{A0: es[0], A1: es[1]}), nil
+	},
+}
+
+func ecGroupByName(g EcGroup) (ecGroupOps, error) {
+	switch g {
+	case BN254_G1:
+		return bn254G1, nil
+	case BN254_G2:
+		return bn254G2, nil
+	case BLS12_381_G1:
+		return bls12381G1, nil
+	case BLS12_381_G2:
+		return bls12381G2, nil
+	default:
+		return nil, errors.Errorf("unsupported group: %s", g)
+	}
+}
+
+// ecSubgroupPoints decodes the points and checks that they are in the subgroup as required by the pairing.
+func ecSubgroupPoints[P any, PP ecPoint[P]](g ecGroup[P, PP], bs []byte) ([]P, error) {
+	ps, err := g.points(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ps {
+		if !PP(&ps[i]).IsInSubGroup() {
+			return nil, errors.New("point is not in the subgroup")
+		}
+	}
+
+	return ps, nil
+}
+
+// gnarkEc implements the ec_* ops with gnark-crypto.
+type gnarkEc struct{}
+
+func (gnarkEc) Add(g EcGroup, x []byte, y []byte) ([]byte, error) {
+	ops, err := ecGroupByName(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return ops.add(x, y)
+}
+
+func (gnarkEc) ScalarMul(g EcGroup, p []byte, k []byte) ([]byte, error) {
+	ops, err := ecGroupByName(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return ops.scalarMul(p, k)
+}
+
+func (gnarkEc) MultiExp(g EcGroup, ps []byte, ks []byte) ([]byte, error) {
+	ops, err := ecGroupByName(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return ops.multiExp(ps, ks)
+}
+
+func (gnarkEc) SubgroupCheck(g EcGroup, p []byte) (bool, error) {
+	ops, err := ecGroupByName(g)
+	if err != nil {
+		return false, err
+	}
+
+	return ops.subgroupCheck(p)
+}
+
+func (gnarkEc) MapTo(g EcGroup, f []byte) ([]byte, error) {
+	ops, err := ecGroupByName(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return ops.mapToBytes(f)
+}
+
+// PairingCheck checks the G1 points of x against the G2 points of y, the G2 group swaps them.
+func (gnarkEc) PairingCheck(g EcGroup, x []byte, y []byte) (bool, error) {
+	switch g {
+	case BN254_G2, BLS12_381_G2:
+		x, y = y, x
+	}
+
+	switch g {
+	case BN254_G1, BN254_G2:
+		ps, err := ecSubgroupPoints(bn254G1, x)
+		if err != nil {
+			return false, err
+		}
+
+		qs, err := ecSubgroupPoints(bn254G2, y)
+		if err != nil {
+			return false, err
+		}
+
+		if len(ps) != len(qs) || len(ps) == 0 {
+			return false, errors.New("mismatched number of points")
+		}
+
+		return bn254.PairingCheck(ps, qs)
+	case BLS12_381_G1, BLS12_381_G2:
+		ps, err := ecSubgroupPoints(bls12381G1, x)
+		if err != nil {
+			return false, err
+		}
+
+		qs, err := ecSubgroupPoints(bls12381G2, y)
+		if err != nil {
+			return false, err
+		}
+
+		if len(ps) != len(qs) || len(ps) == 0 {
+			return false, errors.New("mismatched number of points")
+		}
+
+		return bls12381.PairingCheck(ps, qs)
+	default:
+		return false, errors.Errorf("unsupported group: %s", g)
+	}
+}
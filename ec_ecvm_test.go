@@ -0,0 +1,69 @@
+//go:build ecvm
+
+package teal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+func ecProgram(lines ...string) string {
+	return "#pragma version 9\n" + strings.Join(lines, "\n")
+}
+
+func TestEcOps(t *testing.T) {
+	_, _, bnG1, bnG2 := bn254.Generators()
+	_, _, blsG1, blsG2 := bls12381.Generators()
+
+	var bnNegG1 bn254.G1Affine
+	bnNegG1.Neg(&bnG1)
+
+	var blsNegG1 bls12381.G1Affine
+	blsNegG1.Neg(&blsG1)
+
+	var bnG1x2 bn254.G1Affine
+	bnG1x2.ScalarMultiplication(&bnG1, big.NewInt(2))
+
+	var blsG2x3 bls12381.G2Affine
+	blsG2x3.ScalarMultiplication(&blsG2, big.NewInt(3))
+
+	g1 := fmt.Sprintf("0x%x", bn254G1.encode(&bnG1))
+	g1x2 := fmt.Sprintf("0x%x", bn254G1.encode(&bnG1x2))
+	g2 := fmt.Sprintf("0x%x", bn254G2.encode(&bnG2))
+	neg := fmt.Sprintf("0x%x", bn254G1.encode(&bnNegG1))
+
+	blsg1 := fmt.Sprintf("0x%x", bls12381G1.encode(&blsG1))
+	blsneg := fmt.Sprintf("0x%x", bls12381G1.encode(&blsNegG1))
+	blsg2 := fmt.Sprintf("0x%x", bls12381G2.encode(&blsG2))
+	blsg2x3 := fmt.Sprintf("0x%x", bls12381G2.encode(&blsG2x3))
+
+	tests := []struct {
+		name     string
+		source   string
+		expected VmExit
+	}{
+		{"add", ecProgram("byte "+g1, "byte "+g1, "ec_add BN254_G1", "byte "+g1x2, "==", "return"), VmExitApprove},
+		{"scalar mul", ecProgram("byte "+g1, "byte 0x02", "ec_scalar_mul BN254_G1", "byte "+g1x2, "==", "return"), VmExitApprove},
+		{"multi exp length", ecProgram("byte "+g1+"0000", "byte 0x"+strings.Repeat("00", 31)+"01"+strings.Repeat("00", 31)+"01", "ec_multi_exp BN254_G1", "byte "+g1x2, "==", "return"), VmExitFail},
+		{"multi exp", ecProgram("byte "+g1+g1[2:], "byte 0x"+strings.Repeat("00", 31)+"01"+strings.Repeat("00", 31)+"01", "ec_multi_exp BN254_G1", "byte "+g1x2, "==", "return"), VmExitApprove},
+		{"g2 scalar mul", ecProgram("byte "+blsg2, "byte 0x03", "ec_scalar_mul BLS12_381_G2", "byte "+blsg2x3, "==", "return"), VmExitApprove},
+		{"pairing", ecProgram("byte "+g1+neg[2:], "byte "+g2+g2[2:], "ec_pairing_check BN254_G1", "return"), VmExitApprove},
+		{"pairing mismatch", ecProgram("byte "+g1+g1[2:], "byte "+g2+g2[2:], "ec_pairing_check BN254_G1", "return"), VmExitReject},
+		{"bls pairing", ecProgram("byte "+blsg1+blsneg[2:], "byte "+blsg2+blsg2[2:], "ec_pairing_check BLS12_381_G1", "return"), VmExitApprove},
+		{"bls g2 pairing", ecProgram("byte "+blsg2+blsg2[2:], "byte "+blsg1+blsneg[2:], "ec_pairing_check BLS12_381_G2", "return"), VmExitApprove},
+		{"subgroup", ecProgram("byte "+blsg1, "ec_subgroup_check BLS12_381_G1", "return"), VmExitApprove},
+		{"not on curve", ecProgram("byte 0x"+strings.Repeat("00", 63)+"01", "ec_subgroup_check BN254_G1", "return"), VmExitFail},
+		{"map to", ecProgram("byte 0x"+strings.Repeat("00", 31)+"01", "ec_map_to BN254_G1", "ec_subgroup_check BN254_G1", "return"), VmExitApprove},
+	}
+
+	for _, test := range tests {
+		if exit := runExit(t, test.source); exit != test.expected {
+			t.Errorf("unexpected exit for %s: %s", test.name, exit)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package teal
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const entryMaxBranches = 1000
+
+// TestEntry is an entry of the program reached by routing on a method selector or on the OnCompletion value,
+// listed as a test in the test explorers.
+type TestEntry struct {
+	// Id is unique in the program, e.g. "method:add(uint64,uint64)uint64" or "oc:OptIn"
+	Id    string `json:"id"`
+	Label string `json:"label"`
+
+	// Target is the label the routing branches to, Line is its source line
+	Target string `json:"target"`
+	Line   int    `json:"line"`
+
+	Method       string `json:"method,omitempty"`
+	Selector     string `json:"selector,omitempty"`
+	OnCompletion string `json:"onCompletion,omitempty"`
+}
+
+// TestStatus is the outcome of running the program for an entry.
+type TestStatus string
+
+const (
+	TestPassed    TestStatus = "passed"
+	TestFailed    TestStatus = "failed"
+	TestUnreached TestStatus = "unreached"
+)
+
+// TestResult is the result of running the program for an entry.
+type TestResult struct {
+	Id      string     `json:"id"`
+	Status  TestStatus `json:"status"`
+	Message string     `json:"message,omitempty"`
+
+	// not all of the paths could be explored
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// entryOps are the instructions of the listing without the nops.
+func entryOps(l Listing) []Op {
+	var ops []Op
+
+	for _, op := range l {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops
+}
+
+func isSelectorArg(op Op) bool {
+	t, ok := op.(*TxnaExpr)
+	return ok && t.Field == ApplicationArgs && t.Index == 0
+}
+
+func isOnCompletion(op Op) bool {
+	t, ok := op.(*TxnExpr)
+	return ok && t.Field == OnCompletion
+}
+
+func methodEntry(sig string, target string) TestEntry {
+	sig = strings.Trim(sig, "\"")
+	h := sha512.Sum512_256([]byte(sig))
+
+	return TestEntry{
+		Id:       "method:" + sig,
+		Label:    sig,
+		Target:   target,
+		Method:   sig,
+		Selector: hex.EncodeToString(h[:4]),
+	}
+}
+
+func onCompletionEntry(v uint64, target string) TestEntry {
+	name := OnCompletionConstType(v).String()
+
+	return TestEntry{
+		Id:           "oc:" + name,
+		Label:        name,
+		Target:       target,
+		OnCompletion: name,
+	}
+}
+
+// TestEntries returns the entries the program routes to on the method selectors, with `==` and `bnz`
+// or with `match`, and on the OnCompletion values, with `==` and `bnz` or with `switch`.
+func (r ProcessResult) TestEntries() []TestEntry {
+	ops := entryOps(r.Listing)
+
+	var res []TestEntry
+	seen := map[string]bool{}
+
+	add := func(e TestEntry) {
+		if seen[e.Id] {
+			return
+		}
+
+		line, ok := r.labelLine(e.Target)
+		if !ok {
+			return
+		}
+
+		seen[e.Id] = true
+		e.Line = line
+		res = append(res, e)
+	}
+
+	for i, op := range ops {
+		// x; y; ==; bnz target with the arg or the OnCompletion compared to a constant
+		if b, ok := op.(*BnzExpr); ok && i >= 3 {
+			if _, ok := ops[i-1].(*EqExpr); ok {
+				x, y := ops[i-3], ops[i-2]
+
+				for k := 0; k < 2; k++ {
+					if isSelectorArg(x) {
+						if m, ok := y.(*MethodExpr); ok {
+							add(methodEntry(m.Signature, b.Label.Name))
+						}
+					}
+					if isOnCompletion(x) {
+						if v, ok := constUint(y); ok {
+							add(onCompletionEntry(v, b.Label.Name))
+						}
+					}
+					x, y = y, x
+				}
+			}
+		}
+
+		// method a; method b; txna ApplicationArgs 0; match ta tb
+		if m, ok := op.(*MatchExpr); ok && i >= 1 && isSelectorArg(ops[i-1]) {
+			n := len(m.Targets)
+			if i-1 >= n {
+				for k, t := range m.Targets {
+					if sig, ok := ops[i-1-n+k].(*MethodExpr); ok {
+						add(methodEntry(sig.Signature, t.Name))
+					}
+				}
+			}
+		}
+
+		// txn OnCompletion; switch t0 t1 ...
+		if s, ok := op.(*SwitchExpr); ok && i >= 1 && isOnCompletion(ops[i-1]) {
+			for k, t := range s.Targets {
+				if uint64(k) < uint64(invalidOnCompletionConst) {
+					add(onCompletionEntry(uint64(k), t.Name))
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func (r ProcessResult) labelLine(name string) (int, bool) {
+	for i, op := range r.Listing {
+		if l, ok := op.(*LabelExpr); ok && l.Name == name {
+			return r.SourceLine(i), true
+		}
+	}
+
+	return 0, false
+}
+
+// RunTestEntry runs the application call of the entry - the method calls are NoOp calls with the selector
+// as the first arg - and reports whether the paths reaching the entry target approve.
+func (r *ProcessResult) RunTestEntry(e TestEntry) TestResult {
+	tr := TestResult{Id: e.Id}
+
+	vm := NewVm(r)
+	vm.Txn = map[TxnField]uint64{
+		ApplicationID: 1,
+		OnCompletion:  uint64(NoOp),
+	}
+
+	if e.OnCompletion != "" {
+		for oc := NoOp; oc < invalidOnCompletionConst; oc++ {
+			if oc.String() == e.OnCompletion {
+				vm.Txn[OnCompletion] = uint64(oc)
+			}
+		}
+	}
+
+	if e.Selector != "" {
+		sel, err := hex.DecodeString(e.Selector)
+		if err == nil {
+			vm.Args = [][]byte{sel}
+		}
+	}
+
+	tr.Incomplete = !vm.RunAll(entryMaxBranches)
+
+	// the first op of the target marks the branches reaching it
+	var first Op
+	if i := r.ListingIndex(e.Line); i >= 0 {
+		for ; i < len(r.Listing); i++ {
+			if _, ok := r.Listing[i].(Nop); !ok {
+				first = r.Listing[i]
+				break
+			}
+		}
+	}
+
+	reached := false
+	approved := false
+
+	for _, b := range vm.Branches {
+		hit := false
+		for _, op := range b.Trace {
+			if op == first {
+				hit = true
+				break
+			}
+		}
+
+		if !hit {
+			continue
+		}
+
+		reached = true
+
+		switch b.Exit {
+		case VmExitApprove, VmExitUnknown:
+			approved = true
+		case VmExitReject:
+			if tr.Message == "" {
+				tr.Message = fmt.Sprintf("rejected at line %d", b.sourceLine(b.Line)+1)
+			}
+		case VmExitFail:
+			if b.Err != nil {
+				tr.Message = b.Err.Error()
+			} else {
+				tr.Message = "failed"
+			}
+			tr.Status = TestFailed
+		}
+	}
+
+	switch {
+	case !reached:
+		tr.Status = TestUnreached
+		tr.Message = fmt.Sprintf("%s is not reached", e.Target)
+	case tr.Status == TestFailed:
+	case approved:
+		tr.Status = TestPassed
+		tr.Message = ""
+	default:
+		tr.Status = TestFailed
+	}
+
+	return tr
+}
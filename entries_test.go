@@ -0,0 +1,97 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestTestEntries(t *testing.T) {
+	res := Process(`#pragma version 8
+txn ApplicationID
+bz create
+txn OnCompletion
+int OptIn
+==
+bnz optin
+method "add(uint64,uint64)uint64"
+txna ApplicationArgs 0
+==
+bnz add
+method "fail()void"
+method "reject()void"
+txna ApplicationArgs 0
+match fail reject
+err
+create:
+int 1
+return
+optin:
+int 1
+return
+add:
+int 1
+return
+fail:
+err
+reject:
+int 0
+return`)
+
+	es := res.TestEntries()
+
+	expected := []struct {
+		id     string
+		target string
+		line   int
+		status TestStatus
+	}{
+		{"oc:OptIn", "optin", 19, TestPassed},
+		{"method:add(uint64,uint64)uint64", "add", 22, TestPassed},
+		{"method:fail()void", "fail", 25, TestFailed},
+		{"method:reject()void", "reject", 27, TestFailed},
+	}
+
+	if len(es) != len(expected) {
+		t.Fatalf("unexpected entries: %v", es)
+	}
+
+	for i, e := range expected {
+		if es[i].Id != e.id || es[i].Target != e.target || es[i].Line != e.line {
+			t.Errorf("unexpected entry %d: %+v", i, es[i])
+		}
+
+		r := res.RunTestEntry(es[i])
+		if r.Status != e.status {
+			t.Errorf("unexpected status of %s: %s (%s)", e.id, r.Status, r.Message)
+		}
+	}
+
+	if es[1].Selector != "fe6bdf69" {
+		t.Errorf("unexpected selector: %s", es[1].Selector)
+	}
+}
+
+func TestTestEntriesSwitch(t *testing.T) {
+	res := Process(`#pragma version 8
+txn OnCompletion
+switch noop optin
+err
+noop:
+int 1
+return
+optin:
+int 0
+return`)
+
+	es := res.TestEntries()
+	if len(es) != 2 || es[0].OnCompletion != "NoOp" || es[1].OnCompletion != "OptIn" {
+		t.Fatalf("unexpected entries: %v", es)
+	}
+
+	if r := res.RunTestEntry(es[0]); r.Status != TestPassed {
+		t.Errorf("unexpected status: %s", r.Status)
+	}
+
+	if r := res.RunTestEntry(es[1]); r.Status != TestFailed {
+		t.Errorf("unexpected status: %s", r.Status)
+	}
+}
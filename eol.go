@@ -0,0 +1,52 @@
+package teal
+
+import "strings"
+
+// DefaultEol is used for the generated text of the sources without line endings.
+const DefaultEol = "\r\n"
+
+// Eols are the supported line endings in the order of preference on ties.
+var Eols = []string{"\r\n", "\n", "\r"}
+
+// DetectEol returns the dominant line ending of the source.
+func DetectEol(source string) string {
+	counts := map[string]int{}
+
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case '\r':
+			if i+1 < len(source) && source[i+1] == '\n' {
+				counts["\r\n"]++
+				i++
+			} else {
+				counts["\r"]++
+			}
+		case '\n':
+			counts["\n"]++
+		}
+	}
+
+	eol := DefaultEol
+	max := 0
+
+	for _, e := range Eols {
+		if counts[e] > max {
+			eol = e
+			max = counts[e]
+		}
+	}
+
+	return eol
+}
+
+// NormalizeEol replaces all the line endings of the text with the eol.
+func NormalizeEol(s string, eol string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	if eol != "\n" {
+		s = strings.ReplaceAll(s, "\n", eol)
+	}
+
+	return s
+}
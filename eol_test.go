@@ -0,0 +1,54 @@
+package teal
+
+import "testing"
+
+func TestDetectEol(t *testing.T) {
+	tests := []struct {
+		src string
+		eol string
+	}{
+		{src: "int 1", eol: DefaultEol},
+		{src: "int 1\nint 2\r\nint 3\n", eol: "\n"},
+		{src: "int 1\r\nint 2\r\n", eol: "\r\n"},
+		{src: "int 1\rint 2\rint 3\n", eol: "\r"},
+		{src: "int 1\nint 2\r\n", eol: "\r\n"},
+	}
+
+	for _, test := range tests {
+		if eol := DetectEol(test.src); eol != test.eol {
+			t.Errorf("%q: expected %q, got %q", test.src, test.eol, eol)
+		}
+	}
+}
+
+func TestNormalizeEol(t *testing.T) {
+	s := NormalizeEol("a\r\nb\rc\nd", "\r\n")
+	if s != "a\r\nb\r\nc\r\nd" {
+		t.Errorf("unexpected: %q", s)
+	}
+
+	s = NormalizeEol("a\r\nb\rc\nd", "\r")
+	if s != "a\rb\rc\rd" {
+		t.Errorf("unexpected: %q", s)
+	}
+}
+
+func TestFixesUseDocumentEol(t *testing.T) {
+	res := Process("#pragma version 8\r\nb missing\r\nint 1\r\n")
+
+	var found bool
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+		if fa.Kind != FixCreateLabel {
+			continue
+		}
+
+		found = true
+		if fa.Edits[0].NewText != "\r\nmissing:\r\n" {
+			t.Errorf("unexpected text: %q", fa.Edits[0].NewText)
+		}
+	}
+
+	if !found {
+		t.Error("create label fix not found")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -21,7 +22,7 @@ type Nop interface {
 type ErrExpr struct{}
 
 func (e *ErrExpr) Execute(b *VmBranch) error {
-	b.exit()
+	b.failf("err")
 	return nil
 }
 
@@ -46,8 +47,8 @@ func (e *Sha3256Expr) Cost(b *VmBranch) []int {
 }
 
 func (e *Sha3256Expr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeBytes)
+	vmHash(b, v, sha3_256Sum, VmValue{T: VmTypeBytes})
 	b.Line++
 	return nil
 }
@@ -73,7 +74,7 @@ func (e *Sha256Expr) Cost(b *VmBranch) []int {
 
 func (e *Sha256Expr) Execute(b *VmBranch) error {
 	v := b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes, src: vmOpSource{e: e, args: []vmSource{v}}})
+	vmHash(b, v, sha256Sum, VmValue{T: VmTypeBytes, src: vmOpSource{e: e, args: []vmSource{v}}})
 
 	b.Line++
 	return nil
@@ -103,8 +104,8 @@ func (e *Keccak256Expr) Cost(b *VmBranch) []int {
 }
 
 func (e *Keccak256Expr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeBytes)
+	vmHash(b, v, keccak256Sum, VmValue{T: VmTypeBytes})
 
 	b.Line++
 	return nil
@@ -130,8 +131,8 @@ func (e *Sha512256Expr) Cost(b *VmBranch) []int {
 }
 
 func (e *Sha512256Expr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeBytes)
+	vmHash(b, v, sha512_256Sum, VmValue{T: VmTypeBytes})
 
 	b.Line++
 	return nil
@@ -180,7 +181,7 @@ func (e *EcdsaVerifyExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *EcdsaVerifyExpr) String() string {
-	return fmt.Sprintf("%s %d", e.Name(), e.Index)
+	return fmt.Sprintf("%s %s", e.Name(), e.Index)
 }
 
 func (e *EcdsaVerifyExpr) Name() string {
@@ -194,7 +195,11 @@ func (e *EcdsaVerifyExpr) Execute(b *VmBranch) error {
 	v4 := b.pop(VmTypeBytes)
 	v5 := b.pop(VmTypeBytes)
 
-	b.push(VmValue{T: VmTypeUint64, src: vmOpSource{e: e, args: []vmSource{v1, v2, v3, v4, v5}}})
+	if bs, ok := vmKnownBytes(v5, v4, v3, v2, v1); ok {
+		b.push(vmBool(ecdsaVerify(e.Index, bs[0], bs[1], bs[2], bs[3], bs[4])))
+	} else {
+		b.push(VmValue{T: VmTypeUint64, src: vmOpSource{e: e, args: []vmSource{v1, v2, v3, v4, v5}}})
+	}
 
 	b.Line++
 	return nil
@@ -220,7 +225,7 @@ func (e *EcdsaPkDecompressExpr) Execute(b *VmBranch) error {
 }
 
 func (e *EcdsaPkDecompressExpr) String() string {
-	return fmt.Sprintf("ecdsa_pk_decompress %d", e.Index)
+	return fmt.Sprintf("ecdsa_pk_decompress %s", e.Index)
 }
 
 // ecdsa_pk_recover
@@ -246,7 +251,7 @@ func (e *EcdsaPkRecoverExpr) Execute(b *VmBranch) error {
 }
 
 func (e *EcdsaPkRecoverExpr) String() string {
-	return fmt.Sprintf("ecdsa_pk_recover %d", e.Index)
+	return fmt.Sprintf("ecdsa_pk_recover %s", e.Index)
 }
 
 // +
@@ -337,9 +342,9 @@ func (e *LtExpr) String() string {
 }
 
 func (e *LtExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x < y }))
 
 	b.Line++
 	return nil
@@ -353,9 +358,9 @@ type GtExpr struct {
 }
 
 func (e *GtExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x > y }))
 
 	b.Line++
 	return nil
@@ -373,9 +378,9 @@ type LtEqExpr struct {
 }
 
 func (e *LtEqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x <= y }))
 
 	b.Line++
 	return nil
@@ -397,9 +402,9 @@ func (e *GtEqExpr) String() string {
 }
 
 func (e *GtEqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x >= y }))
 
 	b.Line++
 	return nil
@@ -413,9 +418,9 @@ type AndExpr struct {
 }
 
 func (e *AndExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x != 0 && y != 0 }))
 
 	b.Line++
 	return nil
@@ -437,9 +442,9 @@ func (e *OrExpr) String() string {
 }
 
 func (e *OrExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeUint64)
+	x := b.pop(VmTypeUint64)
+	b.push(vmCompare(x, y, func(x, y uint64) bool { return x != 0 || y != 0 }))
 
 	b.Line++
 	return nil
@@ -453,9 +458,10 @@ type EqExpr struct {
 }
 
 func (e *EqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeAny)
-	b.pop(VmTypeAny)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeAny)
+	x := b.pop(VmTypeAny)
+	b.checkLengths(x, y)
+	b.push(vmEqual(x, y, true))
 
 	b.Line++
 	return nil
@@ -473,9 +479,10 @@ type NeqExpr struct {
 }
 
 func (e *NeqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeAny)
-	b.pop(VmTypeAny)
-	b.push(VmValue{T: VmTypeUint64})
+	y := b.pop(VmTypeAny)
+	x := b.pop(VmTypeAny)
+	b.checkLengths(x, y)
+	b.push(vmEqual(x, y, false))
 
 	b.Line++
 	return nil
@@ -496,8 +503,8 @@ func (e *NotExpr) String() string {
 }
 
 func (e *NotExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeUint64})
+	v := b.pop(VmTypeUint64)
+	b.push(vmCompare(v, vmUint64(0), func(x, y uint64) bool { return x == y }))
 
 	b.Line++
 	return nil
@@ -533,7 +540,7 @@ func (e *ItobExpr) String() string {
 
 func (e *ItobExpr) Execute(b *VmBranch) error {
 	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeBytes})
+	b.push(VmValue{T: VmTypeBytes, src: vmFixedValue{n: 8, s: "itob"}})
 
 	b.Line++
 	return nil
@@ -1024,10 +1031,16 @@ func (e *TxnExpr) String() string {
 func (e *TxnExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	if v, ok := b.vm.Txn[e.Field]; ok {
+		b.push(vmUint64(v))
+		b.Line++
+		return nil
+	}
+
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -1064,10 +1077,10 @@ func (e *GlobalExpr) String() string {
 func (e *GlobalExpr) Execute(b *VmBranch) error {
 	spec, ok := globalFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmGlobalField(spec))
 
 	b.Line++
 	return nil
@@ -1124,10 +1137,20 @@ func (e *TxnaExpr) String() string {
 func (e *TxnaExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	if e.Field == ApplicationArgs {
+		if int(e.Index) < len(b.vm.Args) {
+			b.push(NewVmBytes(b.vm.Args[e.Index]))
+		} else {
+			b.push(VmValue{T: VmTypeBytes, src: vmUserValue{s: e.String()}})
+		}
+		b.Line++
+		return nil
+	}
+
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -1145,10 +1168,10 @@ func (e *GtxnExpr) String() string {
 func (e *GtxnExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -1167,11 +1190,11 @@ func (e *GtxnsExpr) String() string {
 func (e *GtxnsExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.pop(VmTypeUint64)
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -1264,7 +1287,7 @@ func (e *PushIntExpr) String() string {
 }
 
 func (e *PushIntExpr) Execute(b *VmBranch) error {
-	b.push(VmValue{T: VmTypeUint64})
+	b.push(vmUint64(e.Value))
 	b.Line++
 	return nil
 }
@@ -1335,7 +1358,8 @@ func (e *BoxGetExpr) String() string {
 }
 
 func (e *BoxGetExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessRead, key, VmValue{})
 	b.push(VmValue{T: VmTypeBytes})
 	b.push(VmValue{T: VmTypeUint64})
 	b.Line++
@@ -1352,8 +1376,9 @@ func (e *BoxPutExpr) String() string {
 }
 
 func (e *BoxPutExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
+	v := b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessWrite, key, v)
 	b.Line++
 	return nil
 }
@@ -1365,7 +1390,8 @@ type BoxCreateExpr struct {
 
 func (e *BoxCreateExpr) Execute(b *VmBranch) error {
 	b.pop(VmTypeUint64)
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessWrite, key, VmValue{T: VmTypeBytes})
 	b.push(VmValue{T: VmTypeUint64})
 	b.Line++
 	return nil
@@ -1379,7 +1405,8 @@ func (e *BoxLenExpr) String() string {
 }
 
 func (e *BoxLenExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessRead, key, VmValue{})
 	b.push(VmValue{T: VmTypeUint64})
 	b.push(VmValue{T: VmTypeUint64})
 	b.Line++
@@ -1396,7 +1423,8 @@ func (e *BoxDelExpr) String() string {
 }
 
 func (e *BoxDelExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessDelete, key, VmValue{})
 	b.push(VmValue{T: VmTypeUint64})
 	b.Line++
 	return nil
@@ -1420,9 +1448,10 @@ func (e *BoxReplaceExpr) String() string {
 }
 
 func (e *BoxReplaceExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	v := b.pop(VmTypeBytes)
 	b.pop(VmTypeUint64)
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessWrite, key, v)
 	b.Line++
 	return nil
 }
@@ -1439,7 +1468,8 @@ func (e *BoxExtractExpr) String() string {
 func (e *BoxExtractExpr) Execute(b *VmBranch) error {
 	b.pop(VmTypeUint64)
 	b.pop(VmTypeUint64)
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessBox, VmAccessRead, key, VmValue{})
 	b.push(VmValue{T: VmTypeBytes})
 	b.Line++
 	return nil
@@ -1455,6 +1485,26 @@ func (e *PragmaExpr) String() string {
 	return fmt.Sprintf("#pragma version %d", e.Version)
 }
 
+type TypeTrackExpr struct {
+	Enabled bool
+}
+
+func (e *TypeTrackExpr) IsNop() {}
+
+func (e *TypeTrackExpr) String() string {
+	return fmt.Sprintf("#pragma typetrack %t", e.Enabled)
+}
+
+type IncludeExpr struct {
+	Path string
+}
+
+func (e *IncludeExpr) IsNop() {}
+
+func (e *IncludeExpr) String() string {
+	return fmt.Sprintf("#include %s", strconv.Quote(e.Path))
+}
+
 type BnzExpr struct {
 	Label *LabelExpr
 }
@@ -1462,7 +1512,16 @@ type BnzExpr struct {
 func (e *BnzExpr) IsBranch() {}
 
 func (e *BnzExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
+	v := b.pop(VmTypeUint64)
+	if n, ok := v.Uint64(); ok {
+		if n != 0 {
+			b.jump(e.Label.Name)
+		} else {
+			b.Line++
+		}
+		return nil
+	}
+
 	b.fork(e.Label.Name)
 	b.Line++
 	return nil
@@ -1483,7 +1542,16 @@ type BzExpr struct {
 func (e *BzExpr) IsBranch() {}
 
 func (e *BzExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
+	v := b.pop(VmTypeUint64)
+	if n, ok := v.Uint64(); ok {
+		if n == 0 {
+			b.jump(e.Label.Name)
+		} else {
+			b.Line++
+		}
+		return nil
+	}
+
 	b.fork(e.Label.Name)
 	b.Line++
 	return nil
@@ -1534,8 +1602,9 @@ func (e *AppLocalGetExpr) String() string {
 }
 
 func (e *AppLocalGetExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
 	b.pop(VmTypeAny)
+	b.access(VmAccessLocal, VmAccessRead, key, VmValue{})
 	b.push(VmValue{T: VmTypeAny})
 	b.Line++
 	return nil
@@ -1550,9 +1619,10 @@ func (e *AppLocalPutExpr) String() string {
 }
 
 func (e *AppLocalPutExpr) Execute(b *VmBranch) error {
+	v := b.pop(VmTypeAny)
+	key := b.pop(VmTypeBytes)
 	b.pop(VmTypeAny)
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeAny)
+	b.access(VmAccessLocal, VmAccessWrite, key, v)
 	b.Line++
 	return nil
 }
@@ -1566,8 +1636,9 @@ func (e *AppGlobalPutExpr) String() string {
 }
 
 func (e *AppGlobalPutExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeAny)
-	b.pop(VmTypeBytes)
+	v := b.pop(VmTypeAny)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessGlobal, VmAccessWrite, key, v)
 	b.Line++
 	return nil
 }
@@ -1581,7 +1652,8 @@ func (e *AppGlobalGetExpr) String() string {
 }
 
 func (e *AppGlobalGetExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessGlobal, VmAccessRead, key, VmValue{})
 	b.push(VmValue{T: VmTypeAny})
 	b.Line++
 	return nil
@@ -1631,8 +1703,9 @@ func (e *AppLocalDelExpr) String() string {
 }
 
 func (e *AppLocalDelExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
 	b.pop(VmTypeAny)
+	b.access(VmAccessLocal, VmAccessDelete, key, VmValue{})
 	b.Line++
 	return nil
 }
@@ -1682,8 +1755,7 @@ type ReturnExpr struct {
 }
 
 func (e *ReturnExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.exit()
+	b.finish(b.pop(VmTypeUint64))
 	return nil
 }
 
@@ -1700,7 +1772,15 @@ type SwitchExpr struct {
 func (e *SwitchExpr) IsBranch() {}
 
 func (e *SwitchExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
+	v := b.pop(VmTypeUint64)
+	if n, ok := v.Uint64(); ok {
+		if n < uint64(len(e.Targets)) {
+			b.jump(e.Targets[n].Name)
+		} else {
+			b.Line++
+		}
+		return nil
+	}
 
 	for _, t := range e.Targets {
 		b.fork(t.Name)
@@ -1815,7 +1895,12 @@ func (e *AssertExpr) String() string {
 }
 
 func (e *AssertExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
+	v := b.pop(VmTypeUint64)
+	if n, ok := v.Uint64(); ok && n == 0 {
+		b.failWith(&AssertFailedError{Line: b.sourceLine(b.Line), PC: b.Line})
+		return nil
+	}
+
 	b.Line++
 	return nil
 }
@@ -1883,7 +1968,7 @@ func (e *DupNExpr) String() string {
 
 func (e *DupNExpr) Execute(b *VmBranch) error {
 	v := b.pop(VmTypeAny)
-	for i := uint8(0); i <= e.Count; i++ {
+	for i := 0; i < int(e.Count)+1; i++ {
 		b.push(v)
 	}
 	b.Line++
@@ -1943,7 +2028,7 @@ type Replace2Expr struct {
 }
 
 func (e *Replace2Expr) String() string {
-	return fmt.Sprintf("repace2 %d", e.Start)
+	return fmt.Sprintf("replace2 %d", e.Start)
 }
 
 func (e *Replace2Expr) Execute(b *VmBranch) error {
@@ -1992,7 +2077,7 @@ func (e *Base64DecodeExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *Base64DecodeExpr) String() string {
-	return fmt.Sprintf("base64_decode %d", e.Index)
+	return fmt.Sprintf("base64_decode %s", Base64Encoding(e.Index))
 }
 
 func (e *Base64DecodeExpr) Execute(b *VmBranch) error {
@@ -2023,7 +2108,7 @@ func (e *JsonRefExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *JsonRefExpr) String() string {
-	return fmt.Sprintf("json_ref %d", e.Index)
+	return fmt.Sprintf("json_ref %s", JSONRefType(e.Index))
 }
 
 func (e *JsonRefExpr) Execute(b *VmBranch) error {
@@ -2045,10 +2130,22 @@ func (e *Ed25519VerifyBareExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *Ed25519VerifyBareExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
+	pk := b.pop(VmTypeBytes)
+	sig := b.pop(VmTypeBytes)
+	data := b.pop(VmTypeBytes)
+
+	if bs, ok := vmKnownBytes(data, sig, pk); ok {
+		valid, ok := ed25519VerifyBare(bs[0], bs[1], bs[2])
+		if !ok {
+			b.failf("invalid signature or public key length")
+			return nil
+		}
+
+		b.push(vmBool(valid))
+	} else {
+		b.push(VmValue{T: VmTypeUint64})
+	}
+
 	b.Line++
 	return nil
 }
@@ -2112,10 +2209,10 @@ func (e *GtxnasExpr) Execute(b *VmBranch) error {
 
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2165,10 +2262,10 @@ func (e *ItxnasExpr) Execute(b *VmBranch) error {
 
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2188,10 +2285,10 @@ func (e *GitxnasExpr) Execute(b *VmBranch) error {
 
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2206,8 +2303,8 @@ type PushIntsExpr struct {
 }
 
 func (e *PushIntsExpr) Execute(b *VmBranch) error {
-	for range e.Ints {
-		b.push(VmValue{T: VmTypeBytes})
+	for _, v := range e.Ints {
+		b.push(vmUint64(v))
 	}
 	b.Line++
 	return nil
@@ -2220,8 +2317,7 @@ func (e *PushIntsExpr) String() string {
 		ss = append(ss, strconv.FormatUint(i, 10))
 	}
 
-	return fmt.Sprintf("pushbytess %s", strings.Join(ss, " "))
-
+	return fmt.Sprintf("pushints %s", strings.Join(ss, " "))
 }
 
 type MethodExpr struct {
@@ -2229,7 +2325,12 @@ type MethodExpr struct {
 }
 
 func (e *MethodExpr) String() string {
-	return fmt.Sprintf("method \"%s\"", strings.ReplaceAll(e.Signature, "\"", "\\\""))
+	// the parsed signature keeps its quotes
+	if strings.HasPrefix(e.Signature, "\"") {
+		return fmt.Sprintf("method %s", e.Signature)
+	}
+
+	return fmt.Sprintf("method %s", strconv.Quote(e.Signature))
 }
 
 func (e *MethodExpr) Execute(b *VmBranch) error {
@@ -2273,7 +2374,7 @@ type EcAddExpr struct {
 }
 
 func (e *EcAddExpr) String() string {
-	return fmt.Sprintf("ec_add %d", e.Group)
+	return fmt.Sprintf("ec_add %s", e.Group)
 }
 
 func (e *EcAddExpr) Cost(b *VmBranch) []int {
@@ -2292,10 +2393,11 @@ func (e *EcAddExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *EcAddExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+	vmEcBytes(b, []VmValue{x, y}, func(bs [][]byte) ([]byte, error) {
+		return ecBackend.Add(e.Group, bs[0], bs[1])
+	})
 	return nil
 }
 
@@ -2304,7 +2406,7 @@ type EcScalarMul struct {
 }
 
 func (e *EcScalarMul) String() string {
-	return fmt.Sprintf("ec_scalar_mul %d", e.Group)
+	return fmt.Sprintf("ec_scalar_mul %s", e.Group)
 }
 
 func (e *EcScalarMul) Cost(b *VmBranch) []int {
@@ -2323,10 +2425,11 @@ func (e *EcScalarMul) Cost(b *VmBranch) []int {
 }
 
 func (e *EcScalarMul) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	k := b.pop(VmTypeBytes)
+	p := b.pop(VmTypeBytes)
+	vmEcBytes(b, []VmValue{p, k}, func(bs [][]byte) ([]byte, error) {
+		return ecBackend.ScalarMul(e.Group, bs[0], bs[1])
+	})
 	return nil
 }
 
@@ -2335,7 +2438,7 @@ type EcPairingCheckExpr struct {
 }
 
 func (e *EcPairingCheckExpr) String() string {
-	return fmt.Sprintf("ec_pairing_check %d", e.Group)
+	return fmt.Sprintf("ec_pairing_check %s", e.Group)
 }
 
 func (e *EcPairingCheckExpr) Cost(b *VmBranch) []int {
@@ -2354,10 +2457,11 @@ func (e *EcPairingCheckExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *EcPairingCheckExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+	vmEcBool(b, []VmValue{x, y}, func(bs [][]byte) (bool, error) {
+		return ecBackend.PairingCheck(e.Group, bs[0], bs[1])
+	})
 	return nil
 }
 
@@ -2366,14 +2470,15 @@ type EcMultiExpExpr struct {
 }
 
 func (e *EcMultiExpExpr) String() string {
-	return fmt.Sprintf("ec_multi_exp %d", e.Group)
+	return fmt.Sprintf("ec_multi_exp %s", e.Group)
 }
 
 func (e *EcMultiExpExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	ks := b.pop(VmTypeBytes)
+	ps := b.pop(VmTypeBytes)
+	vmEcBytes(b, []VmValue{ps, ks}, func(bs [][]byte) ([]byte, error) {
+		return ecBackend.MultiExp(e.Group, bs[0], bs[1])
+	})
 	return nil
 }
 
@@ -2397,13 +2502,14 @@ type EcSubgroupCheckExpr struct {
 }
 
 func (e *EcSubgroupCheckExpr) String() string {
-	return fmt.Sprintf("ec_subgroup_check %d", e.Group)
+	return fmt.Sprintf("ec_subgroup_check %s", e.Group)
 }
 
 func (e *EcSubgroupCheckExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	p := b.pop(VmTypeBytes)
+	vmEcBool(b, []VmValue{p}, func(bs [][]byte) (bool, error) {
+		return ecBackend.SubgroupCheck(e.Group, bs[0])
+	})
 	return nil
 }
 
@@ -2427,13 +2533,14 @@ type EcMapToExpr struct {
 }
 
 func (e *EcMapToExpr) String() string {
-	return fmt.Sprintf("ec_map_to %d", e.Group)
+	return fmt.Sprintf("ec_map_to %s", e.Group)
 }
 
 func (e *EcMapToExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	f := b.pop(VmTypeBytes)
+	vmEcBytes(b, []VmValue{f}, func(bs [][]byte) ([]byte, error) {
+		return ecBackend.MapTo(e.Group, bs[0])
+	})
 	return nil
 }
 
@@ -2586,7 +2693,7 @@ func (e *AssetParamsGetExpr) Execute(b *VmBranch) error {
 
 	spec, ok := assetParamsFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.push(VmValue{T: spec.Type().Vm()})
@@ -2603,9 +2710,15 @@ func (e *ConcatExpr) String() string {
 }
 
 func (e *ConcatExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	y := b.pop(VmTypeBytes)
+	x := b.pop(VmTypeBytes)
+
+	if n := vmMinLength(x) + vmMinLength(y); n > MaxStringSize {
+		b.failf("concat result exceeds %d bytes", MaxStringSize)
+		return nil
+	}
+
+	b.push(vmConcat(x, y))
 	b.Line++
 	return nil
 }
@@ -2635,10 +2748,10 @@ type ItxnExpr struct {
 func (e *ItxnExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2708,10 +2821,10 @@ func (e *GtxnaExpr) String() string {
 func (e *GtxnaExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2729,11 +2842,11 @@ func (e *GtxnsaExpr) String() string {
 func (e *GtxnsaExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.pop(VmTypeUint64)
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2815,10 +2928,16 @@ func (e *TxnasExpr) Execute(b *VmBranch) error {
 
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	if e.Field == ApplicationArgs {
+		b.push(VmValue{T: VmTypeBytes, src: vmUserValue{s: e.String()}})
+		b.Line++
+		return nil
+	}
+
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -2873,7 +2992,7 @@ func (e *AppParamsGetExpr) Execute(b *VmBranch) error {
 
 	spec, ok := appParamsFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.push(VmValue{T: spec.Type().Vm()})
@@ -2896,7 +3015,7 @@ func (e *AcctParamsGetExpr) Execute(b *VmBranch) error {
 
 	spec, ok := acctParamsFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.push(VmValue{T: spec.Type().Vm()})
@@ -2913,7 +3032,7 @@ func (e *LogExpr) String() string {
 }
 
 func (e *LogExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	b.Logs = append(b.Logs, b.pop(VmTypeBytes))
 	b.Line++
 	return nil
 }
@@ -2929,7 +3048,7 @@ func (e *BlockExpr) Execute(b *VmBranch) error {
 
 	spec, ok := blockFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.push(VmValue{T: spec.Type().Vm()})
@@ -3003,7 +3122,7 @@ func (e *AssetHoldingGetExpr) Execute(b *VmBranch) error {
 
 	spec, ok := assetHoldingFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
 	b.push(VmValue{T: spec.Type().Vm()})
@@ -3048,10 +3167,10 @@ func (e *GitxnExpr) String() string {
 func (e *GitxnExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -3173,10 +3292,10 @@ func (e *GtxnsasExpr) Execute(b *VmBranch) error {
 
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -3211,6 +3330,11 @@ func (e *BminusExpr) Cost(b *VmBranch) []int {
 	return []int{10}
 }
 
+func (e *BminusExpr) Execute(b *VmBranch) error {
+	vmByteMath(b, bytesMinus)
+	return nil
+}
+
 var BytesMinus = &BminusExpr{}
 
 type BmulExpr struct{}
@@ -3224,10 +3348,7 @@ func (e *BmulExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BmulExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteMath(b, bytesMul)
 	return nil
 }
 
@@ -3244,10 +3365,7 @@ func (e *BdivExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BdivExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteMath(b, bytesDiv)
 	return nil
 }
 
@@ -3264,10 +3382,7 @@ func (e *BplusExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BplusExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteMath(b, bytesPlus)
 	return nil
 }
 
@@ -3426,10 +3541,7 @@ func (e *BGtExpr) String() string {
 }
 
 func (e *BGtExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c > 0 })
 	return nil
 }
 
@@ -3442,10 +3554,7 @@ func (e *BytesLeExpr) String() string {
 }
 
 func (e *BytesLeExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c <= 0 })
 	return nil
 }
 
@@ -3458,10 +3567,7 @@ func (e *BytesGeExpr) String() string {
 }
 
 func (e *BytesGeExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c >= 0 })
 	return nil
 }
 
@@ -3474,10 +3580,7 @@ func (e *BytesEqExpr) String() string {
 }
 
 func (e *BytesEqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c == 0 })
 	return nil
 }
 
@@ -3490,10 +3593,7 @@ func (e *BytesNeqExpr) String() string {
 }
 
 func (e *BytesNeqExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c != 0 })
 	return nil
 }
 
@@ -3510,10 +3610,7 @@ func (e *BytesModuloExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BytesModuloExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteMath(b, bytesModulo)
 	return nil
 }
 
@@ -3529,6 +3626,11 @@ func (e *BytesBitOrExpr) Cost(b *VmBranch) []int {
 	return []int{6}
 }
 
+func (e *BytesBitOrExpr) Execute(b *VmBranch) error {
+	vmByteBitwise(b, func(x, y byte) byte { return x | y })
+	return nil
+}
+
 var BytesBitOr = &BytesBitOrExpr{}
 
 type BytesBitAndExpr struct{}
@@ -3542,10 +3644,7 @@ func (e *BytesBitAndExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BytesBitAndExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteBitwise(b, func(x, y byte) byte { return x & y })
 	return nil
 }
 
@@ -3562,8 +3661,19 @@ func (e *BsqrtExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BsqrtExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeBytes)
+
+	if bs, ok := v.Bytes(); ok {
+		if len(bs) > MaxByteMathSize {
+			b.failf("bsqrt operand exceeds %d bytes", MaxByteMathSize)
+			return nil
+		}
+
+		b.push(NewVmBytes(new(big.Int).Sqrt(new(big.Int).SetBytes(bs)).Bytes()))
+	} else {
+		b.push(VmValue{T: VmTypeBytes})
+	}
+
 	b.Line++
 	return nil
 }
@@ -3583,10 +3693,10 @@ func (e *GitxnaExpr) String() string {
 func (e *GitxnaExpr) Execute(b *VmBranch) error {
 	spec, ok := txnFieldSpecByField(e.Field)
 	if !ok {
-		panic("unknown field")
+		panic(b.invalidField(e.Field))
 	}
 
-	b.push(VmValue{T: spec.Type().Vm()})
+	b.push(vmTxnField(spec))
 
 	b.Line++
 	return nil
@@ -3599,7 +3709,8 @@ func (e *AppGlobalDelExpr) String() string {
 }
 
 func (e *AppGlobalDelExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
+	key := b.pop(VmTypeBytes)
+	b.access(VmAccessGlobal, VmAccessDelete, key, VmValue{})
 	b.Line++
 	return nil
 }
@@ -3613,10 +3724,7 @@ func (e *BltExpr) String() string {
 }
 
 func (e *BltExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeUint64})
-	b.Line++
+	vmByteCompare(b, func(c int) bool { return c < 0 })
 	return nil
 }
 
@@ -3641,10 +3749,7 @@ func (e *BytesBitXorExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BytesBitXorExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
-	b.Line++
+	vmByteBitwise(b, func(x, y byte) byte { return x ^ y })
 	return nil
 }
 
@@ -3661,8 +3766,19 @@ func (e *BytesBitNotExpr) Cost(b *VmBranch) []int {
 }
 
 func (e *BytesBitNotExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeBytes)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeBytes)
+
+	if bs, ok := v.Bytes(); ok {
+		res := make([]byte, len(bs))
+		for i, x := range bs {
+			res[i] = ^x
+		}
+
+		b.push(NewVmBytes(res))
+	} else {
+		b.push(VmValue{T: VmTypeBytes})
+	}
+
 	b.Line++
 	return nil
 }
@@ -3676,8 +3792,19 @@ func (e *BytesZeroExpr) String() string {
 }
 
 func (e *BytesZeroExpr) Execute(b *VmBranch) error {
-	b.pop(VmTypeUint64)
-	b.push(VmValue{T: VmTypeBytes})
+	v := b.pop(VmTypeUint64)
+
+	if n, ok := v.Uint64(); ok {
+		if n > MaxStringSize {
+			b.failf("bzero length exceeds %d bytes", MaxStringSize)
+			return nil
+		}
+
+		b.push(NewVmBytes(make([]byte, n)))
+	} else {
+		b.push(VmValue{T: VmTypeBytes})
+	}
+
 	b.Line++
 	return nil
 }
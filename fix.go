@@ -0,0 +1,317 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// FixRemoveLine removes a redundant line
+	FixRemoveLine = "remove-line"
+
+	// FixCreateLabel creates a missing label at the end of the program
+	FixCreateLabel = "create-label"
+
+	// FixNamedValue replaces a numeric immediate with its name
+	FixNamedValue = "named-value"
+
+	// FixLiteral replaces a hex encoded bytes constant with its string literal
+	FixLiteral = "literal"
+
+	// FixDeprecated replaces a deprecated construct
+	FixDeprecated = "deprecated"
+
+	// FixVersion updates the program version to the one required by the ops
+	FixVersion = "version"
+
+	// FixWideMath rewrites a*b/c with the wide math ops
+	FixWideMath = "wide-math"
+
+	// FixProto removes an unused arg or result from the subroutine proto and its callsites
+	FixProto = "proto"
+
+	// FixMethodSignature replaces the method signature with its canonical form
+	FixMethodSignature = "method-signature"
+
+	// FixImmediateForm replaces a stack form op with its constant args with the immediate form
+	FixImmediateForm = "immediate-form"
+
+	// FixGtxnLoop rewrites an unrolled gtxn sequence as a gtxns loop, offered with WithExperimentalFixes
+	FixGtxnLoop = "gtxn-loop"
+
+	// FixNumAppArgs asserts txn NumAppArgs covers the application args read by the program
+	FixNumAppArgs = "num-app-args"
+)
+
+// FixPosition is a zero-based line and character of the source.
+type FixPosition struct {
+	Line      int
+	Character int
+}
+
+// FixEdit replaces the text between the start and the end position, the end is exclusive.
+type FixEdit struct {
+	Start FixPosition
+	End   FixPosition
+
+	NewText string
+}
+
+func tokenEdit(t Range, s string) FixEdit {
+	return FixEdit{
+		Start:   FixPosition{Line: t.StartLine(), Character: t.StartCharacter()},
+		End:     FixPosition{Line: t.EndLine(), Character: t.EndCharacter()},
+		NewText: s,
+	}
+}
+
+// FixAction is a quick fix of the program.
+type FixAction struct {
+	Title string
+	Kind  string
+	Edits []FixEdit
+}
+
+func inLines(rg Range, line int) bool {
+	return rg.StartLine() <= line && rg.EndLine() >= line
+}
+
+// CodeActions returns the quick fixes available in the range.
+func (r ProcessResult) CodeActions(rg Range) []FixAction {
+	var fas []FixAction
+
+	for _, red := range r.Redundants {
+		if !inLines(rg, red.Line()) {
+			continue
+		}
+
+		fas = append(fas, FixAction{
+			Title: red.String(),
+			Kind:  FixRemoveLine,
+			Edits: []FixEdit{
+				{
+					Start: FixPosition{Line: red.Line()},
+					End:   FixPosition{Line: red.Line() + 1},
+				},
+			},
+		})
+	}
+
+	for _, ref := range r.MissRefs {
+		if !Overlaps(rg, ref) {
+			continue
+		}
+
+		fas = append(fas, FixAction{
+			Title: fmt.Sprintf("Create label '%s'", ref.String()),
+			Kind:  FixCreateLabel,
+			Edits: []FixEdit{
+				{
+					Start:   FixPosition{Line: len(r.Lines)},
+					End:     FixPosition{Line: len(r.Lines)},
+					NewText: fmt.Sprintf("%s%s:%s", r.Eol, ref.String(), r.Eol),
+				},
+			},
+		})
+	}
+
+	hs := r.InlayHints(rg)
+
+	for _, named := range hs.Named {
+		fas = append(fas, FixAction{
+			Title: fmt.Sprintf("Replace with '%s'", named.Name),
+			Kind:  FixNamedValue,
+			Edits: []FixEdit{tokenEdit(named.T, named.Name)},
+		})
+	}
+
+	for _, named := range hs.Decoded {
+		fas = append(fas, FixAction{
+			Title: fmt.Sprintf("Replace with literal '%s'", named.Value),
+			Kind:  FixLiteral,
+			Edits: []FixEdit{tokenEdit(named.T, fmt.Sprintf("\"%s\"", strings.ReplaceAll(named.Value, "\"", "\\\"")))},
+		})
+	}
+
+	for _, d := range r.Deprecations() {
+		if d.Replacement == "" || !inLines(rg, d.Line) {
+			continue
+		}
+
+		fas = append(fas, FixAction{
+			Title: fmt.Sprintf("Replace with '%s'", d.Replacement),
+			Kind:  FixDeprecated,
+			Edits: []FixEdit{
+				{
+					Start:   FixPosition{Line: d.Line, Character: d.Begin},
+					End:     FixPosition{Line: d.Line, Character: d.End},
+					NewText: d.Replacement,
+				},
+			},
+		})
+	}
+
+	for _, v := range r.Versions {
+		if !Overlaps(rg, v) {
+			continue
+		}
+
+		fas = append(fas, FixAction{
+			Title: fmt.Sprintf("Update version to %d", v.Version),
+			Kind:  FixVersion,
+			Edits: []FixEdit{r.versionEdit(v.Version)},
+		})
+	}
+
+	for _, w := range r.WideMath() {
+		if w.Div == 0 || w.Div >= len(r.Sublines) || !inLines(rg, r.Sublines[w.Index].Line) {
+			continue
+		}
+
+		fas = append(fas, r.wideMathFix(w))
+	}
+
+	if us := r.UnusedFrames(); len(us) > 0 {
+		names := r.FrameNames()
+
+		for _, u := range us {
+			if !inLines(rg, r.SourceLine(u.Proto)) {
+				continue
+			}
+
+			if fa, ok := r.unusedFrameFix(u, names); ok {
+				fas = append(fas, fa)
+			}
+		}
+	}
+
+	for _, ms := range r.MethodSignatures() {
+		if ms.Canonical == ms.Signature || !inLines(rg, ms.Line) {
+			continue
+		}
+
+		fas = append(fas, ms.fix())
+	}
+
+	for _, c := range r.ConstantStackArgs() {
+		if !inLines(rg, r.Sublines[c.Index].Line) {
+			continue
+		}
+
+		fas = append(fas, r.constantStackArgsFix(c))
+	}
+
+	for _, u := range r.UncheckedAppArgs() {
+		if !inLines(rg, r.Sublines[u.Index].Line) {
+			continue
+		}
+
+		fas = append(fas, r.numAppArgsFix(u))
+	}
+
+	if r.ExperimentalFixes {
+		for _, c := range r.GtxnChains() {
+			if !inLines(rg, r.Sublines[c.Index].Line) {
+				continue
+			}
+
+			if fa, ok := r.gtxnLoopFix(c); ok {
+				fas = append(fas, fa)
+			}
+		}
+	}
+
+	return fas
+}
+
+// versionEdit replaces the #pragma version value or inserts the pragma at the beginning.
+func (r ProcessResult) versionEdit(version uint64) FixEdit {
+	if r.VersionToken != nil {
+		return tokenEdit(r.VersionToken, fmt.Sprintf("%d", version))
+	}
+
+	return FixEdit{
+		NewText: fmt.Sprintf("#pragma version %d%s", version, r.Eol),
+	}
+}
+
+// SafeFixes are the mechanical fixes that do not change the program logic and can be applied without a review.
+var SafeFixes = []string{
+	FixVersion,
+	FixRemoveLine,
+	FixNamedValue,
+}
+
+type fixSpan struct {
+	begin int
+	end   int
+	text  string
+}
+
+func (s fixSpan) overlaps(o fixSpan) bool {
+	if s.begin == s.end || o.begin == o.end {
+		return s.begin <= o.end && o.begin <= s.end
+	}
+
+	return s.begin < o.end && o.begin < s.end
+}
+
+// ApplyFixes applies the fixes to the source in order, skipping the ones with edits overlapping the already applied fixes.
+// It returns the fixed source and the applied fixes.
+func ApplyFixes(source string, fas []FixAction) (string, []FixAction) {
+	starts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+
+	offset := func(p FixPosition) int {
+		if p.Line >= len(starts) {
+			return len(source)
+		}
+
+		o := starts[p.Line] + p.Character
+		if o > len(source) {
+			o = len(source)
+		}
+
+		return o
+	}
+
+	var spans []fixSpan
+	var applied []FixAction
+
+	for _, fa := range fas {
+		var ss []fixSpan
+		ok := true
+
+		for _, e := range fa.Edits {
+			s := fixSpan{begin: offset(e.Start), end: offset(e.End), text: e.NewText}
+			for _, prev := range spans {
+				if s.overlaps(prev) {
+					ok = false
+				}
+			}
+			ss = append(ss, s)
+		}
+
+		if !ok {
+			continue
+		}
+
+		spans = append(spans, ss...)
+		applied = append(applied, fa)
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].begin > spans[j].begin
+	})
+
+	for _, s := range spans {
+		source = source[:s.begin] + s.text + source[s.end:]
+	}
+
+	return source, applied
+}
@@ -0,0 +1,76 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestCodeActions(t *testing.T) {
+	res := Process("#pragma version 2\nunused:\nb missing\nint 1\nbox_create\nreturn\n")
+
+	kinds := map[string]FixAction{}
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+		kinds[fa.Kind] = fa
+	}
+
+	rm, ok := kinds[FixRemoveLine]
+	if !ok {
+		t.Fatalf("missing remove line fix: %v", kinds)
+	}
+	if rm.Edits[0].Start.Line != 1 || rm.Edits[0].End.Line != 2 {
+		t.Errorf("unexpected remove line edit: %+v", rm.Edits[0])
+	}
+
+	cl, ok := kinds[FixCreateLabel]
+	if !ok {
+		t.Fatalf("missing create label fix: %v", kinds)
+	}
+	if cl.Edits[0].NewText != "\nmissing:\n" {
+		t.Errorf("unexpected create label edit: %+v", cl.Edits[0])
+	}
+
+	v, ok := kinds[FixVersion]
+	if !ok {
+		t.Fatalf("missing version fix: %v", kinds)
+	}
+	if v.Edits[0].NewText != "8" || v.Edits[0].Start.Line != 0 {
+		t.Errorf("unexpected version edit: %+v", v.Edits[0])
+	}
+
+	if fas := res.CodeActions(LinesRange{Start: 3, End: 3}); len(fas) != 0 {
+		t.Errorf("unexpected fixes outside of the range: %v", fas)
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	src := "#pragma version 2\nunused:\nint 1\nbox_create\nreturn\n"
+	res := Process(src)
+
+	fas := res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)})
+
+	fixed, applied := ApplyFixes(src, fas)
+	if len(applied) != 2 {
+		t.Fatalf("unexpected applied fixes: %v", applied)
+	}
+
+	expected := "#pragma version 8\nint 1\nbox_create\nreturn\n"
+	if fixed != expected {
+		t.Errorf("unexpected fixed source: %q, expected: %q", fixed, expected)
+	}
+
+	edit := func(line int, begin int, end int, s string) FixAction {
+		return FixAction{
+			Edits: []FixEdit{
+				{
+					Start:   FixPosition{Line: line, Character: begin},
+					End:     FixPosition{Line: line, Character: end},
+					NewText: s,
+				},
+			},
+		}
+	}
+
+	fixed, applied = ApplyFixes("int 1\n", []FixAction{edit(0, 4, 5, "2"), edit(0, 0, 5, "pushint 3"), edit(0, 0, 3, "pushint")})
+	if len(applied) != 2 || fixed != "pushint 2\n" {
+		t.Errorf("unexpected overlapping fixes result: %q, applied: %v", fixed, applied)
+	}
+}
@@ -0,0 +1,235 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpForm pairs an op taking constant immediates with the op taking the same values from the stack.
+type OpForm struct {
+	Immediate string
+	Stack     string
+
+	// Args are the immediates of the immediate form the stack form pops, the top one last
+	Args []string
+
+	// ArgsFirst is set when the Args precede the immediates kept by the stack form, e.g. t of gtxn t f
+	ArgsFirst bool
+
+	// Depth is the number of the stack values above the Args, e.g. the replacement of replace3
+	Depth int
+}
+
+// opForms are the ops with both the immediate and the stack form.
+var opForms = []OpForm{
+	{Immediate: "extract", Stack: "extract3", Args: []string{"s", "l"}},
+	{Immediate: "substring", Stack: "substring3", Args: []string{"s", "e"}},
+	{Immediate: "replace2", Stack: "replace3", Args: []string{"s"}, Depth: 1},
+	{Immediate: "load", Stack: "loads", Args: []string{"i"}},
+	{Immediate: "store", Stack: "stores", Args: []string{"i"}, Depth: 1},
+	{Immediate: "arg", Stack: "args", Args: []string{"index"}},
+	{Immediate: "txna", Stack: "txnas", Args: []string{"i"}},
+	{Immediate: "gtxn", Stack: "gtxns", Args: []string{"t"}, ArgsFirst: true},
+	{Immediate: "gtxna", Stack: "gtxnsa", Args: []string{"t"}, ArgsFirst: true},
+	{Immediate: "gtxna", Stack: "gtxnas", Args: []string{"i"}},
+	{Immediate: "gtxnsa", Stack: "gtxnsas", Args: []string{"i"}},
+	{Immediate: "itxna", Stack: "itxnas", Args: []string{"i"}},
+	{Immediate: "gitxna", Stack: "gitxnas", Args: []string{"i"}},
+	{Immediate: "gload", Stack: "gloads", Args: []string{"t"}, ArgsFirst: true},
+	{Immediate: "gaid", Stack: "gaids", Args: []string{"t"}, ArgsFirst: true},
+}
+
+// formsDoc describes the other forms of the op for the hover.
+func formsDoc(name string) string {
+	var lines []string
+
+	for _, f := range opForms {
+		switch name {
+		case f.Immediate:
+			lines = append(lines, fmt.Sprintf("Stack form: `%s` takes %s from the stack.", f.Stack, strings.Join(f.Args, ", ")))
+		case f.Stack:
+			lines = append(lines, fmt.Sprintf("Immediate form: `%s` takes constant %s as immediates, saving the pushes.", f.Immediate, strings.Join(f.Args, ", ")))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// opFormsOf returns the immediate and the stack form names of the op.
+func opFormsOf(name string) (string, string) {
+	var imm []string
+	var stack []string
+
+	for _, f := range opForms {
+		switch name {
+		case f.Immediate:
+			stack = append(stack, f.Stack)
+		case f.Stack:
+			imm = append(imm, f.Immediate)
+		}
+	}
+
+	return strings.Join(imm, ","), strings.Join(stack, ",")
+}
+
+// ConstantStackArgs is a stack form op whose args are pushed as constants right before it.
+type ConstantStackArgs struct {
+	// Index is the listing index of the op, the constants are at the preceding indexes
+	Index int
+
+	Form   OpForm
+	Values []uint64
+
+	// Replacement is the immediate form of the op
+	Replacement string
+}
+
+// ConstantStackArgs returns the stack form ops that can take their constant args as immediates.
+func (r ProcessResult) ConstantStackArgs() []ConstantStackArgs {
+	var res []ConstantStackArgs
+
+	for i, op := range r.Listing {
+		if i >= len(r.Sublines) {
+			break
+		}
+
+		fs := strings.Fields(op.String())
+		if len(fs) == 0 {
+			continue
+		}
+
+	forms:
+		for _, f := range opForms {
+			if f.Stack != fs[0] || f.Depth > 0 {
+				continue
+			}
+
+			n := len(f.Args)
+			if i < n {
+				continue
+			}
+
+			values := make([]uint64, n)
+			for k := 0; k < n; k++ {
+				v, ok := constUint(r.Listing[i-n+k])
+				if !ok || v > 255 {
+					continue forms
+				}
+				values[k] = v
+			}
+
+			var args []string
+			for _, v := range values {
+				args = append(args, fmt.Sprintf("%d", v))
+			}
+
+			imms := fs[1:]
+			if f.ArgsFirst {
+				imms = append(args, imms...)
+			} else {
+				imms = append(append([]string{}, imms...), args...)
+			}
+
+			res = append(res, ConstantStackArgs{
+				Index:       i,
+				Form:        f,
+				Values:      values,
+				Replacement: strings.Join(append([]string{f.Immediate}, imms...), " "),
+			})
+
+			break
+		}
+	}
+
+	return res
+}
+
+// codeEnd returns the end of the subline tokens before the comment.
+func codeEnd(sub Subline) int {
+	e := sub.Tokens.Begin()
+	for _, t := range sub.Tokens {
+		if t.Type() == TokenComment {
+			break
+		}
+		e = t.End()
+	}
+
+	return e
+}
+
+// constantStackArgsFix removes the constant pushes and replaces the op with its immediate form.
+func (r ProcessResult) constantStackArgsFix(c ConstantStackArgs) FixAction {
+	counts := map[int]int{}
+	for _, sub := range r.Sublines {
+		counts[sub.Line]++
+	}
+
+	var edits []FixEdit
+
+	for k := c.Index - len(c.Values); k < c.Index; k++ {
+		sub := r.Sublines[k]
+
+		if counts[sub.Line] == 1 {
+			edits = append(edits, FixEdit{
+				Start: FixPosition{Line: sub.Line},
+				End:   FixPosition{Line: sub.Line + 1},
+			})
+			continue
+		}
+
+		// the separator goes with the push when the next op is on the same line
+		e := codeEnd(sub)
+		if next := r.Sublines[k+1]; next.Line == sub.Line {
+			e = next.Tokens.Begin()
+		}
+
+		edits = append(edits, FixEdit{
+			Start: FixPosition{Line: sub.Line, Character: sub.Tokens.Begin()},
+			End:   FixPosition{Line: sub.Line, Character: e},
+		})
+	}
+
+	sub := r.Sublines[c.Index]
+	edits = append(edits, FixEdit{
+		Start:   FixPosition{Line: sub.Line, Character: sub.Tokens.Begin()},
+		End:     FixPosition{Line: sub.Line, Character: codeEnd(sub)},
+		NewText: c.Replacement,
+	})
+
+	return FixAction{
+		Title: fmt.Sprintf("Replace with '%s'", c.Replacement),
+		Kind:  FixImmediateForm,
+		Edits: edits,
+	}
+}
+
+type ImmediateFormRule struct{}
+
+func (r ImmediateFormRule) Id() string {
+	return "LINT0024"
+}
+
+func (r ImmediateFormRule) Desc() string {
+	return "Suggests the immediate form of the ops taking constant args from the stack"
+}
+
+var ImmediateFormRuleInstance = ImmediateFormRule{}
+
+func checkImmediateForms(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, c := range res.ConstantStackArgs() {
+		sub := res.Sublines[c.Index]
+
+		diags = append(diags, lintError{
+			error: fmt.Errorf("%s takes constant %s from the stack - use %s", c.Form.Stack, strings.Join(c.Form.Args, ", "), c.Replacement),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     codeEnd(sub),
+			s:     DiagHint,
+			r:     ImmediateFormRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,94 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstantStackArgs(t *testing.T) {
+	res := Process(`#pragma version 8
+txna ApplicationArgs 0
+int 0
+int 4
+extract3
+int 2
+gtxns Amount
+int 1
+txnas Accounts
+txn NumAppArgs
+txnas ApplicationArgs
+int 300
+loads
+byte "a"
+int 1
+byte "b"
+replace3
+int 1
+return`)
+
+	cs := res.ConstantStackArgs()
+
+	expected := []string{"extract 0 4", "gtxn 2 Amount", "txna Accounts 1"}
+	if len(cs) != len(expected) {
+		t.Fatalf("unexpected ops: %v", cs)
+	}
+
+	for i, e := range expected {
+		if cs[i].Replacement != e {
+			t.Errorf("expected %s, got: %s", e, cs[i].Replacement)
+		}
+	}
+
+	n := 0
+	for _, d := range res.Diagnostics {
+		if d.Rule() == ImmediateFormRuleInstance.Id() {
+			n++
+		}
+	}
+
+	if n != len(expected) {
+		t.Errorf("expected %d hints, got: %d", len(expected), n)
+	}
+}
+
+func TestConstantStackArgsFix(t *testing.T) {
+	src := "#pragma version 8\ntxna ApplicationArgs 0\nint 0\nint 4 // len\nextract3 // body\nint 1; txnas Accounts\nreturn"
+
+	res := Process(src)
+
+	var fas []FixAction
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+		if fa.Kind == FixImmediateForm {
+			fas = append(fas, fa)
+		}
+	}
+
+	if len(fas) != 2 {
+		t.Fatalf("expected 2 fixes, got: %v", fas)
+	}
+
+	fixed, _ := ApplyFixes(src, fas)
+
+	expected := "#pragma version 8\ntxna ApplicationArgs 0\nextract 0 4 // body\ntxna Accounts 1\nreturn"
+	if fixed != expected {
+		t.Fatalf("expected: %q, got: %q", expected, fixed)
+	}
+}
+
+func TestFormsDoc(t *testing.T) {
+	res := Process("#pragma version 8\nextract3\ntxna ApplicationArgs 0")
+
+	if doc := res.DocAt(1, 0); !strings.Contains(doc, "Immediate form: `extract`") {
+		t.Errorf("unexpected doc: %s", doc)
+	}
+
+	if doc := res.DocAt(2, 0); !strings.Contains(doc, "Stack form: `txnas` takes i from the stack") {
+		t.Errorf("unexpected doc: %s", doc)
+	}
+
+	for _, s := range AvailableOps(8, ModeApp) {
+		if s.Name == "gtxna" && s.StackForm != "gtxnsa,gtxnas" {
+			t.Errorf("unexpected stack forms: %s", s.StackForm)
+		}
+	}
+}
@@ -0,0 +1,674 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// UnusedFrame is a subroutine arg that is never read or a result that is never written before retsub.
+type UnusedFrame struct {
+	// Label is the subroutine label
+	Label string
+
+	// Proto is the listing index of the proto op
+	Proto int
+
+	// Result is set for the results, the args otherwise
+	Result bool
+
+	// Index is the zero-based position of the arg or the result in the proto order
+	Index int
+
+	// Default is the listing index of the constant the unwritten result is allocated with, -1 if unknown
+	Default int
+
+	// frame ops and retsubs of the subroutine body, used by the fix
+	ops []int
+
+	// written is set for the args that are buried to without being read
+	written bool
+}
+
+// the tags of the stack values above the frame: the listing index of the constant the local is
+// allocated with in the subroutine prologue, frameMixed for the locals allocated with different
+// constants on different paths and frameValue for the computed values
+const (
+	frameValue = -1
+	frameMixed = -2
+)
+
+func joinFrameTag(a, b int) int {
+	if a == b {
+		return a
+	}
+
+	if a == frameValue || b == frameValue {
+		return frameValue
+	}
+
+	return frameMixed
+}
+
+// isConstPush reports whether the op pushes a single constant.
+func isConstPush(op Op) bool {
+	switch op.(type) {
+	case *IntExpr, *ByteExpr, *PushIntExpr, *PushBytesExpr, *AddrExpr, *MethodExpr,
+		*IntcExpr, *Intc0Expr, *Intc1Expr, *Intc2Expr, *Intc3Expr,
+		*BytecExpr, *Bytec0Expr, *Bytec1Expr, *Bytec2Expr, *Bytec3Expr:
+		return true
+	}
+
+	return false
+}
+
+// subroutineProtos returns the labels of the subroutines starting with proto keyed by the proto listing index.
+func (r ProcessResult) subroutineProtos() map[int][]string {
+	res := map[int][]string{}
+
+	for i, op := range r.Listing {
+		l, ok := op.(*LabelExpr)
+		if !ok {
+			continue
+		}
+
+		for j := i + 1; j < len(r.Listing); j++ {
+			if _, ok := r.Listing[j].(Nop); ok {
+				continue
+			}
+
+			if _, ok := r.Listing[j].(*ProtoExpr); ok {
+				res[j] = append(res[j], l.Name)
+			}
+
+			break
+		}
+	}
+
+	return res
+}
+
+// frameUsage is the data flow of the values above the frame of a single subroutine.
+type frameUsage struct {
+	r      *ProcessResult
+	labels map[string]int
+
+	proto *ProtoExpr
+
+	// prologue are the listing indexes of the constants allocating the locals after the proto
+	prologue map[int]bool
+
+	states  [][]int
+	visited []bool
+	todo    []int
+
+	// reads and writes of the args by the frame ops
+	reads  map[int]bool
+	writes map[int]bool
+
+	// results are the tags of the results joined over the retsubs
+	results []int
+	retsubs int
+
+	ops  []int
+	seen map[int]bool
+
+	err error
+}
+
+func (u *frameUsage) op(i int) {
+	if !u.seen[i] {
+		u.seen[i] = true
+		u.ops = append(u.ops, i)
+	}
+}
+
+func (u *frameUsage) flow(i int, st []int) {
+	if i >= len(u.states) {
+		return
+	}
+
+	if !u.visited[i] {
+		u.visited[i] = true
+		u.states[i] = append([]int{}, st...)
+		u.todo = append(u.todo, i)
+		return
+	}
+
+	prev := u.states[i]
+	if len(prev) != len(st) {
+		u.err = errors.Errorf("inconsistent stack depth at %d", i)
+		return
+	}
+
+	changed := false
+	for k := range prev {
+		t := joinFrameTag(prev[k], st[k])
+		if t != prev[k] {
+			prev[k] = t
+			changed = true
+		}
+	}
+
+	if changed {
+		u.todo = append(u.todo, i)
+	}
+}
+
+func (u *frameUsage) target(l *LabelExpr) (int, bool) {
+	i, ok := u.labels[l.Name]
+	return i, ok
+}
+
+// step applies the op to the stack, returning false for the ops that do not continue to the next one.
+func (u *frameUsage) step(i int, st []int) ([]int, bool) {
+	op := u.r.Listing[i]
+	args := int(u.proto.Args)
+
+	switch op := op.(type) {
+	case *FrameDigExpr:
+		u.op(i)
+
+		idx := int(op.Index)
+		if idx < 0 {
+			if args+idx >= 0 {
+				u.reads[args+idx] = true
+			}
+			return append(st, frameValue), true
+		}
+
+		if idx >= len(st) {
+			u.err = errors.Errorf("frame_dig %d above the stack", idx)
+			return nil, false
+		}
+
+		return append(st, st[idx]), true
+
+	case *FrameBuryExpr:
+		u.op(i)
+
+		if len(st) == 0 {
+			u.err = errors.New("frame_bury below the frame")
+			return nil, false
+		}
+
+		t := st[len(st)-1]
+		st = st[:len(st)-1]
+
+		idx := int(op.Index)
+		if idx < 0 {
+			if args+idx >= 0 {
+				u.writes[args+idx] = true
+			}
+			return st, true
+		}
+
+		if idx >= len(st) {
+			u.err = errors.Errorf("frame_bury %d above the stack", idx)
+			return nil, false
+		}
+
+		st[idx] = t
+
+		return st, true
+
+	case *CallSubExpr:
+		j, ok := u.target(op.Label)
+		if !ok {
+			u.err = errors.Errorf("missing label: %s", op.Label.Name)
+			return nil, false
+		}
+
+		var callee *ProtoExpr
+		for ; j < len(u.r.Listing); j++ {
+			if _, ok := u.r.Listing[j].(Nop); ok {
+				continue
+			}
+			callee, _ = u.r.Listing[j].(*ProtoExpr)
+			break
+		}
+
+		if callee == nil {
+			u.err = errors.Errorf("unknown effect of the call to %s", op.Label.Name)
+			return nil, false
+		}
+
+		if int(callee.Args) > len(st) {
+			u.err = errors.New("call args below the frame")
+			return nil, false
+		}
+
+		st = st[:len(st)-int(callee.Args)]
+		for k := 0; k < int(callee.Results); k++ {
+			st = append(st, frameValue)
+		}
+
+		return st, true
+
+	case *RetSubExpr:
+		u.op(i)
+
+		n := int(u.proto.Results)
+		if len(st) < n {
+			u.err = errors.New("retsub below the frame")
+			return nil, false
+		}
+
+		rs := st[len(st)-n:]
+		if u.retsubs == 0 {
+			u.results = append([]int{}, rs...)
+		} else {
+			for k := range rs {
+				u.results[k] = joinFrameTag(u.results[k], rs[k])
+			}
+		}
+		u.retsubs++
+
+		return nil, false
+	}
+
+	if u.prologue[i] {
+		switch op := op.(type) {
+		case *DupExpr:
+			if len(st) > 0 {
+				return append(st, st[len(st)-1]), true
+			}
+		case *DupNExpr:
+			if len(st) > 0 {
+				for k := 0; k < int(op.Count); k++ {
+					st = append(st, st[len(st)-1])
+				}
+				return st, true
+			}
+		default:
+			return append(st, i), true
+		}
+	}
+
+	need, delta, ok := stackEffect(op)
+	if !ok {
+		u.err = errors.Errorf("unknown effect of %s", op)
+		return nil, false
+	}
+
+	if need > len(st) {
+		u.err = errors.Errorf("%s reads below the frame", op)
+		return nil, false
+	}
+
+	st = st[:len(st)-need]
+	for k := 0; k < need+delta; k++ {
+		st = append(st, frameValue)
+	}
+
+	switch op.(type) {
+	case *ReturnExpr, *ErrExpr, *BExpr:
+		return st, false
+	}
+
+	return st, true
+}
+
+func (u *frameUsage) run(p int) {
+	n := len(u.r.Listing)
+
+	// the constants, dup and dupn right after the proto allocate the locals unless they are the whole body
+	for i := p + 1; i < n; i++ {
+		op := u.r.Listing[i]
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		switch op.(type) {
+		case *DupExpr, *DupNExpr:
+			if len(u.prologue) > 0 {
+				u.prologue[i] = true
+				continue
+			}
+		default:
+			if isConstPush(op) {
+				u.prologue[i] = true
+				continue
+			}
+		}
+
+		if _, ok := op.(*RetSubExpr); ok {
+			u.prologue = map[int]bool{}
+		}
+
+		break
+	}
+
+	u.flow(p+1, nil)
+
+	for len(u.todo) > 0 && u.err == nil {
+		i := u.todo[len(u.todo)-1]
+		u.todo = u.todo[:len(u.todo)-1]
+
+		st, next := u.step(i, append([]int{}, u.states[i]...))
+		if u.err != nil {
+			return
+		}
+
+		switch op := u.r.Listing[i].(type) {
+		case *BExpr:
+			if j, ok := u.target(op.Label); ok {
+				u.flow(j, st)
+			}
+		case *BzExpr:
+			if j, ok := u.target(op.Label); ok {
+				u.flow(j, st)
+			}
+		case *BnzExpr:
+			if j, ok := u.target(op.Label); ok {
+				u.flow(j, st)
+			}
+		case *SwitchExpr:
+			for _, t := range op.Targets {
+				if j, ok := u.target(t); ok {
+					u.flow(j, st)
+				}
+			}
+		case *MatchExpr:
+			for _, t := range op.Targets {
+				if j, ok := u.target(t); ok {
+					u.flow(j, st)
+				}
+			}
+		}
+
+		if next {
+			u.flow(i+1, st)
+		}
+	}
+}
+
+// UnusedFrames returns the args of the subroutines with proto that are never read with frame_dig
+// and the results that are never written before retsub, i.e. still hold the constants the locals
+// are allocated with. The subroutines with the stack effects that cannot be followed are skipped.
+func (r ProcessResult) UnusedFrames() []UnusedFrame {
+	var res []UnusedFrame
+
+	protos := r.subroutineProtos()
+	if len(protos) == 0 {
+		return nil
+	}
+
+	labels := map[string]int{}
+	for i, op := range r.Listing {
+		if l, ok := op.(*LabelExpr); ok {
+			if _, ok := labels[l.Name]; !ok {
+				labels[l.Name] = i
+			}
+		}
+	}
+
+	for p := range r.Listing {
+		names, ok := protos[p]
+		if !ok {
+			continue
+		}
+
+		proto := r.Listing[p].(*ProtoExpr)
+
+		u := &frameUsage{
+			r:        &r,
+			labels:   labels,
+			proto:    proto,
+			prologue: map[int]bool{},
+			states:   make([][]int, len(r.Listing)),
+			visited:  make([]bool, len(r.Listing)),
+			reads:    map[int]bool{},
+			writes:   map[int]bool{},
+			seen:     map[int]bool{},
+		}
+
+		u.run(p)
+		if u.err != nil {
+			continue
+		}
+
+		for j := 0; j < int(proto.Args); j++ {
+			if u.reads[j] {
+				continue
+			}
+
+			res = append(res, UnusedFrame{
+				Label:   names[0],
+				Proto:   p,
+				Index:   j,
+				Default: -1,
+				ops:     u.ops,
+				written: u.writes[j],
+			})
+		}
+
+		if u.retsubs == 0 {
+			continue
+		}
+
+		for k, t := range u.results {
+			if t == frameValue {
+				continue
+			}
+
+			def := t
+			if def == frameMixed {
+				def = -1
+			}
+
+			res = append(res, UnusedFrame{
+				Label:   names[0],
+				Proto:   p,
+				Result:  true,
+				Index:   k,
+				Default: def,
+				ops:     u.ops,
+			})
+		}
+	}
+
+	return res
+}
+
+// frameName returns the name of the arg or the result declared with the proto comment, the position otherwise.
+func (r ProcessResult) frameName(u UnusedFrame, names map[string]FrameNames) string {
+	n := names[u.Label]
+
+	ns := n.Args
+	if u.Result {
+		ns = n.Results
+	}
+
+	if u.Index < len(ns) && ns[u.Index] != "" {
+		return fmt.Sprintf("'%s'", ns[u.Index])
+	}
+
+	return fmt.Sprintf("%d", u.Index)
+}
+
+// callsites returns the listing indexes of the calls to the subroutine of the proto.
+func (r ProcessResult) callsites(p int) []int {
+	names := map[string]bool{}
+	for _, l := range r.subroutineProtos()[p] {
+		names[l] = true
+	}
+
+	var res []int
+	for i, op := range r.Listing {
+		if c, ok := op.(*CallSubExpr); ok && names[c.Label.Name] {
+			res = append(res, i)
+		}
+	}
+
+	return res
+}
+
+// dropText returns the ops removing the value at the depth from the top of the stack.
+func dropText(depth int) string {
+	if depth == 0 {
+		return "pop"
+	}
+
+	return fmt.Sprintf("uncover %d; pop", depth)
+}
+
+// unusedFrameFix removes the arg or the result from the proto, the frame ops and the callsites,
+// the callers drop the removed arg before the call and push the default of the removed result after it.
+func (r ProcessResult) unusedFrameFix(u UnusedFrame, names map[string]FrameNames) (FixAction, bool) {
+	if u.written || (u.Result && u.Default < 0) {
+		return FixAction{}, false
+	}
+
+	proto := r.Listing[u.Proto].(*ProtoExpr)
+
+	valid := func(i int) bool {
+		return i < len(r.Sublines)
+	}
+
+	if !valid(u.Proto) || len(r.Sublines[u.Proto].Tokens) < 3 {
+		return FixAction{}, false
+	}
+
+	var edits []FixEdit
+
+	before := func(i int, text string) {
+		t := r.Sublines[i].Tokens[0]
+		p := FixPosition{Line: t.StartLine(), Character: t.StartCharacter()}
+		edits = append(edits, FixEdit{Start: p, End: p, NewText: text + "; "})
+	}
+
+	after := func(i int, text string) {
+		ts := r.Sublines[i].Tokens
+		t := ts[len(ts)-1]
+		p := FixPosition{Line: t.EndLine(), Character: t.EndCharacter()}
+		edits = append(edits, FixEdit{Start: p, End: p, NewText: "; " + text})
+	}
+
+	calls := r.callsites(u.Proto)
+	for _, c := range calls {
+		if !valid(c) {
+			return FixAction{}, false
+		}
+	}
+
+	for _, i := range u.ops {
+		if !valid(i) {
+			return FixAction{}, false
+		}
+	}
+
+	var title string
+
+	if u.Result {
+		title = fmt.Sprintf("Remove unused result %s of '%s'", r.frameName(u, names), u.Label)
+
+		if !valid(u.Default) {
+			return FixAction{}, false
+		}
+
+		edits = append(edits, tokenEdit(r.Sublines[u.Proto].Tokens[2], fmt.Sprintf("%d", proto.Results-1)))
+
+		depth := int(proto.Results) - 1 - u.Index
+
+		for _, i := range u.ops {
+			if _, ok := r.Listing[i].(*RetSubExpr); ok {
+				before(i, dropText(depth))
+			}
+		}
+
+		var vs []string
+		for _, t := range r.Sublines[u.Default].Tokens {
+			vs = append(vs, t.String())
+		}
+
+		push := strings.Join(vs, " ")
+		if depth > 0 {
+			push += fmt.Sprintf("; cover %d", depth)
+		}
+
+		for _, c := range calls {
+			after(c, push)
+		}
+	} else {
+		title = fmt.Sprintf("Remove unused arg %s of '%s'", r.frameName(u, names), u.Label)
+
+		edits = append(edits, tokenEdit(r.Sublines[u.Proto].Tokens[1], fmt.Sprintf("%d", proto.Args-1)))
+
+		// the args below the removed one move one slot closer to the frame
+		removed := u.Index - int(proto.Args)
+
+		for _, i := range u.ops {
+			var idx int8
+			switch op := r.Listing[i].(type) {
+			case *FrameDigExpr:
+				idx = op.Index
+			case *FrameBuryExpr:
+				idx = op.Index
+			default:
+				continue
+			}
+
+			if int(idx) >= removed || len(r.Sublines[i].Tokens) < 2 {
+				continue
+			}
+
+			edits = append(edits, tokenEdit(r.Sublines[i].Tokens[1], fmt.Sprintf("%d", idx+1)))
+		}
+
+		depth := int(proto.Args) - 1 - u.Index
+		for _, c := range calls {
+			before(c, dropText(depth))
+		}
+	}
+
+	return FixAction{
+		Title: title,
+		Kind:  FixProto,
+		Edits: edits,
+	}, true
+}
+
+type UnusedFrameRule struct{}
+
+func (r UnusedFrameRule) Id() string {
+	return "LINT0019"
+}
+
+func (r UnusedFrameRule) Desc() string {
+	return "Checks for subroutine args that are never read and results that are never written before retsub"
+}
+
+var UnusedFrameRuleInstance = UnusedFrameRule{}
+
+func checkUnusedFrames(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	names := res.FrameNames()
+
+	for _, u := range res.UnusedFrames() {
+		if u.Proto >= len(res.Sublines) {
+			continue
+		}
+
+		var msg string
+		if u.Result {
+			msg = fmt.Sprintf("result %s of '%s' is never written before retsub", res.frameName(u, names), u.Label)
+		} else {
+			msg = fmt.Sprintf("arg %s of '%s' is never read", res.frameName(u, names), u.Label)
+		}
+
+		sub := res.Sublines[u.Proto]
+		diags = append(diags, lintError{
+			error: errors.New(msg),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagWarn,
+			r:     UnusedFrameRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
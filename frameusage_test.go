@@ -0,0 +1,138 @@
+package teal
+
+import "testing"
+
+func TestUnusedFrames(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+int 2
+int 3
+callsub f
+pop
+callsub g
+return
+// proto: a b c -> x y
+f:
+proto 3 2
+int 0
+dup
+frame_dig -3
+frame_bury 0
+retsub
+g:
+proto 1 1
+frame_dig -1
+retsub`)
+
+	type unused struct {
+		label  string
+		result bool
+		index  int
+	}
+
+	expected := []unused{
+		{label: "f", index: 1},
+		{label: "f", index: 2},
+		{label: "f", result: true, index: 1},
+	}
+
+	us := res.UnusedFrames()
+	if len(us) != len(expected) {
+		t.Fatalf("unexpected unused frames: %+v", us)
+	}
+
+	for i, u := range us {
+		if (unused{label: u.Label, result: u.Result, index: u.Index}) != expected[i] {
+			t.Errorf("unexpected unused frame: %+v, expected: %+v", u, expected[i])
+		}
+	}
+
+	if us[2].Default != 11 {
+		t.Errorf("unexpected default: %d", us[2].Default)
+	}
+
+	var msgs []string
+	for _, d := range res.Diagnostics {
+		if d.Rule() == UnusedFrameRuleInstance.Id() {
+			msgs = append(msgs, d.String())
+
+			if d.Line() != 10 {
+				t.Errorf("unexpected diagnostic line: %d", d.Line())
+			}
+		}
+	}
+
+	expectedMsgs := []string{
+		"arg 'b' of 'f' is never read",
+		"arg 'c' of 'f' is never read",
+		"result 'y' of 'f' is never written before retsub",
+	}
+
+	if len(msgs) != len(expectedMsgs) {
+		t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	for i, msg := range msgs {
+		if msg != expectedMsgs[i] {
+			t.Errorf("unexpected message: %s, expected: %s", msg, expectedMsgs[i])
+		}
+	}
+}
+
+func TestUnusedFramesSkipped(t *testing.T) {
+	for _, src := range []string{
+		// the results are computed on every path
+		"#pragma version 8\nint 1\ncallsub f\nreturn\nf:\nproto 1 1\nint 0\nframe_dig -1\nbnz one\nretsub\none:\nint 1\nframe_bury 0\nretsub",
+		// the whole body is the constant
+		"#pragma version 8\ncallsub f\nreturn\nf:\nproto 0 1\nint 1\nretsub",
+		// the callee without proto has an unknown effect
+		"#pragma version 8\nint 1\ncallsub f\nreturn\nf:\nproto 1 1\ncallsub g\nframe_dig -1\nretsub\ng:\nretsub",
+	} {
+		us := Process(src).UnusedFrames()
+		if len(us) != 0 {
+			t.Errorf("unexpected unused frames: %+v\n%s", us, src)
+		}
+	}
+}
+
+func TestUnusedFrameFixes(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{
+			src:      "#pragma version 8\nint 1\nint 2\nint 3\ncallsub f\nreturn\nf:\nproto 3 1\nframe_dig -3\nframe_dig -1\n+\nretsub",
+			expected: "#pragma version 8\nint 1\nint 2\nint 3\nuncover 1; pop; callsub f\nreturn\nf:\nproto 2 1\nframe_dig -2\nframe_dig -1\n+\nretsub",
+		},
+		{
+			src:      "#pragma version 8\nint 1\ncallsub f\npop\nreturn\nf:\nproto 1 2\nint 0\npushint 7\nframe_dig -1\nframe_bury 1\nretsub",
+			expected: "#pragma version 8\nint 1\ncallsub f; int 0; cover 1\npop\nreturn\nf:\nproto 1 1\nint 0\npushint 7\nframe_dig -1\nframe_bury 1\nuncover 1; pop; retsub",
+		},
+	}
+
+	for _, test := range tests {
+		res := Process(test.src)
+
+		var fas []FixAction
+		for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+			if fa.Kind == FixProto {
+				fas = append(fas, fa)
+			}
+		}
+
+		if len(fas) != 1 {
+			t.Fatalf("expected a single proto fix, got: %+v", fas)
+		}
+
+		fixed, _ := ApplyFixes(test.src, fas)
+		if fixed != test.expected {
+			t.Errorf("unexpected fixed source:\n%s\nexpected:\n%s", fixed, test.expected)
+		}
+
+		for _, d := range Process(fixed).Diagnostics {
+			if d.Severity() == DiagErr || d.Rule() == UnusedFrameRuleInstance.Id() {
+				t.Errorf("unexpected diagnostic after the fix: %s", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build ignore
+
+// gen_rules writes the rules reference to RULES.md.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dragmz/teal"
+)
+
+func main() {
+	err := os.WriteFile("RULES.md", []byte(teal.RulesReference()), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
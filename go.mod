@@ -4,18 +4,25 @@ go 1.19
 
 require (
 	github.com/algorand/go-algorand-sdk v1.24.0
+	github.com/consensys/gnark-crypto v0.12.1
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/dragmz/abs v0.0.0-20221120174236-615259d8ebd1
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/joe-p/tealfmt v0.0.0-20221219211223-cec2ea891d52
 	github.com/pkg/errors v0.9.1
 	github.com/samber/lo v1.37.0
+	golang.org/x/crypto v0.10.0
 	golang.org/x/tools v0.4.0
 )
 
 require (
 	github.com/algorand/go-codec/codec v1.1.9 // indirect
+	github.com/bits-and-blooms/bitset v1.7.0 // indirect
+	github.com/consensys/bavard v0.1.13 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
-	golang.org/x/crypto v0.4.0 // indirect
+	github.com/mmcloughlin/addchain v0.4.0 // indirect
 	golang.org/x/exp v0.0.0-20221217163422-3c43f8badb15 // indirect
 	golang.org/x/mod v0.7.0 // indirect
-	golang.org/x/sys v0.3.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+	rsc.io/tmplfunc v0.0.3 // indirect
 )
@@ -0,0 +1,410 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupExpectation is a comparison of a field of another group transaction with a value.
+type GroupExpectation struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+	Line  int    `json:"line"`
+}
+
+func (e GroupExpectation) String() string {
+	return fmt.Sprintf("%s %s %s", e.Field, e.Op, e.Value)
+}
+
+// GroupMember is a group transaction referenced by the program.
+type GroupMember struct {
+	Index int  `json:"index"`
+	Self  bool `json:"self,omitempty"`
+
+	Fields       []string           `json:"fields,omitempty"`
+	Expectations []GroupExpectation `json:"expectations,omitempty"`
+
+	// Created is set if the program reads the id of the asset or app created by the transaction
+	Created bool `json:"created,omitempty"`
+
+	// Scratch holds the scratch slots of the transaction read by the program
+	Scratch []uint8 `json:"scratch,omitempty"`
+
+	Lines []int `json:"lines,omitempty"`
+}
+
+// GroupDeps are the other group transactions the program depends on.
+type GroupDeps struct {
+	// Self is the group index of the program transaction or -1 if unknown
+	Self    int           `json:"self"`
+	Members []GroupMember `json:"members"`
+}
+
+func (m GroupMember) typeName() string {
+	for _, e := range m.Expectations {
+		if e.Field == TypeEnum.String() && e.Op == "==" {
+			return e.Value
+		}
+	}
+
+	return ""
+}
+
+// Description summarizes the expectations, e.g. "pay to app address".
+func (m GroupMember) Description() string {
+	var parts []string
+
+	t := m.typeName()
+
+	if m.Self {
+		if t == "" {
+			t = "appl"
+		}
+		parts = append(parts, "this "+t)
+	} else if t != "" {
+		parts = append(parts, t)
+	} else {
+		parts = append(parts, "txn")
+	}
+
+	var rest []string
+
+	for _, e := range m.Expectations {
+		switch {
+		case e.Field == TypeEnum.String() && e.Op == "==":
+		case (e.Field == Receiver.String() || e.Field == AssetReceiver.String()) && e.Op == "==" && e.Value == groupAppAddress:
+			parts = append(parts, "to app address")
+		default:
+			rest = append(rest, e.String())
+		}
+	}
+
+	if m.Created {
+		rest = append(rest, "creates an asset or app")
+	}
+
+	for _, s := range m.Scratch {
+		rest = append(rest, fmt.Sprintf("scratch %d", s))
+	}
+
+	s := strings.Join(parts, " ")
+	if len(rest) > 0 {
+		s += " (" + strings.Join(rest, ", ") + ")"
+	}
+
+	return s
+}
+
+// Summary lists the expected group transactions, e.g. "tx0=pay to app address, tx1=this appl".
+func (g GroupDeps) Summary() string {
+	var parts []string
+
+	for _, m := range g.Members {
+		parts = append(parts, fmt.Sprintf("tx%d=%s", m.Index, m.Description()))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Mermaid renders the dependencies as a Mermaid sequence diagram.
+func (g GroupDeps) Mermaid() string {
+	var sb strings.Builder
+
+	sb.WriteString("sequenceDiagram\n")
+
+	self := "this"
+	if g.Self != -1 {
+		self = fmt.Sprintf("tx%d", g.Self)
+	} else {
+		sb.WriteString("    participant this as this program\n")
+	}
+
+	for _, m := range g.Members {
+		sb.WriteString(fmt.Sprintf("    participant tx%d as tx%d: %s\n", m.Index, m.Index, m.Description()))
+	}
+
+	for _, m := range g.Members {
+		if m.Self {
+			continue
+		}
+
+		to := fmt.Sprintf("tx%d", m.Index)
+
+		for _, e := range m.Expectations {
+			sb.WriteString(fmt.Sprintf("    %s->>%s: %s\n", self, to, e))
+		}
+
+		for _, f := range m.Fields {
+			found := false
+			for _, e := range m.Expectations {
+				if e.Field == f {
+					found = true
+				}
+			}
+
+			if !found {
+				sb.WriteString(fmt.Sprintf("    %s->>%s: reads %s\n", self, to, f))
+			}
+		}
+
+		if m.Created {
+			sb.WriteString(fmt.Sprintf("    %s->>%s: gaid\n", self, to))
+		}
+
+		for _, s := range m.Scratch {
+			sb.WriteString(fmt.Sprintf("    %s->>%s: gload %d\n", self, to, s))
+		}
+	}
+
+	return sb.String()
+}
+
+// Member returns the member at the group index.
+func (g GroupDeps) Member(index int) (GroupMember, bool) {
+	for _, m := range g.Members {
+		if m.Index == index {
+			return m, true
+		}
+	}
+
+	return GroupMember{}, false
+}
+
+const groupAppAddress = "app address"
+
+// groupValue renders the value compared with the field.
+func groupValue(field TxnField, op Op) (string, bool) {
+	switch op := op.(type) {
+	case *IntExpr, *PushIntExpr:
+		v, _ := constUint(op)
+
+		switch field {
+		case TypeEnum:
+			if v < uint64(len(TxnTypeNames)) {
+				return TxnTypeNames[v], true
+			}
+		case OnCompletion:
+			return OnCompletionConstType(v).String(), true
+		}
+
+		return fmt.Sprint(v), true
+	case *GlobalExpr:
+		switch op.Field {
+		case CurrentApplicationAddress:
+			return groupAppAddress, true
+		case CurrentApplicationID:
+			return "this app", true
+		}
+
+		return op.String(), true
+	case *TxnExpr:
+		return "this " + op.Field.String(), true
+	case *ByteExpr, *PushBytesExpr, *AddrExpr, *MethodExpr:
+		return op.String(), true
+	default:
+		return "", false
+	}
+}
+
+func compareOpName(op Op) (string, bool) {
+	switch op.(type) {
+	case *EqExpr:
+		return "==", true
+	case *NeqExpr:
+		return "!=", true
+	case *LtExpr:
+		return "<", true
+	case *GtExpr:
+		return ">", true
+	case *LtEqExpr:
+		return "<=", true
+	case *GtEqExpr:
+		return ">=", true
+	default:
+		return "", false
+	}
+}
+
+// groupField returns the group index and the field read by the op at i.
+func groupField(ops []vectorOp, i int) (int, TxnField, bool) {
+	switch op := ops[i].op.(type) {
+	case *GtxnExpr:
+		return int(op.Group), op.Field, true
+	case *GtxnaExpr:
+		return int(op.Group), op.Field, true
+	case *GtxnsExpr:
+		if i > 0 {
+			if v, ok := constUint(ops[i-1].op); ok {
+				return int(v), op.Field, true
+			}
+		}
+	case *GtxnsaExpr:
+		if i > 0 {
+			if v, ok := constUint(ops[i-1].op); ok {
+				return int(v), op.Field, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// AnalyzeGroup finds the references to the other group transactions made with gtxn, gtxns, gaid and gload ops
+// and the comparisons of their fields with known values.
+func AnalyzeGroup(res *ProcessResult) GroupDeps {
+	var ops []vectorOp
+	for i, op := range res.Listing {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		ops = append(ops, vectorOp{op: op, line: i})
+	}
+
+	g := GroupDeps{Self: -1}
+
+	members := map[int]*GroupMember{}
+	// member returns the member at the group index and records the listing index of the reference
+	member := func(index int, i int) *GroupMember {
+		m, ok := members[index]
+		if !ok {
+			m = &GroupMember{Index: index}
+			members[index] = m
+		}
+
+		m.Lines = append(m.Lines, i)
+
+		return m
+	}
+
+	for i, op := range ops {
+		switch o := op.op.(type) {
+		case *GaidExpr:
+			member(int(o.Group), op.line).Created = true
+			continue
+		case *GloadExpr:
+			m := member(int(o.Group), op.line)
+			m.Scratch = append(m.Scratch, o.Index)
+			continue
+		case *TxnExpr:
+			if o.Field != GroupIndex {
+				continue
+			}
+
+			// txn GroupIndex; int c; == or int c; txn GroupIndex; ==
+			for _, k := range []int{i + 1, i - 1} {
+				if k < 0 || k >= len(ops) {
+					continue
+				}
+
+				v, ok := constUint(ops[k].op)
+				if !ok {
+					continue
+				}
+
+				c := i
+				if k > c {
+					c = k
+				}
+				c++
+
+				if c < len(ops) {
+					if _, ok := ops[c].op.(*EqExpr); ok {
+						g.Self = int(v)
+					}
+				}
+			}
+			continue
+		}
+
+		index, field, ok := groupField(ops, i)
+		if !ok {
+			continue
+		}
+
+		m := member(index, op.line)
+
+		name := field.String()
+
+		found := false
+		for _, f := range m.Fields {
+			if f == name {
+				found = true
+			}
+		}
+
+		if !found {
+			m.Fields = append(m.Fields, name)
+		}
+
+		// field; value; cmp or value; field; cmp
+		for _, k := range []int{i + 1, i - 1} {
+			if k < 0 || k >= len(ops) {
+				continue
+			}
+
+			// the comparison follows both values
+			c := i
+			if k > c {
+				c = k
+			}
+			c++
+
+			if c >= len(ops) {
+				continue
+			}
+
+			cmp, ok := compareOpName(ops[c].op)
+			if !ok {
+				continue
+			}
+
+			if k == i-1 {
+				if _, ok := ops[i].op.(*GtxnsExpr); ok {
+					continue
+				}
+				if _, ok := ops[i].op.(*GtxnsaExpr); ok {
+					continue
+				}
+			}
+
+			v, ok := groupValue(field, ops[k].op)
+			if !ok {
+				continue
+			}
+
+			m.Expectations = append(m.Expectations, GroupExpectation{
+				Field: name,
+				Op:    cmp,
+				Value: v,
+				Line:  res.SourceLine(ops[c].line),
+			})
+		}
+	}
+
+	if g.Self != -1 {
+		m, ok := members[g.Self]
+		if !ok {
+			m = &GroupMember{Index: g.Self}
+			members[g.Self] = m
+		}
+
+		m.Self = true
+	}
+
+	for _, m := range members {
+		for i, l := range m.Lines {
+			m.Lines[i] = res.SourceLine(l)
+		}
+
+		g.Members = append(g.Members, *m)
+	}
+
+	sort.Slice(g.Members, func(i, j int) bool {
+		return g.Members[i].Index < g.Members[j].Index
+	})
+
+	return g
+}
@@ -0,0 +1,58 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeGroup(t *testing.T) {
+	res := Process(`#pragma version 8
+txn GroupIndex
+int 1
+==
+assert
+gtxn 0 TypeEnum
+int pay
+==
+assert
+global CurrentApplicationAddress
+gtxn 0 Receiver
+==
+assert
+gtxn 0 Amount
+int 1000
+>=
+assert
+int 2
+gtxns XferAsset
+int 5
+==
+assert
+gaid 3
+pop
+gload 4 7
+pop
+int 1`)
+
+	g := AnalyzeGroup(res)
+
+	if g.Self != 1 {
+		t.Errorf("unexpected self: %d", g.Self)
+	}
+
+	s := g.Summary()
+	expected := "tx0=pay to app address (Amount >= 1000), tx1=this appl, tx2=txn (XferAsset == 5), tx3=txn (creates an asset or app), tx4=txn (scratch 7)"
+	if s != expected {
+		t.Errorf("unexpected summary: %s", s)
+	}
+
+	m, ok := g.Member(0)
+	if !ok || len(m.Lines) != 3 || m.Lines[0] != 5 {
+		t.Errorf("unexpected member: %+v", m)
+	}
+
+	md := g.Mermaid()
+	if !strings.Contains(md, "tx1->>tx0: Receiver == app address\n") || !strings.Contains(md, "tx1->>tx4: gload 7\n") {
+		t.Errorf("unexpected mermaid:\n%s", md)
+	}
+}
@@ -0,0 +1,280 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gtxnChainMin is the min number of the unrolled blocks suggested to be rewritten as a loop
+const gtxnChainMin = 3
+
+// gtxnLoopMinSaving is the min number of the saved bytes worth the extra budget of the loop
+const gtxnLoopMinSaving = 8
+
+// gtxnLoopOverhead is the size of the loop ops around the body: the counter init, load and increment, the bound check and the branch
+const gtxnLoopOverhead = 21
+
+// GtxnChain is an unrolled sequence of the blocks reading the same field of the consecutive group transactions
+// with `gtxn i F` followed by the same ops consuming the value, e.g. `gtxn 0 RekeyTo; global ZeroAddress; ==; assert`.
+type GtxnChain struct {
+	// Index is the listing index of the first gtxn, Len is the number of the listing items of the chain
+	Index int
+	Len   int
+
+	Field TxnField
+
+	// First is the group index of the first block, Count is the number of the blocks
+	First uint8
+	Count int
+
+	// Body are the ops following the gtxn of each block
+	Body []Op
+
+	// Saving is the estimated number of bytes saved by the loop
+	Saving int
+}
+
+// opSize estimates the assembled size of the op, the constants are counted as 2 byte references.
+func opSize(op Op) int {
+	switch op := op.(type) {
+	case Nop:
+		return 0
+	case *IntExpr, *ByteExpr, *AddrExpr, *MethodExpr:
+		return 2
+	case *PushIntExpr:
+		return 1 + varuintSize(op.Value)
+	case *PushBytesExpr:
+		return 1 + bytesSize(op.Value)
+	}
+
+	if s, ok := langOpSizes[opName(op.String())]; ok {
+		return s
+	}
+
+	return 1
+}
+
+// gtxnBody reports whether the ops can be repeated in a loop: straight-line code consuming the gtxn value.
+func gtxnBody(ops []Op) bool {
+	depth := 1
+
+	for _, op := range ops {
+		switch op.(type) {
+		case Nop, *LabelExpr, *CallSubExpr, *RetSubExpr, *ProtoExpr, Terminator,
+			*LoadExpr, *StoreExpr, *LoadsExpr, *StoresExpr, *GtxnExpr:
+			return false
+		}
+
+		if _, ok := op.(interface{ IsBranch() }); ok {
+			return false
+		}
+
+		need, delta, ok := stackEffect(op)
+		if !ok || need > depth {
+			return false
+		}
+
+		depth += delta
+	}
+
+	return depth == 0
+}
+
+// GtxnChains returns the unrolled gtxn sequences that are smaller as a gtxns loop.
+func (r ProcessResult) GtxnChains() []GtxnChain {
+	if r.Version < 3 {
+		return nil
+	}
+
+	var res []GtxnChain
+
+	for i := 0; i < len(r.Listing); i++ {
+		g, ok := r.Listing[i].(*GtxnExpr)
+		if !ok {
+			continue
+		}
+
+		// the body ends at the next gtxn of the same field and the next group index
+		n := 0
+		for k := i + 1; k < len(r.Listing); k++ {
+			if next, ok := r.Listing[k].(*GtxnExpr); ok {
+				if next.Field == g.Field && next.Group == g.Group+1 {
+					n = k - i - 1
+				}
+				break
+			}
+		}
+
+		body := r.Listing[i+1 : i+1+n]
+		if n == 0 || !gtxnBody(body) {
+			continue
+		}
+
+		count := 1
+		for {
+			k := i + count*(n+1)
+			if k+n >= len(r.Listing) {
+				break
+			}
+
+			next, ok := r.Listing[k].(*GtxnExpr)
+			if !ok || next.Field != g.Field || int(next.Group) != int(g.Group)+count {
+				break
+			}
+
+			same := true
+			for j, op := range body {
+				if r.Listing[k+1+j].String() != op.String() {
+					same = false
+					break
+				}
+			}
+
+			if !same {
+				break
+			}
+
+			count++
+		}
+
+		if count < gtxnChainMin {
+			continue
+		}
+
+		size := 0
+		for _, op := range body {
+			size += opSize(op)
+		}
+
+		// each unrolled block has the 3 byte gtxn, the loop has the 2 byte gtxns
+		saving := count*(3+size) - (gtxnLoopOverhead + 2 + size)
+		if saving < gtxnLoopMinSaving {
+			continue
+		}
+
+		res = append(res, GtxnChain{
+			Index:  i,
+			Len:    count * (n + 1),
+			Field:  g.Field,
+			First:  g.Group,
+			Count:  count,
+			Body:   body,
+			Saving: saving,
+		})
+
+		i += count*(n+1) - 1
+	}
+
+	return res
+}
+
+// freeScratchSlot returns the highest scratch slot not used by the program, false if the slots are accessed dynamically.
+func (r ProcessResult) freeScratchSlot() (uint8, bool) {
+	used := map[uint8]bool{}
+
+	for _, op := range r.Listing {
+		switch op := op.(type) {
+		case *LoadExpr:
+			used[op.Index] = true
+		case *StoreExpr:
+			used[op.Index] = true
+		case *LoadsExpr, *StoresExpr:
+			return 0, false
+		}
+	}
+
+	for s := 255; s >= 0; s-- {
+		if !used[uint8(s)] {
+			return uint8(s), true
+		}
+	}
+
+	return 0, false
+}
+
+// gtxnLoopFix replaces the chain with a loop over the group indexes kept in a free scratch slot.
+func (r ProcessResult) gtxnLoopFix(c GtxnChain) (FixAction, bool) {
+	slot, ok := r.freeScratchSlot()
+	if !ok {
+		return FixAction{}, false
+	}
+
+	label := fmt.Sprintf("gtxn_loop_%d", r.SourceLine(c.Index)+1)
+	for _, sym := range r.Symbols {
+		if sym.Name() == label {
+			return FixAction{}, false
+		}
+	}
+
+	eol := r.Eol
+
+	lines := []string{
+		fmt.Sprintf("int %d", c.First),
+		fmt.Sprintf("store %d", slot),
+		label + ":",
+		fmt.Sprintf("load %d", slot),
+		fmt.Sprintf("gtxns %s", c.Field),
+	}
+
+	for _, op := range c.Body {
+		lines = append(lines, op.String())
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("load %d", slot),
+		"int 1",
+		"+",
+		"dup",
+		fmt.Sprintf("store %d", slot),
+		fmt.Sprintf("int %d", int(c.First)+c.Count),
+		"<",
+		"bnz "+label,
+	)
+
+	first := r.Sublines[c.Index]
+	last := r.Sublines[c.Index+c.Len-1]
+
+	return FixAction{
+		Title: fmt.Sprintf("Rewrite with a gtxns loop (saves about %d bytes)", c.Saving),
+		Kind:  FixGtxnLoop,
+		Edits: []FixEdit{
+			{
+				Start:   FixPosition{Line: first.Line, Character: first.Tokens.Begin()},
+				End:     FixPosition{Line: last.Line, Character: codeEnd(last)},
+				NewText: strings.Join(lines, eol),
+			},
+		},
+	}, true
+}
+
+type GtxnLoopRule struct{}
+
+func (r GtxnLoopRule) Id() string {
+	return "LINT0025"
+}
+
+func (r GtxnLoopRule) Desc() string {
+	return "Suggests a gtxns loop for the unrolled gtxn sequences"
+}
+
+var GtxnLoopRuleInstance = GtxnLoopRule{}
+
+func checkGtxnChains(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, c := range res.GtxnChains() {
+		first := res.Sublines[c.Index]
+
+		diags = append(diags, lintError{
+			error: fmt.Errorf("gtxn %d..%d %s is unrolled %d times - a gtxns loop saves about %d bytes at the cost of the loop ops budget",
+				c.First, int(c.First)+c.Count-1, c.Field, c.Count, c.Saving),
+			l: first.Line,
+			b: first.Tokens.Begin(),
+			e: codeEnd(first),
+			s: DiagHint,
+			r: GtxnLoopRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
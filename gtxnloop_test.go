@@ -0,0 +1,101 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func gtxnChainSource(count int) string {
+	var b strings.Builder
+	b.WriteString("#pragma version 8\nload 1\npop\n")
+
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "gtxn %d RekeyTo\nglobal ZeroAddress\n==\nassert\n", i)
+	}
+
+	b.WriteString("int 1")
+
+	return b.String()
+}
+
+func gtxnLoopFixes(res *ProcessResult) []FixAction {
+	var fas []FixAction
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+		if fa.Kind == FixGtxnLoop {
+			fas = append(fas, fa)
+		}
+	}
+
+	return fas
+}
+
+func TestGtxnChains(t *testing.T) {
+	res := Process(gtxnChainSource(5))
+
+	cs := res.GtxnChains()
+	if len(cs) != 1 {
+		t.Fatalf("unexpected chains: %v", cs)
+	}
+
+	c := cs[0]
+	if c.First != 0 || c.Count != 5 || c.Field != RekeyTo || len(c.Body) != 3 {
+		t.Errorf("unexpected chain: %+v", c)
+	}
+
+	// 5 * (3 + 4) unrolled, 21 + 2 + 4 looped
+	if c.Saving != 8 {
+		t.Errorf("expected saving of 8 bytes, got: %d", c.Saving)
+	}
+
+	n := 0
+	for _, d := range res.Diagnostics {
+		if d.Rule() == GtxnLoopRuleInstance.Id() {
+			n++
+		}
+	}
+
+	if n != 1 {
+		t.Errorf("expected 1 hint, got: %d", n)
+	}
+
+	if len(Process(gtxnChainSource(4)).GtxnChains()) != 0 {
+		t.Error("expected no chains when the loop is not smaller")
+	}
+}
+
+func TestGtxnChainsDifferentBody(t *testing.T) {
+	res := Process("#pragma version 8\ngtxn 0 Fee\nint 1000\n<=\nassert\ngtxn 1 Fee\nint 2000\n<=\nassert\ngtxn 2 Fee\nint 1000\n<=\nassert\nint 1")
+
+	if cs := res.GtxnChains(); len(cs) != 0 {
+		t.Errorf("unexpected chains: %v", cs)
+	}
+}
+
+func TestGtxnLoopFix(t *testing.T) {
+	src := gtxnChainSource(5)
+
+	if fas := gtxnLoopFixes(Process(src)); len(fas) != 0 {
+		t.Fatalf("expected no fixes without the experimental fixes, got: %v", fas)
+	}
+
+	res := Process(src, WithExperimentalFixes())
+
+	fas := gtxnLoopFixes(res)
+	if len(fas) != 1 {
+		t.Fatalf("expected 1 fix, got: %v", fas)
+	}
+
+	fixed, _ := ApplyFixes(src, fas)
+
+	expected := "#pragma version 8\nload 1\npop\nint 0\nstore 255\ngtxn_loop_4:\nload 255\ngtxns RekeyTo\nglobal ZeroAddress\n==\nassert\nload 255\nint 1\n+\ndup\nstore 255\nint 5\n<\nbnz gtxn_loop_4\nint 1"
+	if fixed != expected {
+		t.Fatalf("expected: %q, got: %q", expected, fixed)
+	}
+
+	for _, d := range Process(fixed).Diagnostics {
+		if d.Severity() == DiagErr {
+			t.Errorf("unexpected error in the fixed program: %s", d)
+		}
+	}
+}
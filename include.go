@@ -0,0 +1,158 @@
+package teal
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// withIncluder makes the included file inherit the includer settings and state.
+func withIncluder(cfg processConfig, path string, stack []string, version uint64, mode ProgramMode) ProcessOption {
+	return func(c *processConfig) {
+		*c = cfg
+		c.path = path
+		c.stack = stack
+		c.version = version
+		c.mode = mode
+	}
+}
+
+type Include struct {
+	Path   string
+	Line   int
+	Result *ProcessResult
+}
+
+func (c *parserContext) include() {
+	line := c.args.Curr().Line()
+	c.mcrs = append(c.mcrs, c.args.Curr())
+
+	name := c.mustRead("path")
+	c.strs = append(c.strs, c.args.Curr())
+
+	rel, err := strconv.Unquote(name)
+	if err != nil {
+		c.failCurr(errors.New("include path must be a quoted string"))
+	}
+
+	// the path next to the including file first, then the include paths in order
+	paths := []string{filepath.Clean(rel)}
+	if !filepath.IsAbs(rel) {
+		paths[0] = filepath.Clean(filepath.Join(filepath.Dir(c.cfg.path), rel))
+		for _, dir := range c.cfg.includePaths {
+			paths = append(paths, filepath.Clean(filepath.Join(dir, rel)))
+		}
+	}
+
+	readFile := c.cfg.readFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+
+	// the error of the path next to the including file is reported if none of the paths is found
+	path := paths[0]
+	bs, err := readFile(path)
+	for _, p := range paths[1:] {
+		if err == nil {
+			break
+		}
+
+		if alt, aerr := readFile(p); aerr == nil {
+			path, bs, err = p, alt, nil
+		}
+	}
+
+	stack := append(append([]string{}, c.cfg.stack...), filepath.Clean(c.cfg.path))
+	for _, p := range stack {
+		if p == path {
+			c.failCurr(errors.Errorf("include cycle: %s", name))
+		}
+	}
+
+	if err != nil {
+		c.failCurr(errors.Wrapf(err, "failed to read include: %s", name))
+	}
+
+	res := Process(string(bs), withIncluder(c.cfg, path, stack, c.version, c.mode))
+
+	c.incs = append(c.incs, &Include{
+		Path:   path,
+		Line:   line,
+		Result: res,
+	})
+
+	c.emit(&IncludeExpr{Path: rel})
+}
+
+// Included returns all the directly and transitively included files, each path listed once.
+func (r ProcessResult) Included() []*Include {
+	var res []*Include
+
+	seen := map[string]bool{}
+
+	var walk func(incs []*Include)
+	walk = func(incs []*Include) {
+		for _, inc := range incs {
+			if seen[inc.Path] {
+				continue
+			}
+
+			seen[inc.Path] = true
+			res = append(res, inc)
+
+			walk(inc.Result.Includes)
+		}
+	}
+
+	walk(r.Includes)
+
+	return res
+}
+
+// IncludeFor returns the included file that defines the named symbol.
+func (r ProcessResult) IncludeFor(name string) (*Include, []Symbol) {
+	for _, inc := range r.Included() {
+		syms := inc.Result.SymByName(name)
+		if len(syms) > 0 {
+			return inc, syms
+		}
+	}
+
+	return nil, nil
+}
+
+// filterIncludedUnused drops unused label warnings from included files for labels referenced by the includer.
+func filterIncludedUnused(incs []*Include, used map[string]int) {
+	rule := UnusedLabelsRule{}.Id()
+
+	for _, inc := range incs {
+		res := inc.Result
+
+		labels := map[int]string{}
+		for i, op := range res.Listing {
+			if lbl, ok := op.(*LabelExpr); ok {
+				labels[res.SourceLine(i)] = lbl.Name
+			}
+		}
+
+		var diags []Diagnostic
+		for _, d := range res.Diagnostics {
+			if d.Rule() == rule && used[labels[d.Line()]] > 0 {
+				continue
+			}
+			diags = append(diags, d)
+		}
+		res.Diagnostics = diags
+
+		var reds []RedundantLine
+		for _, red := range res.Redundants {
+			if lbl, ok := red.(*RedundantLabelLine); ok && used[lbl.name] > 0 {
+				continue
+			}
+			reds = append(reds, red)
+		}
+		res.Redundants = reds
+	}
+}
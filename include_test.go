@@ -0,0 +1,111 @@
+package teal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testReadFile(files map[string]string) func(path string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		s, ok := files[filepath.ToSlash(path)]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(s), nil
+	}
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"src/lib/math.teal": `double:
+proto 1 1
+frame_dig -1
+int 2
+*
+retsub
+unused:
+retsub`,
+	}
+
+	res := Process(`#pragma version 8
+#include "lib/math.teal"
+int 1
+callsub double
+return`, WithPath("src/main.teal"), WithReadFile(testReadFile(files)))
+
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	if len(res.Includes) != 1 {
+		t.Fatalf("unexpected includes count: %d", len(res.Includes))
+	}
+
+	inc := res.Includes[0]
+	if filepath.ToSlash(inc.Path) != "src/lib/math.teal" || inc.Line != 1 {
+		t.Errorf("unexpected include: %s:%d", inc.Path, inc.Line)
+	}
+
+	if len(inc.Result.Diagnostics) != 1 || inc.Result.Diagnostics[0].Line() != 6 {
+		t.Errorf("expected a single unused label diagnostic in the included file, got: %v", inc.Result.Diagnostics)
+	}
+
+	if res.Listing[1].String() != `#include "lib/math.teal"` {
+		t.Errorf("unexpected include op: %s", res.Listing[1])
+	}
+
+	found, syms := res.IncludeFor("double")
+	if found != inc || len(syms) != 1 {
+		t.Error("failed to resolve included symbol")
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	files := map[string]string{
+		"a.teal": `#include "b.teal"`,
+		"b.teal": `#include "a.teal"`,
+	}
+
+	res := Process(`#include "a.teal"`, WithPath("main.teal"), WithReadFile(testReadFile(files)))
+
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	b := res.Includes[0].Result.Includes[0].Result
+	if len(b.Diagnostics) != 1 {
+		t.Fatalf("expected include cycle diagnostic, got: %v", b.Diagnostics)
+	}
+}
+
+func TestIncludeMissing(t *testing.T) {
+	res := Process(`#include "missing.teal"`, WithReadFile(testReadFile(nil)))
+
+	if len(res.Diagnostics) != 1 {
+		t.Errorf("expected missing include diagnostic, got: %v", res.Diagnostics)
+	}
+}
+
+func TestIncludePaths(t *testing.T) {
+	files := map[string]string{
+		"lib/common/math.teal": "double:\nretsub",
+		"src/local.teal":       "local:\nretsub",
+	}
+
+	res := Process(`#pragma version 8
+#include "math.teal"
+#include "local.teal"
+callsub double
+callsub local
+int 1
+return`, WithPath("src/main.teal"), WithReadFile(testReadFile(files)), WithIncludePaths("vendor", "lib/common"))
+
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	if filepath.ToSlash(res.Includes[0].Path) != "lib/common/math.teal" || filepath.ToSlash(res.Includes[1].Path) != "src/local.teal" {
+		t.Errorf("unexpected include paths: %s, %s", res.Includes[0].Path, res.Includes[1].Path)
+	}
+}
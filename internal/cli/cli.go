@@ -0,0 +1,183 @@
+// Package cli runs the teal tools as commands sharing the flag parsing, the config file loading and the error reporting.
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Command is a tool or a subcommand of the teal tool.
+type Command struct {
+	Name    string
+	Summary string
+
+	// Flags registers the command flags, the values are set before Run is called
+	Flags func(fs *flag.FlagSet)
+
+	// Run runs the command and returns the process exit code
+	Run func() (int, error)
+
+	// Commands are the subcommands selected by the first arg, Run is not used if set
+	Commands []Command
+}
+
+// Config holds the flag values of the commands keyed by the command path, e.g. "lint" or "abi encode".
+type Config map[string]map[string]interface{}
+
+// ReadConfig reads the JSON config file.
+func ReadConfig(path string) (Config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	var c Config
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return nil, errors.Wrap(err, "failed to parse config file")
+	}
+
+	return c, nil
+}
+
+// apply sets the flags of the command that were not set in the args.
+func (c Config) apply(path string, fs *flag.FlagSet) error {
+	vs, ok := c[path]
+	if !ok {
+		return nil
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	names := make([]string, 0, len(vs))
+	for k := range vs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		if set[k] {
+			continue
+		}
+
+		if fs.Lookup(k) == nil {
+			return errors.Errorf("unknown flag in config: %s.%s", path, k)
+		}
+
+		if err := fs.Set(k, fmt.Sprint(vs[k])); err != nil {
+			return errors.Wrapf(err, "invalid config value: %s.%s", path, k)
+		}
+	}
+
+	return nil
+}
+
+// Main runs the command with the args and returns the process exit code.
+// The errors are reported to stderr prefixed with the program name.
+func Main(c Command, prog string, args []string) int {
+	return run(c, prog, c.Name, args, os.Stdout, os.Stderr)
+}
+
+func printJSONHelp(c Command, prog string, stdout io.Writer, stderr io.Writer) int {
+	if c.Name == "" {
+		c.Name = prog
+	}
+
+	if err := writeJSONHelp(c, stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+		return 1
+	}
+
+	return 0
+}
+
+func run(c Command, prog string, path string, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(c.Commands) > 0 {
+		return dispatch(c, prog, path, args, stdout, stderr)
+	}
+
+	fs, config, jsonHelp := c.flagSet(prog)
+	fs.SetOutput(stderr)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+
+		return 2
+	}
+
+	if *jsonHelp {
+		return printJSONHelp(c, prog, stdout, stderr)
+	}
+
+	if *config != "" {
+		cfg, err := ReadConfig(*config)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+			return 2
+		}
+
+		if err := cfg.apply(path, fs); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+			return 2
+		}
+	}
+
+	code, err := c.Run()
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func usage(c Command, prog string, w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n\ncommands:\n", prog)
+
+	for _, sc := range c.Commands {
+		fmt.Fprintf(w, "  %-10s %s\n", sc.Name, sc.Summary)
+	}
+}
+
+func dispatch(c Command, prog string, path string, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(c, prog, stderr)
+		return 2
+	}
+
+	name := args[0]
+
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage(c, prog, stderr)
+		return 0
+	case "-json-help", "--json-help":
+		return printJSONHelp(c, prog, stdout, stderr)
+	}
+
+	for _, sc := range c.Commands {
+		if sc.Name != name {
+			continue
+		}
+
+		return run(sc, prog+" "+name, strings.TrimSpace(path+" "+name), args[1:], stdout, stderr)
+	}
+
+	fmt.Fprintf(stderr, "%s: unknown command: %s\n", prog, name)
+	usage(c, prog, stderr)
+
+	return 2
+}
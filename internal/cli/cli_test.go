@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCommand returns the command tree recording the flag values of the run commands.
+func testCommand(ran *string, name *string, strict *bool) Command {
+	leaf := func(path string) Command {
+		return Command{
+			Name:    path[strings.LastIndex(path, " ")+1:],
+			Summary: "run " + path,
+			Flags: func(fs *flag.FlagSet) {
+				fs.StringVar(name, "name", "default", "the name")
+				fs.BoolVar(strict, "strict", false, "the strictness")
+			},
+			Run: func() (int, error) {
+				*ran = path
+				return 0, nil
+			},
+		}
+	}
+
+	return Command{
+		Commands: []Command{
+			leaf("lint"),
+			{
+				Name:    "abi",
+				Summary: "abi tools",
+				Commands: []Command{
+					leaf("abi encode"),
+				},
+			},
+		},
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	type test struct {
+		args   []string
+		code   int
+		ran    string
+		name   string
+		strict bool
+		stderr string
+	}
+
+	tests := []test{
+		{args: nil, code: 2, stderr: "usage: teal <command>"},
+		{args: []string{"help"}, code: 0, stderr: "usage: teal <command>"},
+		{args: []string{"missing"}, code: 2, stderr: "teal: unknown command: missing"},
+		{args: []string{"lint"}, code: 0, ran: "lint", name: "default"},
+		{args: []string{"lint", "-name", "x", "-strict"}, code: 0, ran: "lint", name: "x", strict: true},
+		{args: []string{"lint", "-unknown"}, code: 2, name: "default", stderr: "flag provided but not defined: -unknown"},
+		{args: []string{"abi"}, code: 2, stderr: "usage: teal abi <command>"},
+		{args: []string{"abi", "encode", "-name", "y"}, code: 0, ran: "abi encode", name: "y"},
+		{args: []string{"abi", "missing"}, code: 2, stderr: "teal abi: unknown command: missing"},
+	}
+
+	for _, test := range tests {
+		var ran, name string
+		var strict bool
+
+		var stdout, stderr bytes.Buffer
+		code := run(testCommand(&ran, &name, &strict), "teal", "", test.args, &stdout, &stderr)
+
+		if code != test.code {
+			t.Errorf("unexpected code for %v - expected: %d, actual: %d", test.args, test.code, code)
+		}
+
+		if ran != test.ran || name != test.name || strict != test.strict {
+			t.Errorf("unexpected run for %v - expected: %s %s %t, actual: %s %s %t", test.args, test.ran, test.name, test.strict, ran, name, strict)
+		}
+
+		if !strings.Contains(stderr.String(), test.stderr) {
+			t.Errorf("unexpected stderr for %v - expected: %s, actual: %s", test.args, test.stderr, stderr.String())
+		}
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	type test struct {
+		config string
+		args   []string
+		code   int
+		name   string
+		strict bool
+		stderr string
+	}
+
+	tests := []test{
+		{config: `{"lint": {"name": "cfg", "strict": true}}`, args: []string{"lint"}, name: "cfg", strict: true},
+		{config: `{"lint": {"name": "cfg"}}`, args: []string{"lint", "-name", "arg"}, name: "arg"},
+		{config: `{"abi encode": {"name": "cfg"}}`, args: []string{"lint"}, name: "default"},
+		{config: `{"abi encode": {"name": "cfg"}}`, args: []string{"abi", "encode"}, name: "cfg"},
+		{config: `{"lint": {"missing": 1}}`, args: []string{"lint"}, code: 2, name: "default", stderr: "unknown flag in config: lint.missing"},
+		{config: `{"lint": {"strict": "maybe"}}`, args: []string{"lint"}, code: 2, name: "default", stderr: "invalid config value: lint.strict"},
+		{config: `{`, args: []string{"lint"}, code: 2, name: "default", stderr: "failed to parse config file"},
+	}
+
+	dir := t.TempDir()
+
+	for i, test := range tests {
+		path := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(path, []byte(test.config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var ran, name string
+		var strict bool
+
+		args := append(append([]string{}, test.args...), "-config", path)
+
+		var stdout, stderr bytes.Buffer
+		code := run(testCommand(&ran, &name, &strict), "teal", "", args, &stdout, &stderr)
+
+		if code != test.code {
+			t.Errorf("unexpected code of test %d - expected: %d, actual: %d, stderr: %s", i, test.code, code, stderr.String())
+		}
+
+		if name != test.name || strict != test.strict {
+			t.Errorf("unexpected flags of test %d - expected: %s %t, actual: %s %t", i, test.name, test.strict, name, strict)
+		}
+
+		if !strings.Contains(stderr.String(), test.stderr) {
+			t.Errorf("unexpected stderr of test %d - expected: %s, actual: %s", i, test.stderr, stderr.String())
+		}
+	}
+}
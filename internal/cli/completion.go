@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// completionNode is a command with its path from the root, e.g. "abi encode".
+type completionNode struct {
+	path string
+	help Help
+}
+
+func completionNodes(h Help, path string) []completionNode {
+	res := []completionNode{{path: path, help: h}}
+
+	for _, sc := range h.Commands {
+		p := strings.TrimSpace(path + " " + sc.Name)
+		res = append(res, completionNodes(sc, p)...)
+	}
+
+	return res
+}
+
+// words returns the completions of the command - the subcommand names or the flags.
+func (n completionNode) words() []string {
+	var res []string
+
+	for _, sc := range n.help.Commands {
+		res = append(res, sc.Name)
+	}
+
+	for _, f := range n.help.Flags {
+		res = append(res, "-"+f.Name)
+	}
+
+	return res
+}
+
+func shellFuncName(prog string) string {
+	return "_" + strings.NewReplacer("-", "_", ".", "_").Replace(prog) + "_completion"
+}
+
+// WriteBashCompletion writes the bash completion script of the command tree.
+func WriteBashCompletion(w io.Writer, prog string, root Command) error {
+	nodes := completionNodes(root.Help(), "")
+	fn := shellFuncName(prog)
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# bash completion for %s\n", prog)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("    local path=\"\"\n")
+	sb.WriteString("    local i\n")
+	sb.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	sb.WriteString("        case \"$path\" in\n")
+
+	// only the commands with subcommands take the next word as a command name
+	var groups []string
+	for _, n := range nodes {
+		if len(n.help.Commands) > 0 {
+			groups = append(groups, fmt.Sprintf("%q", n.path))
+		}
+	}
+
+	fmt.Fprintf(&sb, "            %s)\n", strings.Join(groups, " | "))
+	sb.WriteString("                case \"${COMP_WORDS[i]}\" in\n")
+	sb.WriteString("                    -*) ;;\n")
+	sb.WriteString("                    *) path=\"${path:+$path }${COMP_WORDS[i]}\" ;;\n")
+	sb.WriteString("                esac\n")
+	sb.WriteString("                ;;\n")
+	sb.WriteString("        esac\n")
+	sb.WriteString("    done\n")
+	sb.WriteString("    local words=\"\"\n")
+	sb.WriteString("    case \"$path\" in\n")
+
+	for _, n := range nodes {
+		fmt.Fprintf(&sb, "        %q) words=%q ;;\n", n.path, strings.Join(n.words(), " "))
+	}
+
+	sb.WriteString("    esac\n")
+	sb.WriteString("    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	sb.WriteString("}\n")
+	fmt.Fprintf(&sb, "complete -F %s %s\n", fn, prog)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteZshCompletion writes the zsh completion script of the command tree, it uses the bash completion compatibility.
+func WriteZshCompletion(w io.Writer, prog string, root Command) error {
+	fmt.Fprintf(w, "#compdef %s\n", prog)
+	fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+
+	return WriteBashCompletion(w, prog, root)
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "\\", "\\\\"), "'", "\\'") + "'"
+}
+
+// WriteFishCompletion writes the fish completion script of the command tree.
+func WriteFishCompletion(w io.Writer, prog string, root Command) error {
+	nodes := completionNodes(root.Help(), "")
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# fish completion for %s\n", prog)
+	fmt.Fprintf(&sb, "complete -c %s -f\n", prog)
+
+	for _, n := range nodes {
+		// the condition matches the words of the command path and none of its subcommands
+		var conds []string
+
+		if n.path == "" {
+			conds = append(conds, "__fish_use_subcommand")
+		} else {
+			for _, name := range strings.Fields(n.path) {
+				conds = append(conds, "__fish_seen_subcommand_from "+name)
+			}
+		}
+
+		if len(n.help.Commands) > 0 && n.path != "" {
+			var names []string
+			for _, sc := range n.help.Commands {
+				names = append(names, sc.Name)
+			}
+
+			conds = append(conds, "not __fish_seen_subcommand_from "+strings.Join(names, " "))
+		}
+
+		cond := fishQuote(strings.Join(conds, "; and "))
+
+		for _, sc := range n.help.Commands {
+			fmt.Fprintf(&sb, "complete -c %s -n %s -a %s -d %s\n", prog, cond, sc.Name, fishQuote(sc.Summary))
+		}
+
+		for _, f := range n.help.Flags {
+			line := fmt.Sprintf("complete -c %s -n %s -o %s -d %s", prog, cond, f.Name, fishQuote(f.Usage))
+			if !f.Bool {
+				line += " -r -F"
+			}
+
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// CompletionCommand returns the command printing the completion script of the root command for the shell.
+func CompletionCommand(prog string, root func() Command) Command {
+	write := func(f func(w io.Writer, prog string, root Command) error) func() (int, error) {
+		return func() (int, error) {
+			if err := f(os.Stdout, prog, root()); err != nil {
+				return 1, errors.Wrap(err, "failed to write completion script")
+			}
+
+			return 0, nil
+		}
+	}
+
+	return Command{
+		Name:    "completion",
+		Summary: "print the shell completion script: bash, zsh or fish",
+		Commands: []Command{
+			{Name: "bash", Summary: "print the bash completion script", Flags: func(fs *flag.FlagSet) {}, Run: write(WriteBashCompletion)},
+			{Name: "zsh", Summary: "print the zsh completion script", Flags: func(fs *flag.FlagSet) {}, Run: write(WriteZshCompletion)},
+			{Name: "fish", Summary: "print the fish completion script", Flags: func(fs *flag.FlagSet) {}, Run: write(WriteFishCompletion)},
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONHelp(t *testing.T) {
+	type test struct {
+		args     []string
+		name     string
+		commands []string
+		flags    []string
+	}
+
+	tests := []test{
+		{args: []string{"--json-help"}, name: "teal", commands: []string{"lint", "abi"}},
+		{args: []string{"abi", "-json-help"}, name: "abi", commands: []string{"encode"}},
+		{args: []string{"lint", "-json-help"}, name: "lint", flags: []string{"config", "json-help", "name", "strict"}},
+	}
+
+	for _, test := range tests {
+		var ran, name string
+		var strict bool
+
+		var stdout, stderr bytes.Buffer
+		if code := run(testCommand(&ran, &name, &strict), "teal", "", test.args, &stdout, &stderr); code != 0 {
+			t.Fatalf("unexpected code for %v: %d, stderr: %s", test.args, code, stderr.String())
+		}
+
+		if ran != "" {
+			t.Errorf("unexpected run for %v: %s", test.args, ran)
+		}
+
+		var h Help
+		if err := json.Unmarshal(stdout.Bytes(), &h); err != nil {
+			t.Fatalf("failed to parse help for %v: %s", test.args, err)
+		}
+
+		if h.Name != test.name {
+			t.Errorf("unexpected name for %v - expected: %s, actual: %s", test.args, test.name, h.Name)
+		}
+
+		var commands []string
+		for _, c := range h.Commands {
+			commands = append(commands, c.Name)
+		}
+
+		if strings.Join(commands, " ") != strings.Join(test.commands, " ") {
+			t.Errorf("unexpected commands for %v - expected: %v, actual: %v", test.args, test.commands, commands)
+		}
+
+		var flags []string
+		for _, f := range h.Flags {
+			flags = append(flags, f.Name)
+		}
+
+		if strings.Join(flags, " ") != strings.Join(test.flags, " ") {
+			t.Errorf("unexpected flags for %v - expected: %v, actual: %v", test.args, test.flags, flags)
+		}
+	}
+}
+
+func TestHelpFlags(t *testing.T) {
+	var ran, name string
+	var strict bool
+
+	h := testCommand(&ran, &name, &strict).Commands[0].Help()
+
+	for _, f := range h.Flags {
+		switch f.Name {
+		case "strict":
+			if !f.Bool || f.Default != "false" {
+				t.Errorf("unexpected strict flag: %+v", f)
+			}
+		case "name":
+			if f.Bool || f.Default != "default" || f.Usage != "the name" {
+				t.Errorf("unexpected name flag: %+v", f)
+			}
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	type test struct {
+		shell string
+		write func(c Command, w *bytes.Buffer) error
+		lines []string
+	}
+
+	tests := []test{
+		{
+			shell: "bash",
+			write: func(c Command, w *bytes.Buffer) error { return WriteBashCompletion(w, "teal", c) },
+			lines: []string{
+				"_teal_completion() {",
+				`        "") words="lint abi" ;;`,
+				`        "lint") words="-config -json-help -name -strict" ;;`,
+				`        "abi") words="encode" ;;`,
+				`        "abi encode") words="-config -json-help -name -strict" ;;`,
+				`            "" | "abi")`,
+				"complete -F _teal_completion teal",
+			},
+		},
+		{
+			shell: "zsh",
+			write: func(c Command, w *bytes.Buffer) error { return WriteZshCompletion(w, "teal", c) },
+			lines: []string{
+				"#compdef teal",
+				"autoload -U +X bashcompinit && bashcompinit",
+				"complete -F _teal_completion teal",
+			},
+		},
+		{
+			shell: "fish",
+			write: func(c Command, w *bytes.Buffer) error { return WriteFishCompletion(w, "teal", c) },
+			lines: []string{
+				"complete -c teal -f",
+				"complete -c teal -n '__fish_use_subcommand' -a lint -d 'run lint'",
+				"complete -c teal -n '__fish_seen_subcommand_from abi; and not __fish_seen_subcommand_from encode' -a encode -d 'run abi encode'",
+				"complete -c teal -n '__fish_seen_subcommand_from lint' -o strict -d 'the strictness'",
+				"complete -c teal -n '__fish_seen_subcommand_from lint' -o name -d 'the name' -r -F",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var ran, name string
+		var strict bool
+
+		var w bytes.Buffer
+		if err := test.write(testCommand(&ran, &name, &strict), &w); err != nil {
+			t.Fatalf("failed to write %s completion: %s", test.shell, err)
+		}
+
+		lines := map[string]bool{}
+		for _, l := range strings.Split(w.String(), "\n") {
+			lines[l] = true
+		}
+
+		for _, l := range test.lines {
+			if !lines[l] {
+				t.Errorf("missing %s completion line: %s\n%s", test.shell, l, w.String())
+			}
+		}
+	}
+}
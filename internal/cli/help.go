@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+)
+
+// FlagHelp describes a flag of a command.
+type FlagHelp struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default string `json:"default,omitempty"`
+
+	// Bool is set for the flags that do not take a value
+	Bool bool `json:"bool,omitempty"`
+}
+
+// Help is the machine-readable description of a command printed with --json-help.
+type Help struct {
+	Name     string     `json:"name"`
+	Summary  string     `json:"summary,omitempty"`
+	Flags    []FlagHelp `json:"flags,omitempty"`
+	Commands []Help     `json:"commands,omitempty"`
+}
+
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// flagSet returns the flag set of the command with the common flags.
+func (c Command) flagSet(name string) (*flag.FlagSet, *string, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	config := fs.String("config", "", "JSON config file with the default flag values keyed by the command, e.g. {\"lint\": {\"strict\": true}}")
+	jsonHelp := fs.Bool("json-help", false, "print the command description as JSON")
+
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+
+	return fs, config, jsonHelp
+}
+
+// Help describes the command and its subcommands.
+func (c Command) Help() Help {
+	h := Help{
+		Name:    c.Name,
+		Summary: c.Summary,
+	}
+
+	if len(c.Commands) > 0 {
+		for _, sc := range c.Commands {
+			h.Commands = append(h.Commands, sc.Help())
+		}
+
+		return h
+	}
+
+	fs, _, _ := c.flagSet(c.Name)
+	fs.VisitAll(func(f *flag.Flag) {
+		fh := FlagHelp{
+			Name:    f.Name,
+			Usage:   f.Usage,
+			Default: f.DefValue,
+		}
+
+		if b, ok := f.Value.(boolFlag); ok && b.IsBoolFlag() {
+			fh.Bool = true
+		}
+
+		h.Flags = append(h.Flags, fh)
+	})
+
+	return h
+}
+
+func writeJSONHelp(c Command, w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "\t")
+
+	return e.Encode(c.Help())
+}
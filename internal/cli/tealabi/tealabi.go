@@ -0,0 +1,138 @@
+package tealabi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/dragmz/teal/abi"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type encodeArgs struct {
+	Type   string
+	Method string
+	Value  string
+}
+
+type decodeArgs struct {
+	Type string
+	Hex  string
+}
+
+func parseValue(s string) (interface{}, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+
+	var v interface{}
+	err := d.Decode(&v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse JSON value")
+	}
+
+	return v, nil
+}
+
+func runEncode(a encodeArgs) error {
+	v, err := parseValue(a.Value)
+	if err != nil {
+		return err
+	}
+
+	if a.Method != "" {
+		m, err := abi.ParseMethod(a.Method)
+		if err != nil {
+			return err
+		}
+
+		vs, ok := v.([]interface{})
+		if !ok {
+			return errors.New("method args value must be a JSON array")
+		}
+
+		args, err := m.EncodeArgs(vs)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args {
+			fmt.Printf("0x%x\n", arg)
+		}
+
+		return nil
+	}
+
+	t, err := abi.ParseType(a.Type)
+	if err != nil {
+		return err
+	}
+
+	bs, err := abi.Encode(t, v)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("0x%x\n", bs)
+
+	return nil
+}
+
+func runDecode(a decodeArgs) error {
+	t, err := abi.ParseType(a.Type)
+	if err != nil {
+		return err
+	}
+
+	bs, err := hex.DecodeString(strings.TrimPrefix(a.Hex, "0x"))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse hex value")
+	}
+
+	v, err := abi.Decode(t, bs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(abi.Format(t, v))
+
+	return nil
+}
+
+// Command returns the ABI encoder and decoder command.
+func Command() cli.Command {
+	var ea encodeArgs
+	var da decodeArgs
+
+	return cli.Command{
+		Name:    "abi",
+		Summary: "encode and decode ARC-4 ABI values",
+		Commands: []cli.Command{
+			{
+				Name:    "encode",
+				Summary: "encode a JSON value",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&ea.Type, "type", "", "ABI type, e.g. (uint64,string)")
+					fs.StringVar(&ea.Method, "method", "", "method signature to encode app args for, e.g. add(uint64,uint64)uint128")
+					fs.StringVar(&ea.Value, "value", "", "JSON value to encode")
+				},
+				Run: func() (int, error) {
+					return 0, runEncode(ea)
+				},
+			},
+			{
+				Name:    "decode",
+				Summary: "decode a hex value",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&da.Type, "type", "", "ABI type, e.g. (uint64,string)")
+					fs.StringVar(&da.Hex, "hex", "", "hex encoded value to decode")
+				},
+				Run: func() (int, error) {
+					return 0, runDecode(da)
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package tealbench
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dragmz/teal/bench"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Old string
+	New string
+
+	Threshold float64
+}
+
+func readResults(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open benchmark results")
+	}
+	defer f.Close()
+
+	return bench.ReadResults(f)
+}
+
+func run(a args) (int, error) {
+	old, err := readResults(a.Old)
+	if err != nil {
+		return -1, err
+	}
+
+	new, err := readResults(a.New)
+	if err != nil {
+		return -1, err
+	}
+
+	code := 0
+
+	for _, c := range bench.Compare(old, new, a.Threshold) {
+		mark := ""
+		if c.Regression {
+			mark = " REGRESSION"
+			code = 1
+		}
+
+		fmt.Printf("%-50s %14.0f %14.0f %+8.2f%%%s\n", c.Name, c.Old, c.New, c.Delta, mark)
+	}
+
+	return code, nil
+}
+
+// Command returns the benchmark comparison command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "benchcompare",
+		Summary: "compare go test -bench results of the bench package and fail on regressions",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Old, "old", "", "baseline go test -bench output file")
+			fs.StringVar(&a.New, "new", "", "new go test -bench output file")
+			fs.Float64Var(&a.Threshold, "threshold", 10, "max allowed slowdown in percent")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
@@ -0,0 +1,297 @@
+package tealbuild
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type algodArgs struct {
+	Algod      string
+	AlgodToken string
+}
+
+func (a algodArgs) client() (*algod.Client, error) {
+	ac, err := algod.MakeClient(a.Algod, a.AlgodToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make algod client")
+	}
+
+	return ac, nil
+}
+
+type assembleArgs struct {
+	algodArgs
+
+	Source    string
+	Out       string
+	Info      string
+	SourceMap string
+
+	NoCheck bool
+}
+
+type verifyArgs struct {
+	algodArgs
+
+	Info    string
+	Source  string
+	Program string
+	AppId   uint64
+	Clear   bool
+}
+
+func assemblerVersion(ac *algod.Client) (string, error) {
+	v, err := ac.Versions().Do(context.Background())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get algod version")
+	}
+
+	b := v.Build
+
+	return fmt.Sprintf("algod %d.%d.%d.%s [%s] (commit #%s)", b.Major, b.Minor, b.BuildNumber, b.Channel, b.Branch, b.CommitHash), nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal JSON")
+	}
+
+	if err := os.WriteFile(path, append(bs, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write file: %s", path)
+	}
+
+	return nil
+}
+
+// checkSource prints all of the errors of the source with their ranges, algod stops on the first one.
+func checkSource(path string, src []byte, w io.Writer) (int, error) {
+	res := teal.Process(string(src), teal.WithPath(path))
+
+	var ds []teal.Diagnostic
+	for _, d := range res.Diagnostics {
+		if d.Severity() == teal.DiagErr {
+			ds = append(ds, d)
+		}
+	}
+
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].Line() != ds[j].Line() {
+			return ds[i].Line() < ds[j].Line()
+		}
+
+		return ds[i].Begin() < ds[j].Begin()
+	})
+
+	for _, d := range ds {
+		_, err := fmt.Fprintf(w, "%s:%d:%d-%d: %s [%s]\n", path, d.Line()+1, d.Begin()+1, d.End()+1, d, d.Rule())
+		if err != nil {
+			return len(ds), errors.Wrap(err, "failed to report errors")
+		}
+	}
+
+	return len(ds), nil
+}
+
+func runAssemble(a assembleArgs) error {
+	if a.Source == "" {
+		return errors.New("missing source file")
+	}
+
+	src, err := teal.ReadSource(a.Source)
+	if err != nil {
+		return err
+	}
+
+	if !a.NoCheck {
+		n, err := checkSource(a.Source, src, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return errors.Errorf("found %d errors, not assembling", n)
+		}
+	}
+
+	ac, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	resp, err := ac.TealCompile(src).Sourcemap(a.SourceMap != "").Do(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "failed to compile program")
+	}
+
+	prog, err := base64.StdEncoding.DecodeString(resp.Result)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode compiled program")
+	}
+
+	b := teal.NewBuildInfo(src, prog)
+
+	b.Assembler, err = assemblerVersion(ac)
+	if err != nil {
+		return err
+	}
+
+	b.Options = map[string]string{
+		"sourcemap": fmt.Sprint(a.SourceMap != ""),
+	}
+
+	out := a.Out
+	if out == "" {
+		out = a.Source + ".tok"
+	}
+
+	if err := os.WriteFile(out, prog, 0644); err != nil {
+		return errors.Wrap(err, "failed to write program file")
+	}
+
+	info := a.Info
+	if info == "" {
+		info = out + ".build.json"
+	}
+
+	if err := writeJSON(info, b); err != nil {
+		return err
+	}
+
+	if a.SourceMap != "" && resp.Sourcemap != nil {
+		if err := writeJSON(a.SourceMap, resp.Sourcemap); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s: %s\n", out, b.ProgramHash)
+
+	return nil
+}
+
+func readProgram(a verifyArgs) ([]byte, error) {
+	if a.Program != "" {
+		bs, err := os.ReadFile(a.Program)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read program file")
+		}
+
+		return bs, nil
+	}
+
+	if a.AppId == 0 {
+		return nil, errors.New("missing program file or app id")
+	}
+
+	ac, err := a.client()
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := ac.GetApplicationByID(a.AppId).Do(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get app")
+	}
+
+	if a.Clear {
+		return app.Params.ClearStateProgram, nil
+	}
+
+	return app.Params.ApprovalProgram, nil
+}
+
+func runVerify(a verifyArgs) error {
+	if a.Info == "" {
+		return errors.New("missing build info file")
+	}
+
+	bs, err := os.ReadFile(a.Info)
+	if err != nil {
+		return errors.Wrap(err, "failed to read build info file")
+	}
+
+	var b teal.BuildInfo
+	if err := json.Unmarshal(bs, &b); err != nil {
+		return errors.Wrap(err, "failed to parse build info file")
+	}
+
+	if a.Source != "" {
+		src, err := teal.ReadSource(a.Source)
+		if err != nil {
+			return err
+		}
+
+		if err := b.VerifySource(src); err != nil {
+			return err
+		}
+	}
+
+	prog, err := readProgram(a)
+	if err != nil {
+		return err
+	}
+
+	if err := b.VerifyProgram(prog); err != nil {
+		return err
+	}
+
+	fmt.Printf("verified: %s\n", b.ProgramHash)
+
+	return nil
+}
+
+// Command returns the build command that assembles the programs with the build info and verifies them.
+func Command() cli.Command {
+	var aa assembleArgs
+	var va verifyArgs
+
+	return cli.Command{
+		Name:    "build",
+		Summary: "assemble programs with build info and verify them",
+		Commands: []cli.Command{
+			{
+				Name:    "assemble",
+				Summary: "assemble a program with algod and write its build info",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&aa.Algod, "algod", "http://localhost:4001", "algod address")
+					fs.StringVar(&aa.AlgodToken, "algod-token", "", "algod token")
+					fs.StringVar(&aa.Source, "src", "", "teal source file")
+					fs.StringVar(&aa.Out, "out", "", "program output file, defaults to the source file with .tok extension appended")
+					fs.StringVar(&aa.Info, "info", "", "build info output file, defaults to the program file with .build.json extension appended")
+					fs.StringVar(&aa.SourceMap, "sourcemap", "", "source map output file")
+					fs.BoolVar(&aa.NoCheck, "no-check", false, "skip reporting all of the source errors before assembling, algod reports only the first one")
+				},
+				Run: func() (int, error) {
+					return 0, runAssemble(aa)
+				},
+			},
+			{
+				Name:    "verify",
+				Summary: "verify a program against its build info",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&va.Algod, "algod", "http://localhost:4001", "algod address")
+					fs.StringVar(&va.AlgodToken, "algod-token", "", "algod token")
+					fs.StringVar(&va.Info, "info", "", "build info file")
+					fs.StringVar(&va.Source, "src", "", "teal source file to verify, optional")
+					fs.StringVar(&va.Program, "program", "", "program file to verify")
+					fs.Uint64Var(&va.AppId, "app-id", 0, "id of the deployed app to verify if the program file is not set")
+					fs.BoolVar(&va.Clear, "clear", false, "verify the clear state program of the app instead of the approval program")
+				},
+				Run: func() (int, error) {
+					return 0, runVerify(va)
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package tealbuild
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSource(t *testing.T) {
+	type test struct {
+		src string
+		n   int
+		out string
+	}
+
+	tests := []test{
+		{
+			src: "#pragma version 8\nint 1\nreturn\n",
+		},
+		{
+			src: "#pragma version 8\nunknown_op\nint 1\nb missing\nbyte\n",
+			n:   3,
+			out: "a.teal:2:1-11: unknown opcode: unknown_op [PARSE]\n" +
+				"a.teal:4:1-10: missing label: \"missing\" [LINT0005]\n" +
+				"a.teal:5:1-5: missing arg: value [PARSE]\n",
+		},
+		{
+			src: "#pragma version 8\nint 1 2; bad_op\n",
+			n:   2,
+			out: "a.teal:2:7-8: too many values [PARSE]\n" +
+				"a.teal:2:10-16: unknown opcode: bad_op [PARSE]\n",
+		},
+	}
+
+	for _, test := range tests {
+		var w bytes.Buffer
+
+		n, err := checkSource("a.teal", []byte(test.src), &w)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if n != test.n {
+			t.Errorf("unexpected errors count of %q - expected: %d, actual: %d", test.src, test.n, n)
+		}
+
+		if w.String() != test.out {
+			t.Errorf("unexpected output of %q - expected:\n%s\nactual:\n%s", test.src, test.out, w.String())
+		}
+	}
+}
+
+func TestAssembleChecksSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.teal")
+	if err := os.WriteFile(path, []byte("#pragma version 8\nunknown_op\nbad_op\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// algod is not called for the sources with errors
+	err := runAssemble(assembleArgs{algodArgs: algodArgs{Algod: "http://127.0.0.1:1"}, Source: path})
+	if err == nil || !strings.Contains(err.Error(), "found 2 errors, not assembling") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
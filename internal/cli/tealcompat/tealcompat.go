@@ -0,0 +1,56 @@
+package tealcompat
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+)
+
+type args struct {
+	Path string
+}
+
+func outcome(s string) string {
+	if s == "" {
+		return "ok"
+	}
+
+	return s
+}
+
+func run(a args) (int, error) {
+	bs, err := teal.ReadSource(a.Path)
+	if err != nil {
+		return -1, err
+	}
+
+	ds := teal.CheckGoalCompat(string(bs), teal.WithPath(a.Path))
+
+	for _, d := range ds {
+		fmt.Printf("%s:%d: goal: %s, teal: %s\n", a.Path, d.Line+1, outcome(d.Goal), outcome(d.Teal))
+	}
+
+	if len(ds) > 0 {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// Command returns the goal clerk compile compatibility check command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "compat",
+		Summary: "report the lines goal clerk compile treats differently",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Path, "path", "", "source file path")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package tealdiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+type args struct {
+	Old string
+	New string
+
+	Color bool
+
+	Upgrade bool
+}
+
+func colorize(a args, color string, s string) string {
+	if !a.Color {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+func lineColor(k teal.DiffKind) string {
+	switch k {
+	case teal.DiffAdded:
+		return colorGreen
+	case teal.DiffRemoved:
+		return colorRed
+	case teal.DiffModified:
+		return colorYellow
+	default:
+		return ""
+	}
+}
+
+func run(a args) (int, error) {
+	obs, err := teal.ReadSource(a.Old)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to read old source file")
+	}
+
+	nbs, err := teal.ReadSource(a.New)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to read new source file")
+	}
+
+	if a.Upgrade {
+		return upgrade(obs, nbs)
+	}
+
+	d := teal.Diff(string(obs), string(nbs))
+
+	for _, b := range d.Blocks {
+		if b.Kind == teal.DiffEqual {
+			continue
+		}
+
+		header := colorCyan
+		if b.Kind != teal.DiffModified {
+			header = lineColor(b.Kind)
+		}
+
+		fmt.Println(colorize(a, header, b.Header()))
+
+		for _, l := range b.Lines {
+			if l.Kind == teal.DiffEqual {
+				fmt.Println(l)
+			} else {
+				fmt.Println(colorize(a, lineColor(l.Kind), l.String()))
+			}
+		}
+	}
+
+	if d.Changed() {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// upgrade prints the upgrade impact report of the approval programs as json, the exit code is 1 if the upgrade is breaking.
+func upgrade(obs []byte, nbs []byte) (int, error) {
+	r := teal.AnalyzeUpgrade(teal.Process(string(obs)), teal.Process(string(nbs)))
+
+	err := json.NewEncoder(os.Stdout).Encode(r)
+	if err != nil {
+		return -3, errors.Wrap(err, "failed to encode upgrade report")
+	}
+
+	if r.Breaking {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// Command returns the structural diff command.
+func Command() cli.Command {
+	var a args
+	var fs *flag.FlagSet
+
+	return cli.Command{
+		Name:    "diff",
+		Summary: "print the structural diff of two teal files",
+		Flags: func(f *flag.FlagSet) {
+			fs = f
+			fs.StringVar(&a.Old, "old", "", "old teal source file, or the first arg")
+			fs.StringVar(&a.New, "new", "", "new teal source file, or the second arg")
+			fs.BoolVar(&a.Color, "color", true, "colorize the output")
+			fs.BoolVar(&a.Upgrade, "upgrade", false, "print the upgrade impact of the old approval program replaced with the new one as json instead of the diff: the removed methods, the changed key types and the schema growth")
+		},
+		Run: func() (int, error) {
+			if args := fs.Args(); len(args) == 2 && a.Old == "" && a.New == "" {
+				a.Old, a.New = args[0], args[1]
+			}
+
+			return run(a)
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package tealgrep
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Path    string
+	Pattern string
+	Format  string
+}
+
+type match struct {
+	Path string `json:"path"`
+
+	// one-based source lines of the first and the last matched op
+	Line    int `json:"line"`
+	EndLine int `json:"endLine"`
+
+	Text string `json:"text"`
+}
+
+func run(a args) (int, error) {
+	p, err := teal.CompilePattern(a.Pattern)
+	if err != nil {
+		return -1, err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+
+	switch a.Format {
+	case "text", "jsonl":
+	default:
+		return -1, errors.Errorf("unknown format: %s", a.Format)
+	}
+
+	found := false
+
+	err = filepath.WalkDir(a.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrap(err, "failed to walk path")
+		}
+
+		if d.IsDir() || path != a.Path && !strings.HasSuffix(d.Name(), ".teal") {
+			return nil
+		}
+
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read source file")
+		}
+
+		// the compiled programs and the other binary files are skipped like grep does
+		if err := teal.CheckSource(bs); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: skipped: %s\n", path, err)
+			return nil
+		}
+
+		res := teal.Process(string(bs), teal.WithPath(path))
+		lines := strings.Split(string(bs), "\n")
+
+		for _, m := range p.Match(res.Listing) {
+			found = true
+
+			line := res.SourceLine(m.Start)
+
+			r := match{
+				Path:    path,
+				Line:    line + 1,
+				EndLine: res.SourceLine(m.End) + 1,
+			}
+
+			if line < len(lines) {
+				r.Text = strings.TrimSpace(lines[line])
+			}
+
+			if a.Format == "jsonl" {
+				if err := enc.Encode(r); err != nil {
+					return errors.Wrap(err, "failed to encode match")
+				}
+				continue
+			}
+
+			if r.EndLine != r.Line {
+				fmt.Printf("%s:%d-%d: %s\n", r.Path, r.Line, r.EndLine, r.Text)
+			} else {
+				fmt.Printf("%s:%d: %s\n", r.Path, r.Line, r.Text)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return -2, err
+	}
+
+	// no matches exit with 1 like grep
+	if !found {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// Command returns the op pattern search command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "grep",
+		Summary: "search teal files for op sequence patterns",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Path, "path", ".", "path to a teal file or a dir to search")
+			fs.StringVar(&a.Pattern, "e", "", "op sequence pattern, e.g. 'itxn_field RekeyTo; ...5; itxn_submit'")
+			fs.StringVar(&a.Format, "format", "text", "output format: text or jsonl")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package tealint
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// safeFixes returns the safe fixes of the program.
+func safeFixes(res *teal.ProcessResult) []teal.FixAction {
+	safe := map[string]bool{}
+	for _, k := range teal.SafeFixes {
+		safe[k] = true
+	}
+
+	var fas []teal.FixAction
+	for _, fa := range res.CodeActions(teal.LinesRange{Start: 0, End: len(res.Lines)}) {
+		if safe[fa.Kind] {
+			fas = append(fas, fa)
+		}
+	}
+
+	return fas
+}
+
+// printDiff prints the changed lines of the file with their one-based line numbers.
+func printDiff(path string, ls []teal.DiffLine) {
+	fmt.Printf("--- %s\n+++ %s\n", path, path)
+
+	for _, l := range ls {
+		switch l.Kind {
+		case teal.DiffAdded:
+			fmt.Printf("%d: + %s\n", l.NewLine+1, l.New)
+		case teal.DiffRemoved:
+			fmt.Printf("%d: - %s\n", l.OldLine+1, l.Old)
+		case teal.DiffModified:
+			fmt.Printf("%d: - %s\n%d: + %s\n", l.OldLine+1, l.Old, l.NewLine+1, l.New)
+		}
+	}
+}
+
+// fix applies the safe fixes to the file and writes it back, prints it or prints the diff.
+// The fixed source is linted again so the remaining diagnostics are reported.
+func (l *linter) fix(path string, src string, res *teal.ProcessResult) (*teal.ProcessResult, bool, error) {
+	fixed, applied := teal.ApplyFixes(src, safeFixes(res))
+
+	switch {
+	case l.a.Diff:
+		if len(applied) > 0 {
+			printDiff(path, teal.DiffText(src, fixed))
+		}
+		return nil, false, nil
+	case l.a.Stdout:
+		fmt.Print(fixed)
+		return nil, false, nil
+	}
+
+	if len(applied) == 0 {
+		return res, true, nil
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to stat source file")
+	}
+
+	if err := os.WriteFile(path, []byte(fixed), st.Mode()); err != nil {
+		return nil, false, errors.Wrap(err, "failed to write fixed source file")
+	}
+
+	for _, fa := range applied {
+		fmt.Fprintf(os.Stderr, "%s: fixed: %s\n", path, fa.Title)
+	}
+
+	return teal.Process(fixed, l.a.options(path)...), true, nil
+}
@@ -0,0 +1,119 @@
+package tealint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// githubReport prints the diagnostics as GitHub Actions workflow commands so they annotate the pull requests.
+type githubReport struct {
+	w io.Writer
+
+	files  int
+	counts map[teal.DiagnosticSeverity]int
+	rules  map[string]int
+}
+
+func newGithubReport(w io.Writer) *githubReport {
+	return &githubReport{
+		w:      w,
+		counts: map[teal.DiagnosticSeverity]int{},
+		rules:  map[string]int{},
+	}
+}
+
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func githubCommand(s teal.DiagnosticSeverity) string {
+	switch s {
+	case teal.DiagErr:
+		return "error"
+	case teal.DiagWarn:
+		return "warning"
+	default:
+		return "notice"
+	}
+}
+
+// file prints the diagnostics of the file in a collapsible group.
+func (r *githubReport) file(path string, diags []teal.Diagnostic) error {
+	r.files++
+
+	if len(diags) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(r.w, "::group::%s\n", githubEscapeData(path))
+
+	for _, d := range diags {
+		r.counts[d.Severity()]++
+		r.rules[d.Rule()]++
+
+		fmt.Fprintf(r.w, "::%s file=%s,line=%d,col=%d,endColumn=%d,title=%s::%s\n",
+			githubCommand(d.Severity()),
+			githubEscapeProperty(path),
+			d.Line()+1, d.Begin()+1, d.End(),
+			githubEscapeProperty(d.Rule()),
+			githubEscapeData(d.String()))
+	}
+
+	_, err := fmt.Fprintln(r.w, "::endgroup::")
+	return err
+}
+
+// summary returns the markdown summary of the findings.
+func (r *githubReport) summary() string {
+	var sb strings.Builder
+
+	sb.WriteString("## tealint\n\n")
+	fmt.Fprintf(&sb, "%d file(s): %d error(s), %d warning(s), %d notice(s)\n",
+		r.files, r.counts[teal.DiagErr], r.counts[teal.DiagWarn], r.counts[teal.DiagInfo]+r.counts[teal.DiagHint])
+
+	if len(r.rules) > 0 {
+		sb.WriteString("\n| Rule | Count |\n| --- | --- |\n")
+
+		rules := make([]string, 0, len(r.rules))
+		for rule := range r.rules {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+
+		for _, rule := range rules {
+			fmt.Fprintf(&sb, "| %s | %d |\n", rule, r.rules[rule])
+		}
+	}
+
+	return sb.String()
+}
+
+// finish appends the summary to the file set in GITHUB_STEP_SUMMARY, if any.
+func (r *githubReport) finish() error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open step summary")
+	}
+	defer f.Close()
+
+	if _, err := io.WriteString(f, r.summary()); err != nil {
+		return errors.Wrap(err, "failed to write step summary")
+	}
+
+	return nil
+}
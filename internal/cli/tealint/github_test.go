@@ -0,0 +1,127 @@
+package tealint
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+func TestGithubEscape(t *testing.T) {
+	type test struct {
+		s        string
+		data     string
+		property string
+	}
+
+	tests := []test{
+		{s: "plain", data: "plain", property: "plain"},
+		{s: "100%", data: "100%25", property: "100%25"},
+		{s: "a\r\nb", data: "a%0D%0Ab", property: "a%0D%0Ab"},
+		{s: "a:b,c", data: "a:b,c", property: "a%3Ab%2Cc"},
+	}
+
+	for _, test := range tests {
+		if d := githubEscapeData(test.s); d != test.data {
+			t.Errorf("unexpected data of %q - expected: %s, actual: %s", test.s, test.data, d)
+		}
+
+		if p := githubEscapeProperty(test.s); p != test.property {
+			t.Errorf("unexpected property of %q - expected: %s, actual: %s", test.s, test.property, p)
+		}
+	}
+}
+
+func TestGithubReport(t *testing.T) {
+	type file struct {
+		path  string
+		diags []teal.Diagnostic
+	}
+
+	type test struct {
+		files   []file
+		out     string
+		summary string
+	}
+
+	tests := []test{
+		{
+			files: []file{{path: "clean.teal"}},
+			out:   "",
+			summary: "## tealint\n\n" +
+				"1 file(s): 0 error(s), 0 warning(s), 0 notice(s)\n",
+		},
+		{
+			files: []file{
+				{path: "a.teal", diags: []teal.Diagnostic{
+					teal.NewDiagnostic(0, 0, 4, teal.DiagErr, "PE0001", "unexpected op"),
+					teal.NewDiagnostic(2, 1, 5, teal.DiagWarn, "LINT0001", "unused label: x"),
+				}},
+				{path: "b.teal", diags: []teal.Diagnostic{
+					teal.NewDiagnostic(1, 0, 3, teal.DiagInfo, "LINT0001", "100% done"),
+				}},
+			},
+			out: "::group::a.teal\n" +
+				"::error file=a.teal,line=1,col=1,endColumn=4,title=PE0001::unexpected op\n" +
+				"::warning file=a.teal,line=3,col=2,endColumn=5,title=LINT0001::unused label: x\n" +
+				"::endgroup::\n" +
+				"::group::b.teal\n" +
+				"::notice file=b.teal,line=2,col=1,endColumn=3,title=LINT0001::100%25 done\n" +
+				"::endgroup::\n",
+			summary: "## tealint\n\n" +
+				"2 file(s): 1 error(s), 1 warning(s), 1 notice(s)\n" +
+				"\n| Rule | Count |\n| --- | --- |\n" +
+				"| LINT0001 | 2 |\n" +
+				"| PE0001 | 1 |\n",
+		},
+	}
+
+	for i, test := range tests {
+		var w bytes.Buffer
+		r := newGithubReport(&w)
+
+		for _, f := range test.files {
+			if err := r.file(f.path, f.diags); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if w.String() != test.out {
+			t.Errorf("unexpected output of test %d - expected:\n%s\nactual:\n%s", i, test.out, w.String())
+		}
+
+		if s := r.summary(); s != test.summary {
+			t.Errorf("unexpected summary of test %d - expected:\n%s\nactual:\n%s", i, test.summary, s)
+		}
+	}
+}
+
+func TestGithubStepSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	if err := os.WriteFile(path, []byte("# previous step\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+
+	r := newGithubReport(&bytes.Buffer{})
+	if err := r.file("a.teal", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# previous step\n## tealint\n\n1 file(s): 0 error(s), 0 warning(s), 0 notice(s)\n"
+	if string(bs) != expected {
+		t.Errorf("unexpected step summary - expected:\n%s\nactual:\n%s", expected, bs)
+	}
+}
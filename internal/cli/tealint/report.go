@@ -0,0 +1,126 @@
+package tealint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// reporter prints the diagnostics of the linted files.
+type reporter interface {
+	file(path string, diags []teal.Diagnostic) error
+	finish() error
+}
+
+func newReporter(format string, w io.Writer) (reporter, error) {
+	switch format {
+	case "", "text":
+		return textReport{w: w}, nil
+	case "github":
+		return newGithubReport(w), nil
+	case "jsonl":
+		return newJsonlReport(w), nil
+	default:
+		return nil, errors.Errorf("unknown format: %s", format)
+	}
+}
+
+// textReport prints a line per diagnostic.
+type textReport struct {
+	w io.Writer
+}
+
+func (r textReport) file(path string, diags []teal.Diagnostic) error {
+	for _, d := range diags {
+		_, err := fmt.Fprintf(r.w, "%s:%d:%d: %s: %s [%s]\n", path, d.Line()+1, d.Begin()+1, d.Severity(), d, d.Rule())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r textReport) finish() error {
+	return nil
+}
+
+type jsonlDiagnostic struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+type jsonlSummary struct {
+	Files    int `json:"files"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Infos    int `json:"infos"`
+}
+
+// jsonlReport prints a JSON object per diagnostic as the files are linted and the summary object at the end,
+// the diagnostics are not kept in memory.
+type jsonlReport struct {
+	e *json.Encoder
+	s jsonlSummary
+}
+
+func newJsonlReport(w io.Writer) *jsonlReport {
+	return &jsonlReport{e: json.NewEncoder(w)}
+}
+
+func jsonlSeverity(s teal.DiagnosticSeverity) string {
+	switch s {
+	case teal.DiagErr:
+		return "error"
+	case teal.DiagWarn:
+		return "warning"
+	case teal.DiagInfo:
+		return "info"
+	default:
+		return "hint"
+	}
+}
+
+func (r *jsonlReport) file(path string, diags []teal.Diagnostic) error {
+	r.s.Files++
+
+	for _, d := range diags {
+		switch d.Severity() {
+		case teal.DiagErr:
+			r.s.Errors++
+		case teal.DiagWarn:
+			r.s.Warnings++
+		default:
+			r.s.Infos++
+		}
+
+		err := r.e.Encode(jsonlDiagnostic{
+			Path:      path,
+			Line:      d.Line() + 1,
+			Column:    d.Begin() + 1,
+			EndColumn: d.End(),
+			Severity:  jsonlSeverity(d.Severity()),
+			Rule:      d.Rule(),
+			Message:   d.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *jsonlReport) finish() error {
+	return r.e.Encode(struct {
+		Summary jsonlSummary `json:"summary"`
+	}{r.s})
+}
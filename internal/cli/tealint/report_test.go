@@ -0,0 +1,142 @@
+package tealint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+func TestNewReporter(t *testing.T) {
+	type test struct {
+		format string
+		err    bool
+	}
+
+	tests := []test{
+		{format: ""},
+		{format: "text"},
+		{format: "github"},
+		{format: "jsonl"},
+		{format: "xml", err: true},
+	}
+
+	for _, test := range tests {
+		_, err := newReporter(test.format, &bytes.Buffer{})
+		if (err != nil) != test.err {
+			t.Errorf("unexpected error of format %s: %v", test.format, err)
+		}
+	}
+}
+
+func TestJsonlReport(t *testing.T) {
+	var w bytes.Buffer
+	r := newJsonlReport(&w)
+
+	diags := []teal.Diagnostic{
+		teal.NewDiagnostic(0, 0, 4, teal.DiagErr, "PE0001", "unexpected op"),
+		teal.NewDiagnostic(2, 1, 5, teal.DiagWarn, "LINT0001", "unused label: x"),
+		teal.NewDiagnostic(3, 0, 2, teal.DiagInfo, "LINT0002", "info"),
+		teal.NewDiagnostic(4, 0, 2, teal.DiagHint, "LINT0003", "hint"),
+	}
+
+	if err := r.file("a.teal", diags); err != nil {
+		t.Fatal(err)
+	}
+
+	// the diagnostics are streamed as the files are reported
+	if n := strings.Count(w.String(), "\n"); n != len(diags) {
+		t.Fatalf("unexpected streamed lines count: %d", n)
+	}
+
+	if err := r.file("b.teal", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`{"path":"a.teal","line":1,"column":1,"endColumn":4,"severity":"error","rule":"PE0001","message":"unexpected op"}`,
+		`{"path":"a.teal","line":3,"column":2,"endColumn":5,"severity":"warning","rule":"LINT0001","message":"unused label: x"}`,
+		`{"path":"a.teal","line":4,"column":1,"endColumn":2,"severity":"info","rule":"LINT0002","message":"info"}`,
+		`{"path":"a.teal","line":5,"column":1,"endColumn":2,"severity":"hint","rule":"LINT0003","message":"hint"}`,
+		`{"summary":{"files":2,"errors":1,"warnings":1,"infos":2}}`,
+	}
+
+	lines := strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected lines count - expected: %d, actual: %d\n%s", len(expected), len(lines), w.String())
+	}
+
+	for i, l := range lines {
+		if l != expected[i] {
+			t.Errorf("unexpected line %d - expected: %s, actual: %s", i, expected[i], l)
+		}
+	}
+}
+
+func TestLintJsonl(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a.teal":     "#pragma version 8\nunknown_op\n",
+		"lib/b.teal": "#pragma version 8\nint 1\nreturn\n",
+		"c.txt":      "not teal",
+	}
+
+	for name, s := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var code int
+	var err error
+
+	out := captureStdout(t, func() {
+		code, err = run(args{Path: dir, Format: "jsonl"})
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if code != 1 {
+		t.Errorf("unexpected exit code: %d", code)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	var summary struct {
+		Summary jsonlSummary `json:"summary"`
+	}
+
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("failed to parse summary: %s", err)
+	}
+
+	if summary.Summary.Files != 2 || summary.Summary.Errors == 0 {
+		t.Errorf("unexpected summary: %+v", summary.Summary)
+	}
+
+	for _, l := range lines[:len(lines)-1] {
+		var d jsonlDiagnostic
+		if err := json.Unmarshal([]byte(l), &d); err != nil {
+			t.Fatalf("failed to parse diagnostic: %s", err)
+		}
+
+		if d.Path != filepath.Join(dir, "a.teal") {
+			t.Errorf("unexpected diagnostic path: %s", d.Path)
+		}
+	}
+}
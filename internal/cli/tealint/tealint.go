@@ -0,0 +1,522 @@
+package tealint
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Path string
+
+	Clear      bool
+	Strict     bool
+	Permissive bool
+
+	Lifecycle string
+
+	State  bool
+	Schema string
+
+	Audit string
+
+	Vectors bool
+
+	Group string
+
+	Template bool
+
+	Summarize bool
+
+	Validity bool
+
+	Speedscope bool
+
+	Scratch bool
+
+	Format string
+
+	Watch       bool
+	ClearScreen bool
+
+	Fix    bool
+	Stdout bool
+	Diff   bool
+
+	Explain string
+
+	Policy string
+	ops    []teal.OpPolicy
+
+	Addresses string
+	addrs     []teal.KnownAddress
+
+	Consensus uint64
+	consensus *teal.ConsensusParams
+}
+
+func (a args) schema() (*teal.Schema, error) {
+	if a.Schema == "" {
+		return nil, nil
+	}
+
+	var s teal.Schema
+
+	_, err := fmt.Sscanf(a.Schema, "%d,%d,%d,%d", &s.GlobalNumUint, &s.GlobalNumByteSlice, &s.LocalNumUint, &s.LocalNumByteSlice)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse schema")
+	}
+
+	return &s, nil
+}
+
+type stateReport struct {
+	Path   string           `json:"path"`
+	State  teal.StateSchema `json:"state"`
+	Issues []string         `json:"issues,omitempty"`
+}
+
+type auditReport struct {
+	Approval string          `json:"approval"`
+	Clear    string          `json:"clear"`
+	Audit    teal.StateAudit `json:"audit"`
+}
+
+type vectorsReport struct {
+	Path    string            `json:"path"`
+	Vectors []teal.TestVector `json:"vectors"`
+}
+
+type groupReport struct {
+	Path  string         `json:"path"`
+	Group teal.GroupDeps `json:"group"`
+}
+
+type scratchReport struct {
+	Path    string            `json:"path"`
+	Scratch teal.ScratchUsage `json:"scratch"`
+}
+
+type validityReport struct {
+	Path     string        `json:"path"`
+	Validity teal.Validity `json:"validity"`
+}
+
+type lifecycleReport struct {
+	Path      string         `json:"path"`
+	Lifecycle teal.Lifecycle `json:"lifecycle"`
+}
+
+func (a args) options(path string) []teal.ProcessOption {
+	opts := []teal.ProcessOption{teal.WithPath(path)}
+
+	if a.Clear {
+		opts = append(opts, teal.WithClear())
+	}
+	if a.Strict {
+		opts = append(opts, teal.WithStrict())
+	}
+	if a.Permissive {
+		opts = append(opts, teal.WithPermissive())
+	}
+	if len(a.ops) > 0 {
+		opts = append(opts, teal.WithOpPolicies(a.ops...))
+	}
+	if len(a.addrs) > 0 {
+		opts = append(opts, teal.WithKnownAddresses(a.addrs...))
+	}
+	if a.consensus != nil {
+		opts = append(opts, teal.WithConsensus(*a.consensus))
+	}
+
+	return opts
+}
+
+// walkPaths calls f for the teal file or for each teal file in the dir as they are found.
+func walkPaths(root string, f func(path string) error) error {
+	st, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !st.IsDir() {
+		return f(root)
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrap(err, "failed to walk dir")
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".teal") {
+			return f(path)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func findPaths(root string) ([]string, error) {
+	var paths []string
+
+	err := walkPaths(root, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+
+	return paths, err
+}
+
+// eachPath returns the walker of the paths.
+func eachPath(paths []string) func(f func(path string) error) error {
+	return func(f func(path string) error) error {
+		for _, path := range paths {
+			if err := f(path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// explain prints the rule from the rules registry, all prints the whole rules reference.
+func explain(id string) (int, error) {
+	if id == "all" {
+		fmt.Print(teal.RulesReference())
+		return 0, nil
+	}
+
+	r, ok := teal.LookupRule(id)
+	if !ok {
+		return -1, errors.Errorf("unknown rule: %s", id)
+	}
+
+	fmt.Print(r.Text())
+
+	return 0, nil
+}
+
+func run(a args) (int, error) {
+	if a.Explain != "" {
+		return explain(a.Explain)
+	}
+
+	if a.Policy != "" {
+		ops, err := teal.ReadOpPolicies(a.Policy)
+		if err != nil {
+			return -1, err
+		}
+		a.ops = ops
+	}
+
+	if a.Addresses != "" {
+		addrs, err := teal.ReadKnownAddresses(a.Addresses)
+		if err != nil {
+			return -1, err
+		}
+		a.addrs = addrs
+	}
+
+	if a.Consensus != 0 {
+		p, err := teal.Consensus(a.Consensus)
+		if err != nil {
+			return -1, err
+		}
+		a.consensus = &p
+	}
+
+	if a.Audit != "" {
+		return audit(a)
+	}
+
+	if a.Watch {
+		paths, err := findPaths(a.Path)
+		if err != nil {
+			return -1, err
+		}
+
+		return watch(a, paths)
+	}
+
+	// the files are linted as they are found so the huge dirs are not kept in memory
+	return lint(a, func(f func(path string) error) error {
+		return walkPaths(a.Path, f)
+	})
+}
+
+// audit prints the state audit of the approval program at the path and the clear state program as json,
+// the exit code is 1 if there are any findings.
+func audit(a args) (int, error) {
+	approval, err := teal.ReadSource(a.Path)
+	if err != nil {
+		return -2, errors.Wrap(err, "failed to read approval program")
+	}
+
+	clear, err := teal.ReadSource(a.Audit)
+	if err != nil {
+		return -2, errors.Wrap(err, "failed to read clear state program")
+	}
+
+	r := auditReport{
+		Approval: a.Path,
+		Clear:    a.Audit,
+		Audit: teal.AuditState(
+			teal.Process(string(approval), a.options(a.Path)...),
+			teal.Process(string(clear), append(a.options(a.Audit), teal.WithClear())...),
+		),
+	}
+
+	err = json.NewEncoder(os.Stdout).Encode(r)
+	if err != nil {
+		return -3, errors.Wrap(err, "failed to encode audit")
+	}
+
+	if len(r.Audit.Findings) > 0 {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// lint lints the files of the walker.
+func lint(a args, walk func(f func(path string) error) error) (int, error) {
+	schema, err := a.schema()
+	if err != nil {
+		return -1, err
+	}
+
+	r, err := newReporter(a.Format, os.Stdout)
+	if err != nil {
+		return -4, err
+	}
+
+	l := &linter{a: a, schema: schema, r: r}
+
+	fail := -1
+
+	err = walk(func(path string) error {
+		code, err := l.file(path)
+		if err != nil {
+			fail = code
+		}
+
+		return err
+	})
+	if err != nil {
+		return fail, err
+	}
+
+	if err := r.finish(); err != nil {
+		return -3, errors.Wrap(err, "failed to finish report")
+	}
+
+	return l.code, nil
+}
+
+type linter struct {
+	a      args
+	schema *teal.Schema
+	r      reporter
+
+	// code is the exit code, 1 if any of the files has errors
+	code int
+}
+
+// file lints the file and returns the exit code on failure.
+func (l *linter) file(path string) (int, error) {
+	bs, err := teal.ReadSource(path)
+	if err != nil {
+		return -2, err
+	}
+
+	res := teal.Process(string(bs), l.a.options(path)...)
+
+	if l.a.Fix || l.a.Diff {
+		var report bool
+		res, report, err = l.fix(path, string(bs), res)
+		if err != nil {
+			return -3, err
+		}
+		if !report {
+			return 0, nil
+		}
+	}
+
+	if l.a.State {
+		r := stateReport{
+			Path:  path,
+			State: teal.AnalyzeState(res),
+		}
+
+		if l.schema != nil {
+			r.Issues = r.State.Check(*l.schema)
+			if len(r.Issues) > 0 {
+				l.code = 1
+			}
+		}
+
+		err := json.NewEncoder(os.Stdout).Encode(r)
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode state")
+		}
+		return 0, nil
+	}
+
+	if l.a.Vectors {
+		err := json.NewEncoder(os.Stdout).Encode(vectorsReport{
+			Path:    path,
+			Vectors: teal.GenerateVectors(res),
+		})
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode vectors")
+		}
+		return 0, nil
+	}
+
+	if l.a.Template {
+		if m, ok := teal.MatchTemplate(res); ok {
+			fmt.Printf("%s: %s\n", path, m)
+		} else {
+			fmt.Printf("%s: no template\n", path)
+		}
+		return 0, nil
+	}
+
+	if l.a.Summarize {
+		fmt.Printf("%s: %s\n", path, teal.SynthesizePrecondition(res).Summary())
+		return 0, nil
+	}
+
+	if l.a.Validity {
+		err := json.NewEncoder(os.Stdout).Encode(validityReport{
+			Path:     path,
+			Validity: teal.AnalyzeValidity(res),
+		})
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode validity")
+		}
+		return 0, nil
+	}
+
+	if l.a.Speedscope {
+		c := teal.EstimateCost(res, teal.ProgramBudget(res.Mode))
+		err := c.Path.WriteSpeedscope(os.Stdout, filepath.Base(path), path)
+		if err != nil {
+			return -3, err
+		}
+		return 0, nil
+	}
+
+	if l.a.Scratch {
+		err := json.NewEncoder(os.Stdout).Encode(scratchReport{
+			Path:    path,
+			Scratch: teal.AnalyzeScratch(res),
+		})
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode scratch")
+		}
+		return 0, nil
+	}
+
+	switch l.a.Group {
+	case "":
+	case "summary":
+		fmt.Printf("%s: %s\n", path, teal.AnalyzeGroup(res).Summary())
+		return 0, nil
+	case "mermaid":
+		fmt.Printf("## %s\n\n```mermaid\n%s```\n\n", path, teal.AnalyzeGroup(res).Mermaid())
+		return 0, nil
+	case "json":
+		err := json.NewEncoder(os.Stdout).Encode(groupReport{
+			Path:  path,
+			Group: teal.AnalyzeGroup(res),
+		})
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode group")
+		}
+		return 0, nil
+	default:
+		return -4, errors.Errorf("unknown group format: %s", l.a.Group)
+	}
+
+	switch l.a.Lifecycle {
+	case "":
+	case "md":
+		fmt.Printf("## %s\n\n%s\n", path, teal.AnalyzeLifecycle(res).Markdown())
+		return 0, nil
+	case "json":
+		err := json.NewEncoder(os.Stdout).Encode(lifecycleReport{
+			Path:      path,
+			Lifecycle: teal.AnalyzeLifecycle(res),
+		})
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to encode lifecycle")
+		}
+		return 0, nil
+	default:
+		return -4, errors.Errorf("unknown lifecycle format: %s", l.a.Lifecycle)
+	}
+
+	if err := l.r.file(path, res.Diagnostics); err != nil {
+		return -3, errors.Wrap(err, "failed to report diagnostics")
+	}
+
+	for _, d := range res.Diagnostics {
+		if d.Severity() == teal.DiagErr {
+			l.code = 1
+		}
+	}
+
+	return 0, nil
+}
+
+// Command returns the linter command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "lint",
+		Summary: "lint teal files",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Path, "path", ".", "path to a teal file or a dir to lint")
+			fs.BoolVar(&a.Clear, "clear", false, "lint as a clear state program")
+			fs.BoolVar(&a.Strict, "strict", false, "report selected warnings (unused labels, redundant ops, version mismatch) as errors")
+			fs.BoolVar(&a.Permissive, "permissive", false, "silence style rules, e.g. for disassembled code")
+			fs.StringVar(&a.Lifecycle, "lifecycle", "", "print the application lifecycle matrix instead of linting: md or json")
+			fs.BoolVar(&a.State, "state", false, "print the inferred global and local state keys as json instead of linting")
+			fs.StringVar(&a.Schema, "schema", "", "declared state schema to check the state keys against: GlobalNumUint,GlobalNumByteSlice,LocalNumUint,LocalNumByteSlice")
+			fs.StringVar(&a.Audit, "audit", "", "path to the clear state program audited with the approval program at -path, prints the dead state keys and the missed local state cleanup as json instead of linting")
+			fs.BoolVar(&a.Vectors, "vectors", false, "print the suggested test inputs that exercise the branch conditions as json instead of linting")
+			fs.StringVar(&a.Group, "group", "", "print the expected group transactions instead of linting: summary, mermaid or json")
+			fs.BoolVar(&a.Template, "template", false, "print the standard template (htlc, periodic-payment, delegated-asset-transfer) the program is an instance of with its parameters instead of linting")
+			fs.BoolVar(&a.Summarize, "summarize", false, "print the precondition over the transaction fields and the group size the approval of the logic signature requires instead of linting")
+			fs.BoolVar(&a.Validity, "validity", false, "print the temporal constraints over the validity rounds, the round and the timestamp the program enforces as json instead of linting")
+			fs.BoolVar(&a.Speedscope, "speedscope", false, "print the speedscope profile of the budget consumed by the subroutines on the most expensive path instead of linting")
+			fs.BoolVar(&a.Scratch, "scratch", false, "print the used scratch slots, the free holes between them and the max number of the slots live at once as json instead of linting")
+			fs.StringVar(&a.Format, "format", "text", "diagnostics output format: text, github (GitHub Actions workflow commands with a step summary) or jsonl")
+			fs.BoolVar(&a.Watch, "watch", false, "watch the path and lint the changed files again")
+			fs.BoolVar(&a.ClearScreen, "clear-screen", false, "clear the screen before each run in the watch mode")
+			fs.BoolVar(&a.Fix, "fix", false, "apply the safe fixes (version update, redundant lines removal, named fields) to the files in place")
+			fs.BoolVar(&a.Stdout, "stdout", false, "print the fixed sources instead of writing the files, used with -fix")
+			fs.BoolVar(&a.Diff, "diff", false, "print the diff of the safe fixes instead of applying them")
+			fs.StringVar(&a.Policy, "policy", "", "path to a JSON file of the op policies reporting the forbidden opcodes as errors")
+			fs.StringVar(&a.Addresses, "addresses", "", "path to a JSON file of the known addresses added to the built-in ones")
+			fs.Uint64Var(&a.Consensus, "consensus", 0, "check the programs against the limits of the consensus version, e.g. 36")
+			fs.StringVar(&a.Explain, "explain", "", "print the description and the examples of a rule, e.g. LINT0005, or all for the markdown rules reference")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
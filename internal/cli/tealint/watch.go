@@ -0,0 +1,203 @@
+package tealint
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// watchDelay is the time to wait for more changes before linting, editors often write a file in several steps.
+const watchDelay = 100 * time.Millisecond
+
+type watcher struct {
+	a    args
+	root string
+	file bool
+
+	w *fsnotify.Watcher
+}
+
+// add watches the dir and its subdirs.
+func (w *watcher) add(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if err := w.w.Add(path); err != nil {
+				return errors.Wrapf(err, "failed to watch dir: %s", path)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (w *watcher) match(path string) bool {
+	if w.file {
+		return filepath.Clean(path) == w.root
+	}
+
+	return strings.HasSuffix(path, ".teal")
+}
+
+func (w *watcher) header(msg string) {
+	if w.a.ClearScreen {
+		fmt.Print("\033[H\033[2J")
+	}
+
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), msg)
+}
+
+// lint lints the paths, the files failing to be read are reported without stopping the watch.
+func (w *watcher) lint(paths []string) error {
+	_, err := lint(w.a, func(f func(path string) error) error {
+		for _, path := range paths {
+			if err := f(path); err != nil {
+				fmt.Printf("%s: %s\n", path, err)
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// changed lints the changed paths that still exist.
+func (w *watcher) changed(changed map[string]bool) error {
+	var paths []string
+	for path := range changed {
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("%s: removed\n", path)
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return w.lint(paths)
+}
+
+// event records the paths changed by the event, it returns false if none of the linted files changed.
+func (w *watcher) event(e fsnotify.Event, changed map[string]bool) (bool, error) {
+	if e.Has(fsnotify.Create) && !w.file {
+		if st, err := os.Stat(e.Name); err == nil && st.IsDir() {
+			if err := w.add(e.Name); err != nil {
+				return false, err
+			}
+
+			// the files created in the dir before it is watched are linted too
+			paths, err := findPaths(e.Name)
+			if err != nil {
+				fmt.Println(err)
+			}
+
+			for _, path := range paths {
+				changed[filepath.Clean(path)] = true
+			}
+
+			return len(paths) > 0, nil
+		}
+	}
+
+	if !w.match(e.Name) || e.Op == fsnotify.Chmod {
+		return false, nil
+	}
+
+	changed[filepath.Clean(e.Name)] = true
+
+	return true, nil
+}
+
+func watch(a args, paths []string) (int, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return -5, errors.Wrap(err, "failed to create watcher")
+	}
+	defer fw.Close()
+
+	st, err := os.Stat(a.Path)
+	if err != nil {
+		return -5, err
+	}
+
+	w := &watcher{
+		a:    a,
+		root: filepath.Clean(a.Path),
+		file: !st.IsDir(),
+		w:    fw,
+	}
+
+	if w.file {
+		// the dir is watched as editors replace the file on save
+		err = fw.Add(filepath.Dir(w.root))
+	} else {
+		err = w.add(w.root)
+	}
+	if err != nil {
+		return -5, err
+	}
+
+	w.header(fmt.Sprintf("linting %d file(s)", len(paths)))
+	if err := w.lint(paths); err != nil {
+		fmt.Println(err)
+	}
+
+	changed := map[string]bool{}
+
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case e, ok := <-fw.Events:
+			if !ok {
+				return 0, nil
+			}
+
+			ok, err := w.event(e, changed)
+			if err != nil {
+				return -5, err
+			}
+
+			if ok {
+				timer.Reset(watchDelay)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return 0, nil
+			}
+
+			return -5, errors.Wrap(err, "failed to watch")
+		case <-timer.C:
+			names := make([]string, 0, len(changed))
+			for path := range changed {
+				names = append(names, path)
+			}
+			sort.Strings(names)
+
+			w.header(fmt.Sprintf("changed: %s", strings.Join(names, ", ")))
+
+			if err := w.changed(changed); err != nil {
+				fmt.Println(err)
+			}
+
+			changed = map[string]bool{}
+		}
+	}
+}
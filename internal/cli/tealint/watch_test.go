@@ -0,0 +1,195 @@
+package tealint
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan string)
+	go func() {
+		bs, _ := io.ReadAll(r)
+		done <- string(bs)
+	}()
+
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	f()
+
+	w.Close()
+	return <-done
+}
+
+func TestWatchMatch(t *testing.T) {
+	type test struct {
+		root  string
+		file  bool
+		path  string
+		match bool
+	}
+
+	tests := []test{
+		{root: "src", path: "src/main.teal", match: true},
+		{root: "src", path: "src/lib/math.teal", match: true},
+		{root: "src", path: "src/main.teal.swp", match: false},
+		{root: "src", path: "src/README.md", match: false},
+		{root: "src/main.teal", file: true, path: "src/main.teal", match: true},
+		{root: "src/main.teal", file: true, path: "src/./main.teal", match: true},
+		{root: "src/main.teal", file: true, path: "src/other.teal", match: false},
+	}
+
+	for _, test := range tests {
+		w := &watcher{root: filepath.Clean(test.root), file: test.file}
+
+		if m := w.match(filepath.FromSlash(test.path)); m != test.match {
+			t.Errorf("unexpected match of %s in %s - expected: %t, actual: %t", test.path, test.root, test.match, m)
+		}
+	}
+}
+
+func TestWatchAdd(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib", "math"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	w := &watcher{root: dir, w: fw}
+	if err := w.add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(fw.WatchList()); l != 3 {
+		t.Errorf("unexpected watched dirs count: %d", l)
+	}
+}
+
+func TestWatchLint(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.teal")
+	invalid := filepath.Join(dir, "invalid.teal")
+	removed := filepath.Join(dir, "removed.teal")
+	binary := filepath.Join(dir, "binary.teal")
+
+	if err := os.WriteFile(valid, []byte("#pragma version 8\nint 1\nreturn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(invalid, []byte("#pragma version 8\nunknown_op\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(binary, []byte{0xff, 0xfe, 0x00, 0x01}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &watcher{a: args{Path: dir}, root: dir}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = w.changed(map[string]bool{binary: true, valid: true, invalid: true, removed: true})
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !strings.Contains(out, removed+": removed") {
+		t.Errorf("missing removed file in the output:\n%s", out)
+	}
+
+	// the file failing to be read does not stop the linting of the others
+	if !strings.Contains(out, binary+": invalid source file") {
+		t.Errorf("missing read error in the output:\n%s", out)
+	}
+
+	if !strings.Contains(out, invalid+":2") || strings.Contains(out, valid+":") {
+		t.Errorf("unexpected diagnostics in the output:\n%s", out)
+	}
+}
+
+func TestWatchEvent(t *testing.T) {
+	dir := t.TempDir()
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	w := &watcher{root: dir, w: fw}
+	if err := w.add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// the dir is created with the files before its watch is added
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(filepath.Join(sub, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.teal", "nested/b.teal", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(sub, filepath.FromSlash(name)), []byte("#pragma version 8\nint 1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type test struct {
+		e       fsnotify.Event
+		ok      bool
+		changed []string
+	}
+
+	tests := []test{
+		{e: fsnotify.Event{Name: sub, Op: fsnotify.Create}, ok: true, changed: []string{filepath.Join(sub, "a.teal"), filepath.Join(sub, "nested", "b.teal")}},
+		{e: fsnotify.Event{Name: filepath.Join(dir, "main.teal"), Op: fsnotify.Write}, ok: true, changed: []string{filepath.Join(dir, "main.teal")}},
+		{e: fsnotify.Event{Name: filepath.Join(dir, "main.teal"), Op: fsnotify.Chmod}},
+		{e: fsnotify.Event{Name: filepath.Join(dir, "notes.txt"), Op: fsnotify.Write}},
+	}
+
+	for _, test := range tests {
+		changed := map[string]bool{}
+
+		ok, err := w.event(test.e, changed)
+		if err != nil {
+			t.Fatalf("unexpected error of %s: %s", test.e, err)
+		}
+
+		if ok != test.ok || len(changed) != len(test.changed) {
+			t.Errorf("unexpected changes of %s - expected: %v, actual: %t %v", test.e, test.changed, ok, changed)
+			continue
+		}
+
+		for _, path := range test.changed {
+			if !changed[path] {
+				t.Errorf("missing change of %s: %s", test.e, path)
+			}
+		}
+	}
+
+	if l := len(fw.WatchList()); l != 3 {
+		t.Errorf("expected the created dirs watched, got: %v", fw.WatchList())
+	}
+}
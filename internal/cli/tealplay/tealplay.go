@@ -0,0 +1,311 @@
+package tealplay
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+//go:embed static
+var static embed.FS
+
+// maxSourceSize limits the size of the posted requests
+const maxSourceSize = 1 << 20
+
+// maxBranches limits the branches run by the simulation
+const maxBranches = 100
+
+type args struct {
+	Addr string
+}
+
+type request struct {
+	Source string `json:"source"`
+	Clear  bool   `json:"clear,omitempty"`
+
+	// simulate only: the goal style app args - str:, int:, b64: or hex: prefixed, the OnCompletion name and the app creation
+	Args         []string `json:"args,omitempty"`
+	OnCompletion string   `json:"onCompletion,omitempty"`
+	Create       bool     `json:"create,omitempty"`
+}
+
+// readFile refuses the includes, the posted sources must not read the files of the server.
+func readFile(path string) ([]byte, error) {
+	return nil, errors.New("includes are not supported in the playground")
+}
+
+func (r request) process() *teal.ProcessResult {
+	opts := []teal.ProcessOption{teal.WithReadFile(readFile)}
+	if r.Clear {
+		opts = append(opts, teal.WithClear())
+	}
+
+	return teal.Process(r.Source, opts...)
+}
+
+type diagnostic struct {
+	Line     int    `json:"line"`
+	Begin    int    `json:"begin"`
+	End      int    `json:"end"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+type lintResponse struct {
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type assembleResponse struct {
+	Version uint64           `json:"version"`
+	Size    int              `json:"size"`
+	Cost    int              `json:"cost"`
+	Listing []teal.ListingOp `json:"listing"`
+}
+
+type branch struct {
+	Name  string   `json:"name"`
+	Exit  string   `json:"exit"`
+	Cost  int      `json:"cost"`
+	Error string   `json:"error,omitempty"`
+	Lines []int    `json:"lines"`
+	Logs  []string `json:"logs,omitempty"`
+}
+
+type simulateResponse struct {
+	Branches   []branch `json:"branches"`
+	Incomplete bool     `json:"incomplete,omitempty"`
+}
+
+func diagnostics(res *teal.ProcessResult) []diagnostic {
+	ds := []diagnostic{}
+	for _, d := range res.Diagnostics {
+		ds = append(ds, diagnostic{
+			Line:     d.Line(),
+			Begin:    d.Begin(),
+			End:      d.End(),
+			Severity: d.Severity().String(),
+			Rule:     d.Rule(),
+			Message:  d.String(),
+		})
+	}
+
+	return ds
+}
+
+// parseArg parses a goal style app arg.
+func parseArg(s string) ([]byte, error) {
+	kind, v, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, errors.Errorf("missing arg type: %s", s)
+	}
+
+	switch kind {
+	case "str":
+		return []byte(v), nil
+	case "int":
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid int arg: %s", v)
+		}
+		bs := make([]byte, 8)
+		binary.BigEndian.PutUint64(bs, n)
+		return bs, nil
+	case "b64":
+		bs, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid b64 arg: %s", v)
+		}
+		return bs, nil
+	case "hex":
+		bs, err := hex.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid hex arg: %s", v)
+		}
+		return bs, nil
+	default:
+		return nil, errors.Errorf("unknown arg type: %s", kind)
+	}
+}
+
+// simulate runs the program paths in the offline VM with the known app args and transaction fields.
+func simulate(r request) (simulateResponse, error) {
+	res := r.process()
+
+	vm := teal.NewVm(res)
+
+	for _, s := range r.Args {
+		bs, err := parseArg(s)
+		if err != nil {
+			return simulateResponse{}, err
+		}
+		vm.Args = append(vm.Args, bs)
+	}
+
+	oc := teal.NoOp
+	if r.Clear {
+		oc = teal.ClearState
+	}
+
+	if r.OnCompletion != "" {
+		found := false
+		for i, name := range teal.OnCompletionNames {
+			if name == r.OnCompletion {
+				oc = teal.OnCompletionConstType(i)
+				found = true
+			}
+		}
+
+		if !found {
+			return simulateResponse{}, errors.Errorf("unknown OnCompletion: %s", r.OnCompletion)
+		}
+	}
+
+	id := uint64(1)
+	if r.Create {
+		id = 0
+	}
+
+	vm.Txn = map[teal.TxnField]uint64{
+		teal.ApplicationID: id,
+		teal.OnCompletion:  uint64(oc),
+	}
+
+	budget := teal.ProgramBudget(res.Mode)
+	for _, b := range vm.Branches {
+		b.Budget = budget
+	}
+
+	resp := simulateResponse{
+		Branches:   []branch{},
+		Incomplete: !vm.RunAll(maxBranches),
+	}
+
+	for _, b := range vm.Branches {
+		br := branch{
+			Name:  b.Name,
+			Exit:  b.Exit.String(),
+			Cost:  budget - b.Budget,
+			Lines: []int{},
+		}
+
+		if b.Err != nil {
+			br.Error = b.Err.Error()
+		}
+
+		for _, i := range b.Lines {
+			br.Lines = append(br.Lines, res.SourceLine(i))
+		}
+
+		for _, l := range b.Logs {
+			br.Logs = append(br.Logs, l.String())
+		}
+
+		resp.Branches = append(resp.Branches, br)
+	}
+
+	return resp, nil
+}
+
+// handle decodes the posted request and encodes the response of the endpoint.
+func handle(f func(r request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, hr *http.Request) {
+		if hr.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var r request
+
+		err := json.NewDecoder(http.MaxBytesReader(w, hr.Body, maxSourceSize)).Decode(&r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := f(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// Handler returns the handler of the playground UI and of its endpoints.
+func Handler() (http.Handler, error) {
+	ui, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the playground assets")
+	}
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/", http.FileServer(http.FS(ui)))
+
+	mux.HandleFunc("/api/lint", handle(func(r request) (any, error) {
+		return lintResponse{Diagnostics: diagnostics(r.process())}, nil
+	}))
+
+	mux.HandleFunc("/api/assemble", handle(func(r request) (any, error) {
+		res := r.process()
+		return assembleResponse{
+			Version: res.Version,
+			Size:    teal.EstimateSize(res),
+			Cost:    teal.EstimateCost(res, teal.ProgramBudget(res.Mode)).Max,
+			Listing: res.Listing.Ops(),
+		}, nil
+	}))
+
+	mux.HandleFunc("/api/simulate", handle(func(r request) (any, error) {
+		return simulate(r)
+	}))
+
+	return mux, nil
+}
+
+func run(a args) error {
+	h, err := Handler()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("serving the playground at http://%s\n", a.Addr)
+
+	err = http.ListenAndServe(a.Addr, h)
+	if err != nil {
+		return errors.Wrap(err, "failed to serve the playground")
+	}
+
+	return nil
+}
+
+// Command returns the playground command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "play",
+		Summary: "serve a web playground to lint, assemble and simulate programs in the offline VM",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Addr, "addr", "localhost:8080", "address to serve the playground at")
+		},
+		Run: func() (int, error) {
+			return 0, run(a)
+		},
+	}
+}
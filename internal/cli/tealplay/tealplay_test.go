@@ -0,0 +1,183 @@
+package tealplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseArg(t *testing.T) {
+	type test struct {
+		s   string
+		v   []byte
+		err bool
+	}
+
+	tests := []test{
+		{s: "str:abc", v: []byte("abc")},
+		{s: "str:a:b", v: []byte("a:b")},
+		{s: "int:258", v: []byte{0, 0, 0, 0, 0, 0, 1, 2}},
+		{s: "b64:AQI=", v: []byte{1, 2}},
+		{s: "hex:0102", v: []byte{1, 2}},
+		{s: "int:-1", err: true},
+		{s: "b64:!", err: true},
+		{s: "hex:0", err: true},
+		{s: "abc", err: true},
+		{s: "addr:abc", err: true},
+	}
+
+	for _, test := range tests {
+		v, err := parseArg(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("unexpected error of %s: %v", test.s, err)
+			continue
+		}
+
+		if !bytes.Equal(v, test.v) {
+			t.Errorf("unexpected value of %s - expected: %x, actual: %x", test.s, test.v, v)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h, err := Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		method string
+		path   string
+		body   string
+		status int
+		check  func(t *testing.T, body []byte)
+	}
+
+	tests := []test{
+		{method: http.MethodGet, path: "/", status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			if !strings.Contains(string(body), "<html") {
+				t.Errorf("unexpected page: %s", body)
+			}
+		}},
+		{method: http.MethodGet, path: "/api/lint", status: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/api/lint", body: "{", status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/api/lint", body: `{"source": "#pragma version 8\nunknown_op\n"}`, status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			var r lintResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			found := false
+			for _, d := range r.Diagnostics {
+				if d.Line == 1 && d.Severity == "error" && strings.Contains(d.Message, "unknown_op") {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("missing unknown op diagnostic: %+v", r.Diagnostics)
+			}
+		}},
+		{method: http.MethodPost, path: "/api/assemble", body: `{"source": "#pragma version 8\nint 1\nreturn\n"}`, status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			var r assembleResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Version != 8 || r.Size == 0 || r.Cost != 2 || len(r.Listing) == 0 {
+				t.Errorf("unexpected assemble response: %+v", r)
+			}
+		}},
+		{method: http.MethodPost, path: "/api/simulate", body: `{"source": "#pragma version 8\ntxna ApplicationArgs 0\nbyte \"yes\"\n==\nreturn\n", "args": ["str:yes"]}`, status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			var r simulateResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Incomplete || len(r.Branches) != 1 || r.Branches[0].Exit != "approve" || len(r.Branches[0].Lines) != 4 {
+				t.Errorf("unexpected simulate response: %+v", r)
+			}
+		}},
+		{method: http.MethodPost, path: "/api/simulate", body: `{"source": "#pragma version 8\nint 1\n", "onCompletion": "Missing"}`, status: http.StatusBadRequest},
+		// the includes do not read the files of the server
+		{method: http.MethodPost, path: "/api/lint", body: `{"source": "#pragma version 8\n#include \"/etc/hostname\"\n#include \"/missing/file.teal\"\nint 1\n"}`, status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			var r lintResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			refused := 0
+			for _, d := range r.Diagnostics {
+				if strings.Contains(d.Message, "includes are not supported in the playground") {
+					refused++
+				}
+
+				if strings.Contains(d.Message, "no such file") {
+					t.Errorf("unexpected file system error: %s", d.Message)
+				}
+			}
+
+			if refused != 2 {
+				t.Errorf("expected both includes refused: %+v", r.Diagnostics)
+			}
+		}},
+		{method: http.MethodPost, path: "/api/simulate", body: `{"source": "#pragma version 8\n#include \"/etc/hostname\"\nint 1\nreturn\n"}`, status: http.StatusOK, check: func(t *testing.T, body []byte) {
+			var r simulateResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(r.Branches) != 1 || len(r.Branches[0].Lines) != 2 {
+				t.Errorf("unexpected simulate response: %+v", r)
+			}
+		}},
+		{method: http.MethodPost, path: "/api/simulate", body: `{"source": "#pragma version 8\nint 1\n", "args": ["abc"]}`, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("unexpected status of %s %s - expected: %d, actual: %d, body: %s", test.method, test.path, test.status, w.Code, w.Body.String())
+			continue
+		}
+
+		if test.check != nil {
+			test.check(t, w.Body.Bytes())
+		}
+	}
+}
+
+func TestSimulateOnCompletion(t *testing.T) {
+	src := "#pragma version 8\ntxn OnCompletion\nint DeleteApplication\n==\ntxn ApplicationID\n&&\nreturn\n"
+
+	type test struct {
+		oc     string
+		create bool
+		exit   string
+	}
+
+	tests := []test{
+		{oc: "DeleteApplication", exit: "approve"},
+		{oc: "DeleteApplication", create: true, exit: "reject"},
+		{oc: "NoOp", exit: "reject"},
+		{exit: "reject"},
+	}
+
+	for _, test := range tests {
+		r, err := simulate(request{Source: src, OnCompletion: test.oc, Create: test.create})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(r.Branches) != 1 || r.Branches[0].Exit != test.exit {
+			t.Errorf("unexpected branches of %s (create: %t) - expected: %s, actual: %+v", test.oc, test.create, test.exit, r.Branches)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tealprof
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Source    string
+	SourceMap string
+	Simulate  string
+
+	Group   int
+	Txn     int
+	Program string
+
+	Format string
+	Out    string
+}
+
+// profile runs the program in the VM or maps the simulate trace of the transaction program to the source.
+func (a args) profile(res *teal.ProcessResult) (teal.Profile, error) {
+	name := filepath.Base(a.Source)
+
+	if a.Simulate == "" {
+		c := teal.EstimateCost(res, teal.ProgramBudget(res.Mode))
+		return c.Path.Profile(name, a.Source), nil
+	}
+
+	if a.SourceMap == "" {
+		return teal.Profile{}, errors.New("missing source map of the simulated program")
+	}
+
+	m, err := teal.ReadSourceMap(a.SourceMap)
+	if err != nil {
+		return teal.Profile{}, err
+	}
+
+	bs, err := os.ReadFile(a.Simulate)
+	if err != nil {
+		return teal.Profile{}, errors.Wrap(err, "failed to read simulate response")
+	}
+
+	gs, err := teal.ParseSimulateTraces(bs)
+	if err != nil {
+		return teal.Profile{}, err
+	}
+
+	if a.Group < 0 || a.Group >= len(gs) || a.Txn < 0 || a.Txn >= len(gs[a.Group]) {
+		return teal.Profile{}, errors.Errorf("no transaction %d in group %d", a.Txn, a.Group)
+	}
+
+	t := gs[a.Group][a.Txn]
+
+	var pcs []int
+	switch a.Program {
+	case "approval":
+		pcs = t.Approval
+	case "clear":
+		pcs = t.ClearState
+	case "logicsig":
+		pcs = t.LogicSig
+	default:
+		return teal.Profile{}, errors.Errorf("unknown program: %s", a.Program)
+	}
+
+	return teal.SimulateProfile(res, m, pcs, name, a.Source)
+}
+
+func run(a args) (int, error) {
+	if a.Source == "" {
+		return -1, errors.New("missing source file")
+	}
+
+	bs, err := teal.ReadSource(a.Source)
+	if err != nil {
+		return -1, err
+	}
+
+	res := teal.Process(string(bs), teal.WithPath(a.Source))
+
+	p, err := a.profile(res)
+	if err != nil {
+		return -2, err
+	}
+
+	var w io.Writer = os.Stdout
+	if a.Out != "" {
+		f, err := os.Create(a.Out)
+		if err != nil {
+			return -3, errors.Wrap(err, "failed to create output file")
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	switch a.Format {
+	case "speedscope":
+		err = p.WriteSpeedscope(w)
+	case "pprof":
+		err = p.WritePprof(w)
+	default:
+		return -4, errors.Errorf("unknown format: %s", a.Format)
+	}
+
+	if err != nil {
+		return -3, err
+	}
+
+	return 0, nil
+}
+
+// Command returns the profile export command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "profile",
+		Summary: "export the budget of the most expensive VM path or the ops of an algod simulate trace by line and subroutine",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Source, "src", "", "teal source file")
+			fs.StringVar(&a.SourceMap, "sourcemap", "", "source map of the compiled program, used with -simulate")
+			fs.StringVar(&a.Simulate, "simulate", "", "algod simulate response with the exec trace, the VM is used if empty")
+			fs.IntVar(&a.Group, "group", 0, "group index of the simulated transaction")
+			fs.IntVar(&a.Txn, "txn", 0, "index of the simulated transaction in the group")
+			fs.StringVar(&a.Program, "program", "approval", "simulated program: approval, clear or logicsig")
+			fs.StringVar(&a.Format, "format", "speedscope", "output format: speedscope or pprof")
+			fs.StringVar(&a.Out, "out", "", "output file, stdout if empty")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
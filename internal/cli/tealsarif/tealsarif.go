@@ -0,0 +1,296 @@
+package tealsarif
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/dragmz/teal/internal/sarif"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Path string
+
+	Strict     bool
+	Permissive bool
+
+	Policy string
+}
+
+func ToSarifLevel(s teal.DiagnosticSeverity) string {
+	switch s {
+	case teal.DiagInfo:
+		return "note"
+	case teal.DiagHint:
+		return "note"
+	case teal.DiagWarn:
+		return "warning"
+	case teal.DiagErr:
+		return "error"
+	default:
+		panic("unexpected severity")
+	}
+}
+
+// rulesUri is the rules reference generated from the rules registry.
+const rulesUri = "https://github.com/dragmz/teal/blob/main/RULES.md"
+
+// sarifRules converts the rules info to the SARIF rules, mapping the security rules to the CWE taxonomy.
+func sarifRules(infos []teal.RuleInfo) ([]sarif.Rule, []sarif.ToolComponent) {
+	var rules []sarif.Rule
+
+	cwe := sarif.ToolComponent{
+		Name:           "CWE",
+		Organization:   "MITRE",
+		InformationUri: "https://cwe.mitre.org/",
+	}
+
+	taxa := map[int]bool{}
+
+	for _, info := range infos {
+		r := sarif.Rule{
+			Id: info.Id,
+			ShortDescription: sarif.Description{
+				Text: info.Desc,
+			},
+			FullDescription: &sarif.Description{
+				Text: info.Rationale,
+			},
+			HelpUri: rulesUri + "#" + info.Anchor(),
+			Help: &sarif.Message{
+				Text:     info.Text(),
+				Markdown: info.Markdown(),
+			},
+			DefaultConfiguration: &sarif.Configuration{
+				Level: ToSarifLevel(info.Level),
+			},
+		}
+
+		if info.Security() {
+			tags := []string{"security"}
+
+			for _, c := range info.Cwe {
+				id := strconv.Itoa(c.Id)
+
+				tags = append(tags, "external/cwe/cwe-"+id)
+				r.Relationships = append(r.Relationships, sarif.Relationship{
+					Target: sarif.ReportingDescriptorReference{
+						Id:            id,
+						ToolComponent: &sarif.ToolComponentReference{Name: cwe.Name},
+					},
+					Kinds: []string{"superset"},
+				})
+
+				if !taxa[c.Id] {
+					taxa[c.Id] = true
+					cwe.Taxa = append(cwe.Taxa, sarif.Taxon{
+						Id:               id,
+						ShortDescription: &sarif.Description{Text: c.Name},
+						HelpUri:          fmt.Sprintf("https://cwe.mitre.org/data/definitions/%d.html", c.Id),
+					})
+				}
+			}
+
+			r.Properties = &sarif.Properties{Tags: tags}
+		}
+
+		rules = append(rules, r)
+	}
+
+	if len(cwe.Taxa) == 0 {
+		return rules, nil
+	}
+
+	return rules, []sarif.ToolComponent{cwe}
+}
+
+func run(a args) error {
+	var ops []teal.OpPolicy
+	if a.Policy != "" {
+		var err error
+		ops, err = teal.ReadOpPolicies(a.Policy)
+		if err != nil {
+			return err
+		}
+	}
+
+	sr := sarif.Results{
+		Version: "2.1.0",
+		Schema:  "http://json.schemastore.org/sarif-2.1.0-rtm.4",
+		Runs:    []sarif.Run{},
+	}
+
+	rules, taxonomies := sarifRules(teal.Rules())
+
+	indexes := map[string]int{}
+	for i, r := range rules {
+		indexes[r.Id] = i
+	}
+
+	run := sarif.Run{
+		Tool: sarif.Tool{
+			Driver: sarif.Driver{
+				Name:           "tealscan",
+				InformationUri: "https://github.com/dragmz/teal",
+				Rules:          rules,
+			},
+		},
+		Artifacts:  []sarif.Artifact{},
+		Results:    []sarif.Result{},
+		Taxonomies: taxonomies,
+	}
+
+	var paths []string
+
+	fi, err := os.Stat(a.Path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read path")
+	}
+
+	if fi.IsDir() {
+		err := filepath.WalkDir(a.Path, func(path string, d fs.DirEntry, err error) error {
+			if !d.IsDir() {
+				if filepath.Ext(path) == ".teal" {
+					paths = append(paths, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to walk dir")
+		}
+	} else {
+		paths = append(paths, a.Path)
+	}
+
+	for _, path := range paths {
+		s, err := teal.ReadSource(path)
+		if err != nil {
+			return err
+		}
+
+		opts := []teal.ProcessOption{teal.WithPath(path)}
+		if a.Strict {
+			opts = append(opts, teal.WithStrict())
+		}
+		if a.Permissive {
+			opts = append(opts, teal.WithPermissive())
+		}
+		if len(ops) > 0 {
+			opts = append(opts, teal.WithOpPolicies(ops...))
+		}
+
+		res := teal.Process(string(s), opts...)
+
+		ab, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		u := url.URL{
+			Scheme: "file",
+			Path:   ab,
+		}
+
+		up := u.String()
+
+		artifact := sarif.Artifact{
+			Location: sarif.Location{
+				Uri: up,
+			},
+		}
+
+		if m := res.Metadata; !m.Empty() {
+			if m.Description != "" {
+				artifact.Description = &sarif.Message{Text: m.Description}
+			}
+
+			artifact.Properties = map[string]interface{}{}
+			for k, v := range m.Extra {
+				artifact.Properties[k] = v
+			}
+			if m.Name != "" {
+				artifact.Properties["name"] = m.Name
+			}
+			if m.Author != "" {
+				artifact.Properties["author"] = m.Author
+			}
+			if m.Version != "" {
+				artifact.Properties["version"] = m.Version
+			}
+			if len(m.Tags) > 0 {
+				artifact.Properties["tags"] = m.Tags
+			}
+		}
+
+		run.Artifacts = append(run.Artifacts, artifact)
+
+		for i, d := range res.Diagnostics {
+			var index *int
+			if ri, ok := indexes[d.Rule()]; ok {
+				index = &ri
+			}
+
+			run.Results = append(run.Results, sarif.Result{
+				RuleId:    d.Rule(),
+				RuleIndex: index,
+				Level:     ToSarifLevel(d.Severity()),
+				Message: sarif.Message{
+					Text: d.String(),
+				},
+				Locations: []sarif.ResultLocation{
+					{
+						PhysicalLocation: sarif.PhysicalLocation{
+							ArtifactLocation: sarif.ArtifactLocation{
+								Uri:   up,
+								Index: i,
+							},
+							Region: sarif.Region{
+								StartLine:   d.Line() + 1,
+								StartColumn: d.Begin() + 1,
+							},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	sr.Runs = append(sr.Runs, run)
+
+	rb, err := json.MarshalIndent(sr, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(rb))
+
+	return err
+}
+
+// Command returns the SARIF report command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "sarif",
+		Summary: "print the diagnostics of teal files as a SARIF report",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Path, "path", "", "path to scan")
+			fs.BoolVar(&a.Strict, "strict", false, "report selected warnings (unused labels, redundant ops, version mismatch) as errors")
+			fs.BoolVar(&a.Permissive, "permissive", false, "silence style rules, e.g. for disassembled code")
+			fs.StringVar(&a.Policy, "policy", "", "path to a JSON file of the op policies reporting the forbidden opcodes as errors")
+		},
+		Run: func() (int, error) {
+			return 0, run(a)
+		},
+	}
+}
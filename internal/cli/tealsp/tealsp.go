@@ -0,0 +1,153 @@
+package tealsp
+
+import (
+	"flag"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dragmz/teal/lsp"
+
+	"github.com/dragmz/teal/internal/cli"
+	"github.com/pkg/errors"
+)
+
+type args struct {
+	Debug    string
+	LogLevel string
+	Strict   bool
+
+	Addr string
+	Net  string
+
+	Lsif string
+	Path string
+
+	Index string
+}
+
+// defaultIndexDir is the dir of the persisted workspace indexes in the user cache dir, empty if there is none.
+func defaultIndexDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "teal", "lsp")
+}
+
+// lsif writes the LSIF dump of the teal files of the path.
+func lsif(a args) (int, error) {
+	var paths []string
+
+	err := filepath.WalkDir(a.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrap(err, "failed to walk dir")
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".teal") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	root := a.Path
+	if st, err := os.Stat(root); err == nil && !st.IsDir() {
+		root = filepath.Dir(root)
+	}
+
+	f, err := os.Create(a.Lsif)
+	if err != nil {
+		return -2, errors.Wrap(err, "failed to create lsif file")
+	}
+	defer f.Close()
+
+	err = lsp.WriteLsif(f, root, paths)
+	if err != nil {
+		return -3, err
+	}
+
+	return 0, errors.Wrap(f.Close(), "failed to close lsif file")
+}
+
+func run(a args) (int, error) {
+	if a.Lsif != "" {
+		return lsif(a)
+	}
+
+	var r io.Reader
+	var w io.Writer
+
+	if a.Addr != "" && a.Net != "" {
+		c, err := net.Dial(a.Net, a.Addr)
+		if err != nil {
+			return -1, errors.Wrap(err, "failed to connect to the client")
+		}
+
+		r = c
+		w = c
+	} else {
+		r = os.Stdin
+		w = os.Stdout
+	}
+
+	var opts []lsp.LspOption
+	if a.Debug != "" {
+		level, err := lsp.ParseLogLevel(a.LogLevel)
+		if err != nil {
+			return -2, err
+		}
+
+		f, err := os.Create(a.Debug)
+		if err != nil {
+			return -2, errors.Wrap(err, "failed to create debug output file")
+		}
+
+		opts = append(opts, lsp.WithLog(f, level))
+	}
+
+	if a.Strict {
+		opts = append(opts, lsp.WithStrict())
+	}
+
+	if a.Index != "" {
+		opts = append(opts, lsp.WithIndex(a.Index))
+	}
+
+	l, err := lsp.New(r, w, opts...)
+	if err != nil {
+		return -3, errors.Wrap(err, "failed to create lsp")
+	}
+
+	return l.Run()
+}
+
+// Command returns the language server command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "lsp",
+		Summary: "run the language server",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Net, "net", "tcp", "client network")
+			fs.StringVar(&a.Addr, "addr", "", "client address, stdin and stdout are used if empty")
+			fs.StringVar(&a.Debug, "debug", "", "debug file path")
+			fs.StringVar(&a.LogLevel, "log-level", "debug", "level of the debug file: debug, info, warn or error")
+			fs.BoolVar(&a.Strict, "strict", false, "reject unknown methods")
+			fs.StringVar(&a.Lsif, "lsif", "", "write the LSIF dump of the teal files of -path to the file instead of running the server")
+			fs.StringVar(&a.Path, "path", ".", "path to a teal file or a dir to dump with -lsif")
+			fs.StringVar(&a.Index, "index", defaultIndexDir(), "dir of the persisted workspace indexes, empty to disable")
+		},
+		Run: func() (int, error) {
+			return run(a)
+		},
+	}
+}
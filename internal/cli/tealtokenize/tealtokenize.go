@@ -0,0 +1,53 @@
+package tealtokenize
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/internal/cli"
+)
+
+type args struct {
+	Path string
+}
+
+func run(a args) error {
+	bs, err := teal.ReadSource(a.Path)
+	if err != nil {
+		return err
+	}
+
+	z := teal.Lexer{Source: bs}
+	for z.Scan() {
+		t := z.Curr()
+		switch t.Type() {
+		case teal.TokenEol:
+			fmt.Printf("%d:%d:%d: %s\n", t.Line()+1, t.Begin()+1, t.End()+1, t.Type())
+		default:
+			fmt.Printf("%d:%d:%d: %s = %s\n", t.Line()+1, t.Begin()+1, t.End()+1, t.Type(), t)
+		}
+	}
+
+	for _, err := range z.Errors() {
+		fmt.Println(err)
+	}
+
+	return nil
+}
+
+// Command returns the tokenizer command.
+func Command() cli.Command {
+	var a args
+
+	return cli.Command{
+		Name:    "tokenize",
+		Summary: "print the tokens of a teal file",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&a.Path, "path", "", "source file path")
+		},
+		Run: func() (int, error) {
+			return 0, run(a)
+		},
+	}
+}
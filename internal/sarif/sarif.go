@@ -7,9 +7,10 @@ type Results struct {
 }
 
 type Run struct {
-	Tool      Tool       `json:"tool"`
-	Artifacts []Artifact `json:"artifacts"`
-	Results   []Result   `json:"results"`
+	Tool       Tool            `json:"tool"`
+	Artifacts  []Artifact      `json:"artifacts"`
+	Results    []Result        `json:"results"`
+	Taxonomies []ToolComponent `json:"taxonomies,omitempty"`
 }
 
 type Result struct {
@@ -40,11 +41,14 @@ type Region struct {
 }
 
 type Message struct {
-	Text string `json:"text"`
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
 }
 
 type Artifact struct {
-	Location Location `json:"location"`
+	Location    Location               `json:"location"`
+	Description *Message               `json:"description,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
 }
 
 type Location struct {
@@ -62,16 +66,54 @@ type Driver struct {
 }
 
 type Rule struct {
-	Id               string      `json:"id"`
-	ShortDescription Description `json:"shortDescription,omitempty"`
-	HelpUri          string      `json:"helpUri,omitempty"`
-	Properties       []Property  `json:"properties,omitempty"`
+	Id                   string         `json:"id"`
+	ShortDescription     Description    `json:"shortDescription,omitempty"`
+	FullDescription      *Description   `json:"fullDescription,omitempty"`
+	HelpUri              string         `json:"helpUri,omitempty"`
+	Help                 *Message       `json:"help,omitempty"`
+	DefaultConfiguration *Configuration `json:"defaultConfiguration,omitempty"`
+	Relationships        []Relationship `json:"relationships,omitempty"`
+	Properties           *Properties    `json:"properties,omitempty"`
 }
 
 type Description struct {
 	Text string `json:"text"`
 }
 
-type Property struct {
-	Category string `json:"category"`
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+// Properties are the rule properties read by GitHub code scanning.
+type Properties struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Relationship links the rule to a taxon of a taxonomy, e.g. a CWE entry.
+type Relationship struct {
+	Target ReportingDescriptorReference `json:"target"`
+	Kinds  []string                     `json:"kinds,omitempty"`
+}
+
+type ReportingDescriptorReference struct {
+	Id            string                  `json:"id"`
+	ToolComponent *ToolComponentReference `json:"toolComponent,omitempty"`
+}
+
+type ToolComponentReference struct {
+	Name string `json:"name"`
+}
+
+// ToolComponent is a taxonomy, e.g. the CWE list.
+type ToolComponent struct {
+	Name           string  `json:"name"`
+	Organization   string  `json:"organization,omitempty"`
+	InformationUri string  `json:"informationUri,omitempty"`
+	Taxa           []Taxon `json:"taxa"`
+}
+
+type Taxon struct {
+	Id               string       `json:"id"`
+	ShortDescription *Description `json:"shortDescription,omitempty"`
+	HelpUri          string       `json:"helpUri,omitempty"`
 }
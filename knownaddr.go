@@ -0,0 +1,228 @@
+package teal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/algorand/go-algorand-sdk/types"
+	"github.com/pkg/errors"
+)
+
+// KnownAddress is a well-known address shown by its name.
+type KnownAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+
+	// Burn marks the address whose received funds can not be spent by anyone or go to the protocol
+	Burn bool `json:"burn,omitempty"`
+}
+
+// DefaultKnownAddresses are the well-known addresses of the protocol.
+var DefaultKnownAddresses = []KnownAddress{
+	{Address: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ", Name: "zero address", Burn: true},
+	{Address: "Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA", Name: "MainNet fee sink", Burn: true},
+	{Address: "737777777777777777777777777777777777777777777777777UFEJ2CI", Name: "MainNet rewards pool", Burn: true},
+	{Address: "A7NMWS3NT3IUDMLVO26ULGXGIIOUQ3ND2TXSER6EBGRZNOBOUIQXHIBGDE", Name: "TestNet fee sink", Burn: true},
+	{Address: "7777777777777777777777777777777777777777777777777774MSJUVU", Name: "TestNet rewards pool", Burn: true},
+}
+
+// ParseKnownAddresses parses the JSON array of the known addresses.
+func ParseKnownAddresses(bs []byte) ([]KnownAddress, error) {
+	var as []KnownAddress
+
+	err := json.Unmarshal(bs, &as)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse known addresses")
+	}
+
+	for i, a := range as {
+		if a.Name == "" {
+			return nil, errors.Errorf("missing name of known address %d", i)
+		}
+
+		_, err := types.DecodeAddress(a.Address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid known address '%s'", a.Name)
+		}
+	}
+
+	return as, nil
+}
+
+// ReadKnownAddresses reads the JSON file of the known addresses.
+func ReadKnownAddresses(path string) ([]KnownAddress, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read known addresses")
+	}
+
+	return ParseKnownAddresses(bs)
+}
+
+// WithKnownAddresses adds the addresses to the default known addresses, the later names take precedence.
+func WithKnownAddresses(as ...KnownAddress) ProcessOption {
+	return func(c *processConfig) {
+		c.addresses = append(c.addresses, as...)
+	}
+}
+
+func knownAddresses(extra []KnownAddress) []KnownAddress {
+	return append(append([]KnownAddress{}, DefaultKnownAddresses...), extra...)
+}
+
+// KnownAddressRef is a literal of a known address, Begin and End are the range of the literal value.
+type KnownAddressRef struct {
+	Index int
+	Line  int
+	Begin int
+	End   int
+
+	Address KnownAddress
+}
+
+// lookupAddress returns the known address of the 32 bytes, the last matching entry wins.
+func lookupAddress(as []KnownAddress, bs []byte) (KnownAddress, bool) {
+	if len(bs) != len(types.ZeroAddress) {
+		return KnownAddress{}, false
+	}
+
+	var addr types.Address
+	copy(addr[:], bs)
+
+	s := addr.String()
+
+	for i := len(as) - 1; i >= 0; i-- {
+		if as[i].Address == s {
+			return as[i], true
+		}
+	}
+
+	return KnownAddress{}, false
+}
+
+// literalAddress returns the 32 bytes pushed by the addr or the bytes literal.
+func literalAddress(op Op) ([]byte, bool) {
+	switch op := op.(type) {
+	case *AddrExpr:
+		addr, err := types.DecodeAddress(op.Address)
+		if err != nil {
+			return nil, false
+		}
+		return addr[:], true
+	case *ByteExpr:
+		return op.Value, true
+	case *PushBytesExpr:
+		return op.Value, true
+	}
+
+	return nil, false
+}
+
+// AddressRefs returns the literals of the known addresses.
+func (r ProcessResult) AddressRefs() []KnownAddressRef {
+	var res []KnownAddressRef
+
+	for i, op := range r.Listing {
+		if i >= len(r.Sublines) {
+			break
+		}
+
+		bs, ok := literalAddress(op)
+		if !ok {
+			continue
+		}
+
+		a, ok := lookupAddress(r.KnownAddresses, bs)
+		if !ok {
+			continue
+		}
+
+		sub := r.Sublines[i]
+		if len(sub.Tokens) < 2 {
+			continue
+		}
+
+		// the value is the last token of the literal, e.g. of byte base32 X
+		t := sub.Tokens[len(sub.Tokens)-1]
+		for _, tt := range sub.Tokens[1:] {
+			if tt.Type() == TokenComment {
+				break
+			}
+			t = tt
+		}
+
+		res = append(res, KnownAddressRef{
+			Index:   i,
+			Line:    sub.Line,
+			Begin:   t.Begin(),
+			End:     t.End(),
+			Address: a,
+		})
+	}
+
+	return res
+}
+
+// AddressAt returns the known address of the literal at the position.
+func (r ProcessResult) AddressAt(line int, ch int) (KnownAddressRef, bool) {
+	for _, ref := range r.AddressRefs() {
+		if ref.Line == line && ch >= ref.Begin && ch <= ref.End {
+			return ref, true
+		}
+	}
+
+	return KnownAddressRef{}, false
+}
+
+type BurnReceiverRule struct{}
+
+func (r BurnReceiverRule) Id() string {
+	return "LINT0023"
+}
+
+func (r BurnReceiverRule) Desc() string {
+	return "Checks for the inner transactions sending funds to a known burn or fee sink address"
+}
+
+var BurnReceiverRuleInstance = BurnReceiverRule{}
+
+// checkBurnReceivers reports the known burn addresses pushed right before they are set as the inner transaction receiver.
+func checkBurnReceivers(res *ProcessResult) []Diagnostic {
+	refs := map[int]KnownAddressRef{}
+	for _, ref := range res.AddressRefs() {
+		if ref.Address.Burn {
+			refs[ref.Index] = ref
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var diags []Diagnostic
+
+	prev := -1
+	for i, op := range res.Listing {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		if f, ok := op.(*ItxnFieldExpr); ok && (f.Field == Receiver || f.Field == AssetReceiver) {
+			if ref, ok := refs[prev]; ok {
+				diags = append(diags, lintError{
+					error: fmt.Errorf("%s is set to the %s, the sent funds can not be recovered", f.Field, ref.Address.Name),
+					l:     ref.Line,
+					b:     ref.Begin,
+					e:     ref.End,
+					s:     DiagWarn,
+					r:     BurnReceiverRuleInstance.Id(),
+				})
+			}
+		}
+
+		prev = i
+	}
+
+	return diags
+}
@@ -0,0 +1,94 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+func TestDefaultKnownAddresses(t *testing.T) {
+	for _, a := range DefaultKnownAddresses {
+		if _, err := types.DecodeAddress(a.Address); err != nil {
+			t.Errorf("invalid known address %s: %s", a.Name, err)
+		}
+	}
+}
+
+func TestParseKnownAddresses(t *testing.T) {
+	as, err := ParseKnownAddresses([]byte(`[{"address": "Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA", "name": "sink"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(as) != 1 || as[0].Name != "sink" || as[0].Burn {
+		t.Errorf("unexpected addresses: %+v", as)
+	}
+
+	invalid := []string{
+		`{}`,
+		`[{"address": "Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA"}]`,
+		`[{"address": "Y77M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA", "name": "typo"}]`,
+	}
+
+	for _, s := range invalid {
+		if _, err := ParseKnownAddresses([]byte(s)); err == nil {
+			t.Errorf("expected error for: %s", s)
+		}
+	}
+}
+
+func TestAddressRefs(t *testing.T) {
+	treasury := KnownAddress{Address: "7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q", Name: "treasury"}
+
+	res := Process(`#pragma version 8
+itxn_begin
+int pay
+itxn_field TypeEnum
+addr AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ
+itxn_field Receiver
+byte 0x0000000000000000000000000000000000000000000000000000000000000000 // zero
+itxn_field CloseRemainderTo
+addr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q
+itxn_field Receiver
+itxn_submit
+int 1
+return`, WithKnownAddresses(treasury))
+
+	refs := res.AddressRefs()
+	if len(refs) != 3 {
+		t.Fatalf("unexpected refs: %+v", refs)
+	}
+
+	if refs[0].Address.Name != "zero address" || refs[0].Line != 4 || refs[0].Begin != 5 {
+		t.Errorf("unexpected ref: %+v", refs[0])
+	}
+
+	if refs[1].Address.Name != "zero address" || refs[1].End != 71 {
+		t.Errorf("unexpected ref: %+v", refs[1])
+	}
+
+	if refs[2].Address.Name != "treasury" {
+		t.Errorf("unexpected ref: %+v", refs[2])
+	}
+
+	if ref, ok := res.AddressAt(8, 10); !ok || ref.Address.Name != "treasury" {
+		t.Errorf("unexpected address at position: %+v", ref)
+	}
+
+	hs := res.InlayHints(LinesRange{Start: 0, End: 13})
+	if len(hs.Addresses) != 3 || hs.Addresses[2].Label != "treasury" || hs.Addresses[2].Line != 8 {
+		t.Errorf("unexpected inlay hints: %+v", hs.Addresses)
+	}
+
+	var msgs []string
+	for _, d := range res.Diagnostics {
+		if d.Rule() == BurnReceiverRuleInstance.Id() {
+			msgs = append(msgs, d.String())
+		}
+	}
+
+	if strings.Join(msgs, "\n") != "Receiver is set to the zero address, the sent funds can not be recovered" {
+		t.Errorf("unexpected diagnostics:\n%s", strings.Join(msgs, "\n"))
+	}
+}
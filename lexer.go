@@ -13,6 +13,8 @@ const (
 	TokenValue = "Value" // value
 
 	TokenComment = "Comment" // value
+
+	TokenSemicolon = "Semicolon" // separates instructions on a single line
 )
 
 type Token struct {
@@ -117,6 +119,7 @@ func isTerminating(c rune) bool {
 	case '\n':
 	case ' ':
 	case '\t':
+	case ';':
 	default:
 		return false
 	}
@@ -316,6 +319,9 @@ func (z *Lexer) readTokens() {
 			z.readComment()
 		} else if c == '\n' || c == '\r' {
 			z.readEol()
+		} else if c == ';' {
+			z.inc(n)
+			z.emit(TokenSemicolon)
 		} else {
 			z.readValue()
 		}
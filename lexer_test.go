@@ -77,6 +77,16 @@ func TestLexer(t *testing.T) {
 			o: []TokenType{TokenValue, TokenValue, TokenValue},
 			v: []string{"#pragma", "version", "8"},
 		},
+		{
+			i: "int 1; int 2 ;pop",
+			o: []TokenType{TokenValue, TokenValue, TokenSemicolon, TokenValue, TokenValue, TokenSemicolon, TokenValue},
+			v: []string{"int", "1", ";", "int", "2", ";", "pop"},
+		},
+		{
+			i: "byte \"a;b\"",
+			o: []TokenType{TokenValue, TokenValue},
+			v: []string{"byte", "\"a;b\""},
+		},
 		{
 			i: "byte \"some multiword byte string\"",
 			o: []TokenType{TokenValue, TokenValue},
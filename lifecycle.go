@@ -0,0 +1,183 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+const lifecycleMaxBranches = 1000
+
+// LifecycleCase is the result of running the program for a single OnCompletion value on create or call.
+type LifecycleCase struct {
+	OnCompletion string `json:"onCompletion"`
+	Create       bool   `json:"create"`
+
+	Approve bool `json:"approve"` // at least one path may approve
+	Reject  bool `json:"reject"`  // at least one path may reject
+	Fail    bool `json:"fail"`    // at least one path fails, assertions on unknown values are assumed to pass
+
+	// not all of the paths could be explored
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+func (c LifecycleCase) String() string {
+	var res []string
+
+	if c.Approve {
+		res = append(res, "approve")
+	}
+	if c.Reject {
+		res = append(res, "reject")
+	}
+	if c.Fail {
+		if len(res) == 0 {
+			res = append(res, "always fails")
+		} else {
+			res = append(res, "fail")
+		}
+	}
+	if c.Incomplete {
+		res = append(res, "incomplete")
+	}
+
+	if len(res) == 0 {
+		return "unknown"
+	}
+
+	return strings.Join(res, "/")
+}
+
+// Lifecycle is the application lifecycle matrix of a program.
+type Lifecycle struct {
+	// source line of the routing on OnCompletion or ApplicationID, -1 if there is none
+	Line int `json:"line"`
+
+	Cases []LifecycleCase `json:"cases"`
+}
+
+func (l Lifecycle) find(oc string, create bool) (LifecycleCase, bool) {
+	for _, c := range l.Cases {
+		if c.OnCompletion == oc && c.Create == create {
+			return c, true
+		}
+	}
+
+	return LifecycleCase{}, false
+}
+
+// Case returns the result for the OnCompletion value on create or call.
+func (l Lifecycle) Case(oc OnCompletionConstType, create bool) (LifecycleCase, bool) {
+	return l.find(oc.String(), create)
+}
+
+// Markdown formats the matrix as a markdown table with a row per OnCompletion value.
+func (l Lifecycle) Markdown() string {
+	var b strings.Builder
+
+	b.WriteString("| OnCompletion | create | call |\n")
+	b.WriteString("|---|---|---|\n")
+
+	seen := map[string]bool{}
+
+	for _, c := range l.Cases {
+		if seen[c.OnCompletion] {
+			continue
+		}
+
+		seen[c.OnCompletion] = true
+
+		cells := []string{"-", "-"}
+		if cr, ok := l.find(c.OnCompletion, true); ok {
+			cells[0] = cr.String()
+		}
+		if cl, ok := l.find(c.OnCompletion, false); ok {
+			cells[1] = cl.String()
+		}
+
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", c.OnCompletion, cells[0], cells[1]))
+	}
+
+	return b.String()
+}
+
+func lifecycleLine(res *ProcessResult) int {
+	line := -1
+
+	for i, op := range res.Listing {
+		switch op := op.(type) {
+		case *TxnExpr:
+			switch op.Field {
+			case OnCompletion:
+				return res.SourceLine(i)
+			case ApplicationID:
+				if line == -1 {
+					line = res.SourceLine(i)
+				}
+			}
+		}
+	}
+
+	return line
+}
+
+func runLifecycleCase(res *ProcessResult, oc OnCompletionConstType, create bool) LifecycleCase {
+	c := LifecycleCase{
+		OnCompletion: oc.String(),
+		Create:       create,
+	}
+
+	// calls are evaluated with a non-zero application id
+	id := uint64(1)
+	if create {
+		id = 0
+	}
+
+	vm := NewVm(res)
+	vm.Txn = map[TxnField]uint64{
+		ApplicationID: id,
+		OnCompletion:  uint64(oc),
+	}
+
+	c.Incomplete = !vm.RunAll(lifecycleMaxBranches)
+
+	for _, b := range vm.Branches {
+		switch b.Exit {
+		case VmExitApprove:
+			c.Approve = true
+		case VmExitReject:
+			c.Reject = true
+		case VmExitUnknown:
+			c.Approve = true
+			c.Reject = true
+		case VmExitFail:
+			c.Fail = true
+		}
+	}
+
+	return c
+}
+
+// AnalyzeLifecycle runs the program for each of the OnCompletion values on create and call
+// with the known transaction fields propagated through the conditions.
+// Clear state programs are only run for ClearState calls.
+func AnalyzeLifecycle(res *ProcessResult) Lifecycle {
+	l := Lifecycle{
+		Line: lifecycleLine(res),
+	}
+
+	if res.Clear {
+		l.Cases = append(l.Cases, runLifecycleCase(res, ClearState, false))
+		return l
+	}
+
+	for oc := NoOp; oc < invalidOnCompletionConst; oc++ {
+		if oc == ClearState {
+			continue
+		}
+
+		l.Cases = append(l.Cases, runLifecycleCase(res, oc, true))
+		l.Cases = append(l.Cases, runLifecycleCase(res, oc, false))
+	}
+
+	return l
+}
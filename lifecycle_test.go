@@ -0,0 +1,85 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLifecycle(t *testing.T) {
+	res := Process(`#pragma version 8
+txn ApplicationID
+bz create
+txn OnCompletion
+int NoOp
+==
+bnz call
+txn OnCompletion
+int OptIn
+==
+bnz optin
+txn OnCompletion
+int DeleteApplication
+==
+bnz delete
+err
+create:
+int 1
+return
+call:
+txn NumAppArgs
+int 1
+==
+return
+optin:
+int 0
+return
+delete:
+txn Sender
+global CreatorAddress
+==
+assert
+int 1
+return`)
+
+	l := AnalyzeLifecycle(res)
+	if l.Line != 3 {
+		t.Errorf("unexpected routing line: %d", l.Line)
+	}
+
+	tests := []struct {
+		oc     OnCompletionConstType
+		create bool
+		s      string
+	}{
+		{NoOp, true, "approve"},
+		{OptIn, true, "approve"},
+		{NoOp, false, "approve/reject"},
+		{OptIn, false, "reject"},
+		{CloseOut, false, "always fails"},
+		{UpdateApplication, false, "always fails"},
+		{DeleteApplication, false, "approve"},
+	}
+
+	for _, test := range tests {
+		c, ok := l.Case(test.oc, test.create)
+		if !ok {
+			t.Fatalf("missing case: %s", test.oc)
+		}
+
+		if c.String() != test.s {
+			t.Errorf("unexpected %s (create: %v) result - expected: %s, got: %s", test.oc, test.create, test.s, c)
+		}
+	}
+
+	md := l.Markdown()
+	if !strings.Contains(md, "| CloseOut | approve | always fails |") {
+		t.Errorf("unexpected markdown: %s", md)
+	}
+}
+
+func TestLifecycleClear(t *testing.T) {
+	l := AnalyzeLifecycle(Process("#pragma version 8\nint 1\n", WithClear()))
+	if len(l.Cases) != 1 || l.Cases[0].String() != "approve" || l.Line != -1 {
+		t.Errorf("unexpected clear state lifecycle: %+v", l)
+	}
+}
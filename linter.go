@@ -2,6 +2,9 @@ package teal
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 type RedundantLine interface {
@@ -44,6 +47,15 @@ type LineError interface {
 type Linter struct {
 	l Listing
 
+	// labels defined in included files
+	ext map[string]bool
+
+	// clear state program
+	clear bool
+
+	// goal clerk compile compatibility
+	goal bool
+
 	errs []LineError
 	reds []RedundantLine
 }
@@ -219,6 +231,93 @@ func (e PragmaVersionAfterInstrError) Rule() string {
 	return e.rule
 }
 
+type PragmaVersionMismatchError struct {
+	l       int
+	version uint8
+	prev    uint8
+	rule    string
+}
+
+func (e PragmaVersionMismatchError) Line() int {
+	return e.l
+}
+
+func (e PragmaVersionMismatchError) Error() string {
+	return fmt.Sprintf("version mismatch: assembling v%d with v%d assembler", e.version, e.prev)
+}
+
+func (e PragmaVersionMismatchError) Severity() DiagnosticSeverity {
+	return DiagErr
+}
+
+func (e PragmaVersionMismatchError) Rule() string {
+	return e.rule
+}
+
+type ClearStateFailingOpError struct {
+	l    int
+	name string
+	rule string
+}
+
+func (e ClearStateFailingOpError) Line() int {
+	return e.l
+}
+
+func (e ClearStateFailingOpError) Error() string {
+	return fmt.Sprintf("op can fail and block clearing the state: %s", e.name)
+}
+
+func (e ClearStateFailingOpError) Severity() DiagnosticSeverity {
+	return DiagWarn
+}
+
+func (e ClearStateFailingOpError) Rule() string {
+	return e.rule
+}
+
+type ClearStateRejectError struct {
+	l    int
+	rule string
+}
+
+func (e ClearStateRejectError) Line() int {
+	return e.l
+}
+
+func (e ClearStateRejectError) Error() string {
+	return "clear state program rejects"
+}
+
+func (e ClearStateRejectError) Severity() DiagnosticSeverity {
+	return DiagWarn
+}
+
+func (e ClearStateRejectError) Rule() string {
+	return e.rule
+}
+
+type FeePoolingError struct {
+	l    int
+	rule string
+}
+
+func (e FeePoolingError) Line() int {
+	return e.l
+}
+
+func (e FeePoolingError) Error() string {
+	return "inner transaction fee is 0 - the outer transaction must cover it through fee pooling: fee >= (1 + inner transactions) * min fee"
+}
+
+func (e FeePoolingError) Severity() DiagnosticSeverity {
+	return DiagInfo
+}
+
+func (e FeePoolingError) Rule() string {
+	return e.rule
+}
+
 func (l *Linter) getLabelsUsers() map[string][]int {
 	used := map[string][]int{}
 
@@ -435,7 +534,7 @@ func (r CheckLoopsRule) Run(l *Linter) {
 
 	for name, users := range used {
 		_, ok := all[name]
-		if !ok {
+		if !ok && !l.ext[name] {
 			for _, user := range users {
 				l.errs = append(l.errs, MissingLabelError{l: user, name: name, rule: r.Id()})
 			}
@@ -493,6 +592,11 @@ func (r CheckPragmaRule) Desc() string {
 }
 
 func (r CheckPragmaRule) Run(l *Linter) {
+	if l.goal {
+		r.runGoal(l)
+		return
+	}
+
 	var prev Op
 	for i, op := range l.l {
 		switch op := op.(type) {
@@ -511,6 +615,37 @@ func (r CheckPragmaRule) Run(l *Linter) {
 	}
 }
 
+// runGoal accepts the repeated #pragma version of the same value before the instructions like goal does.
+func (r CheckPragmaRule) runGoal(l *Linter) {
+	var first *PragmaExpr
+	instrs := false
+
+	for i, op := range l.l {
+		switch op := op.(type) {
+		case *PragmaExpr:
+			switch {
+			case instrs:
+				l.errs = append(l.errs, PragmaVersionAfterInstrError{
+					l:    i,
+					rule: r.Id(),
+				})
+			case first == nil:
+				first = op
+			case op.Version != first.Version:
+				l.errs = append(l.errs, PragmaVersionMismatchError{
+					l:       i,
+					version: op.Version,
+					prev:    first.Version,
+					rule:    r.Id(),
+				})
+			}
+		case Nop:
+		default:
+			instrs = true
+		}
+	}
+}
+
 type OpCodeAvailabilityInModeRule struct {
 }
 
@@ -537,6 +672,298 @@ func (r OpCodeVersionCompatibilityCheckRule) Desc() string {
 
 var OpCodeVersionCompatibilityCheckRuleInstance = OpCodeVersionCompatibilityCheckRule{}
 
+type ClearStateFailingOpsRule struct{}
+
+func (r ClearStateFailingOpsRule) Id() string {
+	return "LINT0009"
+}
+
+func (r ClearStateFailingOpsRule) Desc() string {
+	return "Checks for ops that can fail in clear state programs"
+}
+
+func (r ClearStateFailingOpsRule) Run(l *Linter) {
+	if !l.clear {
+		return
+	}
+
+	for i, op := range l.l {
+		switch op.(type) {
+		case *AssertExpr,
+			*PlusExpr, *MinusExpr, *MulExpr, *DivExpr, *ModExpr, *ExpExpr, *DivwExpr,
+			*BminusExpr, *BdivExpr, *BytesModuloExpr,
+			*BtoiExpr, *ExtractExpr, *Extract3Expr, *ExtractUint16Expr, *ExtractUint32Expr, *ExtractUint64Expr,
+			*SubstringExpr, *Substring3Expr, *Replace2Expr, *Replace3Expr,
+			*GetByteExpr, *SetByteExpr, *GetBitExpr, *SetBitExpr, *BzeroExpr,
+			*Base64DecodeExpr, *JsonRefExpr,
+			*AppGlobalPutExpr, *AppLocalPutExpr,
+			*BoxCreateExpr, *BoxPutExpr, *BoxReplaceExpr, *BoxExtractExpr,
+			*ItxnFieldExpr, *ItxnSubmitExpr, *ItxnNextExpr:
+			l.errs = append(l.errs, ClearStateFailingOpError{l: i, name: strings.Fields(op.String())[0], rule: r.Id()})
+		}
+	}
+}
+
+type ClearStateRejectRule struct{}
+
+func (r ClearStateRejectRule) Id() string {
+	return "LINT0010"
+}
+
+func (r ClearStateRejectRule) Desc() string {
+	return "Checks for clear state program paths that reject"
+}
+
+func (r ClearStateRejectRule) Run(l *Linter) {
+	if !l.clear {
+		return
+	}
+
+	var prev Op
+	for i, op := range l.l {
+		switch op.(type) {
+		case *ErrExpr:
+			l.errs = append(l.errs, ClearStateRejectError{l: i, rule: r.Id()})
+		case *ReturnExpr:
+			switch prev := prev.(type) {
+			case *IntExpr:
+				if prev.Value == 0 {
+					l.errs = append(l.errs, ClearStateRejectError{l: i, rule: r.Id()})
+				}
+			case *PushIntExpr:
+				if prev.Value == 0 {
+					l.errs = append(l.errs, ClearStateRejectError{l: i, rule: r.Id()})
+				}
+			}
+		case *LabelExpr:
+			prev = nil
+			continue
+		case Nop:
+			continue
+		}
+
+		prev = op
+	}
+}
+
+type ClearStateBudgetRule struct{}
+
+func (r ClearStateBudgetRule) Id() string {
+	return "LINT0011"
+}
+
+func (r ClearStateBudgetRule) Desc() string {
+	return "Checks clear state program cost does not exceed the clear state budget"
+}
+
+var ClearStateBudgetRuleInstance = ClearStateBudgetRule{}
+
+// max number of branches explored when looking for budget overruns
+const clearStateMaxBranches = 1000
+
+func checkClearStateBudget(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	vm := NewVm(res)
+	vm.RunAll(clearStateMaxBranches)
+
+	seen := map[int]bool{}
+	for _, b := range vm.Branches {
+		if !b.Exhausted || seen[b.ExhaustedAt] || b.ExhaustedAt >= len(res.Sublines) {
+			continue
+		}
+
+		seen[b.ExhaustedAt] = true
+
+		sub := res.Sublines[b.ExhaustedAt]
+		diags = append(diags, lintError{
+			error: errors.Errorf("clear state program may exceed the budget of %d", VmDefaultBudget),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagWarn,
+			r:     ClearStateBudgetRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
+
+type BoxKeyCollisionRule struct{}
+
+func (r BoxKeyCollisionRule) Id() string {
+	return "LINT0012"
+}
+
+func (r BoxKeyCollisionRule) Desc() string {
+	return "Checks box names built by concatenation with user input for possible collisions"
+}
+
+var BoxKeyCollisionRuleInstance = BoxKeyCollisionRule{}
+
+type BudgetPoolingRule struct{}
+
+func (r BudgetPoolingRule) Id() string {
+	return "LINT0013"
+}
+
+func (r BudgetPoolingRule) Desc() string {
+	return "Explains the group budget pooling required by app programs that cost more than a single app call budget"
+}
+
+var BudgetPoolingRuleInstance = BudgetPoolingRule{}
+
+// max number of branches explored when looking for the exhausted budget and the pooled program cost
+const budgetPoolingMaxBranches = 1000
+
+// max number of app calls in a group
+const maxGroupSize = 16
+
+func checkBudgetPooling(res *ProcessResult) []Diagnostic {
+	vm := NewVm(res)
+	vm.RunAll(budgetPoolingMaxBranches)
+
+	var lines []int
+
+	seen := map[int]bool{}
+	for _, b := range vm.Branches {
+		if !b.Exhausted || seen[b.ExhaustedAt] || b.ExhaustedAt >= len(res.Sublines) {
+			continue
+		}
+
+		seen[b.ExhaustedAt] = true
+		lines = append(lines, b.ExhaustedAt)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	pooled := VmDefaultBudget * maxGroupSize
+
+	var msg string
+
+	pvm := NewVm(res)
+	for _, b := range pvm.Branches {
+		b.Budget = pooled
+	}
+
+	// the cost of a truncated run is not the max one
+	if !pvm.RunAll(budgetPoolingMaxBranches) {
+		return nil
+	}
+
+	max := 0
+	exhausted := false
+	for _, b := range pvm.Branches {
+		if b.Exhausted {
+			exhausted = true
+		}
+
+		if used := pooled - b.Budget; used > max {
+			max = used
+		}
+	}
+
+	switch {
+	case exhausted:
+		msg = fmt.Sprintf("program may cost more than the pooled budget of %d app calls (%d), it needs op-up inner app calls", maxGroupSize, pooled)
+	case max <= VmDefaultBudget:
+		return nil
+	default:
+		calls := (max + VmDefaultBudget - 1) / VmDefaultBudget
+		msg = fmt.Sprintf("program may cost up to %d, more than the budget of a single app call (%d) - it relies on budget pooling and needs a group of at least %d app calls or op-up inner app calls", max, VmDefaultBudget, calls)
+	}
+
+	var diags []Diagnostic
+
+	for _, line := range lines {
+		sub := res.Sublines[line]
+		diags = append(diags, lintError{
+			error: errors.New(msg),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagInfo,
+			r:     BudgetPoolingRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
+
+type DeprecatedRule struct{}
+
+func (r DeprecatedRule) Id() string {
+	return "LINT0015"
+}
+
+func (r DeprecatedRule) Desc() string {
+	return "Checks for legacy constructs that have better alternatives in the program version and mode"
+}
+
+var DeprecatedRuleInstance = DeprecatedRule{}
+
+func checkDeprecations(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, d := range res.Deprecations() {
+		diags = append(diags, lintError{
+			error: errors.New(d.Message),
+			l:     d.Line,
+			b:     d.Begin,
+			e:     d.End,
+			s:     DiagWarn,
+			r:     DeprecatedRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
+
+type FeePoolingRule struct{}
+
+func (r FeePoolingRule) Id() string {
+	return "LINT0014"
+}
+
+func (r FeePoolingRule) Desc() string {
+	return "Explains the fee pooling required by inner transactions with a zero fee"
+}
+
+func (r FeePoolingRule) Run(l *Linter) {
+	var prev Op
+	for i, op := range l.l {
+		switch op := op.(type) {
+		case *ItxnFieldExpr:
+			if op.Field == Fee {
+				if v, ok := constUint(prev); ok && v == 0 {
+					l.errs = append(l.errs, FeePoolingError{l: i, rule: r.Id()})
+				}
+			}
+		case *LabelExpr:
+			prev = nil
+			continue
+		case Nop:
+			continue
+		}
+
+		prev = op
+	}
+}
+
+type ByteLengthMismatchRule struct{}
+
+func (r ByteLengthMismatchRule) Id() string {
+	return "LINT0017"
+}
+
+func (r ByteLengthMismatchRule) Desc() string {
+	return "Checks == and != comparisons of byte values with different statically known lengths"
+}
+
+var ByteLengthMismatchRuleInstance = ByteLengthMismatchRule{}
+
 var LintRules []LintRule
 
 func init() {
@@ -548,6 +975,29 @@ func init() {
 	LintRules = append(LintRules, CheckPragmaRule{})
 	LintRules = append(LintRules, OpCodeAvailabilityInModeRuleInstance)
 	LintRules = append(LintRules, OpCodeVersionCompatibilityCheckRuleInstance)
+	LintRules = append(LintRules, ClearStateFailingOpsRule{})
+	LintRules = append(LintRules, ClearStateRejectRule{})
+	LintRules = append(LintRules, ClearStateBudgetRuleInstance)
+	LintRules = append(LintRules, BoxKeyCollisionRuleInstance)
+	LintRules = append(LintRules, BudgetPoolingRuleInstance)
+	LintRules = append(LintRules, FeePoolingRule{})
+	LintRules = append(LintRules, DeprecatedRuleInstance)
+	LintRules = append(LintRules, StackDepthRuleInstance)
+	LintRules = append(LintRules, ByteLengthMismatchRuleInstance)
+	LintRules = append(LintRules, WideMathRuleInstance)
+	LintRules = append(LintRules, UnusedFrameRuleInstance)
+	LintRules = append(LintRules, StackCommentRuleInstance)
+	LintRules = append(LintRules, MethodSignatureRuleInstance)
+	LintRules = append(LintRules, OpPolicyRuleInstance)
+	LintRules = append(LintRules, BurnReceiverRuleInstance)
+	LintRules = append(LintRules, ImmediateFormRuleInstance)
+	LintRules = append(LintRules, GtxnLoopRuleInstance)
+	LintRules = append(LintRules, ScratchPressureRuleInstance)
+	LintRules = append(LintRules, MissingExpiryRuleInstance)
+	LintRules = append(LintRules, UncheckedAppArgsRuleInstance)
+	LintRules = append(LintRules, UnavailableRefRuleInstance)
+	LintRules = append(LintRules, ConsensusRuleInstance)
+	LintRules = append(LintRules, BoxBudgetRuleInstance)
 }
 
 func (l *Linter) Lint() {
@@ -0,0 +1,126 @@
+package teal
+
+import "testing"
+
+func TestClearStateRules(t *testing.T) {
+	src := `#pragma version 8
+txn NumAppArgs
+int 1
++
+bnz reject
+int 1
+return
+reject:
+int 0
+return`
+
+	res := Process(src)
+	if len(res.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics for approval program: %v", res.Diagnostics)
+	}
+
+	for _, res := range []*ProcessResult{
+		Process(src, WithClear()),
+		Process("//#pragma program clear\n" + src),
+	} {
+		if !res.Clear {
+			t.Fatal("expected clear state program")
+		}
+
+		rules := map[string]int{}
+		for _, d := range res.Diagnostics {
+			rules[d.Rule()]++
+		}
+
+		if rules[(ClearStateFailingOpsRule{}).Id()] != 1 || rules[(ClearStateRejectRule{}).Id()] != 1 || len(res.Diagnostics) != 2 {
+			t.Errorf("unexpected clear state diagnostics: %v", res.Diagnostics)
+		}
+	}
+}
+
+func TestClearStateBudgetRule(t *testing.T) {
+	res := Process(`#pragma version 8
+byte "x"
+loop:
+sha512_256
+b loop`, WithClear())
+
+	found := false
+	for _, d := range res.Diagnostics {
+		if d.Rule() == ClearStateBudgetRuleInstance.Id() {
+			found = true
+			if d.Line() != 3 {
+				t.Errorf("unexpected budget diagnostic line: %d", d.Line())
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("expected budget diagnostic, got: %v", res.Diagnostics)
+	}
+}
+
+func TestBudgetPoolingRule(t *testing.T) {
+	res := Process(`#pragma version 8
+byte "x"
+keccak256
+keccak256
+keccak256
+keccak256
+keccak256
+keccak256
+len
+return`)
+
+	var ds []Diagnostic
+	for _, d := range res.Diagnostics {
+		if d.Rule() == BudgetPoolingRuleInstance.Id() {
+			ds = append(ds, d)
+		}
+	}
+
+	if len(ds) != 1 || ds[0].Line() != 7 || ds[0].Severity() != DiagInfo {
+		t.Fatalf("unexpected budget pooling diagnostics: %v", res.Diagnostics)
+	}
+
+	expected := "program may cost up to 783, more than the budget of a single app call (700) - it relies on budget pooling and needs a group of at least 2 app calls or op-up inner app calls"
+	if ds[0].String() != expected {
+		t.Errorf("unexpected message: %s", ds[0])
+	}
+
+	if len(Process("#pragma version 8\nbyte \"x\"\nkeccak256\nlen\nreturn").Diagnostics) != 0 {
+		t.Error("unexpected diagnostics for a cheap program")
+	}
+
+	// the truncated pooled run does not find the max cost
+	for _, d := range Process("#pragma version 8\ntxn Amount\nl1:\nint 1\n-\ndup\nbnz l1\nreturn").Diagnostics {
+		if d.Rule() == BudgetPoolingRuleInstance.Id() {
+			t.Errorf("unexpected budget pooling diagnostic of the truncated run: %s", d)
+		}
+	}
+}
+
+func TestFeePoolingRule(t *testing.T) {
+	res := Process(`#pragma version 8
+itxn_begin
+int pay
+itxn_field TypeEnum
+int 0
+itxn_field Fee
+itxn_submit
+int 1`)
+
+	found := false
+	for _, d := range res.Diagnostics {
+		if d.Rule() == (FeePoolingRule{}).Id() {
+			found = true
+			if d.Line() != 5 {
+				t.Errorf("unexpected fee pooling diagnostic line: %d", d.Line())
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("expected fee pooling diagnostic, got: %v", res.Diagnostics)
+	}
+}
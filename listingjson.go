@@ -0,0 +1,95 @@
+package teal
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ListingOp is the JSON form of an op.
+type ListingOp struct {
+	// Op is the op name, "label" for the labels and empty for the empty lines
+	Op string `json:"op"`
+
+	// Args are the immediate arguments or the label name
+	Args []string `json:"args,omitempty"`
+
+	// Text is the TEAL text of the op, it is used to decode the op
+	Text string `json:"text"`
+}
+
+// NewListingOp returns the JSON form of the op.
+func NewListingOp(op Op) ListingOp {
+	text := op.String()
+
+	if op, ok := op.(*LabelExpr); ok {
+		return ListingOp{Op: "label", Args: []string{op.Name}, Text: text}
+	}
+
+	res := ListingOp{Text: text}
+
+	z := Lexer{Source: []byte(text)}
+	for z.Scan() {
+		t := z.Curr()
+
+		switch t.Type() {
+		case TokenValue:
+			if res.Op == "" {
+				res.Op = t.String()
+			} else {
+				res.Args = append(res.Args, t.String())
+			}
+		}
+	}
+
+	return res
+}
+
+// Ops returns the JSON form of the ops.
+func (l Listing) Ops() []ListingOp {
+	res := make([]ListingOp, len(l))
+	for i, op := range l {
+		res[i] = NewListingOp(op)
+	}
+
+	return res
+}
+
+// MarshalJSON encodes the listing as an array of ListingOp.
+func (l Listing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Ops())
+}
+
+// UnmarshalJSON decodes the listing by processing the text of the ops, one op per line.
+func (l *Listing) UnmarshalJSON(bs []byte) error {
+	var ops []ListingOp
+	if err := json.Unmarshal(bs, &ops); err != nil {
+		return err
+	}
+
+	lines := make([]string, len(ops))
+	for i, op := range ops {
+		if strings.ContainsAny(op.Text, "\r\n") {
+			return errors.Errorf("op %d text must be a single line", i)
+		}
+
+		lines[i] = op.Text
+	}
+
+	res := Process(strings.Join(lines, "\n"))
+
+	for _, d := range res.Diagnostics {
+		if d.Severity() == DiagErr {
+			return errors.Errorf("failed to decode op %d: %s", d.Line(), d)
+		}
+	}
+
+	if len(res.Listing) != len(ops) {
+		return errors.Errorf("decoded %d ops, expected: %d", len(res.Listing), len(ops))
+	}
+
+	*l = res.Listing
+
+	return nil
+}
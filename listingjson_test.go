@@ -0,0 +1,50 @@
+package teal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestListingJSON(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+byte "a b"
+method "add(uint64)uint64"
+pushints 1 2
+ecdsa_verify Secp256k1
+l1:
+bnz l1
+int 1
+return`)
+
+	bs, err := json.Marshal(res.Listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []ListingOp
+	if err := json.Unmarshal(bs, &ops); err != nil {
+		t.Fatal(err)
+	}
+
+	if op := ops[4]; op.Op != "pushints" || len(op.Args) != 2 || op.Args[1] != "2" {
+		t.Errorf("unexpected op: %#v", op)
+	}
+
+	if op := ops[6]; op.Op != "label" || len(op.Args) != 1 || op.Args[0] != "l1" {
+		t.Errorf("unexpected label: %#v", op)
+	}
+
+	var l Listing
+	if err := json.Unmarshal(bs, &l); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.String() != res.Listing.String() {
+		t.Errorf("unexpected decoded listing:\n%s\nexpected:\n%s", l, res.Listing)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"op":"foo","text":"foo"}]`), &l); err == nil {
+		t.Error("expected an error for an unknown op")
+	}
+}
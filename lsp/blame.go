@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dragmz/teal"
+)
+
+type tealOpenSourceCommandArgs struct {
+	Uri  string `json:"uri"`
+	Line int    `json:"line"`
+}
+
+type lspShowDocumentParams struct {
+	Uri       string    `json:"uri"`
+	TakeFocus bool      `json:"takeFocus,omitempty"`
+	Selection *lspRange `json:"selection,omitempty"`
+}
+
+// sourceMapPath returns the path of the source map of the generated program, e.g. approval.teal.map.
+func sourceMapPath(path string) string {
+	return path + ".map"
+}
+
+// sourceLenses returns the code lenses with the originating high-level lines of the blocks of a generated program,
+// e.g. "from contract.py:42", when its source map and the original sources are present.
+func (l *lsp) sourceLenses(uri string) []lspCodeLens {
+	path := uriToPath(uri)
+	if path == "" {
+		return nil
+	}
+
+	bs, err := l.readFile(sourceMapPath(path))
+	if err != nil {
+		return nil
+	}
+
+	m, err := teal.ParseSourceMap(bs)
+	if err != nil {
+		l.log.Warn("failed to parse the source map", "path", sourceMapPath(path), "err", err)
+		return nil
+	}
+
+	blocks, err := m.Blocks()
+	if err != nil {
+		l.log.Warn("failed to decode the source map", "path", sourceMapPath(path), "err", err)
+		return nil
+	}
+
+	exists := map[string]bool{}
+
+	var cls []lspCodeLens
+
+	for _, b := range blocks {
+		src := filepath.FromSlash(b.Origin.Source)
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(filepath.Dir(path), src)
+		}
+
+		// the source maps of the compiled programs point back to the program itself
+		if src == path {
+			continue
+		}
+
+		ok, seen := exists[src]
+		if !seen {
+			_, err := os.Stat(src)
+			ok = err == nil
+			exists[src] = ok
+		}
+
+		if !ok {
+			continue
+		}
+
+		cls = append(cls, lspCodeLens{
+			Range: lspRange{
+				Start: lspPosition{
+					Line: b.Start,
+				},
+				End: lspPosition{
+					Line: b.End,
+				},
+			},
+			Command: &lspCommand{
+				Title:   fmt.Sprintf("from %s:%d", b.Origin.Source, b.Origin.Line+1),
+				Command: "teal.source.open",
+				Arguments: []interface{}{
+					tealOpenSourceCommandArgs{
+						Uri:  pathToUri(src),
+						Line: b.Origin.Line,
+					},
+				},
+			},
+		})
+	}
+
+	return cls
+}
@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceLenses(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "approval.teal")
+	src := "#pragma version 8\nint 1\nint 2\n+\nreturn"
+
+	for name, content := range map[string]string{
+		"approval.teal":     src,
+		"approval.teal.map": `{"version":3,"sources":["contract.py","missing.py"],"names":[],"mappings":"AAAA;AACA;AAAA;AACA;CAAA"}`,
+		"contract.py":       "from pyteal import *\nprogram = Return(Int(1) + Int(2))\n",
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := pathToUri(path)
+	testOpen(l, uri, src)
+
+	cls := l.sourceLenses(uri)
+
+	expected := []struct {
+		line  int
+		title string
+		src   int
+	}{
+		{0, "from contract.py:1", 0},
+		{1, "from contract.py:2", 1},
+		{3, "from contract.py:3", 2},
+	}
+
+	if len(cls) != len(expected) {
+		t.Fatalf("unexpected lenses: %+v", cls)
+	}
+
+	for i, e := range expected {
+		cl := cls[i]
+		if cl.Range.Start.Line != e.line || cl.Command.Title != e.title || cl.Command.Command != "teal.source.open" {
+			t.Errorf("unexpected lens %d: %+v", i, cl.Command)
+		}
+
+		args := cl.Command.Arguments[0].(tealOpenSourceCommandArgs)
+		if args.Uri != pathToUri(filepath.Join(dir, "contract.py")) || args.Line != e.src {
+			t.Errorf("unexpected lens %d args: %+v", i, args)
+		}
+	}
+
+	if cls[1].Range.End.Line != 2 {
+		t.Errorf("unexpected block end: %d", cls[1].Range.End.Line)
+	}
+
+	out := &bytes.Buffer{}
+	l.w.Reset(out)
+
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "workspace/executeCommand",
+		"params": map[string]any{
+			"command":   "teal.source.open",
+			"arguments": cls[1].Command.Arguments,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = l.serve(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 2 || rs[0].Method != "window/showDocument" {
+		t.Fatalf("unexpected messages: %+v", rs)
+	}
+
+	var p lspShowDocumentParams
+	err = json.Unmarshal(rs[0].Params, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Uri != pathToUri(filepath.Join(dir, "contract.py")) || p.Selection == nil || p.Selection.Start.Line != 1 {
+		t.Errorf("unexpected show document params: %+v", p)
+	}
+}
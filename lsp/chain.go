@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// chainStateTtl is the time after which the cached on-chain state is fetched again
+const chainStateTtl = 30 * time.Second
+
+// chainStateTimeout is the timeout of a single state fetch
+const chainStateTimeout = 10 * time.Second
+
+// chainState caches the global state of the configured application.
+// The state is fetched in the background so the requests never wait for the network.
+type chainState struct {
+	fetch func(ctx context.Context) ([]models.TealKeyValue, error)
+
+	mu      sync.Mutex
+	loading bool
+	fetched time.Time
+	globals map[string]models.TealValue
+	err     error
+}
+
+func newChainState(address string, token string, app uint64) (*chainState, error) {
+	ac, err := algod.MakeClient(address, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create algod client")
+	}
+
+	return &chainState{
+		fetch: func(ctx context.Context) ([]models.TealKeyValue, error) {
+			info, err := ac.GetApplicationByID(app).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return info.Params.GlobalState, nil
+		},
+	}, nil
+}
+
+// refresh starts fetching the state unless it is already being fetched or the cached one is fresh.
+func (c *chainState) refresh() {
+	if c.loading || time.Since(c.fetched) < chainStateTtl {
+		return
+	}
+
+	c.loading = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), chainStateTimeout)
+		defer cancel()
+
+		kvs, err := c.fetch(ctx)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.loading = false
+		c.fetched = time.Now()
+		c.err = err
+
+		if err != nil {
+			return
+		}
+
+		c.globals = map[string]models.TealValue{}
+		for _, kv := range kvs {
+			k, err := base64.StdEncoding.DecodeString(kv.Key)
+			if err != nil {
+				continue
+			}
+			c.globals[string(k)] = kv.Value
+		}
+	}()
+}
+
+// global describes the cached value of the global key, it refreshes the cache in the background if needed.
+func (c *chainState) global(key []byte) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refresh()
+
+	switch {
+	case c.globals == nil && c.err != nil:
+		return fmt.Sprintf("failed to fetch the on-chain state: %s", c.err)
+	case c.globals == nil:
+		return "fetching the on-chain state.."
+	}
+
+	v, ok := c.globals[string(key)]
+	if !ok {
+		return "not set on-chain"
+	}
+
+	switch v.Type {
+	case 1:
+		bs, err := base64.StdEncoding.DecodeString(v.Bytes)
+		if err != nil {
+			return "invalid on-chain value"
+		}
+		return fmt.Sprintf("on-chain: %s", teal.FormatKey(bs))
+	default:
+		return fmt.Sprintf("on-chain: %d", v.Uint)
+	}
+}
+
+// chainHover describes the on-chain value of the global key read at the line.
+func chainHover(c *chainState, res *teal.ProcessResult, line int) string {
+	if c == nil {
+		return ""
+	}
+
+	key, ok := teal.GlobalKeyAt(res, line)
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("Global %s %s", teal.FormatKey(key), c.global(key))
+}
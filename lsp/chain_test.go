@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/dragmz/teal"
+)
+
+func TestChainHover(t *testing.T) {
+	done := make(chan struct{})
+
+	c := &chainState{
+		fetch: func(ctx context.Context) ([]models.TealKeyValue, error) {
+			defer close(done)
+			return []models.TealKeyValue{
+				{
+					Key:   base64.StdEncoding.EncodeToString([]byte("counter")),
+					Value: models.TealValue{Type: 2, Uint: 42},
+				},
+			}, nil
+		},
+	}
+
+	res := teal.Process("#pragma version 8\nbyte \"counter\"\napp_global_get\nreturn")
+
+	if s := chainHover(c, res, 1); s != "" {
+		t.Errorf("unexpected hover of a line without a global read: %s", s)
+	}
+
+	if s := chainHover(c, res, 2); !strings.Contains(s, "fetching") {
+		t.Errorf("expected the state to be fetched in the background, got: %s", s)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("state not fetched")
+	}
+
+	// the cache is updated right after the fetch returns
+	for i := 0; i < 100; i++ {
+		if s := chainHover(c, res, 2); s == "Global counter on-chain: 42" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Errorf("unexpected hover: %s", chainHover(c, res, 2))
+}
@@ -0,0 +1,99 @@
+package lsp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dragmz/teal"
+)
+
+// parseHexColor parses the 0xRRGGBB or 0xRRGGBBAA byte literal.
+func parseHexColor(s string) (lspColor, bool) {
+	if !strings.HasPrefix(s, "0x") {
+		return lspColor{}, false
+	}
+
+	bs, err := hex.DecodeString(s[2:])
+	if err != nil || len(bs) != 3 && len(bs) != 4 {
+		return lspColor{}, false
+	}
+
+	c := lspColor{
+		Red:   float64(bs[0]) / 255,
+		Green: float64(bs[1]) / 255,
+		Blue:  float64(bs[2]) / 255,
+		Alpha: 1,
+	}
+
+	if len(bs) == 4 {
+		c.Alpha = float64(bs[3]) / 255
+	}
+
+	return c, true
+}
+
+func colorByte(v float64) byte {
+	return byte(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// documentColors returns the byte and pushbytes hex literals of 3 or 4 bytes as RGB or RGBA colors.
+func documentColors(res *teal.ProcessResult) []lspColorInformation {
+	cs := []lspColorInformation{}
+
+	for _, sub := range res.Sublines {
+		ln := sub.Tokens
+		if len(ln) != 2 {
+			continue
+		}
+
+		switch ln[0].String() {
+		case "byte", "pushbytes":
+		default:
+			continue
+		}
+
+		t := ln[1]
+
+		c, ok := parseHexColor(t.String())
+		if !ok {
+			continue
+		}
+
+		cs = append(cs, lspColorInformation{
+			Range: lspRange{
+				Start: lspPosition{Line: t.Line(), Character: t.Begin()},
+				End:   lspPosition{Line: t.Line(), Character: t.End()},
+			},
+			Color: c,
+		})
+	}
+
+	return cs
+}
+
+// colorPresentations returns the hex literals of the color, the RGB literal is offered only for the opaque colors.
+func colorPresentations(c lspColor, r lspRange) []lspColorPresentation {
+	rgb := fmt.Sprintf("0x%02x%02x%02x", colorByte(c.Red), colorByte(c.Green), colorByte(c.Blue))
+	rgba := fmt.Sprintf("%s%02x", rgb, colorByte(c.Alpha))
+
+	var labels []string
+	if colorByte(c.Alpha) == 255 {
+		labels = append(labels, rgb)
+	}
+	labels = append(labels, rgba)
+
+	ps := []lspColorPresentation{}
+	for _, label := range labels {
+		ps = append(ps, lspColorPresentation{
+			Label: label,
+			TextEdit: &lspTextEdit{
+				Range:   r,
+				NewText: label,
+			},
+		})
+	}
+
+	return ps
+}
@@ -0,0 +1,249 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/stdlib"
+)
+
+const (
+	lspCompletionItemKindOperator = 25
+	lspCompletionItemKindSnippet  = 15
+
+	lspInsertTextFormatSnippet = 2
+
+	lspCompletionItemTagDeprecated = 1
+)
+
+// completionRecentLines is the number of lines before the cursor with the ops boosted in the completion
+const completionRecentLines = 20
+
+// the completion items are sorted by the rank first
+const (
+	completionRankRecent = iota
+	completionRankValid
+	completionRankDeprecated
+	completionRankUnavailable
+)
+
+func completionSortText(rank int, order int, label string) string {
+	return fmt.Sprintf("%d%03d%s", rank, order, label)
+}
+
+// typedPrefix returns the part of the token before the cursor.
+func typedPrefix(tok teal.Token, ch int) string {
+	s := tok.String()
+
+	n := ch - tok.Begin()
+	if n < 0 {
+		return ""
+	}
+	if n < len(s) {
+		return s[:n]
+	}
+
+	return s
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// recentOps returns the distance in lines of the ops used before the line, up to completionRecentLines.
+func recentOps(res *teal.ProcessResult, line int) map[string]int {
+	ds := map[string]int{}
+
+	for _, t := range res.Ops {
+		d := line - t.Line()
+		if d <= 0 || d > completionRecentLines {
+			continue
+		}
+
+		name := t.String()
+		if prev, ok := ds[name]; !ok || d < prev {
+			ds[name] = d
+		}
+	}
+
+	return ds
+}
+
+// argCompletions returns the values of the immediate argument at the position that match the typed prefix.
+func argCompletions(res *teal.ProcessResult, ln teal.Line, line int, ch int) []lspCompletionItem {
+	var prefix string
+	if tok, _, ok := ln.ImmAt(ch); ok {
+		prefix = typedPrefix(tok, ch)
+	}
+
+	ccs := []lspCompletionItem{}
+
+	for _, v := range res.ArgValsAt(line, ch) {
+		if !hasPrefixFold(v.Name, prefix) {
+			continue
+		}
+
+		var d *lspCompletionItemLabelDetails
+		if !v.NoValue {
+			d = &lspCompletionItemLabelDetails{
+				Detail: fmt.Sprintf(" = %d", v.Value),
+			}
+		} else if v.Signature != "" {
+			d = &lspCompletionItemLabelDetails{
+				Detail: fmt.Sprintf(" %s", v.Signature),
+			}
+		}
+		ccs = append(ccs, lspCompletionItem{
+			LabelDetails: d,
+			Label:        v.Name,
+			Documentation: lspMarkupContent{
+				Kind:  "markdown",
+				Value: v.Docs,
+			},
+			SortText: v.Name,
+		})
+	}
+
+	sort.Slice(ccs, func(i, j int) bool {
+		return ccs[i].SortText < ccs[j].SortText
+	})
+
+	return ccs
+}
+
+func snippetCompletions() []lspCompletionItem {
+	kind := lspCompletionItemKindSnippet
+	format := lspInsertTextFormatSnippet
+
+	var at string
+	var bt string
+	for i, name := range teal.OnCompletionNames {
+		if i > 0 {
+			at += " "
+		}
+
+		field := fmt.Sprintf("${%d:%s}", i+1, strings.ToLower(name))
+
+		at += field
+		bt += fmt.Sprintf("%s:\n", field)
+
+		if i < len(teal.OnCompletionNames)-1 {
+			bt += fmt.Sprintf("b ${%d:then}\n", len(teal.OnCompletionNames)+2)
+		}
+
+		bt += "\n"
+	}
+
+	bt += fmt.Sprintf("${%d:then}:\n$%d", len(teal.OnCompletionNames)+2, len(teal.OnCompletionNames)+3)
+
+	items := []lspCompletionItem{
+		{
+			Label:            "soc",
+			Kind:             &kind,
+			Detail:           "switch on OnCompletion",
+			InsertText:       fmt.Sprintf("txn OnCompletion\nswitch %s\n%s", at, bt),
+			InsertTextFormat: &format,
+		},
+		{
+			Label:            "func",
+			Kind:             &kind,
+			Detail:           "create subroutine",
+			InsertText:       "${1:sub}:\r\n\r\n\tproto ${2:0} ${3:0}\r\n\t${4}\r\n\tretsub\r\n",
+			InsertTextFormat: &format,
+		},
+	}
+
+	for _, s := range stdlib.Snippets() {
+		items = append(items, lspCompletionItem{
+			Label:            s.Name,
+			Kind:             &kind,
+			Detail:           s.Detail,
+			InsertText:       s.Text() + "\n",
+			InsertTextFormat: &format,
+		})
+	}
+
+	return items
+}
+
+// opCompletions returns the ops matching the typed prefix ranked by relevance: the ops used in the preceding lines,
+// the other ops valid in the program version and mode, the deprecated ops and the ops of the later versions.
+// The ops not available in the mode are omitted.
+func opCompletions(res *teal.ProcessResult, mode teal.ProgramMode, line int, prefix string) []lspCompletionItem {
+	var ccs []lspCompletionItem
+
+	for _, c := range snippetCompletions() {
+		if strings.HasPrefix(c.Label, prefix) {
+			c.SortText = completionSortText(completionRankValid, 0, c.Label)
+			ccs = append(ccs, c)
+		}
+	}
+
+	recent := recentOps(res, line)
+
+	kind := lspCompletionItemKindOperator
+	format := lspInsertTextFormatSnippet
+
+	// the available ops include the later versions to rank them last
+	for _, spec := range teal.AvailableOps(^uint64(0), mode) {
+		if !strings.HasPrefix(spec.Name, prefix) {
+			continue
+		}
+
+		min := spec.MinVersion(mode)
+
+		c := lspCompletionItem{
+			Label: spec.Name,
+			Documentation: lspMarkupContent{
+				Kind:  "markdown",
+				Value: spec.Doc,
+			},
+			Kind: &kind,
+			LabelDetails: &lspCompletionItemLabelDetails{
+				Description: fmt.Sprintf("v%d", min),
+				Detail:      " " + strings.TrimPrefix(strings.TrimPrefix(spec.Signature, spec.Name), " "),
+			},
+		}
+
+		if len(spec.Args) > 0 {
+			var placeholders []string
+			for i, arg := range spec.Args {
+				placeholders = append(placeholders, fmt.Sprintf("${%d:%s}", i+1, arg.Name))
+			}
+
+			c.InsertText = fmt.Sprintf("%s %s", spec.Name, strings.Join(placeholders, " "))
+			c.InsertTextFormat = &format
+		}
+
+		rank := completionRankValid
+		order := 0
+
+		if msg := teal.OpDeprecation(spec.Name, res.Version, mode); msg != "" {
+			rank = completionRankDeprecated
+
+			deprecated := true
+			c.Deprecated = &deprecated
+			c.Tags = []int{lspCompletionItemTagDeprecated}
+			c.Detail = msg
+		} else if d, ok := recent[spec.Name]; ok {
+			rank = completionRankRecent
+			order = d
+		}
+
+		if min > res.Version {
+			rank = completionRankUnavailable
+		}
+
+		c.SortText = completionSortText(rank, order, spec.Name)
+
+		ccs = append(ccs, c)
+	}
+
+	sort.SliceStable(ccs, func(i, j int) bool {
+		return ccs[i].SortText < ccs[j].SortText
+	})
+
+	return ccs
+}
@@ -0,0 +1,237 @@
+package lsp
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+type lspDidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+// lspRefresh are the refresh requests the client supports.
+type lspRefresh struct {
+	SemanticTokens bool
+	InlayHint      bool
+	CodeLens       bool
+	Diagnostics    bool
+}
+
+func (c *lspWorkspaceClientCapabilities) refreshes() lspRefresh {
+	supported := func(c *lspRefreshClientCapabilities) bool {
+		return c != nil && c.RefreshSupport
+	}
+
+	return lspRefresh{
+		SemanticTokens: supported(c.SemanticTokens),
+		InlayHint:      supported(c.InlayHint),
+		CodeLens:       supported(c.CodeLens),
+		Diagnostics:    supported(c.Diagnostics),
+	}
+}
+
+// apply sets the options present in o.
+func (c *tealConfig) apply(o tealInitializationOptions) {
+	set := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&c.SemanticTokens, o.SemanticTokens)
+	set(&c.InlayNamed, o.InlayNamed)
+	set(&c.InlayDecoded, o.InlayDecoded)
+	set(&c.InlayAddress, o.InlayAddress)
+	set(&c.InlayAbi, o.InlayAbi)
+	set(&c.InlayStackDepth, o.InlayStackDepth)
+	set(&c.LensRefs, o.LensRefs)
+	set(&c.LensSource, o.LensSource)
+	set(&c.LensLifecycle, o.LensLifecycle)
+	set(&c.LensSize, o.LensSize)
+	set(&c.ExperimentalFixes, o.ExperimentalFixes)
+	set(&c.Usage, o.Usage)
+
+	if o.ExtraPages != nil {
+		c.ExtraPages = *o.ExtraPages
+	}
+	if o.Mode != nil {
+		c.Mode = *o.Mode
+	}
+	if o.FormatSublines != nil {
+		c.FormatSublines = *o.FormatSublines
+	}
+	if o.FormatWidth != nil {
+		c.FormatWidth = *o.FormatWidth
+	}
+	if o.FormatCommentColumn != nil {
+		c.FormatCommentColumn = *o.FormatCommentColumn
+	}
+	if o.Algod != nil {
+		c.Algod = *o.Algod
+	}
+	if o.AlgodToken != nil {
+		c.AlgodToken = *o.AlgodToken
+	}
+	if o.AppId != nil {
+		c.AppId = *o.AppId
+	}
+	if o.Eol != nil {
+		c.Eol = *o.Eol
+	}
+	if o.KnownAddresses != nil {
+		c.KnownAddresses = *o.KnownAddresses
+	}
+	if o.Silence != nil {
+		c.Silence = o.Silence
+	}
+	if o.Escalate != nil {
+		c.Escalate = o.Escalate
+	}
+	if o.Folders != nil {
+		c.Folders = o.Folders
+	}
+}
+
+// configure applies the options and reloads the known addresses and the on-chain state if their settings changed,
+// it returns the previous config.
+func (l *lsp) configure(o tealInitializationOptions) tealConfig {
+	prev := l.config
+	l.config.apply(o)
+
+	l.stats.collect(l.config.Usage)
+
+	if l.config.KnownAddresses != prev.KnownAddresses {
+		l.addresses = nil
+
+		if l.config.KnownAddresses != "" {
+			as, err := teal.ReadKnownAddresses(l.config.KnownAddresses)
+			if err != nil {
+				l.log.Warn("failed to read the known addresses", "err", err)
+			}
+			l.addresses = as
+		}
+	}
+
+	if l.config.Algod != prev.Algod || l.config.AlgodToken != prev.AlgodToken || l.config.AppId != prev.AppId {
+		l.chain = nil
+
+		if l.config.Algod != "" && l.config.AppId != 0 {
+			chain, err := newChainState(l.config.Algod, l.config.AlgodToken, l.config.AppId)
+			if err != nil {
+				l.log.Warn("failed to configure the on-chain state", "err", err)
+			}
+			l.chain = chain
+		}
+	}
+
+	return prev
+}
+
+// settingsOptions reads the options from the "teal" section of the settings, or from the settings themselves.
+func settingsOptions(settings json.RawMessage) (tealInitializationOptions, error) {
+	var o tealInitializationOptions
+
+	if len(settings) == 0 || string(settings) == "null" {
+		return o, nil
+	}
+
+	var section struct {
+		Teal *tealInitializationOptions `json:"teal"`
+	}
+
+	err := json.Unmarshal(settings, &section)
+	if err != nil {
+		return o, errors.Wrap(err, "failed to parse settings")
+	}
+
+	if section.Teal != nil {
+		return *section.Teal, nil
+	}
+
+	err = json.Unmarshal(settings, &o)
+	if err != nil {
+		return o, errors.Wrap(err, "failed to parse settings")
+	}
+
+	return o, nil
+}
+
+// reconfigure applies the changed settings at runtime. The cached results of the open documents are kept
+// unless the options they are processed with changed, the client is asked to refresh the affected features.
+func (l *lsp) reconfigure(settings json.RawMessage) error {
+	o, err := settingsOptions(settings)
+	if err != nil {
+		return invalidParams(err)
+	}
+
+	prev := l.configure(o)
+	cfg := l.config
+
+	processed := cfg.ExperimentalFixes != prev.ExperimentalFixes ||
+		cfg.KnownAddresses != prev.KnownAddresses ||
+		!reflect.DeepEqual(cfg.Silence, prev.Silence) ||
+		!reflect.DeepEqual(cfg.Escalate, prev.Escalate) ||
+		!reflect.DeepEqual(cfg.Folders, prev.Folders)
+
+	if processed {
+		// the diagnostics, the inlay hints and the code lenses are refreshed too
+		err := l.reprocess()
+		if err != nil {
+			return err
+		}
+	}
+
+	inlays := cfg.InlayNamed != prev.InlayNamed || cfg.InlayDecoded != prev.InlayDecoded ||
+		cfg.InlayAddress != prev.InlayAddress || cfg.InlayAbi != prev.InlayAbi || cfg.InlayStackDepth != prev.InlayStackDepth
+
+	lenses := cfg.LensRefs != prev.LensRefs || cfg.LensLifecycle != prev.LensLifecycle ||
+		cfg.LensSize != prev.LensSize || cfg.LensSource != prev.LensSource ||
+		cfg.ExtraPages != prev.ExtraPages || cfg.Mode != prev.Mode
+
+	return l.refreshClient([]lspRefreshRequest{
+		{"workspace/semanticTokens/refresh", cfg.SemanticTokens != prev.SemanticTokens && l.refresh.SemanticTokens},
+		{"workspace/inlayHint/refresh", inlays && !processed && l.refresh.InlayHint},
+		{"workspace/codeLens/refresh", lenses && !processed && l.refresh.CodeLens},
+	})
+}
+
+// reprocess drops the cached results of the open documents, e.g. after the options they are processed with changed,
+// and asks the client to refresh the features computed from them.
+func (l *lsp) reprocess() error {
+	for uri, doc := range l.allDocs() {
+		doc.reconfigure(l.docOptions(uri))
+	}
+
+	l.indexWorkspace()
+
+	return l.refreshClient([]lspRefreshRequest{
+		{"workspace/diagnostic/refresh", l.refresh.Diagnostics},
+		{"workspace/inlayHint/refresh", l.refresh.InlayHint},
+		{"workspace/codeLens/refresh", l.refresh.CodeLens},
+	})
+}
+
+// lspRefreshRequest is a refresh request sent to the client if needed.
+type lspRefreshRequest struct {
+	method string
+	send   bool
+}
+
+// refreshClient sends the needed refresh requests.
+func (l *lsp) refreshClient(rs []lspRefreshRequest) error {
+	for _, r := range rs {
+		if !r.send {
+			continue
+		}
+
+		err := l.request(r.method, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
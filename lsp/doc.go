@@ -1,9 +1,27 @@
 package lsp
 
+import "github.com/dragmz/teal"
+
 var (
 	EOL = []string{"\n", "\r\n", "\r"}
 )
 
+// eols are the line endings of the eol option.
+var eols = map[string]string{
+	"lf":   "\n",
+	"crlf": "\r\n",
+	"cr":   "\r",
+}
+
+// eol returns the line ending of the generated text, the configured one or the dominant one of the document.
+func (l *lsp) eol(res *teal.ProcessResult) string {
+	if eol, ok := eols[l.config.Eol]; ok {
+		return eol
+	}
+
+	return res.Eol
+}
+
 type doc struct {
 	s     string
 	lines []string
@@ -1,6 +1,10 @@
 package lsp
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -63,3 +67,143 @@ func TestSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshotUnchangedByUpdate(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "file:///src/main.teal"
+	testOpen(l, uri, "#pragma version 8\nint 1\nreturn")
+
+	doc := l.doc(uri)
+	snap := doc.Snapshot()
+
+	doc.Update("#pragma version 8\nint 1\nint 2\n+\nreturn")
+
+	if len(snap.Results().Lines) != 3 {
+		t.Errorf("expected the snapshot to keep the old text, got: %d lines", len(snap.Results().Lines))
+	}
+
+	if len(doc.Results().Lines) != 5 {
+		t.Errorf("expected the doc to have the new text, got: %d lines", len(doc.Results().Lines))
+	}
+}
+
+func TestConcurrentDocs(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := "file:///src/main.teal"
+	lib := "file:///src/lib.teal"
+
+	testOpen(l, lib, "double:\ndup\n+\nretsub")
+	testOpen(l, main, "#pragma version 8\n#include \"lib.teal\"\nint 1\ncallsub double\nreturn")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				src := "double:\n" + strings.Repeat("dup\n+\n", i+j%3) + "retsub"
+				l.doc(lib).Update(src)
+				l.invalidate(lib)
+			}
+		}(i)
+	}
+
+	errs := make(chan error, 16)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				for uri, doc := range l.allDocs() {
+					snap := doc.Snapshot()
+					res := snap.Results()
+
+					if n := len(strings.Split(snap.s, "\n")); len(res.Lines) != n {
+						errs <- fmt.Errorf("%s: results of %d lines for text of %d lines", uri, len(res.Lines), n)
+						return
+					}
+
+					_ = doc.Includes("/src/lib.teal")
+				}
+
+				_, _ = l.readFile("/src/lib.teal")
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for j := 0; j < 50; j++ {
+			uri := fmt.Sprintf("file:///src/tmp%d.teal", j)
+			testOpen(l, uri, "#pragma version 8\nint 1")
+			l.close(uri)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// the invalidated snapshot depends on the files once it is processed
+	doc := l.doc(main)
+	doc.Results()
+
+	if !doc.Includes("/src/lib.teal") {
+		t.Error("expected main to include lib")
+	}
+}
+
+func TestIncludesWhileProcessing(t *testing.T) {
+	reading := make(chan struct{})
+	release := make(chan struct{})
+
+	read := func(path string) ([]byte, error) {
+		close(reading)
+		<-release
+		return []byte("double:\ndup\n+\nretsub"), nil
+	}
+
+	doc := newLspDoc("/src/main.teal", read)
+	doc.Update("#pragma version 8\n#include \"lib.teal\"\nint 1\ncallsub double\nreturn")
+
+	if doc.Includes("/src/lib.teal") {
+		t.Error("expected the doc not processed yet not to include lib")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		doc.Results()
+	}()
+
+	<-reading
+
+	// the doc still being processed is invalidated by the changes of lib
+	if !doc.Includes("/src/lib.teal") {
+		t.Error("expected the doc being processed to include lib")
+	}
+
+	close(release)
+	<-done
+
+	if !doc.Includes("/src/lib.teal") {
+		t.Error("expected the processed doc to include lib")
+	}
+}
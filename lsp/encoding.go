@@ -0,0 +1,337 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/dragmz/teal"
+)
+
+// The token columns are byte offsets while the LSP positions count UTF-16 code units unless
+// the client supports the UTF-8 or the UTF-32 position encoding, the positions of the messages
+// are converted when they are written and read.
+
+const (
+	positionEncodingUtf8  = "utf-8"
+	positionEncodingUtf16 = "utf-16"
+	positionEncodingUtf32 = "utf-32"
+)
+
+// positionEncodings are the supported encodings in the order of preference, utf-8 needs no conversion.
+var positionEncodings = []string{
+	positionEncodingUtf8,
+	positionEncodingUtf16,
+	positionEncodingUtf32,
+}
+
+// negotiateEncoding picks the preferred encoding supported by the client, utf-16 if the client lists none.
+func negotiateEncoding(client []string) string {
+	for _, e := range positionEncodings {
+		for _, c := range client {
+			if c == e {
+				return e
+			}
+		}
+	}
+
+	return positionEncodingUtf16
+}
+
+// columnUnits returns the function counting the code units of a rune in the encoding, nil for utf-8.
+func columnUnits(encoding string) func(r rune) int {
+	switch encoding {
+	case positionEncodingUtf8:
+		return nil
+	case positionEncodingUtf32:
+		return utf32Units
+	default:
+		return utf16Units
+	}
+}
+
+// utf16Units returns the number of the UTF-16 code units of the rune.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+func utf32Units(r rune) int {
+	return 1
+}
+
+// encodeColumn converts the byte offset in the line to the column of the code units,
+// the offsets past the end of the line are kept past the end.
+func encodeColumn(units func(r rune) int, line string, b int) int {
+	if b <= 0 {
+		return b
+	}
+
+	n := 0
+	if b > len(line) {
+		n = b - len(line)
+		b = len(line)
+	}
+
+	for _, r := range line[:b] {
+		n += units(r)
+	}
+
+	return n
+}
+
+// decodeColumn converts the column of the code units in the line to the byte offset.
+func decodeColumn(units func(r rune) int, line string, c int) int {
+	if c <= 0 {
+		return c
+	}
+
+	n := 0
+	for i, r := range line {
+		if n >= c {
+			return i
+		}
+		n += units(r)
+	}
+
+	return len(line) + c - n
+}
+
+// encoder converts the byte offsets to the columns of the negotiated encoding, nil for utf-8.
+func (l *lsp) encoder() func(line string, b int) int {
+	units := columnUnits(l.encoding)
+	if units == nil {
+		return nil
+	}
+
+	return func(line string, b int) int {
+		return encodeColumn(units, line, b)
+	}
+}
+
+// decoder converts the columns of the negotiated encoding to the byte offsets, nil for utf-8.
+func (l *lsp) decoder() func(line string, c int) int {
+	units := columnUnits(l.encoding)
+	if units == nil {
+		return nil
+	}
+
+	return func(line string, c int) int {
+		return decodeColumn(units, line, c)
+	}
+}
+
+// docLines returns the lines of the document if any of them has non-ASCII characters, nil otherwise.
+func (l *lsp) docLines(uri string) []string {
+	var s string
+
+	if doc := l.doc(uri); doc != nil {
+		s = doc.Text()
+	} else {
+		path := uriToPath(uri)
+		if path == "" {
+			return nil
+		}
+
+		bs, err := l.readFile(path)
+		if err != nil {
+			return nil
+		}
+		s = string(bs)
+	}
+
+	return textLines(s)
+}
+
+// textLines returns the lines of the text if any of them has non-ASCII characters, nil otherwise.
+func textLines(s string) []string {
+	if isASCII(s) {
+		return nil
+	}
+
+	return splitLines(s)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// positionConverter rewrites the {line, character} objects of a message using the lines
+// of the document of the closest uri.
+type positionConverter struct {
+	l     *lsp
+	conv  func(line string, c int) int
+	lines map[string][]string
+
+	changed bool
+}
+
+func (pc *positionConverter) docLines(uri string) []string {
+	if ls, ok := pc.lines[uri]; ok {
+		return ls
+	}
+
+	ls := pc.l.docLines(uri)
+	pc.lines[uri] = ls
+
+	return ls
+}
+
+func (pc *positionConverter) convert(v interface{}, uri string) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			pc.convert(item, uri)
+		}
+
+	case map[string]interface{}:
+		if s, ok := v["uri"].(string); ok {
+			uri = s
+		}
+
+		if td, ok := v["textDocument"].(map[string]interface{}); ok {
+			if s, ok := td["uri"].(string); ok {
+				uri = s
+			}
+		}
+
+		if pc.position(v, uri) {
+			return
+		}
+
+		for k, item := range v {
+			switch k {
+			case "relatedDocuments", "changes":
+				// keyed by the document uri
+				if m, ok := item.(map[string]interface{}); ok {
+					for duri, ditem := range m {
+						pc.convert(ditem, duri)
+					}
+					continue
+				}
+			}
+
+			pc.convert(item, uri)
+		}
+	}
+}
+
+// position converts the character of the position object, reporting whether the object is a position.
+func (pc *positionConverter) position(v map[string]interface{}, uri string) bool {
+	if len(v) != 2 {
+		return false
+	}
+
+	ln, ok := v["line"].(json.Number)
+	if !ok {
+		return false
+	}
+
+	ch, ok := v["character"].(json.Number)
+	if !ok {
+		return false
+	}
+
+	li, err := ln.Int64()
+	if err != nil {
+		return true
+	}
+
+	ci, err := ch.Int64()
+	if err != nil {
+		return true
+	}
+
+	lines := pc.docLines(uri)
+	if li < 0 || int(li) >= len(lines) {
+		return true
+	}
+
+	if c := pc.conv(lines[li], int(ci)); c != int(ci) {
+		v["character"] = c
+		pc.changed = true
+	}
+
+	return true
+}
+
+// convertPositions rewrites the positions of the JSON message, uri is the document of the positions without a closer uri.
+func (l *lsp) convertPositions(data []byte, uri string, conv func(line string, c int) int) []byte {
+	if conv == nil {
+		return data
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v interface{}
+
+	err := d.Decode(&v)
+	if err != nil {
+		return data
+	}
+
+	pc := &positionConverter{
+		l:     l,
+		conv:  conv,
+		lines: map[string][]string{},
+	}
+	pc.convert(v, uri)
+
+	if !pc.changed {
+		return data
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+
+	return out
+}
+
+// requestUri returns the uri of the text document of the request params, empty if none.
+func requestUri(data []byte) string {
+	var m struct {
+		Params struct {
+			TextDocument struct {
+				Uri string `json:"uri"`
+			} `json:"textDocument"`
+		} `json:"params"`
+	}
+
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return ""
+	}
+
+	return m.Params.TextDocument.Uri
+}
+
+// encodeSemanticTokens converts the byte offsets of the tokens to the columns of the lines.
+func encodeSemanticTokens(enc func(line string, b int) int, lines []string, st teal.SemanticTokens) {
+	if enc == nil || lines == nil {
+		return
+	}
+
+	for i, t := range st {
+		if t.Line < 0 || t.Line >= len(lines) {
+			continue
+		}
+
+		b := enc(lines[t.Line], t.Index)
+		e := enc(lines[t.Line], t.Index+t.Length)
+
+		st[i].Index = b
+		st[i].Length = e - b
+	}
+}
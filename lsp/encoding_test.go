@@ -0,0 +1,250 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUtf16Columns(t *testing.T) {
+	line := `byte "é😀"; int 1`
+
+	tests := []struct {
+		b int
+		c int
+	}{
+		{b: 0, c: 0},
+		{b: 5, c: 5},
+		{b: 6, c: 6},
+		{b: 8, c: 7},
+		{b: 12, c: 9},
+		{b: 15, c: 12},
+		{b: len(line), c: 17},
+		{b: len(line) + 2, c: 19},
+	}
+
+	for _, test := range tests {
+		if c := encodeColumn(utf16Units, line, test.b); c != test.c {
+			t.Errorf("encodeColumn(%d): expected %d, got %d", test.b, test.c, c)
+		}
+
+		if b := decodeColumn(utf16Units, line, test.c); b != test.b {
+			t.Errorf("decodeColumn(%d): expected %d, got %d", test.c, test.b, b)
+		}
+	}
+}
+
+func testServe(t *testing.T, l *lsp, body string) testResponse {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	l.w.Reset(out)
+
+	err := l.serve([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 1 {
+		t.Fatalf("expected 1 response, got: %d", len(rs))
+	}
+
+	if rs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", rs[0].Error.Message)
+	}
+
+	return rs[0]
+}
+
+func TestConvertPositions(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "file:///a.teal"
+	testOpen(l, uri, "#pragma version 8\nbyte \"é😀\"; int 1")
+
+	in := `{"params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":1,"character":13}}}`
+	if s := string(l.convertPositions([]byte(in), "", l.decoder())); !strings.Contains(s, `"character":16`) {
+		t.Errorf("unexpected input conversion: %s", s)
+	}
+
+	out := `{"result":[{"uri":"file:///a.teal","range":{"start":{"line":1,"character":15},"end":{"line":1,"character":18}}}]}`
+	if s := string(l.convertPositions([]byte(out), "", l.encoder())); !strings.Contains(s, `{"character":12,"line":1}`) || !strings.Contains(s, `{"character":15,"line":1}`) {
+		t.Errorf("unexpected output conversion: %s", s)
+	}
+
+	ascii := `{"result":{"start":{"line":0,"character":3}}}`
+	if s := string(l.convertPositions([]byte(ascii), uri, l.encoder())); s != ascii {
+		t.Errorf("expected unchanged message, got: %s", s)
+	}
+}
+
+func testSemanticTokens(t *testing.T, encodings string) []testToken {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServe(t, l, `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"capabilities":{"general":{"positionEncodings":`+encodings+`}}}}`)
+	testOpen(l, "file:///a.teal", "#pragma version 8\nbyte \"é😀\" // ü")
+
+	r := testServe(t, l, `{"jsonrpc":"2.0","id":1,"method":"textDocument/semanticTokens/full","params":{"textDocument":{"uri":"file:///a.teal"}}}`)
+
+	var st lspSemanticTokens
+	err = json.Unmarshal(r.Result, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ts []testToken
+	var line, start uint32
+	for i := 0; i+4 < len(st.Data); i += 5 {
+		if st.Data[i] > 0 {
+			start = 0
+		}
+		line += st.Data[i]
+		start += st.Data[i+1]
+		ts = append(ts, testToken{line, start, st.Data[i+2], st.Data[i+3]})
+	}
+
+	return ts
+}
+
+type testToken struct {
+	line, start, length, kind uint32
+}
+
+func TestSemanticTokensEncoding(t *testing.T) {
+	tests := []struct {
+		encodings string
+		want      []testToken
+	}{
+		{
+			encodings: `[]`,
+			want: []testToken{
+				{1, 5, 5, semanticTokenString},
+				{1, 11, 4, semanticTokenComment},
+			},
+		},
+		{
+			encodings: `["utf-32","utf-8"]`,
+			want: []testToken{
+				{1, 5, 8, semanticTokenString},
+				{1, 14, 5, semanticTokenComment},
+			},
+		},
+		{
+			encodings: `["utf-32"]`,
+			want: []testToken{
+				{1, 5, 4, semanticTokenString},
+				{1, 10, 4, semanticTokenComment},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := testSemanticTokens(t, test.encodings)
+		for _, w := range test.want {
+			found := false
+			for _, tok := range ts {
+				if tok == w {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("%s: token %+v not found in: %+v", test.encodings, w, ts)
+			}
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testServe(t, l, `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"capabilities":{"general":{"positionEncodings":["utf-16","utf-8"]}}}}`)
+
+	var res lspInitializeResult
+	err = json.Unmarshal(r.Result, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Capabilities.PositionEncoding != positionEncodingUtf8 {
+		t.Errorf("expected utf-8, got: %s", res.Capabilities.PositionEncoding)
+	}
+
+	if e := negotiateEncoding([]string{"utf-7"}); e != positionEncodingUtf16 {
+		t.Errorf("expected utf-16 fallback, got: %s", e)
+	}
+}
+
+func TestFormattingEol(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testOpen(l, "file:///a.teal", "#pragma version 8\rint 1\rreturn\r")
+
+	r := testServe(t, l, `{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///a.teal"}}}`)
+
+	var edits []lspTextEdit
+	err = json.Unmarshal(r.Result, &edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits) != 1 || strings.Contains(edits[0].NewText, "\n") || !strings.Contains(edits[0].NewText, "int 1\r") {
+		t.Errorf("expected CR line endings, got: %q", edits)
+	}
+}
+
+func TestCodeActionEol(t *testing.T) {
+	src := "#pragma version 8\r\nb missing\r\nint 1\r\n"
+
+	for _, test := range []struct {
+		eol  string
+		text string
+	}{
+		{eol: "", text: "\r\nmissing:\r\n"},
+		{eol: "lf", text: "\nmissing:\n"},
+	} {
+		l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l.config.Eol = test.eol
+		testOpen(l, "file:///a.teal", src)
+
+		r := testServe(t, l, `{"jsonrpc":"2.0","id":1,"method":"textDocument/codeAction","params":{"textDocument":{"uri":"file:///a.teal"},"range":{"start":{"line":1,"character":0},"end":{"line":1,"character":9}}}}`)
+
+		var cas []lspCodeAction
+		err = json.Unmarshal(r.Result, &cas)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		for _, ca := range cas {
+			if strings.HasPrefix(ca.Title, "Create label") {
+				found = true
+				if s := ca.Edit.DocumentChanges[0].Edits[0].NewText; s != test.text {
+					t.Errorf("eol %q: expected %q, got %q", test.eol, test.text, s)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("eol %q: create label action not found", test.eol)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"github.com/dragmz/teal"
+)
+
+func fixPosition(p teal.FixPosition) lspPosition {
+	return lspPosition{
+		Line:      p.Line,
+		Character: p.Character,
+	}
+}
+
+// codeAction returns the quick fix with the edits applied to the document using the eol line endings.
+func codeAction(uri string, fa teal.FixAction, eol string) lspCodeAction {
+	kind := "quickfix"
+
+	edits := []lspTextEdit{}
+	for _, e := range fa.Edits {
+		edits = append(edits, lspTextEdit{
+			Range: lspRange{
+				Start: fixPosition(e.Start),
+				End:   fixPosition(e.End),
+			},
+			NewText: teal.NormalizeEol(e.NewText, eol),
+		})
+	}
+
+	return lspCodeAction{
+		Title: fa.Title,
+		Kind:  &kind,
+		Edit: &lspWorkspaceEdit{
+			DocumentChanges: []lspTextDocumentEdit{
+				{
+					TextDocument: lspOptionalVersionedTextDocumentIdentifier{
+						Uri: uri,
+					},
+					Edits: edits,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,169 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dragmz/teal"
+)
+
+type lspWorkspaceFolder struct {
+	Uri  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type lspWorkspaceFoldersServerCapabilities struct {
+	Supported           bool `json:"supported"`
+	ChangeNotifications bool `json:"changeNotifications"`
+}
+
+type lspWorkspaceServerCapabilities struct {
+	WorkspaceFolders *lspWorkspaceFoldersServerCapabilities `json:"workspaceFolders,omitempty"`
+}
+
+type lspWorkspaceFoldersChangeEvent struct {
+	Added   []lspWorkspaceFolder `json:"added"`
+	Removed []lspWorkspaceFolder `json:"removed"`
+}
+
+type lspDidChangeWorkspaceFoldersParams struct {
+	Event lspWorkspaceFoldersChangeEvent `json:"event"`
+}
+
+// tealFolderOptions are the settings of a workspace folder, the paths are relative to the folder.
+type tealFolderOptions struct {
+	// version of the files without #pragma version
+	Version *uint64 `json:"version,omitempty"`
+
+	// dirs searched for the #include paths not found next to the including file
+	IncludePaths []string `json:"includePaths,omitempty"`
+
+	// JSON file of the named constants substituted for the op args
+	Constants *string `json:"constants,omitempty"`
+}
+
+// folder returns the innermost workspace folder of the document, the first folder for the documents without a file path,
+// e.g. the untitled ones.
+func (l *lsp) folder(uri string) (lspWorkspaceFolder, bool) {
+	path := uriToPath(uri)
+
+	var res lspWorkspaceFolder
+	best := -1
+
+	for _, f := range l.folders {
+		root := uriToPath(f.Uri)
+		if root == "" {
+			continue
+		}
+
+		if path == "" {
+			return f, true
+		}
+
+		if path != root && !strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			continue
+		}
+
+		if len(root) > best {
+			res = f
+			best = len(root)
+		}
+	}
+
+	return res, best >= 0
+}
+
+// folderOptions are the process options of the settings of the document folder.
+func (l *lsp) folderOptions(uri string) []teal.ProcessOption {
+	f, ok := l.folder(uri)
+	if !ok {
+		return nil
+	}
+
+	fo, ok := l.config.Folders[f.Name]
+	if !ok || f.Name == "" {
+		fo, ok = l.config.Folders[f.Uri]
+		if !ok {
+			return nil
+		}
+	}
+
+	root := uriToPath(f.Uri)
+
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(root, path)
+	}
+
+	var opts []teal.ProcessOption
+
+	if fo.Version != nil {
+		opts = append(opts, teal.WithVersion(*fo.Version))
+	}
+
+	if len(fo.IncludePaths) > 0 {
+		var dirs []string
+		for _, dir := range fo.IncludePaths {
+			dirs = append(dirs, abs(dir))
+		}
+
+		opts = append(opts, teal.WithIncludePaths(dirs...))
+	}
+
+	if fo.Constants != nil && *fo.Constants != "" {
+		cs, err := teal.ReadConstants(abs(*fo.Constants))
+		if err != nil {
+			l.log.Warn("failed to read the constants", "folder", f.Uri, "err", err)
+		} else {
+			opts = append(opts, teal.WithConstants(cs))
+		}
+	}
+
+	return opts
+}
+
+// docOptions are the process options of the document, the server options followed by the ones of its folder.
+func (l *lsp) docOptions(uri string) []teal.ProcessOption {
+	return append(append(l.processOptions(), l.virtualPath(uri)...), l.folderOptions(uri)...)
+}
+
+// virtualPath places the document without a file path in the root of its folder, so its relative #include paths
+// are resolved against the folder instead of the working directory of the server.
+func (l *lsp) virtualPath(uri string) []teal.ProcessOption {
+	if uriToPath(uri) != "" {
+		return nil
+	}
+
+	f, ok := l.folder(uri)
+	if !ok {
+		return nil
+	}
+
+	name := uriName(uri)
+	if name == "" {
+		name = "untitled"
+	}
+
+	return []teal.ProcessOption{teal.WithPath(filepath.Join(uriToPath(f.Uri), name))}
+}
+
+// changeFolders updates the workspace folders and processes the documents again with the settings of their folders.
+func (l *lsp) changeFolders(added []lspWorkspaceFolder, removed []lspWorkspaceFolder) error {
+	gone := map[string]bool{}
+	for _, f := range removed {
+		gone[f.Uri] = true
+	}
+
+	var folders []lspWorkspaceFolder
+	for _, f := range l.folders {
+		if !gone[f.Uri] {
+			folders = append(folders, f)
+		}
+	}
+
+	l.folders = append(folders, added...)
+
+	return l.reprocess()
+}
@@ -0,0 +1,574 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// lspIndexVersion is bumped when the format or the meaning of the entries change, the indexes of the other versions are dropped
+const lspIndexVersion = 1
+
+// lspIndexEntry is the indexed results of a file, the positions are the byte offsets.
+type lspIndexEntry struct {
+	// Hash is the key of the file contents and of the settings the file was processed with
+	Hash string `json:"hash"`
+
+	// Deps are the content hashes of the included files by the path
+	Deps map[string]string `json:"deps,omitempty"`
+
+	Symbols     []lspDocumentSymbol `json:"symbols"`
+	Lenses      []lspCodeLens       `json:"lenses,omitempty"`
+	Diagnostics []lspDiagnostic     `json:"diagnostics"`
+}
+
+type lspIndexData struct {
+	Version int                       `json:"version"`
+	Entries map[string]*lspIndexEntry `json:"entries"`
+}
+
+// lspIndex is the index of the teal files of the workspace persisted across the sessions, the entries of the unchanged files
+// are served right after the start while the changed files are processed again in the background.
+type lspIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*lspIndexEntry
+
+	// done is closed when the background re-validation finishes
+	done chan struct{}
+}
+
+// indexPath returns the path of the index file of the workspace folders in the dir.
+func indexPath(dir string, folders []lspWorkspaceFolder) string {
+	var uris []string
+	for _, f := range folders {
+		uris = append(uris, f.Uri)
+	}
+
+	sort.Strings(uris)
+
+	h := sha256.Sum256([]byte(strings.Join(uris, "\n")))
+
+	return filepath.Join(dir, hex.EncodeToString(h[:8])+".json")
+}
+
+// loadIndex reads the index file, the missing, the unreadable and the outdated ones are replaced by an empty index.
+func loadIndex(path string) *lspIndex {
+	x := &lspIndex{
+		path:    path,
+		entries: map[string]*lspIndexEntry{},
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return x
+	}
+
+	var data lspIndexData
+	if json.Unmarshal(bs, &data) != nil || data.Version != lspIndexVersion || data.Entries == nil {
+		return x
+	}
+
+	x.entries = data.Entries
+
+	return x
+}
+
+func (x *lspIndex) get(path string) *lspIndexEntry {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.entries[path]
+}
+
+func (x *lspIndex) put(path string, e *lspIndexEntry) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.entries[path] = e
+}
+
+// paths returns the indexed paths in order.
+func (x *lspIndex) paths() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return sortedKeys(x.entries)
+}
+
+// retain drops the entries of the files not in the paths, e.g. the deleted ones.
+func (x *lspIndex) retain(paths map[string]bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for path := range x.entries {
+		if !paths[path] {
+			delete(x.entries, path)
+		}
+	}
+}
+
+// save writes the index file, replacing the previous one at once.
+func (x *lspIndex) save() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	bs, err := json.Marshal(lspIndexData{
+		Version: lspIndexVersion,
+		Entries: x.entries,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal index")
+	}
+
+	err = os.MkdirAll(filepath.Dir(x.path), 0o755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create index dir")
+	}
+
+	tmp := x.path + ".tmp"
+
+	err = os.WriteFile(tmp, bs, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write index")
+	}
+
+	return errors.Wrap(os.Rename(tmp, x.path), "failed to replace index")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func contentHash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// indexTool identifies the build of the server, the results of the other builds may differ.
+func indexTool() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	tool := bi.Main.Version
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+			tool += " " + s.Value
+		}
+	}
+
+	return tool
+}
+
+// indexFingerprint identifies the settings the files of the folder are processed with.
+func (l *lsp) indexFingerprint(folder lspWorkspaceFolder) string {
+	bs, _ := json.Marshal(struct {
+		Version int
+		Tool    string
+		Folder  string
+
+		LensSize      bool
+		LensRefs      bool
+		LensLifecycle bool
+		ExtraPages    int
+		Mode          string
+
+		KnownAddresses    string
+		ExperimentalFixes bool
+		Silence           []string
+		Escalate          []string
+		Folders           map[string]tealFolderOptions
+	}{
+		Version: lspIndexVersion,
+		Tool:    indexTool(),
+		Folder:  folder.Uri,
+
+		LensSize:      l.config.LensSize,
+		LensRefs:      l.config.LensRefs,
+		LensLifecycle: l.config.LensLifecycle,
+		ExtraPages:    l.config.ExtraPages,
+		Mode:          l.config.Mode,
+
+		KnownAddresses:    l.config.KnownAddresses,
+		ExperimentalFixes: l.config.ExperimentalFixes,
+		Silence:           l.config.Silence,
+		Escalate:          l.config.Escalate,
+		Folders:           l.config.Folders,
+	})
+
+	return string(bs)
+}
+
+func indexKey(fingerprint string, text string) string {
+	return contentHash(fingerprint + "\x00" + text)
+}
+
+// newIndexEntry indexes the results of the file.
+func (l *lsp) newIndexEntry(key string, res *teal.ProcessResult, cfg tealConfig) *lspIndexEntry {
+	e := &lspIndexEntry{
+		Hash:        key,
+		Symbols:     documentSymbols(res),
+		Lenses:      codeLenses(res, cfg),
+		Diagnostics: l.toDiagnostics(res),
+	}
+
+	for _, inc := range res.Included() {
+		bs, err := l.readFile(inc.Path)
+		if err != nil {
+			continue
+		}
+
+		if e.Deps == nil {
+			e.Deps = map[string]string{}
+		}
+
+		e.Deps[inc.Path] = contentHash(string(bs))
+	}
+
+	return e
+}
+
+// fresh reports whether the entry is of the key and none of the included files changed.
+func (l *lsp) fresh(e *lspIndexEntry, key string) bool {
+	if e == nil || e.Hash != key {
+		return false
+	}
+
+	for path, hash := range e.Deps {
+		bs, err := l.readFile(path)
+		if err != nil || contentHash(string(bs)) != hash {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cachedEntry returns the fresh entry of the document unless its results are already computed.
+func (l *lsp) cachedEntry(uri string, snap *lspSnapshot) *lspIndexEntry {
+	if l.index == nil || snap.processed() != nil {
+		return nil
+	}
+
+	path := uriToPath(uri)
+	if path == "" {
+		return nil
+	}
+
+	f, _ := l.folder(uri)
+
+	e := l.index.get(path)
+	if !l.fresh(e, indexKey(l.indexFingerprint(f), snap.s)) {
+		return nil
+	}
+
+	return e
+}
+
+func (l *lsp) cachedLenses(uri string, snap *lspSnapshot) []lspCodeLens {
+	if e := l.cachedEntry(uri, snap); e != nil {
+		return append([]lspCodeLens{}, e.Lenses...)
+	}
+
+	return nil
+}
+
+func (l *lsp) cachedSymbols(uri string, snap *lspSnapshot) []lspDocumentSymbol {
+	if e := l.cachedEntry(uri, snap); e != nil {
+		return e.Symbols
+	}
+
+	return nil
+}
+
+// lspIndexFolder is a workspace folder with the settings its files are processed with.
+type lspIndexFolder struct {
+	root        string
+	fingerprint string
+	opts        []teal.ProcessOption
+}
+
+// indexWorkspace processes the teal files of the workspace folders changed since the index was saved in the background,
+// the index is saved when done.
+func (l *lsp) indexWorkspace() {
+	if l.index == nil {
+		return
+	}
+
+	var folders []lspIndexFolder
+
+	for _, f := range l.folders {
+		root := uriToPath(f.Uri)
+		if root == "" {
+			continue
+		}
+
+		folders = append(folders, lspIndexFolder{
+			root:        root,
+			fingerprint: l.indexFingerprint(f),
+			opts:        append(l.processOptions(), l.folderOptions(f.Uri)...),
+		})
+	}
+
+	cfg := l.config
+	x := l.index
+
+	// the runs of the previous settings finish first so their results do not replace the current ones
+	prev := x.done
+
+	done := make(chan struct{})
+	x.done = done
+
+	go func() {
+		defer close(done)
+
+		if prev != nil {
+			<-prev
+		}
+
+		seen := map[string]bool{}
+
+		for _, f := range folders {
+			_ = filepath.WalkDir(f.root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+
+				if d.IsDir() {
+					if path != f.root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+
+				if !strings.HasSuffix(d.Name(), ".teal") || seen[path] {
+					return nil
+				}
+
+				// the files of the nested folders are processed with their settings
+				inner := f
+				for _, o := range folders {
+					if len(o.root) > len(inner.root) && strings.HasPrefix(path, o.root+string(filepath.Separator)) {
+						inner = o
+					}
+				}
+
+				seen[path] = true
+
+				bs, err := os.ReadFile(path)
+				if err != nil {
+					return nil
+				}
+
+				key := indexKey(inner.fingerprint, string(bs))
+				if l.fresh(x.get(path), key) {
+					return nil
+				}
+
+				opts := append([]teal.ProcessOption{teal.WithPath(path), teal.WithReadFile(l.readFile)}, inner.opts...)
+				res := l.indexProcess(path, string(bs), opts...)
+				if res == nil {
+					return nil
+				}
+
+				x.put(path, l.newIndexEntry(key, res, cfg))
+
+				return nil
+			})
+		}
+
+		x.retain(seen)
+
+		err := x.save()
+		if err != nil {
+			l.log.Warn("failed to save the index", "err", err)
+		}
+	}()
+}
+
+// indexProcess processes the workspace file in the background, the files panicking the processing are logged and skipped
+// instead of stopping the server.
+func (l *lsp) indexProcess(path string, s string, opts ...teal.ProcessOption) (res *teal.ProcessResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			l.log.Error("indexing panic", "path", path, "panic", p, "stack", string(debug.Stack()))
+			res = nil
+		}
+	}()
+
+	return teal.Process(s, opts...)
+}
+
+// saveIndex updates the entries of the processed open documents and saves the index.
+func (l *lsp) saveIndex() {
+	if l.index == nil {
+		return
+	}
+
+	for uri, doc := range l.allDocs() {
+		path := uriToPath(uri)
+		if path == "" {
+			continue
+		}
+
+		f, ok := l.folder(uri)
+		if !ok {
+			continue
+		}
+
+		snap := doc.Snapshot()
+
+		res := snap.processed()
+		if res == nil {
+			continue
+		}
+
+		l.index.put(path, l.newIndexEntry(indexKey(l.indexFingerprint(f), snap.s), res, l.config))
+	}
+
+	err := l.index.save()
+	if err != nil {
+		l.log.Warn("failed to save the index", "err", err)
+	}
+}
+
+type lspWorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type lspSymbolInformation struct {
+	Name          string        `json:"name"`
+	Kind          lspSymbolKind `json:"kind"`
+	Location      lspLocation   `json:"location"`
+	ContainerName string        `json:"containerName,omitempty"`
+}
+
+// workspaceSymbols returns the symbols of the open documents and of the indexed files matching the query.
+func (l *lsp) workspaceSymbols(query string) []lspSymbolInformation {
+	res := []lspSymbolInformation{}
+
+	query = strings.ToLower(query)
+
+	add := func(uri string, syms []lspDocumentSymbol) {
+		for _, s := range syms {
+			if len(s.Children) > 0 {
+				for _, c := range s.Children {
+					if strings.Contains(strings.ToLower(c.Name), query) {
+						res = append(res, lspSymbolInformation{Name: c.Name, Kind: c.Kind, Location: lspLocation{Uri: uri, Range: c.SelectionRange}, ContainerName: s.Name})
+					}
+				}
+				continue
+			}
+
+			if strings.Contains(strings.ToLower(s.Name), query) {
+				res = append(res, lspSymbolInformation{Name: s.Name, Kind: s.Kind, Location: lspLocation{Uri: uri, Range: s.SelectionRange}})
+			}
+		}
+	}
+
+	docs := l.allDocs()
+
+	for _, uri := range sortedKeys(docs) {
+		add(uri, documentSymbols(docs[uri].Results()))
+	}
+
+	if l.index != nil {
+		for _, path := range l.index.paths() {
+			uri := pathToUri(path)
+			if _, ok := docs[uri]; ok {
+				continue
+			}
+
+			if e := l.index.get(path); e != nil {
+				add(uri, e.Symbols)
+			}
+		}
+	}
+
+	return res
+}
+
+type lspPreviousResultId struct {
+	Uri   string `json:"uri"`
+	Value string `json:"value"`
+}
+
+type lspWorkspaceDiagnosticParams struct {
+	PreviousResultIds []lspPreviousResultId `json:"previousResultIds"`
+}
+
+type lspWorkspaceDocumentDiagnosticReport struct {
+	Kind     string `json:"kind"`
+	Uri      string `json:"uri"`
+	Version  *int   `json:"version"`
+	ResultId string `json:"resultId,omitempty"`
+
+	// Items are the diagnostics of the full reports, nil for the unchanged ones
+	Items interface{} `json:"items,omitempty"`
+}
+
+type lspWorkspaceDiagnosticReport struct {
+	Items []lspWorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+// workspaceDiagnostics returns the indexed diagnostics of the files not open, the reports of the files
+// unchanged since the previous results are marked as such.
+func (l *lsp) workspaceDiagnostics(prev []lspPreviousResultId) lspWorkspaceDiagnosticReport {
+	res := lspWorkspaceDiagnosticReport{Items: []lspWorkspaceDocumentDiagnosticReport{}}
+
+	if l.index == nil {
+		return res
+	}
+
+	ids := map[string]string{}
+	for _, p := range prev {
+		ids[p.Uri] = p.Value
+	}
+
+	docs := l.allDocs()
+
+	for _, path := range l.index.paths() {
+		uri := pathToUri(path)
+		if _, ok := docs[uri]; ok {
+			continue
+		}
+
+		e := l.index.get(path)
+		if e == nil {
+			continue
+		}
+
+		if ids[uri] == e.Hash {
+			res.Items = append(res.Items, lspWorkspaceDocumentDiagnosticReport{Kind: "unchanged", Uri: uri, ResultId: e.Hash})
+			continue
+		}
+
+		items := e.Diagnostics
+		if items == nil {
+			items = []lspDiagnostic{}
+		}
+
+		res.Items = append(res.Items, lspWorkspaceDocumentDiagnosticReport{Kind: "full", Uri: uri, ResultId: e.Hash, Items: items})
+	}
+
+	return res
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dragmz/teal"
+	"github.com/dragmz/teal/bridge"
+)
+
+// debugState returns the state of an active debug session of the document, nil if there is none.
+// The sessions found with the bridge take precedence over the published state files.
+func (l *lsp) debugState(uri string) *teal.DebugState {
+	path := uriToPath(uri)
+	if path == "" {
+		return nil
+	}
+
+	s, err := bridge.State(path)
+	if err != nil {
+		l.log.Warn("failed to query debug sessions", "err", err)
+	}
+	if s != nil {
+		return s
+	}
+
+	s, err = teal.ReadDebugState(path)
+	if err != nil {
+		l.log.Warn("failed to read debug state", "err", err)
+		return nil
+	}
+
+	return s
+}
+
+func inlineValues(res *teal.ProcessResult, s *teal.DebugState, rg lspRange) []lspInlineValueText {
+	ls := []lspInlineValueText{}
+
+	if s == nil {
+		return ls
+	}
+
+	for _, v := range res.InlineValues(*s, rg) {
+		ls = append(ls, lspInlineValueText{
+			Range: lspRange{
+				Start: lspPosition{Line: v.Line, Character: v.Begin},
+				End:   lspPosition{Line: v.Line, Character: v.End},
+			},
+			Text: v.Text,
+		})
+	}
+
+	return ls
+}
+
+// debugHover describes the runtime state at the line paused in the debugger.
+func debugHover(s *teal.DebugState, line int) string {
+	if s == nil || s.Line != line {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("Debugger: paused at this line")
+
+	if len(s.Stack) > 0 {
+		sb.WriteString("\r\nStack (top first):")
+		for i := len(s.Stack) - 1; i >= 0; i-- {
+			sb.WriteString(fmt.Sprintf("\r\n%d: %s", i, s.Stack[i]))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package lsp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseLogLevel(s string) (LogLevel, error) {
+	for _, l := range []LogLevel{LogDebug, LogInfo, LogWarn, LogError} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+
+	return LogDebug, errors.Errorf("unknown log level: %s", s)
+}
+
+// logger writes the leveled logfmt lines, a nil logger discards them.
+type logger struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	level LogLevel
+
+	now func() time.Time
+}
+
+func newLogger(w io.Writer, level LogLevel) *logger {
+	return &logger{
+		w:     bufio.NewWriter(w),
+		level: level,
+		now:   time.Now,
+	}
+}
+
+func logValue(v interface{}) string {
+	var s string
+
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case time.Duration:
+		s = v.String()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// log writes the message with the key-value pairs.
+func (lg *logger) log(level LogLevel, msg string, kvs ...interface{}) {
+	if lg == nil || level < lg.level {
+		return
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("time=")
+	sb.WriteString(lg.now().UTC().Format(time.RFC3339Nano))
+	sb.WriteString(" level=")
+	sb.WriteString(level.String())
+	sb.WriteString(" msg=")
+	sb.WriteString(logValue(msg))
+
+	for i := 0; i+1 < len(kvs); i += 2 {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(kvs[i]))
+		sb.WriteString("=")
+		sb.WriteString(logValue(kvs[i+1]))
+	}
+
+	sb.WriteString("\n")
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	lg.w.WriteString(sb.String())
+	lg.w.Flush()
+}
+
+func (lg *logger) Debug(msg string, kvs ...interface{}) {
+	lg.log(LogDebug, msg, kvs...)
+}
+
+func (lg *logger) Info(msg string, kvs ...interface{}) {
+	lg.log(LogInfo, msg, kvs...)
+}
+
+func (lg *logger) Warn(msg string, kvs ...interface{}) {
+	lg.log(LogWarn, msg, kvs...)
+}
+
+func (lg *logger) Error(msg string, kvs ...interface{}) {
+	lg.log(LogError, msg, kvs...)
+}
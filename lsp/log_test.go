@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	lg := newLogger(out, LogInfo)
+	lg.now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	lg.Debug("skipped")
+	lg.Info("handled", "method", "textDocument/hover", "duration", 1500*time.Microsecond, "err", "a \"b\"")
+
+	exp := "time=2023-01-02T03:04:05Z level=info msg=handled method=textDocument/hover duration=1.5ms err=\"a \\\"b\\\"\"\n"
+	if out.String() != exp {
+		t.Errorf("expected: %s, got: %s", exp, out.String())
+	}
+
+	var nl *logger
+	nl.Error("discarded")
+}
+
+func TestParseLogLevel(t *testing.T) {
+	l, err := ParseLogLevel("warn")
+	if err != nil || l != LogWarn {
+		t.Errorf("unexpected level: %s, err: %v", l, err)
+	}
+
+	_, err = ParseLogLevel("verbose")
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	log := &bytes.Buffer{}
+
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{}, WithDebug(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testOpen(l, "file:///a.teal", "#pragma version 8\nint 1")
+
+	hover := `{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":1,"character":0}}}`
+	testServe(t, l, hover)
+	testServe(t, l, hover)
+
+	r := testServe(t, l, `{"jsonrpc":"2.0","id":2,"method":"workspace/executeCommand","params":{"command":"teal.server.stats","arguments":[]}}`)
+
+	var s string
+	err = json.Unmarshal(r.Result, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{
+		`teal_lsp_messages_total{method="textDocument/hover"} 2`,
+		"teal_lsp_cache_hits_total 1",
+		"teal_lsp_cache_misses_total 1",
+		"teal_lsp_documents 1",
+		"teal_lsp_documents_bytes 23",
+	} {
+		if !strings.Contains(s, exp) {
+			t.Errorf("missing %s in:\n%s", exp, s)
+		}
+	}
+
+	if !strings.Contains(log.String(), "level=debug msg=handled method=textDocument/hover") || !strings.Contains(log.String(), "cache=hit") {
+		t.Errorf("unexpected log:\n%s", log.String())
+	}
+}
@@ -0,0 +1,231 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// lsifVersion is the version of the LSIF spec of the dump.
+const lsifVersion = "0.4.3"
+
+// lsifWriter writes the LSIF vertices and edges as JSON lines, the first error stops the writing.
+type lsifWriter struct {
+	enc *json.Encoder
+	id  int
+	err error
+}
+
+func (w *lsifWriter) emit(kind string, label string, kvs map[string]interface{}) int {
+	w.id++
+
+	if w.err != nil {
+		return w.id
+	}
+
+	e := map[string]interface{}{
+		"id":    w.id,
+		"type":  kind,
+		"label": label,
+	}
+	for k, v := range kvs {
+		e[k] = v
+	}
+
+	w.err = w.enc.Encode(e)
+
+	return w.id
+}
+
+func (w *lsifWriter) vertex(label string, kvs map[string]interface{}) int {
+	return w.emit("vertex", label, kvs)
+}
+
+func (w *lsifWriter) edge(label string, out int, in int) {
+	w.emit("edge", label, map[string]interface{}{"outV": out, "inV": in})
+}
+
+func (w *lsifWriter) edges(label string, out int, in []int, kvs map[string]interface{}) {
+	if len(in) == 0 {
+		return
+	}
+
+	e := map[string]interface{}{"outV": out, "inVs": in}
+	for k, v := range kvs {
+		e[k] = v
+	}
+
+	w.emit("edge", label, e)
+}
+
+func (w *lsifWriter) event(kind string, scope string, data int) {
+	w.vertex("$event", map[string]interface{}{"kind": kind, "scope": scope, "data": data})
+}
+
+func lsifPosition(lines []string, line int, b int) map[string]interface{} {
+	ch := b
+	if line >= 0 && line < len(lines) {
+		ch = encodeColumn(utf16Units, lines[line], b)
+	}
+
+	return map[string]interface{}{"line": line, "character": ch}
+}
+
+func lsifHover(s string) map[string]interface{} {
+	return map[string]interface{}{
+		"result": map[string]interface{}{
+			"contents": lspMarkupContent{Kind: "plaintext", Value: s},
+		},
+	}
+}
+
+// document writes the ranges of the labels with their definitions, references and hovers
+// and the ranges of the ops with their docs.
+func (w *lsifWriter) document(path string, source string) int {
+	lines := textLines(source)
+	res := teal.Process(source, teal.WithPath(path))
+
+	doc := w.vertex("document", map[string]interface{}{
+		"uri":        pathToUri(path),
+		"languageId": "teal",
+	})
+	w.event("begin", "document", doc)
+
+	var ranges []int
+
+	rng := func(line int, b int, e int) int {
+		r := w.vertex("range", map[string]interface{}{
+			"start": lsifPosition(lines, line, b),
+			"end":   lsifPosition(lines, line, e),
+		})
+		ranges = append(ranges, r)
+		return r
+	}
+
+	type label struct {
+		set  int
+		defs []int
+		refs []int
+	}
+
+	labels := map[string]*label{}
+	var names []string
+
+	for _, sym := range res.Symbols {
+		l := labels[sym.Name()]
+		if l == nil {
+			l = &label{set: w.vertex("resultSet", nil)}
+			labels[sym.Name()] = l
+			names = append(names, sym.Name())
+
+			if h := sym.Docs(); h != "" {
+				w.edge("textDocument/hover", l.set, w.vertex("hoverResult", lsifHover(h)))
+			}
+		}
+
+		r := rng(sym.Line(), sym.Begin(), sym.End())
+		w.edge("next", r, l.set)
+		l.defs = append(l.defs, r)
+	}
+
+	for _, t := range res.SymbolRefs {
+		l := labels[t.String()]
+		if l == nil {
+			continue
+		}
+
+		r := rng(t.Line(), t.Begin(), t.End())
+		w.edge("next", r, l.set)
+		l.refs = append(l.refs, r)
+	}
+
+	for _, name := range names {
+		l := labels[name]
+
+		def := w.vertex("definitionResult", nil)
+		w.edge("textDocument/definition", l.set, def)
+		w.edges("item", def, l.defs, map[string]interface{}{"document": doc})
+
+		ref := w.vertex("referenceResult", nil)
+		w.edge("textDocument/references", l.set, ref)
+		w.edges("item", ref, l.defs, map[string]interface{}{"document": doc, "property": "definitions"})
+		w.edges("item", ref, l.refs, map[string]interface{}{"document": doc, "property": "references"})
+	}
+
+	// the ops of the same name share the result set with the hover
+	ops := map[string]int{}
+
+	for _, t := range res.Ops {
+		set, ok := ops[t.String()]
+		if !ok {
+			h := res.DocAt(t.Line(), t.Begin())
+			if h == "" {
+				continue
+			}
+
+			set = w.vertex("resultSet", nil)
+			w.edge("textDocument/hover", set, w.vertex("hoverResult", lsifHover(h)))
+			ops[t.String()] = set
+		}
+
+		r := rng(t.Line(), t.Begin(), t.End())
+		w.edge("next", r, set)
+	}
+
+	w.edges("contains", doc, ranges, nil)
+	w.event("end", "document", doc)
+
+	return doc
+}
+
+// WriteLsif writes the LSIF dump of the teal files with the definitions and the references of the labels
+// and the hovers of the labels and the ops, for the code browsers serving the navigation without the server.
+func WriteLsif(w io.Writer, root string, paths []string) error {
+	lw := &lsifWriter{enc: json.NewEncoder(w)}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve project root")
+	}
+
+	lw.vertex("metaData", map[string]interface{}{
+		"version":          lsifVersion,
+		"projectRoot":      pathToUri(abs),
+		"positionEncoding": positionEncodingUtf16,
+		"toolInfo":         map[string]interface{}{"name": "tealsp"},
+	})
+
+	project := lw.vertex("project", map[string]interface{}{"kind": "teal"})
+	lw.event("begin", "project", project)
+
+	paths = append([]string{}, paths...)
+	sort.Strings(paths)
+
+	var docs []int
+	for _, path := range paths {
+		bs, err := teal.ReadSource(path)
+		if err != nil {
+			return err
+		}
+
+		p, err := filepath.Abs(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to resolve source path")
+		}
+
+		docs = append(docs, lw.document(p, string(bs)))
+	}
+
+	lw.edges("contains", project, docs, nil)
+	lw.event("end", "project", project)
+
+	if lw.err != nil {
+		return errors.Wrap(lw.err, "failed to write lsif")
+	}
+
+	return nil
+}
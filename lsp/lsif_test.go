@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteLsif(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.teal")
+
+	err := os.WriteFile(path, []byte("#pragma version 8\nb end\nend:\nint 1\nreturn"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = WriteLsif(&buf, dir, []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var elems []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid line: %s", line)
+		}
+		elems = append(elems, e)
+	}
+
+	if elems[0]["label"] != "metaData" || elems[1]["label"] != "project" {
+		t.Fatalf("unexpected header: %v", elems[:2])
+	}
+
+	counts := map[string]int{}
+	byId := map[float64]map[string]interface{}{}
+	for i, e := range elems {
+		if e["id"] != float64(i+1) {
+			t.Errorf("unexpected id: %v", e["id"])
+		}
+		counts[e["label"].(string)]++
+		byId[e["id"].(float64)] = e
+	}
+
+	if counts["document"] != 1 || counts["definitionResult"] != 1 || counts["referenceResult"] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	// b and return ops with the docs, the label definition and its reference
+	if counts["range"] != 4 {
+		t.Errorf("unexpected ranges: %d", counts["range"])
+	}
+
+	var props []string
+	for _, e := range elems {
+		if e["label"] == "item" {
+			if p, ok := e["property"]; ok {
+				props = append(props, p.(string))
+			}
+		}
+	}
+
+	if strings.Join(props, ",") != "definitions,references" {
+		t.Errorf("unexpected reference items: %v", props)
+	}
+
+	hovers := 0
+	for _, e := range elems {
+		if e["label"] == "hoverResult" {
+			hovers++
+			contents := e["result"].(map[string]interface{})["contents"].(map[string]interface{})
+			if contents["value"] == "" {
+				t.Error("empty hover")
+			}
+		}
+	}
+
+	if hovers != 2 {
+		t.Errorf("unexpected hovers: %d", hovers)
+	}
+
+	for _, e := range elems {
+		if e["label"] == "range" {
+			start := e["start"].(map[string]interface{})
+			if start["line"] == float64(2) && start["character"] != float64(0) {
+				t.Errorf("unexpected label range: %v", e)
+			}
+		}
+	}
+}
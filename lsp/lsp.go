@@ -2,13 +2,17 @@ package lsp
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/textproto"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/dragmz/teal"
 	"github.com/joe-p/tealfmt"
@@ -35,22 +39,233 @@ const (
 	semanticTokenFunction = 8
 )
 
+// lspSnapshot is an immutable version of the document text with its lazily computed results,
+// safe to be used concurrently while the document is being changed.
+type lspSnapshot struct {
+	s string
+
+	once sync.Once
+	res  atomic.Pointer[teal.ProcessResult]
+
+	path string
+	read func(path string) ([]byte, error)
+	opts []teal.ProcessOption
+
+	// done is called with the results once they are computed, optional
+	done func(res *teal.ProcessResult)
+
+	// deps are the paths read by the processing so far, recorded before they are read so the snapshot being processed
+	// is invalidated by the changes of the files it is about to read too
+	depsMu sync.Mutex
+	deps   map[string]bool
+}
+
+// readDep records the path as a dependency of the snapshot and reads it.
+func (s *lspSnapshot) readDep(path string) ([]byte, error) {
+	s.depsMu.Lock()
+	if s.deps == nil {
+		s.deps = map[string]bool{}
+	}
+	s.deps[path] = true
+	s.depsMu.Unlock()
+
+	if s.read == nil {
+		return os.ReadFile(path)
+	}
+
+	return s.read(path)
+}
+
+// depends reports whether the processing of the snapshot has read the file.
+func (s *lspSnapshot) depends(path string) bool {
+	s.depsMu.Lock()
+	defer s.depsMu.Unlock()
+
+	return s.deps[path]
+}
+
+func (s *lspSnapshot) Results() *teal.ProcessResult {
+	s.once.Do(func() {
+		opts := append([]teal.ProcessOption{teal.WithPath(s.path), teal.WithReadFile(s.readDep)}, s.opts...)
+		res := teal.Process(s.s, opts...)
+		if s.done != nil {
+			s.done(res)
+		}
+
+		s.res.Store(res)
+	})
+
+	return s.res.Load()
+}
+
+// processed returns the results if they are already computed, nil otherwise.
+func (s *lspSnapshot) processed() *teal.ProcessResult {
+	return s.res.Load()
+}
+
+// lspDoc is an open document, the changes replace its snapshot instead of modifying it.
 type lspDoc struct {
-	s   string
-	res *teal.ProcessResult
+	mu   sync.Mutex
+	snap *lspSnapshot
+
+	path string
+	read func(path string) ([]byte, error)
+	opts []teal.ProcessOption
+
+	// done is called with the results of each snapshot once they are computed, optional
+	done func(res *teal.ProcessResult)
+}
+
+func newLspDoc(path string, read func(path string) ([]byte, error), opts ...teal.ProcessOption) *lspDoc {
+	d := &lspDoc{
+		path: path,
+		read: read,
+		opts: opts,
+	}
+	d.Update("")
+
+	return d
 }
 
 func (d *lspDoc) Update(s string) {
-	d.s = s
-	d.res = nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.snap = &lspSnapshot{
+		s:    s,
+		path: d.path,
+		read: d.read,
+		opts: d.opts,
+		done: d.done,
+	}
+}
+
+// Snapshot returns the current version of the document.
+func (d *lspDoc) Snapshot() *lspSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.snap
+}
+
+// Text returns the current text of the document.
+func (d *lspDoc) Text() string {
+	return d.Snapshot().s
 }
 
 func (d *lspDoc) Results() *teal.ProcessResult {
-	if d.res == nil {
-		d.res = teal.Process(d.s)
+	return d.Snapshot().Results()
+}
+
+// invalidate replaces the snapshot with the one of the same text to compute the results again.
+func (d *lspDoc) invalidate() {
+	d.Update(d.Text())
+}
+
+// reconfigure replaces the process options and computes the results again.
+func (d *lspDoc) reconfigure(opts []teal.ProcessOption) {
+	d.mu.Lock()
+	d.opts = opts
+	d.mu.Unlock()
+
+	d.invalidate()
+}
+
+// Includes reports whether the results of the doc directly or transitively depend on the file, including the results
+// still being computed. The snapshots not processed yet read the current files when they are.
+func (d *lspDoc) Includes(path string) bool {
+	return d.Snapshot().depends(path)
+}
+
+// readFile reads included files preferring the contents of open documents.
+func (l *lsp) readFile(path string) ([]byte, error) {
+	for uri, doc := range l.allDocs() {
+		if uriToPath(uri) == path {
+			return []byte(doc.Text()), nil
+		}
+	}
+
+	return os.ReadFile(path)
+}
+
+// doc returns the open document, nil if not open.
+func (l *lsp) doc(uri string) *lspDoc {
+	l.docsMu.RLock()
+	defer l.docsMu.RUnlock()
+
+	return l.docs[uri]
+}
+
+// processOptions are the options of the configured server the documents are processed with.
+func (l *lsp) processOptions() []teal.ProcessOption {
+	opts := append([]teal.ProcessOption{}, l.opts...)
+
+	if len(l.addresses) > 0 {
+		opts = append(opts, teal.WithKnownAddresses(l.addresses...))
+	}
+
+	if l.config.ExperimentalFixes {
+		opts = append(opts, teal.WithExperimentalFixes())
+	}
+
+	if len(l.config.Silence) > 0 {
+		opts = append(opts, teal.WithSilence(l.config.Silence...))
+	}
+
+	if len(l.config.Escalate) > 0 {
+		opts = append(opts, teal.WithEscalate(l.config.Escalate...))
+	}
+
+	return opts
+}
+
+// open returns the document, adding it if not open yet.
+func (l *lsp) open(uri string) *lspDoc {
+	l.docsMu.Lock()
+	defer l.docsMu.Unlock()
+
+	doc := l.docs[uri]
+	if doc == nil {
+		doc = newLspDoc(uriToPath(uri), l.readFile, l.docOptions(uri)...)
+		doc.done = l.stats.diagnostics
+		l.docs[uri] = doc
+	}
+
+	return doc
+}
+
+func (l *lsp) close(uri string) {
+	l.docsMu.Lock()
+	defer l.docsMu.Unlock()
+
+	delete(l.docs, uri)
+}
+
+// allDocs returns a copy of the open documents safe to iterate while the documents are opened and closed.
+func (l *lsp) allDocs() map[string]*lspDoc {
+	l.docsMu.RLock()
+	defer l.docsMu.RUnlock()
+
+	docs := make(map[string]*lspDoc, len(l.docs))
+	for uri, doc := range l.docs {
+		docs[uri] = doc
+	}
+
+	return docs
+}
+
+// invalidate drops cached results of the docs that include the changed doc.
+func (l *lsp) invalidate(uri string) {
+	path := uriToPath(uri)
+	if path == "" {
+		return
 	}
 
-	return d.res
+	for _, doc := range l.allDocs() {
+		if doc.Includes(path) {
+			doc.invalidate()
+		}
+	}
 }
 
 type lsp struct {
@@ -58,7 +273,9 @@ type lsp struct {
 
 	config tealConfig
 
-	docs     map[string]*lspDoc
+	docsMu sync.RWMutex
+	docs   map[string]*lspDoc
+
 	shutdown bool
 
 	exit     bool
@@ -67,14 +284,85 @@ type lsp struct {
 	tp *textproto.Reader
 	w  *bufio.Writer
 
-	debug *bufio.Writer
+	// leveled log of the handled messages, nil if disabled
+	log *logger
+
+	stats *serverStats
+
+	// in-flight requests keyed by requestKey
+	reqsMu sync.Mutex
+	reqs   map[string]context.CancelFunc
+
+	// context of the request being served, nil for notifications
+	ctx context.Context
+
+	// document of the message being served, used for the positions of the replies
+	uri string
+
+	// position encoding negotiated with the client
+	encoding string
+
+	// strict rejects the unknown methods and the messages other than JSON-RPC 2.0
+	strict bool
+
+	// on-chain state of the configured app, nil if not configured
+	chain *chainState
+
+	// known addresses read from the configured file
+	addresses []teal.KnownAddress
+
+	// options of the embedding program the documents are processed with, e.g. the analyzers
+	opts []teal.ProcessOption
+
+	// refresh requests supported by the client
+	refresh lspRefresh
+
+	// folders of the workspace, the documents are processed with the settings of the folder they belong to
+	folders []lspWorkspaceFolder
+
+	// dir of the persisted indexes, empty if disabled
+	indexDir string
+
+	// index of the workspace files, nil if disabled or without the workspace folders
+	index *lspIndex
 }
 
 type LspOption func(l *lsp) error
 
+// WithDebug logs everything including the message bodies to the writer.
 func WithDebug(w io.Writer) LspOption {
+	return WithLog(w, LogDebug)
+}
+
+// WithLog logs the messages of the level and above to the writer.
+func WithLog(w io.Writer, level LogLevel) LspOption {
+	return func(l *lsp) error {
+		l.log = newLogger(w, level)
+		return nil
+	}
+}
+
+// WithStrict makes the server reject the unknown methods with the method not found error.
+func WithStrict() LspOption {
+	return func(l *lsp) error {
+		l.strict = true
+		return nil
+	}
+}
+
+// WithIndex persists the index of the symbols, the code lenses and the diagnostics of the workspace files in the dir
+// to serve them right after the start while the changed files are processed again in the background.
+func WithIndex(dir string) LspOption {
+	return func(l *lsp) error {
+		l.indexDir = dir
+		return nil
+	}
+}
+
+// WithProcessOptions processes the documents with the options, e.g. teal.WithAnalyzer to publish the diagnostics of custom checks.
+func WithProcessOptions(opts ...teal.ProcessOption) LspOption {
 	return func(l *lsp) error {
-		l.debug = bufio.NewWriter(w)
+		l.opts = append(l.opts, opts...)
 		return nil
 	}
 }
@@ -84,11 +372,25 @@ func New(r io.Reader, w io.Writer, opts ...LspOption) (*lsp, error) {
 		tp:   textproto.NewReader(bufio.NewReader(r)),
 		w:    bufio.NewWriter(w),
 		docs: map[string]*lspDoc{},
+		reqs: map[string]context.CancelFunc{},
+
+		stats: newServerStats(),
+
+		encoding: positionEncodingUtf16,
+
 		config: tealConfig{
 			SemanticTokens: true,
 			InlayNamed:     true,
 			InlayDecoded:   true,
+			InlayAddress:   true,
+			InlayAbi:       true,
 			LensRefs:       true,
+			LensLifecycle:  true,
+			LensSize:       true,
+			LensSource:     true,
+			FormatWidth:    80,
+
+			FormatCommentColumn: 40,
 		},
 	}
 
@@ -128,8 +430,9 @@ type jsonRpcResponse struct {
 }
 
 type lspFullDocumentDiagnosticReport struct {
-	Kind  string          `json:"kind"`
-	Items []lspDiagnostic `json:"items"`
+	Kind             string                                     `json:"kind"`
+	Items            []lspDiagnostic                            `json:"items"`
+	RelatedDocuments map[string]lspFullDocumentDiagnosticReport `json:"relatedDocuments,omitempty"`
 }
 
 type lspRequest[T any] struct {
@@ -172,6 +475,7 @@ type lspCompletionItem struct {
 	CommitCharacters    []string      `json:"commitCharacters,omitempty"`
 	Command             *lspCommand   `json:"command,omitempty"`
 	Data                interface{}   `json:"data,omitempty"`
+	Tags                []int         `json:"tags,omitempty"`
 }
 
 type lspCompletionList struct {
@@ -221,7 +525,8 @@ type lspSignatureHelpOptions struct {
 }
 
 type lspParameterInformation struct {
-	Label string `json:"label"`
+	// string | [uinteger, uinteger]
+	Label interface{} `json:"label"`
 
 	// string | [uinteger, uinteger]
 	Documentation interface{} `json:"documentation,omitempty"`
@@ -250,15 +555,24 @@ type lspServerCapabilities struct {
 	ExecuteCommandProvider     *lspExecuteCommandProvider `json:"executeCommandProvider,omitempty"`
 	RenameProvider             *lspRenameOptions          `json:"renameProvider,omitempty"`
 	ColorProvider              *bool                      `json:"colorProvider,omitempty"`
+	SelectionRangeProvider     *bool                      `json:"selectionRangeProvider,omitempty"`
 	DocumentHighlightProvider  *bool                      `json:"documentHighlightProvider,omitempty"`
 	SemanticTokensProvider     *lspSemanticTokensProvider `json:"semanticTokensProvider,omitempty"`
 	DocumentFormattingProvider *bool                      `json:"documentFormattingProvider,omitempty"`
+	ReferencesProvider         *bool                      `json:"referencesProvider,omitempty"`
 	DefinitionProvider         *bool                      `json:"definitionProvider,omitempty"`
 	HoverProvider              *bool                      `json:"hoverProvider,omitempty"`
 	SignatureHelpProvider      *lspSignatureHelpOptions   `json:"signatureHelpProvider,omitempty"`
 	InlayHintProvider          *bool                      `json:"inlayHintProvider,omitempty"`
+	WorkspaceSymbolProvider    *bool                      `json:"workspaceSymbolProvider,omitempty"`
 	InlineValueProvider        *bool                      `json:"inlineValueProvider,omitempty"`
 	CodeLensProvider           *lspCodeLensProvider       `json:"codeLensProvider,omitempty"`
+
+	DocumentOnTypeFormattingProvider *lspDocumentOnTypeFormattingOptions `json:"documentOnTypeFormattingProvider,omitempty"`
+
+	Workspace *lspWorkspaceServerCapabilities `json:"workspace,omitempty"`
+
+	PositionEncoding string `json:"positionEncoding,omitempty"`
 }
 
 type lspInitializeResult struct {
@@ -268,15 +582,19 @@ type lspInitializeResult struct {
 type lspSymbolKind int
 
 const (
-	lspSymbolKindMethod   = 6
-	lspSymbolKindOperator = 25
+	lspSymbolKindNamespace = 3
+	lspSymbolKindMethod    = 6
+	lspSymbolKindKey       = 20
+	lspSymbolKindOperator  = 25
 )
 
 type lspDocumentSymbol struct {
-	Name           string        `json:"name"`
-	Kind           lspSymbolKind `json:"kind"`
-	Range          lspRange      `json:"range"`
-	SelectionRange lspRange      `json:"selectionRange"`
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           lspSymbolKind       `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
 }
 
 type lspInitializeClientInfo struct {
@@ -288,20 +606,126 @@ type tealInitializationOptions struct {
 	SemanticTokens *bool `json:"semanticTokens,omitempty"`
 	InlayNamed     *bool `json:"inlayNamed,omitempty"`
 	InlayDecoded   *bool `json:"inlayDecoded,omitempty"`
+	InlayAddress   *bool `json:"inlayAddress,omitempty"`
 	LensRefs       *bool `json:"lensRefs,omitempty"`
+	LensLifecycle  *bool `json:"lensLifecycle,omitempty"`
+	LensSize       *bool `json:"lensSize,omitempty"`
+
+	// decoded values of the byte literals that look ABI encoded, e.g. (uint64 1000000, address 7ZUEC…)
+	InlayAbi *bool `json:"inlayAbi,omitempty"`
+
+	// lenses with the originating lines of the generated programs read from the <file>.map source maps
+	LensSource *bool `json:"lensSource,omitempty"`
+
+	// end of line inlay hints with the stack depth before the instruction, disabled by default
+	InlayStackDepth *bool `json:"inlayStackDepth,omitempty"`
+
+	// number of the extra program pages and the "app" or "sig" mode used by the size lens,
+	// the mode defaults to the program mode
+	ExtraPages *int    `json:"extraPages,omitempty"`
+	Mode       *string `json:"mode,omitempty"`
+
+	// "split" or "join" ';' separated instructions when formatting
+	FormatSublines *string `json:"formatSublines,omitempty"`
+	FormatWidth    *int    `json:"formatWidth,omitempty"`
+
+	// column of the trailing comments aligned when typing a new line, 0 to disable
+	FormatCommentColumn *int `json:"formatCommentColumn,omitempty"`
+
+	// algod endpoint and the app id used to show the on-chain values of the global keys on hover
+	Algod      *string `json:"algod,omitempty"`
+	AlgodToken *string `json:"algodToken,omitempty"`
+	AppId      *uint64 `json:"appId,omitempty"`
+
+	// "lf", "crlf" or "cr" line endings of the generated edits and the formatted documents,
+	// the dominant line ending of the document is kept by default
+	Eol *string `json:"eol,omitempty"`
+
+	// path to a JSON file of the known addresses added to the built-in ones
+	KnownAddresses *string `json:"knownAddresses,omitempty"`
+
+	// experimental code actions like the gtxns loop rewrite, disabled by default
+	ExperimentalFixes *bool `json:"experimentalFixes,omitempty"`
+
+	// aggregate usage counters reported by the teal.server.usage command, disabled by default
+	Usage *bool `json:"usage,omitempty"`
+
+	// ids of the rules whose diagnostics are dropped or reported as errors, e.g. ["LINT0005"]
+	Silence  []string `json:"silence,omitempty"`
+	Escalate []string `json:"escalate,omitempty"`
+
+	// settings of the workspace folders by the folder name or uri
+	Folders map[string]tealFolderOptions `json:"folders,omitempty"`
 }
 
 type tealConfig struct {
 	SemanticTokens bool
 	InlayNamed     bool
 	InlayDecoded   bool
+	InlayAddress   bool
+	InlayAbi       bool
 	LensRefs       bool
+	LensLifecycle  bool
+	LensSize       bool
+	LensSource     bool
+
+	InlayStackDepth bool
+
+	ExtraPages int
+	Mode       string
+
+	FormatSublines string
+	FormatWidth    int
+
+	FormatCommentColumn int
+
+	Algod      string
+	AlgodToken string
+	AppId      uint64
+
+	Eol string
+
+	KnownAddresses string
+
+	ExperimentalFixes bool
+
+	Usage bool
+
+	Silence  []string
+	Escalate []string
+
+	Folders map[string]tealFolderOptions
+}
+
+type lspGeneralClientCapabilities struct {
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
+}
+
+type lspRefreshClientCapabilities struct {
+	RefreshSupport bool `json:"refreshSupport,omitempty"`
+}
+
+type lspWorkspaceClientCapabilities struct {
+	SemanticTokens *lspRefreshClientCapabilities `json:"semanticTokens,omitempty"`
+	InlayHint      *lspRefreshClientCapabilities `json:"inlayHint,omitempty"`
+	CodeLens       *lspRefreshClientCapabilities `json:"codeLens,omitempty"`
+	Diagnostics    *lspRefreshClientCapabilities `json:"diagnostics,omitempty"`
+}
+
+type lspClientCapabilities struct {
+	General   *lspGeneralClientCapabilities   `json:"general,omitempty"`
+	Workspace *lspWorkspaceClientCapabilities `json:"workspace,omitempty"`
 }
 
 type lspInitializeRequestParams struct {
 	ProcessId             int                        `json:"id"`
 	ClientInfo            *lspInitializeClientInfo   `json:"clientInfo"`
+	Capabilities          *lspClientCapabilities     `json:"capabilities,omitempty"`
 	InitializationOptions *tealInitializationOptions `json:"initializationOptions,omitempty"`
+
+	// folders of a multi-root workspace, the deprecated rootUri is used if not set
+	WorkspaceFolders []lspWorkspaceFolder `json:"workspaceFolders,omitempty"`
+	RootUri          *string              `json:"rootUri,omitempty"`
 }
 
 type lspDidOpenTextDocument struct {
@@ -365,6 +789,18 @@ type tealRemoveLabelCommandArgs struct {
 	Name string `json:"name"`
 }
 
+type tealTestsCommandArgs struct {
+	Uri string `json:"uri"`
+
+	// Ids limits the run to the entries, all of the entries are run if empty
+	Ids []string `json:"ids,omitempty"`
+}
+
+type tealTestResultsParams struct {
+	Uri     string            `json:"uri"`
+	Results []teal.TestResult `json:"results"`
+}
+
 type tealRemoveLineCommandArgs struct {
 	Uri  string `json:"uri"`
 	Line int    `json:"line"`
@@ -430,10 +866,10 @@ type lspDocumentColorRequestTextDocument struct {
 }
 
 type lspColor struct {
-	R float32 `json:"r"`
-	G float32 `json:"g"`
-	B float32 `json:"b"`
-	A float32 `json:"a"`
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+	Alpha float64 `json:"alpha"`
 }
 
 type lspColorInformation struct {
@@ -441,10 +877,42 @@ type lspColorInformation struct {
 	Color lspColor `json:"color"`
 }
 
+type lspDocumentOnTypeFormattingRequestParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+	Ch           string                    `json:"ch"`
+}
+
+type lspDocumentOnTypeFormattingOptions struct {
+	FirstTriggerCharacter string   `json:"firstTriggerCharacter"`
+	MoreTriggerCharacter  []string `json:"moreTriggerCharacter,omitempty"`
+}
+
+type lspSelectionRangeRequestParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Positions    []lspPosition             `json:"positions"`
+}
+
+type lspSelectionRange struct {
+	Range  lspRange           `json:"range"`
+	Parent *lspSelectionRange `json:"parent,omitempty"`
+}
+
 type lspDocumentColorRequestParams struct {
 	TextDocument lspDocumentColorRequestTextDocument `json:"textDocument"`
 }
 
+type lspColorPresentation struct {
+	Label    string       `json:"label"`
+	TextEdit *lspTextEdit `json:"textEdit,omitempty"`
+}
+
+type lspColorPresentationRequestParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Color        lspColor                  `json:"color"`
+	Range        lspRange                  `json:"range"`
+}
+
 type lspPrepareRenameResponse struct {
 	Range       lspRange `json:"range"`
 	Placeholder string   `json:"placeholder"`
@@ -602,6 +1070,16 @@ type lspDefinitionRequestParams struct {
 	Position     lspPosition               `json:"position"`
 }
 
+type lspReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type lspReferencesRequestParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+	Context      lspReferenceContext       `json:"context"`
+}
+
 type lspLocation struct {
 	Uri   string   `json:"uri"`
 	Range lspRange `json:"range"`
@@ -664,21 +1142,29 @@ type lspCodeLens struct {
 type lspDidChange lspRequest[*lspDidChangeParams]
 type lspDidOpen lspRequest[*lspDidOpenParams]
 type lspDidSave lspRequest[*lspDidSaveParams]
+type lspDidChangeConfiguration lspRequest[*lspDidChangeConfigurationParams]
+type lspDidChangeWorkspaceFolders lspRequest[*lspDidChangeWorkspaceFoldersParams]
 
 // requests
 type lspDocumentSymbolRequest lspRequest[*lspDocumentSymbolParams]
 type lspWorkspaceExecuteCommand lspRequest[*lspWorkspaceExecuteCommandHeader]
 type lspDiagnosticRequest lspRequest[*lspDiagnosticRequestParams]
+type lspWorkspaceSymbolRequest lspRequest[lspWorkspaceSymbolParams]
+type lspWorkspaceDiagnosticRequest lspRequest[lspWorkspaceDiagnosticParams]
 type lspCodeActionRequest lspRequest[*lspCodeActionRequestParams]
 type lspRenameRequest lspRequest[*lspRenameRequestParams]
 type lspPrepareRenameRequest lspRequest[*lspPrepareRenameRequestParams]
 type lspDocumentColorRequest lspRequest[*lspDocumentColorRequestParams]
+type lspSelectionRangeRequest lspRequest[*lspSelectionRangeRequestParams]
+type lspDocumentOnTypeFormattingRequest lspRequest[*lspDocumentOnTypeFormattingRequestParams]
+type lspColorPresentationRequest lspRequest[*lspColorPresentationRequestParams]
 type lspDidCloseRequest lspRequest[*lspDidCloseRequestParams]
 type lspDocumentHighlightRequest lspRequest[*lspDocumentHighlightRequestParams]
 type lspSemanticTokensFullRequest lspRequest[*lspSemanticTokensFullRequestParams]
 type lspCompletionRequest lspRequest[*lspCompletionRequestParams]
 type lspDocumentFormattingRequest lspRequest[*lspDocumentFormattingRequestParams]
 type lspDefinitionRequest lspRequest[*lspDefinitionRequestParams]
+type lspReferencesRequest lspRequest[*lspReferencesRequestParams]
 type lspHoverRequest lspRequest[*lspHoverRequestParams]
 type lspSignatureHelpRequest lspRequest[*lspSignatureHelpRequestParams]
 type lspInlayHintRequest lspRequest[*lspInlayHintRequestParams]
@@ -686,72 +1172,362 @@ type lspInlineValueRequest lspRequest[*lspInlineValueRequestParams]
 type lspInitializeRequest lspRequest[*lspInitializeRequestParams]
 type lspCodeLensRequest lspRequest[*lspCodeLensRequestParams]
 
-func readInto(b []byte, v interface{}) error {
-	err := json.Unmarshal(b, &v)
-	if err != nil {
-		return err
+func lineRange(res *teal.ProcessResult, line int) lspRange {
+	r := lspRange{
+		Start: lspPosition{
+			Line: line,
+		},
+		End: lspPosition{
+			Line: line,
+		},
 	}
 
-	return nil
-}
+	if line < len(res.Lines) {
+		r.Start.Character = res.Lines[line].Begin()
+		r.End.Character = res.Lines[line].End()
+	}
 
-func (l *lsp) reply(id interface{}, result interface{}, err interface{}) error {
-	return l.write(jsonRpcResponse{
-		JsonRpc: "2.0",
-		Id:      id,
-		Result:  result,
-		Error:   err,
-	})
+	return r
 }
 
-func (l *lsp) fail(id interface{}, err interface{}) error {
-	return l.reply(id, nil, err)
-}
+// keysSymbol groups the inferred state or box keys under a named symbol.
+func keysSymbol(res *teal.ProcessResult, name string, keys []teal.StateKey) (lspDocumentSymbol, bool) {
+	if len(keys) == 0 {
+		return lspDocumentSymbol{}, false
+	}
 
-func (l *lsp) success(id interface{}, result interface{}) error {
-	return l.reply(id, result, nil)
-}
+	group := lspDocumentSymbol{
+		Name: name,
+		Kind: lspSymbolKindNamespace,
+	}
 
-func read[T any](b []byte) (T, error) {
-	var v T
+	first := -1
+	last := -1
 
-	err := readInto(b, &v)
-	if err != nil {
-		return v, err
+	for _, k := range keys {
+		var access []string
+		if k.Read {
+			access = append(access, "read")
+		}
+		if k.Write {
+			access = append(access, "write")
+		}
+		if k.Delete {
+			access = append(access, "delete")
+		}
+
+		detail := k.Scope
+		if k.Type != "" {
+			detail += " " + k.Type
+		}
+		detail += " " + strings.Join(access, "/")
+
+		r := lineRange(res, k.Lines[0])
+
+		group.Children = append(group.Children, lspDocumentSymbol{
+			Name:           k.Key,
+			Detail:         detail,
+			Kind:           lspSymbolKindKey,
+			Range:          r,
+			SelectionRange: r,
+		})
+
+		if first == -1 || k.Lines[0] < first {
+			first = k.Lines[0]
+		}
+		if k.Lines[len(k.Lines)-1] > last {
+			last = k.Lines[len(k.Lines)-1]
+		}
 	}
 
-	return v, nil
+	group.Range = lineRange(res, first)
+	group.Range.End = lineRange(res, last).End
+	group.SelectionRange = lineRange(res, first)
+
+	return group, true
 }
 
-func (l *lsp) request(method string, params interface{}) error {
-	l.id++
-	return l.write(jsonRpcRequest{
-		JsonRpc: "2.0",
-		Id:      strconv.Itoa(l.id),
-		Method:  method,
-		Params:  params,
-	})
+// lifecycleTitle lists the OnCompletion values that may be approved on create and call.
+func lifecycleTitle(lc teal.Lifecycle) string {
+	var create []string
+	var call []string
+
+	for _, c := range lc.Cases {
+		if !c.Approve {
+			continue
+		}
+
+		if c.Create {
+			create = append(create, c.OnCompletion)
+		} else {
+			call = append(call, c.OnCompletion)
+		}
+	}
+
+	format := func(ocs []string) string {
+		if len(ocs) == 0 {
+			return "none"
+		}
+		return strings.Join(ocs, ", ")
+	}
+
+	return fmt.Sprintf("approves on create: %s; call: %s", format(create), format(call))
 }
 
-func (l *lsp) notify(method string, params interface{}) error {
-	return l.write(lspNotification{
-		JsonRpc: "2.0",
-		Method:  method,
-		Params:  params,
-	})
+// addressHover describes the known address of the literal.
+func addressHover(a teal.KnownAddress) string {
+	if a.Burn {
+		return fmt.Sprintf("Known address: %s - the sent funds can not be recovered", a.Name)
+	}
+
+	return fmt.Sprintf("Known address: %s", a.Name)
 }
 
-func (l *lsp) notifyDiagnostics(uri string, lds []lspDiagnostic) error {
-	return l.notify("textDocument/publishDiagnostics", lspPublishDiagnostic{
-		Uri:         uri,
-		Diagnostics: lds,
-	})
+func abiHover(c teal.AbiConstant) string {
+	return fmt.Sprintf("ABI encoded %s: %s", c.Type, c)
 }
 
-func (l *lsp) doDiagnostic(doc *lspDoc) []lspDiagnostic {
-	res := doc.Results()
+// groupHover describes the expectations of the group transaction referenced by the op at the position.
+func groupHover(res *teal.ProcessResult, line int, ch int) string {
+	ln := res.LineAt(line, ch)
+	if len(ln) == 0 {
+		return ""
+	}
 
-	lds := []lspDiagnostic{}
+	switch ln[0].String() {
+	case "gtxn", "gtxna", "gtxns", "gtxnsa", "gtxnas", "gtxnsas", "gaid", "gload":
+	default:
+		return ""
+	}
+
+	for _, m := range teal.AnalyzeGroup(res).Members {
+		for _, l := range m.Lines {
+			if l == line {
+				return fmt.Sprintf("expects tx%d: %s", m.Index, m.Description())
+			}
+		}
+	}
+
+	return ""
+}
+
+// programMode returns the mode of the program unless overridden in the config.
+func programMode(res *teal.ProcessResult, cfg tealConfig) teal.ProgramMode {
+	switch cfg.Mode {
+	case "app":
+		return teal.ModeApp
+	case "sig":
+		return teal.ModeSig
+	}
+
+	return res.Mode
+}
+
+// sizeTitle shows the estimated program size and cost against the limits of the configured mode.
+func sizeTitle(res *teal.ProcessResult, cfg tealConfig) string {
+	mode := programMode(res, cfg)
+
+	size := teal.EstimateSize(res)
+	max := teal.ProgramMaxSize(mode, cfg.ExtraPages)
+
+	c := teal.EstimateCost(res, teal.ProgramBudget(mode))
+
+	cost := fmt.Sprintf("%d/%d", c.Max, c.Budget)
+	if c.Exceeded {
+		cost = fmt.Sprintf(">%d/%d", c.Budget, c.Budget)
+	} else if c.Incomplete {
+		cost = fmt.Sprintf(">=%d/%d", c.Max, c.Budget)
+	}
+
+	return fmt.Sprintf("size: %d/%d bytes, cost: %s", size, max, cost)
+}
+
+// codeLenses are the lenses computed from the results, the source lenses read from the source maps are not included.
+func codeLenses(res *teal.ProcessResult, cfg tealConfig) []lspCodeLens {
+	var cls []lspCodeLens
+
+	if cfg.LensSize {
+		cls = append(cls, lspCodeLens{
+			Range: lspRange{
+				Start: lspPosition{
+					Line: 0,
+				},
+				End: lspPosition{
+					Line: 0,
+				},
+			},
+			Command: &lspCommand{
+				Title: sizeTitle(res, cfg),
+			},
+		})
+	}
+
+	if cfg.LensRefs {
+		for _, sym := range res.Symbols {
+			count := res.RefCounts[sym.Name()]
+			if count > 0 {
+				cls = append(cls, lspCodeLens{
+					Range: lspRange{
+						Start: lspPosition{
+							Line: sym.StartLine(),
+						},
+						End: lspPosition{
+							Line: sym.EndLine(),
+						},
+					},
+					Command: &lspCommand{
+						Title: fmt.Sprintf("refs: %d", count),
+					},
+				})
+			}
+		}
+	}
+
+	if cfg.LensLifecycle {
+		lc := teal.AnalyzeLifecycle(res)
+		if lc.Line != -1 {
+			cls = append(cls, lspCodeLens{
+				Range: lspRange{
+					Start: lspPosition{
+						Line: lc.Line,
+					},
+					End: lspPosition{
+						Line: lc.Line,
+					},
+				},
+				Command: &lspCommand{
+					Title: lifecycleTitle(lc),
+				},
+			})
+		}
+	}
+
+	return cls
+}
+
+// documentSymbols are the labels along with the state keys and the boxes of the results.
+func documentSymbols(res *teal.ProcessResult) []lspDocumentSymbol {
+	syms := []lspDocumentSymbol{}
+	for _, s := range res.Symbols {
+		r := lspRange{
+			Start: lspPosition{
+				Line:      s.Line(),
+				Character: s.Begin(),
+			},
+			End: lspPosition{
+				Line:      s.Line(),
+				Character: s.End(),
+			},
+		}
+		syms = append(syms, lspDocumentSymbol{
+			Name:           s.Name(),
+			Kind:           lspSymbolKindMethod,
+			Range:          r,
+			SelectionRange: r,
+		})
+	}
+
+	state := teal.AnalyzeState(res)
+
+	if sym, ok := keysSymbol(res, "State", state.Keys); ok {
+		syms = append(syms, sym)
+	}
+	if sym, ok := keysSymbol(res, "Boxes", state.Boxes); ok {
+		syms = append(syms, sym)
+	}
+
+	return syms
+}
+
+func readInto(b []byte, v interface{}) error {
+	var m struct {
+		Params json.RawMessage `json:"params"`
+	}
+
+	err := json.Unmarshal(b, &m)
+	if err != nil {
+		return invalidParams(err)
+	}
+
+	if len(m.Params) == 0 || string(m.Params) == "null" {
+		return invalidParams(errors.New("missing params"))
+	}
+
+	err = json.Unmarshal(b, &v)
+	if err != nil {
+		return invalidParams(err)
+	}
+
+	return nil
+}
+
+func (l *lsp) reply(id interface{}, result interface{}, err interface{}) error {
+	if err == nil && l.cancelled() {
+		result = nil
+		err = lspError{Code: lspRequestCancelled, Message: "request cancelled"}
+	}
+
+	return l.write(jsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      id,
+		Result:  result,
+		Error:   err,
+	})
+}
+
+func (l *lsp) fail(id interface{}, err interface{}) error {
+	return l.reply(id, nil, err)
+}
+
+func (l *lsp) success(id interface{}, result interface{}) error {
+	return l.reply(id, result, nil)
+}
+
+func read[T any](b []byte) (T, error) {
+	var v T
+
+	err := readInto(b, &v)
+	if err != nil {
+		return v, err
+	}
+
+	if vd, ok := any(v).(validator); ok {
+		err := vd.validate()
+		if err != nil {
+			return v, invalidParams(err)
+		}
+	}
+
+	return v, nil
+}
+
+func (l *lsp) request(method string, params interface{}) error {
+	l.id++
+	return l.write(jsonRpcRequest{
+		JsonRpc: "2.0",
+		Id:      strconv.Itoa(l.id),
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (l *lsp) notify(method string, params interface{}) error {
+	return l.write(lspNotification{
+		JsonRpc: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (l *lsp) notifyDiagnostics(uri string, lds []lspDiagnostic) error {
+	return l.notify("textDocument/publishDiagnostics", lspPublishDiagnostic{
+		Uri:         uri,
+		Diagnostics: lds,
+	})
+}
+
+func (l *lsp) toDiagnostics(res *teal.ProcessResult) []lspDiagnostic {
+	lds := []lspDiagnostic{}
 	for _, d := range res.Diagnostics {
 		sev := int(d.Severity())
 
@@ -774,13 +1550,100 @@ func (l *lsp) doDiagnostic(doc *lspDoc) []lspDiagnostic {
 	return lds
 }
 
-func (l *lsp) prepare(uri string) (*lspDoc, *teal.ProcessResult, error) {
-	doc := l.docs[uri]
+type lspFile struct {
+	uri string
+	res *teal.ProcessResult
+}
+
+// files returns the doc results along with its included files and the open docs that include it.
+func (l *lsp) files(uri string, res *teal.ProcessResult) []lspFile {
+	fs := []lspFile{{uri: uri, res: res}}
+	seen := map[string]bool{uri: true}
+
+	add := func(res *teal.ProcessResult) {
+		for _, inc := range res.Included() {
+			iuri := pathToUri(inc.Path)
+			if !seen[iuri] {
+				seen[iuri] = true
+				fs = append(fs, lspFile{uri: iuri, res: inc.Result})
+			}
+		}
+	}
+
+	add(res)
+
+	path := uriToPath(uri)
+	if path != "" {
+		for duri, doc := range l.allDocs() {
+			if seen[duri] {
+				continue
+			}
+
+			dres := doc.Results()
+			if doc.Includes(path) {
+				seen[duri] = true
+				fs = append(fs, lspFile{uri: duri, res: dres})
+				add(dres)
+			}
+		}
+	}
+
+	return fs
+}
+
+// symbolLocations returns the references, and optionally the definitions, of the named label across files.
+func (l *lsp) symbolLocations(uri string, res *teal.ProcessResult, name string, decl bool) []lspLocation {
+	ls := []lspLocation{}
+
+	for _, f := range l.files(uri, res) {
+		if decl {
+			for _, sym := range f.res.SymByName(name) {
+				ls = append(ls, lspLocation{
+					Uri: f.uri,
+					Range: lspRange{
+						Start: lspPosition{
+							Line:      sym.Line(),
+							Character: sym.Begin(),
+						},
+						End: lspPosition{
+							Line:      sym.Line(),
+							Character: sym.Begin() + len(sym.Name()),
+						},
+					},
+				})
+			}
+		}
+
+		for _, ref := range f.res.SymRefByName(name) {
+			ls = append(ls, lspLocation{
+				Uri: f.uri,
+				Range: lspRange{
+					Start: lspPosition{
+						Line:      ref.Line(),
+						Character: ref.Begin(),
+					},
+					End: lspPosition{
+						Line:      ref.Line(),
+						Character: ref.End(),
+					},
+				},
+			})
+		}
+	}
+
+	return ls
+}
+
+// prepare returns the current snapshot of the doc along with its results.
+func (l *lsp) prepare(uri string) (*lspSnapshot, *teal.ProcessResult, error) {
+	doc := l.doc(uri)
 	if doc == nil {
 		return nil, nil, errors.New("doc not found")
 	}
 
-	return doc, doc.Results(), nil
+	snap := doc.Snapshot()
+
+	return snap, snap.Results(), nil
 }
 
 func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
@@ -800,8 +1663,36 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 		return nil
 	}
 
+	if l.shutdown && h.Method != "exit" {
+		if h.Id == nil {
+			return nil
+		}
+
+		return rpcError{code: jsonRpcInvalidRequest, err: errors.New("server is shut down")}
+	}
+
 	switch h.Method { // notifications
 	case "initialized":
+		l.indexWorkspace()
+
+	case "$/cancelRequest":
+		return l.cancelRequest(b)
+
+	case "workspace/didChangeConfiguration":
+		req, err := read[lspDidChangeConfiguration](b)
+		if err != nil {
+			return err
+		}
+
+		return l.reconfigure(req.Params.Settings)
+
+	case "workspace/didChangeWorkspaceFolders":
+		req, err := read[lspDidChangeWorkspaceFolders](b)
+		if err != nil {
+			return err
+		}
+
+		return l.changeFolders(req.Params.Event.Added, req.Params.Event.Removed)
 
 	case "exit":
 		l.exit = true
@@ -815,13 +1706,9 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			return err
 		}
 
-		doc := l.docs[req.Params.TextDocument.Uri]
-		if doc == nil {
-			doc = &lspDoc{}
-			l.docs[req.Params.TextDocument.Uri] = doc
-		}
-
+		doc := l.open(req.Params.TextDocument.Uri)
 		doc.Update(req.Params.TextDocument.Text)
+		l.invalidate(req.Params.TextDocument.Uri)
 
 	case "textDocument/didChange":
 		req, err := read[lspDidChange](b)
@@ -830,7 +1717,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 		}
 
 		for _, ch := range req.Params.ContentChanges {
-			doc := l.docs[req.Params.TextDocument.Uri]
+			doc := l.doc(req.Params.TextDocument.Uri)
 			if doc == nil {
 				return errors.New("doc not found")
 			}
@@ -838,6 +1725,8 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			doc.Update(ch.Text)
 		}
 
+		l.invalidate(req.Params.TextDocument.Uri)
+
 	case "textDocument/didSave":
 		_, err := read[lspDidSave](b)
 		if err != nil {
@@ -848,16 +1737,11 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 
 	default: // requests
 
-		if l.shutdown {
-			return errors.New("cannot process requests - server is shut down")
-		}
-
 		switch h.Method {
 		case "shutdown":
 			l.shutdown = true
-			return l.success(h.Id, []struct{}{})
-
-		case "$/cancelRequest":
+			l.saveIndex()
+			return l.success(h.Id, json.RawMessage("null"))
 
 		case "textDocument/didClose":
 			req, err := read[lspDidCloseRequest](b)
@@ -865,7 +1749,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			delete(l.docs, req.Params.TextDocument.Uri)
+			l.close(req.Params.TextDocument.Uri)
 
 		case "workspace/executeCommand":
 			req, err := read[lspWorkspaceExecuteCommand](b)
@@ -873,6 +1757,8 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
+			l.stats.command(req.Params.Command)
+
 			switch req.Params.Command {
 			case "teal.version.update":
 				var body lspWorkspaceExecuteCommandBody[[]tealUpdateVersion]
@@ -888,7 +1774,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 
 				arg := args[0]
 
-				doc := l.docs[arg.Uri]
+				doc := l.doc(arg.Uri)
 				if doc == nil {
 					return errors.New("doc not found")
 				}
@@ -923,7 +1809,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 								Character: 0,
 							},
 						},
-						NewText: fmt.Sprintf("#pragma version %d\r\n", arg.Version),
+						NewText: fmt.Sprintf("#pragma version %d%s", arg.Version, l.eol(res)),
 					})
 
 				}
@@ -953,7 +1839,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				if len(args) != 1 {
 					return errors.New("unexpected number of args")
 				}
-				doc := l.docs[args[0].Uri]
+				doc := l.doc(args[0].Uri)
 				if doc == nil {
 					return errors.New("doc not found")
 				}
@@ -990,7 +1876,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 					return errors.New("unexpected number of args")
 				}
 
-				doc := l.docs[args[0].Uri]
+				doc := l.doc(args[0].Uri)
 				if doc == nil {
 					return errors.New("doc not found")
 				}
@@ -1094,7 +1980,8 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				}
 
 				name := args[0].Name
-				s := fmt.Sprintf("\r\n%s:\r\n", name)
+				eol := l.eol(res)
+				s := fmt.Sprintf("%s%s:%s", eol, name, eol)
 
 				return l.request("workspace/applyEdit", lspWorkspaceApplyEditRequestParams{
 					Label: fmt.Sprintf("Create label: %s", name),
@@ -1124,6 +2011,130 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 					},
 				})
 
+			case "teal.server.stats":
+				return l.success(h.Id, l.stats.Prometheus(l.allDocs()))
+
+			case "teal.server.usage":
+				var body lspWorkspaceExecuteCommandBody[[]tealUsageCommandArgs]
+				err := readInto(b, &body)
+				if err != nil {
+					return err
+				}
+
+				u, ok := l.stats.Usage(l.allDocs())
+				if !ok {
+					return errors.New("usage statistics are disabled, set the usage option to enable them")
+				}
+
+				args := body.Params.Arguments
+				if len(args) > 0 && args[0].Path != "" {
+					bs, err := json.MarshalIndent(u, "", "  ")
+					if err != nil {
+						return err
+					}
+
+					err = os.WriteFile(args[0].Path, bs, 0o644)
+					if err != nil {
+						return errors.Wrap(err, "failed to write the usage")
+					}
+				}
+
+				return l.success(h.Id, u)
+
+			case "teal.tests.list":
+				var body lspWorkspaceExecuteCommandBody[[]tealTestsCommandArgs]
+				err := readInto(b, &body)
+				if err != nil {
+					return err
+				}
+
+				args := body.Params.Arguments
+				if len(args) != 1 {
+					return errors.New("unexpected number of args")
+				}
+
+				_, res, err := l.prepare(args[0].Uri)
+				if err != nil {
+					return err
+				}
+
+				es := res.TestEntries()
+				if es == nil {
+					es = []teal.TestEntry{}
+				}
+
+				return l.success(h.Id, es)
+
+			case "teal.source.open":
+				var body lspWorkspaceExecuteCommandBody[[]tealOpenSourceCommandArgs]
+				err := readInto(b, &body)
+				if err != nil {
+					return err
+				}
+
+				args := body.Params.Arguments
+				if len(args) != 1 {
+					return errors.New("unexpected number of args")
+				}
+
+				err = l.request("window/showDocument", lspShowDocumentParams{
+					Uri:       args[0].Uri,
+					TakeFocus: true,
+					Selection: &lspRange{
+						Start: lspPosition{
+							Line: args[0].Line,
+						},
+						End: lspPosition{
+							Line: args[0].Line,
+						},
+					},
+				})
+				if err != nil {
+					return err
+				}
+
+				return l.success(h.Id, nil)
+
+			case "teal.tests.run":
+				var body lspWorkspaceExecuteCommandBody[[]tealTestsCommandArgs]
+				err := readInto(b, &body)
+				if err != nil {
+					return err
+				}
+
+				args := body.Params.Arguments
+				if len(args) != 1 {
+					return errors.New("unexpected number of args")
+				}
+
+				_, res, err := l.prepare(args[0].Uri)
+				if err != nil {
+					return err
+				}
+
+				ids := map[string]bool{}
+				for _, id := range args[0].Ids {
+					ids[id] = true
+				}
+
+				rs := []teal.TestResult{}
+				for _, e := range res.TestEntries() {
+					if len(ids) > 0 && !ids[e.Id] {
+						continue
+					}
+					rs = append(rs, res.RunTestEntry(e))
+				}
+
+				err = l.notify("teal/testResults", tealTestResultsParams{
+					Uri:     args[0].Uri,
+					Results: rs,
+				})
+				if err != nil {
+					return err
+				}
+
+				return l.success(h.Id, nil)
+
 			default:
 				return l.fail(h.Id, lspError{
 					Code:    1,
@@ -1187,102 +2198,44 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			chs := []lspTextEdit{}
-			for _, edited := range res.SymbolsWithin(req.Params.Position) {
-				for _, sym := range res.SymByName(edited.Name()) {
-					chs = append(chs, lspTextEdit{
-						Range: lspRange{
-							Start: lspPosition{
-								Line:      sym.Line(),
-								Character: sym.Begin(),
-							},
-							End: lspPosition{
-								Line:      sym.Line(),
-								Character: sym.Begin() + len(sym.Name()),
-							},
-						},
-						NewText: req.Params.NewName,
-					})
-				}
-				for _, ref := range res.SymRefByName(edited.Name()) {
-					chs = append(chs, lspTextEdit{
-						Range: lspRange{
-							Start: lspPosition{
-								Line:      ref.Line(),
-								Character: ref.Begin(),
-							},
-							End: lspPosition{
-								Line:      ref.Line(),
-								Character: ref.End(),
-							},
-						},
-						NewText: req.Params.NewName,
-					})
-				}
+			chs := map[string][]lspTextEdit{
+				req.Params.TextDocument.Uri: {},
 			}
 
-			for _, edited := range res.SymbolRefsWithin(req.Params.Position) {
-				for _, sym := range res.SymByName(edited.String()) {
-					chs = append(chs, lspTextEdit{
-						Range: lspRange{
-							Start: lspPosition{
-								Line:      sym.Line(),
-								Character: sym.Begin(),
-							},
-							End: lspPosition{
-								Line:      sym.Line(),
-								Character: sym.Begin() + len(sym.Name()),
-							},
-						},
-						NewText: req.Params.NewName,
-					})
-				}
-
-				for _, ref := range res.SymRefByName(edited.String()) {
-					chs = append(chs, lspTextEdit{
-						Range: lspRange{
-							Start: lspPosition{
-								Line:      ref.Line(),
-								Character: ref.Begin(),
-							},
-							End: lspPosition{
-								Line:      ref.Line(),
-								Character: ref.End(),
-							},
-						},
+			if name := res.SymOrRefAt(req.Params.Position); name != "" {
+				for _, loc := range l.symbolLocations(req.Params.TextDocument.Uri, res, name, true) {
+					chs[loc.Uri] = append(chs[loc.Uri], lspTextEdit{
+						Range:   loc.Range,
 						NewText: req.Params.NewName,
 					})
 				}
 			}
 
 			return l.success(h.Id, lspWorkspaceEdit{
-				Changes: map[string][]lspTextEdit{
-					req.Params.TextDocument.Uri: chs,
-				},
+				Changes: chs,
 			})
 
-		case "textDocument/inlineValue":
-			req, err := read[lspInlineValueRequest](b)
+		case "textDocument/references":
+			req, err := read[lspReferencesRequest](b)
 			if err != nil {
 				return err
 			}
 
-			_, _, err = l.prepare(req.Params.TextDocument.Uri)
+			_, res, err := l.prepare(req.Params.TextDocument.Uri)
 			if err != nil {
 				return err
 			}
 
-			ls := []lspInlineValueText{}
+			ls := []lspLocation{}
 
-			ls = append(ls, lspInlineValueText{
-				Range: req.Params.Range,
-				Text:  "Debugger Test",
-			})
+			if name := res.SymOrRefAt(req.Params.Position); name != "" {
+				ls = append(ls, l.symbolLocations(req.Params.TextDocument.Uri, res, name, req.Params.Context.IncludeDeclaration)...)
+			}
 
 			return l.success(h.Id, ls)
 
-		case "textDocument/codeLens":
-			req, err := read[lspCodeLensRequest](b)
+		case "textDocument/inlineValue":
+			req, err := read[lspInlineValueRequest](b)
 			if err != nil {
 				return err
 			}
@@ -1292,27 +2245,28 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			var cls []lspCodeLens
+			return l.success(h.Id, inlineValues(res, l.debugState(req.Params.TextDocument.Uri), req.Params.Range))
 
-			if l.config.LensRefs {
-				for _, sym := range res.Symbols {
-					count := res.RefCounts[sym.Name()]
-					if count > 0 {
-						cls = append(cls, lspCodeLens{
-							Range: lspRange{
-								Start: lspPosition{
-									Line: sym.StartLine(),
-								},
-								End: lspPosition{
-									Line: sym.EndLine(),
-								},
-							},
-							Command: &lspCommand{
-								Title: fmt.Sprintf("refs: %d", count),
-							},
-						})
-					}
-				}
+		case "textDocument/codeLens":
+			req, err := read[lspCodeLensRequest](b)
+			if err != nil {
+				return err
+			}
+
+			doc := l.doc(req.Params.TextDocument.Uri)
+			if doc == nil {
+				return errors.New("doc not found")
+			}
+
+			snap := doc.Snapshot()
+
+			cls := l.cachedLenses(req.Params.TextDocument.Uri, snap)
+			if cls == nil {
+				cls = codeLenses(snap.Results(), l.config)
+			}
+
+			if l.config.LensSource {
+				cls = append(cls, l.sourceLenses(req.Params.TextDocument.Uri)...)
 			}
 
 			return l.success(h.Id, cls)
@@ -1366,6 +2320,45 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				}
 			}
 
+			if l.config.InlayAddress {
+				for _, addr := range hs.Addresses {
+					ihs = append(ihs, lspInlayHint{
+						Position: lspPosition{
+							Line:      addr.Line,
+							Character: addr.Character,
+						},
+						Label:       addr.Label,
+						PaddingLeft: padding,
+					})
+				}
+			}
+
+			if l.config.InlayAbi {
+				for _, c := range hs.Abi {
+					ihs = append(ihs, lspInlayHint{
+						Position: lspPosition{
+							Line:      c.Line,
+							Character: c.Character,
+						},
+						Label:       c.Label,
+						PaddingLeft: padding,
+					})
+				}
+			}
+
+			if l.config.InlayStackDepth {
+				for _, depth := range hs.Depths {
+					ihs = append(ihs, lspInlayHint{
+						Position: lspPosition{
+							Line:      depth.Line,
+							Character: depth.Character,
+						},
+						Label:       depth.Label,
+						PaddingLeft: padding,
+					})
+				}
+			}
+
 			return l.success(h.Id, ihs)
 
 		case "textDocument/completion":
@@ -1379,10 +2372,7 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			var ln teal.Line
-			if len(res.Lines) > req.Params.Position.Line {
-				ln = res.Lines[req.Params.Position.Line]
-			}
+			ln := res.LineAt(req.Params.Position.Line, req.Params.Position.Character)
 
 			ccs := []lspCompletionItem{}
 
@@ -1396,118 +2386,16 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				if len(ln) > 0 {
 					if req.Params.Position.Character <= ln[0].End() {
 						mode = tealCompletionOp
-						prefix = ln[0].String()
+						prefix = typedPrefix(ln[0], req.Params.Position.Character)
 					}
 				}
 			}
 
 			switch mode {
 			case tealCompletionArg:
-				for _, v := range res.ArgValsAt(req.Params.Position.Line, req.Params.Position.Character) {
-					var d *lspCompletionItemLabelDetails
-					if !v.NoValue {
-						d = &lspCompletionItemLabelDetails{
-							Detail: fmt.Sprintf(" = %d", v.Value),
-						}
-					} else if v.Signature != "" {
-						d = &lspCompletionItemLabelDetails{
-							Detail: fmt.Sprintf(" %s", v.Signature),
-						}
-					}
-					ccs = append(ccs, lspCompletionItem{
-						LabelDetails: d,
-						Label:        v.Name,
-						Documentation: lspMarkupContent{
-							Kind:  "markdown",
-							Value: v.Docs,
-						},
-					})
-				}
-
+				ccs = argCompletions(res, ln, req.Params.Position.Line, req.Params.Position.Character)
 			case tealCompletionOp:
-				operator := new(int)
-				*operator = 25
-
-				snippet := 15
-
-				snippetFormat := new(int)
-				*snippetFormat = 2
-
-				var at string
-				var bt string
-				for i, name := range teal.OnCompletionNames {
-					if i > 0 {
-						at += " "
-					}
-
-					field := fmt.Sprintf("${%d:%s}", i+1, strings.ToLower(name))
-
-					at += field
-					bt += fmt.Sprintf("%s:\n", field)
-
-					if i < len(teal.OnCompletionNames)-1 {
-						bt += fmt.Sprintf("b ${%d:then}\n", len(teal.OnCompletionNames)+2)
-					}
-
-					bt += "\n"
-				}
-
-				bt += fmt.Sprintf("${%d:then}:\n$%d", len(teal.OnCompletionNames)+2, len(teal.OnCompletionNames)+3)
-
-				ccs = append(ccs, lspCompletionItem{
-					Label:            "soc",
-					Kind:             &snippet,
-					Detail:           "switch on OnCompletion",
-					InsertText:       fmt.Sprintf("txn OnCompletion\nswitch %s\n%s", at, bt),
-					InsertTextFormat: snippetFormat,
-				})
-
-				ccs = append(ccs, lspCompletionItem{
-					Label:            "func",
-					Kind:             &snippet,
-					Detail:           "create subroutine",
-					InsertText:       "${1:sub}:\r\n\r\n\tproto ${2:0} ${3:0}\r\n\t${4}\r\n\tretsub\r\n",
-					InsertTextFormat: snippetFormat,
-				})
-
-				for name, info := range teal.Ops.Items {
-					if info.AppVersion <= res.Version && strings.HasPrefix(name, prefix) {
-						var insert string
-						var format *int
-						if len(info.Args) > 0 {
-							var placeholders string
-							for i, arg := range info.Args {
-								if i > 0 {
-									placeholders += " "
-								}
-
-								placeholders += fmt.Sprintf("${%d:%s}", i+1, arg.Name)
-							}
-
-							insert = fmt.Sprintf("%s %s", name, placeholders)
-							format = snippetFormat
-						} else {
-							insert = ""
-							format = nil
-						}
-
-						ld := fmt.Sprintf("v%d", info.AppVersion)
-						ccs = append(ccs, lspCompletionItem{
-							Label: name,
-							Documentation: lspMarkupContent{
-								Kind:  "markdown",
-								Value: info.Doc,
-							},
-							Kind:             operator,
-							InsertText:       insert,
-							InsertTextFormat: format,
-							LabelDetails: &lspCompletionItemLabelDetails{
-								Description: ld,
-								Detail:      " " + info.ArgsSig,
-							},
-						})
-					}
-				}
+				ccs = opCompletions(res, programMode(res, l.config), req.Params.Position.Line, prefix)
 			}
 
 			if len(ccs) == 0 {
@@ -1516,6 +2404,11 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				})
 			}
 
+			eol := l.eol(res)
+			for i := range ccs {
+				ccs[i].InsertText = teal.NormalizeEol(ccs[i].InsertText, eol)
+			}
+
 			return l.success(h.Id, ccs)
 
 		case "textDocument/hover":
@@ -1532,6 +2425,21 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			var c interface{} = struct{}{}
 
 			s := res.DocAt(req.Params.Position.Line, req.Params.Position.Character)
+			if a, ok := res.AddressAt(req.Params.Position.Line, req.Params.Position.Character); ok {
+				s += "\r\n\r\n" + addressHover(a.Address)
+			}
+			if c, ok := res.AbiConstantAt(req.Params.Position.Line, req.Params.Position.Character); ok {
+				s += "\r\n\r\n" + abiHover(c)
+			}
+			if g := groupHover(res, req.Params.Position.Line, req.Params.Position.Character); g != "" {
+				s += "\r\n\r\n" + g
+			}
+			if c := chainHover(l.chain, res, req.Params.Position.Line); c != "" {
+				s += "\r\n\r\n" + c
+			}
+			if d := debugHover(l.debugState(req.Params.TextDocument.Uri), req.Params.Position.Line); d != "" {
+				s += "\r\n\r\n" + d
+			}
 			if s != "" {
 				c = lspHover{
 					Contents: lspMarkupContent{
@@ -1556,9 +2464,24 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 
 			ls := []lspLocation{}
 
-			for _, sym := range res.SymbolsForRefWithin(req.Params.Position) {
+			uri := req.Params.TextDocument.Uri
+			syms := res.SymbolsForRefWithin(req.Params.Position)
+
+			if len(syms) == 0 {
+				if name := res.SymOrRefAt(req.Params.Position); name != "" {
+					for _, f := range l.files(uri, res)[1:] {
+						syms = f.res.SymByName(name)
+						if len(syms) > 0 {
+							uri = f.uri
+							break
+						}
+					}
+				}
+			}
+
+			for _, sym := range syms {
 				ls = append(ls, lspLocation{
-					Uri: req.Params.TextDocument.Uri,
+					Uri: uri,
 					Range: lspRange{
 						Start: lspPosition{
 							Line:      sym.Line(),
@@ -1585,7 +2508,19 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			formatted := tealfmt.Format(strings.NewReader(doc.s))
+			// the formatting works on the \n line endings
+			src := teal.NormalizeEol(doc.s, "\n")
+			if l.config.FormatSublines == "split" {
+				src = teal.SplitSublines(src)
+			}
+
+			formatted := tealfmt.Format(strings.NewReader(src))
+
+			if l.config.FormatSublines == "join" {
+				formatted = teal.JoinSublines(formatted, l.config.FormatWidth)
+			}
+
+			formatted = teal.NormalizeEol(formatted, l.eol(res))
 
 			return l.success(h.Id, []lspTextEdit{
 				{
@@ -1603,6 +2538,19 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				},
 			})
 
+		case "textDocument/onTypeFormatting":
+			req, err := read[lspDocumentOnTypeFormattingRequest](b)
+			if err != nil {
+				return err
+			}
+
+			doc, _, err := l.prepare(req.Params.TextDocument.Uri)
+			if err != nil {
+				return err
+			}
+
+			return l.success(h.Id, onTypeFormat(splitLines(doc.s), req.Params.Position, req.Params.Ch, l.config.FormatCommentColumn))
+
 		case "textDocument/signatureHelp":
 			req, err := read[lspSignatureHelpRequest](b)
 			if err != nil {
@@ -1615,48 +2563,8 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			}
 
 			var sh interface{} = struct{}{}
-			for _, op := range res.Ops {
-				if op.Line() == req.Params.Position.Line {
-					info, ok := teal.Ops.Get(teal.OpContext{
-						Name:    op.String(),
-						Version: res.Version,
-					})
-					if ok {
-						_, idx, _ := res.ArgAt(req.Params.Position.Line, req.Params.Position.Character)
-
-						active := new(int)
-						*active = idx
-
-						var doc interface{}
-
-						if info.FullDoc != "" {
-							doc = lspMarkupContent{
-								Kind:  "markdown",
-								Value: info.FullDoc,
-							}
-						}
-
-						ps := []lspParameterInformation{}
-
-						for _, arg := range info.Args {
-							ps = append(ps, lspParameterInformation{
-								Label: arg.Name,
-							})
-						}
-
-						sh = &lspSignatureHelp{
-							Signatures: []lspSignatureInformation{
-								{
-									Label:           info.FullSig,
-									Documentation:   doc,
-									Parameters:      ps,
-									ActiveParameter: active,
-								},
-							},
-						}
-					}
-					break
-				}
+			if h := signatureHelp(res, req.Params.Position.Line, req.Params.Position.Character); h != nil {
+				sh = h
 			}
 
 			return l.success(h.Id, sh)
@@ -1674,149 +2582,102 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 
 			cas := []lspCodeAction{}
 
-			for _, red := range res.Redundants {
-				if req.Params.Range.Start.Line <= red.Line() && req.Params.Range.End.Line >= red.Line() {
-					kind := "quickfix"
-					title := red.String()
-
-					cas = append(cas, lspCodeAction{
-						Title: title,
-						Kind:  &kind,
-						Command: &lspCommand{
-							Title:   title,
-							Command: "teal.line.remove",
-							Arguments: []interface{}{
-								tealRemoveLineCommandArgs{
-									Uri:  req.Params.TextDocument.Uri,
-									Line: red.Line(),
-								},
-							},
-						},
-					})
-				}
+			for _, fa := range res.CodeActions(req.Params.Range) {
+				cas = append(cas, codeAction(req.Params.TextDocument.Uri, fa, l.eol(res)))
+			}
+
+			return l.success(h.Id, cas)
+		case "textDocument/diagnostic":
+			req, err := read[lspDiagnosticRequest](b)
+			if err != nil {
+				return err
 			}
 
-			for _, ref := range res.MissRefs {
-				if !teal.Overlaps(req.Params.Range, ref) {
-					continue
+			doc := l.doc(req.Params.TextDocument.Uri)
+
+			var ds []lspDiagnostic
+			var rds map[string]lspFullDocumentDiagnosticReport
+
+			if doc != nil {
+				res := doc.Results()
+				ds = l.toDiagnostics(res)
+
+				for _, inc := range res.Included() {
+					if rds == nil {
+						rds = map[string]lspFullDocumentDiagnosticReport{}
+					}
+
+					rds[pathToUri(inc.Path)] = lspFullDocumentDiagnosticReport{
+						Kind:  "full",
+						Items: l.toDiagnostics(inc.Result),
+					}
 				}
+			} else {
+				ds = []lspDiagnostic{}
+			}
 
-				kind := "quickfix"
-				cas = append(cas, lspCodeAction{
-					Title: fmt.Sprintf("Create label '%s'", ref.String()),
-					Kind:  &kind,
-					Command: &lspCommand{
-						Title:   "Create label",
-						Command: "teal.label.create",
-						Arguments: []interface{}{
-							tealCreateLabelCommandArgs{
-								Uri:  req.Params.TextDocument.Uri,
-								Name: ref.String(),
-							},
-						},
-					},
-				})
+			return l.success(h.Id, lspFullDocumentDiagnosticReport{
+				Kind:             "full",
+				Items:            ds,
+				RelatedDocuments: rds,
+			})
+
+		case "workspace/symbol":
+			req, err := read[lspWorkspaceSymbolRequest](b)
+			if err != nil {
+				return err
 			}
 
-			hs := res.InlayHints(req.Params.Range)
+			return l.success(h.Id, l.workspaceSymbols(req.Params.Query))
 
-			for _, named := range hs.Named {
-				kind := "quickfix"
-				cas = append(cas, lspCodeAction{
-					Title: fmt.Sprintf("Replace with '%s'", named.Name),
-					Kind:  &kind,
-					Command: &lspCommand{
-						Title:   "Replace with named const",
-						Command: "teal.value.replace",
-						Arguments: []interface{}{
-							tealReplaceValueCommandArgs{
-								Uri: req.Params.TextDocument.Uri,
-								Range: lspRange{
-									Start: lspPosition{
-										Line:      named.T.Line(),
-										Character: named.T.Begin(),
-									},
-									End: lspPosition{
-										Line:      named.T.Line(),
-										Character: named.T.End(),
-									},
-								},
-								Value: named.Name,
-							},
-						},
-					},
-				})
+		case "workspace/diagnostic":
+			req, err := read[lspWorkspaceDiagnosticRequest](b)
+			if err != nil {
+				return err
 			}
 
-			for _, named := range hs.Decoded {
-				kind := "quickfix"
-				cas = append(cas, lspCodeAction{
-					Title: fmt.Sprintf("Replace with literal '%s'", named.Value),
-					Kind:  &kind,
-					Command: &lspCommand{
-						Title:   "Replace with literal",
-						Command: "teal.value.replace",
-						Arguments: []interface{}{
-							tealReplaceValueCommandArgs{
-								Uri: req.Params.TextDocument.Uri,
-								Range: lspRange{
-									Start: lspPosition{
-										Line:      named.T.Line(),
-										Character: named.T.Begin(),
-									},
-									End: lspPosition{
-										Line:      named.T.Line(),
-										Character: named.T.End(),
-									},
-								},
-								Value: fmt.Sprintf("\"%s\"", strings.ReplaceAll(named.Value, "\"", "\\\"")),
-							},
-						},
-					},
-				})
+			return l.success(h.Id, l.workspaceDiagnostics(req.Params.PreviousResultIds))
+
+		case "textDocument/selectionRange":
+			req, err := read[lspSelectionRangeRequest](b)
+			if err != nil {
+				return err
 			}
 
-			{
-				kind := "quickfix"
-				for _, v := range res.Versions {
-					if teal.Overlaps(req.Params.Range, v) {
-						cas = append(cas, lspCodeAction{
-							Title: fmt.Sprintf("Update version to %d", v.Version),
-							Kind:  &kind,
-							Command: &lspCommand{
-								Title:   "Update version",
-								Command: "teal.version.update",
-								Arguments: []interface{}{
-									tealUpdateVersion{
-										Uri:     req.Params.TextDocument.Uri,
-										Version: v.Version,
-									},
-								},
-							},
-						})
-					}
-				}
+			doc, res, err := l.prepare(req.Params.TextDocument.Uri)
+			if err != nil {
+				return err
 			}
-			return l.success(h.Id, cas)
-		case "textDocument/diagnostic":
-			req, err := read[lspDiagnosticRequest](b)
+
+			lines := splitLines(doc.s)
+
+			srs := []*lspSelectionRange{}
+			for _, p := range req.Params.Positions {
+				srs = append(srs, selectionRange(res, lines, p))
+			}
+
+			return l.success(h.Id, srs)
+
+		case "textDocument/documentColor":
+			req, err := read[lspDocumentColorRequest](b)
+			if err != nil {
+				return err
+			}
+
+			_, res, err := l.prepare(req.Params.TextDocument.Uri)
 			if err != nil {
 				return err
 			}
 
-			doc := l.docs[req.Params.TextDocument.Uri]
+			return l.success(h.Id, documentColors(res))
 
-			var ds []lspDiagnostic
-			if doc != nil {
-				ds = l.doDiagnostic(doc)
-			} else {
-				ds = []lspDiagnostic{}
+		case "textDocument/colorPresentation":
+			req, err := read[lspColorPresentationRequest](b)
+			if err != nil {
+				return err
 			}
 
-			return l.success(h.Id, lspFullDocumentDiagnosticReport{
-				Kind:  "full",
-				Items: ds,
-			})
+			return l.success(h.Id, colorPresentations(req.Params.Color, req.Params.Range))
 
 		case "textDocument/documentHighlight":
 			req, err := read[lspDocumentHighlightRequest](b)
@@ -1874,29 +2735,16 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			_, res, err := l.prepare(req.Params.TextDocument.Uri)
-			if err != nil {
-				return err
+			doc := l.doc(req.Params.TextDocument.Uri)
+			if doc == nil {
+				return errors.New("doc not found")
 			}
 
-			syms := []lspDocumentSymbol{}
-			for _, s := range res.Symbols {
-				r := lspRange{
-					Start: lspPosition{
-						Line:      s.Line(),
-						Character: s.Begin(),
-					},
-					End: lspPosition{
-						Line:      s.Line(),
-						Character: s.End(),
-					},
-				}
-				syms = append(syms, lspDocumentSymbol{
-					Name:           s.Name(),
-					Kind:           lspSymbolKindMethod,
-					Range:          r,
-					SelectionRange: r,
-				})
+			snap := doc.Snapshot()
+
+			syms := l.cachedSymbols(req.Params.TextDocument.Uri, snap)
+			if syms == nil {
+				syms = documentSymbols(snap.Results())
 			}
 
 			return l.success(h.Id, syms)
@@ -1907,11 +2755,16 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			_, res, err := l.prepare(req.Params.TextDocument.Uri)
+			snap, res, err := l.prepare(req.Params.TextDocument.Uri)
 			if err != nil {
 				return err
 			}
 
+			// the tokens can be disabled after the initialization
+			if !l.config.SemanticTokens {
+				return l.success(h.Id, lspSemanticTokens{Data: []uint32{}})
+			}
+
 			st := teal.SemanticTokens{}
 
 			for _, m := range res.Macros {
@@ -1999,6 +2852,8 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				})
 			}
 
+			encodeSemanticTokens(l.encoder(), textLines(snap.s), st)
+
 			data := st.Encode()
 
 			return l.success(h.Id, lspSemanticTokens{
@@ -2011,21 +2866,27 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 				return err
 			}
 
-			if req.Params != nil {
-				if req.Params.InitializationOptions != nil {
-					if req.Params.InitializationOptions.SemanticTokens != nil {
-						l.config.SemanticTokens = *req.Params.InitializationOptions.SemanticTokens
-					}
-					if req.Params.InitializationOptions.InlayNamed != nil {
-						l.config.InlayNamed = *req.Params.InitializationOptions.InlayNamed
-					}
-					if req.Params.InitializationOptions.InlayDecoded != nil {
-						l.config.InlayDecoded = *req.Params.InitializationOptions.InlayDecoded
-					}
-					if req.Params.InitializationOptions.LensRefs != nil {
-						l.config.LensRefs = *req.Params.InitializationOptions.LensRefs
-					}
-				}
+			if req.Params != nil && req.Params.InitializationOptions != nil {
+				l.configure(*req.Params.InitializationOptions)
+			}
+
+			var encodings []string
+			if req.Params.Capabilities != nil && req.Params.Capabilities.General != nil {
+				encodings = req.Params.Capabilities.General.PositionEncodings
+			}
+			l.encoding = negotiateEncoding(encodings)
+
+			if req.Params.Capabilities != nil && req.Params.Capabilities.Workspace != nil {
+				l.refresh = req.Params.Capabilities.Workspace.refreshes()
+			}
+
+			l.folders = req.Params.WorkspaceFolders
+			if len(l.folders) == 0 && req.Params.RootUri != nil && *req.Params.RootUri != "" {
+				l.folders = []lspWorkspaceFolder{{Uri: *req.Params.RootUri}}
+			}
+
+			if l.indexDir != "" && len(l.folders) > 0 {
+				l.index = loadIndex(indexPath(l.indexDir, l.folders))
 			}
 
 			sync := new(int)
@@ -2034,6 +2895,9 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			definition := new(bool)
 			*definition = true
 
+			references := new(bool)
+			*references = true
+
 			symbol := new(bool)
 			*symbol = true
 
@@ -2056,13 +2920,22 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 			*hover = true
 
 			inlayHint := new(bool)
-			if l.config.InlayNamed || l.config.InlayDecoded {
+			if l.config.InlayNamed || l.config.InlayDecoded || l.config.InlayAddress || l.config.InlayAbi || l.config.InlayStackDepth {
 				*inlayHint = true
 			}
 
 			inlineValue := new(bool)
 			*inlineValue = true
 
+			color := new(bool)
+			*color = true
+
+			selection := new(bool)
+			*selection = true
+
+			workspaceSymbol := new(bool)
+			*workspaceSymbol = true
+
 			var semanticTokensProvider *lspSemanticTokensProvider
 
 			if l.config.SemanticTokens {
@@ -2077,11 +2950,16 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 
 			return l.success(h.Id, lspInitializeResult{
 				Capabilities: &lspServerCapabilities{
+					PositionEncoding:          l.encoding,
 					TextDocumentSync:          sync,
 					DocumentHighlightProvider: highlight,
-					DiagnosticProvider:        &lspDiagnosticProvider{},
-					DocumentSymbolProvider:    symbol,
-					CodeActionProvider:        action,
+					DiagnosticProvider: &lspDiagnosticProvider{
+						InterFileDependencies: true,
+						WorkspaceDiagnostics:  l.index != nil,
+					},
+					WorkspaceSymbolProvider: workspaceSymbol,
+					DocumentSymbolProvider:  symbol,
+					CodeActionProvider:      action,
 					ExecuteCommandProvider: &lspExecuteCommandProvider{
 						Commands: []string{
 							"teal.label.create",
@@ -2089,6 +2967,11 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 							"teal.value.replace",
 							"teal.line.remove",
 							"teal.version.update",
+							"teal.server.stats",
+							"teal.server.usage",
+							"teal.tests.list",
+							"teal.tests.run",
+							"teal.source.open",
 						},
 					},
 					RenameProvider: &lspRenameOptions{
@@ -2100,15 +2983,31 @@ func (l *lsp) handle(h jsonRpcHeader, b []byte) error {
 					},
 					DocumentFormattingProvider: formatting,
 					DefinitionProvider:         definition,
+					ReferencesProvider:         references,
 					HoverProvider:              hover,
-					SignatureHelpProvider:      &lspSignatureHelpOptions{},
-					InlayHintProvider:          inlayHint,
-					InlineValueProvider:        inlineValue,
-					CodeLensProvider:           &lspCodeLensProvider{},
+					SignatureHelpProvider: &lspSignatureHelpOptions{
+						TriggerCharacters:   []string{" "},
+						RetriggerCharacters: []string{" "},
+					},
+					InlayHintProvider:      inlayHint,
+					InlineValueProvider:    inlineValue,
+					CodeLensProvider:       &lspCodeLensProvider{},
+					ColorProvider:          color,
+					SelectionRangeProvider: selection,
+					Workspace: &lspWorkspaceServerCapabilities{
+						WorkspaceFolders: &lspWorkspaceFoldersServerCapabilities{
+							Supported:           true,
+							ChangeNotifications: true,
+						},
+					},
+					DocumentOnTypeFormattingProvider: &lspDocumentOnTypeFormattingOptions{
+						FirstTriggerCharacter: ":",
+						MoreTriggerCharacter:  []string{"\n"},
+					},
 				},
 			})
 		default:
-			return errors.New("unknown method")
+			return l.unknown(h)
 		}
 	}
 
@@ -2121,7 +3020,10 @@ func (l *lsp) write(v interface{}) error {
 		return errors.Wrap(err, "failed to marshal response")
 	}
 
-	l.trace(fmt.Sprintf("OUT: %s", string(rb)))
+	rb = l.convertPositions(rb, l.uri, l.encoder())
+
+	l.stats.send(len(rb))
+	l.log.Debug("out", "body", string(rb))
 
 	h := http.Header{}
 	h.Set("Content-Length", strconv.Itoa(len(rb)))
@@ -2149,65 +3051,67 @@ func (l *lsp) write(v interface{}) error {
 	return nil
 }
 
-func (l *lsp) trace(s string) {
-	if l.debug == nil {
-		return
+// readMessage reads the body of the next message.
+func (l *lsp) readMessage() ([]byte, error) {
+	mh, err := l.tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read request headers")
+	}
+
+	h := http.Header(mh)
+
+	length, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse content length")
 	}
 
-	l.debug.WriteString(s)
-	l.debug.WriteString("\n")
+	if length < 0 || length > maxContentLength {
+		return nil, errors.Errorf("invalid content length: %d", length)
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(l.tp.R, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read content body")
+	}
 
-	l.debug.Flush()
+	l.stats.receive(len(data))
+	l.log.Debug("in", "body", string(data))
+
+	return data, nil
 }
 
 func (l *lsp) Run() (int, error) {
-	l.trace("TEAL LSP running..")
+	l.log.Info("TEAL LSP running")
 	defer func() {
-		l.trace("TEAL LSP exited.")
+		l.log.Info("TEAL LSP exited", "code", l.exitCode)
 	}()
 
-	for !l.exit {
-		err := func() error {
-			mh, err := l.tp.ReadMIMEHeader()
-			if err != nil {
-				return errors.Wrap(err, "failed to read request headers")
-			}
-
-			h := http.Header(mh)
-
-			length, err := strconv.Atoi(h.Get("Content-Length"))
-			if err != nil {
-				return errors.Wrap(err, "failed to parse content length")
-			}
+	done := make(chan struct{})
+	defer close(done)
 
-			data := make([]byte, length)
-			_, err = io.ReadFull(l.tp.R, data)
-			if err != nil {
-				return errors.Wrap(err, "failed to read content body")
-			}
+	msgs := make(chan lspMessage, 64)
+	go l.receive(msgs, done)
 
-			l.trace(fmt.Sprintf("IN: %s", string(data)))
+	for !l.exit {
+		msg, ok := <-msgs
+		if !ok {
+			break
+		}
 
-			var jh jsonRpcHeader
-			err = json.Unmarshal(data, &jh)
-			if err != nil {
-				return errors.Wrap(err, "failed to unmarshal json rpc header")
-			}
+		err := msg.err
+		if err == nil {
+			l.ctx = msg.ctx
+			err = l.serve(msg.data)
+			l.ctx = nil
 
-			err = l.handle(jh, data)
-			if err != nil {
-				return errors.Wrap(err, "failed to handle request")
+			if msg.ctx != nil {
+				l.untrack(msg.id)
 			}
-
-			return nil
-		}()
+		}
 
 		if err != nil {
-			l.trace(fmt.Sprintf("ERR: %s", err))
-
-			if errors.Is(err, io.EOF) {
-				break
-			}
+			l.log.Error("failed to serve", "err", err)
 		}
 	}
 
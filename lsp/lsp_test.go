@@ -0,0 +1,746 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+func testOpen(l *lsp, uri string, s string) {
+	l.open(uri).Update(s)
+}
+
+func TestSymbolLocationsAcrossIncludes(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := "file:///src/main.teal"
+	lib := "file:///src/lib.teal"
+
+	testOpen(l, lib, "double:\nretsub")
+	testOpen(l, main, "#pragma version 8\n#include \"lib.teal\"\ncallsub double\nint 1\nreturn")
+
+	_, res, err := l.prepare(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := l.symbolLocations(main, res, "double", true)
+	if len(ls) != 2 {
+		t.Fatalf("unexpected locations count: %d", len(ls))
+	}
+
+	if ls[0].Uri != main || ls[0].Range.Start.Line != 2 {
+		t.Errorf("unexpected reference location: %v", ls[0])
+	}
+
+	if ls[1].Uri != lib || ls[1].Range.Start.Line != 0 {
+		t.Errorf("unexpected definition location: %v", ls[1])
+	}
+
+	_, res, err = l.prepare(lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls = l.symbolLocations(lib, res, "double", false)
+	if len(ls) != 1 || ls[0].Uri != main {
+		t.Errorf("expected the includer reference, got: %v", ls)
+	}
+}
+
+func TestLifecycleTitle(t *testing.T) {
+	res := teal.Process("#pragma version 8\ntxn OnCompletion\nint OptIn\n==\nreturn")
+
+	s := lifecycleTitle(teal.AnalyzeLifecycle(res))
+	if s != "approves on create: OptIn; call: OptIn" {
+		t.Errorf("unexpected title: %s", s)
+	}
+}
+
+func TestSizeTitle(t *testing.T) {
+	res := teal.Process("#pragma version 8\nint 1\nreturn")
+
+	s := sizeTitle(res, tealConfig{ExtraPages: 1})
+	if s != "size: 4/4096 bytes, cost: 2/700" {
+		t.Errorf("unexpected title: %s", s)
+	}
+
+	s = sizeTitle(res, tealConfig{Mode: "sig"})
+	if s != "size: 4/1000 bytes, cost: 2/20000" {
+		t.Errorf("unexpected title: %s", s)
+	}
+}
+
+func TestGroupHover(t *testing.T) {
+	res := teal.Process("#pragma version 8\ngtxn 0 TypeEnum\nint pay\n==\nassert\nint 1")
+
+	s := groupHover(res, 1, 1)
+	if s != "expects tx0: pay" {
+		t.Errorf("unexpected hover: %s", s)
+	}
+
+	if s := groupHover(res, 2, 1); s != "" {
+		t.Errorf("unexpected hover: %s", s)
+	}
+}
+
+func TestAddressHover(t *testing.T) {
+	doc := newLspDoc("app.teal", nil, teal.WithKnownAddresses(teal.KnownAddress{Address: "7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q", Name: "treasury"}))
+	doc.Update("#pragma version 8\naddr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q\naddr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA\n==")
+
+	res := doc.Results()
+
+	a, ok := res.AddressAt(1, 10)
+	if !ok || addressHover(a.Address) != "Known address: treasury" {
+		t.Errorf("unexpected address: %+v", a)
+	}
+
+	a, ok = res.AddressAt(2, 10)
+	if !ok || addressHover(a.Address) != "Known address: MainNet fee sink - the sent funds can not be recovered" {
+		t.Errorf("unexpected address: %+v", a)
+	}
+}
+
+func TestAbiHover(t *testing.T) {
+	res := teal.Process("#pragma version 8\nbyte 0x00000000000f4240000a000568656c6c6f\npop\nint 1")
+
+	c, ok := res.AbiConstantAt(1, 10)
+	if !ok {
+		t.Fatal("expected an ABI constant")
+	}
+
+	if s := abiHover(c); s != "ABI encoded (uint64,string): (uint64 1000000, string \"hello\")" {
+		t.Errorf("unexpected hover: %s", s)
+	}
+}
+
+func TestStateSymbol(t *testing.T) {
+	res := teal.Process("#pragma version 8\nbyte \"a\"\nint 1\napp_global_put\nbyte \"a\"\napp_global_get")
+
+	sym, ok := keysSymbol(res, "State", teal.AnalyzeState(res).Keys)
+	if !ok || len(sym.Children) != 1 {
+		t.Fatalf("unexpected state symbol: %+v", sym)
+	}
+
+	if sym.Children[0].Name != "a" || sym.Children[0].Detail != "global uint64 read/write" {
+		t.Errorf("unexpected key symbol: %+v", sym.Children[0])
+	}
+
+	if sym.Range.Start.Line != 3 || sym.Range.End.Line != 5 {
+		t.Errorf("unexpected state range: %+v", sym.Range)
+	}
+}
+
+func TestOpCompletions(t *testing.T) {
+	res := teal.Process("#pragma version 5\nint 1\nbyte \"a\"\n")
+
+	ccs := opCompletions(res, teal.ModeApp, 3, "")
+	if len(ccs) < 2 || ccs[0].Label != "byte" || ccs[1].Label != "int" {
+		t.Fatalf("expected the recent ops first, got: %v", ccs[:2])
+	}
+
+	ranks := map[string]string{}
+	for _, c := range ccs {
+		ranks[c.Label] = c.SortText
+	}
+
+	if _, ok := ranks["arg"]; ok {
+		t.Error("unexpected logicsig op in app mode")
+	}
+
+	if !(ranks["extract"] < ranks["substring"] && ranks["substring"] < ranks["proto"]) {
+		t.Errorf("unexpected ranking: extract %s, substring %s, proto %s", ranks["extract"], ranks["substring"], ranks["proto"])
+	}
+
+	for _, c := range ccs {
+		if c.Label == "substring" && (c.Deprecated == nil || !*c.Deprecated || len(c.Tags) != 1) {
+			t.Errorf("expected substring to be deprecated: %+v", c)
+		}
+	}
+
+	for _, c := range opCompletions(res, teal.ModeApp, 3, "ext") {
+		if !strings.HasPrefix(c.Label, "ext") {
+			t.Errorf("unexpected completion for prefix: %s", c.Label)
+		}
+	}
+}
+
+func TestStdlibCompletions(t *testing.T) {
+	res := teal.Process("#pragma version 8\n")
+
+	for _, c := range opCompletions(res, teal.ModeApp, 1, "muld") {
+		if c.Label == "muldiv" {
+			if c.Kind == nil || *c.Kind != lspCompletionItemKindSnippet || !strings.Contains(c.InsertText, "mulw") {
+				t.Errorf("unexpected muldiv completion: %+v", c)
+			}
+			return
+		}
+	}
+
+	t.Error("expected muldiv")
+}
+
+func TestArgCompletions(t *testing.T) {
+	res := teal.Process("#pragma version 8\ntxn Se")
+
+	ln := res.LineAt(1, 6)
+
+	ccs := argCompletions(res, ln, 1, 6)
+	if len(ccs) == 0 {
+		t.Fatal("expected completions")
+	}
+
+	found := false
+	for _, c := range ccs {
+		if !strings.HasPrefix(c.Label, "Se") {
+			t.Errorf("unexpected completion for prefix: %s", c.Label)
+		}
+
+		if c.Label == "Sender" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Error("expected Sender")
+	}
+}
+
+func TestSignatureHelpArray(t *testing.T) {
+	res := teal.Process("#pragma version 8\nint 0\nswitch a b c \na:\nb:\nc:\nint 1")
+
+	type test struct {
+		ch     int
+		active int
+		label  string
+	}
+
+	tests := []test{
+		{7, 0, "label 1 of 3"},
+		{9, 1, "label 2 of 3"},
+		{11, 2, "label 3 of 3"},
+		{12, 2, "label 3 of 3"},
+		{13, 3, "label 4 of 4"},
+	}
+
+	for _, test := range tests {
+		h := signatureHelp(res, 2, test.ch)
+		if h == nil || len(h.Signatures) != 1 {
+			t.Fatalf("expected signature help at %d", test.ch)
+		}
+
+		s := h.Signatures[0]
+		if *s.ActiveParameter != test.active {
+			t.Errorf("unexpected active parameter at %d: %d, expected: %d", test.ch, *s.ActiveParameter, test.active)
+			continue
+		}
+
+		r := s.Parameters[test.active].Label.([]int)
+		if l := s.Label[r[0]:r[1]]; l != test.label {
+			t.Errorf("unexpected parameter label at %d: %s, expected: %s", test.ch, l, test.label)
+		}
+	}
+}
+
+func TestDocumentColors(t *testing.T) {
+	res := teal.Process("#pragma version 8\nbyte 0xff0000\npushbytes 0x00ff0080\nbyte 0x0102\nbyte \"abc\"")
+
+	cs := documentColors(res)
+	if len(cs) != 2 {
+		t.Fatalf("unexpected colors: %v", cs)
+	}
+
+	if cs[0].Color != (lspColor{Red: 1, Alpha: 1}) || cs[0].Range.Start.Line != 1 || cs[0].Range.Start.Character != 5 || cs[0].Range.End.Character != 13 {
+		t.Errorf("unexpected color: %+v", cs[0])
+	}
+
+	if cs[1].Color.Green != 1 || cs[1].Color.Alpha != float64(0x80)/255 {
+		t.Errorf("unexpected color: %+v", cs[1])
+	}
+
+	ps := colorPresentations(cs[0].Color, cs[0].Range)
+	if len(ps) != 2 || ps[0].Label != "0xff0000" || ps[1].Label != "0xff0000ff" {
+		t.Errorf("unexpected presentations: %v", ps)
+	}
+
+	ps = colorPresentations(cs[1].Color, cs[1].Range)
+	if len(ps) != 1 || ps[0].Label != "0x00ff0080" || ps[0].TextEdit.Range != cs[1].Range {
+		t.Errorf("unexpected presentations: %v", ps)
+	}
+}
+
+func TestSelectionRange(t *testing.T) {
+	src := "#pragma version 8\ncallsub sub\nreturn\nsub:\nint 0\nswitch a b\na:\nb:\nretsub"
+	res := teal.Process(src)
+
+	sr := selectionRange(res, splitLines(src), lspPosition{Line: 5, Character: 8})
+
+	expected := []lspRange{
+		{Start: lspPosition{Line: 5, Character: 7}, End: lspPosition{Line: 5, Character: 8}},
+		{Start: lspPosition{Line: 5, Character: 7}, End: lspPosition{Line: 5, Character: 10}},
+		{Start: lspPosition{Line: 5, Character: 0}, End: lspPosition{Line: 5, Character: 10}},
+		{Start: lspPosition{Line: 3, Character: 0}, End: lspPosition{Line: 5, Character: 10}},
+		{Start: lspPosition{Line: 3, Character: 0}, End: lspPosition{Line: 8, Character: 6}},
+	}
+
+	for i, r := range expected {
+		if sr == nil {
+			t.Fatalf("missing range %d", i)
+		}
+
+		if sr.Range != r {
+			t.Errorf("unexpected range %d: %v, expected: %v", i, sr.Range, r)
+		}
+
+		sr = sr.Parent
+	}
+
+	if sr != nil {
+		t.Errorf("unexpected range: %v", sr.Range)
+	}
+}
+
+func TestOnTypeFormat(t *testing.T) {
+	type test struct {
+		src    string
+		p      lspPosition
+		ch     string
+		column int
+		result string
+	}
+
+	tests := []test{
+		{"#pragma version 8\n\tmain:", lspPosition{Line: 1, Character: 6}, ":", 0, "#pragma version 8\nmain:"},
+		{"main:\nint 1\n", lspPosition{Line: 2}, "\n", 0, "main:\nint 1\n\t"},
+		{"#pragma version 8\n  int 1", lspPosition{Line: 1, Character: 2}, "\n", 0, "#pragma version 8\nint 1"},
+		{"main:\n\tint 1 // one\n", lspPosition{Line: 2}, "\n", 12, "main:\n\tint 1      // one\n\t"},
+		{"main:\n\tint 1      // one\n\t", lspPosition{Line: 2, Character: 1}, "\n", 4, "main:\n\tint 1 // one\n\t"},
+		{"main:\n\tbyte \"//\"\n\t", lspPosition{Line: 2, Character: 1}, "\n", 40, "main:\n\tbyte \"//\"\n\t"},
+	}
+
+	for i, test := range tests {
+		lines := splitLines(test.src)
+
+		edits := onTypeFormat(lines, test.p, test.ch, test.column)
+
+		// the edits are applied from the last one as they do not overlap
+		for j := len(edits) - 1; j >= 0; j-- {
+			e := edits[j]
+			s := lines[e.Range.Start.Line]
+			lines[e.Range.Start.Line] = s[:e.Range.Start.Character] + e.NewText + s[e.Range.End.Character:]
+		}
+
+		if r := strings.Join(lines, "\n"); r != test.result {
+			t.Errorf("test %d: unexpected result: %q, expected: %q", i, r, test.result)
+		}
+	}
+}
+
+func TestProcessOptions(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{}, WithProcessOptions(teal.WithAnalyzer(func(res *teal.ProcessResult) {
+		res.Diagnostics = append(res.Diagnostics, teal.NewDiagnostic(1, 0, 5, teal.DiagInfo, "CUSTOM0001", "custom check"))
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "file:///a.teal"
+	testOpen(l, uri, "#pragma version 8\nint 1\nreturn")
+
+	_, res, err := l.prepare(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, d := range l.toDiagnostics(res) {
+		if d.Message == "custom check" && d.Range.Start.Line == 1 {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("expected the analyzer diagnostic, got: %v", res.Diagnostics)
+	}
+}
+
+func TestDidChangeConfiguration(t *testing.T) {
+	var out bytes.Buffer
+
+	l, err := New(&bytes.Buffer{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.refresh = lspRefresh{InlayHint: true, Diagnostics: true}
+
+	uri := "file:///a.teal"
+	testOpen(l, uri, "#pragma version 8\nunused:\nint 1\nreturn")
+
+	has := func() bool {
+		_, res, err := l.prepare(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range res.Diagnostics {
+			if d.Rule() == "LINT0002" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !has() {
+		t.Fatal("expected the unused label diagnostic")
+	}
+
+	snap := l.doc(uri).Snapshot()
+
+	err = l.reconfigure([]byte(`{"teal":{"inlayNamed":false}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.w.Flush()
+
+	if l.config.InlayNamed || l.doc(uri).Snapshot() != snap {
+		t.Error("expected the inlay option applied and the cached results kept")
+	}
+
+	if !strings.Contains(out.String(), "workspace/inlayHint/refresh") || strings.Contains(out.String(), "workspace/diagnostic/refresh") {
+		t.Errorf("unexpected refresh requests: %s", out.String())
+	}
+
+	err = l.reconfigure([]byte(`{"silence":["LINT0002"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.w.Flush()
+
+	if has() {
+		t.Error("expected the silenced diagnostic dropped")
+	}
+
+	if !strings.Contains(out.String(), "workspace/diagnostic/refresh") {
+		t.Errorf("expected the diagnostic refresh, got: %s", out.String())
+	}
+}
+
+func TestWorkspaceFolders(t *testing.T) {
+	root := t.TempDir()
+
+	for path, s := range map[string]string{
+		"a/lib/common.teal": "double:\nint 2\n*\nretsub",
+		"a/consts.json":     `{"FEE": 1000}`,
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := lspWorkspaceFolder{Uri: pathToUri(filepath.Join(root, "a")), Name: "a"}
+	b := lspWorkspaceFolder{Uri: pathToUri(filepath.Join(root, "b")), Name: "b"}
+
+	l.folders = []lspWorkspaceFolder{a, b}
+
+	err = l.reconfigure([]byte(`{"teal":{"folders":{"a":{"version":8,"includePaths":["lib"],"constants":"consts.json"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "#include \"common.teal\"\nint FEE\ncallsub double\nreturn"
+
+	inA := pathToUri(filepath.Join(root, "a", "src", "main.teal"))
+	inB := pathToUri(filepath.Join(root, "b", "main.teal"))
+
+	testOpen(l, inA, src)
+	testOpen(l, inB, src)
+
+	errs := func(uri string) int {
+		_, res, err := l.prepare(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := 0
+		for _, d := range res.Diagnostics {
+			if d.Severity() == teal.DiagErr {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	if n := errs(inA); n != 0 {
+		t.Errorf("expected the folder settings applied, got %d errors", n)
+	}
+
+	if errs(inB) == 0 {
+		t.Error("expected the errors without the folder settings")
+	}
+
+	if f, ok := l.folder(inA); !ok || f.Name != "a" {
+		t.Errorf("unexpected folder: %v", f)
+	}
+
+	err = l.changeFolders(nil, []lspWorkspaceFolder{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errs(inA) == 0 {
+		t.Error("expected the errors after the folder is removed")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+
+	src := "#pragma version 8\nb main\nmain:\nint 1\nreturn"
+
+	path := filepath.Join(root, "app.teal")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	folders := []lspWorkspaceFolder{{Uri: pathToUri(root), Name: "root"}}
+
+	start := func() *lsp {
+		l, err := New(&bytes.Buffer{}, &bytes.Buffer{}, WithIndex(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l.folders = folders
+		l.index = loadIndex(indexPath(dir, folders))
+
+		return l
+	}
+
+	l := start()
+	l.indexWorkspace()
+	<-l.index.done
+
+	syms := l.workspaceSymbols("MAI")
+	if len(syms) != 1 || syms[0].Name != "main" || syms[0].Location.Uri != pathToUri(path) {
+		t.Fatalf("unexpected workspace symbols: %+v", syms)
+	}
+
+	ds := l.workspaceDiagnostics(nil)
+	if len(ds.Items) != 1 || ds.Items[0].Kind != "full" {
+		t.Fatalf("unexpected workspace diagnostics: %+v", ds)
+	}
+
+	ds = l.workspaceDiagnostics([]lspPreviousResultId{{Uri: ds.Items[0].Uri, Value: ds.Items[0].ResultId}})
+	if len(ds.Items) != 1 || ds.Items[0].Kind != "unchanged" {
+		t.Errorf("expected the unchanged report, got: %+v", ds)
+	}
+
+	// the next session serves the persisted entries before processing the document
+	l = start()
+
+	uri := pathToUri(path)
+	testOpen(l, uri, src)
+
+	snap := l.doc(uri).Snapshot()
+	if syms := l.cachedSymbols(uri, snap); len(syms) != 1 || syms[0].Name != "main" {
+		t.Errorf("expected the cached symbols, got: %+v", syms)
+	}
+
+	if snap.processed() != nil {
+		t.Error("expected the document not processed")
+	}
+
+	testOpen(l, uri, src+"\nother:")
+	if l.cachedSymbols(uri, l.doc(uri).Snapshot()) != nil {
+		t.Error("expected no cached symbols of the changed document")
+	}
+}
+
+func TestUntitledDocument(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(root, "lib.teal"), []byte("double:\nint 2\n*\nretsub"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "untitled:Untitled-1"
+	src := `#pragma version 8\n#include \"lib.teal\"\nint 1\ncallsub double\nb missing`
+
+	in := testMessage(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"workspaceFolders":[{"uri":"`+pathToUri(root)+`","name":"root"}]}}`) +
+		testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"`+uri+`","text":"`+src+`"}}}`) +
+		testMessage(`{"jsonrpc":"2.0","id":2,"method":"textDocument/diagnostic","params":{"textDocument":{"uri":"`+uri+`"}}}`) +
+		testMessage(`{"jsonrpc":"2.0","id":3,"method":"textDocument/codeAction","params":{"textDocument":{"uri":"`+uri+`"},"range":{"start":{"line":4,"character":0},"end":{"line":4,"character":9}},"context":{"diagnostics":[]}}}`) +
+		testMessage(`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":{"textDocument":{"uri":"`+uri+`"},"position":{"line":3,"character":9}}}`) +
+		testMessage(`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"`+uri+`"}}}`)
+
+	out := &bytes.Buffer{}
+	l, err := New(bytes.NewBufferString(in), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.doc(uri) != nil {
+		t.Error("expected the document to be closed")
+	}
+
+	rs := map[string]testResponse{}
+	for _, r := range testResponses(t, out.Bytes()) {
+		if r.Id != nil {
+			rs[fmt.Sprint(r.Id)] = r
+		}
+	}
+
+	for _, id := range []string{"2", "3", "4"} {
+		if r, ok := rs[id]; !ok || r.Error != nil {
+			t.Fatalf("unexpected response %s: %+v", id, r)
+		}
+	}
+
+	var report lspFullDocumentDiagnosticReport
+	if err := json.Unmarshal(rs["2"].Result, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range report.Items {
+		if d.Severity != nil && *d.Severity == int(teal.DiagErr) && !strings.Contains(d.Message, "missing") {
+			t.Errorf("unexpected error: %s", d.Message)
+		}
+	}
+
+	if _, ok := report.RelatedDocuments[pathToUri(filepath.Join(root, "lib.teal"))]; !ok {
+		t.Errorf("expected the include resolved against the workspace folder, got: %v", report.RelatedDocuments)
+	}
+
+	var cas []lspCodeAction
+	if err := json.Unmarshal(rs["3"].Result, &cas); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, ca := range cas {
+		if ca.Edit == nil {
+			continue
+		}
+
+		for _, dc := range ca.Edit.DocumentChanges {
+			if dc.TextDocument.Uri == uri && len(dc.Edits) > 0 {
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("expected the create label action editing the untitled document, got: %+v", cas)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	usage := func(id int) string {
+		return testMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"workspace/executeCommand","params":{"command":"teal.server.usage","arguments":[{"path":%q}]}}`, id, path))
+	}
+
+	hover := func(id int) string {
+		return testMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":2,"character":0}}}`, id))
+	}
+
+	enable := func(on bool) string {
+		return testMessage(fmt.Sprintf(`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":{"teal":{"usage":%t}}}}`, on))
+	}
+
+	change := func(version int) string {
+		return testMessage(fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.teal","version":%d},"contentChanges":[{"text":"#pragma version 8\nunused:\nint %d"}]}}`, version, version))
+	}
+
+	// nothing is collected before the usage is enabled
+	in := testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.teal","text":"#pragma version 8\nunused:\nint 1"}}}`) +
+		hover(1) +
+		usage(2) +
+		enable(true) +
+		change(2) +
+		hover(3) +
+		hover(4) +
+		change(3) +
+		hover(5) +
+		usage(6) +
+		enable(false) +
+		usage(7)
+
+	out := &bytes.Buffer{}
+	l, err := New(bytes.NewBufferString(in), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 7 {
+		t.Fatalf("expected 7 responses, got: %d", len(rs))
+	}
+
+	if rs[1].Error == nil {
+		t.Error("expected the usage to be disabled by default")
+	}
+
+	if rs[6].Error == nil {
+		t.Error("expected the usage to be disabled again")
+	}
+
+	if rs[5].Error != nil {
+		t.Fatalf("unexpected error: %s", rs[5].Error.Message)
+	}
+
+	var u tealUsage
+	if err := json.Unmarshal(rs[5].Result, &u); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Requests["textDocument/hover"] != 3 || u.Commands["teal.server.usage"] != 1 || len(u.Errors) != 0 || u.Documents != 1 {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+
+	// the hits accumulate over the processings of the changes, the cached results are not counted again
+	if u.Rules[teal.UnusedLabelsRule{}.Id()] != 2 {
+		t.Errorf("expected the unused label hit, got: %v", u.Rules)
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fu tealUsage
+	if err := json.Unmarshal(bs, &fu); err != nil || fu.Documents != 1 {
+		t.Errorf("unexpected usage file: %s", bs)
+	}
+}
@@ -0,0 +1,123 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dragmz/teal"
+)
+
+// the label lines as recognized by the full formatter
+var labelLineRegex = regexp.MustCompile(`^\S+:($|\s+//)`)
+
+func isLabelLine(s string) bool {
+	return labelLineRegex.MatchString(strings.TrimSpace(s))
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// bodyIndent returns the indent of the instructions at the line like the full formatter does:
+// a tab after the first label, none before it.
+func bodyIndent(lines []string, line int) string {
+	for i := line - 1; i >= 0; i-- {
+		if isLabelLine(lines[i]) {
+			return "\t"
+		}
+	}
+
+	return ""
+}
+
+func replaceLeading(line int, s string, indent string) []lspTextEdit {
+	ws := leadingSpace(s)
+	if ws == indent {
+		return nil
+	}
+
+	return []lspTextEdit{
+		{
+			Range: lspRange{
+				Start: lspPosition{Line: line},
+				End:   lspPosition{Line: line, Character: len(ws)},
+			},
+			NewText: indent,
+		},
+	}
+}
+
+// alignComment moves the trailing comment of the line to the column or one space after the code if it is longer.
+func alignComment(line int, s string, column int) []lspTextEdit {
+	z := teal.Lexer{Source: []byte(s)}
+
+	var code *teal.Token
+	var comment *teal.Token
+
+	for z.Scan() {
+		t := z.Curr()
+
+		switch t.Type() {
+		case teal.TokenComment:
+			comment = &t
+		case teal.TokenEol:
+		default:
+			if comment == nil {
+				code = &t
+			}
+		}
+	}
+
+	if code == nil || comment == nil {
+		return nil
+	}
+
+	pad := column - code.End()
+	if pad < 1 {
+		pad = 1
+	}
+
+	text := strings.Repeat(" ", pad)
+	if s[code.End():comment.Begin()] == text {
+		return nil
+	}
+
+	return []lspTextEdit{
+		{
+			Range: lspRange{
+				Start: lspPosition{Line: line, Character: code.End()},
+				End:   lspPosition{Line: line, Character: comment.Begin()},
+			},
+			NewText: text,
+		},
+	}
+}
+
+// onTypeFormat outdents the label typed with ":" and on a new line indents it as a label body instruction
+// and aligns the trailing comment of the previous line to the comment column, if set.
+func onTypeFormat(lines []string, p lspPosition, ch string, commentColumn int) []lspTextEdit {
+	edits := []lspTextEdit{}
+
+	if p.Line >= len(lines) {
+		return edits
+	}
+
+	switch ch {
+	case ":":
+		s := lines[p.Line]
+		if isLabelLine(s) {
+			edits = append(edits, replaceLeading(p.Line, s, "")...)
+		}
+	case "\n":
+		if p.Line > 0 && commentColumn > 0 {
+			edits = append(edits, alignComment(p.Line-1, lines[p.Line-1], commentColumn)...)
+		}
+
+		s := lines[p.Line]
+		if !isLabelLine(s) {
+			edits = append(edits, replaceLeading(p.Line, s, bodyIndent(lines, p.Line))...)
+		}
+	}
+
+	return edits
+}
@@ -0,0 +1,338 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JSON-RPC error codes
+const (
+	jsonRpcParseError     = -32700
+	jsonRpcInvalidRequest = -32600
+	jsonRpcMethodNotFound = -32601
+	jsonRpcInvalidParams  = -32602
+	jsonRpcInternalError  = -32603
+
+	lspRequestCancelled = -32800
+)
+
+// maxContentLength limits the size of a single message body.
+const maxContentLength = 64 * 1024 * 1024
+
+// rpcError is the error replied to the failed request with the JSON-RPC error code.
+type rpcError struct {
+	code int
+	err  error
+}
+
+func (e rpcError) Error() string {
+	return e.err.Error()
+}
+
+func (e rpcError) Unwrap() error {
+	return e.err
+}
+
+func invalidParams(err error) error {
+	return rpcError{code: jsonRpcInvalidParams, err: errors.Wrap(err, "invalid params")}
+}
+
+func methodNotFound(method string) error {
+	return rpcError{code: jsonRpcMethodNotFound, err: errors.Errorf("unknown method: %s", method)}
+}
+
+// validator checks the required fields of the decoded params.
+type validator interface {
+	validate() error
+}
+
+func (r lspDidOpen) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	return nil
+}
+
+func (r lspDidChange) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	for _, ch := range r.Params.ContentChanges {
+		if ch == nil {
+			return errors.New("missing content change")
+		}
+	}
+
+	return nil
+}
+
+func (r lspDidSave) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	return nil
+}
+
+func (r lspDidChangeConfiguration) validate() error {
+	if r.Params == nil {
+		return errors.New("missing params")
+	}
+
+	return nil
+}
+
+func (r lspDidChangeWorkspaceFolders) validate() error {
+	if r.Params == nil {
+		return errors.New("missing params")
+	}
+
+	return nil
+}
+
+func (r lspDidCloseRequest) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	return nil
+}
+
+func (r lspDiagnosticRequest) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	return nil
+}
+
+func (r lspDocumentSymbolRequest) validate() error {
+	if r.Params.TextDocument == nil {
+		return errors.New("missing textDocument")
+	}
+
+	return nil
+}
+
+// toLspError converts the handler error to the JSON-RPC error object.
+func toLspError(err error) lspError {
+	var re rpcError
+	if errors.As(err, &re) {
+		return lspError{Code: re.code, Message: re.Error()}
+	}
+
+	return lspError{Code: jsonRpcInternalError, Message: err.Error()}
+}
+
+// validId reports whether the id is a string, a number or null.
+func validId(id interface{}) bool {
+	switch id.(type) {
+	case nil, string, float64:
+		return true
+	}
+
+	return false
+}
+
+// isRequest reports whether the message expects a response.
+func isRequest(h jsonRpcHeader) bool {
+	return h.Method != "" && h.Id != nil
+}
+
+// serve handles the message body, replying with the JSON-RPC error object to the requests that fail.
+func (l *lsp) serve(data []byte) error {
+	var h jsonRpcHeader
+
+	err := json.Unmarshal(data, &h)
+	if err != nil {
+		code := jsonRpcParseError
+		if json.Valid(data) {
+			code = jsonRpcInvalidRequest
+		}
+
+		ferr := l.fail(nil, lspError{Code: code, Message: err.Error()})
+		if ferr != nil {
+			return errors.Wrap(ferr, "failed to reply with error")
+		}
+
+		return errors.Wrap(err, "failed to unmarshal json rpc header")
+	}
+
+	if isRequest(h) && l.cancelled() {
+		return l.fail(h.Id, lspError{Code: lspRequestCancelled, Message: "request cancelled"})
+	}
+
+	if !validId(h.Id) || (l.strict && h.JsonRpc != "2.0") {
+		ferr := l.fail(nil, lspError{Code: jsonRpcInvalidRequest, Message: "invalid request"})
+		if ferr != nil {
+			return errors.Wrap(ferr, "failed to reply with error")
+		}
+
+		return errors.New("invalid request")
+	}
+
+	l.uri = requestUri(data)
+	defer func() {
+		l.uri = ""
+	}()
+
+	data = l.convertPositions(data, l.uri, l.decoder())
+
+	start := time.Now()
+	size, cache := l.docStats(l.uri)
+
+	err = l.recoverHandle(h, data)
+	l.observe(h, time.Since(start), size, cache, err)
+
+	if err != nil {
+		if isRequest(h) {
+			ferr := l.fail(h.Id, toLspError(err))
+			if ferr != nil {
+				return errors.Wrap(ferr, "failed to reply with error")
+			}
+		}
+
+		return errors.Wrap(err, "failed to handle request")
+	}
+
+	return nil
+}
+
+// recoverHandle handles the message converting a panic of the handler to the internal error, so a single malformed
+// document does not stop the server. The stack is logged for the bug reports.
+func (l *lsp) recoverHandle(h jsonRpcHeader, data []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			l.log.Error("handler panic", "method", h.Method, "uri", l.uri, "panic", p, "stack", string(debug.Stack()))
+			err = rpcError{code: jsonRpcInternalError, err: errors.Errorf("internal error: %v", p)}
+		}
+	}()
+
+	return l.handle(h, data)
+}
+
+// unknown handles the message of unknown method - the strict mode rejects it while
+// the default mode ignores the notifications and replies to the requests with null.
+func (l *lsp) unknown(h jsonRpcHeader) error {
+	if l.strict {
+		l.log.Warn("unknown method", "method", h.Method)
+		return methodNotFound(h.Method)
+	}
+
+	if h.Id == nil {
+		return nil
+	}
+
+	return l.success(h.Id, json.RawMessage("null"))
+}
+
+type lspCancelParams struct {
+	Id interface{} `json:"id"`
+}
+
+type lspCancelRequest lspRequest[*lspCancelParams]
+
+// lspMessage is the message read in the background, ctx is set for the requests.
+type lspMessage struct {
+	data []byte
+	err  error
+
+	id  interface{}
+	ctx context.Context
+}
+
+// requestKey distinguishes the numeric and the string ids of the same value.
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// track registers the in-flight request, returning the context cancelled by $/cancelRequest.
+func (l *lsp) track(id interface{}) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l.reqsMu.Lock()
+	defer l.reqsMu.Unlock()
+
+	l.reqs[requestKey(id)] = cancel
+
+	return ctx
+}
+
+func (l *lsp) untrack(id interface{}) {
+	l.reqsMu.Lock()
+	defer l.reqsMu.Unlock()
+
+	key := requestKey(id)
+	if cancel, ok := l.reqs[key]; ok {
+		cancel()
+		delete(l.reqs, key)
+	}
+}
+
+func (l *lsp) cancelRequest(b []byte) error {
+	req, err := read[lspCancelRequest](b)
+	if err != nil {
+		return err
+	}
+
+	l.reqsMu.Lock()
+	defer l.reqsMu.Unlock()
+
+	if cancel, ok := l.reqs[requestKey(req.Params.Id)]; ok {
+		cancel()
+	}
+
+	return nil
+}
+
+// cancelled reports whether the request being served was cancelled.
+func (l *lsp) cancelled() bool {
+	return l.ctx != nil && l.ctx.Err() != nil
+}
+
+// receive reads the messages ahead of the requests being served so that the cancellations
+// are applied as soon as they arrive.
+func (l *lsp) receive(msgs chan<- lspMessage, done <-chan struct{}) {
+	defer close(msgs)
+
+	for {
+		var msg lspMessage
+
+		msg.data, msg.err = l.readMessage()
+		if msg.err == nil {
+			var h jsonRpcHeader
+			if json.Unmarshal(msg.data, &h) == nil {
+				if h.Method == "$/cancelRequest" {
+					err := l.cancelRequest(msg.data)
+					if err != nil {
+						l.log.Error("failed to cancel request", "err", err)
+					}
+					continue
+				}
+
+				if isRequest(h) && validId(h.Id) {
+					msg.id = h.Id
+					msg.ctx = l.track(h.Id)
+				}
+			}
+		}
+
+		select {
+		case msgs <- msg:
+		case <-done:
+			return
+		}
+
+		if errors.Is(msg.err, io.EOF) {
+			return
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+type testResponse struct {
+	Id     interface{}     `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *lspError       `json:"error"`
+
+	// set for the notifications
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func testResponses(t *testing.T, out []byte) []testResponse {
+	t.Helper()
+
+	var rs []testResponse
+
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(out)))
+	for {
+		mh, err := tp.ReadMIMEHeader()
+		if err == io.EOF {
+			return rs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		length, err := strconv.Atoi(http.Header(mh).Get("Content-Length"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := make([]byte, length)
+		_, err = io.ReadFull(tp.R, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r testResponse
+		err = json.Unmarshal(data, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rs = append(rs, r)
+	}
+}
+
+func testMessage(body string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func TestServeErrors(t *testing.T) {
+	type test struct {
+		name   string
+		body   string
+		strict bool
+		code   int
+		id     interface{}
+	}
+
+	tests := []test{
+		{
+			name: "parse error",
+			body: `{"jsonrpc":"2.0","id":1,"method":`,
+			code: jsonRpcParseError,
+		},
+		{
+			name: "wrong method type",
+			body: `{"jsonrpc":"2.0","id":1,"method":5}`,
+			code: jsonRpcInvalidRequest,
+		},
+		{
+			name: "object id",
+			body: `{"jsonrpc":"2.0","id":{"a":1},"method":"shutdown"}`,
+			code: jsonRpcInvalidRequest,
+		},
+		{
+			name: "wrong params type",
+			body: `{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":[]}}`,
+			code: jsonRpcInvalidParams,
+			id:   float64(2),
+		},
+		{
+			name: "doc not found",
+			body: `{"jsonrpc":"2.0","id":"x","method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"}}}`,
+			code: jsonRpcInternalError,
+			id:   "x",
+		},
+		{
+			name:   "unknown method strict",
+			body:   `{"jsonrpc":"2.0","id":3,"method":"teal/unknown"}`,
+			strict: true,
+			code:   jsonRpcMethodNotFound,
+			id:     float64(3),
+		},
+		{
+			name:   "jsonrpc version strict",
+			body:   `{"jsonrpc":"1.0","id":4,"method":"shutdown"}`,
+			strict: true,
+			code:   jsonRpcInvalidRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []LspOption
+			if test.strict {
+				opts = append(opts, WithStrict())
+			}
+
+			out := &bytes.Buffer{}
+			l, err := New(bytes.NewBufferString(testMessage(test.body)), out, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l.Run()
+
+			rs := testResponses(t, out.Bytes())
+			if len(rs) != 1 {
+				t.Fatalf("expected 1 response, got: %d", len(rs))
+			}
+
+			r := rs[0]
+			if r.Error == nil {
+				t.Fatalf("expected error, got result: %s", r.Result)
+			}
+
+			if r.Error.Code != test.code {
+				t.Errorf("expected code: %d, got: %d (%s)", test.code, r.Error.Code, r.Error.Message)
+			}
+
+			if r.Id != test.id {
+				t.Errorf("expected id: %v, got: %v", test.id, r.Id)
+			}
+		})
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	in := testMessage(`{"jsonrpc":"2.0","method":"teal/unknownNotification"}`) +
+		testMessage(`{"jsonrpc":"2.0","id":1,"method":"teal/unknown"}`)
+
+	out := &bytes.Buffer{}
+	l, err := New(bytes.NewBufferString(in), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Run()
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 1 {
+		t.Fatalf("expected 1 response, got: %d", len(rs))
+	}
+
+	if rs[0].Error != nil || string(rs[0].Result) != "null" {
+		t.Errorf("expected null result, got: %+v", rs[0])
+	}
+}
+
+func FuzzRun(f *testing.F) {
+	f.Add([]byte(testMessage(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)))
+	f.Add([]byte(testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.teal","text":"int 1"}}}`)))
+	f.Add([]byte("Content-Length: -1\r\n\r\n{}"))
+	f.Add([]byte("Content-Length: 100\r\n\r\n{\"jsonrpc\":\"2.0\""))
+	f.Add([]byte("Content-Length 5\r\n\r\nhello"))
+	f.Add([]byte("\r\n\r\n"))
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		l, err := New(bytes.NewReader(in), io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l.Run()
+	})
+}
+
+func FuzzServe(f *testing.F) {
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":0,"character":0}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":"1","method":"textDocument/completion","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":-1,"character":99}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":null,"method":"textDocument/semanticTokens/range","params":{"textDocument":{"uri":"file:///a.teal"},"range":{"start":{"line":5,"character":0},"end":{"line":0,"character":0}}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":[1],"method":"shutdown"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"method":"workspace/executeCommand","params":{"command":"teal.line.remove","arguments":[{"uri":"file:///a.teal","line":100}]}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		l, err := New(&bytes.Buffer{}, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testOpen(l, "file:///a.teal", "#pragma version 8\nint 1\nstore 0\nload 0\ncallsub f\nreturn\nf:\nproto 1 1\nframe_dig -1\nretsub")
+
+		l.serve(body)
+	})
+}
+
+func TestShutdown(t *testing.T) {
+	in := testMessage(`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`) +
+		testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.teal","text":"int 1"}}}`) +
+		testMessage(`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":0,"character":0}}}`) +
+		testMessage(`{"jsonrpc":"2.0","method":"exit"}`) +
+		testMessage(`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`)
+
+	out := &bytes.Buffer{}
+	l, err := New(bytes.NewBufferString(in), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := l.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != 0 {
+		t.Errorf("expected exit code 0, got: %d", code)
+	}
+
+	if l.doc("file:///a.teal") != nil {
+		t.Error("expected didOpen to be ignored after shutdown")
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 responses, got: %d", len(rs))
+	}
+
+	if rs[0].Error != nil || string(rs[0].Result) != "null" {
+		t.Errorf("expected null shutdown result, got: %+v", rs[0])
+	}
+
+	if rs[1].Error == nil || rs[1].Error.Code != jsonRpcInvalidRequest {
+		t.Errorf("expected invalid request error, got: %+v", rs[1])
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	l, err := New(bytes.NewBufferString(testMessage(`{"jsonrpc":"2.0","method":"exit"}`)), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := l.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != 1 {
+		t.Errorf("expected exit code 1, got: %d", code)
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	out := &bytes.Buffer{}
+	l, err := New(&bytes.Buffer{}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testOpen(l, "file:///a.teal", "#pragma version 8\nint 1")
+
+	hover := func(id string) []byte {
+		return []byte(`{"jsonrpc":"2.0","id":"` + id + `","method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.teal"},"position":{"line":1,"character":0}}}`)
+	}
+
+	serve := func(id string, cancel string) {
+		l.ctx = l.track(id)
+		defer func() {
+			l.untrack(id)
+			l.ctx = nil
+		}()
+
+		err := l.cancelRequest([]byte(`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":` + cancel + `}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l.serve(hover(id))
+	}
+
+	// the numeric id of the same value is a different request
+	serve("1", `1`)
+	serve("2", `"2"`)
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 responses, got: %d", len(rs))
+	}
+
+	if rs[0].Error != nil {
+		t.Errorf("expected result, got: %+v", rs[0].Error)
+	}
+
+	if rs[1].Error == nil || rs[1].Error.Code != lspRequestCancelled {
+		t.Errorf("expected cancelled error, got: %+v", rs[1])
+	}
+
+	if len(l.reqs) != 0 {
+		t.Errorf("expected no in-flight requests, got: %d", len(l.reqs))
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	boom := teal.WithAnalyzer(func(res *teal.ProcessResult) {
+		for _, line := range res.Lines {
+			for _, tok := range line {
+				if strings.HasPrefix(tok.String(), "boom") {
+					panic("boom")
+				}
+			}
+		}
+	})
+
+	hover := func(id int, uri string) string {
+		return testMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"textDocument/hover","params":{"textDocument":{"uri":"%s"},"position":{"line":1,"character":0}}}`, id, uri))
+	}
+
+	in := testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.teal","text":"#pragma version 8\nboom:\nint 1"}}}`) +
+		testMessage(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///b.teal","text":"#pragma version 8\nint 1"}}}`) +
+		hover(1, "file:///a.teal") +
+		hover(2, "file:///b.teal")
+
+	out := &bytes.Buffer{}
+	log := &bytes.Buffer{}
+
+	l, err := New(bytes.NewBufferString(in), out, WithProcessOptions(boom), WithLog(log, LogError))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 responses, got: %d", len(rs))
+	}
+
+	if rs[0].Error == nil || rs[0].Error.Code != jsonRpcInternalError || !strings.Contains(rs[0].Error.Message, "boom") {
+		t.Errorf("expected the internal error, got: %+v", rs[0])
+	}
+
+	if rs[1].Error != nil {
+		t.Errorf("expected the server to keep serving, got: %+v", rs[1].Error)
+	}
+
+	if !strings.Contains(log.String(), "handler panic") || !strings.Contains(log.String(), "runtime/debug.Stack") {
+		t.Errorf("expected the stack logged, got: %s", log.String())
+	}
+}
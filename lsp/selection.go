@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"github.com/dragmz/teal"
+)
+
+func rangeContains(outer lspRange, inner lspRange) bool {
+	before := func(a lspPosition, b lspPosition) bool {
+		return a.Line < b.Line || a.Line == b.Line && a.Character <= b.Character
+	}
+
+	return before(outer.Start, inner.Start) && before(inner.End, outer.End)
+}
+
+// blockRange returns the lines from the closest label at or before the line to the line before the next label.
+// The labels are filtered by the match func. The block starts at the first line if there is no label before the line.
+func blockRange(res *teal.ProcessResult, lines []string, line int, match func(name string) bool) (lspRange, bool) {
+	start := -1
+	end := len(lines)
+
+	for _, sym := range res.Symbols {
+		if !match(sym.Name()) {
+			continue
+		}
+
+		l := sym.Line()
+		if l <= line && l > start {
+			start = l
+		}
+		if l > line && l < end {
+			end = l
+		}
+	}
+
+	if start == -1 {
+		if end == len(lines) {
+			return lspRange{}, false
+		}
+		start = 0
+	}
+
+	last := end - 1
+
+	return lspRange{
+		Start: lspPosition{Line: start},
+		End:   lspPosition{Line: last, Character: len(lines[last])},
+	}, true
+}
+
+// selectionRange returns the ranges around the position for the expand selection: token, immediate arguments,
+// instruction, line, labelled block and subroutine.
+func selectionRange(res *teal.ProcessResult, lines []string, p lspPosition) *lspSelectionRange {
+	var rs []lspRange
+
+	add := func(r lspRange) {
+		if len(rs) > 0 {
+			prev := rs[len(rs)-1]
+			if r == prev || !rangeContains(r, prev) {
+				return
+			}
+		}
+
+		rs = append(rs, r)
+	}
+
+	tokens := func(l int, first teal.Token, last teal.Token) lspRange {
+		return lspRange{
+			Start: lspPosition{Line: l, Character: first.Begin()},
+			End:   lspPosition{Line: l, Character: last.End()},
+		}
+	}
+
+	ln := res.LineAt(p.Line, p.Character)
+	if len(ln) > 0 {
+		for i, t := range ln {
+			if p.Character < t.Begin() || p.Character > t.End() {
+				continue
+			}
+
+			add(tokens(p.Line, t, t))
+
+			if i > 0 && len(ln) > 2 {
+				add(tokens(p.Line, ln[1], ln[len(ln)-1]))
+			}
+
+			break
+		}
+
+		add(tokens(p.Line, ln[0], ln[len(ln)-1]))
+	}
+
+	if p.Line < len(lines) {
+		add(lspRange{
+			Start: lspPosition{Line: p.Line},
+			End:   lspPosition{Line: p.Line, Character: len(lines[p.Line])},
+		})
+	}
+
+	if r, ok := blockRange(res, lines, p.Line, func(string) bool { return true }); ok {
+		add(r)
+	}
+
+	subs := map[string]bool{}
+	for _, op := range res.Listing {
+		if op, ok := op.(*teal.CallSubExpr); ok {
+			subs[op.Label.Name] = true
+		}
+	}
+
+	if r, ok := blockRange(res, lines, p.Line, func(name string) bool { return subs[name] }); ok {
+		add(r)
+	}
+
+	if len(rs) == 0 {
+		return &lspSelectionRange{
+			Range: lspRange{Start: p, End: p},
+		}
+	}
+
+	var sr *lspSelectionRange
+	for i := len(rs) - 1; i >= 0; i-- {
+		sr = &lspSelectionRange{
+			Range:  rs[i],
+			Parent: sr,
+		}
+	}
+
+	return sr
+}
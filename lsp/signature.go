@@ -0,0 +1,112 @@
+package lsp
+
+import (
+	"fmt"
+
+	"github.com/dragmz/teal"
+)
+
+// signatureHelp returns the signature of the op at the position with the immediate argument being typed.
+// The array arguments, e.g. the labels of switch, are expanded to a parameter per typed immediate.
+func signatureHelp(res *teal.ProcessResult, line int, ch int) *lspSignatureHelp {
+	ln := res.LineAt(line, ch)
+	if len(ln) == 0 {
+		return nil
+	}
+
+	isOp := false
+	for _, op := range res.Ops {
+		if op == ln[0] {
+			isOp = true
+			break
+		}
+	}
+
+	if !isOp {
+		return nil
+	}
+
+	info, ok := teal.Ops.Get(teal.OpContext{
+		Name:    ln[0].String(),
+		Version: res.Version,
+	})
+	if !ok {
+		return nil
+	}
+
+	imm := res.ImmIndexAt(line, ch)
+
+	var doc interface{}
+	if info.FullDoc != "" {
+		doc = lspMarkupContent{
+			Kind:  "markdown",
+			Value: info.FullDoc,
+		}
+	}
+
+	label := info.FullSig
+	active := imm
+
+	ps := []lspParameterInformation{}
+
+	n := len(info.Args)
+	if n > 0 && info.Args[n-1].Array {
+		fixed := n - 1
+
+		// the typed immediates or at least the one at the position
+		count := len(ln) - 1 - fixed
+		if imm-fixed+1 > count {
+			count = imm - fixed + 1
+		}
+		if count < 1 {
+			count = 1
+		}
+
+		label = info.Name
+
+		add := func(name string, doc string) {
+			label += " "
+			begin := len(label)
+			label += name
+
+			ps = append(ps, lspParameterInformation{
+				Label:         []int{begin, len(label)},
+				Documentation: doc,
+			})
+		}
+
+		for _, arg := range info.Args[:fixed] {
+			add(arg.Name, arg.Type.String())
+		}
+
+		arg := info.Args[fixed]
+		for i := 0; i < count; i++ {
+			add(fmt.Sprintf("%s %d of %d", arg.Name, i+1, count), arg.Type.String())
+		}
+	} else {
+		for _, arg := range info.Args {
+			ps = append(ps, lspParameterInformation{
+				Label: arg.Name,
+			})
+		}
+
+		if active >= n {
+			active = n - 1
+		}
+	}
+
+	if active < 0 {
+		active = 0
+	}
+
+	return &lspSignatureHelp{
+		Signatures: []lspSignatureInformation{
+			{
+				Label:           label,
+				Documentation:   doc,
+				Parameters:      ps,
+				ActiveParameter: &active,
+			},
+		},
+	}
+}
@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dragmz/teal"
+)
+
+// slowRequest is the duration of the handling logged as a warning.
+const slowRequest = time.Second
+
+type methodStats struct {
+	count    int
+	errors   int
+	duration time.Duration
+	max      time.Duration
+}
+
+// serverStats are the counters of the handled messages dumped by the teal.server.stats command.
+type serverStats struct {
+	mu sync.Mutex
+
+	methods map[string]*methodStats
+
+	hits   int
+	misses int
+
+	received int
+	sent     int
+
+	// usage are the counters of the teal.server.usage command, nil when the usage option is off
+	usage *tealUsage
+}
+
+func newServerStats() *serverStats {
+	return &serverStats{
+		methods: map[string]*methodStats{},
+	}
+}
+
+// collect starts or stops collecting the usage counters, the counters collected so far are dropped when stopped.
+func (s *serverStats) collect(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case !on:
+		s.usage = nil
+	case s.usage == nil:
+		s.usage = &tealUsage{
+			Requests: map[string]int{},
+			Errors:   map[string]int{},
+			Commands: map[string]int{},
+			Rules:    map[string]int{},
+		}
+	}
+}
+
+func (s *serverStats) observe(method string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.methods[method]
+	if ms == nil {
+		ms = &methodStats{}
+		s.methods[method] = ms
+	}
+
+	ms.count++
+	ms.duration += d
+	if d > ms.max {
+		ms.max = d
+	}
+
+	if err != nil {
+		ms.errors++
+	}
+
+	if s.usage != nil {
+		s.usage.Requests[method]++
+		if err != nil {
+			s.usage.Errors[method]++
+		}
+	}
+}
+
+func (s *serverStats) command(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usage != nil {
+		s.usage.Commands[name]++
+	}
+}
+
+// diagnostics records the rule hits of the processed document.
+func (s *serverStats) diagnostics(res *teal.ProcessResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usage == nil {
+		return
+	}
+
+	for _, d := range res.Diagnostics {
+		if d.Rule() != "" {
+			s.usage.Rules[d.Rule()]++
+		}
+	}
+}
+
+func (s *serverStats) cache(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hit {
+		s.hits++
+	} else {
+		s.misses++
+	}
+}
+
+func (s *serverStats) receive(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received += n
+}
+
+func (s *serverStats) send(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent += n
+}
+
+// Prometheus returns the stats in the Prometheus text exposition format.
+func (s *serverStats) Prometheus(docs map[string]*lspDoc) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sb strings.Builder
+
+	metric := func(name string, kind string, help string) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	var names []string
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metric("teal_lsp_messages_total", "counter", "Handled messages by method.")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "teal_lsp_messages_total{method=%q} %d\n", name, s.methods[name].count)
+	}
+
+	metric("teal_lsp_message_errors_total", "counter", "Failed messages by method.")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "teal_lsp_message_errors_total{method=%q} %d\n", name, s.methods[name].errors)
+	}
+
+	metric("teal_lsp_message_duration_seconds_sum", "counter", "Total handling time by method.")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "teal_lsp_message_duration_seconds_sum{method=%q} %g\n", name, s.methods[name].duration.Seconds())
+	}
+
+	metric("teal_lsp_message_duration_seconds_max", "gauge", "Longest handling time by method.")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "teal_lsp_message_duration_seconds_max{method=%q} %g\n", name, s.methods[name].max.Seconds())
+	}
+
+	metric("teal_lsp_cache_hits_total", "counter", "Requests served from the already processed documents.")
+	fmt.Fprintf(&sb, "teal_lsp_cache_hits_total %d\n", s.hits)
+
+	metric("teal_lsp_cache_misses_total", "counter", "Requests that processed the documents.")
+	fmt.Fprintf(&sb, "teal_lsp_cache_misses_total %d\n", s.misses)
+
+	metric("teal_lsp_received_bytes_total", "counter", "Received message bytes.")
+	fmt.Fprintf(&sb, "teal_lsp_received_bytes_total %d\n", s.received)
+
+	metric("teal_lsp_sent_bytes_total", "counter", "Sent message bytes.")
+	fmt.Fprintf(&sb, "teal_lsp_sent_bytes_total %d\n", s.sent)
+
+	size := 0
+	for _, doc := range docs {
+		size += len(doc.Text())
+	}
+
+	metric("teal_lsp_documents", "gauge", "Open documents.")
+	fmt.Fprintf(&sb, "teal_lsp_documents %d\n", len(docs))
+
+	metric("teal_lsp_documents_bytes", "gauge", "Total size of the open documents.")
+	fmt.Fprintf(&sb, "teal_lsp_documents_bytes %d\n", size)
+
+	return sb.String()
+}
+
+// tealUsage are the aggregate feature usage counters reported by the teal.server.usage command, they contain no paths,
+// no document contents and are never sent anywhere by the server.
+type tealUsage struct {
+	// Requests and Errors are the handled and the failed messages by method
+	Requests map[string]int `json:"requests"`
+	Errors   map[string]int `json:"errors"`
+
+	Commands map[string]int `json:"commands"`
+
+	// Rules are the diagnostics by rule id of every processing of the documents, so a document processed again after
+	// a change counts its diagnostics again
+	Rules map[string]int `json:"rules"`
+
+	// Documents are the open documents
+	Documents int `json:"documents"`
+}
+
+type tealUsageCommandArgs struct {
+	// Path is the file the usage JSON is written to, optional
+	Path string `json:"path,omitempty"`
+}
+
+// Usage returns the usage counters collected since the usage option was enabled, false if it is disabled.
+func (s *serverStats) Usage(docs map[string]*lspDoc) (tealUsage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usage == nil {
+		return tealUsage{}, false
+	}
+
+	u := tealUsage{
+		Requests:  map[string]int{},
+		Errors:    map[string]int{},
+		Commands:  map[string]int{},
+		Rules:     map[string]int{},
+		Documents: len(docs),
+	}
+
+	fill := func(dst map[string]int, src map[string]int) {
+		for k, v := range src {
+			dst[k] = v
+		}
+	}
+
+	fill(u.Requests, s.usage.Requests)
+	fill(u.Errors, s.usage.Errors)
+	fill(u.Commands, s.usage.Commands)
+	fill(u.Rules, s.usage.Rules)
+
+	return u, true
+}
+
+// docStats returns the size of the doc and whether its results are already computed,
+// the cache state is empty when the doc is not open.
+func (l *lsp) docStats(uri string) (int, string) {
+	doc := l.doc(uri)
+	if doc == nil {
+		return 0, ""
+	}
+
+	snap := doc.Snapshot()
+	if snap.processed() != nil {
+		return len(snap.s), "hit"
+	}
+
+	return len(snap.s), "miss"
+}
+
+// observe records the handled message.
+func (l *lsp) observe(h jsonRpcHeader, d time.Duration, size int, cache string, err error) {
+	l.stats.observe(h.Method, d, err)
+
+	kvs := []interface{}{"method", h.Method, "duration", d}
+	if h.Id != nil {
+		kvs = append(kvs, "id", h.Id)
+	}
+
+	if cache != "" {
+		kvs = append(kvs, "size", size, "cache", cache)
+
+		if isRequest(h) {
+			l.stats.cache(cache == "hit")
+		}
+	}
+
+	if err != nil {
+		kvs = append(kvs, "err", err)
+	}
+
+	if d >= slowRequest {
+		l.log.Warn("slow message", kvs...)
+	} else {
+		l.log.Debug("handled", kvs...)
+	}
+}
@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/dragmz/teal"
+)
+
+func TestTestsCommands(t *testing.T) {
+	l, err := New(&bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "file:///a.teal"
+	testOpen(l, uri, `#pragma version 8
+method "ok()void"
+method "bad()void"
+txna ApplicationArgs 0
+match ok bad
+err
+ok:
+int 1
+return
+bad:
+int 0
+return`)
+
+	r := testServe(t, l, `{"jsonrpc":"2.0","id":1,"method":"workspace/executeCommand","params":{"command":"teal.tests.list","arguments":[{"uri":"file:///a.teal"}]}}`)
+
+	var es []teal.TestEntry
+	err = json.Unmarshal(r.Result, &es)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(es) != 2 || es[0].Id != "method:ok()void" || es[0].Line != 6 || es[1].Id != "method:bad()void" {
+		t.Fatalf("unexpected entries: %v", es)
+	}
+
+	out := &bytes.Buffer{}
+	l.w.Reset(out)
+
+	err = l.serve([]byte(`{"jsonrpc":"2.0","id":2,"method":"workspace/executeCommand","params":{"command":"teal.tests.run","arguments":[{"uri":"file:///a.teal","ids":["method:bad()void"]}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := testResponses(t, out.Bytes())
+	if len(rs) != 2 || rs[0].Method != "teal/testResults" {
+		t.Fatalf("unexpected messages: %+v", rs)
+	}
+
+	var p tealTestResultsParams
+	err = json.Unmarshal(rs[0].Params, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Uri != uri || len(p.Results) != 1 || p.Results[0].Status != teal.TestFailed {
+		t.Errorf("unexpected results: %+v", p)
+	}
+}
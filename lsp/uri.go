@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// uriToPath returns the path of the file URI, empty for the other schemes, e.g. untitled:Untitled-1.
+// The relative file URIs, e.g. file:src/main.teal, are resolved against the working directory.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	if u.Opaque != "" {
+		p, err := url.PathUnescape(u.Opaque)
+		if err != nil {
+			return ""
+		}
+
+		abs, err := filepath.Abs(filepath.FromSlash(p))
+		if err != nil {
+			return ""
+		}
+
+		return abs
+	}
+
+	p := u.Path
+
+	// windows drive letter, e.g. /c:/dir/file.teal
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+
+	return filepath.FromSlash(p)
+}
+
+func pathToUri(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	u := url.URL{
+		Scheme: "file",
+		Path:   p,
+	}
+
+	return u.String()
+}
+
+// uriName returns the last segment of the URI of any scheme, e.g. Untitled-1 of untitled:Untitled-1.
+func uriName(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+
+	p := u.Path
+	if u.Opaque != "" {
+		p, err = url.PathUnescape(u.Opaque)
+		if err != nil {
+			return ""
+		}
+	}
+
+	name := path.Base(p)
+	if name == "." || name == "/" {
+		return ""
+	}
+
+	return name
+}
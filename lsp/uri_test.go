@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUriToPath(t *testing.T) {
+	type test struct {
+		uri  string
+		path string
+	}
+
+	tests := []test{
+		{uri: "file:///home/user/main.teal", path: "/home/user/main.teal"},
+		{uri: "file:///home/user/my%20app/main.teal", path: "/home/user/my app/main.teal"},
+		{uri: "file:///c:/src/main.teal", path: "c:/src/main.teal"},
+		{uri: "untitled:Untitled-1", path: ""},
+	}
+
+	for _, test := range tests {
+		p := uriToPath(test.uri)
+		if p != filepath.FromSlash(test.path) {
+			t.Errorf("unexpected path for %s - expected: %s, actual: %s", test.uri, test.path, p)
+		}
+
+		if test.path != "" {
+			uri := pathToUri(p)
+			if uri != test.uri {
+				t.Errorf("unexpected uri for %s - expected: %s, actual: %s", p, test.uri, uri)
+			}
+		}
+	}
+}
+
+func TestRelativeFileUri(t *testing.T) {
+	abs, err := filepath.Abs(filepath.FromSlash("src/main.teal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := uriToPath("file:src/main.teal"); p != abs {
+		t.Errorf("unexpected path of the relative uri - expected: %s, actual: %s", abs, p)
+	}
+}
+
+func TestUriName(t *testing.T) {
+	tests := map[string]string{
+		"untitled:Untitled-1":          "Untitled-1",
+		"file:///home/user/main.teal":  "main.teal",
+		"vscode-vfs://github/a/b.teal": "b.teal",
+		"untitled:":                    "",
+	}
+
+	for uri, name := range tests {
+		if n := uriName(uri); n != name {
+			t.Errorf("unexpected name of %s - expected: %s, actual: %s", uri, name, n)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package teal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Metadata is the program identity declared in the header comments, e.g.:
+//
+//	// @name: counter
+//	// @description: counts the calls
+//	// @author: alice
+//	// @version: 1.2.0
+//	// @tags: counter, demo
+type Metadata struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// Extra holds the other @key: value entries
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+var metadataCommentRegexp = regexp.MustCompile(`^@([A-Za-z_][A-Za-z0-9_-]*)\s*:\s*(.*)$`)
+
+// Empty returns true if no metadata is declared.
+func (m Metadata) Empty() bool {
+	return m.Name == "" && m.Description == "" && m.Author == "" && m.Version == "" && len(m.Tags) == 0 && len(m.Extra) == 0
+}
+
+func (m *Metadata) set(key string, value string) {
+	switch strings.ToLower(key) {
+	case "name":
+		m.Name = value
+	case "description", "desc":
+		if m.Description != "" {
+			m.Description += "\n"
+		}
+		m.Description += value
+	case "author":
+		m.Author = value
+	case "version":
+		m.Version = value
+	case "tags", "tag":
+		for _, t := range strings.Split(value, ",") {
+			t = strings.TrimSpace(t)
+			if t != "" {
+				m.Tags = append(m.Tags, t)
+			}
+		}
+	default:
+		if m.Extra == nil {
+			m.Extra = map[string]string{}
+		}
+		m.Extra[key] = value
+	}
+}
+
+// readMetadata reads the @key: value comments of the header - the lines before the first instruction other than #pragma.
+func readMetadata(ts []Token) Metadata {
+	var m Metadata
+
+	line := -1
+
+	for _, t := range ts {
+		first := t.Line() != line
+		line = t.Line()
+
+		if !first {
+			continue
+		}
+
+		if t.Type() != TokenComment {
+			if t.String() == "#pragma" {
+				continue
+			}
+
+			break
+		}
+
+		s := strings.TrimSpace(t.String())
+		if ms := metadataCommentRegexp.FindStringSubmatch(s); ms != nil {
+			m.set(ms[1], strings.TrimSpace(ms[2]))
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,38 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	res := Process(`#pragma version 8
+// @name: counter
+// @description: counts the calls
+// @description: of the app
+// @author: alice
+// @version: 1.2.0
+// @tags: counter, demo
+// @license: MIT
+// a regular comment
+int 1
+// @name: ignored
+return`)
+
+	expected := Metadata{
+		Name:        "counter",
+		Description: "counts the calls\nof the app",
+		Author:      "alice",
+		Version:     "1.2.0",
+		Tags:        []string{"counter", "demo"},
+		Extra:       map[string]string{"license": "MIT"},
+	}
+
+	if !reflect.DeepEqual(res.Metadata, expected) {
+		t.Errorf("unexpected metadata: %#v", res.Metadata)
+	}
+
+	if !Process("#pragma version 8\nint 1\n").Metadata.Empty() {
+		t.Error("expected empty metadata")
+	}
+}
@@ -0,0 +1,119 @@
+package teal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dragmz/teal/abi"
+	"github.com/pkg/errors"
+)
+
+// MethodSignature is the signature of a method pseudo-op, Begin and End are the range of the quoted signature.
+type MethodSignature struct {
+	Index int
+	Line  int
+	Begin int
+	End   int
+
+	// Signature is the signature without the quotes
+	Signature string
+
+	// Canonical is the signature with the aliased types replaced, e.g. uint with uint64
+	Canonical string
+
+	// Method is the parsed canonical signature, valid when Err is nil
+	Method abi.Method
+	Err    error
+}
+
+// MethodSignatures returns the parsed signatures of the method pseudo-ops.
+func (r ProcessResult) MethodSignatures() []MethodSignature {
+	var res []MethodSignature
+
+	for i, op := range r.Listing {
+		if i >= len(r.Sublines) {
+			break
+		}
+
+		m, ok := op.(*MethodExpr)
+		if !ok {
+			continue
+		}
+
+		sub := r.Sublines[i]
+
+		ms := MethodSignature{
+			Index:     i,
+			Line:      sub.Line,
+			Begin:     sub.Tokens.Begin(),
+			End:       sub.Tokens.End(),
+			Signature: strings.Trim(m.Signature, "\""),
+		}
+
+		if len(sub.Tokens) > 1 {
+			ms.Begin = sub.Tokens[1].Begin()
+			ms.End = sub.Tokens[1].End()
+		}
+
+		ms.Canonical = abi.CanonicalMethod(ms.Signature)
+		ms.Method, ms.Err = abi.ParseMethod(ms.Canonical)
+
+		res = append(res, ms)
+	}
+
+	return res
+}
+
+func (ms MethodSignature) fix() FixAction {
+	return FixAction{
+		Title: fmt.Sprintf("Replace with '%s'", ms.Canonical),
+		Kind:  FixMethodSignature,
+		Edits: []FixEdit{
+			{
+				Start:   FixPosition{Line: ms.Line, Character: ms.Begin},
+				End:     FixPosition{Line: ms.Line, Character: ms.End},
+				NewText: strconv.Quote(ms.Canonical),
+			},
+		},
+	}
+}
+
+type MethodSignatureRule struct{}
+
+func (r MethodSignatureRule) Id() string {
+	return "LINT0021"
+}
+
+func (r MethodSignatureRule) Desc() string {
+	return "Checks that the method signatures are valid canonical ARC-4 signatures"
+}
+
+var MethodSignatureRuleInstance = MethodSignatureRule{}
+
+func checkMethodSignatures(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, ms := range res.MethodSignatures() {
+		report := func(err error) {
+			diags = append(diags, lintError{
+				error: err,
+				l:     ms.Line,
+				b:     ms.Begin,
+				e:     ms.End,
+				s:     DiagWarn,
+				r:     MethodSignatureRuleInstance.Id(),
+			})
+		}
+
+		if ms.Canonical != ms.Signature {
+			report(errors.Errorf("non-canonical method signature '%s', the selector differs from the one of '%s'", ms.Signature, ms.Canonical))
+		}
+
+		if ms.Err != nil {
+			report(ms.Err)
+		}
+	}
+
+	return diags
+}
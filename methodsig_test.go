@@ -0,0 +1,67 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMethodSignatures(t *testing.T) {
+	res := Process(`#pragma version 8
+method "add(uint64,uint64)uint128"
+method "add(uint, bytes)uint"
+method "hello(name)void"
+pop
+pop
+pop
+int 1
+return`)
+
+	ms := res.MethodSignatures()
+	if len(ms) != 3 {
+		t.Fatalf("unexpected method signatures: %+v", ms)
+	}
+
+	if ms[0].Err != nil || ms[0].Method.Name != "add" || len(ms[0].Method.Args) != 2 || ms[0].Method.Returns.String() != "uint128" {
+		t.Errorf("unexpected method: %+v", ms[0])
+	}
+
+	if ms[1].Canonical != "add(uint64,byte[])uint64" || ms[1].Err != nil {
+		t.Errorf("unexpected canonical signature: %+v", ms[1])
+	}
+
+	if ms[1].Begin != 7 || ms[1].End != 29 {
+		t.Errorf("unexpected signature range: %d-%d", ms[1].Begin, ms[1].End)
+	}
+
+	var msgs []string
+	for _, d := range res.Diagnostics {
+		if d.Rule() == MethodSignatureRuleInstance.Id() {
+			msgs = append(msgs, d.String())
+		}
+	}
+
+	expected := []string{
+		"non-canonical method signature 'add(uint, bytes)uint', the selector differs from the one of 'add(uint64,byte[])uint64'",
+		"invalid method arg 0: unknown type: name",
+	}
+
+	if strings.Join(msgs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected diagnostics:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	var fas []FixAction
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: 9}) {
+		if fa.Kind == FixMethodSignature {
+			fas = append(fas, fa)
+		}
+	}
+
+	if len(fas) != 1 {
+		t.Fatalf("unexpected fixes: %+v", fas)
+	}
+
+	src, _ := ApplyFixes("#pragma version 8\nmethod \"add(uint64,uint64)uint128\"\nmethod \"add(uint, bytes)uint\"", fas)
+	if src != "#pragma version 8\nmethod \"add(uint64,uint64)uint128\"\nmethod \"add(uint64,byte[])uint64\"" {
+		t.Errorf("unexpected fixed source: %s", src)
+	}
+}
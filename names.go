@@ -0,0 +1,135 @@
+package teal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FrameNames are the names of the subroutine arguments and results.
+type FrameNames struct {
+	Args    []string
+	Results []string
+}
+
+// Arg returns the name of the argument at the frame_dig index, e.g. -1 for the last argument.
+func (n FrameNames) Arg(index int) string {
+	i := len(n.Args) + index
+	if i >= 0 && i < len(n.Args) && n.Args[i] != "" {
+		return n.Args[i]
+	}
+
+	return frameSlotName(index)
+}
+
+// Local returns the name of the local variable at the non-negative frame_dig index.
+func (n FrameNames) Local(index int) string {
+	return frameSlotName(index)
+}
+
+func frameSlotName(index int) string {
+	return "frame[" + strconv.Itoa(index) + "]"
+}
+
+func parseProtoComment(s string) (FrameNames, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "proto:") {
+		return FrameNames{}, false
+	}
+
+	s = strings.TrimPrefix(s, "proto:")
+
+	var n FrameNames
+
+	args, results, _ := strings.Cut(s, "->")
+	n.Args = strings.Fields(args)
+	n.Results = strings.Fields(results)
+
+	return n, true
+}
+
+var scratchCommentRegexp = regexp.MustCompile(`^scratch\s+(\d+)\s*:\s*(\S+)$`)
+
+func (r ProcessResult) comments() map[int][]string {
+	res := map[int][]string{}
+
+	for _, t := range r.Tokens {
+		if t.Type() == TokenComment {
+			res[t.Line()] = append(res[t.Line()], t.String())
+		}
+	}
+
+	return res
+}
+
+// FrameNames returns the frame names of the subroutines keyed by their labels.
+// Names are read from `// proto: a b -> r` comments on the proto line or on the lines right above it.
+func (r ProcessResult) FrameNames() map[string]FrameNames {
+	res := map[string]FrameNames{}
+	cs := r.comments()
+
+	label := ""
+
+	for i, op := range r.Listing {
+		switch op := op.(type) {
+		case *LabelExpr:
+			label = op.Name
+		case *ProtoExpr:
+			if label == "" {
+				continue
+			}
+
+			line := r.SourceLine(i)
+
+			for l := line; l >= 0; l-- {
+				found := false
+
+				for _, c := range cs[l] {
+					if n, ok := parseProtoComment(c); ok {
+						res[label] = n
+						found = true
+					}
+				}
+
+				if found {
+					break
+				}
+
+				// stop at the first line above the proto that is not a comment or a label
+				if l != line && l < len(r.Lines) && len(r.Lines[l]) > 0 {
+					t := r.Lines[l][0]
+					if t.Type() != TokenComment && !strings.HasSuffix(t.String(), ":") {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// ScratchNames returns the scratch slot aliases declared with `// scratch N: name` comments.
+func (r ProcessResult) ScratchNames() map[int]string {
+	res := map[int]string{}
+
+	for _, t := range r.Tokens {
+		if t.Type() != TokenComment {
+			continue
+		}
+
+		m := scratchCommentRegexp.FindStringSubmatch(strings.TrimSpace(t.String()))
+		if m == nil {
+			continue
+		}
+
+		i, err := strconv.Atoi(m[1])
+		if err != nil || i > 255 {
+			continue
+		}
+
+		res[i] = m[2]
+	}
+
+	return res
+}
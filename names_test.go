@@ -0,0 +1,75 @@
+package teal
+
+import "testing"
+
+func TestFrameNames(t *testing.T) {
+	res := Process(`#pragma version 8
+// scratch 1: total
+int 1
+int 2
+callsub add
+store 1
+int 1
+return
+// adds two numbers
+// proto: a b -> sum
+add:
+proto 2 1
+frame_dig -2
+frame_dig -1
++
+retsub
+other:
+proto 1 0 // proto: x
+retsub`)
+
+	ns := res.FrameNames()
+
+	add, ok := ns["add"]
+	if !ok {
+		t.Fatal("missing add frame names")
+	}
+
+	if add.Arg(-2) != "a" || add.Arg(-1) != "b" || len(add.Results) != 1 || add.Results[0] != "sum" {
+		t.Errorf("unexpected add frame names: %+v", add)
+	}
+
+	if add.Local(0) != "frame[0]" {
+		t.Errorf("unexpected local name: %s", add.Local(0))
+	}
+
+	if ns["other"].Arg(-1) != "x" {
+		t.Errorf("unexpected other frame names: %+v", ns["other"])
+	}
+
+	if (FrameNames{}).Arg(-1) != "frame[-1]" {
+		t.Errorf("unexpected default arg name: %s", FrameNames{}.Arg(-1))
+	}
+
+	if res.ScratchNames()[1] != "total" {
+		t.Errorf("unexpected scratch names: %v", res.ScratchNames())
+	}
+}
+
+func TestFrameBase(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+int 2
+callsub add
+return
+add:
+proto 2 1
+frame_dig -2
+frame_dig -1
++
+retsub`)
+
+	vm := NewVm(res)
+	for i := 0; i < 4; i++ {
+		vm.Step()
+	}
+
+	if len(vm.Branch.Frames) != 1 || vm.Branch.Frames[0].Sub != "add" || vm.Branch.Frames[0].Base() != 2 {
+		t.Errorf("unexpected frames: %+v", vm.Branch.Frames)
+	}
+}
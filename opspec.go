@@ -0,0 +1,99 @@
+package teal
+
+import (
+	"sort"
+)
+
+// OpArgSpec is an immediate argument of an op.
+type OpArgSpec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Array    bool   `json:"array,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// OpSpec describes an op, e.g. for the per-version opcode tables.
+type OpSpec struct {
+	Name string `json:"name"`
+
+	// SigVersion and AppVersion are the min versions of the op in the mode, 0 if not available in the mode
+	SigVersion uint64 `json:"sigVersion"`
+	AppVersion uint64 `json:"appVersion"`
+
+	Args []OpArgSpec `json:"args,omitempty"`
+
+	// Signature is the op name followed by the immediate argument names, e.g. "txna f i"
+	Signature string `json:"signature"`
+
+	Doc string `json:"doc,omitempty"`
+
+	// ImmediateForm and StackForm are the ops taking the same values as the immediates or from the stack, comma separated
+	ImmediateForm string `json:"immediateForm,omitempty"`
+	StackForm     string `json:"stackForm,omitempty"`
+}
+
+// MinVersion returns the min version of the op in the mode or 0 if the op is not available in the mode.
+// Both of the modes are considered for ModeNone.
+func (s OpSpec) MinVersion(mode ProgramMode) uint64 {
+	switch mode {
+	case ModeApp:
+		return s.AppVersion
+	case ModeSig:
+		return s.SigVersion
+	}
+
+	if s.AppVersion == 0 || s.SigVersion != 0 && s.SigVersion < s.AppVersion {
+		return s.SigVersion
+	}
+
+	return s.AppVersion
+}
+
+func newOpSpec(item opItem) OpSpec {
+	s := OpSpec{
+		Name:       item.Name,
+		SigVersion: item.SigVersion,
+		AppVersion: item.AppVersion,
+		Signature:  item.Name,
+		Doc:        item.Doc,
+	}
+
+	s.ImmediateForm, s.StackForm = opFormsOf(item.Name)
+
+	if item.ArgsSig != "" {
+		s.Signature += " " + item.ArgsSig
+	}
+
+	for _, arg := range item.Args {
+		s.Args = append(s.Args, OpArgSpec{
+			Name:     arg.Name,
+			Type:     arg.Type.String(),
+			Array:    arg.Array,
+			Optional: arg.Optional,
+		})
+	}
+
+	return s
+}
+
+// AvailableOps returns the ops available at the version in the mode sorted by name.
+func AvailableOps(version uint64, mode ProgramMode) []OpSpec {
+	var res []OpSpec
+
+	for _, item := range Ops.Items {
+		s := newOpSpec(item)
+
+		min := s.MinVersion(mode)
+		if min == 0 || min > version {
+			continue
+		}
+
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
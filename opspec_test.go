@@ -0,0 +1,55 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestAvailableOps(t *testing.T) {
+	type test struct {
+		version uint64
+		mode    ProgramMode
+		name    string
+		ok      bool
+	}
+
+	tests := []test{
+		{1, ModeSig, "arg", true},
+		{8, ModeApp, "arg", false},
+		{8, ModeNone, "arg", true},
+		{3, ModeApp, "gload", false},
+		{4, ModeApp, "gload", true},
+		{8, ModeSig, "gload", false},
+		{7, ModeApp, "proto", false},
+		{8, ModeApp, "proto", true},
+		{8, ModeApp, "txna", true},
+	}
+
+	for _, test := range tests {
+		ops := AvailableOps(test.version, test.mode)
+
+		found := false
+		for i, op := range ops {
+			if i > 0 && ops[i-1].Name >= op.Name {
+				t.Fatalf("ops not sorted: %s, %s", ops[i-1].Name, op.Name)
+			}
+
+			if op.Name == test.name {
+				found = true
+			}
+		}
+
+		if found != test.ok {
+			t.Errorf("unexpected availability of %s at v%d in %s: %v", test.name, test.version, test.mode, found)
+		}
+	}
+
+	for _, op := range AvailableOps(8, ModeApp) {
+		if op.Name != "txna" {
+			continue
+		}
+
+		if op.Signature != "txna f i" || len(op.Args) != 2 {
+			t.Errorf("unexpected txna spec: %+v", op)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package teal
+
+type processConfig struct {
+	path     string
+	readFile func(path string) ([]byte, error)
+
+	// paths of the files currently being processed, used for include cycle detection
+	stack []string
+
+	// version and mode inherited from the including file
+	version uint64
+	mode    ProgramMode
+
+	// clear state program
+	clear bool
+
+	// mirror goal clerk compile in the corner cases where it is more lenient
+	goal bool
+
+	// rule ids whose diagnostics are escalated to errors or dropped
+	escalate map[string]bool
+	silence  map[string]bool
+
+	// op policies checked for the processed file
+	policies []OpPolicy
+
+	// known addresses added to the default ones
+	addresses []KnownAddress
+
+	// offer the experimental quick fixes
+	experimental bool
+
+	// external analyzers run on the result
+	analyzers []Analyzer
+
+	// dirs searched for the #include paths not found relative to the including file
+	includePaths []string
+
+	// named constants substituted for the op args
+	constants map[string]string
+
+	// consensus limits the program is checked against
+	consensus *ConsensusParams
+}
+
+type ProcessOption func(c *processConfig)
+
+// Analyzer extends the processing with the checks and the artifacts of an external package.
+// It is run on the result after the built-in checks and may append to the Diagnostics and the Symbols
+// and set the Artifacts, it must not modify the other fields.
+type Analyzer func(res *ProcessResult)
+
+// WithAnalyzer runs the analyzer on the results of the processed file and of its included files,
+// the diagnostics it appends are escalated or silenced like the built-in ones.
+func WithAnalyzer(a Analyzer) ProcessOption {
+	return func(c *processConfig) {
+		c.analyzers = append(c.analyzers, a)
+	}
+}
+
+// WithPath sets the source file path used to resolve relative #include paths.
+func WithPath(path string) ProcessOption {
+	return func(c *processConfig) {
+		c.path = path
+	}
+}
+
+// WithVersion sets the version of the files without #pragma version, e.g. the target version of a project.
+func WithVersion(v uint64) ProcessOption {
+	return func(c *processConfig) {
+		c.version = v
+	}
+}
+
+// WithIncludePaths adds the dirs searched in order for the relative #include paths not found next to the including file.
+func WithIncludePaths(dirs ...string) ProcessOption {
+	return func(c *processConfig) {
+		c.includePaths = append(c.includePaths, dirs...)
+	}
+}
+
+// WithExperimentalFixes enables the experimental quick fixes, e.g. rewriting the unrolled gtxn sequences as loops.
+func WithExperimentalFixes() ProcessOption {
+	return func(c *processConfig) {
+		c.experimental = true
+	}
+}
+
+// WithReadFile overrides how included files are read, e.g. to prefer unsaved editor buffers.
+func WithReadFile(f func(path string) ([]byte, error)) ProcessOption {
+	return func(c *processConfig) {
+		c.readFile = f
+	}
+}
+
+// WithClear processes the source as a clear state program.
+func WithClear() ProcessOption {
+	return func(c *processConfig) {
+		c.clear = true
+	}
+}
+
+// WithGoalCompat mirrors goal clerk compile in the corner cases where the default processing is stricter,
+// e.g. the repeated #pragma version of the same value.
+func WithGoalCompat() ProcessOption {
+	return func(c *processConfig) {
+		c.goal = true
+	}
+}
+
+// WithEscalate reports diagnostics of the given rules as errors.
+func WithEscalate(rules ...string) ProcessOption {
+	return func(c *processConfig) {
+		if c.escalate == nil {
+			c.escalate = map[string]bool{}
+		}
+		for _, r := range rules {
+			c.escalate[r] = true
+		}
+	}
+}
+
+// WithSilence drops diagnostics of the given rules.
+func WithSilence(rules ...string) ProcessOption {
+	return func(c *processConfig) {
+		if c.silence == nil {
+			c.silence = map[string]bool{}
+		}
+		for _, r := range rules {
+			c.silence[r] = true
+		}
+	}
+}
+
+// StrictRules are the warnings escalated to errors in strict mode.
+var StrictRules = []string{
+	UnusedLabelsRule{}.Id(),
+	OpsAfterUnconditionalBranchRule{}.Id(),
+	CheckBranchJustBeforeLabelRule{}.Id(),
+	OpCodeVersionCompatibilityCheckRuleInstance.Id(),
+}
+
+// StyleRules are the rules silenced in permissive mode, e.g. for disassembled code.
+var StyleRules = []string{
+	UnusedLabelsRule{}.Id(),
+	OpsAfterUnconditionalBranchRule{}.Id(),
+	CheckBranchJustBeforeLabelRule{}.Id(),
+	DeprecatedRuleInstance.Id(),
+}
+
+// WithStrict reports the diagnostics of the StrictRules as errors.
+func WithStrict() ProcessOption {
+	return WithEscalate(StrictRules...)
+}
+
+// WithPermissive drops the diagnostics of the StyleRules, e.g. for disassembled code.
+func WithPermissive() ProcessOption {
+	return WithSilence(StyleRules...)
+}
+
+// ProcessStrict processes the source with WithStrict before the other options.
+func ProcessStrict(source string, opts ...ProcessOption) *ProcessResult {
+	return Process(source, append([]ProcessOption{WithStrict()}, opts...)...)
+}
+
+// ProcessPermissive processes the source with WithPermissive before the other options.
+func ProcessPermissive(source string, opts ...ProcessOption) *ProcessResult {
+	return Process(source, append([]ProcessOption{WithPermissive()}, opts...)...)
+}
+
+type escalatedDiagnostic struct {
+	Diagnostic
+}
+
+func (d escalatedDiagnostic) Severity() DiagnosticSeverity {
+	return DiagErr
+}
+
+func (c processConfig) apply(diags []Diagnostic) []Diagnostic {
+	if len(c.escalate) == 0 && len(c.silence) == 0 {
+		return diags
+	}
+
+	var res []Diagnostic
+	for _, d := range diags {
+		switch {
+		case c.silence[d.Rule()]:
+		case c.escalate[d.Rule()]:
+			res = append(res, escalatedDiagnostic{d})
+		default:
+			res = append(res, d)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,109 @@
+package teal
+
+import "testing"
+
+func TestProcessStrict(t *testing.T) {
+	src := `#pragma version 8
+unused:
+int 1
+return`
+
+	res := Process(src)
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Severity() != DiagWarn {
+		t.Fatalf("expected a single warning, got: %v", res.Diagnostics)
+	}
+
+	res = ProcessStrict(src)
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Severity() != DiagErr {
+		t.Fatalf("expected a single error, got: %v", res.Diagnostics)
+	}
+
+	if res.Diagnostics[0].Rule() != (UnusedLabelsRule{}).Id() {
+		t.Errorf("unexpected rule: %s", res.Diagnostics[0].Rule())
+	}
+}
+
+func TestProcessPermissive(t *testing.T) {
+	res := ProcessPermissive(`#pragma version 8
+b label1
+label1:
+err
+unused:
+int 1
+return`)
+
+	if len(res.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+
+	res = ProcessPermissive(`#pragma version 8
+b missing`)
+
+	if len(res.Diagnostics) != 1 {
+		t.Errorf("expected missing label error, got: %v", res.Diagnostics)
+	}
+}
+
+func TestWithAnalyzer(t *testing.T) {
+	lib := "double:\nint 2\n*\nretsub"
+
+	opts := []ProcessOption{
+		WithPath("/src/main.teal"),
+		WithReadFile(func(path string) ([]byte, error) {
+			return []byte(lib), nil
+		}),
+		WithAnalyzer(func(res *ProcessResult) {
+			n := 0
+			for _, op := range res.Listing {
+				if _, ok := op.(*MulExpr); ok {
+					n++
+				}
+			}
+
+			res.Artifacts["muls"] = n
+
+			for i, op := range res.Listing {
+				if _, ok := op.(*MulExpr); ok {
+					res.Diagnostics = append(res.Diagnostics, NewDiagnostic(res.SourceLine(i), 0, 1, DiagWarn, "CUSTOM0001", "multiplication"))
+				}
+			}
+		}),
+	}
+
+	src := "#pragma version 8\n#include \"lib.teal\"\nint 3\ncallsub double\nint 2\n*\nreturn"
+
+	res := Process(src, opts...)
+
+	if res.Artifacts["muls"] != 1 {
+		t.Errorf("unexpected artifact: %v", res.Artifacts["muls"])
+	}
+
+	var custom []Diagnostic
+	for _, d := range res.Diagnostics {
+		if d.Rule() == "CUSTOM0001" {
+			custom = append(custom, d)
+		}
+	}
+
+	if len(custom) != 1 || custom[0].Line() != 5 || custom[0].String() != "multiplication" || custom[0].Severity() != DiagWarn {
+		t.Fatalf("unexpected diagnostics: %v", custom)
+	}
+
+	if len(res.Includes) != 1 || res.Includes[0].Result.Artifacts["muls"] != 1 {
+		t.Errorf("expected the analyzer to run on the included file")
+	}
+
+	res = Process(src, append(opts, WithEscalate("CUSTOM0001"))...)
+	for _, d := range res.Diagnostics {
+		if d.Rule() == "CUSTOM0001" && d.Severity() != DiagErr {
+			t.Errorf("expected escalated diagnostic, got: %s", d.Severity())
+		}
+	}
+
+	res = Process(src, append(opts, WithSilence("CUSTOM0001"))...)
+	for _, d := range res.Diagnostics {
+		if d.Rule() == "CUSTOM0001" {
+			t.Errorf("unexpected silenced diagnostic: %s", d)
+		}
+	}
+}
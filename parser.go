@@ -1,7 +1,9 @@
 package teal
 
 import (
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -74,10 +76,22 @@ func (e lintError) Rule() string {
 	return e.r
 }
 
+// rangeError describes why the immediate value does not fit its type.
+func rangeError(s string, err error, typ string, min int64, max uint64) error {
+	switch {
+	case min == 0 && strings.HasPrefix(s, "-"):
+		return errors.Errorf("negative value %s where %s is expected, valid range: %d..%d", s, typ, min, max)
+	case errors.Is(err, strconv.ErrRange):
+		return errors.Errorf("value %s exceeds %s, valid range: %d..%d", s, typ, min, max)
+	default:
+		return errors.Errorf("invalid %s value: %s", typ, s)
+	}
+}
+
 func readInt8(s string) (int8, error) {
 	v, err := strconv.ParseInt(s, 10, 8)
 	if err != nil {
-		return 0, err
+		return 0, rangeError(s, err, "int8", math.MinInt8, math.MaxInt8)
 	}
 
 	return int8(v), nil
@@ -86,7 +100,7 @@ func readInt8(s string) (int8, error) {
 func readUint8(s string) (uint8, error) {
 	v, err := strconv.ParseUint(s, 10, 8)
 	if err != nil {
-		return 0, err
+		return 0, rangeError(s, err, "uint8", 0, math.MaxUint8)
 	}
 
 	return uint8(v), nil
@@ -320,7 +334,7 @@ func readTxnField(c fieldContext, v uint64, s string) (TxnField, bool, error) {
 func readInt(a *arguments) (uint64, error) {
 	val, err := strconv.ParseUint(a.Text(), 0, 64)
 	if err != nil {
-		return 0, err
+		return 0, rangeError(a.Text(), err, "uint64", 0, math.MaxUint64)
 	}
 
 	return val, nil
@@ -339,7 +353,7 @@ func readConstInt(a *arguments) (uint64, error) {
 
 	val, err := strconv.ParseUint(a.Text(), 0, 64)
 	if err != nil {
-		return 0, err
+		return 0, rangeError(a.Text(), err, "uint64", 0, math.MaxUint64)
 	}
 
 	return val, nil
@@ -68,3 +68,50 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+func TestImmediateRange(t *testing.T) {
+	tests := []struct {
+		Source   string
+		Expected string
+	}{
+		{
+			Source:   "store 300",
+			Expected: "failed to parse uint8: i: value 300 exceeds uint8, valid range: 0..255 - use stores to take the value from the stack",
+		},
+		{
+			Source:   "frame_dig 200",
+			Expected: "failed to parse int8: index: value 200 exceeds int8, valid range: -128..127",
+		},
+		{
+			Source:   "arg 256",
+			Expected: "failed to parse uint8: index: value 256 exceeds uint8, valid range: 0..255 - use args to take the value from the stack",
+		},
+		{
+			Source:   "load -1",
+			Expected: "failed to parse uint8: i: negative value -1 where uint8 is expected, valid range: 0..255 - use loads to take the value from the stack",
+		},
+		{
+			Source:   "int -1",
+			Expected: "failed to parse uint64: value: negative value -1 where uint64 is expected, valid range: 0..18446744073709551615",
+		},
+		{
+			Source:   "dig x",
+			Expected: "failed to parse uint8: n: invalid uint8 value: x",
+		},
+	}
+
+	for _, test := range tests {
+		res := Process("#pragma version 8\n" + test.Source)
+
+		found := false
+		for _, d := range res.Diagnostics {
+			if d.Line() == 1 && d.String() == test.Expected {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("expected diagnostic '%s' for '%s', got: %v", test.Expected, test.Source, res.Diagnostics)
+		}
+	}
+}
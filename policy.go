@@ -0,0 +1,237 @@
+package teal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// opCategoryPrefix marks the policy pattern matching the langspec group of the ops, e.g. "category:Inner Transactions".
+const opCategoryPrefix = "category:"
+
+// OpPolicy forbids the ops in the programs it applies to. The patterns are op names with the path.Match wildcards,
+// e.g. "itxn_*", or the op categories, e.g. "category:Inner Transactions".
+type OpPolicy struct {
+	Name string `json:"name"`
+
+	// Deny are the forbidden ops
+	Deny []string `json:"deny,omitempty"`
+
+	// Allow are the only allowed ops, all of the ops are allowed if empty
+	Allow []string `json:"allow,omitempty"`
+
+	// Paths are the path.Match patterns of the files the policy applies to, matched against the whole path
+	// and the file name, all of the files if empty
+	Paths []string `json:"paths,omitempty"`
+}
+
+var opCategories = func() map[string][]string {
+	m := map[string][]string{}
+	for _, op := range BuiltInLangSpec.Ops {
+		m[op.Name] = op.Groups
+	}
+
+	return m
+}()
+
+func validatePattern(p string) error {
+	if strings.HasPrefix(p, opCategoryPrefix) {
+		c := strings.TrimPrefix(p, opCategoryPrefix)
+		for _, gs := range opCategories {
+			for _, g := range gs {
+				if strings.EqualFold(g, c) {
+					return nil
+				}
+			}
+		}
+
+		return errors.Errorf("unknown op category: %s", c)
+	}
+
+	_, err := path.Match(p, "")
+	if err != nil {
+		return errors.Wrapf(err, "invalid pattern: %s", p)
+	}
+
+	return nil
+}
+
+// ParseOpPolicies parses the JSON array of the op policies.
+func ParseOpPolicies(bs []byte) ([]OpPolicy, error) {
+	var ps []OpPolicy
+
+	err := json.Unmarshal(bs, &ps)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse op policies")
+	}
+
+	for i, p := range ps {
+		if p.Name == "" {
+			return nil, errors.Errorf("missing name of op policy %d", i)
+		}
+
+		for _, pat := range append(append([]string{}, p.Deny...), p.Allow...) {
+			if err := validatePattern(pat); err != nil {
+				return nil, errors.Wrapf(err, "invalid op policy '%s'", p.Name)
+			}
+		}
+
+		for _, pat := range p.Paths {
+			if _, err := path.Match(pat, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid path pattern of op policy '%s': %s", p.Name, pat)
+			}
+		}
+	}
+
+	return ps, nil
+}
+
+// ReadOpPolicies reads the JSON file of the op policies.
+func ReadOpPolicies(path string) ([]OpPolicy, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read op policies")
+	}
+
+	return ParseOpPolicies(bs)
+}
+
+func matchOp(pattern string, name string) bool {
+	if strings.HasPrefix(pattern, opCategoryPrefix) {
+		c := strings.TrimPrefix(pattern, opCategoryPrefix)
+		for _, g := range opCategories[name] {
+			if strings.EqualFold(g, c) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ok, _ := path.Match(pattern, name)
+
+	return ok
+}
+
+func matchAny(patterns []string, name string) (string, bool) {
+	for _, p := range patterns {
+		if matchOp(p, name) {
+			return p, true
+		}
+	}
+
+	return "", false
+}
+
+// appliesTo reports whether the policy applies to the file, the policy without the paths applies to all of the sources.
+func (p OpPolicy) appliesTo(file string) bool {
+	if len(p.Paths) == 0 {
+		return true
+	}
+
+	file = filepath.ToSlash(file)
+
+	for _, pat := range p.Paths {
+		if ok, _ := path.Match(pat, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(file)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// violation returns the reason the op is forbidden by the policy.
+func (p OpPolicy) violation(name string) (string, bool) {
+	if pat, ok := matchAny(p.Deny, name); ok {
+		if pat == name {
+			return fmt.Sprintf("opcode '%s' is denied by policy '%s'", name, p.Name), true
+		}
+
+		return fmt.Sprintf("opcode '%s' is denied by policy '%s' (%s)", name, p.Name, pat), true
+	}
+
+	if len(p.Allow) > 0 {
+		if _, ok := matchAny(p.Allow, name); !ok {
+			return fmt.Sprintf("opcode '%s' is not allowed by policy '%s'", name, p.Name), true
+		}
+	}
+
+	return "", false
+}
+
+// WithOpPolicies reports the ops forbidden by the policies that apply to the processed file.
+func WithOpPolicies(ps ...OpPolicy) ProcessOption {
+	return func(c *processConfig) {
+		c.policies = append(c.policies, ps...)
+	}
+}
+
+type OpPolicyRule struct{}
+
+func (r OpPolicyRule) Id() string {
+	return "LINT0022"
+}
+
+func (r OpPolicyRule) Desc() string {
+	return "Checks for the opcodes forbidden by the configured op policies"
+}
+
+var OpPolicyRuleInstance = OpPolicyRule{}
+
+func checkOpPolicies(res *ProcessResult, ps []OpPolicy, file string) []Diagnostic {
+	var active []OpPolicy
+	for _, p := range ps {
+		if p.appliesTo(file) {
+			active = append(active, p)
+		}
+	}
+
+	if len(active) == 0 {
+		return nil
+	}
+
+	var diags []Diagnostic
+
+	for i, op := range res.Listing {
+		if i >= len(res.Sublines) {
+			break
+		}
+
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		sub := res.Sublines[i]
+		if len(sub.Tokens) == 0 {
+			continue
+		}
+
+		name := opName(op.String())
+
+		for _, p := range active {
+			msg, ok := p.violation(name)
+			if !ok {
+				continue
+			}
+
+			diags = append(diags, lintError{
+				error: errors.New(msg),
+				l:     sub.Line,
+				b:     sub.Tokens[0].Begin(),
+				e:     sub.Tokens[0].End(),
+				s:     DiagErr,
+				r:     OpPolicyRuleInstance.Id(),
+			})
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,85 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOpPolicies(t *testing.T) {
+	ps, err := ParseOpPolicies([]byte(`[{"name": "treasury", "deny": ["itxn_*", "category:Box Access"], "paths": ["treasury/*.teal"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ps) != 1 || ps[0].Name != "treasury" || len(ps[0].Deny) != 2 {
+		t.Errorf("unexpected policies: %+v", ps)
+	}
+
+	invalid := []string{
+		`{"name": "x"}`,
+		`[{"deny": ["json_ref"]}]`,
+		`[{"name": "x", "deny": ["itxn_["]}]`,
+		`[{"name": "x", "deny": ["category:Unknown"]}]`,
+		`[{"name": "x", "paths": ["[a"]}]`,
+	}
+
+	for _, s := range invalid {
+		if _, err := ParseOpPolicies([]byte(s)); err == nil {
+			t.Errorf("expected error for: %s", s)
+		}
+	}
+}
+
+func TestOpPolicies(t *testing.T) {
+	ps := []OpPolicy{
+		{Name: "no-json", Deny: []string{"json_ref"}},
+		{Name: "treasury", Deny: []string{"itxn_*"}, Paths: []string{"treasury.teal"}},
+		{Name: "minimal", Allow: []string{"int", "return", "txn", "category:Arithmetic"}, Paths: []string{"contracts/min/*"}},
+	}
+
+	src := `#pragma version 8
+txna ApplicationArgs 0
+byte "a"
+json_ref JSONUint64
+itxn_begin
+itxn_submit
+int 1
++
+return`
+
+	msgs := func(path string) string {
+		var ms []string
+		for _, d := range Process(src, WithPath(path), WithOpPolicies(ps...)).Diagnostics {
+			if d.Rule() == OpPolicyRuleInstance.Id() {
+				if d.Severity() != DiagErr {
+					t.Errorf("unexpected severity: %s", d.Severity())
+				}
+				ms = append(ms, d.String())
+			}
+		}
+		return strings.Join(ms, "\n")
+	}
+
+	if s := msgs("app.teal"); s != "opcode 'json_ref' is denied by policy 'no-json'" {
+		t.Errorf("unexpected diagnostics:\n%s", s)
+	}
+
+	expected := `opcode 'json_ref' is denied by policy 'no-json'
+opcode 'itxn_begin' is denied by policy 'treasury' (itxn_*)
+opcode 'itxn_submit' is denied by policy 'treasury' (itxn_*)`
+
+	if s := msgs("contracts/treasury.teal"); s != expected {
+		t.Errorf("unexpected diagnostics:\n%s", s)
+	}
+
+	expected = `opcode 'txna' is not allowed by policy 'minimal'
+opcode 'byte' is not allowed by policy 'minimal'
+opcode 'json_ref' is denied by policy 'no-json'
+opcode 'json_ref' is not allowed by policy 'minimal'
+opcode 'itxn_begin' is not allowed by policy 'minimal'
+opcode 'itxn_submit' is not allowed by policy 'minimal'`
+
+	if s := msgs("contracts/min/app.teal"); s != expected {
+		t.Errorf("unexpected diagnostics:\n%s", s)
+	}
+}
@@ -0,0 +1,1113 @@
+package teal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// preconditionMaxPaths is the max number of the paths explored for the precondition
+const preconditionMaxPaths = 256
+
+// preconditionMaxClauses is the max number of the clauses the disjunctions of a path are expanded into
+const preconditionMaxClauses = 64
+
+// preconditionMaxSteps is the max number of the ops executed on a path, e.g. in the loops
+const preconditionMaxSteps = 10000
+
+// preconditionMaxWalkSteps is the max number of the ops executed on all the paths of a walk
+const preconditionMaxWalkSteps = 200000
+
+// preconditionMaxLits is the max number of the conditions of a path
+const preconditionMaxLits = 256
+
+// preconditionMaxText is the max length of the text of a symbolic value, the values built from themselves in the loops
+// grow exponentially so the longer ones are replaced with the opaque unknown values
+const preconditionMaxText = 256
+
+// preconditionMaxAtoms is the max number of the comparisons of a condition value, it bounds the conditions like the texts
+const preconditionMaxAtoms = 256
+
+// pcond is a condition over the symbolic values, the "&&" and "||" conditions hold their args.
+type pcond struct {
+	x  string
+	op string
+	y  string
+
+	args []pcond
+
+	// group is set for the atoms over the transaction fields or the group size
+	group bool
+
+	// yconst is set if y is a constant
+	yconst bool
+
+	// line is the source line of the comparison
+	line int
+}
+
+var negatedCompare = map[string]string{
+	"==": "!=",
+	"!=": "==",
+	"<":  ">=",
+	">=": "<",
+	">":  "<=",
+	"<=": ">",
+}
+
+var swappedCompare = map[string]string{
+	"==": "==",
+	"!=": "!=",
+	"<":  ">",
+	">":  "<",
+	"<=": ">=",
+	">=": "<=",
+}
+
+func (c pcond) negate() pcond {
+	switch c.op {
+	case "&&", "||":
+		op := "||"
+		if c.op == "||" {
+			op = "&&"
+		}
+
+		n := pcond{op: op}
+		for _, a := range c.args {
+			n.args = append(n.args, a.negate())
+		}
+
+		return n
+	}
+
+	c.op = negatedCompare[c.op]
+
+	return c
+}
+
+func (c pcond) String() string {
+	switch c.op {
+	case "&&", "||":
+		var ss []string
+		for _, a := range c.args {
+			s := a.String()
+			if a.op == "&&" || a.op == "||" {
+				s = "(" + s + ")"
+			}
+			ss = append(ss, s)
+		}
+
+		return strings.Join(ss, " "+c.op+" ")
+	}
+
+	return c.x + " " + c.op + " " + c.y
+}
+
+// bounded reports whether the condition has at most n comparisons, each of them within the text limit.
+func (c pcond) bounded(n *int) bool {
+	switch c.op {
+	case "&&", "||":
+		for _, a := range c.args {
+			if !a.bounded(n) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	*n--
+
+	return *n >= 0 && len(c.x) <= preconditionMaxText && len(c.y) <= preconditionMaxText
+}
+
+// isGroup reports whether the condition is over the group only.
+func (c pcond) isGroup() bool {
+	switch c.op {
+	case "&&", "||":
+		for _, a := range c.args {
+			if !a.isGroup() {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return c.group
+}
+
+// literals splits the asserted condition into its conjuncts.
+func (c pcond) literals() []pcond {
+	if c.op != "&&" {
+		return []pcond{c}
+	}
+
+	var res []pcond
+	for _, a := range c.args {
+		res = append(res, a.literals()...)
+	}
+
+	return res
+}
+
+// symVal is a symbolic value of the precondition walk.
+type symVal struct {
+	text  string
+	group bool
+
+	// field is the transaction field of the value, used to render the constants compared with it
+	field    TxnField
+	hasField bool
+
+	known bool
+	uint  uint64
+
+	isBytes bool
+	bytes   []byte
+
+	cond *pcond
+
+	// infix is set for the arithmetic expressions rendered with the operator between the operands
+	infix bool
+}
+
+func symUint(v uint64) symVal {
+	return symVal{text: fmt.Sprint(v), known: true, uint: v}
+}
+
+func symBool(v bool) symVal {
+	if v {
+		return symUint(1)
+	}
+
+	return symUint(0)
+}
+
+func symBytes(bs []byte) symVal {
+	return symVal{text: renderBytes(bs), isBytes: true, bytes: bs}
+}
+
+func symField(text string, field TxnField) symVal {
+	return symVal{text: text, group: true, field: field, hasField: true}
+}
+
+// renderBytes renders the addresses in their text form, the printable bytes as a string and the other ones as hex.
+func renderBytes(bs []byte) string {
+	if len(bs) == len(types.Address{}) {
+		var a types.Address
+		copy(a[:], bs)
+		return a.String()
+	}
+
+	for _, r := range string(bs) {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return "0x" + hex.EncodeToString(bs)
+		}
+	}
+
+	return strconv.Quote(string(bs))
+}
+
+// render renders the value compared with the other one, e.g. pay for the TypeEnum constant.
+func (v symVal) render(other symVal) string {
+	if v.known && other.hasField {
+		switch other.field {
+		case TypeEnum:
+			if v.uint < uint64(len(TxnTypeNames)) {
+				return TxnTypeNames[v.uint]
+			}
+		case OnCompletion:
+			return OnCompletionConstType(v.uint).String()
+		}
+	}
+
+	if v.cond != nil {
+		return "(" + v.cond.String() + ")"
+	}
+
+	return v.text
+}
+
+// operand renders the value as an operand of an arithmetic expression.
+func (v symVal) operand() string {
+	s := v.render(symVal{})
+	if v.infix {
+		return "(" + s + ")"
+	}
+
+	return s
+}
+
+func (v symVal) isConst() bool {
+	return v.known || v.isBytes
+}
+
+// condition returns the condition of the value being non-zero, ok is set if the value is known.
+func (v symVal) condition() (c pcond, value bool, ok bool) {
+	if v.known {
+		return pcond{}, v.uint != 0, true
+	}
+
+	if v.cond != nil {
+		return *v.cond, false, false
+	}
+
+	return pcond{x: v.text, op: "!=", y: "0", group: v.group, yconst: true}, false, false
+}
+
+// symCond returns the condition value, it is rendered from the condition.
+func symCond(c pcond) symVal {
+	return symVal{group: c.isGroup(), cond: &c}
+}
+
+func symCompare(x symVal, y symVal, op string) symVal {
+	if x.known && y.known {
+		var r bool
+		switch op {
+		case "==":
+			r = x.uint == y.uint
+		case "!=":
+			r = x.uint != y.uint
+		case "<":
+			r = x.uint < y.uint
+		case ">":
+			r = x.uint > y.uint
+		case "<=":
+			r = x.uint <= y.uint
+		case ">=":
+			r = x.uint >= y.uint
+		}
+
+		return symBool(r)
+	}
+
+	// the constant is rendered on the right
+	if x.isConst() && !y.isConst() {
+		x, y = y, x
+		op = swappedCompare[op]
+	}
+
+	return symCond(pcond{
+		x:      x.render(y),
+		op:     op,
+		y:      y.render(x),
+		group:  x.group || y.group,
+		yconst: y.isConst(),
+	})
+}
+
+// symArith returns the arithmetic expression, the known operands are computed unless the op fails.
+func symArith(x symVal, y symVal, op Op) symVal {
+	if x.known && y.known {
+		switch op.(type) {
+		case *PlusExpr:
+			if s, carry := bits.Add64(x.uint, y.uint, 0); carry == 0 {
+				return symUint(s)
+			}
+		case *MinusExpr:
+			if y.uint <= x.uint {
+				return symUint(x.uint - y.uint)
+			}
+		case *MulExpr:
+			if hi, lo := bits.Mul64(x.uint, y.uint); hi == 0 {
+				return symUint(lo)
+			}
+		case *DivExpr:
+			if y.uint != 0 {
+				return symUint(x.uint / y.uint)
+			}
+		case *ModExpr:
+			if y.uint != 0 {
+				return symUint(x.uint % y.uint)
+			}
+		}
+	}
+
+	return symVal{text: fmt.Sprintf("%s %s %s", x.operand(), op, y.operand()), group: x.group || y.group, infix: true}
+}
+
+func symLogic(x symVal, y symVal, and bool) symVal {
+	xc, xv, xok := x.condition()
+	yc, yv, yok := y.condition()
+
+	switch {
+	case xok && yok:
+		if and {
+			return symBool(xv && yv)
+		}
+		return symBool(xv || yv)
+	case xok:
+		if xv == and {
+			return symCond(yc)
+		}
+		return symBool(xv)
+	case yok:
+		if yv == and {
+			return symCond(xc)
+		}
+		return symBool(yv)
+	}
+
+	op := "||"
+	if and {
+		op = "&&"
+	}
+
+	c := pcond{op: op}
+	for _, a := range []pcond{xc, yc} {
+		if a.op == op {
+			c.args = append(c.args, a.args...)
+		} else {
+			c.args = append(c.args, a)
+		}
+	}
+
+	return symCond(c)
+}
+
+// prePath is a path of the precondition walk.
+type prePath struct {
+	pc      int
+	stack   []symVal
+	scratch map[uint8]symVal
+	calls   []int
+	lits    []pcond
+	steps   int
+
+	// args is the first application arg read of the path not checked against NumAppArgs, +1
+	args int
+}
+
+func (p *prePath) clone() *prePath {
+	np := &prePath{
+		pc:      p.pc,
+		stack:   append([]symVal{}, p.stack...),
+		scratch: map[uint8]symVal{},
+		calls:   append([]int{}, p.calls...),
+		lits:    append([]pcond{}, p.lits...),
+		steps:   p.steps,
+		args:    p.args,
+	}
+
+	for k, v := range p.scratch {
+		np.scratch[k] = v
+	}
+
+	return np
+}
+
+func (p *prePath) push(vs ...symVal) {
+	p.stack = append(p.stack, vs...)
+}
+
+func (p *prePath) pop() (symVal, bool) {
+	if len(p.stack) == 0 {
+		return symVal{}, false
+	}
+
+	v := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	return v, true
+}
+
+// equals returns the constant the value is known to be equal to on the path, empty if none.
+func (p *prePath) equals(x string) string {
+	for _, o := range p.lits {
+		if o.op == "==" && o.x == x && o.yconst {
+			return o.y
+		}
+	}
+
+	return ""
+}
+
+// assume adds the conjuncts of the condition to the path, it returns false if they contradict the path.
+func (p *prePath) assume(c pcond) bool {
+	// the conditions of the path are rendered once, they are compared with each of the conjuncts
+	strs := make([]string, len(p.lits))
+	for i, o := range p.lits {
+		strs[i] = o.String()
+	}
+
+	for _, l := range c.literals() {
+		s := l.String()
+		neg := l.negate().String()
+
+		dup := false
+		for i, o := range p.lits {
+			if strs[i] == neg {
+				return false
+			}
+
+			if strs[i] == s {
+				dup = true
+			}
+
+			// x == a and x == b with different constants
+			if l.op == "==" && o.op == "==" && l.x == o.x && l.yconst && o.yconst && l.y != o.y {
+				return false
+			}
+		}
+
+		if dup {
+			continue
+		}
+
+		// x != b is implied by x == a with a different constant
+		if l.op == "!=" && l.yconst && p.equals(l.x) != "" {
+			continue
+		}
+
+		if l.op == "==" && l.yconst {
+			var lits []pcond
+			var ss []string
+			for i, o := range p.lits {
+				if o.op != "!=" || o.x != l.x || !o.yconst {
+					lits = append(lits, o)
+					ss = append(ss, strs[i])
+				}
+			}
+			p.lits, strs = lits, ss
+		}
+
+		p.lits = append(p.lits, l)
+		strs = append(strs, s)
+	}
+
+	return true
+}
+
+// Precondition is a necessary condition of the program approval over the transaction fields and the group size:
+// the program can approve only if one of the clauses holds, each of them is a conjunction of the conditions.
+type Precondition struct {
+	Clauses [][]string `json:"clauses"`
+
+	// not all of the paths could be explored to the end, their clauses hold the conditions found until then
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// String renders the precondition, e.g. "global GroupSize == 2 && gtxn 0 TypeEnum == pay".
+func (p Precondition) String() string {
+	if len(p.Clauses) == 0 {
+		return "false"
+	}
+
+	var ss []string
+	for _, c := range p.Clauses {
+		if len(c) == 0 {
+			return "true"
+		}
+
+		s := strings.Join(c, " && ")
+		if len(p.Clauses) > 1 && len(c) > 1 {
+			s = "(" + s + ")"
+		}
+
+		ss = append(ss, s)
+	}
+
+	return strings.Join(ss, " || ")
+}
+
+// Summary describes the precondition as a contract, e.g. "approves only if global GroupSize == 2".
+func (p Precondition) Summary() string {
+	var s string
+
+	switch p.String() {
+	case "false":
+		s = "never approves"
+	case "true":
+		s = "no transaction or group preconditions"
+	default:
+		s = "approves only if " + p.String()
+	}
+
+	if p.Incomplete {
+		s += " (not all of the paths could be explored)"
+	}
+
+	return s
+}
+
+// preWalk explores the paths of the program symbolically.
+type preWalk struct {
+	res    *ProcessResult
+	labels map[string]int
+
+	ints   []uint64
+	bytess [][]byte
+
+	todo  []*prePath
+	paths int
+	steps int
+
+	// opaques is the number of the values replaced with the opaque unknowns
+	opaques int
+
+	// approved are the conditions of the approving paths
+	approved   [][]pcond
+	incomplete bool
+
+	// pathsOnly skips recording the approving paths for the walks checking the paths only
+	pathsOnly bool
+
+	// txnaRead is called for the array fields of the transaction read with a constant index, i is the listing index of the op
+	txnaRead func(p *prePath, i int, field TxnField, n uint64)
+}
+
+// approve records the conditions of the path approving with the value.
+func (w *preWalk) approve(p *prePath, v symVal) {
+	c, value, ok := v.condition()
+	if ok {
+		if !value {
+			return
+		}
+	} else if !p.assume(c) {
+		return
+	}
+
+	w.record(p)
+}
+
+// record records the conditions of the approving path, the disjunctions are expanded into the separate clauses.
+func (w *preWalk) record(p *prePath) {
+	if w.pathsOnly {
+		return
+	}
+
+	for _, clause := range dnf(p.lits) {
+		np := &prePath{}
+		if np.assume(pcond{op: "&&", args: clause}) {
+			w.approved = append(w.approved, np.lits)
+		}
+	}
+}
+
+// disjuncts returns the condition in the disjunctive normal form.
+func (c pcond) disjuncts() [][]pcond {
+	switch c.op {
+	case "||":
+		var res [][]pcond
+		for _, a := range c.args {
+			res = append(res, a.disjuncts()...)
+		}
+		return res
+	case "&&":
+		return dnf(c.args)
+	}
+
+	return [][]pcond{{c}}
+}
+
+// dnf returns the conjunction of the conditions in the disjunctive normal form,
+// the conjunction is kept as is if it expands to more than preconditionMaxClauses clauses.
+func dnf(cs []pcond) [][]pcond {
+	res := [][]pcond{{}}
+
+	for _, c := range cs {
+		ds := c.disjuncts()
+
+		// the clauses are not shared, the single disjunct is appended in place
+		if len(ds) == 1 {
+			for i := range res {
+				res[i] = append(res[i], ds[0]...)
+			}
+			continue
+		}
+
+		var next [][]pcond
+
+		for _, d := range ds {
+			for _, r := range res {
+				next = append(next, append(append([]pcond{}, r...), d...))
+			}
+		}
+
+		if len(next) > preconditionMaxClauses {
+			return [][]pcond{cs}
+		}
+
+		res = next
+	}
+
+	return res
+}
+
+func (w *preWalk) fork(p *prePath, target string, c pcond) {
+	i, ok := w.labels[target]
+	if !ok {
+		w.incomplete = true
+		return
+	}
+
+	np := p.clone()
+	np.pc = i
+
+	if np.assume(c) {
+		w.todo = append(w.todo, np)
+	}
+}
+
+func (w *preWalk) intc(i int) symVal {
+	if i < len(w.ints) {
+		return symUint(w.ints[i])
+	}
+
+	return symVal{text: fmt.Sprintf("intc %d", i)}
+}
+
+func (w *preWalk) bytec(i int) symVal {
+	if i < len(w.bytess) {
+		return symBytes(w.bytess[i])
+	}
+
+	return symVal{text: fmt.Sprintf("bytec %d", i)}
+}
+
+// push pushes the values to the path, the values with too long texts are replaced with the opaque unknowns.
+func (w *preWalk) push(p *prePath, vs ...symVal) {
+	for i, v := range vs {
+		n := preconditionMaxAtoms
+		if len(v.text) <= preconditionMaxText && (v.cond == nil || v.cond.bounded(&n)) {
+			continue
+		}
+
+		// each opaque value is distinct so the conditions over them never contradict each other
+		w.opaques++
+		w.incomplete = true
+		vs[i] = symVal{text: fmt.Sprintf("unknown %d", w.opaques), group: v.group}
+	}
+
+	p.push(vs...)
+}
+
+// step executes the op of the path, it returns false when the path ends.
+func (w *preWalk) step(p *prePath) bool {
+	n := len(w.res.Listing)
+
+	if p.pc >= n {
+		if len(p.stack) == 1 {
+			w.approve(p, p.stack[0])
+		}
+		return false
+	}
+
+	p.steps++
+	w.steps++
+	if p.steps > preconditionMaxSteps || w.steps > preconditionMaxWalkSteps || len(p.lits) > preconditionMaxLits {
+		w.incomplete = true
+		w.record(p)
+		return false
+	}
+
+	op := w.res.Listing[p.pc]
+	p.pc++
+
+	if w.txnaRead != nil {
+		switch op := op.(type) {
+		case *TxnaExpr:
+			w.txnaRead(p, p.pc-1, op.Field, uint64(op.Index))
+		case *TxnasExpr:
+			if len(p.stack) > 0 && p.stack[len(p.stack)-1].known {
+				w.txnaRead(p, p.pc-1, op.Field, p.stack[len(p.stack)-1].uint)
+			}
+		}
+	}
+
+	pop := func() symVal {
+		v, ok := p.pop()
+		if !ok {
+			panic(errPreconditionUnknown)
+		}
+		return v
+	}
+
+	index := func(v symVal) string {
+		if v.known {
+			return fmt.Sprint(v.uint)
+		}
+		return "(" + v.render(symVal{}) + ")"
+	}
+
+	switch op := op.(type) {
+	case Nop:
+	case *IntcBlockExpr, *BytecBlockExpr:
+	case *IntExpr:
+		w.push(p, symUint(op.Value))
+	case *PushIntExpr:
+		w.push(p, symUint(op.Value))
+	case *PushIntsExpr:
+		for _, v := range op.Ints {
+			w.push(p, symUint(v))
+		}
+	case *IntcExpr:
+		w.push(p, w.intc(int(op.Index)))
+	case *Intc0Expr:
+		w.push(p, w.intc(0))
+	case *Intc1Expr:
+		w.push(p, w.intc(1))
+	case *Intc2Expr:
+		w.push(p, w.intc(2))
+	case *Intc3Expr:
+		w.push(p, w.intc(3))
+	case *ByteExpr:
+		w.push(p, symBytes(op.Value))
+	case *PushBytesExpr:
+		w.push(p, symBytes(op.Value))
+	case *PushBytessExpr:
+		for _, v := range op.Bytess {
+			w.push(p, symBytes(v))
+		}
+	case *BytecExpr:
+		w.push(p, w.bytec(int(op.Index)))
+	case *Bytec0Expr:
+		w.push(p, w.bytec(0))
+	case *Bytec1Expr:
+		w.push(p, w.bytec(1))
+	case *Bytec2Expr:
+		w.push(p, w.bytec(2))
+	case *Bytec3Expr:
+		w.push(p, w.bytec(3))
+	case *AddrExpr:
+		a, err := types.DecodeAddress(op.Address)
+		if err != nil {
+			w.push(p, symVal{text: op.Address})
+		} else {
+			w.push(p, symBytes(a[:]))
+		}
+	case *TxnExpr:
+		w.push(p, symField(fmt.Sprintf("txn %s", op.Field), op.Field))
+	case *TxnaExpr:
+		w.push(p, symField(fmt.Sprintf("txna %s %d", op.Field, op.Index), op.Field))
+	case *GtxnExpr:
+		w.push(p, symField(fmt.Sprintf("gtxn %d %s", op.Group, op.Field), op.Field))
+	case *GtxnaExpr:
+		w.push(p, symField(fmt.Sprintf("gtxna %d %s %d", op.Group, op.Field, op.Index), op.Field))
+	case *GtxnsExpr:
+		w.push(p, symField(fmt.Sprintf("gtxn %s %s", index(pop()), op.Field), op.Field))
+	case *GtxnsaExpr:
+		w.push(p, symField(fmt.Sprintf("gtxna %s %s %d", index(pop()), op.Field, op.Index), op.Field))
+	case *GlobalExpr:
+		w.push(p, symVal{text: fmt.Sprintf("global %s", op.Field), group: op.Field == GlobalGroupSize})
+	case *EqExpr, *NeqExpr, *LtExpr, *GtExpr, *LtEqExpr, *GtEqExpr:
+		cmp, _ := compareOpName(op)
+		y := pop()
+		x := pop()
+		v := symCompare(x, y, cmp)
+		if v.cond != nil {
+			v.cond.line = w.res.SourceLine(p.pc - 1)
+		}
+		w.push(p, v)
+	case *AndExpr:
+		y := pop()
+		x := pop()
+		w.push(p, symLogic(x, y, true))
+	case *OrExpr:
+		y := pop()
+		x := pop()
+		w.push(p, symLogic(x, y, false))
+	case *NotExpr:
+		c, v, ok := pop().condition()
+		if ok {
+			w.push(p, symBool(!v))
+		} else {
+			w.push(p, symCond(c.negate()))
+		}
+	case *AssertExpr:
+		c, v, ok := pop().condition()
+		if ok {
+			return v
+		}
+		return p.assume(c)
+	case *BnzExpr, *BzExpr:
+		var label string
+		jump := true
+
+		switch op := op.(type) {
+		case *BnzExpr:
+			label = op.Label.Name
+		case *BzExpr:
+			label = op.Label.Name
+			jump = false
+		}
+
+		c, v, ok := pop().condition()
+		if ok {
+			if v == jump {
+				i, found := w.labels[label]
+				if !found {
+					w.incomplete = true
+					return false
+				}
+				p.pc = i
+			}
+			return true
+		}
+
+		if jump {
+			w.fork(p, label, c)
+			return p.assume(c.negate())
+		}
+
+		w.fork(p, label, c.negate())
+		return p.assume(c)
+	case *BExpr:
+		i, ok := w.labels[op.Label.Name]
+		if !ok {
+			w.incomplete = true
+			return false
+		}
+		p.pc = i
+	case *SwitchExpr, *MatchExpr:
+		var targets []*LabelExpr
+		switch op := op.(type) {
+		case *SwitchExpr:
+			pop()
+			targets = op.Targets
+		case *MatchExpr:
+			pop()
+			for range op.Targets {
+				pop()
+			}
+			targets = op.Targets
+		}
+
+		for _, t := range targets {
+			w.fork(p, t.Name, pcond{op: "&&"})
+		}
+	case *CallSubExpr:
+		i, ok := w.labels[op.Label.Name]
+		if !ok {
+			w.incomplete = true
+			return false
+		}
+		p.calls = append(p.calls, p.pc)
+		p.pc = i
+	case *RetSubExpr:
+		if len(p.calls) == 0 {
+			panic(errPreconditionUnknown)
+		}
+		p.pc = p.calls[len(p.calls)-1]
+		p.calls = p.calls[:len(p.calls)-1]
+	case *ReturnExpr:
+		w.approve(p, pop())
+		return false
+	case *ErrExpr:
+		return false
+	case *PlusExpr, *MinusExpr, *MulExpr, *DivExpr, *ModExpr:
+		y := pop()
+		x := pop()
+		w.push(p, symArith(x, y, op))
+	case *StoreExpr:
+		p.scratch[op.Index] = pop()
+	case *LoadExpr:
+		v, ok := p.scratch[op.Index]
+		if !ok {
+			v = symVal{text: op.String()}
+		}
+		w.push(p, v)
+	case *DupExpr:
+		v := pop()
+		w.push(p, v, v)
+	case *PopExpr:
+		pop()
+	case *SwapExpr:
+		y := pop()
+		x := pop()
+		w.push(p, y, x)
+	default:
+		need, delta, ok := stackEffect(op)
+		if !ok {
+			panic(errPreconditionUnknown)
+		}
+
+		args := make([]symVal, need)
+		for i := need - 1; i >= 0; i-- {
+			args[i] = pop()
+		}
+
+		var texts []string
+		group := false
+		for _, a := range args {
+			texts = append(texts, a.render(symVal{}))
+			group = group || a.group
+		}
+
+		text := op.String()
+		if len(texts) > 0 {
+			text += "(" + strings.Join(texts, ", ") + ")"
+		}
+
+		for i := 0; i < need+delta; i++ {
+			v := symVal{text: text, group: group}
+			if need+delta > 1 {
+				v.text = fmt.Sprintf("%s[%d]", text, i)
+			}
+			w.push(p, v)
+		}
+	}
+
+	return true
+}
+
+type preconditionError struct{}
+
+func (preconditionError) Error() string {
+	return "unknown effect of the op"
+}
+
+var errPreconditionUnknown = preconditionError{}
+
+// run runs the path to the end, the paths the effect of an op is unknown are recorded with the conditions found so far.
+func (w *preWalk) run(p *prePath) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errPreconditionUnknown {
+				panic(r)
+			}
+
+			w.incomplete = true
+			w.record(p)
+		}
+	}()
+
+	for w.step(p) {
+	}
+}
+
+// SynthesizePrecondition synthesizes the weakest precondition of the program approval over the transaction fields
+// and the group size implied by the checks, e.g. "global GroupSize == 2 && gtxn 0 TypeEnum == pay". The paths are explored
+// symbolically, the conditions of each approving path make a clause and the conditions over the other values, like the args,
+// are left out so the precondition is necessary but may not be sufficient for the approval.
+func SynthesizePrecondition(res *ProcessResult) Precondition {
+	approved, incomplete := walkApproved(res)
+
+	var clauses [][]string
+
+	for _, lits := range approved {
+		var clause []string
+		seen := map[string]bool{}
+
+		for _, l := range lits {
+			if !l.isGroup() {
+				continue
+			}
+
+			s := l.String()
+			if !seen[s] {
+				seen[s] = true
+				clause = append(clause, s)
+			}
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return Precondition{
+		Clauses:    absorbClauses(clauses),
+		Incomplete: incomplete,
+	}
+}
+
+// walkApproved explores the paths of the program symbolically and returns the conditions of the approving paths,
+// incomplete is set if not all of the paths could be explored to the end.
+func walkApproved(res *ProcessResult) ([][]pcond, bool) {
+	w := newPreWalk(res)
+	w.walk()
+
+	return w.approved, w.incomplete
+}
+
+func newPreWalk(res *ProcessResult) *preWalk {
+	w := &preWalk{
+		res:    res,
+		labels: map[string]int{},
+	}
+
+	for i, op := range res.Listing {
+		switch op := op.(type) {
+		case *LabelExpr:
+			if _, ok := w.labels[op.Name]; !ok {
+				w.labels[op.Name] = i
+			}
+		case *IntcBlockExpr:
+			if w.ints == nil {
+				w.ints = op.Values
+			}
+		case *BytecBlockExpr:
+			if w.bytess == nil {
+				w.bytess = op.Values
+			}
+		}
+	}
+
+	return w
+}
+
+// walk explores the paths of the program from the start.
+func (w *preWalk) walk() {
+	w.todo = []*prePath{{scratch: map[uint8]symVal{}}}
+
+	for len(w.todo) > 0 {
+		p := w.todo[len(w.todo)-1]
+		w.todo = w.todo[:len(w.todo)-1]
+
+		w.paths++
+		if w.paths > preconditionMaxPaths {
+			w.incomplete = true
+			w.record(p)
+			continue
+		}
+
+		w.run(p)
+	}
+}
+
+// absorbClauses removes the duplicate clauses and the clauses implied by the weaker ones, e.g. a || a && b is a.
+func absorbClauses(clauses [][]string) [][]string {
+	subset := func(a []string, b []string) bool {
+		set := map[string]bool{}
+		for _, s := range b {
+			set[s] = true
+		}
+
+		for _, s := range a {
+			if !set[s] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	var res [][]string
+
+	for i, c := range clauses {
+		absorbed := false
+
+		for j, o := range clauses {
+			if i == j || !subset(o, c) {
+				continue
+			}
+
+			// the equal clauses keep the first one
+			if len(o) < len(c) || subset(c, o) && j < i {
+				absorbed = true
+				break
+			}
+		}
+
+		if !absorbed {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
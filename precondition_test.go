@@ -0,0 +1,85 @@
+package teal
+
+import "testing"
+
+func TestSynthesizePrecondition(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{
+			src:      "#pragma version 8\nglobal GroupSize\nint 2\n==\ngtxn 0 TypeEnum\nint pay\n==\n&&\ngtxn 0 Receiver\naddr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA\n==\n&&\narg 0\nlen\nint 32\n==\n&&",
+			expected: "global GroupSize == 2 && gtxn 0 TypeEnum == pay && gtxn 0 Receiver == Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA",
+		},
+		{
+			src:      "#pragma version 8\nglobal GroupSize\nint 1\n==\nbnz single\nglobal GroupSize\nint 2\n==\nassert\ngtxn 1 TypeEnum\nint axfer\n==\nreturn\nsingle:\ntxn Fee\nint 1000\n<=\nreturn",
+			expected: "(global GroupSize == 2 && gtxn 1 TypeEnum == axfer) || (global GroupSize == 1 && txn Fee <= 1000)",
+		},
+		{
+			// the constants are resolved from the constant blocks and the index of gtxns
+			src:      "#pragma version 8\nintcblock 0 1 4\nintc_1\ngtxns TypeEnum\nintc_2\n==\nassert\nintc_1\nreturn",
+			expected: "gtxn 1 TypeEnum == axfer",
+		},
+		{
+			// the paths contradicting the conditions are not explored
+			src:      "#pragma version 8\ntxn TypeEnum\nint pay\n==\nbz fail\ntxn TypeEnum\nint pay\n==\nreturn\nfail:\nint 0\nreturn",
+			expected: "txn TypeEnum == pay",
+		},
+		{
+			src:      "#pragma version 8\narg 0\nbyte \"secret\"\n==",
+			expected: "true",
+		},
+		{
+			src:      "#pragma version 8\nint 0",
+			expected: "false",
+		},
+	}
+
+	for _, test := range tests {
+		p := SynthesizePrecondition(Process(test.src))
+		if p.Incomplete {
+			t.Errorf("unexpected incomplete precondition of:\n%s", test.src)
+		}
+
+		if s := p.String(); s != test.expected {
+			t.Errorf("expected: %s, got: %s", test.expected, s)
+		}
+	}
+}
+
+func TestSynthesizePreconditionIncomplete(t *testing.T) {
+	// the loop is unrolled until the steps limit, the conditions found until then are kept
+	p := SynthesizePrecondition(Process("#pragma version 8\ntxn TypeEnum\nint appl\n==\nassert\nloop:\nb loop"))
+
+	if !p.Incomplete {
+		t.Error("expected an incomplete precondition")
+	}
+
+	if s := p.String(); s != "txn TypeEnum == appl" {
+		t.Errorf("unexpected precondition: %s", s)
+	}
+}
+
+// preconditionGrowthSources build the symbolic values from themselves in the loops, their texts grow exponentially
+var preconditionGrowthSources = []string{
+	"#pragma version 8\ntxna ApplicationArgs 0\nint 40\nl1:\nswap\ndup\nconcat\nsha256\nswap\nint 1\n-\ndup\nbnz l1\npop\nlen\nreturn",
+	"#pragma version 10\ngload 2 1\ntxna ApplicationArgs 0\nint 0\nl1:\ncover 2\nint 1\nbnz l1",
+	"#pragma version 8\ntxn Fee\nint 1\n==\nl1:\ndup\n&&\nint 1\nbnz l1",
+	"#pragma version 8\ntxn Fee\nint 1\n==\nl1:\ndup\n==\nint 1\nbnz l1",
+	"#pragma version 8\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 0\nl1:\nswap\nint 1\n-\nswap\ndup\nconcat\nsha256\nswap\ndup\nbnz l1\npop\nlen\nreturn",
+}
+
+func TestSynthesizePreconditionGrowth(t *testing.T) {
+	for _, src := range preconditionGrowthSources {
+		w := newPreWalk(Process(src))
+		w.walk()
+
+		if !w.incomplete {
+			t.Errorf("expected an incomplete walk, source:\n%s", src)
+		}
+
+		if w.opaques == 0 {
+			t.Errorf("expected the opaque values, source:\n%s", src)
+		}
+	}
+}
@@ -519,6 +519,8 @@ type ProcessContext interface {
 	mustReadEcGroup(name string) EcGroup
 	mustReadBase64Encoding(name string) Base64Encoding
 	mustReadPragma(name string) uint64
+	peekPragma() string
+	mustReadTypeTrack(name string) bool
 	mustReadAddr(name string) string
 	mustReadSignature(name string) string
 	mustReadTxnField(name string) TxnField
@@ -596,6 +598,19 @@ func (c *docContext) mustReadPragma(name string) (v uint64) {
 	return
 }
 
+func (c *docContext) peekPragma() string {
+	return ""
+}
+
+func (c *docContext) mustReadTypeTrack(name string) (v bool) {
+	c.arg(opItemArg{
+		Name: name,
+		Type: OpArgTypePragmaName,
+	})
+
+	return
+}
+
 func (c *docContext) mustReadAddr(name string) (v string) {
 	c.arg(opItemArg{
 		Name: name,
@@ -874,6 +889,11 @@ type parserContext struct {
 	protos map[string]*ProtoExpr
 	refc   map[string]int
 
+	cfg  processConfig
+	incs []*Include
+
+	asserts []TypeAssertion
+
 	// current state
 	line     int
 	label    *LabelExpr
@@ -961,6 +981,34 @@ func (c *parserContext) mustReadPragma(argName string) uint64 {
 	return version
 }
 
+func (c *parserContext) peekPragma() string {
+	if c.args.i < len(c.args.ts) {
+		return c.args.ts[c.args.i].String()
+	}
+
+	return ""
+}
+
+func (c *parserContext) mustReadTypeTrack(argName string) bool {
+	c.mcrs = append(c.mcrs, c.args.Curr())
+
+	c.mustRead("name")
+	c.mcrs = append(c.mcrs, c.args.Curr())
+
+	value := c.mustRead("typetrack value")
+	switch value {
+	case "true":
+		c.keys = append(c.keys, c.args.Curr())
+		return true
+	case "false":
+		c.keys = append(c.keys, c.args.Curr())
+		return false
+	default:
+		c.failCurr(errors.Errorf("unexpected typetrack value: %s, expected true or false", value))
+		return true
+	}
+}
+
 func (c *parserContext) mustReadAddr(name string) string {
 	value := c.mustRead("address")
 
@@ -985,7 +1033,7 @@ func (c *parserContext) mustReadSignature(name string) string {
 		c.failCurr(errors.New("missing quotes"))
 	}
 
-	// TODO: validate method sig
+	// the signature is validated by the linter
 	c.strs = append(c.strs, c.args.Curr())
 
 	return c.args.Text()
@@ -1166,7 +1214,15 @@ func (c *parserContext) parseUint64(name string) uint64 {
 }
 
 func (c *parserContext) parseConstUint64(name string) uint64 {
-	v, err := readConstInt(c.args)
+	var v uint64
+	var err error
+
+	// goal does not evaluate the expressions
+	if !c.cfg.goal && isConstIntExpr(c.args.Text()) {
+		v, err = evalConstInt(c.args.Text())
+	} else {
+		v, err = readConstInt(c.args)
+	}
 	if err != nil {
 		c.failCurr(errors.Wrapf(err, "failed to parse uint64: %s", name))
 	}
@@ -1176,10 +1232,46 @@ func (c *parserContext) parseConstUint64(name string) uint64 {
 	return v
 }
 
+// stackForms are the ops that read the immediate from the stack instead, e.g. for dynamic or wider values.
+var stackForms = map[string]string{
+	"load i":      "loads",
+	"store i":     "stores",
+	"arg index":   "args",
+	"txna i":      "txnas",
+	"gtxn t":      "gtxns",
+	"gtxna t":     "gtxnsa",
+	"gtxna i":     "gtxnas",
+	"gtxnsa i":    "gtxnsas",
+	"itxna i":     "itxnas",
+	"gitxna i":    "gitxnas",
+	"gload t":     "gloads",
+	"gaid t":      "gaids",
+	"substring s": "substring3",
+	"substring e": "substring3",
+	"extract s":   "extract3",
+	"extract l":   "extract3",
+	"replace2 s":  "replace3",
+	"intc value":  "int",
+	"bytec index": "byte",
+}
+
+// immediateError adds the stack form of the current op as a suggested replacement.
+func (c *parserContext) immediateError(err error, typ string, name string) error {
+	err = errors.Wrapf(err, "failed to parse %s: %s", typ, name)
+
+	if len(c.args.ts) > 0 {
+		if alt, ok := stackForms[c.args.ts[0].String()+" "+name]; ok {
+			err = errors.Errorf("%s - use %s to take the value from the stack", err, alt)
+		}
+	}
+
+	return err
+}
+
 func (c *parserContext) parseUint8(name string) uint8 {
 	v, err := readUint8(c.args.Text())
 	if err != nil {
-		c.failCurr(errors.Wrapf(err, "failed to parse uint8: %s", name))
+		c.failCurr(c.immediateError(err, "uint8", name))
 	}
 
 	c.nums = append(c.nums, c.args.Curr())
@@ -1190,7 +1282,7 @@ func (c *parserContext) parseUint8(name string) uint8 {
 func (c *parserContext) parseInt8(name string) int8 {
 	v, err := readInt8(c.args.Text())
 	if err != nil {
-		c.failCurr(errors.Wrapf(err, "failed to parse int8: %s", name))
+		c.failCurr(c.immediateError(err, "int8", name))
 	}
 
 	c.nums = append(c.nums, c.args.Curr())
@@ -1466,12 +1558,16 @@ var Ops = func() *opItems {
 		extra := opDocExtras[info.Name]
 
 		full := doc
-		if extra != "" {
+		for _, s := range []string{extra, formsDoc(info.Name)} {
+			if s == "" {
+				continue
+			}
+
 			if full != "" {
 				full += "\n"
 			}
 
-			full += extra
+			full += s
 		}
 
 		var fullnames []string
@@ -1570,6 +1666,12 @@ var immBytess = []interface{}{}
 var typeTxField = []interface{}{}
 
 func opPragma(c ProcessContext) {
+	if c.peekPragma() == "typetrack" {
+		enabled := c.mustReadTypeTrack("typetrack")
+		c.emit(&TypeTrackExpr{Enabled: enabled})
+		return
+	}
+
 	version := c.mustReadPragma("version")
 	c.emit(&PragmaExpr{Version: uint8(version)})
 }
@@ -2549,6 +2651,12 @@ func (v RequiredVersion) EndCharacter() int {
 	return v.End
 }
 
+// ProcessResult is the result of processing a source.
+//
+// The fields used by the analyzers are the extension contract kept stable across the releases: Mode, Version, Clear,
+// Diagnostics, Symbols, Listing, Lines, Sublines, Includes, Metadata and Artifacts with the SourceLine method.
+// The diagnostics and the symbols appended by the analyzers are published by the LSP server created with
+// lsp.WithProcessOptions like the built-in ones. The other fields serve the LSP and may change.
 type ProcessResult struct {
 	Mode ProgramMode
 
@@ -2566,6 +2674,9 @@ type ProcessResult struct {
 	Listing Listing
 	Lines   []Line
 
+	// Sublines holds the instructions of the source lines split by ';', one per Listing item
+	Sublines []Subline
+
 	Ops []Token
 
 	Numbers  []Token
@@ -2576,6 +2687,31 @@ type ProcessResult struct {
 	Redundants []RedundantLine
 
 	RefCounts map[string]int
+
+	Includes []*Include
+
+	Clear bool
+
+	// Metadata is read from the @key: value comments of the header
+	Metadata Metadata
+
+	// TypeAssertions are the stack types forced with `//#pragma type` comments
+	TypeAssertions []TypeAssertion
+
+	// StackDepths are the stack depth bounds before each instruction of the listing
+	StackDepths []StackDepth
+
+	// Eol is the dominant line ending of the source used by the generated edits
+	Eol string
+
+	// KnownAddresses are the labeled addresses the literals are matched against
+	KnownAddresses []KnownAddress
+
+	// ExperimentalFixes enables the quick fixes that restructure the code, e.g. the gtxn-loop rewrite
+	ExperimentalFixes bool
+
+	// Artifacts are the values computed by the analyzers keyed by their names
+	Artifacts map[string]any
 }
 
 func (r ProcessResult) SymbolsForRefWithin(rg Range) []Symbol {
@@ -2658,6 +2794,15 @@ type SignatureInlayHint struct {
 type InlayHints struct {
 	Named   []NamedInlayHint
 	Decoded []DecodedInlayHint
+
+	// Depths are the stack depths before the instructions, placed at the end of the line
+	Depths []InlayHint
+
+	// Addresses are the names of the known address literals
+	Addresses []InlayHint
+
+	// Abi are the decoded values of the literals that look ABI encoded
+	Abi []InlayHint
 }
 
 type InlayHint struct {
@@ -2683,12 +2828,12 @@ func (r ProcessResult) ArgFieldName(t NewOpArgType, v int) string {
 func (r ProcessResult) InlayHints(rg Range) InlayHints {
 	var ihs InlayHints
 
-	for li := rg.StartLine(); li <= rg.EndLine(); li++ {
-		if li >= len(r.Lines) {
+	for _, sub := range r.Sublines {
+		if sub.Line < rg.StartLine() || sub.Line > rg.EndLine() {
 			continue
 		}
 
-		ln := r.Lines[li]
+		ln := sub.Tokens
 
 		var ok bool
 		var spec opItem
@@ -2770,6 +2915,50 @@ func (r ProcessResult) InlayHints(rg Range) InlayHints {
 		}
 	}
 
+	for i, sub := range r.Sublines {
+		if i >= len(r.StackDepths) || !r.StackDepths[i].Known {
+			continue
+		}
+
+		if sub.Line < rg.StartLine() || sub.Line > rg.EndLine() || len(sub.Tokens) == 0 {
+			continue
+		}
+
+		if _, ok := r.getOp(sub.Tokens[0].String()); !ok {
+			continue
+		}
+
+		ihs.Depths = append(ihs.Depths, InlayHint{
+			Line:      sub.Line,
+			Character: sub.Tokens.End(),
+			Label:     fmt.Sprintf("depth %s", r.StackDepths[i]),
+		})
+	}
+
+	for _, ref := range r.AddressRefs() {
+		if ref.Line < rg.StartLine() || ref.Line > rg.EndLine() {
+			continue
+		}
+
+		ihs.Addresses = append(ihs.Addresses, InlayHint{
+			Line:      ref.Line,
+			Character: ref.End,
+			Label:     ref.Address.Name,
+		})
+	}
+
+	for _, c := range r.AbiConstants() {
+		if c.Line < rg.StartLine() || c.Line > rg.EndLine() {
+			continue
+		}
+
+		ihs.Abi = append(ihs.Abi, InlayHint{
+			Line:      c.Line,
+			Character: c.End,
+			Label:     c.Short(),
+		})
+	}
+
 	return ihs
 }
 
@@ -2848,22 +3037,31 @@ func (r ProcessResult) SymOrRefAt(rg Range) string {
 	return ""
 }
 
-func (r ProcessResult) ArgAt(l int, ch int) (opItemArg, int, bool) {
-	var res opItemArg
+// ImmIndexAt returns the index of the immediate argument at the position or of the next immediate argument
+// if the position is not on one, -1 if there is no op at the position.
+func (r ProcessResult) ImmIndexAt(l int, ch int) int {
+	ln := r.LineAt(l, ch)
+	if len(ln) == 0 {
+		return -1
+	}
 
-	if l >= len(r.Lines) {
-		return res, -1, false
+	if _, idx, ok := ln.ImmAt(ch); ok {
+		return idx
 	}
 
-	ln := r.Lines[l]
+	return len(ln) - 1
+}
 
-	curr := len(ln) - 1
+func (r ProcessResult) ArgAt(l int, ch int) (opItemArg, int, bool) {
+	var res opItemArg
 
-	_, idx, ok := ln.ImmAt(ch)
-	if ok {
-		curr = idx
+	ln := r.LineAt(l, ch)
+	if len(ln) == 0 {
+		return res, -1, false
 	}
 
+	curr := r.ImmIndexAt(l, ch)
+
 	op := ln[0]
 	info, ok := Ops.Get(OpContext{
 		Name:    op.String(),
@@ -2905,11 +3103,7 @@ func (r ProcessResult) ArgValsAt(l int, ch int) []opItemArgVal {
 }
 
 func (r ProcessResult) DocAt(l int, ch int) string {
-	if l >= len(r.Lines) {
-		return ""
-	}
-
-	ln := r.Lines[l]
+	ln := r.LineAt(l, ch)
 
 	for i, t := range ln {
 		if t.b > ch || t.End() < ch {
@@ -3048,6 +3242,16 @@ func (r ProcessResult) DocAt(l int, ch int) string {
 								}
 							}
 
+						case OpArgTypeConstInt:
+							if doc := constIntDoc(tok.String()); doc != "" {
+								return doc
+							}
+
+						case OpArgTypeLabel:
+							if doc := r.labelDoc(tok.String()); doc != "" {
+								return doc
+							}
+
 						case OpArgTypeEcdsaCurve:
 							spec, ok := ecdsaCurveSpecByName[tok.String()]
 							if ok {
@@ -3129,7 +3333,7 @@ func readTokens(source string) ([]Token, []Diagnostic) {
 	return ts, diags
 }
 
-func Process(source string) *ProcessResult {
+func Process(source string, opts ...ProcessOption) *ProcessResult {
 	c := &parserContext{
 		version: 1,
 		ops:     []Op{},
@@ -3138,6 +3342,18 @@ func Process(source string) *ProcessResult {
 		refc:    map[string]int{},
 	}
 
+	for _, opt := range opts {
+		opt(&c.cfg)
+	}
+
+	if c.cfg.version != 0 {
+		c.version = c.cfg.version
+	}
+
+	if c.cfg.mode != ModeNone {
+		c.mode = c.cfg.mode
+	}
+
 	var ts []Token
 	ts, c.diag = readTokens(source)
 
@@ -3170,19 +3386,22 @@ func Process(source string) *ProcessResult {
 		}
 	}
 
-	var lts []Line
+	subs := splitSublines(lines)
+
 	var ops []Token
 	var lsyms []*labelSymbol
 	var vers []RequiredVersion
 
-	for line, l := range lines {
-		c.line = line
-		c.args = &arguments{ts: l}
+	for i, sub := range subs {
+		c.line = i
+		c.args = &arguments{ts: substituteConstants(sub.Tokens, c.cfg.constants)}
 		func() {
 			defer func() {
 				switch v := recover().(type) {
 				case recoverable:
-					c.emit(Empty) // consider replacing with Raw string expr
+					if len(c.ops) == i {
+						c.emit(Empty) // consider replacing with Raw string expr
+					}
 				case nil:
 				default:
 					fmt.Printf("unrecoverable: %v", v)
@@ -3196,10 +3415,23 @@ func Process(source string) *ProcessResult {
 			}
 
 			if c.args.Curr().Type() == TokenComment {
-				if strings.TrimSpace(c.args.Curr().String()) == "#pragma mode logicsig" {
+				switch strings.TrimSpace(c.args.Curr().String()) {
+				case "#pragma mode logicsig":
 					c.mode = ModeSig
-				} else {
-					c.comment(c.args.Curr().String())
+				case "#pragma program clear":
+					c.cfg.clear = true
+				default:
+					text := strings.TrimSpace(c.args.Curr().String())
+					if strings.HasPrefix(text, typeAssertionPrefix) {
+						ts, err := parseTypeAssertion(strings.TrimPrefix(text, typeAssertionPrefix))
+						if err != nil {
+							c.failCurr(err)
+						}
+
+						c.asserts = append(c.asserts, TypeAssertion{Index: i, Types: ts})
+					} else {
+						c.comment(c.args.Curr().String())
+					}
 				}
 				c.emit(Empty)
 				return
@@ -3232,6 +3464,8 @@ func Process(source string) *ProcessResult {
 				c.emit(Empty)
 			case "#pragma":
 				opPragma(c)
+			case "#include":
+				c.include()
 			default:
 				info, ok := Ops.Get(OpContext{
 					Name:    name,
@@ -3293,20 +3527,37 @@ func Process(source string) *ProcessResult {
 				return
 			}
 		}()
+	}
 
-		lts = append(lts, c.args.ts)
+	ext := map[string]bool{}
+	used := map[string]int{}
+
+	for k, v := range c.refc {
+		used[k] += v
 	}
 
-	l := &Linter{l: c.ops}
+	incs := ProcessResult{Includes: c.incs}.Included()
+	for _, inc := range incs {
+		for _, sym := range inc.Result.Symbols {
+			ext[sym.Name()] = true
+		}
+		for k, v := range inc.Result.RefCounts {
+			used[k] += v
+		}
+	}
+
+	filterIncludedUnused(incs, used)
+
+	l := &Linter{l: c.ops, ext: ext, clear: c.cfg.clear, goal: c.cfg.goal}
 	l.Lint()
 
 	for _, le := range l.errs {
-		ln := lts[le.Line()]
+		sub := subs[le.Line()]
 		c.diag = append(c.diag, lintError{
 			error: le,
-			l:     le.Line(),
-			b:     ln.Begin(),
-			e:     ln.End(),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
 			s:     le.Severity(),
 			r:     le.Rule(),
 		})
@@ -3319,7 +3570,7 @@ func Process(source string) *ProcessResult {
 
 	var mrefs []Token
 	for _, ref := range c.refs {
-		if _, ok := symm[ref.String()]; !ok {
+		if _, ok := symm[ref.String()]; !ok && !ext[ref.String()] {
 			mrefs = append(mrefs, ref)
 		}
 	}
@@ -3343,21 +3594,88 @@ func Process(source string) *ProcessResult {
 		Mode:         c.mode,
 		Version:      c.version,
 		VersionToken: c.vtok,
-		Diagnostics:  c.diag,
+		Diagnostics:  c.cfg.apply(c.diag),
 		MissRefs:     mrefs,
 		Symbols:      syms,
 		SymbolRefs:   c.refs,
 		Tokens:       ts,
-		Lines:        lts,
+		Lines:        lines,
+		Sublines:     subs,
 		Listing:      c.ops,
 		Ops:          ops,
 		Numbers:      c.nums,
 		Strings:      c.strs,
 		Keywords:     c.keys,
 		Macros:       c.mcrs,
-		Redundants:   l.reds,
+		Redundants:   remapRedundants(l.reds, subs),
 		Versions:     vers,
 		RefCounts:    c.refc,
+		Includes:     c.incs,
+		Clear:        c.cfg.clear,
+		Metadata:     readMetadata(ts),
+		Eol:          DetectEol(source),
+
+		TypeAssertions: c.asserts,
+		KnownAddresses: knownAddresses(c.cfg.addresses),
+
+		ExperimentalFixes: c.cfg.experimental,
+
+		Artifacts: map[string]any{},
+	}
+
+	if result.Clear {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkClearStateBudget(result))...)
+	}
+
+	if result.Mode == ModeApp && !result.Clear {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkBudgetPooling(result))...)
+	}
+
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkDeprecations(result))...)
+
+	result.StackDepths = analyzeStackDepths(result)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkStackDepths(result))...)
+
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkWideMath(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkUnusedFrames(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkStackComments(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkMethodSignatures(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkBurnReceivers(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkImmediateForms(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkGtxnChains(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkScratchPressure(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkMissingExpiry(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkUncheckedAppArgs(result))...)
+	result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkUnavailableRefs(result))...)
+
+	if len(c.cfg.policies) > 0 {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkOpPolicies(result, c.cfg.policies, c.cfg.path))...)
+	}
+
+	if c.cfg.consensus != nil {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkConsensus(result, *c.cfg.consensus))...)
+	}
+
+	if hasBoxOps(result.Listing) {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkBoxKeys(result))...)
+
+		p := LatestConsensus()
+		if c.cfg.consensus != nil {
+			p = *c.cfg.consensus
+		}
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkBoxBudget(result, p))...)
+	}
+
+	if hasEqualityOps(result.Listing) {
+		result.Diagnostics = append(result.Diagnostics, c.cfg.apply(checkLengthMismatches(result))...)
+	}
+
+	for _, a := range c.cfg.analyzers {
+		n := len(result.Diagnostics)
+		a(result)
+
+		added := append([]Diagnostic{}, result.Diagnostics[n:]...)
+		result.Diagnostics = append(result.Diagnostics[:n], c.cfg.apply(added)...)
 	}
 
 	return result
@@ -1,6 +1,7 @@
 package teal
 
 import (
+	"sync"
 	"testing"
 )
 
@@ -145,3 +146,27 @@ func TestOverlaps(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessResultConcurrentReads(t *testing.T) {
+	res := Process("#pragma version 8\nint 1\nstore 0\nload 0\ncallsub double\nreturn\ndouble:\nproto 1 1\nframe_dig -1\ndup\n+\nretsub")
+	rg := testRange{sl: 0, sc: 0, el: 12, ec: 0}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res.InlayHints(rg)
+			res.CodeActions(rg)
+			res.SymbolsWithin(rg)
+			res.AllReferences()
+			res.ScratchNames()
+			res.FrameNames()
+			res.DocAt(4, 2)
+			res.WideMath()
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,433 @@
+package teal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProfileFrame is a subroutine executing the source line, Line is one-based.
+type ProfileFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+}
+
+// ProfileSample is the value consumed with the call stack, the root frame first and the executing line last.
+type ProfileSample struct {
+	Frames []ProfileFrame `json:"frames"`
+	Value  int            `json:"value"`
+}
+
+// Profile is the execution profile keyed by the subroutines and the lines.
+type Profile struct {
+	Name string `json:"name"`
+
+	// Unit is "budget" for the VM runs and "ops" for the simulate traces, which do not report the op costs
+	Unit string `json:"unit"`
+
+	Samples []ProfileSample `json:"samples"`
+}
+
+// Total returns the sum of the sample values.
+func (p Profile) Total() int {
+	n := 0
+	for _, s := range p.Samples {
+		n += s.Value
+	}
+
+	return n
+}
+
+// profileBuilder aggregates the values of the executed ops by the call stack.
+type profileBuilder struct {
+	res  *ProcessResult
+	file string
+
+	stack []ProfileFrame
+
+	p     Profile
+	index map[string]int
+}
+
+func newProfileBuilder(res *ProcessResult, name string, file string, unit string) *profileBuilder {
+	return &profileBuilder{
+		res:   res,
+		file:  file,
+		stack: []ProfileFrame{{Function: MainName, File: file}},
+		p:     Profile{Name: name, Unit: unit},
+		index: map[string]int{},
+	}
+}
+
+// op adds the value of the op at the listing index and follows the callsub and the retsub.
+func (pb *profileBuilder) op(i int, value int) {
+	top := &pb.stack[len(pb.stack)-1]
+	top.Line = pb.res.SourceLine(i) + 1
+
+	var key strings.Builder
+	for _, f := range pb.stack {
+		fmt.Fprintf(&key, "%s:%d;", f.Function, f.Line)
+	}
+
+	if k, ok := pb.index[key.String()]; ok {
+		pb.p.Samples[k].Value += value
+	} else {
+		pb.index[key.String()] = len(pb.p.Samples)
+		pb.p.Samples = append(pb.p.Samples, ProfileSample{
+			Frames: append([]ProfileFrame{}, pb.stack...),
+			Value:  value,
+		})
+	}
+
+	if i < 0 || i >= len(pb.res.Listing) {
+		return
+	}
+
+	switch op := pb.res.Listing[i].(type) {
+	case *CallSubExpr:
+		pb.stack = append(pb.stack, ProfileFrame{Function: op.Label.Name, File: pb.file})
+	case *RetSubExpr:
+		if len(pb.stack) > 1 {
+			pb.stack = pb.stack[:len(pb.stack)-1]
+		}
+	}
+}
+
+// Profile returns the budget consumed by the lines of the branch path.
+func (b *VmBranch) Profile(name string, file string) Profile {
+	pb := newProfileBuilder(b.vm.Process, name, file, "budget")
+
+	for k, i := range b.Lines {
+		cost := 1
+		if k < len(b.Costs) {
+			cost = b.Costs[k]
+		}
+
+		pb.op(i, cost)
+	}
+
+	return pb.p
+}
+
+// SimulateTrace is the program counters executed by the programs of a transaction in an algod simulate response.
+type SimulateTrace struct {
+	Approval   []int
+	ClearState []int
+	LogicSig   []int
+}
+
+type simulateUnit struct {
+	Pc int `json:"pc"`
+}
+
+type simulateResponse struct {
+	TxnGroups []struct {
+		TxnResults []struct {
+			ExecTrace struct {
+				Approval   []simulateUnit `json:"approval-program-trace"`
+				ClearState []simulateUnit `json:"clear-state-program-trace"`
+				LogicSig   []simulateUnit `json:"logic-sig-trace"`
+			} `json:"exec-trace"`
+		} `json:"txn-results"`
+	} `json:"txn-groups"`
+}
+
+func simulatePcs(us []simulateUnit) []int {
+	var res []int
+	for _, u := range us {
+		res = append(res, u.Pc)
+	}
+
+	return res
+}
+
+// ParseSimulateTraces parses the exec traces of the algod simulate response run with the exec trace enabled,
+// indexed by the group and the transaction.
+func ParseSimulateTraces(bs []byte) ([][]SimulateTrace, error) {
+	var r simulateResponse
+
+	err := json.Unmarshal(bs, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse simulate response")
+	}
+
+	var res [][]SimulateTrace
+	for _, g := range r.TxnGroups {
+		var ts []SimulateTrace
+		for _, t := range g.TxnResults {
+			ts = append(ts, SimulateTrace{
+				Approval:   simulatePcs(t.ExecTrace.Approval),
+				ClearState: simulatePcs(t.ExecTrace.ClearState),
+				LogicSig:   simulatePcs(t.ExecTrace.LogicSig),
+			})
+		}
+		res = append(res, ts)
+	}
+
+	return res, nil
+}
+
+// pcIndexes maps the program counters of the source map to the listing indexes,
+// the ops on the same line are assigned to the mapped program counters in order.
+func pcIndexes(res *ProcessResult, m SourceMap) (map[int]int, error) {
+	lines, err := m.PcLines()
+	if err != nil {
+		return nil, err
+	}
+
+	ops := map[int][]int{}
+	for i, op := range res.Listing {
+		if _, ok := op.(Nop); ok || i >= len(res.Sublines) {
+			continue
+		}
+
+		ln := res.Sublines[i].Line
+		ops[ln] = append(ops[ln], i)
+	}
+
+	pcs := make([]int, 0, len(lines))
+	for pc := range lines {
+		pcs = append(pcs, pc)
+	}
+	sort.Ints(pcs)
+
+	index := map[int]int{}
+	seen := map[int]int{}
+
+	for _, pc := range pcs {
+		ln := lines[pc]
+
+		k := seen[ln]
+		seen[ln]++
+
+		if k < len(ops[ln]) {
+			index[pc] = ops[ln][k]
+		}
+	}
+
+	return index, nil
+}
+
+// SimulateProfile returns the number of the ops executed by the lines of the simulate trace program counters,
+// mapped to the source with the source map of the program.
+func SimulateProfile(res *ProcessResult, m SourceMap, pcs []int, name string, file string) (Profile, error) {
+	index, err := pcIndexes(res, m)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	pb := newProfileBuilder(res, name, file, "ops")
+
+	for _, pc := range pcs {
+		i, ok := index[pc]
+		if !ok {
+			return Profile{}, errors.Errorf("no source line for pc: %d", pc)
+		}
+
+		pb.op(i, 1)
+	}
+
+	return pb.p, nil
+}
+
+// WriteSpeedscope writes the profile as a sampled speedscope profile.
+func (p Profile) WriteSpeedscope(w io.Writer) error {
+	var frames []speedscopeFrame
+	index := map[ProfileFrame]int{}
+
+	samples := [][]int{}
+	weights := []int{}
+
+	for _, s := range p.Samples {
+		var ids []int
+		for _, f := range s.Frames {
+			i, ok := index[f]
+			if !ok {
+				i = len(frames)
+				index[f] = i
+				frames = append(frames, speedscopeFrame{Name: f.Function, File: f.File, Line: f.Line})
+			}
+			ids = append(ids, i)
+		}
+
+		samples = append(samples, ids)
+		weights = append(weights, s.Value)
+	}
+
+	err := json.NewEncoder(w).Encode(speedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     p.Name,
+		Exporter: "teal",
+		Shared:   speedscopeShared{Frames: frames},
+		Profiles: []speedscopeProfile{
+			{
+				Type:     "sampled",
+				Name:     p.Name,
+				Unit:     "none",
+				EndValue: p.Total(),
+				Samples:  samples,
+				Weights:  weights,
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to write speedscope profile")
+	}
+
+	return nil
+}
+
+// protoBuffer encodes the protobuf wire format.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	b.WriteByte(byte(v))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(v)
+}
+
+func (b *protoBuffer) bytes(field int, bs []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(bs)))
+	b.Write(bs)
+}
+
+func (b *protoBuffer) packed(field int, vs []uint64) {
+	var p protoBuffer
+	for _, v := range vs {
+		p.varint(v)
+	}
+
+	b.bytes(field, p.Bytes())
+}
+
+// WritePprof writes the profile as a gzipped pprof protobuf, the lines are the locations of the subroutine functions.
+func (p Profile) WritePprof(w io.Writer) error {
+	strs := []string{""}
+	strIndex := map[string]int{"": 0}
+
+	str := func(s string) uint64 {
+		i, ok := strIndex[s]
+		if !ok {
+			i = len(strs)
+			strIndex[s] = i
+			strs = append(strs, s)
+		}
+		return uint64(i)
+	}
+
+	type function struct {
+		name string
+		file string
+	}
+
+	var out protoBuffer
+
+	valueType := func(typ string, unit string) []byte {
+		var vt protoBuffer
+		vt.uint(1, str(typ))
+		vt.uint(2, str(unit))
+		return vt.Bytes()
+	}
+
+	out.bytes(1, valueType(p.Unit, "count"))
+
+	functions := map[function]uint64{}
+	locations := map[ProfileFrame]uint64{}
+
+	var fbufs, lbufs [][]byte
+
+	for _, s := range p.Samples {
+		var ids []uint64
+
+		// the leaf location goes first
+		for k := len(s.Frames) - 1; k >= 0; k-- {
+			f := s.Frames[k]
+
+			lid, ok := locations[f]
+			if !ok {
+				fn := function{name: f.Function, file: f.File}
+
+				fid, ok := functions[fn]
+				if !ok {
+					fid = uint64(len(functions) + 1)
+					functions[fn] = fid
+
+					var fb protoBuffer
+					fb.uint(1, fid)
+					fb.uint(2, str(fn.name))
+					fb.uint(3, str(fn.name))
+					fb.uint(4, str(fn.file))
+					fbufs = append(fbufs, fb.Bytes())
+				}
+
+				lid = uint64(len(locations) + 1)
+				locations[f] = lid
+
+				var line protoBuffer
+				line.uint(1, fid)
+				line.uint(2, uint64(f.Line))
+
+				var lb protoBuffer
+				lb.uint(1, lid)
+				lb.bytes(4, line.Bytes())
+				lbufs = append(lbufs, lb.Bytes())
+			}
+
+			ids = append(ids, lid)
+		}
+
+		var sb protoBuffer
+		sb.packed(1, ids)
+		sb.packed(2, []uint64{uint64(s.Value)})
+		out.bytes(2, sb.Bytes())
+	}
+
+	for _, lb := range lbufs {
+		out.bytes(4, lb)
+	}
+
+	for _, fb := range fbufs {
+		out.bytes(5, fb)
+	}
+
+	period := valueType(p.Unit, "count")
+
+	// the string table is complete only after all of the references are encoded
+	for _, s := range strs {
+		out.bytes(6, []byte(s))
+	}
+
+	out.bytes(11, period)
+	out.uint(12, 1)
+
+	zw := gzip.NewWriter(w)
+
+	_, err := zw.Write(out.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "failed to write pprof profile")
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to write pprof profile")
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package teal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestVmProfile(t *testing.T) {
+	res := Process(budgetSource)
+
+	c := EstimateCost(res, VmDefaultBudget)
+	p := c.Path.Profile("p", "p.teal")
+
+	if p.Unit != "budget" || p.Total() != 14 {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+
+	// the second call of b from the main program
+	expected := []ProfileFrame{
+		{Function: MainName, File: "p.teal", Line: 3},
+		{Function: "b", File: "p.teal", Line: 12},
+	}
+
+	found := false
+	for _, s := range p.Samples {
+		if reflect.DeepEqual(s.Frames, expected) {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("missing sample %v in: %+v", expected, p.Samples)
+	}
+}
+
+func TestSimulateProfile(t *testing.T) {
+	res := Process("#pragma version 8\ncallsub s\nreturn\ns:\nint 1\nretsub")
+
+	m, err := ParseSourceMap([]byte(`{"version":3,"sources":["a.teal"],"names":[],"mappings":";AACA;;;AACA;;;AAEA;;AACA"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := ParseSimulateTraces([]byte(`{"txn-groups":[{"txn-results":[{"exec-trace":{"approval-program-trace":[{"pc":1},{"pc":7},{"pc":9},{"pc":4}]}}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ts) != 1 || len(ts[0]) != 1 {
+		t.Fatalf("unexpected traces: %v", ts)
+	}
+
+	p, err := SimulateProfile(res, m, ts[0][0].Approval, "a", "a.teal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ProfileSample{
+		{Frames: []ProfileFrame{{Function: MainName, File: "a.teal", Line: 2}}, Value: 1},
+		{Frames: []ProfileFrame{{Function: MainName, File: "a.teal", Line: 2}, {Function: "s", File: "a.teal", Line: 5}}, Value: 1},
+		{Frames: []ProfileFrame{{Function: MainName, File: "a.teal", Line: 2}, {Function: "s", File: "a.teal", Line: 6}}, Value: 1},
+		{Frames: []ProfileFrame{{Function: MainName, File: "a.teal", Line: 3}}, Value: 1},
+	}
+
+	if p.Unit != "ops" || !reflect.DeepEqual(p.Samples, expected) {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+
+	if _, err := SimulateProfile(res, m, []int{2}, "a", "a.teal"); err == nil {
+		t.Error("expected unmapped pc error")
+	}
+}
+
+func TestProfileWriters(t *testing.T) {
+	res := Process(budgetSource)
+	p := EstimateCost(res, VmDefaultBudget).Path.Profile("p", "p.teal")
+
+	buf := &bytes.Buffer{}
+	err := p.WriteSpeedscope(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var f speedscopeFile
+	err = json.Unmarshal(buf.Bytes(), &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr := f.Profiles[0]; pr.Type != "sampled" || pr.EndValue != 14 || len(pr.Samples) != len(p.Samples) {
+		t.Errorf("unexpected speedscope profile: %+v", pr)
+	}
+
+	buf.Reset()
+	err = p.WritePprof(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the string table holds the subroutine names and the sample type
+	for _, s := range []string{"budget", "count", MainName, "p.teal"} {
+		if !bytes.Contains(bs, []byte(s)) {
+			t.Errorf("missing %s in pprof profile", s)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package teal
 
+import "math"
+
 type Range interface {
 	StartLine() int
 	StartCharacter() int
@@ -17,3 +19,25 @@ type LineRange int
 func (r LineRange) StartLine() int {
 	return int(r)
 }
+
+// LinesRange is the range of the whole lines from Start to End, inclusive.
+type LinesRange struct {
+	Start int
+	End   int
+}
+
+func (r LinesRange) StartLine() int {
+	return r.Start
+}
+
+func (r LinesRange) StartCharacter() int {
+	return 0
+}
+
+func (r LinesRange) EndLine() int {
+	return r.End
+}
+
+func (r LinesRange) EndCharacter() int {
+	return math.MaxInt
+}
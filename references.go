@@ -0,0 +1,124 @@
+package teal
+
+type ReferenceKind int
+
+const (
+	RefNone ReferenceKind = iota
+	RefCallsub
+	RefB
+	RefBnz
+	RefBz
+	RefSwitch
+	RefMatch
+)
+
+func (k ReferenceKind) String() string {
+	switch k {
+	case RefCallsub:
+		return "callsub"
+	case RefB:
+		return "b"
+	case RefBnz:
+		return "bnz"
+	case RefBz:
+		return "bz"
+	case RefSwitch:
+		return "switch"
+	case RefMatch:
+		return "match"
+	default:
+		return "(none)"
+	}
+}
+
+// IsCall returns true for subroutine calls, other references are branches.
+func (k ReferenceKind) IsCall() bool {
+	return k == RefCallsub
+}
+
+// Reference is a use of a label by a branch or a subroutine call.
+type Reference struct {
+	Token Token
+	Kind  ReferenceKind
+
+	// Index is the listing index of the referencing op
+	Index int
+
+	// Case is the index of the label in the switch or match targets, 0 otherwise
+	Case int
+}
+
+func referenceKind(op Op) ReferenceKind {
+	switch op.(type) {
+	case *CallSubExpr:
+		return RefCallsub
+	case *BExpr:
+		return RefB
+	case *BnzExpr:
+		return RefBnz
+	case *BzExpr:
+		return RefBz
+	case *SwitchExpr:
+		return RefSwitch
+	case *MatchExpr:
+		return RefMatch
+	default:
+		return RefNone
+	}
+}
+
+// AllReferences returns the label references of the program in the listing order.
+func (r ProcessResult) AllReferences() []Reference {
+	var res []Reference
+
+	for i, op := range r.Listing {
+		ul, ok := op.(usesLabels)
+		if !ok || i >= len(r.Sublines) {
+			continue
+		}
+
+		kind := referenceKind(op)
+		toks := r.Sublines[i].Tokens
+
+		for k, lbl := range ul.Labels() {
+			// the first token is the op name
+			if k+1 >= len(toks) || toks[k+1].String() != lbl.Name {
+				continue
+			}
+
+			res = append(res, Reference{
+				Token: toks[k+1],
+				Kind:  kind,
+				Index: i,
+				Case:  k,
+			})
+		}
+	}
+
+	return res
+}
+
+// References returns the references to the label.
+func (r ProcessResult) References(name string) []Reference {
+	var res []Reference
+
+	for _, ref := range r.AllReferences() {
+		if ref.Token.String() == name {
+			res = append(res, ref)
+		}
+	}
+
+	return res
+}
+
+// ReferenceGraph returns the references keyed by the referenced label.
+func (r ProcessResult) ReferenceGraph() map[string][]Reference {
+	res := map[string][]Reference{}
+
+	for _, ref := range r.AllReferences() {
+		name := ref.Token.String()
+		res[name] = append(res[name], ref)
+	}
+
+	return res
+}
@@ -0,0 +1,45 @@
+package teal
+
+import "testing"
+
+func TestReferences(t *testing.T) {
+	res := Process(`#pragma version 8
+callsub sub
+int 1
+bnz end
+int 0
+switch end sub
+b end
+sub:
+retsub
+end:
+int 1`)
+
+	refs := res.References("end")
+	if len(refs) != 3 {
+		t.Fatalf("unexpected references: %v", refs)
+	}
+
+	expected := []struct {
+		Kind  ReferenceKind
+		Line  int
+		Case  int
+		Begin int
+	}{
+		{RefBnz, 3, 0, 4},
+		{RefSwitch, 5, 0, 7},
+		{RefB, 6, 0, 2},
+	}
+
+	for i, e := range expected {
+		ref := refs[i]
+		if ref.Kind != e.Kind || ref.Token.Line() != e.Line || ref.Case != e.Case || ref.Token.Begin() != e.Begin {
+			t.Errorf("unexpected reference %d: %+v", i, ref)
+		}
+	}
+
+	g := res.ReferenceGraph()
+	if len(g["sub"]) != 2 || !g["sub"][0].Kind.IsCall() || g["sub"][1].Kind != RefSwitch || g["sub"][1].Case != 1 {
+		t.Errorf("unexpected sub references: %+v", g["sub"])
+	}
+}
@@ -0,0 +1,159 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// RiskDynamicScratch is set for the programs that access the scratch space mostly by computed indexes
+	RiskDynamicScratch = "dynamic-scratch"
+
+	// RiskDecodedConstants is set for the programs that decode the embedded bytes at runtime
+	RiskDecodedConstants = "decoded-constants"
+
+	// RiskHardcodedSender is set for the programs that approve based only on the sender being a hard-coded address
+	RiskHardcodedSender = "hardcoded-sender"
+)
+
+// riskDynamicScratchMin is the number of loads/stores ops that makes the dynamic scratch access heavy
+const riskDynamicScratchMin = 4
+
+var riskScores = map[string]int{
+	RiskDynamicScratch:   30,
+	RiskDecodedConstants: 30,
+	RiskHardcodedSender:  40,
+}
+
+// RiskFinding is a heuristic matched by the program.
+type RiskFinding struct {
+	Heuristic string `json:"heuristic"`
+	Score     int    `json:"score"`
+	Message   string `json:"message"`
+	Lines     []int  `json:"lines"`
+}
+
+// RiskReport is the likelihood of the program being obfuscated or malicious.
+type RiskReport struct {
+	// Score is the sum of the finding scores, from 0 to 100
+	Score    int           `json:"score"`
+	Findings []RiskFinding `json:"findings,omitempty"`
+}
+
+func (r RiskReport) String() string {
+	var hs []string
+	for _, f := range r.Findings {
+		hs = append(hs, f.Heuristic)
+	}
+
+	if len(hs) == 0 {
+		return fmt.Sprintf("risk %d", r.Score)
+	}
+
+	return fmt.Sprintf("risk %d: %s", r.Score, strings.Join(hs, ", "))
+}
+
+func (r *RiskReport) add(h string, msg string, lines []int) {
+	if len(lines) == 0 {
+		return
+	}
+
+	s := riskScores[h]
+
+	r.Findings = append(r.Findings, RiskFinding{
+		Heuristic: h,
+		Score:     s,
+		Message:   msg,
+		Lines:     lines,
+	})
+
+	r.Score += s
+	if r.Score > 100 {
+		r.Score = 100
+	}
+}
+
+// riskBytesConst matches the ops pushing the constant bytes
+const riskBytesConst = "byte|pushbytes|pushbytess|bytec|bytec_*|addr"
+
+var (
+	riskDecodedPatterns = []Pattern{
+		mustCompilePattern(riskBytesConst + "; base64_decode|b~"),
+		mustCompilePattern(riskBytesConst + "; ...1; b^"),
+	}
+
+	riskSenderPatterns = []Pattern{
+		mustCompilePattern(riskBytesConst + "; txn Sender; =="),
+		mustCompilePattern("txn Sender; " + riskBytesConst + "; =="),
+	}
+)
+
+// riskLines returns the sorted source lines of the last ops of the pattern matches.
+func riskLines(res *ProcessResult, ps []Pattern) []int {
+	seen := map[int]bool{}
+	var lines []int
+
+	for _, p := range ps {
+		for _, m := range p.Match(res.Listing) {
+			line := res.SourceLine(m.End)
+			if !seen[line] {
+				seen[line] = true
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	sort.Ints(lines)
+
+	return lines
+}
+
+func isTxnRead(op Op) bool {
+	switch opName(op.String()) {
+	case "txn", "txna", "txnas", "gtxn", "gtxna", "gtxnas", "gtxns", "gtxnsa", "gtxnsas":
+		return true
+	}
+
+	return false
+}
+
+func isSender(op Op) bool {
+	e, ok := op.(*TxnExpr)
+	return ok && e.Field == Sender
+}
+
+// AnalyzeRisk matches the program against the heuristics of obfuscated or malicious programs:
+// heavy use of the dynamic scratch indexing, decoding of the embedded constants and approval depending only on a hard-coded sender.
+func AnalyzeRisk(res *ProcessResult) RiskReport {
+	var r RiskReport
+
+	var scratch []int
+	otherTxn := false
+
+	for i, op := range res.Listing {
+		switch op.(type) {
+		case *LoadsExpr, *StoresExpr:
+			scratch = append(scratch, res.SourceLine(i))
+		}
+
+		if isTxnRead(op) && !isSender(op) {
+			otherTxn = true
+		}
+	}
+
+	decoded := riskLines(res, riskDecodedPatterns)
+	sender := riskLines(res, riskSenderPatterns)
+
+	if len(scratch) >= riskDynamicScratchMin {
+		r.add(RiskDynamicScratch, fmt.Sprintf("%d dynamic scratch accesses", len(scratch)), scratch)
+	}
+
+	r.add(RiskDecodedConstants, "embedded bytes decoded at runtime", decoded)
+
+	if !otherTxn {
+		r.add(RiskHardcodedSender, "approval depends only on the sender being a hard-coded address", sender)
+	}
+
+	return r
+}
@@ -0,0 +1,98 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestRisk(t *testing.T) {
+	type test struct {
+		src        string
+		heuristics []string
+	}
+
+	tests := []test{
+		{
+			src: `#pragma version 8
+txn Sender
+addr AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ
+==
+return`,
+			heuristics: []string{RiskHardcodedSender},
+		},
+		{
+			src: `#pragma version 8
+txn Sender
+addr AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ
+==
+txn OnCompletion
+int NoOp
+==
+&&
+return`,
+		},
+		{
+			src: `#pragma version 8
+pushbytes "aGVsbG8="
+base64_decode StdEncoding
+byte 0x0102
+b^
+pop
+int 1
+return`,
+			heuristics: []string{RiskDecodedConstants},
+		},
+		{
+			src: `#pragma version 8
+int 1
+int 2
+stores
+int 3
+int 4
+stores
+int 1
+loads
+int 3
+loads
++
+return`,
+			heuristics: []string{RiskDynamicScratch},
+		},
+		{
+			src: `#pragma version 8
+int 1
+int 2
+stores
+int 1
+loads
+return`,
+		},
+	}
+
+	for i, test := range tests {
+		res := Process(test.src)
+		for _, d := range res.Diagnostics {
+			if d.Severity() == DiagErr {
+				t.Fatalf("test %d: unexpected error: %s", i, d)
+			}
+		}
+
+		r := AnalyzeRisk(res)
+
+		if len(r.Findings) != len(test.heuristics) {
+			t.Fatalf("test %d: unexpected findings: %v", i, r.Findings)
+		}
+
+		score := 0
+		for j, h := range test.heuristics {
+			if r.Findings[j].Heuristic != h {
+				t.Errorf("test %d: unexpected heuristic: %s, expected: %s", i, r.Findings[j].Heuristic, h)
+			}
+
+			score += riskScores[h]
+		}
+
+		if r.Score != score {
+			t.Errorf("test %d: unexpected score: %d, expected: %d", i, r.Score, score)
+		}
+	}
+}
@@ -0,0 +1,705 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+)
+
+//go:generate go run gen_rules.go
+
+// Cwe is the Common Weakness Enumeration entry a rule maps to.
+type Cwe struct {
+	Id   int
+	Name string
+}
+
+var (
+	cweIntegerOverflow      = Cwe{Id: 190, Name: "Integer Overflow or Wraparound"}
+	cweUncontrolledResource = Cwe{Id: 400, Name: "Uncontrolled Resource Consumption"}
+	cweSessionExpiration    = Cwe{Id: 613, Name: "Insufficient Session Expiration"}
+	cweDuplicateIdentifier  = Cwe{Id: 694, Name: "Use of Multiple Resources with Duplicate Identifier"}
+	cweIncorrectComparison  = Cwe{Id: 697, Name: "Incorrect Comparison"}
+	cweUncheckedCondition   = Cwe{Id: 754, Name: "Improper Check for Unusual or Exceptional Conditions"}
+	cweInfiniteLoop         = Cwe{Id: 835, Name: "Loop with Unreachable Exit Condition ('Infinite Loop')"}
+)
+
+// RuleExample is a program reported by the rule and its corrected version.
+type RuleExample struct {
+	Bad  string
+	Good string
+
+	// Policies is the JSON of the op policies the examples are processed with
+	Policies string
+
+	// Consensus is the consensus version the examples are checked against
+	Consensus uint64
+}
+
+// options returns the process options of the example.
+func (e RuleExample) options() []ProcessOption {
+	var opts []ProcessOption
+
+	if e.Policies != "" {
+		ps, err := ParseOpPolicies([]byte(e.Policies))
+		if err != nil {
+			panic(err)
+		}
+
+		opts = append(opts, WithOpPolicies(ps...))
+	}
+
+	if e.Consensus != 0 {
+		p, err := Consensus(e.Consensus)
+		if err != nil {
+			panic(err)
+		}
+
+		opts = append(opts, WithConsensus(p))
+	}
+
+	return opts
+}
+
+// RuleInfo describes a diagnostics rule for the reports and the docs.
+type RuleInfo struct {
+	Id    string
+	Title string
+	Desc  string
+
+	// Rationale explains why the reported code is a problem.
+	Rationale string
+
+	Examples []RuleExample
+
+	// Fixes are the kinds of the quick fixes available for the rule diagnostics.
+	Fixes []string
+
+	// Level is the severity of the rule diagnostics in the default mode.
+	Level DiagnosticSeverity
+
+	// Cwe lists the weaknesses of the security rules.
+	Cwe []Cwe
+}
+
+// Security reports whether the rule detects a security weakness.
+func (r RuleInfo) Security() bool {
+	return len(r.Cwe) > 0
+}
+
+// Fixable reports whether the rule diagnostics have quick fixes.
+func (r RuleInfo) Fixable() bool {
+	return len(r.Fixes) > 0
+}
+
+// Anchor is the fragment of the rule section in the rules reference.
+func (r RuleInfo) Anchor() string {
+	return strings.ToLower(r.Id)
+}
+
+type ruleMeta struct {
+	title     string
+	rationale string
+	examples  []RuleExample
+	fixes     []string
+	level     DiagnosticSeverity
+	cwe       []Cwe
+}
+
+// ruleRegistry describes the rules beyond their one-line Desc.
+var ruleRegistry = map[string]ruleMeta{
+	"SYNTAX": {
+		title:     "Syntax error",
+		rationale: "The tokens that cannot be lexed, e.g. unterminated strings or invalid numbers, fail the assembly.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nbyte \"x\nlen",
+				Good: "#pragma version 8\nbyte \"x\"\nlen",
+			},
+		},
+		level: DiagErr,
+	},
+	"PARSE": {
+		title:     "Parse error",
+		rationale: "The ops that cannot be parsed, e.g. unknown opcodes or invalid immediate arguments, fail the assembly.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nfoo",
+				Good: "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0001": {
+		title:     "Duplicate label",
+		rationale: "A label defined more than once makes the branches to it ambiguous and fails the assembly.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nbnz done\nerr\ndone:\nint 1\ndone:\nreturn",
+				Good: "#pragma version 8\nint 1\nbnz done\nerr\ndone:\nint 1\nreturn",
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0002": {
+		title:     "Unused label",
+		rationale: "A label that no branch or callsub references is dead code or a misspelled branch target.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nunused:\nreturn",
+				Good: "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		fixes: []string{FixRemoveLine},
+		level: DiagWarn,
+	},
+	"LINT0003": {
+		title:     "Unreachable code",
+		rationale: "The ops following an unconditional branch, return or err up to the next referenced label are never executed.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nreturn\nint 0\nreturn",
+				Good: "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0004": {
+		title:     "Redundant branch",
+		rationale: "An unconditional branch to the label that immediately follows it has no effect and only costs budget.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nb done\ndone:\nreturn",
+				Good: "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		fixes: []string{FixRemoveLine},
+		level: DiagWarn,
+	},
+	"LINT0005": {
+		title:     "Infinite loop or missing label",
+		rationale: "An unconditional branch back to a label with no way to escape the loop runs until the budget is exhausted, failing the program. A branch to a label that is not defined fails the assembly.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nloop:\nint 1\npop\nb loop",
+				Good: "#pragma version 8\nloop:\ntxn NumAppArgs\nbnz loop\nint 1\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\nint 1\nbnz done\nerr",
+				Good: "#pragma version 8\nint 1\nbnz done\nerr\ndone:\nint 1\nreturn",
+			},
+		},
+		fixes: []string{FixCreateLabel},
+		level: DiagErr,
+		cwe:   []Cwe{cweInfiniteLoop},
+	},
+	"LINT0006": {
+		title:     "Misplaced #pragma version",
+		rationale: "The #pragma version must precede the first op and declare the version once, the later pragmas are rejected by the assembler.",
+		examples: []RuleExample{
+			{
+				Bad:  "int 1\n#pragma version 8\nreturn",
+				Good: "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0007": {
+		title:     "Op unavailable in mode",
+		rationale: "Some ops are available only in the application mode or only in the logic signature mode, using both in a program makes it invalid in either.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\narg 0\nbyte \"x\"\napp_global_get\n==",
+				Good: "#pragma version 8\ntxna ApplicationArgs 0\nbyte \"x\"\napp_global_get\n==",
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0008": {
+		title:     "Op unavailable in version",
+		rationale: "The ops introduced in a later AVM version fail the assembly of a program declaring an older version.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 5\nbyte \"x\"\nbox_len\nreturn",
+				Good: "#pragma version 8\nbyte \"x\"\nbox_len\nreturn",
+			},
+		},
+		fixes: []string{FixVersion},
+		level: DiagErr,
+	},
+	"LINT0009": {
+		title:     "Failing op in clear state program",
+		rationale: "A failing clear state program still clears the local state, so its failing ops skip the cleanup instead of preventing the opt out.",
+		examples: []RuleExample{
+			{
+				Bad:  "//#pragma program clear\n#pragma version 8\ntxn NumAppArgs\nint 1\n+\nreturn",
+				Good: "//#pragma program clear\n#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagWarn,
+		cwe:   []Cwe{cweUncheckedCondition},
+	},
+	"LINT0010": {
+		title:     "Clear state program rejects",
+		rationale: "A clear state program path that rejects still clears the local state while discarding the program effects.",
+		examples: []RuleExample{
+			{
+				Bad:  "//#pragma program clear\n#pragma version 8\nint 0\nreturn",
+				Good: "//#pragma program clear\n#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagWarn,
+		cwe:   []Cwe{cweUncheckedCondition},
+	},
+	"LINT0011": {
+		title:     "Clear state program over budget",
+		rationale: "The clear state program cannot pool the budget, exceeding the budget of a single call fails it and skips the cleanup.",
+		examples: []RuleExample{
+			{
+				Bad:  "//#pragma program clear\n#pragma version 8\nbyte \"x\"\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nlen\nreturn",
+				Good: "//#pragma program clear\n#pragma version 8\nbyte \"x\"\nkeccak256\nlen\nreturn",
+			},
+		},
+		level: DiagWarn,
+		cwe:   []Cwe{cweUncontrolledResource},
+	},
+	"LINT0012": {
+		title:     "Box key collision",
+		rationale: "Box names concatenated from the user input without fixed lengths or separators can collide, letting one key read and overwrite the box of another.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxna ApplicationArgs 0\ntxna ApplicationArgs 1\nconcat\nbyte \"x\"\nbox_put\nint 1\nreturn",
+				Good: "#pragma version 8\ntxn Sender\nbyte \"x\"\nbox_put\nint 1\nreturn",
+			},
+		},
+		level: DiagWarn,
+		cwe:   []Cwe{cweDuplicateIdentifier},
+	},
+	"LINT0013": {
+		title:     "Budget pooling required",
+		rationale: "The ops exceeding the budget of a single call require the budget pooled from the other app calls of the group.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nbyte \"x\"\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nkeccak256\nlen\nreturn",
+				Good: "#pragma version 8\nbyte \"x\"\nkeccak256\nlen\nreturn",
+			},
+		},
+		level: DiagInfo,
+	},
+	"LINT0014": {
+		title:     "Fee pooling required",
+		rationale: "An inner transaction with a zero fee requires the outer transactions of the group to pay for it.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nitxn_begin\nint pay\nitxn_field TypeEnum\nint 0\nitxn_field Fee\nitxn_submit\nint 1",
+				Good: "#pragma version 8\nitxn_begin\nint pay\nitxn_field TypeEnum\nglobal MinTxnFee\nitxn_field Fee\nitxn_submit\nint 1",
+			},
+		},
+		level: DiagInfo,
+	},
+	"LINT0015": {
+		title:     "Deprecated construct",
+		rationale: "The legacy constructs have better alternatives in the program version and mode.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nbyte 0x0102\nsubstring 0 1\nlen",
+				Good: "#pragma version 8\nbyte 0x0102\nextract 0 1\nlen",
+			},
+		},
+		fixes: []string{FixDeprecated},
+		level: DiagWarn,
+	},
+	"LINT0016": {
+		title:     "Stack depth",
+		rationale: "The stack can hold at most 1000 values and popping more values than pushed fails the program on that path.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\n+\nreturn",
+				Good: "#pragma version 8\nint 1\nint 2\n+\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0017": {
+		title:     "Byte length mismatch",
+		rationale: "Comparing the byte values of the lengths that can never match always gives the same result.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxn Sender\nbyte \"admin\"\n==\nreturn",
+				Good: "#pragma version 8\ntxn Sender\nglobal CreatorAddress\n==\nreturn",
+			},
+		},
+		level: DiagWarn,
+		cwe:   []Cwe{cweIncorrectComparison},
+	},
+	"LINT0018": {
+		title:     "Wide math",
+		rationale: "The uint64 arithmetic on the amounts from the transaction fields can overflow and fail the program, the wide math ops keep the full result.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxn Amount\nint 3\n*\nint 100\n/\nreturn",
+				Good: "#pragma version 8\ntxn Amount\nint 3\nmulw\nint 0\nint 100\ndivmodw\npop\npop\nswap\npop\nreturn",
+			},
+		},
+		fixes: []string{FixWideMath},
+		level: DiagHint,
+		cwe:   []Cwe{cweIntegerOverflow},
+	},
+	"LINT0019": {
+		title:     "Unused subroutine arg or result",
+		rationale: "An arg that is never read with frame_dig still has to be pushed by every caller, a result that still holds the constant its local is allocated with returns the same value on every call. Both are common leftovers of refactored or generated code.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nint 2\ncallsub first\nreturn\nfirst:\nproto 2 1\nframe_dig -2\nretsub",
+				Good: "#pragma version 8\nint 1\ncallsub first\nreturn\nfirst:\nproto 1 1\nframe_dig -1\nretsub",
+			},
+			{
+				Bad:  "#pragma version 8\nint 1\ncallsub check\npop\nreturn\ncheck:\nproto 1 2\nint 0\ndup\nframe_dig -1\nframe_bury 0\nretsub",
+				Good: "#pragma version 8\nint 1\ncallsub check\nreturn\ncheck:\nproto 1 1\nint 0\nframe_dig -1\nframe_bury 0\nretsub",
+			},
+		},
+		fixes: []string{FixProto},
+		level: DiagWarn,
+	},
+	"LINT0020": {
+		title:     "Stack comment mismatch",
+		rationale: "A `// stack: [a, b]` comment names the values on the stack, the top one last. A comment that no longer matches the code after an edit misleads the reader more than no comment at all, so the names are checked against the stack depth and the value types computed for every path reaching the line.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nint 1\nbyte \"a\" // stack: [n]\npop\nreturn",
+				Good: "#pragma version 8\nint 1\nbyte \"a\" // stack: [n, key]\npop\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\nint 1\nbyte \"a\" // stack: [n: bytes, key]\npop\nreturn",
+				Good: "#pragma version 8\nint 1\nbyte \"a\" // stack: [..., key: bytes]\npop\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0021": {
+		title:     "Invalid or non-canonical method signature",
+		rationale: "The method pseudo-op pushes the hash of the signature text as is, while the clients compute the selector from the canonical ARC-4 signature. An alias such as `uint` for `uint64`, a space between the types or an unknown type produces a selector that no client ever sends, so the method can not be called.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxna ApplicationArgs 0\nmethod \"add(uint,uint)uint\"\n==\nreturn",
+				Good: "#pragma version 8\ntxna ApplicationArgs 0\nmethod \"add(uint64,uint64)uint64\"\n==\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\ntxna ApplicationArgs 0\nmethod \"hello(name)void\"\n==\nreturn",
+				Good: "#pragma version 8\ntxna ApplicationArgs 0\nmethod \"hello(string)void\"\n==\nreturn",
+			},
+		},
+		fixes: []string{FixMethodSignature},
+		level: DiagWarn,
+	},
+	"LINT0022": {
+		title:     "Opcode forbidden by policy",
+		rationale: "Organizations restrict the opcodes of some programs across the repos, e.g. no inner transactions in a treasury logic signature or no `json_ref` anywhere. The op policies passed with `tealint -policy` deny the ops by name, wildcard or langspec category, or allow only the listed ones, for all of the files or the ones matching the policy paths.",
+		examples: []RuleExample{
+			{
+				Policies: `[{"name": "no-json", "deny": ["json_ref"]}]`,
+				Bad:      "#pragma version 8\ntxna ApplicationArgs 0\nbyte \"amount\"\njson_ref JSONUint64\nreturn",
+				Good:     "#pragma version 8\ntxna ApplicationArgs 0\nbtoi\nreturn",
+			},
+			{
+				Policies: `[{"name": "treasury", "deny": ["category:Inner Transactions"]}]`,
+				Bad:      "#pragma version 8\nitxn_begin\nint pay\nitxn_field TypeEnum\nitxn_submit\nint 1\nreturn",
+				Good:     "#pragma version 8\nint 1\nreturn",
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0023": {
+		title:     "Funds sent to a burn address",
+		rationale: "The zero address, the fee sinks and the rewards pools are copy-pasted from docs and tests as placeholders. Nobody can spend the funds sent to the zero address and the ones sent to the fee sink or the rewards pool belong to the protocol, so an inner payment or asset transfer to them is almost always a leftover placeholder.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nitxn_begin\nint pay\nitxn_field TypeEnum\naddr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA\nitxn_field Receiver\nitxn_submit\nint 1\nreturn",
+				Good: "#pragma version 8\nitxn_begin\nint pay\nitxn_field TypeEnum\ntxn Sender\nitxn_field Receiver\nitxn_submit\nint 1\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0024": {
+		title:     "Constant stack args",
+		rationale: "The stack forms like `extract3` or `txnas` are meant for the values computed at runtime. Pushing constants just to pop them again costs the extra ops and bytes the immediate form like `extract` or `txna` does not.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxna ApplicationArgs 0\nint 0\nint 4\nextract3\nlen\nreturn",
+				Good: "#pragma version 8\ntxna ApplicationArgs 0\nextract 0 4\nlen\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\nint 1\ntxnas ApplicationArgs\nlen\nreturn",
+				Good: "#pragma version 8\ntxna ApplicationArgs 1\nlen\nreturn",
+			},
+		},
+		fixes: []string{FixImmediateForm},
+		level: DiagHint,
+	},
+	"LINT0025": {
+		title:     "Unrolled gtxn sequence",
+		rationale: "Checking the same field of the consecutive group transactions with `gtxn 0 F`, `gtxn 1 F`, ... repeats the checking ops for every transaction. A loop with `gtxns` and the index kept in a scratch slot is smaller once the sequence is long enough, at the cost of the extra budget of the loop ops. The rewrite is offered as the experimental `gtxn-loop` quick fix.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ngtxn 0 RekeyTo\nglobal ZeroAddress\n==\nassert\ngtxn 1 RekeyTo\nglobal ZeroAddress\n==\nassert\ngtxn 2 RekeyTo\nglobal ZeroAddress\n==\nassert\ngtxn 3 RekeyTo\nglobal ZeroAddress\n==\nassert\ngtxn 4 RekeyTo\nglobal ZeroAddress\n==\nassert\nint 1",
+				Good: "#pragma version 8\nint 0\nstore 255\nloop:\nload 255\ngtxns RekeyTo\nglobal ZeroAddress\n==\nassert\nload 255\nint 1\n+\ndup\nstore 255\nint 5\n<\nbnz loop\nint 1",
+			},
+		},
+		level: DiagHint,
+	},
+	"LINT0026": {
+		title:     "Scratch space pressure",
+		rationale: "A program has 256 scratch slots. Generated code often allocates a new slot for every variable, so a large program can run out of them. The slots that are never live at the same time can share a single slot, `tealint -scratch` reports the used slots, the free holes between them and the max number of the slots live at once.",
+		examples: []RuleExample{
+			{
+				Bad:  scratchExample(scratchPressureMin),
+				Good: scratchExample(8),
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0027": {
+		title:     "Missing expiry check",
+		rationale: "An escrow or a delegated logic signature approving the transfers without any condition over the validity rounds authorizes them forever, anyone holding the program can send the matching transactions at any time. Bounding `txn LastValid` by an expiry round, or the lifespan by `txn FirstValid`, limits the window the signature can be used in. `tealint -validity` reports the temporal constraints the program enforces.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\n//#pragma mode logicsig\ntxn TypeEnum\nint pay\n==\ntxn Receiver\naddr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA\n==\n&&\ntxn CloseRemainderTo\nglobal ZeroAddress\n==\n&&",
+				Good: "#pragma version 8\n//#pragma mode logicsig\ntxn TypeEnum\nint pay\n==\ntxn Receiver\naddr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA\n==\n&&\ntxn CloseRemainderTo\nglobal ZeroAddress\n==\n&&\ntxn LastValid\nint 30000000\n<=\n&&",
+			},
+		},
+		cwe:   []Cwe{cweSessionExpiration},
+		level: DiagWarn,
+	},
+	"LINT0028": {
+		title:     "Unchecked application args",
+		rationale: "Reading `txna ApplicationArgs N` when the call passes N args or fewer fails the program with an index out of range error instead of rejecting the call with a clear check. The paths of the program are explored and the highest arg read on each of them is compared with the `txn NumAppArgs` conditions asserted or branched on before the reads. The `num-app-args` quick fix inserts the standard assert before the first unchecked read.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 1\nbtoi\n+\nreturn",
+				Good: "#pragma version 8\ntxn NumAppArgs\nint 1\n>\nassert\ntxna ApplicationArgs 0\nbtoi\ntxna ApplicationArgs 1\nbtoi\n+\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\ntxn NumAppArgs\nint 1\n==\nbnz one\nint 0\nreturn\none:\ntxna ApplicationArgs 1\nlen\nreturn",
+				Good: "#pragma version 8\ntxn NumAppArgs\nint 2\n==\nbnz two\nint 0\nreturn\ntwo:\ntxna ApplicationArgs 1\nlen\nreturn",
+			},
+		},
+		fixes: []string{FixNumAppArgs},
+		level: DiagWarn,
+	},
+	"LINT0029": {
+		title:     "Unavailable reference",
+		rationale: "An app can only access the accounts, assets and apps passed in the foreign arrays of the call, besides the sender and the app itself. Reading `txna Accounts N` without checking `txn NumAccounts` fails when the caller passes fewer accounts, and since v4 an address or an id pushed as a constant for `balance`, `asset_holding_get`, `app_local_get` and the like is available only if every caller includes it in the foreign arrays, which is easy to miss in the client code.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\ntxna Accounts 2\nbalance\nreturn",
+				Good: "#pragma version 8\ntxn NumAccounts\nint 2\n>=\nassert\ntxna Accounts 2\nbalance\nreturn",
+			},
+			{
+				Bad:  "#pragma version 8\naddr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q\nint 31566704\nasset_holding_get AssetBalance\npop\nreturn",
+				Good: "#pragma version 8\ntxn NumAssets\nassert\ntxn Sender\ntxna Assets 0\nasset_holding_get AssetBalance\npop\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+	"LINT0030": {
+		title:     "Consensus limits",
+		rationale: "The network rejects the programs above the limits of its consensus version: the AVM version, the program size and, for the logic signatures, the cost. The foreign arrays and the args of an app call are capped too, so reading `txna Accounts 5` or `txna ApplicationArgs 16` can never succeed. The check is opt-in and runs with the consensus version selected with `-consensus`.",
+		examples: []RuleExample{
+			{
+				Bad:       "#pragma version 8\ntxna Accounts 5\nbalance\nreturn",
+				Good:      "#pragma version 8\ntxna Accounts 4\nbalance\nreturn",
+				Consensus: 36,
+			},
+			{
+				Bad:       "#pragma version 8\nint 1\nreturn",
+				Good:      "#pragma version 6\nint 1\nreturn",
+				Consensus: 31,
+			},
+		},
+		level: DiagErr,
+	},
+	"LINT0031": {
+		title:     "Box I/O budget",
+		rationale: "Each box reference of the group adds 1024 bytes to the box I/O budget and an app call declares at most 8 of them, so a box larger than 8192 bytes can only be accessed when the other app calls of the group add the box references, e.g. the empty calls of the same app. The box accessed by `box_extract` or `box_replace` is at least as large as the start plus the length of the statically known extent. `teal.EstimateBoxIO` returns the box I/O of a call against the budget of its declared box references.",
+		examples: []RuleExample{
+			{
+				Bad:  "#pragma version 8\nbyte \"data\"\nint 8192\nint 64\nbox_extract\nlen\nreturn",
+				Good: "#pragma version 8\nbyte \"data\"\nint 8000\nint 64\nbox_extract\nlen\nreturn",
+			},
+		},
+		level: DiagWarn,
+	},
+}
+
+// scratchExample returns a program storing to the n first scratch slots.
+func scratchExample(n int) string {
+	var b strings.Builder
+	b.WriteString("#pragma version 8\nint 1")
+
+	for i := 0; i < n; i++ {
+		if i%8 == 0 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "dup; store %d", i)
+	}
+
+	b.WriteString("\nreturn")
+
+	return b.String()
+}
+
+// Rules returns the info of the syntax, the parser and the lint rules.
+func Rules() []RuleInfo {
+	infos := []RuleInfo{
+		ruleInfo("SYNTAX", "Syntax checks"),
+		ruleInfo("PARSE", "Parser checks"),
+	}
+
+	for _, r := range LintRules {
+		infos = append(infos, ruleInfo(r.Id(), r.Desc()))
+	}
+
+	return infos
+}
+
+// LookupRule returns the info of the rule, the id is case insensitive.
+func LookupRule(id string) (RuleInfo, bool) {
+	for _, r := range Rules() {
+		if strings.EqualFold(r.Id, id) {
+			return r, true
+		}
+	}
+
+	return RuleInfo{}, false
+}
+
+func ruleInfo(id string, desc string) RuleInfo {
+	m, ok := ruleRegistry[id]
+	if !ok {
+		m = ruleMeta{level: DiagWarn}
+	}
+
+	title := m.title
+	if title == "" {
+		title = id
+	}
+
+	rationale := m.rationale
+	if rationale == "" {
+		rationale = desc
+	}
+
+	return RuleInfo{
+		Id:        id,
+		Title:     title,
+		Desc:      desc,
+		Rationale: rationale,
+		Examples:  m.examples,
+		Fixes:     m.fixes,
+		Level:     m.level,
+		Cwe:       m.cwe,
+	}
+}
+
+// Markdown returns the rule section of the rules reference.
+func (r RuleInfo) Markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "## %s\n\n", r.Id)
+	fmt.Fprintf(&sb, "**%s** - %s\n\n", r.Title, r.Desc)
+	fmt.Fprintf(&sb, "%s\n\n", r.Rationale)
+
+	fmt.Fprintf(&sb, "- Level: %s\n", r.Level)
+
+	if r.Fixable() {
+		fmt.Fprintf(&sb, "- Quick fixes: %s\n", strings.Join(r.Fixes, ", "))
+	} else {
+		sb.WriteString("- Quick fixes: none\n")
+	}
+
+	for _, c := range r.Cwe {
+		fmt.Fprintf(&sb, "- [CWE-%d](https://cwe.mitre.org/data/definitions/%d.html): %s\n", c.Id, c.Id, c.Name)
+	}
+
+	for _, e := range r.Examples {
+		if e.Policies != "" {
+			fmt.Fprintf(&sb, "\nPolicies:\n\n```json\n%s\n```\n", e.Policies)
+		}
+		if e.Consensus != 0 {
+			fmt.Fprintf(&sb, "\nConsensus: v%d\n", e.Consensus)
+		}
+		fmt.Fprintf(&sb, "\nReported:\n\n```\n%s\n```\n", e.Bad)
+		if e.Good != "" {
+			fmt.Fprintf(&sb, "\nCorrected:\n\n```\n%s\n```\n", e.Good)
+		}
+	}
+
+	return sb.String()
+}
+
+// Text returns the rule explanation for the terminal.
+func (r RuleInfo) Text() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s: %s\n\n", r.Id, r.Title)
+	fmt.Fprintf(&sb, "%s.\n\n", r.Desc)
+	fmt.Fprintf(&sb, "%s\n\n", r.Rationale)
+
+	fmt.Fprintf(&sb, "Level: %s\n", r.Level)
+
+	if r.Fixable() {
+		fmt.Fprintf(&sb, "Quick fixes: %s\n", strings.Join(r.Fixes, ", "))
+	}
+
+	for _, c := range r.Cwe {
+		fmt.Fprintf(&sb, "CWE-%d: %s\n", c.Id, c.Name)
+	}
+
+	for _, e := range r.Examples {
+		if e.Policies != "" {
+			fmt.Fprintf(&sb, "\nPolicies:\n\n%s\n", indent(e.Policies))
+		}
+		if e.Consensus != 0 {
+			fmt.Fprintf(&sb, "\nConsensus: v%d\n", e.Consensus)
+		}
+		fmt.Fprintf(&sb, "\nReported:\n\n%s\n", indent(e.Bad))
+		if e.Good != "" {
+			fmt.Fprintf(&sb, "\nCorrected:\n\n%s\n", indent(e.Good))
+		}
+	}
+
+	return sb.String()
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+// RulesReference returns the markdown reference of all the rules, generated into RULES.md.
+func RulesReference() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Rules\n\n")
+	sb.WriteString("<!-- Code generated by go generate; DO NOT EDIT. -->\n\n")
+	sb.WriteString("The diagnostics rules reported by tealint, tealsp and the SARIF report, `tealint -explain RULEID` prints a single rule.\n\n")
+
+	sb.WriteString("| Rule | Title | Level | Quick fixes |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+
+	rules := Rules()
+	for _, r := range rules {
+		fixes := "no"
+		if r.Fixable() {
+			fixes = "yes"
+		}
+		fmt.Fprintf(&sb, "| [%s](#%s) | %s | %s | %s |\n", r.Id, r.Anchor(), r.Title, r.Level, fixes)
+	}
+
+	for _, r := range rules {
+		sb.WriteString("\n")
+		sb.WriteString(r.Markdown())
+	}
+
+	return sb.String()
+}
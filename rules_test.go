@@ -0,0 +1,123 @@
+package teal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	rules := Rules()
+	if len(rules) != len(LintRules)+2 {
+		t.Fatalf("unexpected rules count: %d", len(rules))
+	}
+
+	ids := map[string]bool{}
+	for _, r := range rules {
+		if ids[r.Id] {
+			t.Errorf("duplicate rule: %s", r.Id)
+		}
+		ids[r.Id] = true
+
+		if _, ok := ruleRegistry[r.Id]; !ok {
+			t.Errorf("missing rule metadata: %s", r.Id)
+		}
+
+		if r.Title == "" || r.Rationale == "" || r.Desc == "" {
+			t.Errorf("missing rule description: %s", r.Id)
+		}
+
+		if len(r.Examples) == 0 {
+			t.Errorf("missing rule examples: %s", r.Id)
+		}
+
+		if r.Level < DiagErr || r.Level > DiagHint {
+			t.Errorf("unexpected rule level: %s %d", r.Id, r.Level)
+		}
+	}
+
+	for id := range ruleRegistry {
+		if !ids[id] {
+			t.Errorf("metadata of unknown rule: %s", id)
+		}
+	}
+}
+
+func TestRuleExamples(t *testing.T) {
+	for _, r := range Rules() {
+		for _, e := range r.Examples {
+			count := func(src string) int {
+				n := 0
+				for _, d := range Process(src, e.options()...).Diagnostics {
+					if d.Rule() == r.Id {
+						n++
+
+						if d.Severity() != r.Level {
+							t.Errorf("unexpected severity of %s: %s, rule level: %s", r.Id, d.Severity(), r.Level)
+						}
+					}
+				}
+				return n
+			}
+
+			if count(e.Bad) == 0 {
+				t.Errorf("expected %s to report:\n%s", r.Id, e.Bad)
+			}
+
+			if e.Good != "" && count(e.Good) != 0 {
+				t.Errorf("expected %s not to report:\n%s", r.Id, e.Good)
+			}
+		}
+	}
+}
+
+func TestRuleFixes(t *testing.T) {
+	for _, r := range Rules() {
+		if !r.Fixable() {
+			continue
+		}
+
+		kinds := map[string]bool{}
+		for _, e := range r.Examples {
+			res := Process(e.Bad, e.options()...)
+			for _, fa := range res.CodeActions(LinesRange{Start: 0, End: len(res.Lines)}) {
+				kinds[fa.Kind] = true
+			}
+		}
+
+		for _, kind := range r.Fixes {
+			if !kinds[kind] {
+				t.Errorf("expected %s quick fix for %s examples", kind, r.Id)
+			}
+		}
+	}
+}
+
+func TestLookupRule(t *testing.T) {
+	r, ok := LookupRule("lint0018")
+	if !ok || r.Id != WideMathRuleInstance.Id() {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+
+	if !r.Security() || r.Cwe[0].Id != 190 {
+		t.Errorf("expected wide math rule to map to CWE-190, got: %+v", r.Cwe)
+	}
+
+	if _, ok := LookupRule("LINT9999"); ok {
+		t.Error("expected unknown rule")
+	}
+
+	if r, _ := LookupRule((UnusedLabelsRule{}).Id()); r.Security() {
+		t.Error("expected unused labels rule not to be a security rule")
+	}
+}
+
+func TestRulesReference(t *testing.T) {
+	bs, err := os.ReadFile("RULES.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(bs) != RulesReference() {
+		t.Error("RULES.md is out of date, run go generate")
+	}
+}
@@ -0,0 +1,337 @@
+package teal
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// scratchSlots is the number of the scratch slots of a program
+const scratchSlots = 256
+
+// scratchPressureMin is the number of the used scratch slots reported as close to the exhaustion
+const scratchPressureMin = 240
+
+// ScratchRange is a range of the consecutive scratch slots.
+type ScratchRange struct {
+	First uint8 `json:"first"`
+	Last  uint8 `json:"last"`
+}
+
+func (r ScratchRange) String() string {
+	if r.First == r.Last {
+		return fmt.Sprint(r.First)
+	}
+
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// ScratchUsage is the allocation of the scratch slots accessed by the constant indexes with load and store.
+type ScratchUsage struct {
+	// Used is the number of the slots used out of the 256, Free is the rest of them
+	Used int `json:"used"`
+	Free int `json:"free"`
+
+	// Slots are the ranges of the used slots
+	Slots []ScratchRange `json:"slots,omitempty"`
+
+	// Holes are the ranges of the free slots between the used ones
+	Holes []ScratchRange `json:"holes,omitempty"`
+
+	// HighWater is the max number of the slots live at once, at the source line HighWaterLine
+	HighWater     int `json:"highWater"`
+	HighWaterLine int `json:"highWaterLine"`
+
+	// Dynamic is set if loads or stores access the slots by the computed indexes, the usage covers only the constant ones
+	Dynamic bool `json:"dynamic,omitempty"`
+
+	// Pressure is set if the used slots are close to the exhaustion
+	Pressure bool `json:"pressure,omitempty"`
+}
+
+func (u ScratchUsage) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d of %d scratch slots used, %d free, at most %d live at once", u.Used, scratchSlots, u.Free, u.HighWater)
+
+	if u.Used > 0 {
+		fmt.Fprintf(&b, " (line %d)", u.HighWaterLine+1)
+	}
+
+	if len(u.Holes) > 0 {
+		var hs []string
+		for _, h := range u.Holes {
+			hs = append(hs, h.String())
+		}
+
+		fmt.Fprintf(&b, ", fragmented by the free slots %s", strings.Join(hs, ", "))
+	}
+
+	if u.Dynamic {
+		b.WriteString(", the slots accessed by the computed indexes are not included")
+	}
+
+	return b.String()
+}
+
+// scratchSet is a set of the scratch slots.
+type scratchSet [scratchSlots / 64]uint64
+
+func (s *scratchSet) add(i uint8) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s scratchSet) has(i uint8) bool {
+	return s[i/64]&(1<<(i%64)) != 0
+}
+
+func (s scratchSet) union(o scratchSet) scratchSet {
+	for i := range s {
+		s[i] |= o[i]
+	}
+
+	return s
+}
+
+func (s scratchSet) count() int {
+	n := 0
+	for _, w := range s {
+		n += bits.OnesCount64(w)
+	}
+
+	return n
+}
+
+// successors returns the listing indexes the control flows to after each op,
+// retsub flows to the ops following all of the callsubs.
+func (r ProcessResult) successors() [][]int {
+	n := len(r.Listing)
+
+	labels := map[string]int{}
+	var returns []int
+
+	for i, op := range r.Listing {
+		switch op := op.(type) {
+		case *LabelExpr:
+			if _, ok := labels[op.Name]; !ok {
+				labels[op.Name] = i
+			}
+		case *CallSubExpr:
+			if i+1 < n {
+				returns = append(returns, i+1)
+			}
+		}
+	}
+
+	res := make([][]int, n)
+
+	for i, op := range r.Listing {
+		var next []int
+
+		target := func(l *LabelExpr) {
+			if j, ok := labels[l.Name]; ok {
+				next = append(next, j)
+			}
+		}
+
+		fallthru := true
+
+		switch op := op.(type) {
+		case *BExpr:
+			target(op.Label)
+			fallthru = false
+		case *BzExpr:
+			target(op.Label)
+		case *BnzExpr:
+			target(op.Label)
+		case *SwitchExpr:
+			for _, t := range op.Targets {
+				target(t)
+			}
+		case *MatchExpr:
+			for _, t := range op.Targets {
+				target(t)
+			}
+		case *CallSubExpr:
+			target(op.Label)
+			fallthru = false
+		case *RetSubExpr:
+			next = append(next, returns...)
+			fallthru = false
+		case *ReturnExpr, *ErrExpr:
+			fallthru = false
+		}
+
+		if fallthru && i+1 < n {
+			next = append(next, i+1)
+		}
+
+		res[i] = next
+	}
+
+	return res
+}
+
+// AnalyzeScratch returns the usage of the scratch slots with the max number of the slots live at once,
+// a slot is live from a store until the last load that can read the stored value.
+func AnalyzeScratch(res *ProcessResult) ScratchUsage {
+	var u ScratchUsage
+
+	n := len(res.Listing)
+
+	uses := make([]scratchSet, n)
+	defs := make([]scratchSet, n)
+
+	var used scratchSet
+
+	for i, op := range res.Listing {
+		switch op := op.(type) {
+		case *LoadExpr:
+			uses[i].add(op.Index)
+			used.add(op.Index)
+		case *StoreExpr:
+			defs[i].add(op.Index)
+			used.add(op.Index)
+		case *LoadsExpr, *StoresExpr:
+			u.Dynamic = true
+		}
+	}
+
+	succs := res.successors()
+
+	in := make([]scratchSet, n)
+	out := make([]scratchSet, n)
+
+	for changed := true; changed; {
+		changed = false
+
+		for i := n - 1; i >= 0; i-- {
+			var o scratchSet
+			for _, j := range succs[i] {
+				o = o.union(in[j])
+			}
+
+			v := o
+			for k := range v {
+				v[k] &^= defs[i][k]
+			}
+			v = v.union(uses[i])
+
+			if v != in[i] || o != out[i] {
+				in[i] = v
+				out[i] = o
+				changed = true
+			}
+		}
+	}
+
+	for i := range res.Listing {
+		// the stored slot is live after the store even if the value is never read
+		live := in[i].union(out[i].union(defs[i])).count()
+		if live > u.HighWater {
+			u.HighWater = live
+			u.HighWaterLine = res.SourceLine(i)
+		}
+	}
+
+	first := -1
+	for i := 0; i <= scratchSlots; i++ {
+		if i < scratchSlots && used.has(uint8(i)) {
+			u.Used++
+			if first < 0 {
+				first = i
+			}
+			continue
+		}
+
+		if first >= 0 {
+			u.Slots = append(u.Slots, ScratchRange{First: uint8(first), Last: uint8(i - 1)})
+			first = -1
+		}
+	}
+
+	for i := 1; i < len(u.Slots); i++ {
+		u.Holes = append(u.Holes, ScratchRange{First: u.Slots[i-1].Last + 1, Last: u.Slots[i].First - 1})
+	}
+
+	u.Free = scratchSlots - u.Used
+	u.Pressure = u.Used >= scratchPressureMin
+
+	return u
+}
+
+type ScratchPressureRule struct{}
+
+func (r ScratchPressureRule) Id() string {
+	return "LINT0026"
+}
+
+func (r ScratchPressureRule) Desc() string {
+	return "Reports the programs close to exhausting the scratch slots"
+}
+
+var ScratchPressureRuleInstance = ScratchPressureRule{}
+
+func checkScratchPressure(res *ProcessResult) []Diagnostic {
+	// the liveness is computed only for the programs using enough of the slots
+	var used scratchSet
+	for _, op := range res.Listing {
+		switch op := op.(type) {
+		case *LoadExpr:
+			used.add(op.Index)
+		case *StoreExpr:
+			used.add(op.Index)
+		}
+	}
+
+	if used.count() < scratchPressureMin {
+		return nil
+	}
+
+	u := AnalyzeScratch(res)
+	if !u.Pressure {
+		return nil
+	}
+
+	// the first access of the highest used slot, where the allocation runs out
+	last := u.Slots[len(u.Slots)-1].Last
+
+	for i, op := range res.Listing {
+		var index uint8
+
+		switch op := op.(type) {
+		case *LoadExpr:
+			index = op.Index
+		case *StoreExpr:
+			index = op.Index
+		default:
+			continue
+		}
+
+		if index != last || i >= len(res.Sublines) {
+			continue
+		}
+
+		msg := fmt.Sprintf("%d of the %d scratch slots used, %d free", u.Used, scratchSlots, u.Free)
+		if u.HighWater < u.Used {
+			msg += fmt.Sprintf(" - at most %d are live at once, the slots not live at the same time can be shared", u.HighWater)
+		}
+
+		sub := res.Sublines[i]
+
+		return []Diagnostic{
+			lintError{
+				error: errors.New(msg),
+				l:     sub.Line,
+				b:     sub.Tokens.Begin(),
+				e:     codeEnd(sub),
+				s:     DiagWarn,
+				r:     ScratchPressureRuleInstance.Id(),
+			},
+		}
+	}
+
+	return nil
+}
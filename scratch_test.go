@@ -0,0 +1,73 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeScratch(t *testing.T) {
+	u := AnalyzeScratch(Process("#pragma version 8\nint 1\nstore 0\nload 0\nstore 1\nload 1\nstore 5\nload 5\nreturn"))
+
+	if u.Used != 3 || u.Free != 253 || u.HighWater != 1 || u.Dynamic || u.Pressure {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+
+	if len(u.Slots) != 2 || u.Slots[0] != (ScratchRange{First: 0, Last: 1}) || u.Slots[1] != (ScratchRange{First: 5, Last: 5}) {
+		t.Errorf("unexpected slots: %v", u.Slots)
+	}
+
+	if len(u.Holes) != 1 || u.Holes[0] != (ScratchRange{First: 2, Last: 4}) {
+		t.Errorf("unexpected holes: %v", u.Holes)
+	}
+}
+
+func TestAnalyzeScratchLive(t *testing.T) {
+	u := AnalyzeScratch(Process("#pragma version 8\nint 1\ndup\nstore 0\nstore 1\nload 0\nload 1\n+\nreturn"))
+	if u.HighWater != 2 || u.HighWaterLine != 4 {
+		t.Errorf("unexpected high water: %+v", u)
+	}
+
+	// the slot stored before the call is live in the subroutine
+	u = AnalyzeScratch(Process("#pragma version 8\nint 1\nstore 0\ncallsub f\nload 0\nreturn\nf:\nint 2\nstore 1\nload 1\npop\nretsub"))
+	if u.HighWater != 2 {
+		t.Errorf("expected 2 slots live in the subroutine, got: %+v", u)
+	}
+
+	// the slot of a loop is live across the back edge
+	u = AnalyzeScratch(Process("#pragma version 8\nint 0\nstore 0\nloop:\nint 1\nstore 1\nload 1\npop\nload 0\nint 1\n+\ndup\nstore 0\nint 3\n<\nbnz loop\nint 1\nreturn"))
+	if u.HighWater != 2 {
+		t.Errorf("expected 2 slots live in the loop, got: %+v", u)
+	}
+}
+
+func TestAnalyzeScratchDynamic(t *testing.T) {
+	u := AnalyzeScratch(Process("#pragma version 8\nint 1\nint 2\nstores\nint 1\nreturn"))
+	if !u.Dynamic || u.Used != 0 {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+}
+
+func TestScratchPressure(t *testing.T) {
+	res := Process(scratchExample(250))
+
+	var ds []Diagnostic
+	for _, d := range res.Diagnostics {
+		if d.Rule() == ScratchPressureRuleInstance.Id() {
+			ds = append(ds, d)
+		}
+	}
+
+	if len(ds) != 1 {
+		t.Fatalf("expected 1 warning, got: %v", ds)
+	}
+
+	// the slots are stored and never read so only the one stored last is live
+	if !strings.Contains(ds[0].String(), "250 of the 256 scratch slots used, 6 free - at most 1 are live at once") {
+		t.Errorf("unexpected warning: %s", ds[0])
+	}
+
+	// the stores of 8 slots per line follow the pragma and the int
+	if ds[0].Line() != 1+249/8+1 {
+		t.Errorf("unexpected line: %d", ds[0].Line())
+	}
+}
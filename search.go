@@ -0,0 +1,195 @@
+package teal
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// searchOp matches an op by its name alternatives and its leading immediates, all of them glob patterns.
+type searchOp struct {
+	names []string
+	imms  []string
+}
+
+func (p searchOp) match(op Op) bool {
+	fields := strings.Fields(op.String())
+	if len(fields) == 0 {
+		return false
+	}
+
+	found := false
+	for _, name := range p.names {
+		if ok, _ := path.Match(name, fields[0]); ok {
+			found = true
+			break
+		}
+	}
+
+	if !found || len(fields)-1 < len(p.imms) {
+		return false
+	}
+
+	for i, imm := range p.imms {
+		if ok, _ := path.Match(imm, fields[i+1]); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// searchElem is an op or a gap of up to max ops, -1 for any number of them.
+type searchElem struct {
+	op  *searchOp
+	max int
+}
+
+// Pattern is a compiled op sequence pattern.
+type Pattern struct {
+	elems []searchElem
+}
+
+// SearchMatch is a match of a pattern, Start and End are the listing indexes of the first and the last matched op.
+type SearchMatch struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CompilePattern compiles the op sequence pattern. The ops are separated with ';' or new lines,
+// each of them is a '|' separated list of the op names followed by the leading immediates to match,
+// the names and the immediates are glob patterns, e.g. `gtxn * RekeyTo` or `byte|pushbytes`.
+// The '...' gap matches any number of the ops and the '...N' gap matches up to N of them,
+// e.g. `itxn_field RekeyTo; ...5; itxn_submit`.
+func CompilePattern(s string) (Pattern, error) {
+	var p Pattern
+
+	for _, line := range strings.Split(s, "\n") {
+		for _, part := range strings.Split(line, ";") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 {
+				continue
+			}
+
+			if strings.HasPrefix(fields[0], "...") {
+				if len(fields) > 1 {
+					return Pattern{}, errors.Errorf("unexpected gap immediates: %s", part)
+				}
+
+				max := -1
+				if n := strings.TrimPrefix(fields[0], "..."); n != "" {
+					v, err := strconv.Atoi(n)
+					if err != nil || v < 0 {
+						return Pattern{}, errors.Errorf("invalid gap: %s", fields[0])
+					}
+					max = v
+				}
+
+				p.elems = append(p.elems, searchElem{max: max})
+				continue
+			}
+
+			op := &searchOp{
+				names: strings.Split(fields[0], "|"),
+				imms:  fields[1:],
+			}
+
+			for _, g := range append(append([]string{}, op.names...), op.imms...) {
+				if _, err := path.Match(g, ""); err != nil || g == "" {
+					return Pattern{}, errors.Errorf("invalid op pattern: %s", part)
+				}
+			}
+
+			p.elems = append(p.elems, searchElem{op: op})
+		}
+	}
+
+	if len(p.elems) == 0 {
+		return Pattern{}, errors.New("empty pattern")
+	}
+
+	if p.elems[0].op == nil || p.elems[len(p.elems)-1].op == nil {
+		return Pattern{}, errors.New("pattern cannot start or end with a gap")
+	}
+
+	return p, nil
+}
+
+func mustCompilePattern(s string) Pattern {
+	p, err := CompilePattern(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// match returns the position of the last op matching the elems from the position, the gaps are as short as possible.
+func (p Pattern) match(ops []Op, elems []searchElem, pos int) (int, bool) {
+	e := elems[0]
+
+	if e.op == nil {
+		for n := 0; e.max < 0 || n <= e.max; n++ {
+			if pos+n >= len(ops) {
+				break
+			}
+
+			if end, ok := p.match(ops, elems[1:], pos+n); ok {
+				return end, true
+			}
+		}
+
+		return 0, false
+	}
+
+	if pos >= len(ops) || !e.op.match(ops[pos]) {
+		return 0, false
+	}
+
+	if len(elems) == 1 {
+		return pos, true
+	}
+
+	return p.match(ops, elems[1:], pos+1)
+}
+
+// Match returns the leftmost non-overlapping matches of the pattern in the listing, the nops like the labels and the comments are skipped.
+func (p Pattern) Match(listing []Op) []SearchMatch {
+	var ops []Op
+	var index []int
+
+	for i, op := range listing {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		ops = append(ops, op)
+		index = append(index, i)
+	}
+
+	var res []SearchMatch
+
+	for pos := 0; pos < len(ops); pos++ {
+		end, ok := p.match(ops, p.elems, pos)
+		if !ok {
+			continue
+		}
+
+		res = append(res, SearchMatch{Start: index[pos], End: index[end]})
+		pos = end
+	}
+
+	return res
+}
+
+// Search returns the matches of the op sequence pattern in the listing, see CompilePattern for the pattern syntax.
+func Search(listing []Op, pattern string) ([]SearchMatch, error) {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Match(listing), nil
+}
@@ -0,0 +1,61 @@
+package teal
+
+import "testing"
+
+func TestSearch(t *testing.T) {
+	res := Process(`#pragma version 8
+itxn_begin
+int pay
+itxn_field TypeEnum
+global ZeroAddress
+itxn_field RekeyTo
+// comment
+label:
+int 0
+itxn_field Amount
+itxn_submit
+gtxn 0 RekeyTo
+gtxn 1 Fee
+int 1`)
+
+	tests := []struct {
+		pattern string
+		matches []SearchMatch
+	}{
+		{"itxn_field RekeyTo; ...5; itxn_submit", []SearchMatch{{Start: 5, End: 10}}},
+		{"itxn_field RekeyTo; ...1; itxn_submit", nil},
+		{"itxn_field RekeyTo; ...; itxn_submit", []SearchMatch{{Start: 5, End: 10}}},
+		{"itxn_field Rekey*\nint 0", []SearchMatch{{Start: 5, End: 8}}},
+		{"itxn_field", []SearchMatch{{Start: 3, End: 3}, {Start: 5, End: 5}, {Start: 9, End: 9}}},
+		{"gtxn * Fee", []SearchMatch{{Start: 12, End: 12}}},
+		{"gtxn|txn 0", []SearchMatch{{Start: 11, End: 11}}},
+		{"int|global; ...0; int", nil},
+		{"int; itxn_field; int|global", []SearchMatch{{Start: 2, End: 4}}},
+	}
+
+	for _, test := range tests {
+		ms, err := Search(res.Listing, test.pattern)
+		if err != nil {
+			t.Fatalf("failed to search %s: %s", test.pattern, err)
+		}
+
+		if len(ms) != len(test.matches) {
+			t.Errorf("unexpected matches of %s - expected: %v, got: %v", test.pattern, test.matches, ms)
+			continue
+		}
+
+		for i, m := range ms {
+			if m != test.matches[i] {
+				t.Errorf("unexpected match %d of %s - expected: %v, got: %v", i, test.pattern, test.matches[i], m)
+			}
+		}
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, p := range []string{"", " ; ", "...; int 1", "int 1; ...", "int; ...x; int", "int; ... 1; int", "[; int"} {
+		if _, err := CompilePattern(p); err == nil {
+			t.Errorf("expected error for pattern: %q", p)
+		}
+	}
+}
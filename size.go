@@ -0,0 +1,234 @@
+package teal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+const (
+	// MaxAppProgramLen is the max size of the approval and clear state programs per page
+	MaxAppProgramLen = 2048
+
+	// MaxExtraAppProgramPages is the max number of extra program pages of an application
+	MaxExtraAppProgramPages = 3
+
+	// MaxLogicSigLen is the max size of a logic signature program
+	MaxLogicSigLen = 1000
+
+	// LogicSigBudget is the max cost of a logic signature program
+	LogicSigBudget = 20000
+
+	costMaxBranches = 1000
+)
+
+var langOpSizes = func() map[string]int {
+	res := map[string]int{}
+	for _, op := range BuiltInLangSpec.Ops {
+		res[op.Name] = op.Size
+	}
+	return res
+}()
+
+func varuintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+
+	return n
+}
+
+func bytesSize(bs []byte) int {
+	return varuintSize(uint64(len(bs))) + len(bs)
+}
+
+// constRefsSize returns the size of the constants given their sizes and use counts.
+// The most used values go to the constant block if referencing them is smaller than pushing them, the first 4 get the 1 byte refs.
+func constRefsSize(sizes []int, counts []int, push bool) int {
+	order := make([]int, len(counts))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	size := 0
+	block := 0
+
+	for _, k := range order {
+		ref := 2
+		if block < 4 {
+			ref = 1
+		}
+
+		refs := sizes[k] + ref*counts[k]
+		pushes := (1 + sizes[k]) * counts[k]
+
+		if push && pushes <= refs {
+			size += pushes
+			continue
+		}
+
+		size += refs
+		block++
+	}
+
+	if block > 0 {
+		size += 1 + varuintSize(uint64(block))
+	}
+
+	return size
+}
+
+// EstimateSize estimates the size of the assembled program in bytes.
+// The int, byte, addr and method pseudo ops are counted as pushes or as references to the constant blocks created by the assembler.
+func EstimateSize(res *ProcessResult) int {
+	size := varuintSize(res.Version)
+
+	ints := map[uint64]int{}
+	bytes := map[string]int{}
+
+	for _, op := range res.Listing {
+		switch op := op.(type) {
+		case Nop:
+		case *IntExpr:
+			ints[op.Value]++
+		case *ByteExpr, *MethodExpr:
+			bs, _ := constBytes(op)
+			bytes[string(bs)]++
+		case *AddrExpr:
+			addr, err := types.DecodeAddress(op.Address)
+			if err == nil {
+				bytes[string(addr[:])]++
+			}
+		case *IntcBlockExpr:
+			size += 1 + varuintSize(uint64(len(op.Values)))
+			for _, v := range op.Values {
+				size += varuintSize(v)
+			}
+		case *BytecBlockExpr:
+			size += 1 + varuintSize(uint64(len(op.Values)))
+			for _, v := range op.Values {
+				size += bytesSize(v)
+			}
+		case *PushIntExpr:
+			size += 1 + varuintSize(op.Value)
+		case *PushBytesExpr:
+			size += 1 + bytesSize(op.Value)
+		case *PushIntsExpr:
+			size += 1 + varuintSize(uint64(len(op.Ints)))
+			for _, v := range op.Ints {
+				size += varuintSize(v)
+			}
+		case *PushBytessExpr:
+			size += 1 + varuintSize(uint64(len(op.Bytess)))
+			for _, v := range op.Bytess {
+				size += bytesSize(v)
+			}
+		case *SwitchExpr:
+			size += 2 + 2*len(op.Targets)
+		case *MatchExpr:
+			size += 2 + 2*len(op.Targets)
+		default:
+			name, _, _ := strings.Cut(op.String(), " ")
+			if s, ok := langOpSizes[name]; ok {
+				size += s
+			} else {
+				size++
+			}
+		}
+	}
+
+	// push ops are available since v3
+	push := res.Version >= 3
+
+	var sizes, counts []int
+
+	for v, n := range ints {
+		sizes = append(sizes, varuintSize(v))
+		counts = append(counts, n)
+	}
+
+	size += constRefsSize(sizes, counts, push)
+
+	sizes, counts = nil, nil
+
+	for v, n := range bytes {
+		sizes = append(sizes, bytesSize([]byte(v)))
+		counts = append(counts, n)
+	}
+
+	size += constRefsSize(sizes, counts, push)
+
+	return size
+}
+
+// Cost is the estimated max cost of the program paths.
+type Cost struct {
+	Max    int
+	Budget int
+
+	// Exceeded is set if a path may exceed the budget
+	Exceeded bool
+
+	// Incomplete is set if not all the paths were analyzed
+	Incomplete bool
+
+	// Path is the most expensive path, Subroutines is the budget its subroutines consume
+	Path        *VmBranch
+	Subroutines []SubroutineBudget
+}
+
+// ProgramBudget returns the cost budget of a single program run in the mode.
+func ProgramBudget(mode ProgramMode) int {
+	if mode == ModeSig {
+		return LogicSigBudget
+	}
+
+	return VmDefaultBudget
+}
+
+// ProgramMaxSize returns the max program size in the mode, extra pages apply to applications only.
+func ProgramMaxSize(mode ProgramMode, extraPages int) int {
+	if mode == ModeSig {
+		return MaxLogicSigLen
+	}
+
+	return MaxAppProgramLen * (1 + extraPages)
+}
+
+// EstimateCost runs the program paths with the budget and returns the highest cost.
+func EstimateCost(res *ProcessResult, budget int) Cost {
+	vm := NewVm(res)
+	for _, b := range vm.Branches {
+		b.Budget = budget
+	}
+
+	c := Cost{
+		Budget:     budget,
+		Incomplete: !vm.RunAll(costMaxBranches),
+	}
+
+	for _, b := range vm.Branches {
+		if b.Exhausted {
+			c.Exceeded = true
+		}
+
+		used := budget - b.Budget
+		if used > c.Max || c.Path == nil {
+			c.Max = used
+			c.Path = b
+		}
+	}
+
+	if c.Path != nil {
+		c.Subroutines = c.Path.SubroutineBudgets()
+	}
+
+	return c
+}
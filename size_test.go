@@ -0,0 +1,50 @@
+package teal
+
+import "testing"
+
+func TestEstimateSize(t *testing.T) {
+	tests := []struct {
+		Source string
+		Size   int
+	}{
+		{
+			Source: "#pragma version 8\nint 1\nreturn",
+			Size:   4,
+		},
+		{
+			Source: "#pragma version 8\nint 5\nint 5\nint 5\n+\n+\nreturn",
+			Size:   10,
+		},
+		{
+			Source: "#pragma version 2\nint 1\nreturn",
+			Size:   6,
+		},
+		{
+			Source: "#pragma version 8\nint 1\nbnz l\nl:\nbyte 0x0102\nlen\nreturn",
+			Size:   12,
+		},
+		{
+			Source: "#pragma version 8\npushbytes 0x0102\nintcblock 1 300\nswitch a b\na:\nb:",
+			Size:   16,
+		},
+	}
+
+	for _, test := range tests {
+		size := EstimateSize(Process(test.Source))
+		if size != test.Size {
+			t.Errorf("unexpected size: %d, expected: %d for:\n%s", size, test.Size, test.Source)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	c := EstimateCost(Process("#pragma version 8\nint 1\nint 2\n+\nreturn"), VmDefaultBudget)
+	if c.Max != 4 || c.Exceeded || c.Incomplete {
+		t.Errorf("unexpected cost: %+v", c)
+	}
+
+	c = EstimateCost(Process("#pragma version 8\nbyte 0x01\nl:\nsha256\nb l"), VmDefaultBudget)
+	if !c.Exceeded {
+		t.Errorf("expected exceeded cost: %+v", c)
+	}
+}
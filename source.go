@@ -0,0 +1,71 @@
+package teal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// CompiledProgramError is a compiled program passed as a source.
+type CompiledProgramError struct {
+	Version uint64
+}
+
+func (e *CompiledProgramError) Error() string {
+	return fmt.Sprintf("this looks like compiled TEAL (version %d bytecode), disassemble it first, e.g. with goal clerk compile -D", e.Version)
+}
+
+// binaryOffset returns the offset of the first byte that is not a part of a UTF-8 text, -1 if there is none.
+func binaryOffset(bs []byte) int {
+	for i := 0; i < len(bs); {
+		r, n := utf8.DecodeRune(bs[i:])
+		if r == utf8.RuneError && n == 1 {
+			return i
+		}
+
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' {
+			return i
+		}
+
+		i += n
+	}
+
+	return -1
+}
+
+// CheckSource returns an error if the bytes are not a UTF-8 text, *CompiledProgramError if they look like a compiled program:
+// a varuint version supported by the VM followed by the binary ops.
+func CheckSource(bs []byte) error {
+	if len(bs) == 0 {
+		return nil
+	}
+
+	v, n := binary.Uvarint(bs)
+	if n > 0 && v >= 1 && v <= uint64(BuiltInLangSpec.EvalMaxVersion) && binaryOffset(bs[n:]) != -1 {
+		return &CompiledProgramError{Version: v}
+	}
+
+	if i := binaryOffset(bs); i != -1 {
+		return errors.Errorf("not a UTF-8 text file - invalid byte 0x%02x at offset: %d", bs[i], i)
+	}
+
+	return nil
+}
+
+// ReadSource reads the source file, the compiled programs and the binary files are rejected with the CheckSource errors.
+func ReadSource(path string) ([]byte, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read source file")
+	}
+
+	err = CheckSource(bs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid source file: %s", path)
+	}
+
+	return bs, nil
+}
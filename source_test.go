@@ -0,0 +1,44 @@
+package teal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCheckSource(t *testing.T) {
+	for _, s := range []string{"", "#pragma version 8\r\nint 1\n", "\tint 1\n", "\ufeffint 1 // ünïcode\n"} {
+		if err := CheckSource([]byte(s)); err != nil {
+			t.Errorf("unexpected error for %q: %s", s, err)
+		}
+	}
+
+	err := CheckSource([]byte{0x08, 0x20, 0x01, 0x01, 0x22, 0x43})
+
+	var ce *CompiledProgramError
+	if !errors.As(err, &ce) || ce.Version != 8 {
+		t.Errorf("expected compiled program error, got: %v", err)
+	}
+
+	if err := CheckSource([]byte("int 1 // \xff\n")); err == nil || errors.As(err, &ce) {
+		t.Errorf("expected invalid UTF-8 error, got: %v", err)
+	}
+}
+
+func TestReadSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approval.teal")
+
+	err := os.WriteFile(path, []byte{0x06, 0x81, 0x01, 0x43}, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadSource(path)
+
+	var ce *CompiledProgramError
+	if !errors.As(err, &ce) || ce.Version != 6 {
+		t.Errorf("expected compiled program error, got: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package teal
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SourceMap is the source map of a compiled program as returned by the algod compile endpoint,
+// the generated lines of the mappings are the program counters, or of a TEAL program generated by a high-level language like PyTeal or TEALScript,
+// the generated lines are the TEAL lines.
+type SourceMap struct {
+	Version    int      `json:"version"`
+	SourceRoot string   `json:"sourceRoot,omitempty"`
+	Sources    []string `json:"sources"`
+	Names      []string `json:"names"`
+	Mappings   string   `json:"mappings"`
+}
+
+// ParseSourceMap parses the JSON source map.
+func ParseSourceMap(bs []byte) (SourceMap, error) {
+	var m SourceMap
+
+	err := json.Unmarshal(bs, &m)
+	if err != nil {
+		return m, errors.Wrap(err, "failed to parse source map")
+	}
+
+	if m.Version != 3 {
+		return m, errors.Errorf("unsupported source map version: %d", m.Version)
+	}
+
+	return m, nil
+}
+
+// ReadSourceMap reads the JSON source map file.
+func ReadSourceMap(path string) (SourceMap, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return SourceMap{}, errors.Wrap(err, "failed to read source map")
+	}
+
+	return ParseSourceMap(bs)
+}
+
+const vlqChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// decodeVlq decodes the base64 VLQ values of a mapping segment.
+func decodeVlq(s string) ([]int, error) {
+	var res []int
+
+	v := 0
+	shift := 0
+
+	for _, c := range s {
+		d := strings.IndexRune(vlqChars, c)
+		if d < 0 {
+			return nil, errors.Errorf("invalid mapping character: %c", c)
+		}
+
+		v += (d & 31) << shift
+
+		if d&32 != 0 {
+			shift += 5
+			continue
+		}
+
+		if v&1 != 0 {
+			res = append(res, -(v >> 1))
+		} else {
+			res = append(res, v>>1)
+		}
+
+		v = 0
+		shift = 0
+	}
+
+	if shift != 0 {
+		return nil, errors.Errorf("truncated mapping segment: %s", s)
+	}
+
+	return res, nil
+}
+
+// segments calls f with the generated line and the source index, line and column of each of the mapping segments.
+func (m SourceMap) segments(f func(gen int, src int, line int, col int)) error {
+	src, line, col := 0, 0, 0
+
+	for gen, group := range strings.Split(m.Mappings, ";") {
+		if group == "" {
+			continue
+		}
+
+		for _, seg := range strings.Split(group, ",") {
+			vs, err := decodeVlq(seg)
+			if err != nil {
+				return err
+			}
+
+			if len(vs) < 4 {
+				continue
+			}
+
+			src += vs[1]
+			line += vs[2]
+			col += vs[3]
+
+			f(gen, src, line, col)
+		}
+	}
+
+	return nil
+}
+
+// PcLines returns the zero-based source lines of the program counters with a mapping.
+func (m SourceMap) PcLines() (map[int]int, error) {
+	res := map[int]int{}
+
+	err := m.segments(func(pc int, src int, line int, col int) {
+		res[pc] = line
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// SourceLocation is a zero-based line of a source of a source map.
+type SourceLocation struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+}
+
+// SourceBlock is a range of the generated lines, both zero-based and inclusive, originating from the same source line.
+type SourceBlock struct {
+	Start  int            `json:"start"`
+	End    int            `json:"end"`
+	Origin SourceLocation `json:"origin"`
+}
+
+// source returns the source path of the index prefixed with the source root.
+func (m SourceMap) source(i int) string {
+	if i < 0 || i >= len(m.Sources) {
+		return ""
+	}
+
+	if m.SourceRoot == "" {
+		return m.Sources[i]
+	}
+
+	return strings.TrimSuffix(m.SourceRoot, "/") + "/" + m.Sources[i]
+}
+
+// Blocks returns the consecutive generated lines mapped to the same source line, the first segment of a line maps it.
+func (m SourceMap) Blocks() ([]SourceBlock, error) {
+	var res []SourceBlock
+
+	last := -1
+
+	err := m.segments(func(gen int, src int, line int, col int) {
+		if gen == last {
+			return
+		}
+
+		origin := SourceLocation{Source: m.source(src), Line: line}
+
+		if n := len(res); n > 0 && res[n-1].End == last && last == gen-1 && res[n-1].Origin == origin {
+			res[n-1].End = gen
+		} else {
+			res = append(res, SourceBlock{Start: gen, End: gen, Origin: origin})
+		}
+
+		last = gen
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
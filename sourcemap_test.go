@@ -0,0 +1,66 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeVlq(t *testing.T) {
+	vs, err := decodeVlq("AACDgB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{0, 0, 1, -1, 16}
+	if !reflect.DeepEqual(vs, expected) {
+		t.Errorf("expected: %v, got: %v", expected, vs)
+	}
+
+	if _, err := decodeVlq("g"); err == nil {
+		t.Error("expected truncated segment error")
+	}
+}
+
+func TestSourceMapPcLines(t *testing.T) {
+	m, err := ParseSourceMap([]byte(`{"version":3,"sources":["a.teal"],"names":[],"mappings":";AACA;;AACA;AACA;AADA"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := m.PcLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int]int{1: 1, 3: 2, 4: 3, 5: 2}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected: %v, got: %v", expected, lines)
+	}
+
+	if _, err := ParseSourceMap([]byte(`{"version":2}`)); err == nil {
+		t.Error("expected unsupported version error")
+	}
+}
+
+func TestSourceMapBlocks(t *testing.T) {
+	m, err := ParseSourceMap([]byte(`{"version":3,"sourceRoot":"src/","sources":["contract.py","lib.py"],"names":[],"mappings":"AAAA;AACA,IAAC;AAAA;;ACAA;AAAA;ADAA"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := m.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SourceBlock{
+		{Start: 0, End: 0, Origin: SourceLocation{Source: "src/contract.py", Line: 0}},
+		{Start: 1, End: 2, Origin: SourceLocation{Source: "src/contract.py", Line: 1}},
+		{Start: 4, End: 5, Origin: SourceLocation{Source: "src/lib.py", Line: 1}},
+		{Start: 6, End: 6, Origin: SourceLocation{Source: "src/contract.py", Line: 1}},
+	}
+
+	if !reflect.DeepEqual(blocks, expected) {
+		t.Errorf("expected: %v, got: %v", expected, blocks)
+	}
+}
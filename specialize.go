@@ -0,0 +1,538 @@
+package teal
+
+import (
+	"bytes"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// Binding is the constant value of a bound txn field, Bytes is used for the byte fields.
+type Binding struct {
+	Uint  uint64
+	Bytes []byte
+}
+
+// Bindings are the txn fields and the args the program is specialized for.
+type Bindings struct {
+	Txn map[TxnField]Binding
+
+	// Args are the app call args read with txna ApplicationArgs and the logic signature args read with arg
+	Args map[uint8][]byte
+}
+
+// specializeConst is a constant pushed by an op.
+type specializeConst struct {
+	u     uint64
+	b     []byte
+	bytes bool
+}
+
+// specializeBlocks are the constants of the intcblock and the bytecblock the intc and bytec ops refer to,
+// the constants of the listings with more than one block of a kind are not resolved as the block in effect depends on the path.
+type specializeBlocks struct {
+	ints   []uint64
+	bytess [][]byte
+}
+
+func newSpecializeBlocks(l Listing) specializeBlocks {
+	var cs specializeBlocks
+
+	nints, nbytess := 0, 0
+
+	for _, op := range l {
+		switch op := op.(type) {
+		case *IntcBlockExpr:
+			cs.ints = op.Values
+			nints++
+		case *BytecBlockExpr:
+			cs.bytess = op.Values
+			nbytess++
+		}
+	}
+
+	if nints > 1 {
+		cs.ints = nil
+	}
+
+	if nbytess > 1 {
+		cs.bytess = nil
+	}
+
+	return cs
+}
+
+func (cs specializeBlocks) constOf(op Op) (specializeConst, bool) {
+	if v, ok := constUint(op); ok {
+		return specializeConst{u: v}, true
+	}
+
+	if v, ok := constBytes(op); ok {
+		return specializeConst{b: v, bytes: true}, true
+	}
+
+	i := -1
+	bytes := false
+
+	switch op := op.(type) {
+	case *IntcExpr:
+		i = int(op.Index)
+	case *Intc0Expr:
+		i = 0
+	case *Intc1Expr:
+		i = 1
+	case *Intc2Expr:
+		i = 2
+	case *Intc3Expr:
+		i = 3
+	case *BytecExpr:
+		i, bytes = int(op.Index), true
+	case *Bytec0Expr:
+		i, bytes = 0, true
+	case *Bytec1Expr:
+		i, bytes = 1, true
+	case *Bytec2Expr:
+		i, bytes = 2, true
+	case *Bytec3Expr:
+		i, bytes = 3, true
+	default:
+		return specializeConst{}, false
+	}
+
+	if bytes {
+		if i < len(cs.bytess) {
+			return specializeConst{b: cs.bytess[i], bytes: true}, true
+		}
+	} else if i < len(cs.ints) {
+		return specializeConst{u: cs.ints[i]}, true
+	}
+
+	return specializeConst{}, false
+}
+
+// specializeBytes returns the byte constant, as a string literal if it is printable.
+func specializeBytes(v []byte) *ByteExpr {
+	for _, c := range v {
+		if c < 0x20 || c > 0x7e || c == '"' || c == '\\' {
+			return &ByteExpr{Value: v, Format: BytesBase64}
+		}
+	}
+
+	return &ByteExpr{Value: v, Format: BytesStringLiteral}
+}
+
+func specializeBool(v bool) Op {
+	if v {
+		return Int(1)
+	}
+
+	return Int(0)
+}
+
+// bind returns the constant the bindings replace the op with.
+func (bs Bindings) bind(op Op) (Op, bool) {
+	var index uint8
+
+	switch op := op.(type) {
+	case *TxnExpr:
+		v, ok := bs.Txn[op.Field]
+		if !ok {
+			return nil, false
+		}
+
+		spec, _ := txnFieldSpecByField(op.Field)
+		if spec.ftype == StackBytes {
+			return specializeBytes(v.Bytes), true
+		}
+
+		return Int(v.Uint), true
+	case *TxnaExpr:
+		if op.Field != ApplicationArgs {
+			return nil, false
+		}
+		index = op.Index
+	case *ArgExpr:
+		index = op.Index
+	case *Arg0Expr:
+		index = 0
+	case *Arg1Expr:
+		index = 1
+	case *Arg2Expr:
+		index = 2
+	case *Arg3Expr:
+		index = 3
+	default:
+		return nil, false
+	}
+
+	v, ok := bs.Args[index]
+	if !ok {
+		return nil, false
+	}
+
+	return specializeBytes(v), true
+}
+
+func (bs Bindings) validate() error {
+	for f := range bs.Txn {
+		spec, ok := txnFieldSpecByField(f)
+		if !ok {
+			return errors.Errorf("unknown txn field: %d", f)
+		}
+
+		if spec.array {
+			return errors.Errorf("array txn field cannot be bound: %s", f)
+		}
+	}
+
+	return nil
+}
+
+// specializeOperands returns the listing indexes of the n constants the op at i pops,
+// the constants must directly precede the op without a label in between.
+func specializeOperands(l Listing, cs specializeBlocks, i int, n int) ([]int, []specializeConst, bool) {
+	idxs := make([]int, n)
+	vals := make([]specializeConst, n)
+
+	j := i - 1
+	for k := n - 1; k >= 0; k-- {
+		for ; j >= 0; j-- {
+			if _, ok := l[j].(*LabelExpr); ok {
+				return nil, nil, false
+			}
+			if _, ok := l[j].(Nop); !ok {
+				break
+			}
+		}
+
+		if j < 0 {
+			return nil, nil, false
+		}
+
+		v, ok := cs.constOf(l[j])
+		if !ok {
+			return nil, nil, false
+		}
+
+		idxs[k], vals[k] = j, v
+		j--
+	}
+
+	return idxs, vals, true
+}
+
+// specializeFold returns the ops replacing the op at i and its constant operands.
+func specializeFold(l Listing, cs specializeBlocks, i int) ([]int, []Op, bool) {
+	op := l[i]
+
+	switch op.(type) {
+	case *NotExpr, *LenExpr, *BtoiExpr, *ItobExpr, *PopExpr, *AssertExpr, *BnzExpr, *BzExpr:
+		idxs, vs, ok := specializeOperands(l, cs, i, 1)
+		if !ok {
+			return nil, nil, false
+		}
+
+		v := vs[0]
+
+		if _, ok := op.(*PopExpr); ok {
+			return idxs, nil, true
+		}
+
+		if v.bytes {
+			switch op.(type) {
+			case *LenExpr:
+				return idxs, []Op{Int(uint64(len(v.b)))}, true
+			case *BtoiExpr:
+				if len(v.b) > 8 {
+					return nil, nil, false
+				}
+
+				var n uint64
+				for _, c := range v.b {
+					n = n<<8 | uint64(c)
+				}
+
+				return idxs, []Op{Int(n)}, true
+			}
+
+			return nil, nil, false
+		}
+
+		switch op := op.(type) {
+		case *NotExpr:
+			return idxs, []Op{specializeBool(v.u == 0)}, true
+		case *ItobExpr:
+			b := make([]byte, 8)
+			for k := 0; k < 8; k++ {
+				b[k] = byte(v.u >> (56 - 8*k))
+			}
+			return idxs, []Op{specializeBytes(b)}, true
+		case *AssertExpr:
+			if v.u == 0 {
+				return idxs, []Op{Err}, true
+			}
+			return idxs, nil, true
+		case *BnzExpr:
+			if v.u != 0 {
+				return idxs, []Op{B(op.Label)}, true
+			}
+			return idxs, nil, true
+		case *BzExpr:
+			if v.u == 0 {
+				return idxs, []Op{B(op.Label)}, true
+			}
+			return idxs, nil, true
+		}
+	case *PlusExpr, *MinusExpr, *MulExpr, *DivExpr, *ModExpr, *LtExpr, *GtExpr, *LtEqExpr, *GtEqExpr,
+		*AndExpr, *OrExpr, *BitOrExpr, *BitAndExpr, *BitXorExpr, *EqExpr, *NeqExpr, *ConcatExpr:
+		idxs, vs, ok := specializeOperands(l, cs, i, 2)
+		if !ok {
+			return nil, nil, false
+		}
+
+		x, y := vs[0], vs[1]
+		if x.bytes != y.bytes {
+			return nil, nil, false
+		}
+
+		if x.bytes {
+			switch op.(type) {
+			case *EqExpr:
+				return idxs, []Op{specializeBool(bytes.Equal(x.b, y.b))}, true
+			case *NeqExpr:
+				return idxs, []Op{specializeBool(!bytes.Equal(x.b, y.b))}, true
+			case *ConcatExpr:
+				if len(x.b)+len(y.b) > 4096 {
+					return nil, nil, false
+				}
+				return idxs, []Op{specializeBytes(append(append([]byte{}, x.b...), y.b...))}, true
+			}
+
+			return nil, nil, false
+		}
+
+		var r Op
+
+		// the ops failing at runtime are left for the program to fail
+		switch op.(type) {
+		case *PlusExpr:
+			s, carry := bits.Add64(x.u, y.u, 0)
+			if carry != 0 {
+				return nil, nil, false
+			}
+			r = Int(s)
+		case *MinusExpr:
+			if y.u > x.u {
+				return nil, nil, false
+			}
+			r = Int(x.u - y.u)
+		case *MulExpr:
+			hi, lo := bits.Mul64(x.u, y.u)
+			if hi != 0 {
+				return nil, nil, false
+			}
+			r = Int(lo)
+		case *DivExpr:
+			if y.u == 0 {
+				return nil, nil, false
+			}
+			r = Int(x.u / y.u)
+		case *ModExpr:
+			if y.u == 0 {
+				return nil, nil, false
+			}
+			r = Int(x.u % y.u)
+		case *LtExpr:
+			r = specializeBool(x.u < y.u)
+		case *GtExpr:
+			r = specializeBool(x.u > y.u)
+		case *LtEqExpr:
+			r = specializeBool(x.u <= y.u)
+		case *GtEqExpr:
+			r = specializeBool(x.u >= y.u)
+		case *AndExpr:
+			r = specializeBool(x.u != 0 && y.u != 0)
+		case *OrExpr:
+			r = specializeBool(x.u != 0 || y.u != 0)
+		case *BitOrExpr:
+			r = Int(x.u | y.u)
+		case *BitAndExpr:
+			r = Int(x.u & y.u)
+		case *BitXorExpr:
+			r = Int(x.u ^ y.u)
+		case *EqExpr:
+			r = specializeBool(x.u == y.u)
+		case *NeqExpr:
+			r = specializeBool(x.u != y.u)
+		default:
+			return nil, nil, false
+		}
+
+		return idxs, []Op{r}, true
+	}
+
+	return nil, nil, false
+}
+
+// specializeConstants folds the first foldable op of the listing.
+func specializeConstants(l Listing, cs specializeBlocks) (Listing, bool) {
+	for i := range l {
+		idxs, repl, ok := specializeFold(l, cs, i)
+		if !ok {
+			continue
+		}
+
+		removed := map[int]bool{}
+		for _, j := range idxs {
+			removed[j] = true
+		}
+
+		var res Listing
+		for j, op := range l {
+			switch {
+			case removed[j]:
+			case j == i:
+				res = append(res, repl...)
+			default:
+				res = append(res, op)
+			}
+		}
+
+		return res, true
+	}
+
+	return l, false
+}
+
+// specializeReachable removes the ops unreachable from the entry and the labels no reachable op branches to.
+func specializeReachable(l Listing) Listing {
+	labels := map[string]int{}
+	for i, op := range l {
+		if op, ok := op.(*LabelExpr); ok {
+			labels[op.Name] = i
+		}
+	}
+
+	reach := make([]bool, len(l))
+	used := map[string]bool{}
+
+	todo := []int{0}
+	for len(todo) > 0 {
+		i := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		if i >= len(l) || reach[i] {
+			continue
+		}
+		reach[i] = true
+
+		op := l[i]
+
+		if u, ok := op.(usesLabels); ok {
+			for _, t := range u.Labels() {
+				used[t.Name] = true
+				if j, ok := labels[t.Name]; ok {
+					todo = append(todo, j)
+				}
+			}
+		}
+
+		switch op.(type) {
+		case *BExpr, *ReturnExpr, *ErrExpr, *RetSubExpr:
+		default:
+			todo = append(todo, i+1)
+		}
+	}
+
+	var res Listing
+	for i, op := range l {
+		switch op := op.(type) {
+		case *PragmaExpr:
+			res = append(res, op)
+			continue
+		case *LabelExpr:
+			if !used[op.Name] {
+				continue
+			}
+		}
+
+		if reach[i] {
+			res = append(res, op)
+		}
+	}
+
+	return res
+}
+
+// Specialize partially evaluates the program for the bound txn fields and args: the bound reads are replaced
+// with the constants, the constant expressions and branches are folded and the unreachable code is removed.
+func Specialize(source string, bindings Bindings, opts ...ProcessOption) (string, error) {
+	if err := bindings.validate(); err != nil {
+		return "", err
+	}
+
+	res := Process(source, opts...)
+
+	for _, d := range res.Diagnostics {
+		if _, ok := d.(parseError); ok {
+			return "", errors.Errorf("failed to parse line %d: %s", d.Line()+1, d)
+		}
+	}
+
+	if len(res.Includes) > 0 {
+		return "", errors.New("programs with includes cannot be specialized")
+	}
+
+	var l Listing
+	for _, op := range res.Listing {
+		switch o := op.(type) {
+		case *EmptyExpr:
+			continue
+		case *ByteExpr:
+			op = specializeBytes(o.Value)
+		}
+
+		if c, ok := bindings.bind(op); ok {
+			op = c
+		}
+
+		l = append(l, op)
+	}
+
+	cs := newSpecializeBlocks(l)
+
+	for {
+		n := len(l)
+
+		folded := false
+		for {
+			var ok bool
+			l, ok = specializeConstants(l, cs)
+			if !ok {
+				break
+			}
+			folded = true
+		}
+
+		l = specializeReachable(l)
+		l = removeBJustBeforeItsTargetLabel(l)
+
+		if !folded && len(l) == n {
+			break
+		}
+	}
+
+	// the mode is read from the comment the listing does not keep
+	if res.Mode == ModeSig {
+		i := 0
+		if len(l) > 0 {
+			if _, ok := l[0].(*PragmaExpr); ok {
+				i = 1
+			}
+		}
+
+		l = append(l[:i], append(Listing{&CommentExpr{Text: "#pragma mode logicsig"}}, l[i:]...)...)
+	}
+
+	return l.String(), nil
+}
@@ -0,0 +1,145 @@
+package teal
+
+import "testing"
+
+const specializeRouter = `#pragma version 8
+txn OnCompletion
+int NoOp
+==
+bnz main
+txn OnCompletion
+int OptIn
+==
+bnz optin
+err
+main:
+txna ApplicationArgs 0
+method "add(uint64)void"
+==
+bnz add
+err
+add:
+callsub inc
+int 1
+return
+optin:
+int 1
+return
+inc:
+byte "counter"
+dup
+app_global_get
+int 1
++
+app_global_put
+retsub`
+
+func TestSpecialize(t *testing.T) {
+	add, _ := constBytes(&MethodExpr{Signature: `"add(uint64)void"`})
+
+	tests := []struct {
+		src      string
+		bindings Bindings
+		expected string
+	}{
+		{
+			src:      specializeRouter,
+			bindings: Bindings{Txn: map[TxnField]Binding{OnCompletion: {Uint: 0}}, Args: map[uint8][]byte{0: add}},
+			expected: "#pragma version 8\ncallsub inc\nint 1\nreturn\ninc:\nbyte \"counter\"\ndup\napp_global_get\nint 1\n+\napp_global_put\nretsub\n",
+		},
+		{
+			src:      specializeRouter,
+			bindings: Bindings{Txn: map[TxnField]Binding{OnCompletion: {Uint: 1}}},
+			expected: "#pragma version 8\nint 1\nreturn\n",
+		},
+		// the unknown selector is still checked
+		{
+			src:      specializeRouter,
+			bindings: Bindings{Txn: map[TxnField]Binding{OnCompletion: {Uint: 0}}},
+			expected: "#pragma version 8\ntxna ApplicationArgs 0\nmethod \"add(uint64)void\"\n==\nbnz add\nerr\nadd:\ncallsub inc\nint 1\nreturn\ninc:\nbyte \"counter\"\ndup\napp_global_get\nint 1\n+\napp_global_put\nretsub\n",
+		},
+		{
+			src:      specializeRouter,
+			bindings: Bindings{Txn: map[TxnField]Binding{OnCompletion: {Uint: 5}}},
+			expected: "#pragma version 8\nerr\n",
+		},
+		{
+			src:      "#pragma version 8\n//#pragma mode logicsig\narg 0\nbyte \"secret\"\n==\narg_1\nlen\nint 3\n<\n&&\ntxn Fee\nint 1000\n<=\n&&",
+			bindings: Bindings{Txn: map[TxnField]Binding{Fee: {Uint: 1000}}, Args: map[uint8][]byte{1: {1, 2}}},
+			expected: "#pragma version 8\n//#pragma mode logicsig\narg 0\nbyte \"secret\"\n==\nint 1\n&&\nint 1\n&&\n",
+		},
+		// the failing ops are left for the program to fail
+		{
+			src:      "#pragma version 8\ntxn NumAppArgs\nint 1\n-\nreturn",
+			bindings: Bindings{Txn: map[TxnField]Binding{NumAppArgs: {Uint: 0}}},
+			expected: "#pragma version 8\nint 0\nint 1\n-\nreturn\n",
+		},
+		{
+			src:      "#pragma version 8\ntxn Sender\ntxn Receiver\n==\nassert\nint 1\nreturn",
+			bindings: Bindings{Txn: map[TxnField]Binding{Sender: {Bytes: []byte("a")}, Receiver: {Bytes: []byte("a")}}},
+			expected: "#pragma version 8\nint 1\nreturn\n",
+		},
+		// the constants of the intcblock and the bytecblock are folded too
+		{
+			src:      "#pragma version 8\nintcblock 1 6\ntxn TypeEnum\nintc_1\n==\nbz bad\nintc_0\nreturn\nbad:\nerr",
+			bindings: Bindings{Txn: map[TxnField]Binding{TypeEnum: {Uint: 6}}},
+			expected: "#pragma version 8\nintcblock 1 6\nintc_0\nreturn\n",
+		},
+		{
+			src:      "#pragma version 8\nintcblock 1 6\ntxn TypeEnum\nintc 0\n==\nbz bad\nintc_0\nreturn\nbad:\nerr",
+			bindings: Bindings{Txn: map[TxnField]Binding{TypeEnum: {Uint: 6}}},
+			expected: "#pragma version 8\nintcblock 1 6\nerr\n",
+		},
+		{
+			src:      "#pragma version 8\nintcblock 1\nbytecblock 0x61646d696e\ntxna ApplicationArgs 0\nbytec_0\n==\nbnz ok\nerr\nok:\nintc_0\nreturn",
+			bindings: Bindings{Args: map[uint8][]byte{0: []byte("admin")}},
+			expected: "#pragma version 8\nintcblock 1\nbytecblock 0x61646d696e\nintc_0\nreturn\n",
+		},
+		// the block in effect at the intc ops is not known with more than one block
+		{
+			src:      "#pragma version 8\nintcblock 1 2\nintc_0\npop\nintcblock 5 7\nintc_1\nint 7\n==\nreturn",
+			bindings: Bindings{},
+			expected: "#pragma version 8\nintcblock 1 2\nintc_0\npop\nintcblock 5 7\nintc_1\nint 7\n==\nreturn\n",
+		},
+		{
+			src:      "#pragma version 8\nbytecblock 0x01\nbytec_0\npop\nbytecblock 0x02\nbytec_0\nbyte 0x02\n==\nreturn",
+			bindings: Bindings{},
+			expected: "#pragma version 8\nbytecblock 0x01\nbytec_0\npop\nbytecblock 0x02\nbytec_0\nbyte b64 Ag==\n==\nreturn\n",
+		},
+	}
+
+	for _, test := range tests {
+		out, err := Specialize(test.src, test.bindings)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+
+		if out != test.expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", test.expected, out)
+		}
+
+		res := Process(out)
+		for _, d := range res.Diagnostics {
+			if _, ok := d.(parseError); ok {
+				t.Errorf("unexpected error in the specialized program: %s\n%s", d, out)
+			}
+		}
+	}
+}
+
+func TestSpecializeErrors(t *testing.T) {
+	if _, err := Specialize(specializeRouter, Bindings{Txn: map[TxnField]Binding{Accounts: {}}}); err == nil {
+		t.Error("expected an error binding an array field")
+	}
+
+	if _, err := Specialize("#pragma version 8\nint", Bindings{}); err == nil {
+		t.Error("expected a parse error")
+	}
+
+	if _, err := Specialize("#pragma version 8\n#include \"lib.teal\"\nint 1", Bindings{}, WithReadFile(func(string) ([]byte, error) {
+		return []byte("int 1\npop"), nil
+	})); err == nil {
+		t.Error("expected an error for the includes")
+	}
+}
@@ -0,0 +1,574 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const stackCommentPrefix = "stack:"
+
+// StackComment is a `// stack: [a, b: uint64, c]` annotation naming the values on the stack, the last one is the top.
+// The annotation of a line with instructions describes the stack after them, of a comment line the stack at that point.
+// The leading `...` marks the annotation that names only the top values.
+type StackComment struct {
+	Line  int
+	Begin int
+	End   int
+
+	// Index is the listing index of the line
+	Index int
+
+	Names []string
+
+	// Types are the annotated types, StackAny for the values without a type
+	Types StackTypes
+
+	Partial bool
+}
+
+func parseStackComment(s string) (StackComment, bool, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, stackCommentPrefix) {
+		return StackComment{}, false, nil
+	}
+
+	s = strings.TrimSpace(strings.TrimPrefix(s, stackCommentPrefix))
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return StackComment{}, true, errors.New("expected stack values in brackets, e.g. // stack: [a, b]")
+	}
+
+	s = strings.TrimSpace(s[1 : len(s)-1])
+
+	var c StackComment
+
+	if s == "" {
+		return c, true, nil
+	}
+
+	for i, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "..." {
+			if i != 0 {
+				return StackComment{}, true, errors.New("... is only allowed as the first value")
+			}
+			c.Partial = true
+			continue
+		}
+
+		name, typ, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			return StackComment{}, true, errors.Errorf("missing name of stack value %d", len(c.Names))
+		}
+
+		t := StackAny
+		if ok {
+			var err error
+			t, err = parseStackType(strings.TrimSpace(typ))
+			if err != nil {
+				return StackComment{}, true, err
+			}
+		}
+
+		c.Names = append(c.Names, name)
+		c.Types = append(c.Types, t)
+	}
+
+	return c, true, nil
+}
+
+// stackCommentError is an annotation that could not be parsed.
+type stackCommentError struct {
+	line  int
+	begin int
+	end   int
+	err   error
+}
+
+// StackComments returns the stack annotations of the source lines.
+func (r ProcessResult) StackComments() []StackComment {
+	cs, _ := r.stackComments()
+	return cs
+}
+
+func (r ProcessResult) stackComments() ([]StackComment, []stackCommentError) {
+	var cs []StackComment
+	var errs []stackCommentError
+
+	// the last listing index of each line
+	last := map[int]int{}
+	for i, sub := range r.Sublines {
+		if i < len(r.Listing) {
+			last[sub.Line] = i
+		}
+	}
+
+	for _, t := range r.Tokens {
+		if t.Type() != TokenComment {
+			continue
+		}
+
+		c, ok, err := parseStackComment(t.String())
+		if !ok {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, stackCommentError{line: t.Line(), begin: t.Begin(), end: t.End(), err: err})
+			continue
+		}
+
+		i, ok := last[t.Line()]
+		if !ok {
+			continue
+		}
+
+		c.Line = t.Line()
+		c.Begin = t.Begin()
+		c.End = t.End()
+		c.Index = i
+
+		cs = append(cs, c)
+	}
+
+	return cs, errs
+}
+
+// typeState is the types of the top values of the stack, the values below them are unknown.
+type typeState struct {
+	types StackTypes
+	known bool
+}
+
+func (s typeState) join(o typeState) typeState {
+	if !s.known {
+		return o
+	}
+	if !o.known {
+		return s
+	}
+
+	n := len(s.types)
+	if len(o.types) < n {
+		n = len(o.types)
+	}
+
+	res := typeState{known: true, types: make(StackTypes, n)}
+	for k := 0; k < n; k++ {
+		a := s.types[len(s.types)-n+k]
+		b := o.types[len(o.types)-n+k]
+		if a == b {
+			res.types[k] = a
+		} else {
+			res.types[k] = StackAny
+		}
+	}
+
+	return res
+}
+
+func (s typeState) equal(o typeState) bool {
+	if s.known != o.known || len(s.types) != len(o.types) {
+		return false
+	}
+
+	for k := range s.types {
+		if s.types[k] != o.types[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// top returns the type of the value at the depth from the top, StackAny if unknown.
+func (s typeState) top(depth int) StackType {
+	if depth < 0 || depth >= len(s.types) {
+		return StackAny
+	}
+
+	return s.types[len(s.types)-1-depth]
+}
+
+func (s typeState) pop(n int) typeState {
+	if n > len(s.types) {
+		n = len(s.types)
+	}
+
+	s.types = s.types[:len(s.types)-n]
+
+	return s
+}
+
+func (s typeState) push(ts ...StackType) typeState {
+	s.types = append(s.types, ts...)
+	return s
+}
+
+var langSpecReturns = func() map[string]string {
+	m := map[string]string{}
+	for _, op := range BuiltInLangSpec.Ops {
+		m[op.Name] = op.Returns
+	}
+
+	return m
+}()
+
+func returnType(c rune) StackType {
+	switch c {
+	case 'U':
+		return StackUint64
+	case 'B':
+		return StackBytes
+	default:
+		return StackAny
+	}
+}
+
+// stepTypes applies the op to the types of the stack.
+func stepTypes(op Op, s typeState) typeState {
+	s.types = append(StackTypes{}, s.types...)
+
+	if f, ok := txnFieldOf(op); ok {
+		need, _, _ := stackEffect(op)
+		t := StackAny
+		if fs, ok := txnFieldSpecByField(f); ok {
+			t = fs.Type()
+		}
+		return s.pop(need).push(t)
+	}
+
+	switch op := op.(type) {
+	case *IntExpr, *PushIntExpr, *IntcExpr, *Intc0Expr, *Intc1Expr, *Intc2Expr, *Intc3Expr:
+		return s.push(StackUint64)
+	case *ByteExpr, *PushBytesExpr, *BytecExpr, *Bytec0Expr, *Bytec1Expr, *Bytec2Expr, *Bytec3Expr, *AddrExpr, *MethodExpr:
+		return s.push(StackBytes)
+	case *PushIntsExpr:
+		for range op.Ints {
+			s = s.push(StackUint64)
+		}
+		return s
+	case *PushBytessExpr:
+		for range op.Bytess {
+			s = s.push(StackBytes)
+		}
+		return s
+	case *GlobalExpr:
+		t := StackAny
+		if fs, ok := globalFieldSpecByField(op.Field); ok {
+			t = fs.Type()
+		}
+		return s.push(t)
+	case *DupExpr:
+		return s.push(s.top(0))
+	case *Dup2Expr:
+		return s.push(s.top(1), s.top(0))
+	case *DupNExpr:
+		t := s.top(0)
+		for k := 0; k < int(op.Count); k++ {
+			s = s.push(t)
+		}
+		return s
+	case *DigExpr:
+		return s.push(s.top(int(op.Index)))
+	case *SwapExpr:
+		a, b := s.top(1), s.top(0)
+		return s.pop(2).push(b, a)
+	case *SelectExpr:
+		a, b := s.top(2), s.top(1)
+		t := a
+		if a != b {
+			t = StackAny
+		}
+		return s.pop(3).push(t)
+	case *CoverExpr:
+		return s.permute(int(op.Depth), true)
+	case *UncoverExpr:
+		return s.permute(int(op.Depth), false)
+	case *BuryExpr:
+		n := int(op.Depth)
+		t := s.top(0)
+		s = s.pop(1)
+		if k := len(s.types) - n; k >= 0 && k < len(s.types) {
+			s.types[k] = t
+		}
+		return s
+	}
+
+	need, delta, ok := stackEffect(op)
+	if !ok {
+		return typeState{}
+	}
+
+	s = s.pop(need)
+
+	rs := langSpecReturns[opName(op.String())]
+	if len(rs) == need+delta {
+		for _, c := range rs {
+			s = s.push(returnType(c))
+		}
+		return s
+	}
+
+	for k := 0; k < need+delta; k++ {
+		s = s.push(StackAny)
+	}
+
+	return s
+}
+
+// permute moves the top value down to the depth for cover, or the value at the depth up to the top for uncover.
+func (s typeState) permute(depth int, cover bool) typeState {
+	vs := make(StackTypes, depth+1)
+	for k := 0; k <= depth; k++ {
+		vs[depth-k] = s.top(k)
+	}
+
+	s = s.pop(depth + 1)
+
+	if cover {
+		return s.push(vs[depth]).push(vs[:depth]...)
+	}
+
+	return s.push(vs[1:]...).push(vs[0])
+}
+
+// analyzeStackTypes returns the types of the top stack values before each instruction of the listing.
+// The main program and the subroutines are analyzed from their entries, the values of the callers are unknown.
+func analyzeStackTypes(res *ProcessResult) []typeState {
+	n := len(res.Listing)
+	states := make([]typeState, n)
+
+	labels := map[string]int{}
+	for i, op := range res.Listing {
+		if l, ok := op.(*LabelExpr); ok {
+			if _, ok := labels[l.Name]; !ok {
+				labels[l.Name] = i
+			}
+		}
+	}
+
+	if n == 0 {
+		return states
+	}
+
+	entries := []int{0}
+	for _, op := range res.Listing {
+		if c, ok := op.(*CallSubExpr); ok {
+			if j, ok := labels[c.Label.Name]; ok {
+				entries = append(entries, j)
+			}
+		}
+	}
+
+	updates := make([]int, n)
+
+	var todo []int
+
+	flow := func(i int, s typeState) {
+		if i >= n {
+			return
+		}
+
+		next := states[i].join(s)
+		if updates[i] > 0 && next.equal(states[i]) {
+			return
+		}
+
+		updates[i]++
+		if updates[i] > stackDepthWiden {
+			// the types of the values of the growing loops are forgotten
+			next.types = nil
+		}
+
+		states[i] = next
+		todo = append(todo, i)
+	}
+
+	for _, e := range entries {
+		flow(e, typeState{known: true})
+	}
+
+	for len(todo) > 0 {
+		i := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		op := res.Listing[i]
+		s := states[i]
+
+		if c, ok := op.(*CallSubExpr); ok {
+			// the call keeps the values below its args, the callee effect is known from its proto
+			after := typeState{}
+			if j, ok := labels[c.Label.Name]; ok {
+				for ; j < n; j++ {
+					if _, ok := res.Listing[j].(Nop); ok {
+						continue
+					}
+					if p, ok := res.Listing[j].(*ProtoExpr); ok {
+						after = s.pop(int(p.Args))
+						after.types = append(StackTypes{}, after.types...)
+						for k := 0; k < int(p.Results); k++ {
+							after = after.push(StackAny)
+						}
+					}
+					break
+				}
+			}
+			if !after.known {
+				after = typeState{known: true}
+			}
+			flow(i+1, after)
+			continue
+		}
+
+		after := stepTypes(op, s)
+		if !after.known {
+			after = typeState{known: true}
+		}
+
+		switch op := op.(type) {
+		case *BExpr:
+			if j, ok := labels[op.Label.Name]; ok {
+				flow(j, after)
+			}
+		case *BzExpr:
+			if j, ok := labels[op.Label.Name]; ok {
+				flow(j, after)
+			}
+			flow(i+1, after)
+		case *BnzExpr:
+			if j, ok := labels[op.Label.Name]; ok {
+				flow(j, after)
+			}
+			flow(i+1, after)
+		case *SwitchExpr:
+			for _, t := range op.Targets {
+				if j, ok := labels[t.Name]; ok {
+					flow(j, after)
+				}
+			}
+			flow(i+1, after)
+		case *MatchExpr:
+			for _, t := range op.Targets {
+				if j, ok := labels[t.Name]; ok {
+					flow(j, after)
+				}
+			}
+			flow(i+1, after)
+		case *RetSubExpr, *ReturnExpr, *ErrExpr:
+		default:
+			flow(i+1, after)
+		}
+	}
+
+	return states
+}
+
+func typeName(t StackType) string {
+	switch t {
+	case StackUint64:
+		return "uint64"
+	case StackBytes:
+		return "bytes"
+	default:
+		return "any"
+	}
+}
+
+type StackCommentRule struct{}
+
+func (r StackCommentRule) Id() string {
+	return "LINT0020"
+}
+
+func (r StackCommentRule) Desc() string {
+	return "Checks that the // stack: comments match the computed stack depth and types"
+}
+
+var StackCommentRuleInstance = StackCommentRule{}
+
+// checkStackComments reports the stack annotations that do not match the stack depths and the types
+// computed for all of the paths.
+func checkStackComments(res *ProcessResult) []Diagnostic {
+	cs, errs := res.stackComments()
+	if len(cs) == 0 && len(errs) == 0 {
+		return nil
+	}
+
+	var diags []Diagnostic
+
+	report := func(line, begin, end int, err error) {
+		diags = append(diags, lintError{
+			error: err,
+			l:     line,
+			b:     begin,
+			e:     end,
+			s:     DiagWarn,
+			r:     StackCommentRuleInstance.Id(),
+		})
+	}
+
+	for _, e := range errs {
+		report(e.line, e.begin, e.end, errors.Wrap(e.err, "invalid stack comment"))
+	}
+
+	types := analyzeStackTypes(res)
+
+	for _, c := range cs {
+		i := c.Index
+		if i >= len(res.StackDepths) || i >= len(types) {
+			continue
+		}
+
+		d := res.StackDepths[i]
+		ts := types[i]
+
+		// the annotation of a line with an instruction describes the stack after it
+		if _, ok := res.Listing[i].(Nop); !ok {
+			_, delta, ok := stackEffect(res.Listing[i])
+			if !ok {
+				continue
+			}
+
+			d = d.add(delta)
+			ts = stepTypes(res.Listing[i], ts)
+		}
+
+		if !d.Known {
+			continue
+		}
+
+		n := len(c.Names)
+
+		switch {
+		case c.Partial && d.Min < n:
+			report(c.Line, c.Begin, c.End, errors.Errorf("stack comment names %d values, stack depth is %s", n, d))
+			continue
+		case !c.Partial && d.Min != d.Max:
+			report(c.Line, c.Begin, c.End, errors.Errorf("stack comment names %d values, stack depth varies: %s - use ... for the values below", n, d))
+			continue
+		case !c.Partial && d.Min != n:
+			report(c.Line, c.Begin, c.End, errors.Errorf("stack comment names %d values, stack depth is %s", n, d))
+			continue
+		}
+
+		if !ts.known {
+			continue
+		}
+
+		for k, t := range c.Types {
+			actual := ts.top(n - 1 - k)
+			if t == StackAny || actual == StackAny || actual == t {
+				continue
+			}
+
+			report(c.Line, c.Begin, c.End, fmt.Errorf("stack value '%s' is %s, annotated %s", c.Names[k], typeName(actual), typeName(t)))
+		}
+	}
+
+	return diags
+}
@@ -0,0 +1,145 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStackComment(t *testing.T) {
+	tests := []struct {
+		s       string
+		names   []string
+		types   StackTypes
+		partial bool
+		err     bool
+	}{
+		{s: " stack: []"},
+		{s: "stack: [a, b]", names: []string{"a", "b"}, types: StackTypes{StackAny, StackAny}},
+		{s: "stack: [..., n: uint64, key: bytes]", names: []string{"n", "key"}, types: StackTypes{StackUint64, StackBytes}, partial: true},
+		{s: "stack: a, b", err: true},
+		{s: "stack: [a, ...]", err: true},
+		{s: "stack: [a: str]", err: true},
+		{s: "stack: [, a]", err: true},
+	}
+
+	for _, test := range tests {
+		c, ok, err := parseStackComment(test.s)
+		if !ok {
+			t.Errorf("%q: not recognized", test.s)
+			continue
+		}
+
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected error", test.s)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.s, err)
+			continue
+		}
+
+		if strings.Join(c.Names, ",") != strings.Join(test.names, ",") || c.Partial != test.partial || len(c.Types) != len(test.types) {
+			t.Errorf("%q: unexpected comment: %+v", test.s, c)
+			continue
+		}
+
+		for i := range c.Types {
+			if c.Types[i] != test.types[i] {
+				t.Errorf("%q: unexpected type %d: %d", test.s, i, c.Types[i])
+			}
+		}
+	}
+
+	if _, ok, _ := parseStackComment(" stacks are fun"); ok {
+		t.Error("plain comment recognized as stack comment")
+	}
+}
+
+func TestStackComments(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+byte "a" // stack: [n, key]
+// stack: [n, key: bytes]
+swap // stack: [key, n: bytes]
+pop
+txn Sender // stack: [key, sender]
+// stack: [a]
+concat
+global Round // stack: [..., a, round, b]
+pop
+// stack: [x
+pop
+int 1
+return`)
+
+	cs := res.StackComments()
+	if len(cs) != 6 {
+		t.Fatalf("unexpected stack comments: %+v", cs)
+	}
+
+	if cs[0].Line != 2 || cs[1].Line != 3 || cs[0].Names[1] != "key" {
+		t.Errorf("unexpected stack comments: %+v", cs)
+	}
+
+	var msgs []string
+	for _, d := range res.Diagnostics {
+		if d.Rule() == StackCommentRuleInstance.Id() {
+			msgs = append(msgs, d.String())
+		}
+	}
+
+	expected := []string{
+		"invalid stack comment: expected stack values in brackets, e.g. // stack: [a, b]",
+		"stack value 'n' is uint64, annotated bytes",
+		"stack comment names 1 values, stack depth is 2",
+		"stack comment names 3 values, stack depth is 2",
+	}
+
+	if strings.Join(msgs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected diagnostics:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestStackCommentsBranches(t *testing.T) {
+	res := Process(`#pragma version 8
+txn NumAppArgs
+bz skip
+int 1
+skip:
+// stack: [n]
+int 1
+return`)
+
+	for _, d := range res.Diagnostics {
+		if d.Rule() == StackCommentRuleInstance.Id() {
+			if !strings.Contains(d.String(), "stack depth varies") {
+				t.Errorf("unexpected diagnostic: %s", d)
+			}
+			return
+		}
+	}
+
+	t.Error("missing varying depth diagnostic")
+}
+
+func TestStackCommentsSubroutine(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+byte "a"
+callsub f // stack: [n: uint64, r]
+pop
+return
+f:
+proto 1 1
+frame_dig -1 // stack: [..., key]
+retsub`)
+
+	for _, d := range res.Diagnostics {
+		if d.Rule() == StackCommentRuleInstance.Id() {
+			t.Errorf("unexpected diagnostic: %s", d)
+		}
+	}
+}
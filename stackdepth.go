@@ -0,0 +1,519 @@
+package teal
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// MaxStackDepth is the maximum number of the values on the AVM stack.
+const MaxStackDepth = 1000
+
+// StackDepthUnbounded is the Max of the depth that keeps growing in a loop and -StackDepthUnbounded the Min of the shrinking one.
+const StackDepthUnbounded = 1 << 30
+
+// stackDepthWiden is the number of the updates of the instruction depth after which the growing bound is widened.
+const stackDepthWiden = 8
+
+// StackDepth is the range of the stack depths before the instruction on all of the paths reaching it.
+// Known is false for the unreachable instructions and after the calls that could not be analyzed, e.g. recursive.
+type StackDepth struct {
+	Min   int
+	Max   int
+	Known bool
+}
+
+func (d StackDepth) join(o StackDepth) StackDepth {
+	if !d.Known {
+		return o
+	}
+	if !o.Known {
+		return d
+	}
+
+	if o.Min < d.Min {
+		d.Min = o.Min
+	}
+	if o.Max > d.Max {
+		d.Max = o.Max
+	}
+
+	return d
+}
+
+func (d StackDepth) add(n int) StackDepth {
+	if d.Min > -StackDepthUnbounded && d.Min < StackDepthUnbounded {
+		d.Min += n
+	}
+	if d.Max > -StackDepthUnbounded && d.Max < StackDepthUnbounded {
+		d.Max += n
+	}
+
+	return d
+}
+
+func (d StackDepth) String() string {
+	if !d.Known {
+		return "?"
+	}
+
+	bound := func(v int) string {
+		switch {
+		case v >= StackDepthUnbounded:
+			return "∞"
+		case v <= -StackDepthUnbounded:
+			return "-∞"
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+
+	if d.Min == d.Max {
+		return bound(d.Min)
+	}
+
+	return fmt.Sprintf("%s..%s", bound(d.Min), bound(d.Max))
+}
+
+// stackEffectOps are the ops missing in the built-in language spec: the pseudo ops and the later versions ops.
+var stackEffectOps = map[string][2]int{
+	"int":               {0, 1},
+	"byte":              {0, 1},
+	"addr":              {0, 1},
+	"method":            {0, 1},
+	"ec_add":            {2, 1},
+	"ec_scalar_mul":     {2, 1},
+	"ec_pairing_check":  {2, 1},
+	"ec_multi_exp":      {2, 1},
+	"ec_subgroup_check": {1, 1},
+	"ec_map_to":         {1, 1},
+}
+
+var langSpecStackEffects = func() map[string][2]int {
+	m := map[string][2]int{}
+	for _, op := range BuiltInLangSpec.Ops {
+		m[op.Name] = [2]int{len(op.Args), len(op.Returns)}
+	}
+
+	return m
+}()
+
+// stackEffect returns the number of the values the op needs on the stack and the change of the stack depth.
+func stackEffect(op Op) (int, int, bool) {
+	switch op := op.(type) {
+	case Nop:
+		return 0, 0, true
+	case *DigExpr:
+		return int(op.Index) + 1, 1, true
+	case *CoverExpr:
+		return int(op.Depth) + 1, 0, true
+	case *UncoverExpr:
+		return int(op.Depth) + 1, 0, true
+	case *BuryExpr:
+		return int(op.Depth) + 1, -1, true
+	case *PopNExpr:
+		return int(op.Depth), -int(op.Depth), true
+	case *DupNExpr:
+		return 1, int(op.Count), true
+	case *PushIntsExpr:
+		return 0, len(op.Ints), true
+	case *PushBytessExpr:
+		return 0, len(op.Bytess), true
+	case *MatchExpr:
+		return len(op.Targets) + 1, -(len(op.Targets) + 1), true
+	}
+
+	name := opName(op.String())
+
+	e, ok := stackEffectOps[name]
+	if !ok {
+		e, ok = langSpecStackEffects[name]
+	}
+	if !ok {
+		return 0, 0, false
+	}
+
+	return e[0], e[1] - e[0], true
+}
+
+// stackRegion is the relative stack depths of the main program or of a subroutine, 0 at its entry.
+type stackRegion struct {
+	entry int
+	rel   []StackDepth
+
+	// lowest is the lowest relative depth required by the instructions, the callers need at least -lowest values
+	lowest int
+
+	// ret is the relative depth at the retsub instructions
+	ret StackDepth
+
+	// calls are the listing indexes of the callsub instructions
+	calls []int
+
+	// unknown is set if the depths could not be computed for all of the paths
+	unknown bool
+}
+
+type stackDepthAnalysis struct {
+	res    *ProcessResult
+	labels map[string]int
+
+	regions map[int]*stackRegion
+	active  map[int]bool
+
+	// invalid are the listing indexes on the lines that failed to parse, their effect is unknown
+	invalid map[int]bool
+}
+
+func (a *stackDepthAnalysis) target(l *LabelExpr) (int, bool) {
+	i, ok := a.labels[l.Name]
+	return i, ok
+}
+
+// region analyzes the region starting at the listing index, the called subroutines are analyzed first.
+func (a *stackDepthAnalysis) region(entry int) *stackRegion {
+	if r, ok := a.regions[entry]; ok {
+		return r
+	}
+
+	n := len(a.res.Listing)
+
+	r := &stackRegion{
+		entry: entry,
+		rel:   make([]StackDepth, n),
+		ret:   StackDepth{},
+	}
+
+	a.active[entry] = true
+
+	// retsub of the subroutine with proto removes the frame leaving the results in place of the args
+	var proto *ProtoExpr
+	for i := entry; i < n; i++ {
+		if _, ok := a.res.Listing[i].(Nop); ok {
+			continue
+		}
+		proto, _ = a.res.Listing[i].(*ProtoExpr)
+		break
+	}
+
+	if proto != nil {
+		r.lowest = -int(proto.Args)
+	}
+
+	updates := make([]int, n)
+	called := map[int]bool{}
+
+	todo := []int{entry}
+	r.rel[entry] = StackDepth{Known: true}
+
+	flow := func(i int, d StackDepth) {
+		if i >= n {
+			return
+		}
+
+		prev := r.rel[i]
+		next := prev.join(d)
+		if next == prev {
+			return
+		}
+
+		updates[i]++
+		if updates[i] > stackDepthWiden {
+			if next.Max > prev.Max {
+				next.Max = StackDepthUnbounded
+			}
+			if next.Min < prev.Min {
+				next.Min = -StackDepthUnbounded
+			}
+		}
+
+		r.rel[i] = next
+		todo = append(todo, i)
+	}
+
+	for len(todo) > 0 {
+		i := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		d := r.rel[i]
+		op := a.res.Listing[i]
+
+		if a.invalid[i] {
+			r.unknown = true
+			continue
+		}
+
+		if call, ok := op.(*CallSubExpr); ok {
+			if !called[i] {
+				called[i] = true
+				r.calls = append(r.calls, i)
+			}
+
+			j, ok := a.target(call.Label)
+			if !ok || a.active[j] {
+				r.unknown = true
+				continue
+			}
+
+			sub := a.region(j)
+			if sub.unknown || !sub.ret.Known {
+				r.unknown = sub.unknown || r.unknown
+				continue
+			}
+
+			if low := d.Min + sub.lowest; low < r.lowest {
+				r.lowest = low
+			}
+
+			after := d
+			after.Min += sub.ret.Min
+			after.Max += sub.ret.Max
+			if d.Max >= StackDepthUnbounded || sub.ret.Max >= StackDepthUnbounded {
+				after.Max = StackDepthUnbounded
+			}
+			if d.Min <= -StackDepthUnbounded || sub.ret.Min <= -StackDepthUnbounded {
+				after.Min = -StackDepthUnbounded
+			}
+
+			flow(i+1, after)
+			continue
+		}
+
+		need, delta, ok := stackEffect(op)
+		if !ok {
+			r.unknown = true
+			continue
+		}
+
+		if low := d.Min - need; low < r.lowest {
+			r.lowest = low
+		}
+
+		after := d.add(delta)
+
+		switch op := op.(type) {
+		case *BExpr:
+			if j, ok := a.target(op.Label); ok {
+				flow(j, after)
+			}
+		case *BzExpr:
+			if j, ok := a.target(op.Label); ok {
+				flow(j, after)
+			}
+			flow(i+1, after)
+		case *BnzExpr:
+			if j, ok := a.target(op.Label); ok {
+				flow(j, after)
+			}
+			flow(i+1, after)
+		case *SwitchExpr:
+			for _, t := range op.Targets {
+				if j, ok := a.target(t); ok {
+					flow(j, after)
+				}
+			}
+			flow(i+1, after)
+		case *MatchExpr:
+			for _, t := range op.Targets {
+				if j, ok := a.target(t); ok {
+					flow(j, after)
+				}
+			}
+			flow(i+1, after)
+		case *RetSubExpr:
+			if proto != nil {
+				n := int(proto.Results) - int(proto.Args)
+				r.ret = r.ret.join(StackDepth{Min: n, Max: n, Known: true})
+			} else {
+				r.ret = r.ret.join(d)
+			}
+		case *ReturnExpr, *ErrExpr:
+		default:
+			flow(i+1, after)
+		}
+	}
+
+	delete(a.active, entry)
+	a.regions[entry] = r
+
+	return r
+}
+
+// analyzeStackDepths returns the stack depth before each instruction of the listing.
+// The subroutines are analyzed relative to their entry and placed at the depths of their call sites.
+func analyzeStackDepths(res *ProcessResult) []StackDepth {
+	a := &stackDepthAnalysis{
+		res:     res,
+		labels:  map[string]int{},
+		regions: map[int]*stackRegion{},
+		active:  map[int]bool{},
+		invalid: map[int]bool{},
+	}
+
+	errs := map[int]bool{}
+	for _, d := range res.Diagnostics {
+		if _, ok := d.(parseError); ok {
+			errs[d.Line()] = true
+		}
+	}
+
+	for i, sub := range res.Sublines {
+		if errs[sub.Line] {
+			a.invalid[i] = true
+		}
+	}
+
+	for i, op := range res.Listing {
+		if l, ok := op.(*LabelExpr); ok {
+			if _, ok := a.labels[l.Name]; !ok {
+				a.labels[l.Name] = i
+			}
+		}
+	}
+
+	ds := make([]StackDepth, len(res.Listing))
+	if len(res.Listing) == 0 {
+		return ds
+	}
+
+	main := a.region(0)
+
+	// the absolute depths at the region entries, propagated from the callers
+	entries := map[int]StackDepth{0: {Known: true}}
+	done := map[int]bool{}
+
+	var place func(r *stackRegion)
+	place = func(r *stackRegion) {
+		if done[r.entry] {
+			return
+		}
+		done[r.entry] = true
+
+		base := entries[r.entry]
+		if !base.Known {
+			return
+		}
+
+		for i, rel := range r.rel {
+			if !rel.Known {
+				continue
+			}
+
+			abs := StackDepth{
+				Min:   base.Min + rel.Min,
+				Max:   base.Max + rel.Max,
+				Known: true,
+			}
+			if rel.Max >= StackDepthUnbounded || base.Max >= StackDepthUnbounded {
+				abs.Max = StackDepthUnbounded
+			}
+			if rel.Min <= -StackDepthUnbounded || base.Min <= -StackDepthUnbounded {
+				abs.Min = -StackDepthUnbounded
+			}
+
+			ds[i] = ds[i].join(abs)
+		}
+	}
+
+	// the regions are placed after all of their callers so the entry depth joins all of the call sites
+	var order []*stackRegion
+	seen := map[int]bool{}
+
+	var visit func(r *stackRegion)
+	visit = func(r *stackRegion) {
+		if seen[r.entry] {
+			return
+		}
+		seen[r.entry] = true
+
+		for _, c := range r.calls {
+			if j, ok := a.target(res.Listing[c].(*CallSubExpr).Label); ok {
+				if sub, ok := a.regions[j]; ok {
+					visit(sub)
+				}
+			}
+		}
+
+		order = append(order, r)
+	}
+
+	visit(main)
+
+	for k := len(order) - 1; k >= 0; k-- {
+		r := order[k]
+		place(r)
+
+		for _, c := range r.calls {
+			j, ok := a.target(res.Listing[c].(*CallSubExpr).Label)
+			if !ok || !ds[c].Known {
+				continue
+			}
+
+			entries[j] = entries[j].join(ds[c])
+		}
+	}
+
+	return ds
+}
+
+// StackDepthRule checks the stack depth bounds computed for all of the paths.
+type StackDepthRule struct{}
+
+func (r StackDepthRule) Id() string {
+	return "LINT0016"
+}
+
+func (r StackDepthRule) Desc() string {
+	return "Checks that the stack depth stays within the limit and does not go negative on any path"
+}
+
+var StackDepthRuleInstance = StackDepthRule{}
+
+// checkStackDepths reports the instructions where the stack depth first crosses the limits on some path.
+func checkStackDepths(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	// the unbounded depths are reported once as they spread over the rest of the program
+	under := false
+	over := false
+
+	for i, d := range res.StackDepths {
+		if !d.Known || i >= len(res.Sublines) {
+			continue
+		}
+
+		need, delta, ok := stackEffect(res.Listing[i])
+		if !ok {
+			continue
+		}
+
+		var err error
+
+		switch {
+		case d.Min-need < 0 && d.Min >= 0:
+			err = errors.Errorf("stack can underflow: depth %s, needs %d", d, need)
+		case d.Min <= -StackDepthUnbounded && need > 0 && !under:
+			under = true
+			err = errors.Errorf("stack can underflow: depth %s, needs %d", d, need)
+		case d.Max+delta > MaxStackDepth && d.Max <= MaxStackDepth:
+			err = errors.Errorf("stack depth can exceed %d: depth %s", MaxStackDepth, d.add(delta))
+		case d.Max >= StackDepthUnbounded && delta > 0 && !over:
+			over = true
+			err = errors.Errorf("stack depth can exceed %d: depth %s", MaxStackDepth, d.add(delta))
+		default:
+			continue
+		}
+
+		sub := res.Sublines[i]
+		diags = append(diags, lintError{
+			error: err,
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagWarn,
+			r:     StackDepthRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,127 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackDepths(t *testing.T) {
+	type test struct {
+		name   string
+		src    string
+		line   int
+		depth  string
+		warn   string
+		noWarn bool
+	}
+
+	tests := []test{
+		{
+			name:   "simple",
+			src:    "#pragma version 8\nint 1\nint 2\n+\nreturn",
+			line:   3,
+			depth:  "2",
+			noWarn: true,
+		},
+		{
+			name:  "underflow",
+			src:   "#pragma version 8\npop\nint 1\nreturn",
+			line:  1,
+			depth: "0",
+			warn:  "stack can underflow",
+		},
+		{
+			name:   "branch join",
+			src:    "#pragma version 8\nint 1\nbz skip\nint 2\nskip:\nint 1\nreturn",
+			line:   4,
+			depth:  "0..1",
+			noWarn: true,
+		},
+		{
+			name:  "growing loop",
+			src:   "#pragma version 8\nint 1\nloop:\ndup\nb loop",
+			line:  3,
+			depth: "1..∞",
+			warn:  "stack depth can exceed 1000",
+		},
+		{
+			name:   "callsub proto",
+			src:    "#pragma version 8\nint 1\nint 2\ncallsub add\nreturn\nadd:\nproto 2 1\nframe_dig -1\nframe_dig -2\n+\nretsub",
+			line:   10,
+			depth:  "3",
+			noWarn: true,
+		},
+		{
+			name:   "callsub",
+			src:    "#pragma version 8\nint 1\ncallsub double\nreturn\ndouble:\ndup\n+\nretsub",
+			line:   6,
+			depth:  "2",
+			noWarn: true,
+		},
+		{
+			name:  "callsub underflow",
+			src:   "#pragma version 8\ncallsub sub\nint 1\nreturn\nsub:\npop\nretsub",
+			line:  5,
+			depth: "0",
+			warn:  "stack can underflow",
+		},
+		{
+			name:   "parse error",
+			src:    "#pragma version 8\nint 1\naddr X\n==\nreturn",
+			line:   1,
+			depth:  "0",
+			noWarn: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := Process(test.src)
+
+			found := false
+			for i, sub := range res.Sublines {
+				if sub.Line != test.line || len(sub.Tokens) == 0 {
+					continue
+				}
+
+				found = true
+				if s := res.StackDepths[i].String(); s != test.depth {
+					t.Errorf("expected depth: %s, got: %s", test.depth, s)
+				}
+			}
+
+			if !found {
+				t.Fatalf("no instruction at line: %d", test.line)
+			}
+
+			var warns []string
+			for _, d := range res.Diagnostics {
+				if d.Rule() == StackDepthRuleInstance.Id() {
+					warns = append(warns, d.String())
+				}
+			}
+
+			if test.noWarn && len(warns) > 0 {
+				t.Errorf("unexpected warnings: %v", warns)
+			}
+
+			if test.warn != "" && (len(warns) == 0 || !strings.Contains(warns[0], test.warn)) {
+				t.Errorf("expected warning: %s, got: %v", test.warn, warns)
+			}
+		})
+	}
+}
+
+func TestStackDepthInlayHints(t *testing.T) {
+	res := Process("#pragma version 8\nint 1\nint 2\n+\nreturn")
+
+	hs := res.InlayHints(LinesRange{Start: 0, End: 4})
+	if len(hs.Depths) != 4 {
+		t.Fatalf("expected 4 depth hints, got: %d", len(hs.Depths))
+	}
+
+	h := hs.Depths[2]
+	if h.Line != 3 || h.Character != 1 || h.Label != "depth 2" {
+		t.Errorf("unexpected hint: %+v", h)
+	}
+}
@@ -0,0 +1,263 @@
+package teal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const stateMaxBranches = 1000
+
+// DynamicKey is the key name used for accesses with keys that are not statically known.
+const DynamicKey = "(dynamic)"
+
+// StateKey is a global, local state or box key accessed by the program.
+type StateKey struct {
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+
+	// written value type: uint64, bytes, any if unknown or mixed, empty if never written
+	Type string `json:"type,omitempty"`
+
+	Read    bool `json:"read"`
+	Write   bool `json:"write"`
+	Delete  bool `json:"delete,omitempty"`
+	Dynamic bool `json:"dynamic,omitempty"`
+
+	// source lines of the accesses
+	Lines []int `json:"lines"`
+}
+
+// Schema is the application state schema as declared on creation.
+type Schema struct {
+	GlobalNumUint      uint64 `json:"globalNumUint"`
+	GlobalNumByteSlice uint64 `json:"globalNumByteSlice"`
+	LocalNumUint       uint64 `json:"localNumUint"`
+	LocalNumByteSlice  uint64 `json:"localNumByteSlice"`
+}
+
+// StateSchema is the inferred set of the state keys.
+type StateSchema struct {
+	Keys  []StateKey `json:"keys"`
+	Boxes []StateKey `json:"boxes,omitempty"`
+
+	// not all of the paths could be explored
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// FormatKey formats the printable bytes as a string and the other ones as hex.
+func FormatKey(bs []byte) string {
+	for _, r := range string(bs) {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return "0x" + hex.EncodeToString(bs)
+		}
+	}
+
+	return string(bs)
+}
+
+// keyName formats the key, keys built by concatenation are formatted with the unknown parts in braces.
+func keyName(v VmValue) (string, bool) {
+	parts := v.Parts()
+	if len(parts) == 1 && parts[0].src == nil {
+		return DynamicKey, false
+	}
+
+	var sb strings.Builder
+	static := true
+
+	for _, p := range parts {
+		bs, ok := p.Bytes()
+		if ok {
+			sb.WriteString(FormatKey(bs))
+			continue
+		}
+
+		static = false
+
+		if p.src != nil {
+			sb.WriteString("{" + p.src.String() + "}")
+		} else {
+			sb.WriteString("{?}")
+		}
+	}
+
+	return sb.String(), static
+}
+
+func mergeType(t string, v VmValue) string {
+	var vt string
+	switch v.T {
+	case VmTypeUint64:
+		vt = "uint64"
+	case VmTypeBytes:
+		vt = "bytes"
+	default:
+		vt = "any"
+	}
+
+	if t == "" || t == vt {
+		return vt
+	}
+
+	return "any"
+}
+
+// inferKeys groups the accesses of the scopes by their keys.
+func inferKeys(res *ProcessResult, accesses []VmAccess, scopes ...VmAccessScope) []StateKey {
+	var keys []StateKey
+	index := map[string]int{}
+
+	for _, a := range accesses {
+		found := false
+		for _, s := range scopes {
+			if a.Scope == s {
+				found = true
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		name, static := keyName(a.Key)
+
+		id := a.Scope.String() + ":" + name
+
+		i, ok := index[id]
+		if !ok {
+			i = len(keys)
+			index[id] = i
+			keys = append(keys, StateKey{
+				Scope:   a.Scope.String(),
+				Key:     name,
+				Dynamic: !static,
+			})
+		}
+
+		k := &keys[i]
+
+		switch a.Kind {
+		case VmAccessRead:
+			k.Read = true
+		case VmAccessWrite:
+			k.Write = true
+			k.Type = mergeType(k.Type, a.Value)
+		case VmAccessDelete:
+			k.Delete = true
+		}
+
+		line := res.SourceLine(a.Line)
+
+		dup := false
+		for _, l := range k.Lines {
+			if l == line {
+				dup = true
+			}
+		}
+
+		if !dup {
+			k.Lines = append(k.Lines, line)
+		}
+	}
+
+	for i := range keys {
+		sort.Ints(keys[i].Lines)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].Scope != keys[j].Scope {
+			return keys[i].Scope < keys[j].Scope
+		}
+
+		return keys[i].Key < keys[j].Key
+	})
+
+	return keys
+}
+
+// AnalyzeState infers the global, local state and box keys the program reads and writes
+// from the constant keys passed to the app_global_*, app_local_* and box_* ops.
+func AnalyzeState(res *ProcessResult) StateSchema {
+	vm := NewVm(res)
+	ok := vm.RunAll(stateMaxBranches)
+
+	return StateSchema{
+		Keys:       inferKeys(res, vm.Accesses, VmAccessGlobal, VmAccessLocal),
+		Boxes:      inferKeys(res, vm.Accesses, VmAccessBox),
+		Incomplete: !ok,
+	}
+}
+
+// Check reports the writes that could exceed the declared schema.
+func (s StateSchema) Check(declared Schema) []string {
+	var issues []string
+
+	check := func(scope string, uints uint64, bytes uint64) {
+		var nu, nb, na uint64
+		var dynamic []string
+
+		for _, k := range s.Keys {
+			if k.Scope != scope || !k.Write {
+				continue
+			}
+
+			if k.Dynamic {
+				for _, l := range k.Lines {
+					dynamic = append(dynamic, fmt.Sprint(l+1))
+				}
+				continue
+			}
+
+			switch k.Type {
+			case "uint64":
+				nu++
+			case "bytes":
+				nb++
+			default:
+				na++
+			}
+		}
+
+		if nu+na > uints {
+			issues = append(issues, fmt.Sprintf("%s uint64 keys written: up to %d, declared: %d", scope, nu+na, uints))
+		}
+
+		if nb+na > bytes {
+			issues = append(issues, fmt.Sprintf("%s byte slice keys written: up to %d, declared: %d", scope, nb+na, bytes))
+		}
+
+		if len(dynamic) > 0 {
+			issues = append(issues, fmt.Sprintf("%s keys written dynamically at lines: %s", scope, strings.Join(dynamic, ", ")))
+		}
+	}
+
+	check(VmAccessGlobal.String(), declared.GlobalNumUint, declared.GlobalNumByteSlice)
+	check(VmAccessLocal.String(), declared.LocalNumUint, declared.LocalNumByteSlice)
+
+	return issues
+}
+
+// GlobalKeyAt returns the constant key read with app_global_get at the source line.
+func GlobalKeyAt(res *ProcessResult, line int) ([]byte, bool) {
+	vm := NewVm(res)
+	vm.RunAll(stateMaxBranches)
+
+	for _, a := range vm.Accesses {
+		if a.Scope != VmAccessGlobal || a.Kind != VmAccessRead || res.SourceLine(a.Line) != line {
+			continue
+		}
+
+		if _, ok := res.Listing[a.Line].(*AppGlobalGetExpr); !ok {
+			continue
+		}
+
+		if bs, ok := a.Key.Bytes(); ok {
+			return bs, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,64 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	res := Process(`#pragma version 8
+byte "counter"
+byte "counter"
+app_global_get
+int 1
++
+app_global_put
+byte "owner"
+txn Sender
+app_global_put
+txn Sender
+byte "balance"
+int 0
+app_local_put
+txna ApplicationArgs 0
+int 1
+app_global_put
+int 1
+return`)
+
+	s := AnalyzeState(res)
+	if s.Incomplete {
+		t.Fatal("unexpected incomplete analysis")
+	}
+
+	if len(s.Keys) != 4 {
+		t.Fatalf("unexpected keys: %+v", s.Keys)
+	}
+
+	tests := []StateKey{
+		{Scope: "global", Key: "counter", Type: "uint64", Read: true, Write: true},
+		{Scope: "global", Key: "owner", Type: "bytes", Write: true},
+		{Scope: "global", Key: "{txna ApplicationArgs 0}", Type: "uint64", Write: true, Dynamic: true},
+		{Scope: "local", Key: "balance", Type: "uint64", Write: true},
+	}
+
+	for i, test := range tests {
+		k := s.Keys[i]
+		if k.Scope != test.Scope || k.Key != test.Key || k.Type != test.Type || k.Read != test.Read || k.Write != test.Write || k.Dynamic != test.Dynamic {
+			t.Errorf("unexpected key %d - expected: %+v, got: %+v", i, test, k)
+		}
+	}
+
+	if len(s.Keys[0].Lines) != 2 || s.Keys[0].Lines[0] != 3 || s.Keys[0].Lines[1] != 6 {
+		t.Errorf("unexpected counter lines: %v", s.Keys[0].Lines)
+	}
+
+	issues := s.Check(Schema{GlobalNumUint: 1, GlobalNumByteSlice: 1, LocalNumUint: 1})
+	if len(issues) != 1 || issues[0] != "global keys written dynamically at lines: 17" {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+
+	issues = s.Check(Schema{GlobalNumByteSlice: 1, LocalNumUint: 1})
+	if len(issues) != 2 || issues[0] != "global uint64 keys written: up to 1, declared: 0" {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
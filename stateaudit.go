@@ -0,0 +1,211 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// StateFindingDead is a key written but never read by the approval and the clear state programs
+	StateFindingDead = "dead"
+
+	// StateFindingCleanup is a local key the clear state program does not account for
+	StateFindingCleanup = "cleanup"
+)
+
+// StateFinding is an issue of a state key found by the state audit.
+type StateFinding struct {
+	Kind  string `json:"kind"`
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+
+	// Program is the "approval" or the "clear" program of the source lines
+	Program string `json:"program"`
+	Lines   []int  `json:"lines"`
+
+	Message string `json:"message"`
+}
+
+func (f StateFinding) String() string {
+	var lines []string
+	for _, l := range f.Lines {
+		lines = append(lines, fmt.Sprint(l+1))
+	}
+
+	return fmt.Sprintf("%s %s key %s (%s lines %s): %s", f.Kind, f.Scope, f.Key, f.Program, strings.Join(lines, ", "), f.Message)
+}
+
+// StateAudit is the state of an application audited across its approval and clear state programs.
+type StateAudit struct {
+	Approval StateSchema `json:"approval"`
+	Clear    StateSchema `json:"clear"`
+
+	Findings []StateFinding `json:"findings,omitempty"`
+
+	// not all of the paths could be explored
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// stateUse is the use of a key by both of the programs.
+type stateUse struct {
+	read  bool
+	write bool
+
+	program string
+	lines   []int
+}
+
+// closeOutAccounting returns the local keys read on the CloseOut calls of the approval program if the calls also write the global state,
+// i.e. the values of the leaving account are subtracted from the global totals.
+func closeOutAccounting(res *ProcessResult) []string {
+	vm := NewVm(res)
+	vm.Txn = map[TxnField]uint64{
+		ApplicationID: 1,
+		OnCompletion:  uint64(CloseOut),
+	}
+	vm.RunAll(stateMaxBranches)
+
+	global := false
+	for _, a := range vm.Accesses {
+		if a.Scope == VmAccessGlobal && a.Kind == VmAccessWrite {
+			global = true
+		}
+	}
+
+	if !global {
+		return nil
+	}
+
+	var keys []string
+	for _, k := range inferKeys(res, vm.Accesses, VmAccessLocal) {
+		if k.Read && !k.Dynamic {
+			keys = append(keys, k.Key)
+		}
+	}
+
+	return keys
+}
+
+// AuditState audits the global and local state keys of the approval and the clear state programs of an application.
+// It reports the keys written but never read by either of the programs and the local state cleanup the clear state program misses:
+// the local keys the approval program accounts for on CloseOut but the clear state program does not read, and the local keys
+// the clear state program writes although the local state is deleted by the call.
+func AuditState(approval *ProcessResult, clear *ProcessResult) StateAudit {
+	a := StateAudit{
+		Approval: AnalyzeState(approval),
+		Clear:    AnalyzeState(clear),
+	}
+
+	a.Incomplete = a.Approval.Incomplete || a.Clear.Incomplete
+
+	uses := map[string]*stateUse{}
+	var ids []string
+
+	// the keys read dynamically may be any of the keys of the scope
+	dynamic := map[string]bool{}
+
+	add := func(program string, keys []StateKey) {
+		for _, k := range keys {
+			if k.Dynamic {
+				if k.Read {
+					dynamic[k.Scope] = true
+				}
+				continue
+			}
+
+			id := k.Scope + ":" + k.Key
+
+			u, ok := uses[id]
+			if !ok {
+				u = &stateUse{}
+				uses[id] = u
+				ids = append(ids, id)
+			}
+
+			u.read = u.read || k.Read
+
+			if k.Write && !u.write {
+				u.write = true
+				u.program = program
+				u.lines = k.Lines
+			}
+		}
+	}
+
+	add("approval", a.Approval.Keys)
+	add("clear", a.Clear.Keys)
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		u := uses[id]
+		if !u.write || u.read {
+			continue
+		}
+
+		scope, key, _ := strings.Cut(id, ":")
+		if dynamic[scope] {
+			continue
+		}
+
+		a.Findings = append(a.Findings, StateFinding{
+			Kind:    StateFindingDead,
+			Scope:   scope,
+			Key:     key,
+			Program: u.program,
+			Lines:   u.lines,
+			Message: "written but never read by the approval and the clear state programs, only the off-chain readers see it",
+		})
+	}
+
+	read := map[string]bool{}
+	for _, k := range a.Clear.Keys {
+		if k.Scope == VmAccessLocal.String() && k.Read {
+			if k.Dynamic {
+				read[DynamicKey] = true
+			} else {
+				read[k.Key] = true
+			}
+		}
+	}
+
+	for _, key := range closeOutAccounting(approval) {
+		if read[key] || read[DynamicKey] {
+			continue
+		}
+
+		var lines []int
+		for _, k := range a.Approval.Keys {
+			if k.Scope == VmAccessLocal.String() && k.Key == key {
+				lines = k.Lines
+			}
+		}
+
+		a.Findings = append(a.Findings, StateFinding{
+			Kind:    StateFindingCleanup,
+			Scope:   VmAccessLocal.String(),
+			Key:     key,
+			Program: "approval",
+			Lines:   lines,
+			Message: "accounted for in the global state on CloseOut but not read by the clear state program, clearing the state drops it without the accounting",
+		})
+	}
+
+	for _, k := range a.Clear.Keys {
+		if k.Scope != VmAccessLocal.String() || !k.Write {
+			continue
+		}
+
+		a.Findings = append(a.Findings, StateFinding{
+			Kind:    StateFindingCleanup,
+			Scope:   k.Scope,
+			Key:     k.Key,
+			Program: "clear",
+			Lines:   k.Lines,
+			Message: "written by the clear state program, the local state of the sender is deleted by the call",
+		})
+	}
+
+	return a
+}
@@ -0,0 +1,110 @@
+package teal
+
+import "testing"
+
+const auditApproval = `#pragma version 8
+txn ApplicationID
+bz create
+txn OnCompletion
+int CloseOut
+==
+bnz close
+txn OnCompletion
+int OptIn
+==
+bnz optin
+byte "total"
+byte "total"
+app_global_get
+txn Sender
+byte "staked"
+app_local_get
++
+app_global_put
+int 1
+return
+optin:
+txn Sender
+byte "staked"
+int 0
+app_local_put
+txn Sender
+byte "joined"
+global Round
+app_local_put
+int 1
+return
+close:
+byte "total"
+byte "total"
+app_global_get
+txn Sender
+byte "staked"
+app_local_get
+-
+app_global_put
+int 1
+return
+create:
+byte "total"
+int 0
+app_global_put
+byte "creator"
+txn Sender
+app_global_put
+int 1
+return`
+
+func TestAuditState(t *testing.T) {
+	approval := Process(auditApproval)
+	clear := Process("#pragma version 8\ntxn Sender\nbyte \"note\"\nbyte \"bye\"\napp_local_put\nint 1\nreturn", WithClear())
+
+	a := AuditState(approval, clear)
+	if a.Incomplete {
+		t.Fatal("unexpected incomplete audit")
+	}
+
+	tests := []StateFinding{
+		{Kind: StateFindingDead, Scope: "global", Key: "creator", Program: "approval"},
+		{Kind: StateFindingDead, Scope: "local", Key: "joined", Program: "approval"},
+		{Kind: StateFindingDead, Scope: "local", Key: "note", Program: "clear"},
+		{Kind: StateFindingCleanup, Scope: "local", Key: "staked", Program: "approval"},
+		{Kind: StateFindingCleanup, Scope: "local", Key: "note", Program: "clear"},
+	}
+
+	if len(a.Findings) != len(tests) {
+		t.Fatalf("unexpected findings: %v", a.Findings)
+	}
+
+	for i, test := range tests {
+		f := a.Findings[i]
+		if f.Kind != test.Kind || f.Scope != test.Scope || f.Key != test.Key || f.Program != test.Program {
+			t.Errorf("unexpected finding %d - expected: %+v, got: %+v", i, test, f)
+		}
+	}
+
+	if lines := a.Findings[1].Lines; len(lines) != 1 || lines[0] != 29 {
+		t.Errorf("unexpected joined lines: %v", lines)
+	}
+}
+
+func TestAuditStateClearAccounting(t *testing.T) {
+	approval := Process(auditApproval)
+	clear := Process(`#pragma version 8
+byte "total"
+byte "total"
+app_global_get
+txn Sender
+byte "staked"
+app_local_get
+-
+app_global_put
+int 1
+return`, WithClear())
+
+	for _, f := range AuditState(approval, clear).Findings {
+		if f.Kind == StateFindingCleanup {
+			t.Errorf("unexpected finding: %s", f)
+		}
+	}
+}
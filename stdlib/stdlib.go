@@ -0,0 +1,166 @@
+// Package stdlib is a library of the canonical TEAL idioms built from the teal exprs,
+// shared by the Go translate pipeline and the LSP snippet completions.
+package stdlib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dragmz/teal"
+)
+
+// BasisPoints is the denominator of the Percent rates, 250 is 2.5%.
+const BasisPoints = 10000
+
+// AbiReturnPrefix is the prefix of the logged ARC-4 method return values.
+var AbiReturnPrefix = []byte{0x15, 0x1f, 0x7c, 0x75}
+
+// MulDiv computes a * b / c with the 128 bit intermediate product of mulw, so it does not overflow unless the result does.
+// It fails if c is zero or the result does not fit uint64.
+func MulDiv(a, b, c teal.Expr) teal.Expr {
+	return teal.Block(
+		a,
+		b,
+		teal.Mulw,
+		c,
+		teal.Divw,
+	)
+}
+
+// Percent computes the rate of the amount in basis points, e.g. a 2.5% fee with the rate of 250.
+func Percent(amount, bps teal.Expr) teal.Expr {
+	return MulDiv(amount, bps, teal.Int(BasisPoints))
+}
+
+// Min pushes the lower of the values.
+func Min(a, b teal.Expr) teal.Expr {
+	return teal.Block(
+		a,
+		b,
+		teal.Dup2,
+		teal.Gt,
+		teal.Select,
+	)
+}
+
+// Max pushes the higher of the values.
+func Max(a, b teal.Expr) teal.Expr {
+	return teal.Block(
+		a,
+		b,
+		teal.Dup2,
+		teal.Lt,
+		teal.Select,
+	)
+}
+
+// AbiReturn logs the ABI encoded return value of the method.
+func AbiReturn(value teal.Expr) teal.Expr {
+	return teal.Block(
+		&teal.ByteExpr{Value: AbiReturnPrefix},
+		value,
+		teal.Concat,
+		teal.Log,
+	)
+}
+
+// AbiReturnUint64 logs the uint64 return value of the method.
+func AbiReturnUint64(value teal.Expr) teal.Expr {
+	return AbiReturn(teal.Block(value, teal.Itob))
+}
+
+// RepeatBytes pushes the value concatenated count times, the loop counter is kept in the scratch slot
+// and the labels are prefixed with the name.
+func RepeatBytes(name string, value teal.Expr, count teal.Expr, slot uint8) teal.Expr {
+	loop := teal.Label(name + "_loop")
+	done := teal.Label(name + "_done")
+
+	return teal.Block(
+		&teal.ByteExpr{Format: teal.BytesStringLiteral},
+		count,
+		teal.Store(slot),
+		loop,
+		teal.Load(slot),
+		teal.Bz(done),
+		value,
+		teal.Concat,
+		teal.Load(slot),
+		teal.Int(1),
+		teal.MinusOp,
+		teal.Store(slot),
+		teal.B(loop),
+		done,
+	)
+}
+
+// placeholder is a tab stop of the snippet text with its default value, e.g. ${1:txn Amount}.
+type placeholder struct {
+	n    int
+	text string
+}
+
+func (p placeholder) String() string {
+	return fmt.Sprintf("${%d:%s}", p.n, p.text)
+}
+
+// Snippet is an idiom of the library with the tab stops for the values it takes.
+type Snippet struct {
+	Name   string
+	Detail string
+
+	Body teal.Expr
+}
+
+// Text renders the snippet in the LSP snippet syntax.
+func (s Snippet) Text() string {
+	return strings.TrimSuffix(teal.Compile([]teal.Expr{s.Body}).String(), "\n")
+}
+
+var placeholderRegexp = regexp.MustCompile(`\$\{\d+:([^}]*)\}`)
+
+// Source renders the snippet with the default values of the tab stops.
+func (s Snippet) Source() string {
+	return placeholderRegexp.ReplaceAllString(s.Text(), "$1")
+}
+
+// Snippets returns the idioms of the library offered as the snippet completions.
+func Snippets() []Snippet {
+	return []Snippet{
+		{
+			Name:   "muldiv",
+			Detail: "a * b / c without overflow",
+			Body:   MulDiv(placeholder{1, "txn Amount"}, placeholder{2, "int 3"}, placeholder{3, "int 4"}),
+		},
+		{
+			Name:   "percent",
+			Detail: "rate of the amount in basis points",
+			Body:   Percent(placeholder{1, "txn Amount"}, placeholder{2, "int 250"}),
+		},
+		{
+			Name:   "min",
+			Detail: "lower of the values",
+			Body:   Min(placeholder{1, "int 1"}, placeholder{2, "int 2"}),
+		},
+		{
+			Name:   "max",
+			Detail: "higher of the values",
+			Body:   Max(placeholder{1, "int 1"}, placeholder{2, "int 2"}),
+		},
+		{
+			Name:   "abireturn",
+			Detail: "log the ARC-4 return value",
+			Body:   AbiReturn(placeholder{1, "txna ApplicationArgs 1"}),
+		},
+		{
+			Name:   "abireturnint",
+			Detail: "log the ARC-4 uint64 return value",
+			Body:   AbiReturnUint64(placeholder{1, "int 0"}),
+		},
+		{
+			Name:   "repeat",
+			Detail: "concat the bytes in a loop",
+			Body:   RepeatBytes(placeholder{1, "repeat"}.String(), placeholder{2, "byte \"a\""}, placeholder{3, "int 3"}, 0),
+		},
+	}
+}
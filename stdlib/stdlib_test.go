@@ -0,0 +1,237 @@
+package stdlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"strings"
+	"testing"
+
+	"github.com/dragmz/teal"
+	"github.com/pkg/errors"
+)
+
+// uint64Hook computes the op on the known uint64 args, the VM keeps the arithmetic results unknown.
+func uint64Hook(n int, f func(args []uint64) ([]uint64, error)) teal.VmOpHook {
+	return func(b *teal.VmBranch) error {
+		args := make([]uint64, n)
+		for i := n - 1; i >= 0; i-- {
+			v, ok := b.Pop().Uint64()
+			if !ok {
+				return errors.New("unknown arg")
+			}
+			args[i] = v
+		}
+
+		res, err := f(args)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range res {
+			b.Push(teal.NewVmUint64(v))
+		}
+
+		return nil
+	}
+}
+
+func run(t *testing.T, e teal.Expr) *teal.Vm {
+	t.Helper()
+
+	src := "#pragma version 8\n" + teal.Compile([]teal.Expr{e}).String() + "\nstore 1\nint 1\nreturn"
+
+	res := teal.Process(src)
+	for _, d := range res.Diagnostics {
+		if d.Severity() == teal.DiagErr {
+			t.Fatalf("unexpected error: %s, source:\n%s", d, src)
+		}
+	}
+
+	vm := teal.NewVm(res)
+
+	vm.RegisterOpHook("mulw", uint64Hook(2, func(args []uint64) ([]uint64, error) {
+		hi, lo := bits.Mul64(args[0], args[1])
+		return []uint64{hi, lo}, nil
+	}))
+	vm.RegisterOpHook("divw", uint64Hook(3, func(args []uint64) ([]uint64, error) {
+		if args[2] <= args[0] {
+			return nil, errors.New("divw overflows uint64")
+		}
+		q, _ := bits.Div64(args[0], args[1], args[2])
+		return []uint64{q}, nil
+	}))
+	vm.RegisterOpHook("-", uint64Hook(2, func(args []uint64) ([]uint64, error) {
+		if args[1] > args[0] {
+			return nil, errors.New("- underflows uint64")
+		}
+		return []uint64{args[0] - args[1]}, nil
+	}))
+	vm.RegisterOpHook("select", func(b *teal.VmBranch) error {
+		c, ok := b.Pop().Uint64()
+		if !ok {
+			return errors.New("unknown condition")
+		}
+
+		y := b.Pop()
+		x := b.Pop()
+
+		if c != 0 {
+			b.Push(y)
+		} else {
+			b.Push(x)
+		}
+
+		return nil
+	})
+	vm.RegisterOpHook("itob", func(b *teal.VmBranch) error {
+		v, ok := b.Pop().Uint64()
+		if !ok {
+			return errors.New("unknown arg")
+		}
+
+		bs := make([]byte, 8)
+		binary.BigEndian.PutUint64(bs, v)
+		b.Push(teal.NewVmBytes(bs))
+
+		return nil
+	})
+
+	// the loaded values keep their known scratch values for the loop conditions
+	vm.RegisterOpHook("load", func(b *teal.VmBranch) error {
+		op := vm.Process.Listing[b.Line].(*teal.LoadExpr)
+		b.Push(vm.Scratch.Items[op.Index])
+		return nil
+	})
+
+	if !vm.RunAll(10) {
+		t.Fatalf("failed to run: %v, source:\n%s", vm.Err(), src)
+	}
+
+	return vm
+}
+
+// knownBytes joins the known parts of the concatenated bytes.
+func knownBytes(v teal.VmValue) ([]byte, bool) {
+	var res []byte
+
+	for _, p := range v.Parts() {
+		bs, ok := p.Bytes()
+		if !ok {
+			return nil, false
+		}
+		res = append(res, bs...)
+	}
+
+	return res, true
+}
+
+func result(t *testing.T, e teal.Expr) (teal.VmValue, *teal.VmBranch) {
+	t.Helper()
+
+	vm := run(t, e)
+	if len(vm.Branches) != 1 {
+		t.Fatalf("expected a single branch, got: %d", len(vm.Branches))
+	}
+
+	b := vm.Branches[0]
+	if b.Exit != teal.VmExitApprove {
+		t.Fatalf("unexpected exit: %s, err: %v", b.Exit, b.Err)
+	}
+
+	return vm.Scratch.Items[1], b
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		a, b, c  uint64
+		expected uint64
+	}{
+		{a: 1000, b: 250, c: BasisPoints, expected: 25},
+		// the product overflows uint64
+		{a: 1 << 62, b: 12, c: 16, expected: 3 << 60},
+		{a: 7, b: 3, c: 2, expected: 10},
+	}
+
+	for _, test := range tests {
+		v, _ := result(t, MulDiv(teal.Int(test.a), teal.Int(test.b), teal.Int(test.c)))
+		if n, ok := v.Uint64(); !ok || n != test.expected {
+			t.Errorf("expected %d * %d / %d = %d, got: %s", test.a, test.b, test.c, test.expected, v)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	v, _ := result(t, Percent(teal.Int(1_000_000), teal.Int(250)))
+	if n, ok := v.Uint64(); !ok || n != 25_000 {
+		t.Errorf("unexpected percent: %s", v)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	for _, test := range [][2]uint64{{1, 2}, {2, 1}, {3, 3}} {
+		lo, hi := test[0], test[1]
+		if hi < lo {
+			lo, hi = hi, lo
+		}
+
+		v, _ := result(t, Min(teal.Int(test[0]), teal.Int(test[1])))
+		if n, ok := v.Uint64(); !ok || n != lo {
+			t.Errorf("unexpected min of %v: %s", test, v)
+		}
+
+		v, _ = result(t, Max(teal.Int(test[0]), teal.Int(test[1])))
+		if n, ok := v.Uint64(); !ok || n != hi {
+			t.Errorf("unexpected max of %v: %s", test, v)
+		}
+	}
+}
+
+func TestAbiReturn(t *testing.T) {
+	_, b := result(t, teal.Block(AbiReturnUint64(teal.Int(258)), teal.Int(0)))
+
+	if len(b.Logs) != 1 {
+		t.Fatalf("expected a single log, got: %d", len(b.Logs))
+	}
+
+	expected := append(append([]byte{}, AbiReturnPrefix...), 0, 0, 0, 0, 0, 0, 1, 2)
+	if v, ok := knownBytes(b.Logs[0]); !ok || !bytes.Equal(v, expected) {
+		t.Errorf("unexpected log: %s", b.Logs[0])
+	}
+}
+
+func TestRepeatBytes(t *testing.T) {
+	for _, n := range []uint64{0, 1, 3} {
+		v, _ := result(t, RepeatBytes("rep", teal.StringBytes("ab"), teal.Int(n), 0))
+		if bs, ok := knownBytes(v); !ok || string(bs) != strings.Repeat("ab", int(n)) {
+			t.Errorf("unexpected repeat of %d: %s", n, v)
+		}
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	names := map[string]bool{}
+
+	for _, s := range Snippets() {
+		if names[s.Name] {
+			t.Errorf("duplicate snippet: %s", s.Name)
+		}
+		names[s.Name] = true
+
+		if !strings.Contains(s.Text(), "${1:") {
+			t.Errorf("missing the tab stop in snippet %s:\n%s", s.Name, s.Text())
+		}
+
+		src := "#pragma version 8\n" + s.Source() + "\npop\nint 1\nreturn"
+		if s.Name == "abireturn" || s.Name == "abireturnint" {
+			src = "#pragma version 8\n" + s.Source() + "\nint 1\nreturn"
+		}
+
+		res := teal.Process(src)
+		for _, d := range res.Diagnostics {
+			if d.Severity() == teal.DiagErr {
+				t.Errorf("unexpected error in snippet %s: %s, source:\n%s", s.Name, d, src)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package teal
+
+import (
+	"strings"
+)
+
+// Subline is a single instruction of a source line, lines can hold multiple instructions separated by ';'.
+type Subline struct {
+	Line   int
+	Tokens Line
+
+	b int // first character covered by the subline
+	e int // last character covered by the subline or -1 for the rest of the line
+}
+
+func (s Subline) Contains(ch int) bool {
+	return ch >= s.b && (s.e == -1 || ch <= s.e)
+}
+
+func splitSublines(lines []Line) []Subline {
+	var subs []Subline
+
+	for li, l := range lines {
+		p := 0
+		b := 0
+
+		for i, t := range l {
+			if t.Type() == TokenSemicolon {
+				subs = append(subs, Subline{Line: li, Tokens: l[p:i], b: b, e: t.Begin()})
+				p = i + 1
+				b = t.End()
+			}
+		}
+
+		subs = append(subs, Subline{Line: li, Tokens: l[p:], b: b, e: -1})
+	}
+
+	return subs
+}
+
+// remapRedundants converts listing indexes to source lines, skipping lines shared by multiple instructions.
+func remapRedundants(reds []RedundantLine, subs []Subline) []RedundantLine {
+	counts := map[int]int{}
+	for _, sub := range subs {
+		counts[sub.Line]++
+	}
+
+	var res []RedundantLine
+	for _, red := range reds {
+		line := subs[red.Line()].Line
+		if counts[line] > 1 {
+			continue
+		}
+
+		switch red := red.(type) {
+		case *RedundantLabelLine:
+			res = append(res, &RedundantLabelLine{line: line, name: red.name})
+		case RedundantBLine:
+			res = append(res, RedundantBLine{line: line})
+		default:
+			res = append(res, red)
+		}
+	}
+
+	return res
+}
+
+// SourceLine returns the source line of the listing item.
+func (r ProcessResult) SourceLine(i int) int {
+	if i < 0 || i >= len(r.Sublines) {
+		return i
+	}
+
+	return r.Sublines[i].Line
+}
+
+// ListingIndex returns the index of the first listing item on the source line or -1 if there is none.
+func (r ProcessResult) ListingIndex(line int) int {
+	for i, sub := range r.Sublines {
+		if sub.Line == line {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// LineAt returns the instruction tokens at the position.
+func (r ProcessResult) LineAt(l int, ch int) Line {
+	var res Line
+
+	for _, sub := range r.Sublines {
+		if sub.Line == l && sub.Contains(ch) {
+			res = sub.Tokens
+		}
+	}
+
+	return res
+}
+
+func lineIndent(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// SplitSublines moves each of the ';' separated instructions to its own line.
+func SplitSublines(source string) string {
+	res := Process(source)
+	lines := strings.SplitAfter(source, "\n")
+
+	seps := map[int][]Token{}
+	for _, t := range res.Tokens {
+		if t.Type() == TokenSemicolon {
+			seps[t.Line()] = append(seps[t.Line()], t)
+		}
+	}
+
+	var sb strings.Builder
+
+	for li, line := range lines {
+		ts := seps[li]
+		if len(ts) == 0 {
+			sb.WriteString(line)
+			continue
+		}
+
+		body := strings.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+		if eol == "" {
+			eol = "\n"
+		}
+
+		indent := lineIndent(body)
+
+		var parts []string
+		p := 0
+		for _, t := range ts {
+			parts = append(parts, body[p:t.Begin()])
+			p = t.End()
+		}
+		parts = append(parts, body[p:])
+
+		var out []string
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				out = append(out, indent+part)
+			}
+		}
+
+		sb.WriteString(strings.Join(out, eol))
+		sb.WriteString(line[len(body):])
+	}
+
+	return sb.String()
+}
+
+// JoinSublines joins runs of consecutive instruction lines into ';' separated lines up to the width.
+// Runs are broken by labels, comments, empty lines and after branches.
+func JoinSublines(source string, width int) string {
+	res := Process(source)
+	lines := strings.SplitAfter(source, "\n")
+
+	joinable := make([]bool, len(lines))
+	breaks := make([]bool, len(lines))
+
+	for i, op := range res.Listing {
+		sub := res.Sublines[i]
+		if sub.Line >= len(lines) || len(sub.Tokens) == 0 {
+			continue
+		}
+
+		switch op.(type) {
+		case Nop:
+			continue
+		case Branch, Terminator:
+			breaks[sub.Line] = true
+		}
+
+		joinable[sub.Line] = true
+	}
+
+	for _, t := range res.Tokens {
+		if t.Type() == TokenComment && t.Line() < len(lines) {
+			joinable[t.Line()] = false
+		}
+	}
+
+	for i := range res.Sublines {
+		if res.ListingIndex(res.Sublines[i].Line) != i {
+			// already joined lines are kept as is
+			joinable[res.Sublines[i].Line] = false
+		}
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !joinable[i] {
+			sb.WriteString(line)
+			continue
+		}
+
+		body := strings.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+
+		joined := body
+		for !breaks[i] && i+1 < len(lines) && joinable[i+1] {
+			next := strings.TrimSpace(lines[i+1])
+			if len(joined)+len("; ")+len(next) > width {
+				break
+			}
+
+			i++
+			joined += "; " + next
+			eol = lines[i][len(strings.TrimRight(lines[i], "\r\n")):]
+		}
+
+		sb.WriteString(joined)
+		sb.WriteString(eol)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package teal
+
+import "testing"
+
+func TestSublines(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1; int 2; +
+b end; int 3
+end:
+return`)
+
+	if len(res.Listing) != 8 || len(res.Sublines) != 8 {
+		t.Fatalf("unexpected listing length: %d", len(res.Listing))
+	}
+
+	if res.Listing[3].String() != "+" || res.SourceLine(3) != 1 {
+		t.Errorf("unexpected subline op: %s at line %d", res.Listing[3], res.SourceLine(3))
+	}
+
+	if res.ListingIndex(2) != 4 {
+		t.Errorf("unexpected listing index: %d", res.ListingIndex(2))
+	}
+
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("expected unreachable code diagnostic, got: %v", res.Diagnostics)
+	}
+
+	d := res.Diagnostics[0]
+	if d.Line() != 2 || d.Begin() != 7 || d.End() != 12 {
+		t.Errorf("unexpected diagnostic position: %d:%d-%d", d.Line(), d.Begin(), d.End())
+	}
+
+	ln := res.LineAt(1, 9)
+	if len(ln) != 2 || ln[0].String() != "int" || ln[1].String() != "2" {
+		t.Errorf("unexpected line at position: %v", ln)
+	}
+
+	if res.DocAt(1, 14) == "" {
+		t.Error("missing doc for the third instruction")
+	}
+}
+
+func TestSublinesBreakpoints(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1; int 2; +
+return`)
+
+	vm := NewVm(res)
+	verified := vm.SetBreakpoints([]int{1, 2})
+	if !verified[1] || !verified[2] {
+		t.Fatalf("unexpected verified breakpoints: %v", verified)
+	}
+
+	vm.Run()
+
+	if vm.Branch == nil || res.SourceLine(vm.Branch.Line) != 2 {
+		t.Errorf("expected to stop at line 2")
+	}
+}
+
+func TestSplitSublines(t *testing.T) {
+	s := SplitSublines("#pragma version 8\n\tint 1; int 2 ;+ // sum\nreturn\n")
+	if s != "#pragma version 8\n\tint 1\n\tint 2\n\t+ // sum\nreturn\n" {
+		t.Errorf("unexpected split: %q", s)
+	}
+}
+
+func TestJoinSublines(t *testing.T) {
+	s := JoinSublines("#pragma version 8\nint 1\nint 2\n+\nbnz end\nint 3\n// c\nend:\nint 1\nreturn\n", 14)
+	if s != "#pragma version 8\nint 1; int 2\n+; bnz end\nint 3\n// c\nend:\nint 1; return\n" {
+		t.Errorf("unexpected join: %q", s)
+	}
+}
@@ -1,5 +1,10 @@
 package teal
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Symbol interface {
 	Name() string
 	Line() int
@@ -64,3 +69,101 @@ func (s labelSymbol) Docs() string {
 func (s labelSymbol) Signature() string {
 	return s.sig
 }
+
+// subroutineInstrs returns the number of instructions of the subroutine starting at the label:
+// the instructions reachable from the label without leaving it with retsub, return or err.
+func (r ProcessResult) subroutineInstrs(name string) int {
+	labels := map[string]int{}
+	for i, op := range r.Listing {
+		if op, ok := op.(*LabelExpr); ok {
+			labels[op.Name] = i
+		}
+	}
+
+	start, ok := labels[name]
+	if !ok {
+		return 0
+	}
+
+	seen := map[int]bool{}
+	todo := []int{start}
+
+	count := 0
+
+	for len(todo) > 0 {
+		i := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		if i >= len(r.Listing) || seen[i] {
+			continue
+		}
+		seen[i] = true
+
+		next := true
+
+		var targets []*LabelExpr
+
+		switch op := r.Listing[i].(type) {
+		case *LabelExpr, *EmptyExpr, *CommentExpr, *PragmaExpr:
+			todo = append(todo, i+1)
+			continue
+		case *BExpr:
+			targets = []*LabelExpr{op.Label}
+			next = false
+		case *BzExpr:
+			targets = []*LabelExpr{op.Label}
+		case *BnzExpr:
+			targets = []*LabelExpr{op.Label}
+		case *SwitchExpr:
+			targets = op.Targets
+		case *MatchExpr:
+			targets = op.Targets
+		case *RetSubExpr, *ReturnExpr, *ErrExpr:
+			next = false
+		}
+
+		count++
+
+		if next {
+			todo = append(todo, i+1)
+		}
+
+		for _, t := range targets {
+			if j, ok := labels[t.Name]; ok {
+				todo = append(todo, j)
+			}
+		}
+	}
+
+	return count
+}
+
+// labelDoc describes the label for the hover on its references: the doc comment, the proto stack effect
+// and the number of instructions if it is a subroutine.
+func (r ProcessResult) labelDoc(name string) string {
+	syms := r.SymByName(name)
+	if len(syms) == 0 {
+		return ""
+	}
+
+	sym := syms[0]
+
+	parts := []string{fmt.Sprintf("label %s", name)}
+
+	if sym.Docs() != "" {
+		parts = append(parts, sym.Docs())
+	}
+
+	if sym.Signature() != "" {
+		parts = append(parts, fmt.Sprintf("stack: %s", sym.Signature()))
+	}
+
+	for _, op := range r.Listing {
+		if op, ok := op.(*CallSubExpr); ok && op.Label.Name == name {
+			parts = append(parts, fmt.Sprintf("subroutine: %d instructions", r.subroutineInstrs(name)))
+			break
+		}
+	}
+
+	return strings.Join(parts, "\r\n")
+}
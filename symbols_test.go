@@ -0,0 +1,34 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelDoc(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+callsub double
+b end
+// doubles the value
+double:
+proto 1 1
+frame_dig -1
+int 2
+*
+retsub
+end:
+return`)
+
+	doc := res.DocAt(2, 10)
+	for _, s := range []string{"label double", "doubles the value", "stack: in: 1, out: 1", "subroutine: 5 instructions"} {
+		if !strings.Contains(doc, s) {
+			t.Errorf("expected %q in the doc: %q", s, doc)
+		}
+	}
+
+	doc = res.DocAt(3, 3)
+	if !strings.HasPrefix(doc, "label end") || strings.Contains(doc, "subroutine") {
+		t.Errorf("unexpected branch target doc: %q", doc)
+	}
+}
@@ -0,0 +1,418 @@
+package teal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// Template is a family of programs that differ only in the parameters, e.g. the logic signature templates of the SDKs.
+//
+// The source has an instruction per line. The constants of the `int $name`, `byte $name` and `addr $name` lines
+// are the parameters, the `$name op1|op2` lines match any of the listed ops.
+type Template struct {
+	Name   string
+	Desc   string
+	Source string
+}
+
+// Templates are the standard templates recognized by MatchTemplate.
+var Templates = []Template{
+	{
+		Name: "htlc",
+		Desc: "Hash time lock contract - the receiver closes the account with the preimage of the hash, the owner after the timeout",
+		Source: `txn Fee
+int $fee
+<=
+txn TypeEnum
+int 1
+==
+&&
+txn Receiver
+global ZeroAddress
+==
+&&
+txn Amount
+int 0
+==
+&&
+txn CloseRemainderTo
+addr $receiver
+==
+arg 0
+$hash sha256|keccak256|sha512_256
+byte $image
+==
+&&
+txn CloseRemainderTo
+addr $owner
+==
+txn FirstValid
+int $timeout
+>
+&&
+||
+&&`,
+	},
+	{
+		Name: "periodic-payment",
+		Desc: "Periodic payment - the receiver withdraws the amount once per period, closes the account after the timeout",
+		Source: `txn TypeEnum
+int 1
+==
+txn Fee
+int $fee
+<=
+&&
+txn FirstValid
+int $period
+%
+int 0
+==
+&&
+txn LastValid
+int $duration
+txn FirstValid
++
+==
+&&
+txn Lease
+byte $lease
+==
+&&
+txn CloseRemainderTo
+global ZeroAddress
+==
+txn Receiver
+addr $receiver
+==
+&&
+txn Amount
+int $amount
+==
+&&
+txn CloseRemainderTo
+addr $receiver
+==
+txn Receiver
+global ZeroAddress
+==
+&&
+txn FirstValid
+int $timeout
+>
+&&
+txn Amount
+int 0
+==
+&&
+||
+&&`,
+	},
+	{
+		Name: "delegated-asset-transfer",
+		Desc: "Delegated asset transfer - anyone can send up to the amount of the asset to the receiver from the delegating account",
+		Source: `txn TypeEnum
+int 4
+==
+txn XferAsset
+int $asset
+==
+&&
+txn AssetAmount
+int $amount
+<=
+&&
+txn AssetReceiver
+addr $receiver
+==
+&&
+txn AssetCloseTo
+global ZeroAddress
+==
+&&
+txn RekeyTo
+global ZeroAddress
+==
+&&
+txn Fee
+int $fee
+<=
+&&`,
+	},
+}
+
+// TemplateParam is a parameter value of the matched template.
+type TemplateParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TemplateMatch is the template the program is an instance of.
+type TemplateMatch struct {
+	Template string          `json:"template"`
+	Params   []TemplateParam `json:"params"`
+}
+
+func (m TemplateMatch) String() string {
+	ps := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		ps[i] = fmt.Sprintf("%s: %s", p.Name, p.Value)
+	}
+
+	return fmt.Sprintf("matches template %s with parameters {%s}", m.Template, strings.Join(ps, ", "))
+}
+
+// templateOp is a normalized instruction - the constants are resolved from the constant blocks
+// so the assembled and the disassembled programs compare equal.
+type templateOp struct {
+	op string
+
+	isUint bool
+	uint   uint64
+
+	isBytes bool
+	bytes   []byte
+}
+
+func normalizeListing(l Listing) []templateOp {
+	var res []templateOp
+
+	var ints []uint64
+	var bytess [][]byte
+
+	pushUint := func(v uint64) {
+		res = append(res, templateOp{op: "int", isUint: true, uint: v})
+	}
+
+	pushBytes := func(v []byte) {
+		res = append(res, templateOp{op: "byte", isBytes: true, bytes: v})
+	}
+
+	intc := func(i int) {
+		if i < len(ints) {
+			pushUint(ints[i])
+		} else {
+			res = append(res, templateOp{op: fmt.Sprintf("intc %d", i)})
+		}
+	}
+
+	bytec := func(i int) {
+		if i < len(bytess) {
+			pushBytes(bytess[i])
+		} else {
+			res = append(res, templateOp{op: fmt.Sprintf("bytec %d", i)})
+		}
+	}
+
+	for _, op := range l {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		switch op := op.(type) {
+		case *IntcBlockExpr:
+			ints = op.Values
+		case *BytecBlockExpr:
+			bytess = op.Values
+		case *IntExpr:
+			pushUint(op.Value)
+		case *PushIntExpr:
+			pushUint(op.Value)
+		case *PushIntsExpr:
+			for _, v := range op.Ints {
+				pushUint(v)
+			}
+		case *IntcExpr:
+			intc(int(op.Index))
+		case *Intc0Expr:
+			intc(0)
+		case *Intc1Expr:
+			intc(1)
+		case *Intc2Expr:
+			intc(2)
+		case *Intc3Expr:
+			intc(3)
+		case *ByteExpr:
+			pushBytes(op.Value)
+		case *PushBytesExpr:
+			pushBytes(op.Value)
+		case *PushBytessExpr:
+			for _, v := range op.Bytess {
+				pushBytes(v)
+			}
+		case *BytecExpr:
+			bytec(int(op.Index))
+		case *Bytec0Expr:
+			bytec(0)
+		case *Bytec1Expr:
+			bytec(1)
+		case *Bytec2Expr:
+			bytec(2)
+		case *Bytec3Expr:
+			bytec(3)
+		case *AddrExpr:
+			addr, err := types.DecodeAddress(op.Address)
+			if err != nil {
+				res = append(res, templateOp{op: op.String()})
+				continue
+			}
+			pushBytes(addr[:])
+		case *Arg0Expr:
+			res = append(res, templateOp{op: "arg 0"})
+		case *Arg1Expr:
+			res = append(res, templateOp{op: "arg 1"})
+		case *Arg2Expr:
+			res = append(res, templateOp{op: "arg 2"})
+		case *Arg3Expr:
+			res = append(res, templateOp{op: "arg 3"})
+		default:
+			res = append(res, templateOp{op: op.String()})
+		}
+	}
+
+	return res
+}
+
+// templateLine is an instruction of the template source.
+type templateLine struct {
+	// param is the name of the parameter, empty for the literal instructions
+	param string
+
+	// kind is int, byte or addr for the constant params, op for the op alternatives
+	kind string
+	ops  []string
+
+	literal templateOp
+}
+
+func parseTemplate(s string) []templateLine {
+	var res []templateLine
+
+	for _, line := range strings.Split(s, "\n") {
+		fs := strings.Fields(line)
+		if len(fs) == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(fs[0], "$"):
+			var ops []string
+			if len(fs) > 1 {
+				ops = strings.Split(fs[1], "|")
+			}
+			res = append(res, templateLine{param: fs[0][1:], kind: "op", ops: ops})
+		case len(fs) == 2 && strings.HasPrefix(fs[1], "$"):
+			res = append(res, templateLine{param: fs[1][1:], kind: fs[0]})
+		case len(fs) == 2 && fs[0] == "int":
+			v, err := strconv.ParseUint(fs[1], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			res = append(res, templateLine{literal: templateOp{op: "int", isUint: true, uint: v}})
+		default:
+			res = append(res, templateLine{literal: templateOp{op: strings.Join(fs, " ")}})
+		}
+	}
+
+	return res
+}
+
+func formatParam(kind string, op templateOp) string {
+	switch kind {
+	case "int":
+		return strconv.FormatUint(op.uint, 10)
+	case "addr":
+		var addr types.Address
+		copy(addr[:], op.bytes)
+		return addr.String()
+	case "op":
+		return op.op
+	default:
+		return "0x" + hex.EncodeToString(op.bytes)
+	}
+}
+
+// match returns the parameters of the program matching the template.
+func (t Template) match(ops []templateOp) ([]TemplateParam, bool) {
+	lines := parseTemplate(t.Source)
+	if len(lines) != len(ops) {
+		return nil, false
+	}
+
+	var params []TemplateParam
+	values := map[string]string{}
+
+	for i, line := range lines {
+		op := ops[i]
+
+		if line.param == "" {
+			if line.literal.op != op.op || line.literal.isUint != op.isUint || line.literal.uint != op.uint {
+				return nil, false
+			}
+			continue
+		}
+
+		switch line.kind {
+		case "int":
+			if !op.isUint {
+				return nil, false
+			}
+		case "byte":
+			if !op.isBytes {
+				return nil, false
+			}
+		case "addr":
+			if !op.isBytes || len(op.bytes) != len(types.ZeroAddress) {
+				return nil, false
+			}
+		case "op":
+			found := false
+			for _, name := range line.ops {
+				if name == op.op {
+					found = true
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+
+		v := formatParam(line.kind, op)
+
+		// the repeated parameter has the same value in all of the places
+		if prev, ok := values[line.param]; ok {
+			if prev != v {
+				return nil, false
+			}
+			continue
+		}
+
+		values[line.param] = v
+		params = append(params, TemplateParam{Name: line.param, Value: v})
+	}
+
+	return params, true
+}
+
+// MatchTemplate returns the template of the templates the program is an instance of, the standard Templates if none are passed.
+func MatchTemplate(res *ProcessResult, ts ...Template) (TemplateMatch, bool) {
+	if len(ts) == 0 {
+		ts = Templates
+	}
+
+	ops := normalizeListing(res.Listing)
+
+	for _, t := range ts {
+		if ps, ok := t.match(ops); ok {
+			return TemplateMatch{Template: t.Name, Params: ps}, true
+		}
+	}
+
+	return TemplateMatch{}, false
+}
@@ -0,0 +1,129 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+const testHtlc = `#pragma version 2
+txn Fee
+int 1000
+<=
+txn TypeEnum
+int pay
+==
+&&
+txn Receiver
+global ZeroAddress
+==
+&&
+txn Amount
+int 0
+==
+&&
+txn CloseRemainderTo
+addr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q
+==
+arg 0
+sha256
+byte 0x01020304
+==
+&&
+txn CloseRemainderTo
+addr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA
+==
+txn FirstValid
+int 3000
+>
+&&
+||
+&&`
+
+func TestMatchTemplate(t *testing.T) {
+	m, ok := MatchTemplate(Process(testHtlc))
+	if !ok {
+		t.Fatal("expected htlc match")
+	}
+
+	expected := "matches template htlc with parameters {fee: 1000, receiver: 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q, hash: sha256, image: 0x01020304, owner: Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA, timeout: 3000}"
+	if m.String() != expected {
+		t.Errorf("unexpected match: %s", m)
+	}
+
+	// the disassembled program uses the constant blocks
+	disassembled := `#pragma version 2
+intcblock 1000 1 0 3000
+bytecblock 0x01020304
+txn Fee
+intc_0
+<=
+txn TypeEnum
+intc_1
+==
+&&
+txn Receiver
+global ZeroAddress
+==
+&&
+txn Amount
+intc_2
+==
+&&
+txn CloseRemainderTo
+addr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q
+==
+arg_0
+keccak256
+bytec_0
+==
+&&
+txn CloseRemainderTo
+addr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA
+==
+txn FirstValid
+intc_3
+>
+&&
+||
+&&`
+
+	m, ok = MatchTemplate(ProcessPermissive(disassembled))
+	if !ok || m.Template != "htlc" || m.Params[2].Value != "keccak256" {
+		t.Errorf("unexpected match of disassembled program: %s", m)
+	}
+
+	if _, ok := MatchTemplate(Process(strings.Replace(testHtlc, "int 3000\n>", "int 3000\n<", 1))); ok {
+		t.Error("unexpected match of modified program")
+	}
+}
+
+func TestMatchTemplateRepeatedParam(t *testing.T) {
+	var ts []Template
+	for _, tm := range Templates {
+		if tm.Name == "periodic-payment" {
+			ts = append(ts, tm)
+		}
+	}
+
+	src := "#pragma version 2\n" + strings.NewReplacer(
+		"int $fee", "int 1000",
+		"int $period", "int 100",
+		"int $duration", "int 10",
+		"byte $lease", "byte 0x01",
+		"int $amount", "int 5000",
+		"int $timeout", "int 99999",
+	).Replace(ts[0].Source)
+
+	same := strings.ReplaceAll(src, "addr $receiver", "addr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q")
+
+	m, ok := MatchTemplate(Process(same), ts...)
+	if !ok || len(m.Params) != 7 || m.Params[4].Name != "receiver" {
+		t.Errorf("unexpected match: %s", m)
+	}
+
+	different := strings.Replace(same, "addr 7ZUECA7HFLZTXENRV24SHLU4AVPUTMTTDUFUBNBD64C73F3UHRTHAIOF6Q", "addr Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA", 1)
+
+	if _, ok := MatchTemplate(Process(different), ts...); ok {
+		t.Error("unexpected match of different receivers")
+	}
+}
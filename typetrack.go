@@ -0,0 +1,72 @@
+package teal
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const typeAssertionPrefix = "#pragma type "
+
+// TypeAssertion forces the stack type checker to assume the types of the top stack values
+// at the listing index, e.g. after an intentional cast in generated code. The last type is the top of the stack.
+type TypeAssertion struct {
+	Index int
+	Types StackTypes
+}
+
+func parseStackType(s string) (StackType, error) {
+	switch s {
+	case "uint64", "int":
+		return StackUint64, nil
+	case "bytes", "[]byte", "byte":
+		return StackBytes, nil
+	case "any":
+		return StackAny, nil
+	default:
+		return StackNone, errors.Errorf("unknown stack type: %s, expected uint64, bytes or any", s)
+	}
+}
+
+func parseTypeAssertion(s string) (StackTypes, error) {
+	var ts StackTypes
+
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+		t, err := parseStackType(f)
+		if err != nil {
+			return nil, err
+		}
+
+		ts = append(ts, t)
+	}
+
+	if len(ts) == 0 {
+		return nil, errors.New("missing stack types")
+	}
+
+	return ts, nil
+}
+
+// TypeTracked returns false if the listing index is in a region disabled with `#pragma typetrack false`.
+func (r ProcessResult) TypeTracked(index int) bool {
+	tracked := true
+
+	for i := 0; i <= index && i < len(r.Listing); i++ {
+		if t, ok := r.Listing[i].(*TypeTrackExpr); ok {
+			tracked = t.Enabled
+		}
+	}
+
+	return tracked
+}
+
+// AssertedTypes returns the stack types forced at the listing index.
+func (r ProcessResult) AssertedTypes(index int) (StackTypes, bool) {
+	for _, a := range r.TypeAssertions {
+		if a.Index == index {
+			return a.Types, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,45 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypeTrack(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+#pragma typetrack false
+byte 0x01
+//#pragma type uint64, bytes
+#pragma typetrack true
+pop`)
+
+	for _, d := range res.Diagnostics {
+		t.Errorf("unexpected diagnostic: %s", d)
+	}
+
+	tracked := []bool{true, true, false, false, false, true, true}
+	for i, expected := range tracked {
+		if res.TypeTracked(i) != expected {
+			t.Errorf("unexpected type tracking at %d", i)
+		}
+	}
+
+	ts, ok := res.AssertedTypes(4)
+	if !ok || !reflect.DeepEqual(ts, StackTypes{StackUint64, StackBytes}) {
+		t.Errorf("unexpected asserted types: %v", ts)
+	}
+
+	if res.Listing[2].String() != "#pragma typetrack false" {
+		t.Errorf("unexpected listing: %s", res.Listing[2])
+	}
+}
+
+func TestTypeTrackErrors(t *testing.T) {
+	for _, src := range []string{"#pragma typetrack maybe", "//#pragma type string"} {
+		res := Process("#pragma version 8\n" + src)
+		if len(res.Diagnostics) == 0 {
+			t.Errorf("expected diagnostic for '%s'", src)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package teal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// UpgradeMethodRemoved is a method routed by the old program but not by the new one
+	UpgradeMethodRemoved = "method-removed"
+
+	// UpgradeMethodAdded is a method routed by the new program only
+	UpgradeMethodAdded = "method-added"
+
+	// UpgradeKeyTypeChanged is a state key or a box written with a different value type
+	UpgradeKeyTypeChanged = "key-type-changed"
+
+	// UpgradeKeyRemoved is a state key or a box the new program no longer accesses
+	UpgradeKeyRemoved = "key-removed"
+
+	// UpgradeKeyAdded is a state key or a box the new program accesses but the old one does not
+	UpgradeKeyAdded = "key-added"
+
+	// UpgradeSchemaGrowth is a state schema the new program needs more keys of than the old one
+	UpgradeSchemaGrowth = "schema-growth"
+)
+
+// UpgradeChange is a difference between the old and the new version of an approval program.
+type UpgradeChange struct {
+	Kind string `json:"kind"`
+
+	// Scope is the state scope of the key changes: global, local or box
+	Scope string `json:"scope,omitempty"`
+
+	// Subject is the method signature, the key or the schema the change is about
+	Subject string `json:"subject"`
+
+	// Breaking is set if the existing clients or the existing state are affected by the upgrade
+	Breaking bool `json:"breaking"`
+
+	// Lines are the source lines of the new program, or of the old one for the removed items
+	Lines []int `json:"lines,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (c UpgradeChange) String() string {
+	severity := "info"
+	if c.Breaking {
+		severity = "breaking"
+	}
+
+	subject := c.Subject
+	if c.Scope != "" {
+		subject = c.Scope + " " + subject
+	}
+
+	return fmt.Sprintf("%s %s %s: %s", severity, c.Kind, subject, c.Message)
+}
+
+// UpgradeReport is the upgrade impact of replacing the old approval program with the new one.
+type UpgradeReport struct {
+	// Old and New are the min schemas required by the static state writes of the programs
+	Old Schema `json:"old"`
+	New Schema `json:"new"`
+
+	Changes []UpgradeChange `json:"changes,omitempty"`
+
+	// Breaking is set if any of the changes is breaking
+	Breaking bool `json:"breaking"`
+
+	// not all of the paths could be explored
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// Required returns the min schema of the static global and local keys written, the keys of an unknown value type
+// are counted as both of the types.
+func (s StateSchema) Required() Schema {
+	var res Schema
+
+	for _, k := range s.Keys {
+		if !k.Write || k.Dynamic {
+			continue
+		}
+
+		var uints, bytes *uint64
+		switch k.Scope {
+		case VmAccessGlobal.String():
+			uints, bytes = &res.GlobalNumUint, &res.GlobalNumByteSlice
+		case VmAccessLocal.String():
+			uints, bytes = &res.LocalNumUint, &res.LocalNumByteSlice
+		default:
+			continue
+		}
+
+		switch k.Type {
+		case "uint64":
+			*uints++
+		case "bytes":
+			*bytes++
+		default:
+			*uints++
+			*bytes++
+		}
+	}
+
+	return res
+}
+
+// upgradeMethods returns the routed method entries by the method signature.
+func upgradeMethods(res *ProcessResult) map[string]TestEntry {
+	ms := map[string]TestEntry{}
+
+	for _, e := range res.TestEntries() {
+		if e.Method != "" {
+			ms[e.Method] = e
+		}
+	}
+
+	return ms
+}
+
+func methodName(sig string) string {
+	name, _, _ := strings.Cut(sig, "(")
+	return name
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// upgradeKeys indexes the static keys by the scope and the key.
+func upgradeKeys(keys []StateKey) map[string]StateKey {
+	res := map[string]StateKey{}
+
+	for _, k := range keys {
+		if !k.Dynamic {
+			res[k.Scope+":"+k.Key] = k
+		}
+	}
+
+	return res
+}
+
+// AnalyzeUpgrade compares the ABI methods routed, the state keys and the boxes accessed by the old and the new version
+// of an approval program and reports the changes breaking the existing clients or the existing state: the removed methods,
+// the keys written with a different value type and the schema growth, which the immutable schema of a deployed app cannot accommodate.
+func AnalyzeUpgrade(oldRes *ProcessResult, newRes *ProcessResult) UpgradeReport {
+	ostate := AnalyzeState(oldRes)
+	nstate := AnalyzeState(newRes)
+
+	r := UpgradeReport{
+		Old:        ostate.Required(),
+		New:        nstate.Required(),
+		Incomplete: ostate.Incomplete || nstate.Incomplete,
+	}
+
+	add := func(c UpgradeChange) {
+		r.Changes = append(r.Changes, c)
+		r.Breaking = r.Breaking || c.Breaking
+	}
+
+	om := upgradeMethods(oldRes)
+	nm := upgradeMethods(newRes)
+
+	names := map[string][]string{}
+	for _, sig := range sortedKeys(nm) {
+		names[methodName(sig)] = append(names[methodName(sig)], sig)
+	}
+
+	for _, sig := range sortedKeys(om) {
+		if _, ok := nm[sig]; ok {
+			continue
+		}
+
+		msg := "no longer routed, the calls of the existing clients fail"
+		if sigs := names[methodName(sig)]; len(sigs) > 0 {
+			msg = fmt.Sprintf("signature changed to %s, the calls of the existing clients fail", strings.Join(sigs, ", "))
+		}
+
+		add(UpgradeChange{
+			Kind:     UpgradeMethodRemoved,
+			Subject:  sig,
+			Breaking: true,
+			Lines:    []int{om[sig].Line},
+			Message:  msg,
+		})
+	}
+
+	for _, sig := range sortedKeys(nm) {
+		if _, ok := om[sig]; ok {
+			continue
+		}
+
+		add(UpgradeChange{
+			Kind:    UpgradeMethodAdded,
+			Subject: sig,
+			Lines:   []int{nm[sig].Line},
+			Message: "newly routed",
+		})
+	}
+
+	compare := func(oks []StateKey, nks []StateKey) {
+		ok := upgradeKeys(oks)
+		nk := upgradeKeys(nks)
+
+		for _, id := range sortedKeys(ok) {
+			o := ok[id]
+
+			n, found := nk[id]
+			if !found {
+				add(UpgradeChange{
+					Kind:    UpgradeKeyRemoved,
+					Scope:   o.Scope,
+					Subject: o.Key,
+					Lines:   o.Lines,
+					Message: "no longer accessed, the existing values are left in the state",
+				})
+				continue
+			}
+
+			if o.Type != "" && n.Type != "" && o.Type != n.Type && o.Type != "any" && n.Type != "any" {
+				add(UpgradeChange{
+					Kind:     UpgradeKeyTypeChanged,
+					Scope:    n.Scope,
+					Subject:  n.Key,
+					Breaking: true,
+					Lines:    n.Lines,
+					Message:  fmt.Sprintf("written as %s instead of %s, the existing values have the old type", n.Type, o.Type),
+				})
+			}
+		}
+
+		for _, id := range sortedKeys(nk) {
+			n := nk[id]
+			if _, found := ok[id]; found {
+				continue
+			}
+
+			msg := "newly accessed"
+
+			// the existing state does not have the key if the new program never writes it
+			if n.Read && !n.Write {
+				msg = "read but never written, the existing state does not have it"
+			}
+
+			add(UpgradeChange{
+				Kind:    UpgradeKeyAdded,
+				Scope:   n.Scope,
+				Subject: n.Key,
+				Lines:   n.Lines,
+				Message: msg,
+			})
+		}
+	}
+
+	compare(ostate.Keys, nstate.Keys)
+	compare(ostate.Boxes, nstate.Boxes)
+
+	growth := func(name string, o uint64, n uint64) {
+		if n <= o {
+			return
+		}
+
+		add(UpgradeChange{
+			Kind:     UpgradeSchemaGrowth,
+			Subject:  name,
+			Breaking: true,
+			Message:  fmt.Sprintf("up to %d keys written instead of %d, the schema of a deployed app cannot grow", n, o),
+		})
+	}
+
+	growth("globalNumUint", r.Old.GlobalNumUint, r.New.GlobalNumUint)
+	growth("globalNumByteSlice", r.Old.GlobalNumByteSlice, r.New.GlobalNumByteSlice)
+	growth("localNumUint", r.Old.LocalNumUint, r.New.LocalNumUint)
+	growth("localNumByteSlice", r.Old.LocalNumByteSlice, r.New.LocalNumByteSlice)
+
+	return r
+}
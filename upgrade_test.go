@@ -0,0 +1,110 @@
+package teal
+
+import "testing"
+
+const upgradeOld = `#pragma version 8
+txn ApplicationID
+bz create
+method "add(uint64,uint64)uint64"
+method "get()uint64"
+txna ApplicationArgs 0
+match add get
+err
+add:
+byte "count"
+int 1
+app_global_put
+int 1
+return
+get:
+byte "count"
+app_global_get
+pop
+int 1
+return
+create:
+byte "legacy"
+int 0
+app_global_put
+int 1
+return`
+
+const upgradeNew = `#pragma version 8
+txn ApplicationID
+bz create
+method "add(uint64,uint64,uint64)uint64"
+method "reset()void"
+txna ApplicationArgs 0
+match add reset
+err
+add:
+byte "count"
+byte "one"
+app_global_put
+int 1
+return
+reset:
+byte "owner"
+txn Sender
+app_global_put
+int 1
+return
+create:
+int 1
+return`
+
+func TestAnalyzeUpgrade(t *testing.T) {
+	r := AnalyzeUpgrade(Process(upgradeOld), Process(upgradeNew))
+
+	if !r.Breaking {
+		t.Error("expected a breaking upgrade")
+	}
+
+	found := map[string]UpgradeChange{}
+	for _, c := range r.Changes {
+		found[c.Kind+" "+c.Subject] = c
+	}
+
+	expected := map[string]bool{
+		"method-removed add(uint64,uint64)uint64":      true,
+		"method-removed get()uint64":                   true,
+		"method-added add(uint64,uint64,uint64)uint64": false,
+		"method-added reset()void":                     false,
+		"key-type-changed count":                       true,
+		"key-removed legacy":                           false,
+		"key-added owner":                              false,
+		"schema-growth globalNumByteSlice":             true,
+	}
+
+	for id, breaking := range expected {
+		c, ok := found[id]
+		if !ok {
+			t.Errorf("missing change: %s", id)
+			continue
+		}
+
+		if c.Breaking != breaking {
+			t.Errorf("expected %s breaking: %t, got: %t", id, breaking, c.Breaking)
+		}
+	}
+
+	if len(r.Changes) != len(expected) {
+		t.Errorf("unexpected changes: %v", r.Changes)
+	}
+
+	if c := found["method-removed add(uint64,uint64)uint64"]; c.Message != "signature changed to add(uint64,uint64,uint64)uint64, the calls of the existing clients fail" {
+		t.Errorf("unexpected message: %s", c.Message)
+	}
+
+	if r.Old.GlobalNumUint != 2 || r.New.GlobalNumByteSlice != 2 {
+		t.Errorf("unexpected schemas: %+v, %+v", r.Old, r.New)
+	}
+}
+
+func TestAnalyzeUpgradeSame(t *testing.T) {
+	r := AnalyzeUpgrade(Process(upgradeOld), Process(upgradeOld))
+
+	if r.Breaking || len(r.Changes) != 0 {
+		t.Errorf("expected no changes, got: %v", r.Changes)
+	}
+}
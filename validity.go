@@ -0,0 +1,272 @@
+package teal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ValidityConstraint is a temporal condition enforced by the program, e.g. "valid only after round 1000".
+type ValidityConstraint struct {
+	Condition string `json:"condition"`
+	Message   string `json:"message"`
+	Line      int    `json:"line"`
+
+	// Paths is the number of the approving paths enforcing the constraint
+	Paths int `json:"paths"`
+}
+
+// Validity is the validity window of the transactions approved by the program.
+type Validity struct {
+	Constraints []ValidityConstraint `json:"constraints,omitempty"`
+
+	// Paths is the number of the approving paths, each of the disjunctions counts as a separate path
+	Paths int `json:"paths"`
+
+	// not all of the paths could be explored to the end
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// lifespanBound returns the number of the rounds the LastValid is compared with relative to the FirstValid of the same transaction,
+// e.g. 1000 for txn LastValid <= txn FirstValid + 1000.
+func lifespanBound(c pcond) (string, bool) {
+	if !strings.HasSuffix(c.x, LastValid.String()) {
+		return "", false
+	}
+
+	first := strings.TrimSuffix(c.x, LastValid.String()) + FirstValid.String()
+
+	for _, n := range []string{strings.TrimPrefix(c.y, first+" + "), strings.TrimSuffix(c.y, " + "+first)} {
+		if n == c.y {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(n, 10, 64); err == nil {
+			return n, true
+		}
+	}
+
+	return "", false
+}
+
+// isTemporal reports whether the condition is over the validity rounds, the round or the time of the block.
+func isTemporal(c pcond) bool {
+	s := c.String()
+
+	for _, f := range []string{FirstValid.String(), LastValid.String(), FirstValidTime.String(), "global " + GlobalRound.String(), "global " + GlobalLatestTimestamp.String()} {
+		if strings.Contains(s, f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeValidity describes the temporal condition.
+func describeValidity(c pcond) string {
+	n, err := strconv.ParseUint(c.y, 10, 64)
+	if c.op == "&&" || c.op == "||" || !c.yconst || err != nil {
+		if n, ok := lifespanBound(c); ok {
+			switch c.op {
+			case "<=":
+				return fmt.Sprintf("requires txn lifespan <= %s rounds", n)
+			case "<":
+				return fmt.Sprintf("requires txn lifespan < %s rounds", n)
+			case "==":
+				return fmt.Sprintf("requires txn lifespan of %s rounds", n)
+			}
+		}
+
+		return "enforces " + c.String()
+	}
+
+	rounds := map[string]string{
+		">":  "valid only after round %d",
+		">=": "valid only from round %d",
+		"<":  "valid only before round %d",
+		"<=": "valid only until round %d",
+		"==": "valid only at round %d",
+	}
+
+	expiry := map[string]string{
+		">":  "requires the txn to be valid after round %d",
+		">=": "requires the txn to be valid until at least round %d",
+		"<":  "requires the txn to expire before round %d",
+		"<=": "requires the txn to expire by round %d",
+		"==": "requires the txn to expire at round %d",
+	}
+
+	lifespan := map[string]string{
+		"<":  "requires txn lifespan < %d rounds",
+		"<=": "requires txn lifespan <= %d rounds",
+		"==": "requires txn lifespan of %d rounds",
+	}
+
+	times := map[string]string{
+		">":  "valid only after %s",
+		">=": "valid only from %s",
+		"<":  "valid only before %s",
+		"<=": "valid only until %s",
+	}
+
+	var format string
+
+	switch {
+	case strings.Contains(c.x, LastValid.String()+" - ") && strings.HasSuffix(c.x, " "+FirstValid.String()):
+		format = lifespan[c.op]
+	case strings.HasSuffix(c.x, " "+FirstValid.String()), c.x == "global "+GlobalRound.String():
+		format = rounds[c.op]
+	case strings.HasSuffix(c.x, " "+LastValid.String()):
+		format = expiry[c.op]
+	case strings.HasSuffix(c.x, " "+FirstValidTime.String()), c.x == "global "+GlobalLatestTimestamp.String():
+		if f, ok := times[c.op]; ok {
+			return fmt.Sprintf(f, time.Unix(int64(n), 0).UTC().Format(time.RFC3339))
+		}
+	}
+
+	if format == "" {
+		return "enforces " + c.String()
+	}
+
+	return fmt.Sprintf(format, n)
+}
+
+// AnalyzeValidity collects the conditions over the FirstValid, LastValid, the round and the timestamp
+// the approving paths of the program enforce and describes them, e.g. "valid only after round X"
+// or "requires txn lifespan <= 1000 rounds".
+func AnalyzeValidity(res *ProcessResult) Validity {
+	approved, incomplete := walkApproved(res)
+
+	v := Validity{
+		Paths:      len(approved),
+		Incomplete: incomplete,
+	}
+
+	index := map[string]int{}
+
+	for _, lits := range approved {
+		seen := map[string]bool{}
+
+		for _, l := range lits {
+			if !isTemporal(l) {
+				continue
+			}
+
+			s := l.String()
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+
+			i, ok := index[s]
+			if !ok {
+				i = len(v.Constraints)
+				index[s] = i
+				v.Constraints = append(v.Constraints, ValidityConstraint{
+					Condition: s,
+					Message:   describeValidity(l),
+					Line:      l.line,
+				})
+			}
+
+			v.Constraints[i].Paths++
+		}
+	}
+
+	return v
+}
+
+// fundsFields are the fields of the payments and the asset transfers checked by the escrows
+var fundsFields = []TxnField{Receiver, Amount, CloseRemainderTo, AssetReceiver, AssetAmount, AssetCloseTo, XferAsset}
+
+// fundsCheck returns the check of the path that makes the program authorize moving the funds of the account.
+func fundsCheck(lits []pcond) (pcond, bool) {
+	for _, l := range lits {
+		if strings.Contains(l.String(), ApplicationID.String()) {
+			// the app is responsible for the checks
+			return pcond{}, false
+		}
+	}
+
+	for _, l := range lits {
+		if l.op == "&&" || l.op == "||" || !strings.HasPrefix(l.x, "txn ") {
+			continue
+		}
+
+		field := strings.TrimPrefix(l.x, "txn ")
+
+		if field == TypeEnum.String() && l.op == "==" && (l.y == TxnTypeNames[1] || l.y == TxnTypeNames[4]) {
+			return l, true
+		}
+
+		for _, f := range fundsFields {
+			if field == f.String() {
+				return l, true
+			}
+		}
+	}
+
+	return pcond{}, false
+}
+
+type MissingExpiryRule struct{}
+
+func (r MissingExpiryRule) Id() string {
+	return "LINT0027"
+}
+
+func (r MissingExpiryRule) Desc() string {
+	return "Reports the escrow logic signatures approving transfers without any validity window checks"
+}
+
+var MissingExpiryRuleInstance = MissingExpiryRule{}
+
+func checkMissingExpiry(res *ProcessResult) []Diagnostic {
+	if res.Mode != ModeSig {
+		return nil
+	}
+
+	approved, _ := walkApproved(res)
+
+	var check pcond
+	found := false
+
+	for _, lits := range approved {
+		for _, l := range lits {
+			if isTemporal(l) {
+				return nil
+			}
+		}
+
+		if c, ok := fundsCheck(lits); ok && !found {
+			check = c
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	for _, sub := range res.Sublines {
+		if sub.Line != check.line {
+			continue
+		}
+
+		return []Diagnostic{
+			lintError{
+				error: errors.New("the escrow approves the transfers without any validity window checks, e.g. txn LastValid <= expiry round - the signature authorizes them forever"),
+				l:     sub.Line,
+				b:     sub.Tokens.Begin(),
+				e:     codeEnd(sub),
+				s:     DiagWarn,
+				r:     MissingExpiryRuleInstance.Id(),
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package teal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeValidity(t *testing.T) {
+	src := "#pragma version 2\n//#pragma mode logicsig\n" + strings.NewReplacer(
+		"$fee", "1000",
+		"$period", "100",
+		"$duration", "1000",
+		"$lease", "0x01",
+		"$receiver", "Y76M3MSY6DKBRHBL7C3NNDXGS5IIMQVQVUAB6MP4XEMMGVF2QWNPL226CA",
+		"$amount", "5",
+		"$timeout", "500000",
+	).Replace(Templates[1].Source)
+
+	v := AnalyzeValidity(Process(src))
+
+	if v.Paths != 2 || v.Incomplete {
+		t.Fatalf("unexpected validity: %+v", v)
+	}
+
+	expected := map[string]string{
+		"txn FirstValid % 100 == 0":              "enforces txn FirstValid % 100 == 0",
+		"txn LastValid == 1000 + txn FirstValid": "requires txn lifespan of 1000 rounds",
+		"txn FirstValid > 500000":                "valid only after round 500000",
+	}
+
+	if len(v.Constraints) != len(expected) {
+		t.Errorf("unexpected constraints: %+v", v.Constraints)
+	}
+
+	for _, c := range v.Constraints {
+		m, ok := expected[c.Condition]
+		if !ok || m != c.Message {
+			t.Errorf("unexpected constraint: %+v", c)
+		}
+
+		if c.Condition == "txn FirstValid > 500000" && c.Paths != 1 {
+			t.Errorf("expected the timeout on the close path only, got: %d", c.Paths)
+		}
+	}
+}
+
+func TestDescribeValidity(t *testing.T) {
+	tests := map[string]string{
+		"#pragma version 8\ntxn LastValid\nint 2000\n<=":                    "requires the txn to expire by round 2000",
+		"#pragma version 8\ntxn LastValid\ntxn FirstValid\n-\nint 1000\n<=": "requires txn lifespan <= 1000 rounds",
+		"#pragma version 8\ntxn LastValid\ntxn FirstValid\nint 1000\n+\n<":  "requires txn lifespan < 1000 rounds",
+		"#pragma version 8\nglobal LatestTimestamp\nint 1700000000\n>=":     "valid only from 2023-11-14T22:13:20Z",
+		"#pragma version 8\nint 100\nglobal Round\n<":                       "valid only after round 100",
+	}
+
+	for src, expected := range tests {
+		v := AnalyzeValidity(Process(src))
+		if len(v.Constraints) != 1 || v.Constraints[0].Message != expected {
+			t.Errorf("expected: %s, got: %+v", expected, v.Constraints)
+		}
+	}
+}
+
+func TestMissingExpiry(t *testing.T) {
+	htlc := strings.Replace(testHtlc, "\n", "\n//#pragma mode logicsig\n", 1)
+
+	tests := []struct {
+		src      string
+		expected int
+	}{
+		// the refund after the timeout is a validity window check
+		{src: htlc, expected: 0},
+		// the app guards the escrow
+		{src: "#pragma version 8\n//#pragma mode logicsig\ngtxn 0 ApplicationID\nint 123\n==\ntxn Receiver\nglobal ZeroAddress\n!=\n&&", expected: 0},
+		// not a logic signature
+		{src: "#pragma version 8\ntxn Receiver\nglobal ZeroAddress\n!=", expected: 0},
+		{src: "#pragma version 8\n//#pragma mode logicsig\ntxn TypeEnum\nint axfer\n==\nassert\nint 1", expected: 1},
+	}
+
+	for _, test := range tests {
+		n := 0
+		for _, d := range Process(test.src).Diagnostics {
+			if d.Rule() == MissingExpiryRuleInstance.Id() {
+				n++
+			}
+		}
+
+		if n != test.expected {
+			t.Errorf("expected %d warnings, got: %d, source:\n%s", test.expected, n, test.src)
+		}
+	}
+}
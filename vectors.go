@@ -0,0 +1,294 @@
+package teal
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TestInput is a concrete value of a transaction field or of an application argument, Bytes are hex encoded.
+type TestInput struct {
+	Field string  `json:"field"`
+	Uint  *uint64 `json:"uint,omitempty"`
+	Bytes string  `json:"bytes,omitempty"`
+}
+
+// TestVector is an input that exercises a branch of a condition at the source line.
+type TestVector struct {
+	Line   int         `json:"line"`
+	Branch string      `json:"branch"`
+	Inputs []TestInput `json:"inputs"`
+}
+
+type vectorOp struct {
+	op   Op
+	line int
+}
+
+func uintInput(field string, v uint64) TestInput {
+	return TestInput{Field: field, Uint: &v}
+}
+
+func constUint(op Op) (uint64, bool) {
+	switch op := op.(type) {
+	case *IntExpr:
+		return op.Value, true
+	case *PushIntExpr:
+		return op.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func constBytes(op Op) ([]byte, bool) {
+	switch op := op.(type) {
+	case *ByteExpr:
+		return op.Value, true
+	case *PushBytesExpr:
+		return op.Value, true
+	case *MethodExpr:
+		// the parsed signature keeps its quotes
+		h := sha512.Sum512_256([]byte(strings.Trim(op.Signature, "\"")))
+		return h[:4], true
+	default:
+		return nil, false
+	}
+}
+
+// uintField returns the name of the input pushed by the op.
+func uintField(op Op) (string, bool) {
+	switch op := op.(type) {
+	case *TxnExpr:
+		switch op.Field {
+		case OnCompletion, ApplicationID, NumAppArgs, NumAccounts, NumAssets, NumApplications, TypeEnum, Fee, Amount, GroupIndex:
+			return op.Field.String(), true
+		}
+	case *GlobalExpr:
+		switch op.Field {
+		case GlobalGroupSize:
+			return "GroupSize", true
+		}
+	}
+
+	return "", false
+}
+
+func argField(op Op) (string, bool) {
+	switch op := op.(type) {
+	case *TxnaExpr:
+		if op.Field == ApplicationArgs {
+			return fmt.Sprintf("ApplicationArgs[%d]", op.Index), true
+		}
+	}
+
+	return "", false
+}
+
+// satisfying returns the values that make the comparison of the field with the constant true and false.
+func satisfying(cmp Op, c uint64, fieldFirst bool) (uint64, uint64, bool) {
+	next := c + 1
+	prev := c
+	if c > 0 {
+		prev = c - 1
+	}
+
+	switch cmp.(type) {
+	case *EqExpr:
+		return c, next, true
+	case *NeqExpr:
+		return next, c, true
+	case *LtExpr:
+		if !fieldFirst {
+			return next, c, true
+		}
+		if c == 0 {
+			return 0, 0, false
+		}
+		return prev, c, true
+	case *GtExpr:
+		if fieldFirst {
+			return next, c, true
+		}
+		if c == 0 {
+			return 0, 0, false
+		}
+		return prev, c, true
+	case *LtEqExpr:
+		if fieldFirst {
+			return c, next, true
+		}
+		if c == 0 {
+			return 0, 0, false
+		}
+		return c, prev, true
+	case *GtEqExpr:
+		if !fieldFirst {
+			return c, next, true
+		}
+		if c == 0 {
+			return 0, 0, false
+		}
+		return c, prev, true
+	default:
+		return 0, 0, false
+	}
+}
+
+type vectorGen struct {
+	vs []TestVector
+}
+
+func (g *vectorGen) add(line int, branch string, in TestInput) {
+	g.vs = append(g.vs, TestVector{Line: line, Branch: branch, Inputs: []TestInput{in}})
+}
+
+func (g *vectorGen) compare(ops []vectorOp, i int) {
+	cmp := ops[i+2]
+
+	a := ops[i].op
+	b := ops[i+1].op
+
+	if field, ok := uintField(a); ok {
+		if c, ok := constUint(b); ok {
+			if t, f, ok := satisfying(cmp.op, c, true); ok {
+				g.add(cmp.line, "true", uintInput(field, t))
+				g.add(cmp.line, "false", uintInput(field, f))
+			}
+		}
+	}
+
+	if field, ok := uintField(b); ok {
+		if c, ok := constUint(a); ok {
+			if t, f, ok := satisfying(cmp.op, c, false); ok {
+				g.add(cmp.line, "true", uintInput(field, t))
+				g.add(cmp.line, "false", uintInput(field, f))
+			}
+		}
+	}
+
+	for _, p := range [][2]Op{{a, b}, {b, a}} {
+		field, ok := argField(p[0])
+		if !ok {
+			continue
+		}
+
+		bs, ok := constBytes(p[1])
+		if !ok {
+			continue
+		}
+
+		other := append([]byte{}, bs...)
+		other = append(other, 0)
+
+		t, f := bs, other
+		if _, ok := cmp.op.(*NeqExpr); ok {
+			t, f = f, t
+		}
+
+		switch cmp.op.(type) {
+		case *EqExpr, *NeqExpr:
+			g.add(cmp.line, "true", TestInput{Field: field, Bytes: hex.EncodeToString(t)})
+			g.add(cmp.line, "false", TestInput{Field: field, Bytes: hex.EncodeToString(f)})
+		}
+	}
+}
+
+// length handles comparisons of application argument lengths: txna ApplicationArgs i; len; int c; cmp
+func (g *vectorGen) length(ops []vectorOp, i int) {
+	field, ok := argField(ops[i].op)
+	if !ok {
+		return
+	}
+
+	if _, ok := ops[i+1].op.(*LenExpr); !ok {
+		return
+	}
+
+	c, ok := constUint(ops[i+2].op)
+	if !ok {
+		return
+	}
+
+	cmp := ops[i+3]
+
+	t, f, ok := satisfying(cmp.op, c, true)
+	if !ok {
+		return
+	}
+
+	name := "len(" + field + ")"
+	g.add(cmp.line, "true", uintInput(name, t))
+	g.add(cmp.line, "false", uintInput(name, f))
+}
+
+// branches handles switch and match ops on inputs.
+func (g *vectorGen) branches(ops []vectorOp, i int) {
+	op := ops[i]
+
+	switch sw := op.op.(type) {
+	case *SwitchExpr:
+		if i == 0 {
+			return
+		}
+
+		field, ok := uintField(ops[i-1].op)
+		if !ok {
+			return
+		}
+
+		for k, t := range sw.Targets {
+			g.add(op.line, t.Name, uintInput(field, uint64(k)))
+		}
+
+		g.add(op.line, "(fallthrough)", uintInput(field, uint64(len(sw.Targets))))
+	case *MatchExpr:
+		n := len(sw.Targets)
+		if i < n+1 {
+			return
+		}
+
+		field, ok := argField(ops[i-n-1].op)
+		if !ok {
+			return
+		}
+
+		for k, t := range sw.Targets {
+			bs, ok := constBytes(ops[i-n+k].op)
+			if !ok {
+				return
+			}
+
+			g.add(op.line, t.Name, TestInput{Field: field, Bytes: hex.EncodeToString(bs)})
+		}
+	}
+}
+
+// GenerateVectors suggests concrete inputs that exercise both branches of the conditions
+// on transaction fields, group size and application arguments.
+func GenerateVectors(res *ProcessResult) []TestVector {
+	var ops []vectorOp
+	for i, op := range res.Listing {
+		if _, ok := op.(Nop); ok {
+			continue
+		}
+
+		ops = append(ops, vectorOp{op: op, line: res.SourceLine(i)})
+	}
+
+	g := &vectorGen{}
+
+	for i := range ops {
+		if i+2 < len(ops) {
+			g.compare(ops, i)
+		}
+
+		if i+3 < len(ops) {
+			g.length(ops, i)
+		}
+
+		g.branches(ops, i)
+	}
+
+	return g.vs
+}
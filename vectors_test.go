@@ -0,0 +1,59 @@
+package teal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerateVectors(t *testing.T) {
+	res := Process(`#pragma version 8
+global GroupSize
+int 2
+==
+assert
+int 1
+txn NumAppArgs
+<
+assert
+txna ApplicationArgs 1
+len
+int 32
+<=
+assert
+txn OnCompletion
+switch noop optin
+err
+noop:
+txna ApplicationArgs 0
+method "add(uint64,uint64)uint64"
+match add
+err
+optin:
+add:
+int 1
+return`)
+
+	vs := GenerateVectors(res)
+
+	bs, err := json.Marshal(vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[` +
+		`{"line":3,"branch":"true","inputs":[{"field":"GroupSize","uint":2}]},` +
+		`{"line":3,"branch":"false","inputs":[{"field":"GroupSize","uint":3}]},` +
+		`{"line":7,"branch":"true","inputs":[{"field":"NumAppArgs","uint":2}]},` +
+		`{"line":7,"branch":"false","inputs":[{"field":"NumAppArgs","uint":1}]},` +
+		`{"line":12,"branch":"true","inputs":[{"field":"len(ApplicationArgs[1])","uint":32}]},` +
+		`{"line":12,"branch":"false","inputs":[{"field":"len(ApplicationArgs[1])","uint":33}]},` +
+		`{"line":15,"branch":"noop","inputs":[{"field":"OnCompletion","uint":0}]},` +
+		`{"line":15,"branch":"optin","inputs":[{"field":"OnCompletion","uint":1}]},` +
+		`{"line":15,"branch":"(fallthrough)","inputs":[{"field":"OnCompletion","uint":2}]},` +
+		`{"line":20,"branch":"add","inputs":[{"field":"ApplicationArgs[0]","bytes":"fe6bdf69"}]}` +
+		`]`
+
+	if string(bs) != expected {
+		t.Errorf("unexpected vectors: %s", bs)
+	}
+}
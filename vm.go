@@ -1,17 +1,19 @@
 package teal
 
 import (
+	"bytes"
+	"crypto/sha512"
 	"fmt"
 	"strconv"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
 const (
 	MainName = "(main)"
 	ExitLine = -1
 	ExitName = "(exited)"
+
+	VmDefaultBudget = 700
 )
 
 type VmFrame struct {
@@ -20,6 +22,12 @@ type VmFrame struct {
 	NumReturns uint8
 	p          uint8
 	Name       string
+	Sub        string // called subroutine label
+}
+
+// Base returns the stack index of the first local variable of the frame.
+func (f VmFrame) Base() int {
+	return int(f.p)
 }
 
 type vmSource interface {
@@ -77,6 +85,141 @@ func (c vmByteConst) Lengths() []int {
 	return []int{len(c.v)}
 }
 
+// vmFixedValue is a value of a known length, e.g. itob result.
+type vmFixedValue struct {
+	n int
+	s string
+}
+
+func (v vmFixedValue) String() string {
+	return v.s
+}
+
+func (v vmFixedValue) Lengths() []int {
+	return []int{v.n}
+}
+
+// vmUserValue is a value provided by the caller, e.g. an application argument.
+type vmUserValue struct {
+	s string
+}
+
+func (v vmUserValue) String() string {
+	return v.s
+}
+
+// vmConcatValue is a concatenation of the values.
+type vmConcatValue struct {
+	parts []VmValue
+}
+
+func (v vmConcatValue) String() string {
+	var ss []string
+	for _, p := range v.parts {
+		if p.src != nil {
+			ss = append(ss, p.src.String())
+		} else {
+			ss = append(ss, "?")
+		}
+	}
+
+	return strings.Join(ss, " ++ ")
+}
+
+func (v vmConcatValue) Lengths() []int {
+	n := 0
+
+	for _, p := range v.parts {
+		ls := p.Lengths()
+		if len(ls) != 1 {
+			return []int{}
+		}
+
+		n += ls[0]
+	}
+
+	return []int{n}
+}
+
+// vmConcatMaxParts is the max number of the parts of a concatenation, the values concatenated with themselves
+// in the loops double their parts so the longer ones are collapsed
+const vmConcatMaxParts = 256
+
+func vmConcat(x VmValue, y VmValue) VmValue {
+	var parts []VmValue
+
+	for _, v := range []VmValue{x, y} {
+		switch src := v.src.(type) {
+		case vmConcatValue:
+			parts = append(parts, src.parts...)
+		default:
+			parts = append(parts, v)
+		}
+	}
+
+	// the collapsed value keeps the known length and an unknown part for the parts of the unknown lengths
+	if len(parts) > vmConcatMaxParts {
+		n := 0
+		unknown := false
+
+		for _, p := range parts {
+			ls := p.Lengths()
+			if len(ls) != 1 {
+				unknown = true
+				continue
+			}
+
+			n += ls[0]
+		}
+
+		parts = []VmValue{{T: VmTypeBytes, src: vmFixedValue{n: n, s: "concat"}}}
+		if unknown {
+			parts = append(parts, VmValue{T: VmTypeBytes})
+		}
+	}
+
+	return VmValue{T: VmTypeBytes, src: vmConcatValue{parts: parts}}
+}
+
+// vmMinLength returns the min length of the value, the parts of the unknown lengths count as empty.
+func vmMinLength(v VmValue) int {
+	n := 0
+
+	for _, p := range v.Parts() {
+		ls := p.Lengths()
+		if len(ls) == 0 {
+			continue
+		}
+
+		min := ls[0]
+		for _, l := range ls[1:] {
+			if l < min {
+				min = l
+			}
+		}
+
+		n += min
+	}
+
+	return n
+}
+
+// Parts returns the concatenated values or the value itself.
+func (v VmValue) Parts() []VmValue {
+	switch src := v.src.(type) {
+	case vmConcatValue:
+		return src.parts
+	default:
+		return []VmValue{v}
+	}
+}
+
+// IsUser returns true if the value is provided by the caller.
+func (v VmValue) IsUser() bool {
+	_, ok := v.src.(vmUserValue)
+	return ok
+}
+
 type NamedExpr interface {
 	Name() string
 }
@@ -104,6 +247,65 @@ func (c vmByteConst) String() string {
 	return Bytes{Value: c.v}.String()
 }
 
+// Uint64 returns the value when the value is a known uint64 constant.
+func (v VmValue) Uint64() (uint64, bool) {
+	switch src := v.src.(type) {
+	case vmUint64Const:
+		return src.v, true
+	default:
+		return 0, false
+	}
+}
+
+func vmUint64(v uint64) VmValue {
+	return VmValue{T: VmTypeUint64, src: vmUint64Const{v: v}}
+}
+
+func vmBool(v bool) VmValue {
+	if v {
+		return vmUint64(1)
+	}
+
+	return vmUint64(0)
+}
+
+// vmCompare evaluates the comparison when both of the values are known uint64 constants.
+func vmCompare(x VmValue, y VmValue, f func(x, y uint64) bool) VmValue {
+	xv, xok := x.Uint64()
+	yv, yok := y.Uint64()
+
+	if !xok || !yok {
+		return VmValue{T: VmTypeUint64}
+	}
+
+	return vmBool(f(xv, yv))
+}
+
+// vmEqual evaluates the equality when both of the values are known constants.
+func vmEqual(x VmValue, y VmValue, eq bool) VmValue {
+	xb, xok := x.Bytes()
+	yb, yok := y.Bytes()
+
+	if xok && yok {
+		return vmBool(bytes.Equal(xb, yb) == eq)
+	}
+
+	return vmCompare(x, y, func(x, y uint64) bool { return (x == y) == eq })
+}
+
+// Bytes returns the value bytes when the value is a known byte constant.
+func (v VmValue) Bytes() ([]byte, bool) {
+	switch src := v.src.(type) {
+	case vmByteConst:
+		return src.v, true
+	case vmSignatureValue:
+		h := sha512.Sum512_256([]byte(strings.Trim(src.v, "\"")))
+		return h[:4], true
+	default:
+		return nil, false
+	}
+}
+
 func (v VmValue) String() string {
 	res := v.T.String()
 	if v.src != nil {
@@ -168,8 +370,8 @@ func (b *VmBranch) skipNops() bool {
 		return false
 	}
 
-	for b.Line < len(b.vm.Process.Listing) {
-		op := b.vm.Process.Listing[b.Line]
+	for b.Line < len(b.vm.listing) && !b.vm.ends[b.Line] {
+		op := b.vm.listing[b.Line]
 
 		lbl, ok := op.(*LabelExpr)
 		if ok {
@@ -207,6 +409,12 @@ func (b *VmBranch) store(index VmValue, v VmValue) {
 	switch src := index.src.(type) {
 	case vmUint64Const:
 		b.vm.Scratch.Items[src.v] = v
+
+		for i, w := range b.vm.Watchpoints {
+			if w.Kind == VmWatchScratch && uint64(w.Slot) == src.v {
+				b.vm.watch(b, i)
+			}
+		}
 	}
 }
 
@@ -216,7 +424,7 @@ func (b *VmBranch) peek(index int) VmValue {
 
 func (b *VmBranch) pop(t VmDataType) VmValue {
 	if len(b.Stack.Items) == 0 {
-		panic(errors.Errorf("empty stack - expected: %s", t))
+		panic(&StackUnderflowError{Line: b.sourceLine(b.Line), PC: b.Line, Expected: t})
 	}
 
 	v := b.Stack.Items[len(b.Stack.Items)-1]
@@ -241,6 +449,31 @@ func (s *vmStack) clone() *vmStack {
 	}
 }
 
+type VmExit int
+
+const (
+	VmExitNone    VmExit = iota // the branch is still running
+	VmExitApprove               // exited with a known non-zero value
+	VmExitReject                // exited with a known zero value
+	VmExitUnknown               // exited with a value that is not known
+	VmExitFail                  // err, failed assertion, exhausted budget or invalid stack at the end
+)
+
+func (e VmExit) String() string {
+	switch e {
+	case VmExitApprove:
+		return "approve"
+	case VmExitReject:
+		return "reject"
+	case VmExitUnknown:
+		return "unknown"
+	case VmExitFail:
+		return "fail"
+	default:
+		return "(none)"
+	}
+}
+
 type VmBranch struct {
 	Id int
 
@@ -256,32 +489,57 @@ type VmBranch struct {
 
 	Name  string
 	Trace []Op
+	Logs  []VmValue
+
+	// Costs are the budget consumed by each of the traced ops, Lines are their listing lines
+	Costs []int
+	Lines []int
+
+	// set when the branch exits because an op at ExhaustedAt exceeds the remaining budget
+	Exhausted   bool
+	ExhaustedAt int
+
+	Exit VmExit
+
+	// Err is the reason of the failure, set when the branch exits with VmExitFail
+	Err error
 }
 
 func (b *VmBranch) fork(target string) {
 	nb := &VmBranch{
 		Id:     b.vm.Id,
 		vm:     b.vm,
-		Line:   b.vm.find(target),
+		Line:   b.Line,
 		Stack:  b.Stack.clone(),
 		Budget: b.Budget,
+		Frames: append([]VmFrame{}, b.Frames...),
 		Name:   target,
 		Trace:  append([]Op{}, b.Trace...),
+		Logs:   append([]VmValue{}, b.Logs...),
+		Costs:  append([]int{}, b.Costs...),
+		Lines:  append([]int{}, b.Lines...),
 	}
 
 	b.vm.Id++
 
+	nb.jump(target)
 	nb.skipNops()
 	b.vm.Branches = append(b.vm.Branches, nb)
 }
 
 func (b *VmBranch) jump(target string) {
-	b.Line = b.vm.find(target)
+	ln := b.vm.find(target)
+	if ln == ExitLine {
+		b.failf("unknown label: %s", target)
+		return
+	}
+
+	b.Line = ln
 }
 
 func (b *VmBranch) call(target string) {
-	b.Frames = append(b.Frames, VmFrame{Return: b.Line, NumArgs: 0, NumReturns: 0, p: uint8(len(b.Stack.Items)), Name: b.Name})
-	b.Line = b.vm.find(target)
+	b.Frames = append(b.Frames, VmFrame{Return: b.Line, NumArgs: 0, NumReturns: 0, p: uint8(len(b.Stack.Items)), Name: b.Name, Sub: target})
+	b.jump(target)
 }
 
 func (b *VmBranch) exit() {
@@ -289,6 +547,142 @@ func (b *VmBranch) exit() {
 	b.Name = ExitName
 }
 
+// finish exits the branch with the value as the program result.
+func (b *VmBranch) finish(v VmValue) {
+	if n, ok := v.Uint64(); ok {
+		if n != 0 {
+			b.Exit = VmExitApprove
+		} else {
+			b.Exit = VmExitReject
+		}
+	} else {
+		b.Exit = VmExitUnknown
+	}
+
+	b.exit()
+}
+
+// end exits the branch that ran past the last instruction.
+func (b *VmBranch) end() {
+	if len(b.Stack.Items) != 1 {
+		b.failf("stack must contain exactly one value at the end, got: %d", len(b.Stack.Items))
+		return
+	}
+
+	b.finish(b.Stack.Items[0])
+}
+
+type VmAccessScope int
+
+const (
+	VmAccessGlobal VmAccessScope = iota
+	VmAccessLocal
+	VmAccessBox
+)
+
+func (s VmAccessScope) String() string {
+	switch s {
+	case VmAccessGlobal:
+		return "global"
+	case VmAccessLocal:
+		return "local"
+	case VmAccessBox:
+		return "box"
+	default:
+		return "(none)"
+	}
+}
+
+type VmAccessKind int
+
+const (
+	VmAccessRead VmAccessKind = iota
+	VmAccessWrite
+	VmAccessDelete
+)
+
+// VmAccess is a state or box access made by an op at the listing line.
+type VmAccess struct {
+	Line  int
+	Scope VmAccessScope
+	Kind  VmAccessKind
+	Key   VmValue
+	Value VmValue // written value
+}
+
+func (b *VmBranch) access(scope VmAccessScope, kind VmAccessKind, key VmValue, value VmValue) {
+	b.vm.Accesses = append(b.vm.Accesses, VmAccess{
+		Line:  b.Line,
+		Scope: scope,
+		Kind:  kind,
+		Key:   key,
+		Value: value,
+	})
+
+	if kind == VmAccessRead {
+		return
+	}
+
+	bs, ok := key.Bytes()
+
+	for i, w := range b.vm.Watchpoints {
+		if w.Kind != VmWatchState || w.Scope != scope {
+			continue
+		}
+
+		// writes to keys that are not known may change any of the keys
+		if !ok || string(bs) == string(w.Key) {
+			b.vm.watch(b, i)
+		}
+	}
+}
+
+type VmWatchKind int
+
+const (
+	VmWatchScratch VmWatchKind = iota // scratch slot is written
+	VmWatchState                      // global, local or box key is written or deleted
+	VmWatchStack                      // stack depth exceeds the threshold
+)
+
+// VmWatchpoint is a data breakpoint.
+type VmWatchpoint struct {
+	Kind VmWatchKind
+
+	Slot  int
+	Scope VmAccessScope
+	Key   []byte
+	Depth int
+}
+
+func (w VmWatchpoint) String() string {
+	switch w.Kind {
+	case VmWatchScratch:
+		return fmt.Sprintf("scratch %d", w.Slot)
+	case VmWatchState:
+		return fmt.Sprintf("%s %s", w.Scope, FormatKey(w.Key))
+	case VmWatchStack:
+		return fmt.Sprintf("stack depth > %d", w.Depth)
+	default:
+		return "(none)"
+	}
+}
+
+func (v *Vm) watch(b *VmBranch, i int) {
+	for _, w := range v.Watched[b.Id] {
+		if w == i {
+			return
+		}
+	}
+
+	v.Watched[b.Id] = append(v.Watched[b.Id], i)
+}
+
+// SetWatchpoints sets the data breakpoints, Run stops when any of them is hit.
+func (v *Vm) SetWatchpoints(ws []VmWatchpoint) {
+	v.Watchpoints = ws
+}
+
 type VmScratch struct {
 	Items [256]VmValue
 }
@@ -303,6 +697,12 @@ type Vm struct {
 	Process *ProcessResult
 	syms    map[string]int
 
+	// listing is the program listing with the listings of the included files appended,
+	// lines are the source lines of the include directives of the appended ops and ends the indexes the files end at
+	listing Listing
+	lines   map[int]int
+	ends    map[int]bool
+
 	Scratch VmScratch
 
 	Branches []*VmBranch
@@ -312,6 +712,27 @@ type Vm struct {
 	Breakpoints []VmBreakpoint
 	Triggered   map[int][]int
 
+	// known transaction field values, used to evaluate the conditions depending on them
+	Txn map[TxnField]uint64
+
+	// known ApplicationArgs values
+	Args [][]byte
+
+	// state accesses made by all of the branches
+	Accesses []VmAccess
+
+	// byte comparisons of the values with different known lengths made by all of the branches
+	LengthMismatches []VmLengthMismatch
+
+	Watchpoints []VmWatchpoint
+	Watched     map[int][]int // hit watchpoint indexes by branch id
+
+	// ids of the branches stepped so far, used to replay the execution when stepping back
+	History []int
+
+	// ops executed by the hooks instead of the built-in implementation, keyed by the op name
+	hooks map[string]VmOpHook
+
 	Trace string
 
 	Error any
@@ -326,10 +747,52 @@ func (v *Vm) find(target string) int {
 	return ln
 }
 
+// sourceLine returns the source line of the listing index, the ops of the included files are at their include directive.
+func (v *Vm) sourceLine(i int) int {
+	if ln, ok := v.lines[i]; ok {
+		return ln
+	}
+
+	return v.Process.SourceLine(i)
+}
+
+// include appends the listings of the included files, the included code is reached only by the branches to its labels.
+func (v *Vm) include(incs []*Include, line int, seen map[string]bool) {
+	for _, inc := range incs {
+		if seen[inc.Path] {
+			continue
+		}
+		seen[inc.Path] = true
+
+		ln := line
+		if ln < 0 {
+			ln = inc.Line
+		}
+
+		// the branches falling through to the include op of the file end instead of running into it
+		v.ends[len(v.listing)] = true
+		v.lines[len(v.listing)] = ln
+		v.listing = append(v.listing, &IncludeExpr{Path: inc.Path})
+
+		for _, op := range inc.Result.Listing {
+			if lbl, ok := op.(*LabelExpr); ok {
+				if _, ok := v.syms[lbl.Name]; !ok {
+					v.syms[lbl.Name] = len(v.listing)
+				}
+			}
+
+			v.lines[len(v.listing)] = ln
+			v.listing = append(v.listing, op)
+		}
+
+		v.include(inc.Result.Includes, ln, seen)
+	}
+}
+
 func (v *Vm) updateBreakpoints(br *VmBranch) {
 	for _, bp := range v.Breakpoints {
 		if br.Line == bp.Line {
-			v.Triggered[br.Id] = append(v.Triggered[br.Id], br.Line)
+			v.Triggered[br.Id] = append(v.Triggered[br.Id], v.sourceLine(br.Line))
 		}
 	}
 }
@@ -347,14 +810,20 @@ func NewVm(res *ProcessResult) *Vm {
 	v := &Vm{
 		Process:   res,
 		Triggered: map[int][]int{},
+		Watched:   map[int][]int{},
 		syms:      syms,
+		listing:   append(Listing{}, res.Listing...),
+		lines:     map[int]int{},
+		ends:      map[int]bool{},
 	}
 
+	v.include(res.Includes, -1, map[string]bool{})
+
 	b := &VmBranch{
 		Id:     v.Id,
 		vm:     v,
 		Stack:  &vmStack{},
-		Budget: 700,
+		Budget: VmDefaultBudget,
 		Name:   MainName,
 	}
 
@@ -389,7 +858,7 @@ func (v *Vm) skipNops() {
 			return
 		}
 
-		b.exit()
+		b.end()
 	}
 
 	v.Branch = nil
@@ -405,21 +874,25 @@ func (v *Vm) Switch(id int) {
 	}
 }
 
+// SetBreakpoints sets breakpoints on the first instruction of each of the source lines.
 func (v *Vm) SetBreakpoints(lns []int) map[int]bool {
 	verified := map[int]bool{}
 
 	var res []VmBreakpoint
 
 	for _, ln := range lns {
-		if ln >= len(v.Process.Listing) {
-			continue
-		}
+		for i, sub := range v.Process.Sublines {
+			if sub.Line != ln {
+				continue
+			}
 
-		if _, isnop := v.Process.Listing[ln].(Nop); !isnop {
-			res = append(res, VmBreakpoint{
-				Line: ln,
-			})
-			verified[ln] = true
+			if _, isnop := v.Process.Listing[i].(Nop); !isnop {
+				res = append(res, VmBreakpoint{
+					Line: i,
+				})
+				verified[ln] = true
+				break
+			}
 		}
 	}
 
@@ -438,6 +911,7 @@ func (v *Vm) Step() {
 
 	v.Error = nil
 	v.Triggered = map[int][]int{}
+	v.Watched = map[int][]int{}
 
 	v.skipNops()
 
@@ -446,7 +920,9 @@ func (v *Vm) Step() {
 	}
 
 	if b := v.Branch; b != nil {
-		op := v.Process.Listing[b.Line]
+		v.History = append(v.History, b.Id)
+
+		op := v.listing[b.Line]
 
 		var costs []int
 
@@ -471,6 +947,8 @@ func (v *Vm) Step() {
 					Budget: b.Budget,
 					Name:   b.Name,
 					Trace:  append([]Op{}, b.Trace...),
+					Costs:  append([]int{}, b.Costs...),
+					Lines:  append([]int{}, b.Lines...),
 				}
 
 				b.vm.Id++
@@ -480,30 +958,194 @@ func (v *Vm) Step() {
 			if cb.Budget >= cost {
 				cb.Budget -= cost
 				cb.Trace = append(cb.Trace, op)
+				cb.Costs = append(cb.Costs, cost)
+				cb.Lines = append(cb.Lines, cb.Line)
+
+				depth := len(cb.Stack.Items)
+
+				if hook, ok := v.hooks[opName(op.String())]; ok {
+					line := cb.Line
+					if err := hook(cb); err != nil {
+						cb.failWith(err)
+					} else if cb.Line == line {
+						cb.Line++
+					}
+				} else {
+					switch op := op.(type) {
+					case vmOp:
+						op.Execute(cb)
+					default:
+						cb.Line++
+					}
+				}
 
-				switch op := op.(type) {
-				case vmOp:
-					op.Execute(cb)
-				default:
-					cb.Line++
+				for i, w := range v.Watchpoints {
+					if w.Kind == VmWatchStack && depth <= w.Depth && len(cb.Stack.Items) > w.Depth {
+						v.watch(cb, i)
+					}
 				}
 
 				cb.skipNops()
 				v.skipNops()
 				v.updateBreakpoints(cb)
 			} else {
-				cb.exit()
+				cb.Exhausted = true
+				cb.ExhaustedAt = cb.Line
+				cb.failWith(&BudgetExceededError{Line: cb.sourceLine(cb.Line), PC: cb.Line, Cost: cost, Budget: cb.Budget})
 			}
 		}
 	}
 }
 
-func (v *Vm) Run() {
+// VmOpHook executes an op instead of the built-in implementation. It pops the op arguments and pushes its results,
+// the branch advances to the next op unless the hook moves it. An error fails the branch.
+type VmOpHook func(b *VmBranch) error
+
+// RegisterOpHook overrides the execution of the op with the name, e.g. to mock ops that need real cryptographic inputs
+// or to implement custom ops. A nil hook restores the built-in implementation.
+func (v *Vm) RegisterOpHook(name string, hook VmOpHook) {
+	if hook == nil {
+		delete(v.hooks, name)
+		return
+	}
+
+	if v.hooks == nil {
+		v.hooks = map[string]VmOpHook{}
+	}
+
+	v.hooks[name] = hook
+}
+
+// VmStubHook returns a hook that pops the number of arguments and pushes the results.
+func VmStubHook(args int, results ...VmValue) VmOpHook {
+	return func(b *VmBranch) error {
+		for i := 0; i < args; i++ {
+			b.Pop()
+		}
+
+		for _, r := range results {
+			b.Push(r)
+		}
+
+		return nil
+	}
+}
+
+// NewVmUint64 returns a known uint64 value.
+func NewVmUint64(v uint64) VmValue {
+	return vmUint64(v)
+}
+
+// NewVmBytes returns a known bytes value.
+func NewVmBytes(v []byte) VmValue {
+	return VmValue{T: VmTypeBytes, src: vmByteConst{v: v}}
+}
+
+// Push pushes the value onto the branch stack.
+func (b *VmBranch) Push(v VmValue) {
+	b.push(v)
+}
+
+// Pop pops a value of any type from the branch stack.
+func (b *VmBranch) Pop() VmValue {
+	return b.pop(VmTypeAny)
+}
+
+// RunAll steps through all of the branches until they exit or the number of branches reaches max.
+// It returns false if not all of the branches could be run to the end.
+func (v *Vm) RunAll(max int) bool {
+	for v.Branch != nil && v.Error == nil && len(v.Branches) < max {
+		v.Step()
+	}
+
+	return v.Branch == nil && v.Error == nil
+}
+
+// fresh creates a new vm with the same configuration.
+func (v *Vm) fresh() *Vm {
+	nv := NewVm(v.Process)
+	nv.Txn = v.Txn
+	nv.Args = v.Args
+	nv.Breakpoints = v.Breakpoints
+	nv.Watchpoints = v.Watchpoints
+	nv.hooks = v.hooks
+
+	return nv
+}
+
+// restore replays the first n recorded steps on a fresh copy of the vm.
+func (v *Vm) restore(n int) {
+	nv := v.fresh()
+
+	for _, id := range v.History[:n] {
+		nv.Switch(id)
+		nv.Step()
+	}
+
+	for _, b := range nv.Branches {
+		b.vm = v
+	}
+
+	*v = *nv
+}
+
+// StepBack reverts the last step, returns false if there is no step to revert.
+func (v *Vm) StepBack() bool {
+	if len(v.History) == 0 {
+		return false
+	}
+
+	v.restore(len(v.History) - 1)
+	return true
+}
+
+// ReverseContinue reverts the steps back to the previous breakpoint or watchpoint hit or to the start.
+func (v *Vm) ReverseContinue() {
+	if len(v.History) == 0 {
+		return
+	}
+
+	nv := v.fresh()
+
+	target := 0
+
+	for i, id := range v.History[:len(v.History)-1] {
+		nv.Switch(id)
+		nv.Step()
+
+		if len(nv.Triggered) > 0 || len(nv.Watched) > 0 {
+			target = i + 1
+		}
+	}
+
+	v.restore(target)
+}
+
+// Run steps through the branches until they exit or a breakpoint or watchpoint is hit.
+// It returns the VM error, e.g. a StackUnderflowError, or the error of the first failed branch, e.g. an AssertFailedError.
+func (v *Vm) Run() error {
 	for v.Branch != nil && v.Error == nil {
 		v.Step()
 
-		if len(v.Triggered) > 0 {
-			return
+		if len(v.Triggered) > 0 || len(v.Watched) > 0 {
+			break
 		}
 	}
+
+	return v.Err()
+}
+
+// Err returns the VM error or the error of the first failed branch.
+func (v *Vm) Err() error {
+	if err := vmError(v.Error); err != nil {
+		return err
+	}
+
+	for _, b := range v.Branches {
+		if b.Err != nil {
+			return b.Err
+		}
+	}
+
+	return nil
 }
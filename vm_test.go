@@ -1,6 +1,21 @@
 package teal
 
-import "testing"
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/pkg/errors"
+)
 
 func TestCostly(t *testing.T) {
 	res := Process(`#pragma version 7
@@ -11,6 +26,40 @@ func TestCostly(t *testing.T) {
 	vm.Run()
 }
 
+func TestDupN(t *testing.T) {
+	for _, n := range []int{0, 1, 255} {
+		res := Process(fmt.Sprintf("#pragma version 8\nint 1\ndupn %d", n))
+
+		vm := NewVm(res)
+		vm.Run()
+
+		if l := len(vm.Branches[0].Stack.Items); l != n+1 {
+			t.Errorf("expected %d items after dupn %d, got: %d", n+1, n, l)
+		}
+	}
+}
+
+func TestLogs(t *testing.T) {
+	res := Process(`#pragma version 8
+	byte "hello"
+	log
+	int 1
+	return`)
+
+	vm := NewVm(res)
+	vm.Run()
+
+	b := vm.Branches[0]
+	if len(b.Logs) != 1 {
+		t.Fatalf("unexpected logs count: %d", len(b.Logs))
+	}
+
+	bs, ok := b.Logs[0].Bytes()
+	if !ok || string(bs) != "hello" {
+		t.Errorf("unexpected log value: %s", b.Logs[0])
+	}
+}
+
 func BenchmarkVm(b *testing.B) {
 	res := Process(`
 	#pragma version 8
@@ -46,3 +95,376 @@ func BenchmarkInfiniteLoop(b *testing.B) {
 		vm.Run()
 	}
 }
+
+func TestWatchpoints(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+store 1
+byte "a"
+int 1
+app_global_put
+byte "b"
+int 2
+app_global_put
+int 1
+int 2
+int 3
+pop
+pop
+return`)
+
+	vm := NewVm(res)
+	vm.SetWatchpoints([]VmWatchpoint{
+		{Kind: VmWatchScratch, Slot: 1},
+		{Kind: VmWatchState, Scope: VmAccessGlobal, Key: []byte("b")},
+		{Kind: VmWatchStack, Depth: 2},
+	})
+
+	var lines []int
+	var hits []int
+
+	for {
+		vm.Run()
+		if len(vm.Watched) == 0 {
+			break
+		}
+
+		for _, ws := range vm.Watched {
+			hits = append(hits, ws...)
+		}
+
+		lines = append(lines, res.SourceLine(vm.Branch.Line))
+	}
+
+	if len(hits) != 3 || hits[0] != 0 || hits[1] != 1 || hits[2] != 2 {
+		t.Fatalf("unexpected watchpoint hits: %v", hits)
+	}
+
+	if lines[0] != 3 || lines[1] != 9 || lines[2] != 12 {
+		t.Errorf("unexpected watchpoint lines: %v", lines)
+	}
+}
+
+func TestStepBack(t *testing.T) {
+	res := Process(`#pragma version 8
+int 1
+store 0
+int 2
+store 0
+int 3
+store 0
+int 1
+return`)
+
+	vm := NewVm(res)
+	vm.SetBreakpoints([]int{3})
+
+	for i := 0; i < 6; i++ {
+		vm.Step()
+	}
+
+	if vm.Scratch.Items[0].String() != "uint64: 3" {
+		t.Fatalf("unexpected scratch value: %s", vm.Scratch.Items[0])
+	}
+
+	if !vm.StepBack() || vm.Scratch.Items[0].String() != "uint64: 2" || len(vm.History) != 5 {
+		t.Errorf("unexpected state after step back: %s", vm.Scratch.Items[0])
+	}
+
+	vm.ReverseContinue()
+
+	if res.SourceLine(vm.Branch.Line) != 3 || vm.Scratch.Items[0].String() != "uint64: 1" {
+		t.Errorf("unexpected state after reverse continue: line %d, %s", res.SourceLine(vm.Branch.Line), vm.Scratch.Items[0])
+	}
+
+	vm.ReverseContinue()
+
+	if len(vm.History) != 0 || vm.Scratch.Items[0].T != VmTypeNone {
+		t.Errorf("expected to revert to the start")
+	}
+}
+
+func TestOpHooks(t *testing.T) {
+	res := Process(`#pragma version 8
+byte 0x01
+byte 0x02
+byte 0x03
+ed25519verify
+assert
+int 1
+return`)
+
+	run := func(hook VmOpHook) VmExit {
+		vm := NewVm(res)
+		vm.Branches[0].Budget = LogicSigBudget
+		vm.RegisterOpHook("ed25519verify", hook)
+		vm.RunAll(10)
+
+		return vm.Branches[0].Exit
+	}
+
+	if exit := run(VmStubHook(3, NewVmUint64(1))); exit != VmExitApprove {
+		t.Errorf("unexpected exit with a succeeding hook: %s", exit)
+	}
+
+	if exit := run(VmStubHook(3, NewVmUint64(0))); exit != VmExitFail {
+		t.Errorf("unexpected exit with a failing hook: %s", exit)
+	}
+
+	if exit := run(func(b *VmBranch) error { return errors.New("mock failure") }); exit != VmExitFail {
+		t.Errorf("unexpected exit with an erroring hook: %s", exit)
+	}
+}
+
+func runExit(t *testing.T, source string) VmExit {
+	res := Process(source)
+	for _, d := range res.Diagnostics {
+		if d.Severity() == DiagErr {
+			t.Fatalf("unexpected diagnostic: %s", d)
+		}
+	}
+
+	vm := NewVm(res)
+	vm.Branches[0].Budget = LogicSigBudget
+	vm.RunAll(10)
+
+	return vm.Branches[0].Exit
+}
+
+func TestHashes(t *testing.T) {
+	tests := []struct {
+		op   string
+		data string
+		hash string
+	}{
+		{"sha256", `"abc"`, "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha512_256", `"abc"`, "0x53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{"sha3_256", `""`, "0xa7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{"keccak256", `""`, "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+	}
+
+	for _, test := range tests {
+		for _, hash := range []string{test.hash, test.hash[:len(test.hash)-2] + "00"} {
+			expected := VmExitApprove
+			if hash != test.hash {
+				expected = VmExitReject
+			}
+
+			exit := runExit(t, fmt.Sprintf("#pragma version 8\nbyte %s\n%s\nbyte %s\n==\nreturn", test.data, test.op, hash))
+			if exit != expected {
+				t.Errorf("unexpected %s exit with hash %s: %s", test.op, hash, exit)
+			}
+		}
+	}
+}
+
+func TestEd25519VerifyBare(t *testing.T) {
+	pk, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("data")
+	sig := ed25519.Sign(sk, data)
+
+	for _, test := range []struct {
+		data     string
+		expected VmExit
+	}{
+		{"data", VmExitApprove},
+		{"other", VmExitReject},
+	} {
+		exit := runExit(t, fmt.Sprintf("#pragma version 8\nbyte %q\nbyte 0x%x\nbyte 0x%x\ned25519verify_bare\nreturn", test.data, sig, pk))
+		if exit != test.expected {
+			t.Errorf("unexpected exit for data %s: %s", test.data, exit)
+		}
+	}
+
+	// malformed keys fail the program
+	if exit := runExit(t, fmt.Sprintf("#pragma version 8\nbyte %q\nbyte 0x%x\nbyte 0x01\ned25519verify_bare\npop\nint 1\nreturn", data, sig)); exit != VmExitFail {
+		t.Errorf("unexpected exit for a malformed key: %s", exit)
+	}
+}
+
+func TestEcdsaVerify(t *testing.T) {
+	hash := sha256.Sum256([]byte("data"))
+	other := sha256.Sum256([]byte("other"))
+
+	r1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, r1, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k1, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k1sig := secp256k1ecdsa.Sign(k1, hash[:]).Serialize()
+	k1r, k1s := parseDER(t, k1sig)
+	k1pk := k1.PubKey().SerializeUncompressed()
+
+	tests := []struct {
+		curve string
+		r, s  []byte
+		x, y  []byte
+	}{
+		{"Secp256r1", r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32)), r1.X.FillBytes(make([]byte, 32)), r1.Y.FillBytes(make([]byte, 32))},
+		{"Secp256k1", k1r, k1s, k1pk[1:33], k1pk[33:]},
+	}
+
+	for _, test := range tests {
+		for _, h := range [][]byte{hash[:], other[:]} {
+			expected := VmExitApprove
+			if string(h) != string(hash[:]) {
+				expected = VmExitReject
+			}
+
+			exit := runExit(t, fmt.Sprintf("#pragma version 8\nbyte 0x%x\nbyte 0x%x\nbyte 0x%x\nbyte 0x%x\nbyte 0x%x\necdsa_verify %s\nreturn", h, test.r, test.s, test.x, test.y, test.curve))
+			if exit != expected {
+				t.Errorf("unexpected %s exit: %s", test.curve, exit)
+			}
+		}
+	}
+}
+
+// parseDER returns the 32 bytes r and s values of a DER encoded signature.
+func parseDER(t *testing.T, sig []byte) ([]byte, []byte) {
+	var v struct {
+		R, S *big.Int
+	}
+
+	if _, err := asn1.Unmarshal(sig, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return v.R.FillBytes(make([]byte, 32)), v.S.FillBytes(make([]byte, 32))
+}
+
+func TestVmErrors(t *testing.T) {
+	run := func(source string) error {
+		vm := NewVm(Process(source))
+		return vm.Run()
+	}
+
+	var assertErr *AssertFailedError
+	if err := run("#pragma version 8\nint 1\nint 0\nassert"); !errors.As(err, &assertErr) || assertErr.Line != 3 || assertErr.PC != 3 {
+		t.Errorf("unexpected assert error: %#v", err)
+	}
+
+	var underflowErr *StackUnderflowError
+	if err := run("#pragma version 8\npop"); !errors.As(err, &underflowErr) || underflowErr.Line != 1 {
+		t.Errorf("unexpected stack underflow error: %#v", err)
+	}
+
+	var budgetErr *BudgetExceededError
+	if err := run("#pragma version 8\nbyte 0x01\nbyte 0x02\nbyte 0x03\ned25519verify\nreturn"); !errors.As(err, &budgetErr) || budgetErr.Cost != 1900 || budgetErr.Line != 4 {
+		t.Errorf("unexpected budget error: %#v", err)
+	}
+
+	var failedErr *VmFailedError
+	if err := run("#pragma version 8\nerr"); !errors.As(err, &failedErr) || failedErr.Reason != "err" {
+		t.Errorf("unexpected failure error: %#v", err)
+	}
+
+	if err := run("#pragma version 8\nint 1\nreturn"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestConcatLimit(t *testing.T) {
+	res := Process("#pragma version 8\nbyte 0x0102\nl1:\ndup\nconcat\ndup\nlen\nbnz l1\nbtoi\nreturn")
+
+	vm := NewVm(res)
+	vm.RunAll(100)
+
+	failed := false
+	for _, b := range vm.Branches {
+		if b.Exit == VmExitFail && b.Err != nil && strings.Contains(b.Err.Error(), "concat result exceeds 4096 bytes") {
+			failed = true
+		}
+	}
+
+	if !failed {
+		t.Error("expected a branch failing on the concat limit")
+	}
+}
+
+// the concatenations of the unknown values in the loop are collapsed instead of growing with each iteration
+func TestConcatUnknownLoop(t *testing.T) {
+	res := Process("#pragma version 8\ntxna ApplicationArgs 0\nl1:\ndup\nconcat\ndup\nlen\nbnz l1\nbtoi\nreturn")
+
+	vm := NewVm(res)
+	vm.RunAll(1000)
+
+	for _, b := range vm.Branches {
+		for _, v := range b.Stack.Items {
+			if c, ok := v.src.(vmConcatValue); ok && len(c.parts) > vmConcatMaxParts {
+				t.Errorf("unexpected concat parts: %d", len(c.parts))
+			}
+		}
+	}
+}
+
+func TestVmInclude(t *testing.T) {
+	files := map[string]string{
+		"lib/one.teal": `one:
+int 1
+retsub`,
+	}
+
+	res := Process(`#pragma version 8
+#include "lib/one.teal"
+callsub one
+return`, WithPath("main.teal"), WithReadFile(testReadFile(files)))
+
+	vm := NewVm(res)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b := vm.Branches[0]
+	if b.Exit != VmExitApprove {
+		t.Errorf("unexpected exit: %s", b.Exit)
+	}
+
+	if len(b.Trace) != 4 {
+		t.Errorf("unexpected trace: %v", b.Trace)
+	}
+}
+
+func TestVmIncludeEnd(t *testing.T) {
+	files := map[string]string{
+		"lib.teal": `f:
+int 1`,
+	}
+
+	res := Process(`#pragma version 8
+#include "lib.teal"
+b f`, WithPath("main.teal"), WithReadFile(testReadFile(files)))
+
+	vm := NewVm(res)
+	vm.Run()
+
+	// the branch running past the end of the included file ends the program
+	if b := vm.Branches[0]; b.Exit != VmExitApprove {
+		t.Errorf("unexpected exit: %s", b.Exit)
+	}
+}
+
+func TestVmUnknownLabel(t *testing.T) {
+	res := Process("#pragma version 8\ncallsub missing\nint 1\nreturn")
+
+	vm := NewVm(res)
+	vm.Run()
+
+	b := vm.Branches[0]
+	if b.Exit != VmExitFail || b.Err == nil || b.Err.Error() != "unknown label: missing" {
+		t.Errorf("expected unknown label failure, got: %s %v", b.Exit, b.Err)
+	}
+}
@@ -0,0 +1,104 @@
+package teal
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The VM errors hold the source Line and the listing index - PC - of the failing op.
+
+// StackUnderflowError is an op popping a value from the empty stack.
+type StackUnderflowError struct {
+	Line     int
+	PC       int
+	Expected VmDataType
+}
+
+func (e *StackUnderflowError) Error() string {
+	return fmt.Sprintf("empty stack - expected: %s", e.Expected)
+}
+
+// BudgetExceededError is an op costing more than the remaining budget.
+type BudgetExceededError struct {
+	Line   int
+	PC     int
+	Cost   int
+	Budget int
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded - cost: %d, remaining budget: %d", e.Cost, e.Budget)
+}
+
+// InvalidFieldAccessError is an op reading a field that is not known to the VM.
+type InvalidFieldAccessError struct {
+	Line  int
+	PC    int
+	Field string
+}
+
+func (e *InvalidFieldAccessError) Error() string {
+	return fmt.Sprintf("invalid field access: %s", e.Field)
+}
+
+// AssertFailedError is an assert of a zero value.
+type AssertFailedError struct {
+	Line int
+	PC   int
+}
+
+func (e *AssertFailedError) Error() string {
+	return "assert failed"
+}
+
+// VmFailedError is any other failure, e.g. the err op, an invalid op argument or an invalid stack at the end.
+type VmFailedError struct {
+	Line   int
+	PC     int
+	Reason string
+}
+
+func (e *VmFailedError) Error() string {
+	return e.Reason
+}
+
+// sourceLine returns the source line of the listing index, -1 when the branch is past the end of the program.
+func (b *VmBranch) sourceLine(pc int) int {
+	if pc < 0 || pc >= len(b.vm.listing) {
+		return -1
+	}
+
+	return b.vm.sourceLine(pc)
+}
+
+func (b *VmBranch) invalidField(f fmt.Stringer) error {
+	return &InvalidFieldAccessError{Line: b.sourceLine(b.Line), PC: b.Line, Field: f.String()}
+}
+
+// failf exits the branch with a VmFailedError.
+func (b *VmBranch) failf(format string, args ...any) {
+	b.failWith(&VmFailedError{Line: b.sourceLine(b.Line), PC: b.Line, Reason: fmt.Sprintf(format, args...)})
+}
+
+// failWith exits the branch with the error unless it already failed with another one.
+func (b *VmBranch) failWith(err error) {
+	if b.Err == nil {
+		b.Err = err
+	}
+
+	b.Exit = VmExitFail
+	b.exit()
+}
+
+// vmError converts the recovered panic value to an error.
+func vmError(v any) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return v
+	default:
+		return errors.Errorf("%v", v)
+	}
+}
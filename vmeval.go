@@ -0,0 +1,395 @@
+package teal
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// vmEvalExpr evaluates the watch expressions against the paused branch: the stack[i] items, negative from the top,
+// the scratch[j] slots, the txn.Field, txn.Field[i], gtxn[i].Field and global.Field values, the uint64, 0x hex and
+// quoted string literals, and the +, -, *, /, % and comparison operators with parentheses.
+type vmEvalExpr struct {
+	b *VmBranch
+
+	s string
+	i int
+}
+
+// Evaluate evaluates the watch expression against the current state of the branch, e.g. stack[-1] + scratch[3] or txn.NumAppArgs > 1.
+// The values not known to the VM are returned with their type only.
+func (b *VmBranch) Evaluate(expr string) (VmValue, error) {
+	e := &vmEvalExpr{b: b, s: expr}
+
+	v, err := e.compare()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	e.space()
+	if e.i < len(e.s) {
+		return VmValue{}, errors.Errorf("unexpected %q at %d in expression: %s", e.s[e.i], e.i, expr)
+	}
+
+	return v, nil
+}
+
+func (e *vmEvalExpr) space() {
+	for e.i < len(e.s) && unicode.IsSpace(rune(e.s[e.i])) {
+		e.i++
+	}
+}
+
+// accept consumes the first of the operators found at the current position.
+func (e *vmEvalExpr) accept(ops ...string) string {
+	e.space()
+
+	for _, op := range ops {
+		if strings.HasPrefix(e.s[e.i:], op) {
+			e.i += len(op)
+			return op
+		}
+	}
+
+	return ""
+}
+
+func (e *vmEvalExpr) compare() (VmValue, error) {
+	x, err := e.sum()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	op := e.accept("==", "!=", "<=", ">=", "<", ">")
+	if op == "" {
+		return x, nil
+	}
+
+	y, err := e.sum()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	switch op {
+	case "==", "!=":
+		if x.T != VmTypeAny && y.T != VmTypeAny && x.T != y.T {
+			return VmValue{}, errors.Errorf("%s compares %s with %s", op, x.T, y.T)
+		}
+		return vmEqual(x, y, op == "=="), nil
+	}
+
+	if err := e.uints(op, x, y); err != nil {
+		return VmValue{}, err
+	}
+
+	switch op {
+	case "<":
+		return vmCompare(x, y, func(x, y uint64) bool { return x < y }), nil
+	case "<=":
+		return vmCompare(x, y, func(x, y uint64) bool { return x <= y }), nil
+	case ">":
+		return vmCompare(x, y, func(x, y uint64) bool { return x > y }), nil
+	default:
+		return vmCompare(x, y, func(x, y uint64) bool { return x >= y }), nil
+	}
+}
+
+// uints checks the operands of the arithmetic and the ordering operators.
+func (e *vmEvalExpr) uints(op string, vs ...VmValue) error {
+	for _, v := range vs {
+		if v.T == VmTypeBytes {
+			return errors.Errorf("%s expects uint64 operands, got: %s", op, v)
+		}
+	}
+
+	return nil
+}
+
+// arith applies the operator to the known values, the result is unknown if any of them is.
+func (e *vmEvalExpr) arith(op string, x VmValue, y VmValue) (VmValue, error) {
+	if err := e.uints(op, x, y); err != nil {
+		return VmValue{}, err
+	}
+
+	xv, xok := x.Uint64()
+	yv, yok := y.Uint64()
+
+	if !xok || !yok {
+		return VmValue{T: VmTypeUint64}, nil
+	}
+
+	switch op {
+	case "+":
+		v, c := bits.Add64(xv, yv, 0)
+		if c != 0 {
+			return VmValue{}, errors.New("+ overflows uint64")
+		}
+		return vmUint64(v), nil
+	case "-":
+		v, c := bits.Sub64(xv, yv, 0)
+		if c != 0 {
+			return VmValue{}, errors.New("- underflows uint64")
+		}
+		return vmUint64(v), nil
+	case "*":
+		hi, lo := bits.Mul64(xv, yv)
+		if hi != 0 {
+			return VmValue{}, errors.New("* overflows uint64")
+		}
+		return vmUint64(lo), nil
+	}
+
+	if yv == 0 {
+		return VmValue{}, errors.Errorf("%s by zero", op)
+	}
+
+	if op == "/" {
+		return vmUint64(xv / yv), nil
+	}
+
+	return vmUint64(xv % yv), nil
+}
+
+func (e *vmEvalExpr) sum() (VmValue, error) {
+	v, err := e.product()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	for {
+		op := e.accept("+", "-")
+		if op == "" {
+			return v, nil
+		}
+
+		r, err := e.product()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		v, err = e.arith(op, v, r)
+		if err != nil {
+			return VmValue{}, err
+		}
+	}
+}
+
+func (e *vmEvalExpr) product() (VmValue, error) {
+	v, err := e.factor()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	for {
+		op := e.accept("*", "/", "%")
+		if op == "" {
+			return v, nil
+		}
+
+		r, err := e.factor()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		v, err = e.arith(op, v, r)
+		if err != nil {
+			return VmValue{}, err
+		}
+	}
+}
+
+// word reads the identifier or the number at the current position.
+func (e *vmEvalExpr) word() string {
+	e.space()
+
+	start := e.i
+	for e.i < len(e.s) {
+		c := rune(e.s[e.i])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			break
+		}
+		e.i++
+	}
+
+	return e.s[start:e.i]
+}
+
+// index reads the [i] index, the negative values are allowed for the stack items.
+func (e *vmEvalExpr) index() (int, error) {
+	if e.accept("[") == "" {
+		return 0, errors.Errorf("missing [ at %d in expression: %s", e.i, e.s)
+	}
+
+	neg := e.accept("-") != ""
+
+	w := e.word()
+	i, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, errors.Errorf("invalid index %q in expression: %s", w, e.s)
+	}
+
+	if e.accept("]") == "" {
+		return 0, errors.Errorf("missing ] at %d in expression: %s", e.i, e.s)
+	}
+
+	if neg {
+		i = -i
+	}
+
+	return i, nil
+}
+
+// field reads the .Field name.
+func (e *vmEvalExpr) field() (string, error) {
+	if e.accept(".") == "" {
+		return "", errors.Errorf("missing . at %d in expression: %s", e.i, e.s)
+	}
+
+	name := e.word()
+	if name == "" {
+		return "", errors.Errorf("missing field name at %d in expression: %s", e.i, e.s)
+	}
+
+	return name, nil
+}
+
+func (e *vmEvalExpr) factor() (VmValue, error) {
+	if e.accept("(") != "" {
+		v, err := e.compare()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		if e.accept(")") == "" {
+			return VmValue{}, errors.Errorf("missing ) in expression: %s", e.s)
+		}
+
+		return v, nil
+	}
+
+	if e.accept("\"") != "" {
+		end := strings.IndexByte(e.s[e.i:], '"')
+		if end < 0 {
+			return VmValue{}, errors.Errorf("missing \" in expression: %s", e.s)
+		}
+
+		v := e.s[e.i : e.i+end]
+		e.i += end + 1
+
+		return NewVmBytes([]byte(v)), nil
+	}
+
+	start := e.i
+	w := e.word()
+
+	switch {
+	case w == "":
+		return VmValue{}, errors.Errorf("missing operand at %d in expression: %s", start, e.s)
+	case strings.HasPrefix(w, "0x"):
+		bs, err := hex.DecodeString(w[2:])
+		if err != nil {
+			return VmValue{}, errors.Wrapf(err, "invalid hex bytes %s", w)
+		}
+		return NewVmBytes(bs), nil
+	case unicode.IsDigit(rune(w[0])):
+		v, err := strconv.ParseUint(w, 10, 64)
+		if err != nil {
+			return VmValue{}, errors.Errorf("invalid uint64 %s", w)
+		}
+		return vmUint64(v), nil
+	}
+
+	switch w {
+	case "stack":
+		i, err := e.index()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		items := e.b.Stack.Items
+		if i < 0 {
+			i += len(items)
+		}
+
+		if i < 0 || i >= len(items) {
+			return VmValue{}, errors.Errorf("stack index out of range: %d, stack size: %d", i, len(items))
+		}
+
+		return items[i], nil
+	case "scratch":
+		i, err := e.index()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		if i < 0 || i > 255 {
+			return VmValue{}, errors.Errorf("invalid scratch slot: %d", i)
+		}
+
+		return e.b.vm.Scratch.Items[i], nil
+	case "txn":
+		return e.txn(-1)
+	case "gtxn":
+		i, err := e.index()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		return e.txn(i)
+	case "global":
+		name, err := e.field()
+		if err != nil {
+			return VmValue{}, err
+		}
+
+		spec, ok := globalFieldSpecByName[name]
+		if !ok {
+			return VmValue{}, errors.Errorf("unknown global field: %s", name)
+		}
+
+		return vmGlobalField(spec), nil
+	}
+
+	return VmValue{}, errors.Errorf("unknown name %s in expression: %s", w, e.s)
+}
+
+// txn reads the field of the current transaction or of the group transaction, the known values are the current transaction ones.
+func (e *vmEvalExpr) txn(group int) (VmValue, error) {
+	name, err := e.field()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	spec, ok := txnFieldSpecByName[name]
+	if !ok {
+		return VmValue{}, errors.Errorf("unknown txn field: %s", name)
+	}
+
+	current := group < 0
+	if gi, ok := e.b.vm.Txn[GroupIndex]; ok && uint64(group) == gi {
+		current = true
+	}
+
+	if !spec.array {
+		if v, ok := e.b.vm.Txn[spec.field]; ok && current {
+			return vmUint64(v), nil
+		}
+
+		return vmTxnField(spec), nil
+	}
+
+	i, err := e.index()
+	if err != nil {
+		return VmValue{}, err
+	}
+
+	if spec.field == ApplicationArgs && current && i >= 0 && i < len(e.b.vm.Args) {
+		return NewVmBytes(e.b.vm.Args[i]), nil
+	}
+
+	return vmTxnField(spec), nil
+}
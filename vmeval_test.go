@@ -0,0 +1,50 @@
+package teal
+
+import (
+	"testing"
+)
+
+func TestVmEvaluate(t *testing.T) {
+	res := Process("#pragma version 8\nint 5\nstore 3\nint 7\nbyte \"abc\"\nint 1\nreturn")
+
+	vm := NewVm(res)
+	vm.Txn = map[TxnField]uint64{NumAppArgs: 2}
+	vm.Args = [][]byte{[]byte("hello")}
+
+	for i := 0; i < 4; i++ {
+		vm.Step()
+	}
+
+	tests := map[string]string{
+		"stack[0]":                          "uint64: 7",
+		"stack[-1]":                         "bytes: b64 YWJj",
+		"scratch[3] * (stack[0] - 2)":       "uint64: 25",
+		"scratch[3] + 1 >= 6":               "uint64: 1",
+		"stack[-1] == \"abc\"":              "uint64: 1",
+		"stack[1] != 0x616263":              "uint64: 0",
+		"txn.NumAppArgs > 1":                "uint64: 1",
+		"txn.ApplicationArgs[0]":            "bytes: b64 aGVsbG8=",
+		"txn.Fee + 1":                       "uint64",
+		"gtxn[1].NumAppArgs":                "uint64",
+		"global.ZeroAddress":                "bytes: ZeroAddress",
+		"txn.ApplicationArgs[1] == \"abc\"": "uint64",
+	}
+
+	for expr, expected := range tests {
+		v, err := vm.Branch.Evaluate(expr)
+		if err != nil {
+			t.Errorf("failed to evaluate %s: %s", expr, err)
+			continue
+		}
+
+		if v.String() != expected {
+			t.Errorf("expected %s = %s, got: %s", expr, expected, v)
+		}
+	}
+
+	for _, expr := range []string{"stack[2]", "scratch[256]", "txn.Unknown", "1 / 0", "stack[-1] + 1", "(1 + 2", "1 2", "foo"} {
+		if _, err := vm.Branch.Evaluate(expr); err == nil {
+			t.Errorf("expected %s to fail", expr)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package teal
+
+import "github.com/pkg/errors"
+
+// amountFields are the transaction fields with the amounts provided by the caller
+var amountFields = map[TxnField]bool{
+	Amount:           true,
+	AssetAmount:      true,
+	Fee:              true,
+	ConfigAssetTotal: true,
+	ApplicationArgs:  true,
+}
+
+// txnFieldOf returns the transaction field read by the op.
+func txnFieldOf(op Op) (TxnField, bool) {
+	switch op := op.(type) {
+	case *TxnExpr:
+		return op.Field, true
+	case *TxnaExpr:
+		return op.Field, true
+	case *TxnasExpr:
+		return op.Field, true
+	case *GtxnExpr:
+		return op.Field, true
+	case *GtxnaExpr:
+		return op.Field, true
+	case *GtxnasExpr:
+		return op.Field, true
+	case *GtxnsExpr:
+		return op.Field, true
+	case *GtxnsaExpr:
+		return op.Field, true
+	case *GtxnsasExpr:
+		return op.Field, true
+	case *ItxnExpr:
+		return op.Field, true
+	case *ItxnaExpr:
+		return op.Field, true
+	case *ItxnasExpr:
+		return op.Field, true
+	case *GitxnExpr:
+		return op.Field, true
+	case *GitxnaExpr:
+		return op.Field, true
+	case *GitxnasExpr:
+		return op.Field, true
+	}
+
+	return 0, false
+}
+
+// WideMath is a suggestion to use the wide math ops for the arithmetic on the amounts from the transaction fields.
+type WideMath struct {
+	// Index is the listing index of the * or + op
+	Index int
+
+	// Div is the listing index of the / op of the a*b/c pattern that can be rewritten with mulw and divmodw, 0 if none
+	Div int
+}
+
+// WideMath returns the * and + ops on the amounts derived from the transaction fields that can overflow.
+// The values are tracked within the straight-line code, they are forgotten at the labels and the calls.
+func (r ProcessResult) WideMath() []WideMath {
+	var ws []WideMath
+
+	// whether the stack value is derived from an amount, top last
+	var stack []bool
+
+	for i, op := range r.Listing {
+		if f, ok := txnFieldOf(op); ok {
+			stack = append(stack, amountFields[f])
+			continue
+		}
+
+		switch op.(type) {
+		case *LabelExpr, *CallSubExpr, *RetSubExpr, *ProtoExpr:
+			stack = nil
+			continue
+		}
+
+		need, delta, ok := stackEffect(op)
+		if !ok {
+			stack = nil
+			continue
+		}
+
+		// the values below the known part of the stack are not derived from the amounts
+		for len(stack) < need {
+			stack = append([]bool{false}, stack...)
+		}
+
+		args := stack[len(stack)-need:]
+		stack = stack[:len(stack)-need]
+
+		derived := false
+		for _, a := range args {
+			derived = derived || a
+		}
+
+		switch op.(type) {
+		case *MulExpr:
+			if derived {
+				w := WideMath{Index: i}
+				if r.divPattern(i) {
+					w.Div = i + 2
+				}
+				ws = append(ws, w)
+			}
+		case *PlusExpr:
+			if args[0] && args[1] {
+				ws = append(ws, WideMath{Index: i})
+			}
+		}
+
+		for k := 0; k < need+delta; k++ {
+			stack = append(stack, derived)
+		}
+	}
+
+	return ws
+}
+
+// divPattern reports whether the * at the index is followed by a single value push and /.
+func (r ProcessResult) divPattern(i int) bool {
+	if i+2 >= len(r.Listing) || i+2 >= len(r.Sublines) {
+		return false
+	}
+
+	if _, ok := r.Listing[i+2].(*DivExpr); !ok {
+		return false
+	}
+
+	need, delta, ok := stackEffect(r.Listing[i+1])
+	if !ok || need != 0 || delta != 1 {
+		return false
+	}
+
+	if _, ok := r.Listing[i+1].(*LabelExpr); ok {
+		return false
+	}
+
+	return true
+}
+
+// wideMathFix rewrites a*b/c into mulw and divmodw, failing if the quotient does not fit in uint64 like the / would.
+func (r ProcessResult) wideMathFix(w WideMath) FixAction {
+	mul := r.Sublines[w.Index].Tokens
+	div := r.Sublines[w.Div].Tokens
+
+	return FixAction{
+		Title: "Rewrite with mulw and divmodw",
+		Kind:  FixWideMath,
+		Edits: []FixEdit{
+			{
+				Start:   FixPosition{Line: r.Sublines[w.Index].Line, Character: mul.Begin()},
+				End:     FixPosition{Line: r.Sublines[w.Index].Line, Character: mul.End()},
+				NewText: "mulw; int 0",
+			},
+			{
+				Start:   FixPosition{Line: r.Sublines[w.Div].Line, Character: div.Begin()},
+				End:     FixPosition{Line: r.Sublines[w.Div].Line, Character: div.End()},
+				NewText: "divmodw; pop; pop; swap; !; assert",
+			},
+		},
+	}
+}
+
+type WideMathRule struct{}
+
+func (r WideMathRule) Id() string {
+	return "LINT0018"
+}
+
+func (r WideMathRule) Desc() string {
+	return "Suggests the wide math ops for the arithmetic on the amounts from the transaction fields"
+}
+
+var WideMathRuleInstance = WideMathRule{}
+
+func checkWideMath(res *ProcessResult) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, w := range res.WideMath() {
+		if w.Index >= len(res.Sublines) {
+			continue
+		}
+
+		_, mul := res.Listing[w.Index].(*MulExpr)
+
+		var msg string
+		switch {
+		case w.Div != 0:
+			msg = "a*b/c on amounts from transaction fields can overflow - consider mulw and divmodw"
+		case mul:
+			msg = "* on amounts from transaction fields can overflow - consider mulw"
+		default:
+			msg = "+ of amounts from transaction fields can overflow - consider addw"
+		}
+
+		sub := res.Sublines[w.Index]
+		diags = append(diags, lintError{
+			error: errors.New(msg),
+			l:     sub.Line,
+			b:     sub.Tokens.Begin(),
+			e:     sub.Tokens.End(),
+			s:     DiagHint,
+			r:     WideMathRuleInstance.Id(),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,91 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWideMath(t *testing.T) {
+	res := Process(`#pragma version 8
+txn Amount
+int 3
+*
+int 100
+/
+gtxn 0 Amount
+gtxn 1 Amount
++
+int 1
+int 2
+*
++
+txna ApplicationArgs 0
+btoi
+int 10
+*
+int 2
++
+pop
+pop
+return`)
+
+	expected := []WideMath{
+		{Index: 3, Div: 5},
+		{Index: 8},
+		{Index: 16},
+	}
+
+	ws := res.WideMath()
+	if !reflect.DeepEqual(ws, expected) {
+		t.Fatalf("expected: %v, got: %v", expected, ws)
+	}
+
+	n := 0
+	for _, d := range res.Diagnostics {
+		if d.Rule() == WideMathRuleInstance.Id() {
+			if d.Severity() != DiagHint {
+				t.Errorf("unexpected severity: %s", d.Severity())
+			}
+			n++
+		}
+	}
+
+	if n != len(expected) {
+		t.Errorf("expected %d hints, got: %d", len(expected), n)
+	}
+}
+
+func TestWideMathFix(t *testing.T) {
+	src := "#pragma version 8\ntxn Amount\nint 3\n*\nint 100\n/\nreturn"
+
+	res := Process(src)
+
+	var fas []FixAction
+	for _, fa := range res.CodeActions(LinesRange{Start: 0, End: 6}) {
+		if fa.Kind == FixWideMath {
+			fas = append(fas, fa)
+		}
+	}
+
+	if len(fas) != 1 {
+		t.Fatalf("expected a single fix, got: %v", fas)
+	}
+
+	fixed, _ := ApplyFixes(src, fas)
+
+	expected := "#pragma version 8\ntxn Amount\nint 3\nmulw; int 0\nint 100\ndivmodw; pop; pop; swap; !; assert\nreturn"
+	if fixed != expected {
+		t.Fatalf("expected: %q, got: %q", expected, fixed)
+	}
+
+	res = Process(fixed)
+	for _, d := range res.Diagnostics {
+		if d.Severity() != DiagHint {
+			t.Errorf("unexpected diagnostic in the fixed program: %s", d)
+		}
+	}
+
+	if len(res.WideMath()) != 0 {
+		t.Errorf("unexpected suggestions in the fixed program: %v", res.WideMath())
+	}
+}